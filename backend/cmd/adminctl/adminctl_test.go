@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	// Missing file: a single local profile
+	cfg, err := loadConfig(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("missing file: %v", err)
+	}
+	p, err := cfg.resolve("")
+	if err != nil || p != localProfile {
+		t.Errorf("missing file resolve = %+v, %v, want local profile", p, err)
+	}
+
+	path := filepath.Join(dir, "adminctl.json")
+	os.WriteFile(path, []byte(`{
+		"default_profile": "prod",
+		"profiles": {
+			"prod": {"rest_url": "https://lb.example.com", "grpc_addr": "lb.example.com:443", "token": "s3cret"},
+			"staging": {"rest_url": "https://staging.example.com"}
+		}
+	}`), 0o600)
+	cfg, err = loadConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if p, _ := cfg.resolve(""); p.Token != "s3cret" || p.RESTURL != "https://lb.example.com" {
+		t.Errorf("default profile = %+v, want prod", p)
+	}
+	// Unset fields fall back to the local defaults
+	if p, _ := cfg.resolve("staging"); p.GRPCAddr != localProfile.GRPCAddr || p.Token != "" {
+		t.Errorf("staging = %+v, want local gRPC address and no token", p)
+	}
+	if _, err := cfg.resolve("dev"); err == nil {
+		t.Error("unknown profile resolved")
+	}
+
+	os.WriteFile(path, []byte(`{"profiles": {}}`), 0o600)
+	if _, err := loadConfig(path); err == nil {
+		t.Error("config without profiles accepted")
+	}
+}
+
+func TestParseImportCSV(t *testing.T) {
+	// An export file: extra rank and updated_at columns are ignored
+	in := "rank,leaderboard_id,player_name,score,achieved_at,updated_at\n" +
+		"1,level-42,Alice,300,2025-01-15T10:29:41.123456Z,2025-01-15T10:30:00Z\n" +
+		"2,,Bob,200,,\n"
+	entries, err := parseImportCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.LeaderboardID != "level-42" || e.PlayerName != "Alice" || e.Score != 300 ||
+		e.AchievedAt == nil || e.AchievedAt.Nanosecond() != 123456000 {
+		t.Errorf("entry 0 = %+v", e)
+	}
+	if e := entries[1]; e.LeaderboardID != "" || e.AchievedAt != nil {
+		t.Errorf("entry 1 = %+v, want default board and no achieved_at", e)
+	}
+
+	for name, in := range map[string]string{
+		"missing score column": "player_name\nAlice\n",
+		"invalid score":        "player_name,score\nAlice,lots\n",
+		"invalid achieved_at":  "player_name,score,achieved_at\nAlice,1,yesterday\n",
+	} {
+		if _, err := parseImportCSV(strings.NewReader(in)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestCopyNDJSON(t *testing.T) {
+	in := `{"player_name":"Alice","score":100}
+{"leaderboard_id":"level-2","player_name":"Bob","score":50}
+
+not json
+{"player_name":"Carol","score":10}`
+	var out strings.Builder
+	if err := copyNDJSON(&out, strings.NewReader(in), "level-1"); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"leaderboard_id":"level-1","player_name":"Alice","score":100}
+{"leaderboard_id":"level-2","player_name":"Bob","score":50}
+
+not json
+{"leaderboard_id":"level-1","player_name":"Carol","score":10}
+`
+	if out.String() != want {
+		t.Errorf("copyNDJSON() =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	if o, err := decodeImportOffset("eyJsIjoxMDAwLCJiIjo2NzQyMX0"); err != nil || o != (importOffset{Line: 1000, Bytes: 67421}) {
+		t.Errorf("decodeImportOffset() = %+v, %v", o, err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// apiError is the ErrorResponse body of a failed REST call
+type apiError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s (%d): %s", e.Code, e.Status, e.Message)
+	}
+	return fmt.Sprintf("%s (%d)", e.Code, e.Status)
+}
+
+// client calls the REST and gRPC APIs of one profile
+type client struct {
+	prof profile
+	http *http.Client
+}
+
+func newClient(p profile) *client {
+	return &client{prof: p, http: &http.Client{}}
+}
+
+// do sends a REST request with the profile token. in is JSON-encoded when non-nil.
+// The response body is returned open on 2xx; other statuses are decoded into an *apiError.
+func (c *client) do(ctx context.Context, method, path string, query url.Values, in any) (*http.Response, error) {
+	var body io.Reader
+	header := http.Header{}
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+		header.Set("Content-Type", "application/json")
+	}
+	return c.send(ctx, method, path, query, body, header)
+}
+
+// send is do with a raw request body and headers, e.g. a streamed upload
+func (c *client) send(ctx context.Context, method, path string, query url.Values, body io.Reader, header http.Header) (*http.Response, error) {
+	u := strings.TrimRight(c.prof.RESTURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if c.prof.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.prof.Token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	apiErr := &apiError{Status: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Code == "" {
+		apiErr.Code = http.StatusText(resp.StatusCode)
+	}
+	return nil, apiErr
+}
+
+// doJSON sends a REST request and decodes the response into out (skipped when nil)
+func (c *client) doJSON(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	resp, err := c.do(ctx, method, path, query, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// dial connects to the gRPC API; the profile token rides on every call as authorization metadata
+func (c *client) dial(ctx context.Context) (*grpc.ClientConn, error) {
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+		grpc.WithTimeout(5 * time.Second),
+	}
+	if token := c.prof.Token; token != "" {
+		opts = append(opts,
+			grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				return invoker(withToken(ctx, token), method, req, reply, cc, opts...)
+			}),
+			grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return streamer(withToken(ctx, token), desc, cc, method, opts...)
+			}),
+		)
+	}
+
+	conn, err := grpc.DialContext(ctx, c.prof.GRPCAddr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", c.prof.GRPCAddr, err)
+	}
+	return conn, nil
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
@@ -0,0 +1,871 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+)
+
+// errUsage is returned after a subcommand has printed its usage
+var errUsage = errors.New("invalid usage")
+
+// newFlagSet returns a subcommand flag set whose errors are returned instead of exiting
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: adminctl [global flags] %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses a subcommand and checks its positional argument count
+func parseArgs(fs *flag.FlagSet, args []string, nargs int) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// boardQuery returns the leaderboard_id query of a board flag, empty for the default board
+func boardQuery(board string) url.Values {
+	if board == "" {
+		return nil
+	}
+	return url.Values{"leaderboard_id": {board}}
+}
+
+// runDelete removes a player's score from a board
+func runDelete(ctx context.Context, c *client, args []string) error {
+	fs := newFlagSet("delete", "[-board ID] PLAYER")
+	board := fs.String("board", "", "leaderboard id (default global)")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	player := fs.Arg(0)
+	path := "/scores/" + url.PathEscape(player)
+	if err := c.doJSON(ctx, http.MethodDelete, path, boardQuery(*board), nil, nil); err != nil {
+		return fmt.Errorf("delete %s: %w", player, err)
+	}
+	fmt.Printf("🗑️  Deleted %s\n", player)
+	return nil
+}
+
+// resetResponse is the ResetLeaderboardResponse of DELETE /scores
+type resetResponse struct {
+	LeaderboardID     string `json:"leaderboard_id"`
+	Completed         bool   `json:"completed"`
+	Entries           int64  `json:"entries"`
+	ConfirmationToken string `json:"confirmation_token"`
+	ExpiresAt         string `json:"expires_at"`
+	Deleted           int64  `json:"deleted"`
+	SnapshotID        int64  `json:"snapshot_id"`
+}
+
+// runReset deletes every score of a board. The server's confirmation token is only
+// sent back once the operator has typed the board name (or passed -yes).
+func runReset(ctx context.Context, c *client, args []string) error {
+	fs := newFlagSet("reset", "[-board ID] [-snapshot] [-yes]")
+	board := fs.String("board", "", "leaderboard id (default global)")
+	snapshot := fs.Bool("snapshot", false, "copy the board into a snapshot before deleting")
+	yes := fs.Bool("yes", false, "skip the interactive confirmation")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	query := url.Values{"snapshot": {strconv.FormatBool(*snapshot)}}
+	if *board != "" {
+		query.Set("leaderboard_id", *board)
+	}
+	var preview resetResponse
+	if err := c.doJSON(ctx, http.MethodDelete, "/scores", query, nil, &preview); err != nil {
+		return fmt.Errorf("reset: %w", err)
+	}
+
+	fmt.Printf("⚠️  This deletes all %d scores of %q on %s", preview.Entries, preview.LeaderboardID, c.prof.RESTURL)
+	if *snapshot {
+		fmt.Print(" (a snapshot is taken first)")
+	}
+	fmt.Println()
+	if !*yes {
+		fmt.Printf("Type the leaderboard id to confirm: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != preview.LeaderboardID {
+			return fmt.Errorf("confirmation does not match, nothing deleted")
+		}
+	}
+
+	query.Set("confirm", preview.ConfirmationToken)
+	var res resetResponse
+	if err := c.doJSON(ctx, http.MethodDelete, "/scores", query, nil, &res); err != nil {
+		return fmt.Errorf("reset: %w", err)
+	}
+	fmt.Printf("🧹 Deleted %d scores of %s", res.Deleted, res.LeaderboardID)
+	if res.SnapshotID != 0 {
+		fmt.Printf(" (snapshot %d)", res.SnapshotID)
+	}
+	fmt.Println()
+	return nil
+}
+
+// restoreResponse is the RestoreLeaderboardResponse of POST /scores/restore
+type restoreResponse struct {
+	LeaderboardID string `json:"leaderboard_id"`
+	Completed     bool   `json:"completed"`
+	Entries       int64  `json:"entries"`
+	Added         int64  `json:"added"`
+	Removed       int64  `json:"removed"`
+	Changed       int64  `json:"changed"`
+	Unchanged     int64  `json:"unchanged"`
+	Samples       []struct {
+		PlayerName string `json:"player_name"`
+		Before     *int64 `json:"before"`
+		After      *int64 `json:"after"`
+	} `json:"samples"`
+	ConfirmationToken string `json:"confirmation_token"`
+	Deleted           int64  `json:"deleted"`
+	Restored          int64  `json:"restored"`
+	SnapshotID        int64  `json:"snapshot_id"`
+}
+
+// runRestore replaces a board with an export, read from a file or an http(s) URL such
+// as a presigned S3 URL, or with a snapshot kept by the server. The diff is shown and
+// the server's confirmation token only sent back once the operator has typed the
+// board name (or passed -yes).
+func runRestore(ctx context.Context, c *client, args []string) error {
+	fs := newFlagSet("restore", "[-board ID] [-format csv|json|ndjson] [-snapshot=false] [-yes] FILE|URL | -from-snapshot ID")
+	board := fs.String("board", "", "leaderboard id (default global)")
+	format := fs.String("format", "", "export format: csv, json or ndjson (default from the file extension)")
+	fromSnapshot := fs.Int64("from-snapshot", 0, "restore this server snapshot instead of a file")
+	snapshot := fs.Bool("snapshot", true, "copy the board into a snapshot before replacing it")
+	yes := fs.Bool("yes", false, "skip the interactive confirmation")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	// Either a file or a snapshot
+	if fs.NArg() > 1 || (fs.NArg() == 1) == (*fromSnapshot > 0) {
+		fs.Usage()
+		return errUsage
+	}
+
+	query := url.Values{"snapshot": {strconv.FormatBool(*snapshot)}}
+	if *board != "" {
+		query.Set("leaderboard_id", *board)
+	}
+	var body []byte
+	header := http.Header{}
+	if *fromSnapshot > 0 {
+		query.Set("snapshot_id", strconv.FormatInt(*fromSnapshot, 10))
+	} else {
+		src := fs.Arg(0)
+		name := strings.ToLower(src)
+		if u, err := url.Parse(src); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			name = strings.ToLower(u.Path)
+		}
+		compressed := strings.HasSuffix(name, ".zst")
+		if *format == "" {
+			*format = strings.TrimPrefix(filepath.Ext(strings.TrimSuffix(name, ".zst")), ".")
+		}
+		if *format != "csv" && *format != "json" && *format != "ndjson" {
+			return fmt.Errorf("format must be csv, json or ndjson")
+		}
+		query.Set("format", *format)
+
+		var err error
+		if body, err = readRestoreSource(ctx, src); err != nil {
+			return err
+		}
+		if compressed {
+			header.Set("Content-Encoding", "zstd")
+		}
+	}
+
+	restore := func(out *restoreResponse) error {
+		resp, err := c.send(ctx, http.MethodPost, "/scores/restore", query, bytes.NewReader(body), header)
+		if err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+		defer resp.Body.Close()
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	var preview restoreResponse
+	if err := restore(&preview); err != nil {
+		return err
+	}
+
+	fmt.Printf("⚠️  This replaces every score of %q on %s with %d entries:\n", preview.LeaderboardID, c.prof.RESTURL, preview.Entries)
+	fmt.Printf("   %d added, %d removed, %d changed, %d unchanged\n", preview.Added, preview.Removed, preview.Changed, preview.Unchanged)
+	score := func(v *int64) string {
+		if v == nil {
+			return "-"
+		}
+		return strconv.FormatInt(*v, 10)
+	}
+	for _, ch := range preview.Samples {
+		fmt.Printf("   %-20s %12s -> %s\n", ch.PlayerName, score(ch.Before), score(ch.After))
+	}
+	if *snapshot {
+		fmt.Println("   (a snapshot of the current scores is taken first)")
+	}
+	if !*yes {
+		fmt.Printf("Type the leaderboard id to confirm: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != preview.LeaderboardID {
+			return fmt.Errorf("confirmation does not match, nothing restored")
+		}
+	}
+
+	query.Set("confirm", preview.ConfirmationToken)
+	var res restoreResponse
+	if err := restore(&res); err != nil {
+		return err
+	}
+	fmt.Printf("♻️  Restored %d scores of %s (%d replaced)", res.Restored, res.LeaderboardID, res.Deleted)
+	if res.SnapshotID != 0 {
+		fmt.Printf(", undo with: adminctl restore -board %s -from-snapshot %d", res.LeaderboardID, res.SnapshotID)
+	}
+	fmt.Println()
+	return nil
+}
+
+// readRestoreSource reads an export from a local file or an http(s) URL, as is:
+// compressed exports are decompressed by the server
+func readRestoreSource(ctx context.Context, src string) ([]byte, error) {
+	if u, err := url.Parse(src); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return os.ReadFile(src)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download export: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download export: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// importEntry is one entry of POST /scores/batch
+type importEntry struct {
+	LeaderboardID string     `json:"leaderboard_id,omitempty"`
+	PlayerName    string     `json:"player_name"`
+	Score         int64      `json:"score"`
+	AchievedAt    *time.Time `json:"achieved_at,omitempty"`
+}
+
+// importResponse is the ImportScoresResponse of POST /scores/batch
+type importResponse struct {
+	Applied     int `json:"applied"`
+	NotImproved int `json:"not_improved"`
+	Invalid     int `json:"invalid"`
+	Failed      int `json:"failed"`
+	Results     []struct {
+		Index   int    `json:"index"`
+		Outcome string `json:"outcome"`
+		Reason  string `json:"reason"`
+	} `json:"results"`
+}
+
+// runImport sends a CSV or JSON file to POST /scores/batch in batches, or streams an
+// NDJSON file to POST /scores/import. Files ending in .zst are decompressed.
+func runImport(ctx context.Context, c *client, args []string) error {
+	fs := newFlagSet("import", "[-board ID] [-format csv|json|ndjson] [-batch N] [-resume TOKEN] [-retries N] FILE")
+	board := fs.String("board", "", "leaderboard id for entries without one (default global)")
+	format := fs.String("format", "", "input format: csv, json or ndjson (default from the file extension)")
+	batch := fs.Int("batch", 1000, "entries per request (at most the server's IMPORT_MAX_ENTRIES; csv and json)")
+	resume := fs.String("resume", "", "offset token of an interrupted ndjson import to resume")
+	retries := fs.Int("retries", 3, "times an interrupted ndjson import is resumed automatically")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	if *batch <= 0 {
+		return fmt.Errorf("-batch must be positive")
+	}
+
+	path := fs.Arg(0)
+	name := strings.ToLower(path)
+	compressed := strings.HasSuffix(name, ".zst")
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(strings.TrimSuffix(name, ".zst")), ".")
+	}
+	if *format == "ndjson" {
+		return runImportStream(ctx, c, path, compressed, *board, *resume, *retries)
+	}
+	f, err := openImportFile(path, compressed)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var entries []importEntry
+	switch *format {
+	case "csv":
+		entries, err = parseImportCSV(f)
+	case "json":
+		entries, err = parseImportJSON(f)
+	default:
+		return fmt.Errorf("format must be csv, json or ndjson")
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range entries {
+		if entries[i].LeaderboardID == "" {
+			entries[i].LeaderboardID = *board
+		}
+	}
+
+	// Batches are independent: a rejected batch is reported and the rest still imported
+	var total importResponse
+	var rejected int
+	for start := 0; start < len(entries); start += *batch {
+		end := min(start+*batch, len(entries))
+		var resp importResponse
+		if err := c.doJSON(ctx, http.MethodPost, "/scores/batch", nil, entries[start:end], &resp); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  entries %d-%d: %v\n", start+1, end, err)
+			rejected += end - start
+			continue
+		}
+		total.Applied += resp.Applied
+		total.NotImproved += resp.NotImproved
+		total.Invalid += resp.Invalid
+		total.Failed += resp.Failed
+		for _, r := range resp.Results {
+			if r.Outcome == "invalid" || r.Outcome == "failed" {
+				fmt.Fprintf(os.Stderr, "⚠️  entry %d (%s): %s: %s\n",
+					start+r.Index+1, entries[start+r.Index].PlayerName, r.Outcome, r.Reason)
+			}
+		}
+	}
+
+	fmt.Printf("📥 Imported %d entries: %d applied, %d not improved, %d invalid, %d failed, %d rejected\n",
+		len(entries), total.Applied, total.NotImproved, total.Invalid, total.Failed, rejected)
+	if total.Invalid+total.Failed+rejected > 0 {
+		return fmt.Errorf("%d entries not imported", total.Invalid+total.Failed+rejected)
+	}
+	return nil
+}
+
+// parseImportCSV reads entries from a CSV file with a header line. player_name and
+// score are required columns; leaderboard_id and achieved_at are optional, and other
+// columns (such as the rank of an export) are ignored.
+func parseImportCSV(r io.Reader) ([]importEntry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"player_name", "score"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("missing %s column", required)
+		}
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var entries []importEntry
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		e := importEntry{LeaderboardID: field(rec, "leaderboard_id"), PlayerName: field(rec, "player_name")}
+		if e.Score, err = strconv.ParseInt(field(rec, "score"), 10, 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid score %q", line, field(rec, "score"))
+		}
+		if s := field(rec, "achieved_at"); s != "" {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid achieved_at %q", line, s)
+			}
+			e.AchievedAt = &t
+		}
+		entries = append(entries, e)
+	}
+}
+
+// parseImportJSON reads entries from a JSON array, such as a JSON export
+func parseImportJSON(r io.Reader) ([]importEntry, error) {
+	var entries []importEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// openImportFile opens an import file, decompressing it when it is zstd-compressed
+func openImportFile(path string, compressed bool) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil || !compressed {
+		return f, err
+	}
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return readCloser{zr.IOReadCloser(), f}, nil
+}
+
+// readCloser closes a decompressor and the file under it
+type readCloser struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (r readCloser) Close() error {
+	r.ReadCloser.Close()
+	return r.file.Close()
+}
+
+// importProgress is an ImportProgress line of POST /scores/import
+type importProgress struct {
+	OffsetToken string `json:"offset_token"`
+	Lines       int64  `json:"lines"`
+	Applied     int    `json:"applied"`
+	NotImproved int    `json:"not_improved"`
+	Invalid     int    `json:"invalid"`
+	Failed      int    `json:"failed"`
+	Errors      []struct {
+		Line    int64  `json:"line"`
+		Outcome string `json:"outcome"`
+		Reason  string `json:"reason"`
+	} `json:"errors"`
+	Done  bool   `json:"done"`
+	Error string `json:"error"`
+}
+
+// importOffset is the position an offset token resumes at. Tokens are base64url JSON;
+// the CLI decodes them to skip the lines the server already committed.
+type importOffset struct {
+	Line  int64 `json:"l"`
+	Bytes int64 `json:"b"`
+}
+
+func decodeImportOffset(token string) (importOffset, error) {
+	var o importOffset
+	if token == "" {
+		return o, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(b, &o)
+	}
+	if err != nil {
+		return o, fmt.Errorf("invalid offset token %q", token)
+	}
+	return o, nil
+}
+
+// runImportStream streams an NDJSON file, zstd-compressed on the wire, to
+// POST /scores/import. When the transfer fails it resumes from the last committed
+// chunk, up to retries times, then prints the token to resume with later.
+func runImportStream(ctx context.Context, c *client, path string, compressed bool, board, token string, retries int) error {
+	var total importProgress
+	for attempt := 0; ; attempt++ {
+		last, err := importStreamOnce(ctx, c, path, compressed, board, token)
+		total.Applied += last.Applied
+		total.NotImproved += last.NotImproved
+		total.Invalid += last.Invalid
+		total.Failed += last.Failed
+		if last.OffsetToken != "" {
+			token, total.Lines = last.OffsetToken, last.Lines
+		}
+		if err == nil {
+			break
+		}
+		if attempt >= retries || ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  import stopped after line %d, resume with: -resume %s\n", total.Lines, token)
+			return fmt.Errorf("import %s: %w", path, err)
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  import interrupted after line %d (%v), resuming\n", total.Lines, err)
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+	}
+
+	fmt.Printf("📥 Imported %d lines: %d applied, %d not improved, %d invalid, %d failed\n",
+		total.Lines, total.Applied, total.NotImproved, total.Invalid, total.Failed)
+	if total.Invalid+total.Failed > 0 {
+		return fmt.Errorf("%d entries not imported", total.Invalid+total.Failed)
+	}
+	return nil
+}
+
+// importStreamOnce sends the file from the token's offset and returns the last
+// progress line received
+func importStreamOnce(ctx context.Context, c *client, path string, compressed bool, board, token string) (importProgress, error) {
+	var last importProgress
+	offset, err := decodeImportOffset(token)
+	if err != nil {
+		return last, err
+	}
+	f, err := openImportFile(path, compressed)
+	if err != nil {
+		return last, err
+	}
+	defer f.Close()
+	// Skip by lines: the bytes of the token count lines rewritten with -board
+	r := bufio.NewReader(f)
+	for range offset.Line {
+		if _, err := r.ReadBytes('\n'); err != nil {
+			return last, fmt.Errorf("skip to line %d: %w", offset.Line, err)
+		}
+	}
+
+	// Compress on the fly; the default board is added to lines without one
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		zw, err := zstd.NewWriter(pw)
+		if err == nil {
+			err = copyNDJSON(zw, r, board)
+			if cerr := zw.Close(); err == nil {
+				err = cerr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+
+	query := url.Values{}
+	if token != "" {
+		query.Set("offset", token)
+	}
+	header := http.Header{"Content-Type": {"application/x-ndjson"}, "Content-Encoding": {"zstd"}}
+	resp, err := c.send(ctx, http.MethodPost, "/scores/import", query, pr, header)
+	if err != nil {
+		return last, err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var p importProgress
+		if err := dec.Decode(&p); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return last, fmt.Errorf("read progress: %w", err)
+		}
+		last = p
+		for _, e := range p.Errors {
+			fmt.Fprintf(os.Stderr, "⚠️  line %d: %s: %s\n", e.Line, e.Outcome, e.Reason)
+		}
+		if p.Error != "" {
+			return last, errors.New(p.Error)
+		}
+		if p.Done {
+			return last, nil
+		}
+	}
+}
+
+// copyNDJSON copies NDJSON lines, setting leaderboard_id on lines without one when board is set
+func copyNDJSON(w io.Writer, r io.Reader, board string) error {
+	if board == "" {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var e map[string]json.RawMessage
+			if json.Unmarshal(line, &e) == nil && e["leaderboard_id"] == nil {
+				e["leaderboard_id"], _ = json.Marshal(board)
+				line, _ = json.Marshal(e)
+				line = append(line, '\n')
+			}
+		}
+		// Lines that are not objects are sent as is and reported by the server
+		if _, werr := w.Write(line); werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// runExport streams GET /scores/export to a file or stdout
+func runExport(ctx context.Context, c *client, args []string) error {
+	fs := newFlagSet("export", "[-board ID] [-format csv|json|ndjson] [-zstd] [-out FILE]")
+	board := fs.String("board", "", "leaderboard id (default global)")
+	format := fs.String("format", "csv", "output format: csv, json or ndjson")
+	compress := fs.Bool("zstd", false, "zstd-compress the export (written compressed)")
+	out := fs.String("out", "", "output file (default stdout)")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	query := url.Values{"format": {*format}}
+	if *compress {
+		query.Set("compression", "zstd")
+	}
+	if *board != "" {
+		query.Set("leaderboard_id", *board)
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/scores/export", query, nil)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	defer resp.Body.Close()
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return fmt.Errorf("export interrupted after %d bytes: %w", n, err)
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d bytes to %s\n", n, *out)
+	}
+	return nil
+}
+
+// leaderboard is the LeaderboardResponse of /leaderboards/:id
+type leaderboard struct {
+	LeaderboardID string `json:"leaderboard_id"`
+	SortOrder     string `json:"sort_order"`
+	Secondary     string `json:"secondary_sort_order"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// runBoard shows or updates leaderboard definitions
+func runBoard(ctx context.Context, c *client, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: adminctl [global flags] board get|set ...")
+		return errUsage
+	}
+
+	var lb leaderboard
+	switch args[0] {
+	case "get":
+		fs := newFlagSet("board get", "ID")
+		if err := parseArgs(fs, args[1:], 1); err != nil {
+			return err
+		}
+		if err := c.doJSON(ctx, http.MethodGet, "/leaderboards/"+url.PathEscape(fs.Arg(0)), nil, nil, &lb); err != nil {
+			return fmt.Errorf("get board: %w", err)
+		}
+	case "set":
+		fs := newFlagSet("board set", "-sort-order asc|desc [-secondary-sort-order asc|desc] ID")
+		sortOrder := fs.String("sort-order", "desc", "ranking direction: desc (higher is better) or asc (lower is better)")
+		secondary := fs.String("secondary-sort-order", "desc", "ranking direction of the secondary scores breaking ties")
+		if err := parseArgs(fs, args[1:], 1); err != nil {
+			return err
+		}
+		body := map[string]string{"sort_order": *sortOrder, "secondary_sort_order": *secondary}
+		if err := c.doJSON(ctx, http.MethodPut, "/leaderboards/"+url.PathEscape(fs.Arg(0)), nil, body, &lb); err != nil {
+			return fmt.Errorf("set board: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown board command: %s", args[0])
+	}
+
+	fmt.Printf("Board:      %s\n", lb.LeaderboardID)
+	fmt.Printf("Sort order: %s\n", lb.SortOrder)
+	fmt.Printf("Tiebreaker: %s\n", lb.Secondary)
+	if lb.CreatedAt != "" {
+		fmt.Printf("Created:    %s\n", lb.CreatedAt)
+		fmt.Printf("Updated:    %s\n", lb.UpdatedAt)
+	}
+	return nil
+}
+
+// runStats prints GET /admin/stats as indented JSON
+func runStats(ctx context.Context, c *client, args []string) error {
+	fs := newFlagSet("stats", "")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	var stats json.RawMessage
+	if err := c.doJSON(ctx, http.MethodGet, "/admin/stats", nil, nil, &stats); err != nil {
+		return fmt.Errorf("stats: %w", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(stats)
+}
+
+// replayResponse is the ReplayEventsResponse of POST /admin/events/replay
+type replayResponse struct {
+	FromSeq   int64          `json:"from_seq"`
+	ToSeq     int64          `json:"to_seq"`
+	DryRun    bool           `json:"dry_run"`
+	Events    int            `json:"events"`
+	FirstSeq  int64          `json:"first_seq"`
+	LastSeq   int64          `json:"last_seq"`
+	Ops       map[string]int `json:"ops"`
+	Boards    []string       `json:"boards"`
+	OldestSeq int64          `json:"oldest_seq"`
+	LatestSeq int64          `json:"latest_seq"`
+}
+
+// runEvents runs an outbox events subcommand
+func runEvents(ctx context.Context, c *client, args []string) error {
+	if len(args) == 0 || args[0] != "replay" {
+		fmt.Fprintln(os.Stderr, "usage: adminctl [global flags] events replay ...")
+		return errUsage
+	}
+
+	fs := newFlagSet("events replay", "-from-seq N -to-seq M [-dry-run] [-yes]")
+	from := fs.Int64("from-seq", 0, "first outbox sequence to replay")
+	to := fs.Int64("to-seq", 0, "last outbox sequence to replay")
+	dryRun := fs.Bool("dry-run", false, "report what would be replayed without dispatching it")
+	yes := fs.Bool("yes", false, "skip the interactive confirmation")
+	if err := parseArgs(fs, args[1:], 0); err != nil {
+		return err
+	}
+	if *from < 1 || *to < *from {
+		fs.Usage()
+		return errUsage
+	}
+
+	// Always preview first: the operator sees what a replay would send before it is sent
+	body := map[string]any{"from_seq": *from, "to_seq": *to, "dry_run": true}
+	var preview replayResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/admin/events/replay", nil, body, &preview); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	printReplay(preview)
+	if *dryRun || preview.Events == 0 {
+		return nil
+	}
+	if !*yes {
+		fmt.Printf("Replay %d events to the consumers of %s? [y/N] ", preview.Events, c.prof.RESTURL)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return fmt.Errorf("not confirmed, nothing replayed")
+		}
+	}
+
+	body["dry_run"] = false
+	var res replayResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/admin/events/replay", nil, body, &res); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	fmt.Printf("⏪ Replayed %d events (%d-%d), resynced %s\n", res.Events, res.FirstSeq, res.LastSeq, strings.Join(res.Boards, ", "))
+	return nil
+}
+
+// printReplay prints the events found by a replay dry run
+func printReplay(r replayResponse) {
+	fmt.Printf("Range:   %d-%d (outbox holds %d-%d)\n", r.FromSeq, r.ToSeq, r.OldestSeq, r.LatestSeq)
+	if r.FromSeq < r.OldestSeq {
+		fmt.Printf("⚠️  Events before %d were pruned and cannot be replayed\n", r.OldestSeq)
+	}
+	fmt.Printf("Events:  %d", r.Events)
+	if r.Events > 0 {
+		ops := make([]string, 0, len(r.Ops))
+		for op, n := range r.Ops {
+			ops = append(ops, fmt.Sprintf("%s=%d", op, n))
+		}
+		sort.Strings(ops)
+		fmt.Printf(" (%s)", strings.Join(ops, " "))
+	}
+	fmt.Println()
+	if len(r.Boards) > 0 {
+		fmt.Printf("Boards:  %s\n", strings.Join(r.Boards, ", "))
+	}
+}
+
+// runWatch follows a board's update stream over gRPC
+func runWatch(ctx context.Context, c *client, args []string) error {
+	fs := newFlagSet("watch", "[-board ID] [-limit N]")
+	board := fs.String("board", "", "leaderboard id (default global)")
+	limit := fs.Int("limit", 10, "snapshot size")
+	if err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewLeaderboardServiceClient(conn).StreamLeaderboard(ctx, &pb.SubscribeRequest{
+		InitialLimit:  int32(*limit),
+		LeaderboardId: *board,
+	})
+	if err != nil {
+		return fmt.Errorf("stream leaderboard: %w", err)
+	}
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("receive: %w", err)
+		}
+
+		switch update.Kind {
+		case pb.LeaderboardUpdate_SNAPSHOT:
+			fmt.Printf("=== SNAPSHOT (%d entries) ===\n", len(update.Snapshot))
+			for i, e := range update.Snapshot {
+				fmt.Printf("%d. %s: %d\n", i+1, e.PlayerName, e.Score)
+			}
+		case pb.LeaderboardUpdate_UPSERT:
+			fmt.Printf("%s UPSERT %s %d\n", time.Now().Format(time.TimeOnly), update.Changed.PlayerName, update.Changed.Score)
+		case pb.LeaderboardUpdate_DELETE:
+			fmt.Printf("%s DELETE %s\n", time.Now().Format(time.TimeOnly), update.Changed.PlayerName)
+		case pb.LeaderboardUpdate_TIER_CHANGE:
+			fmt.Printf("%s TIER   %s %q -> %q\n", time.Now().Format(time.TimeOnly),
+				update.Changed.PlayerName, update.PreviousTier, update.Changed.Tier)
+		}
+	}
+}
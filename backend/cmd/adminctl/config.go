@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// profile is the connection and auth settings of one deployment
+type profile struct {
+	RESTURL  string `json:"rest_url"`  // base URL of the REST API
+	GRPCAddr string `json:"grpc_addr"` // host:port of the gRPC API
+	Token    string `json:"token"`     // sent as a bearer token on every call, empty for none
+}
+
+// fileConfig is the adminctl configuration file
+type fileConfig struct {
+	DefaultProfile string             `json:"default_profile"`
+	Profiles       map[string]profile `json:"profiles"`
+}
+
+// localProfile is used when no configuration file exists
+var localProfile = profile{RESTURL: "http://localhost:8080", GRPCAddr: "localhost:50051"}
+
+// configPath returns $ADMINCTL_CONFIG, or adminctl.json in the user config directory
+func configPath() string {
+	if p := os.Getenv("ADMINCTL_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "adminctl.json"
+	}
+	return filepath.Join(dir, "leaderboard", "adminctl.json")
+}
+
+// loadConfig reads the configuration file; a missing file yields a single "local" profile
+func loadConfig(path string) (*fileConfig, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &fileConfig{DefaultProfile: "local", Profiles: map[string]profile{"local": localProfile}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg fileConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(cfg.Profiles) == 0 {
+		return nil, fmt.Errorf("%s defines no profiles", path)
+	}
+	return &cfg, nil
+}
+
+// resolve picks a profile by name (the default profile when empty) and fills
+// unset fields from localProfile
+func (c *fileConfig) resolve(name string) (profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" && len(c.Profiles) == 1 {
+		for n := range c.Profiles {
+			name = n
+		}
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return profile{}, fmt.Errorf("unknown profile %q (have %v)", name, c.names())
+	}
+	if p.RESTURL == "" {
+		p.RESTURL = localProfile.RESTURL
+	}
+	if p.GRPCAddr == "" {
+		p.GRPCAddr = localProfile.GRPCAddr
+	}
+	return p, nil
+}
+
+// names returns the profile names in order
+func (c *fileConfig) names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for n := range c.Profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,115 @@
+// Command adminctl is the operator CLI of the leaderboard: it deletes and imports
+// scores, resets, restores and exports boards, manages leaderboard definitions, replays
+// outbox events and watches update streams through the REST and gRPC APIs.
+// Connection settings and tokens come from named profiles in a JSON configuration
+// file (see README "Admin CLI").
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+// command is one adminctl subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, c *client, args []string) error
+}
+
+var commands = []command{
+	{"delete", "remove a player's score", runDelete},
+	{"reset", "delete every score of a board (admin token required)", runReset},
+	{"restore", "replace a board with an export (file or URL) or a snapshot (admin token required)", runRestore},
+	{"import", "bulk import scores from a CSV, JSON or NDJSON file (.zst allowed)", runImport},
+	{"export", "export a board as CSV, JSON or NDJSON, optionally zstd-compressed", runExport},
+	{"board", "show or update a leaderboard definition (get|set)", runBoard},
+	{"stats", "show leaderboard and maintenance statistics", runStats},
+	{"events", "replay a range of outbox events (replay, admin token required)", runEvents},
+	{"watch", "follow a board's live update stream", runWatch},
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("adminctl", flag.ContinueOnError)
+	profileName := fs.String("profile", os.Getenv("ADMINCTL_PROFILE"), "configuration profile (default: the file's default_profile)")
+	restURL := fs.String("rest", "", "REST base URL, overrides the profile")
+	grpcAddr := fs.String("grpc", "", "gRPC address, overrides the profile")
+	token := fs.String("token", os.Getenv("ADMINCTL_TOKEN"), "admin token, overrides the profile")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	path := configPath()
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	name, args := fs.Arg(0), fs.Args()[1:]
+	if name == "profiles" {
+		for _, n := range cfg.names() {
+			p := cfg.Profiles[n]
+			marker := " "
+			if n == cfg.DefaultProfile {
+				marker = "*"
+			}
+			fmt.Printf("%s %-12s rest=%s grpc=%s token=%t\n", marker, n, p.RESTURL, p.GRPCAddr, p.Token != "")
+		}
+		return nil
+	}
+
+	prof, err := cfg.resolve(*profileName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if *restURL != "" {
+		prof.RESTURL = *restURL
+	}
+	if *grpcAddr != "" {
+		prof.GRPCAddr = *grpcAddr
+	}
+	if *token != "" {
+		prof.Token = *token
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			return cmd.run(ctx, newClient(prof), args)
+		}
+	}
+	fs.Usage()
+	return errUsage
+}
+
+func usage(fs *flag.FlagSet) {
+	var b strings.Builder
+	b.WriteString("usage: adminctl [global flags] COMMAND [flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(&b, "  %-9s %s\n", "profiles", "list configured profiles")
+	b.WriteString("\nGlobal flags:\n")
+	fmt.Fprint(fs.Output(), b.String())
+	fs.PrintDefaults()
+}
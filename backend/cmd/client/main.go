@@ -1,45 +1,79 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 )
 
 func main() {
 	// Command-line flags
 	addr := flag.String("addr", "localhost:50051", "gRPC server address")
-	cmd := flag.String("cmd", "stream", "command to execute: stream, submit, top, rank")
+	cmd := flag.String("cmd", "stream", "command to execute: stream, subscribe, tui, submit, top, rank, info, export")
 	player := flag.String("player", "", "player name (for submit and rank)")
 	score := flag.Int64("score", 0, "score value (for submit)")
 	limit := flag.Int("limit", 10, "limit for top scores or stream")
+	board := flag.String("board", "", "leaderboard id (default global)")
+	format := flag.String("format", "csv", "output format for export: csv or json")
+	out := flag.String("out", "", "output file for export (default stdout)")
+	heartbeatTimeout := flag.Duration("heartbeat-timeout", time.Minute, "give up on a stream silent for this long, heartbeats included (0 = wait forever)")
+	reconnect := flag.Bool("reconnect", true, "reopen the stream with exponential backoff after a transient error (for stream; tui always does)")
 	flag.Parse()
 
-	if err := run(*addr, *cmd, *player, *score, int32(*limit)); err != nil {
+	if *cmd == "export" {
+		if err := runExport(*addr, *board, *format, *out); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := run(*addr, *cmd, *board, *player, *score, int32(*limit), *heartbeatTimeout, *reconnect); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run(addr, cmd, player string, score int64, limit int32) error {
-	// Create gRPC connection
-	ctx := context.Background()
+// dial connects to the gRPC server
+func dial(ctx context.Context, addr string) (*grpc.ClientConn, error) {
 	conn, err := grpc.DialContext(
 		ctx,
 		addr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		// Ping while streams are open; must not be more frequent than GRPC_KEEPALIVE_MIN_TIME
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    30 * time.Second,
+			Timeout: 10 * time.Second,
+		}),
 		grpc.WithBlock(),
 		grpc.WithTimeout(5*time.Second),
 	)
 	if err != nil {
-		return fmt.Errorf("dial: %w", err)
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	return conn, nil
+}
+
+func run(addr, cmd, board, player string, score int64, limit int32, heartbeatTimeout time.Duration, reconnect bool) error {
+	// Create gRPC connection
+	ctx := context.Background()
+	conn, err := dial(ctx, addr)
+	if err != nil {
+		return err
 	}
 	defer conn.Close()
 
@@ -47,29 +81,133 @@ func run(addr, cmd, player string, score int64, limit int32) error {
 
 	switch cmd {
 	case "stream":
-		return streamLeaderboard(ctx, client, limit)
+		return streamLeaderboard(ctx, client, board, limit, heartbeatTimeout, reconnect)
+	case "subscribe":
+		return subscribeLeaderboard(ctx, client, board, limit, heartbeatTimeout)
+	case "tui":
+		return runTUI(ctx, client, board, limit, heartbeatTimeout)
 	case "submit":
-		return submitScore(ctx, client, player, score)
+		return submitScore(ctx, client, board, player, score)
 	case "top":
-		return getTopScores(ctx, client, limit)
+		return getTopScores(ctx, client, board, limit)
 	case "rank":
-		return getPlayerRank(ctx, client, player)
+		return getPlayerRank(ctx, client, board, player)
+	case "info":
+		return getServerInfo(ctx, client)
 	default:
 		return fmt.Errorf("unknown command: %s", cmd)
 	}
 }
 
-// streamLeaderboard demonstrates the server-streaming RPC
-func streamLeaderboard(ctx context.Context, client pb.LeaderboardServiceClient, limit int32) error {
+// errStreamSilent cancels a stream on which nothing, not even a heartbeat, arrived in time
+var errStreamSilent = errors.New("no update or heartbeat received, connection presumed dead")
+
+// watchStream returns a context canceled with errStreamSilent once timeout elapses
+// without a call to alive. Call alive after every received message.
+func watchStream(ctx context.Context, timeout time.Duration) (watched context.Context, alive func(), stop func()) {
+	if timeout <= 0 {
+		return ctx, func() {}, func() {}
+	}
+	watched, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(timeout, func() { cancel(errStreamSilent) })
+	return watched, func() { timer.Reset(timeout) }, func() {
+		timer.Stop()
+		cancel(nil)
+	}
+}
+
+// recvError explains why receiving from a watched stream failed
+func recvError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); errors.Is(cause, errStreamSilent) {
+		return cause
+	}
+	return fmt.Errorf("receive: %w", err)
+}
+
+// streamLeaderboard demonstrates the server-streaming RPC. With reconnect, a
+// stream that fails with a transient error is reopened (see follow), and the
+// SNAPSHOT of the new stream only prints what changed while disconnected.
+func streamLeaderboard(ctx context.Context, client pb.LeaderboardServiceClient, board string, limit int32, heartbeatTimeout time.Duration, reconnect bool) error {
 	fmt.Printf("Subscribing to leaderboard stream (limit=%d)...\n", limit)
 
-	stream, err := client.StreamLeaderboard(ctx, &pb.SubscribeRequest{
-		InitialLimit: limit,
+	v := newView()
+	err := follow(ctx, client, board, limit, heartbeatTimeout, reconnect, func(update *pb.LeaderboardUpdate) {
+		if update.Kind != pb.LeaderboardUpdate_SNAPSHOT {
+			v.apply(update)
+			printUpdate(update)
+			return
+		}
+		if !v.synced {
+			v.resync(update.Snapshot)
+			printUpdate(update)
+			fmt.Println("Waiting for updates... (Press Ctrl+C to stop)")
+			return
+		}
+		printResync(v.resync(update.Snapshot))
+	}, func(err error, delay time.Duration, attempt int) {
+		fmt.Fprintf(os.Stderr, "⚠️  %v, reconnecting in %s (attempt %d)\n", err, delay.Round(time.Millisecond), attempt)
 	})
+	if errors.Is(err, io.EOF) {
+		fmt.Println("Stream closed by server")
+		return nil
+	}
+	return err
+}
+
+// printResync prints what a new snapshot changed in the view
+func printResync(changed []*pb.ScoreEntry, gone []string) {
+	if len(changed) == 0 && len(gone) == 0 {
+		fmt.Println("🔄 Resynced, nothing changed")
+		return
+	}
+	fmt.Printf("🔄 Resynced, %d changed and %d left the view:\n", len(changed), len(gone))
+	for _, e := range changed {
+		printUpdate(&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_UPSERT, Changed: e})
+	}
+	for _, player := range gone {
+		fmt.Printf("↘️  %s left the view\n", player)
+	}
+}
+
+// subscribeLeaderboard demonstrates the bidirectional streaming RPC.
+// Control commands are read from stdin: "limit N", "pause", "resume", "snapshot".
+func subscribeLeaderboard(ctx context.Context, client pb.LeaderboardServiceClient, board string, limit int32, heartbeatTimeout time.Duration) error {
+	fmt.Printf("Opening leaderboard subscription (limit=%d)...\n", limit)
+
+	ctx, alive, stop := watchStream(ctx, heartbeatTimeout)
+	defer stop()
+
+	stream, err := client.SubscribeLeaderboard(ctx)
 	if err != nil {
-		return fmt.Errorf("stream leaderboard: %w", err)
+		return fmt.Errorf("subscribe leaderboard: %w", err)
 	}
 
+	if err := stream.Send(&pb.SubscribeControl{
+		Action:        pb.SubscribeControl_SET_LIMIT,
+		Limit:         limit,
+		LeaderboardId: board,
+	}); err != nil {
+		return fmt.Errorf("send initial limit: %w", err)
+	}
+
+	// Forward stdin commands as control messages
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			control, err := parseControl(scanner.Text())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+				continue
+			}
+			if err := stream.Send(control); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  send control: %v\n", err)
+				return
+			}
+		}
+		stream.CloseSend()
+	}()
+
+	fmt.Println("Commands: limit N | pause | resume | snapshot (Ctrl+C to stop)")
 	for {
 		update, err := stream.Recv()
 		if err == io.EOF {
@@ -77,35 +215,74 @@ func streamLeaderboard(ctx context.Context, client pb.LeaderboardServiceClient,
 			return nil
 		}
 		if err != nil {
-			return fmt.Errorf("receive: %w", err)
+			return recvError(ctx, err)
 		}
+		alive()
+		printUpdate(update)
+	}
+}
 
-		switch update.Kind {
-		case pb.LeaderboardUpdate_SNAPSHOT:
-			fmt.Println("\n=== SNAPSHOT ===")
-			for i, entry := range update.Snapshot {
-				fmt.Printf("%d. %s: %d (updated: %s)\n",
-					i+1, entry.PlayerName, entry.Score, entry.UpdatedAt)
-			}
-			fmt.Println("================\n")
-			fmt.Println("Waiting for updates... (Press Ctrl+C to stop)")
-
-		case pb.LeaderboardUpdate_UPSERT:
-			fmt.Printf("🔔 UPDATE: %s scored %d (updated: %s)\n",
-				update.Changed.PlayerName, update.Changed.Score, update.Changed.UpdatedAt)
+// parseControl converts a stdin command into a control message
+func parseControl(line string) (*pb.SubscribeControl, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
 
-		case pb.LeaderboardUpdate_DELETE:
-			fmt.Printf("🗑️  DELETE: %s removed from leaderboard\n",
-				update.Changed.PlayerName)
+	switch fields[0] {
+	case "limit":
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("usage: limit N")
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %s", fields[1])
+		}
+		return &pb.SubscribeControl{Action: pb.SubscribeControl_SET_LIMIT, Limit: int32(n)}, nil
+	case "pause":
+		return &pb.SubscribeControl{Action: pb.SubscribeControl_PAUSE}, nil
+	case "resume":
+		return &pb.SubscribeControl{Action: pb.SubscribeControl_RESUME}, nil
+	case "snapshot":
+		return &pb.SubscribeControl{Action: pb.SubscribeControl_SNAPSHOT}, nil
+	default:
+		return nil, fmt.Errorf("unknown command: %s", fields[0])
+	}
+}
 
-		default:
-			fmt.Printf("Unknown update kind: %v\n", update.Kind)
+// printUpdate prints a single leaderboard update
+func printUpdate(update *pb.LeaderboardUpdate) {
+	switch update.Kind {
+	case pb.LeaderboardUpdate_SNAPSHOT:
+		fmt.Println("\n=== SNAPSHOT ===")
+		for i, entry := range update.Snapshot {
+			fmt.Printf("%d. %s: %d (updated: %s)\n",
+				i+1, entry.PlayerName, entry.Score, entry.UpdatedAt)
 		}
+		fmt.Print("================\n\n")
+
+	case pb.LeaderboardUpdate_UPSERT:
+		fmt.Printf("🔔 UPDATE: %s scored %d (updated: %s)\n",
+			update.Changed.PlayerName, update.Changed.Score, update.Changed.UpdatedAt)
+
+	case pb.LeaderboardUpdate_DELETE:
+		fmt.Printf("🗑️  DELETE: %s removed from leaderboard\n",
+			update.Changed.PlayerName)
+
+	case pb.LeaderboardUpdate_TIER_CHANGE:
+		fmt.Printf("🏅 TIER: %s moved from %q to %q\n",
+			update.Changed.PlayerName, update.PreviousTier, update.Changed.Tier)
+
+	case pb.LeaderboardUpdate_HEARTBEAT:
+		fmt.Printf("💓 heartbeat (server time: %s)\n", update.ServerTime)
+
+	default:
+		fmt.Printf("Unknown update kind: %v\n", update.Kind)
 	}
 }
 
 // submitScore demonstrates the unary RPC for submitting scores
-func submitScore(ctx context.Context, client pb.LeaderboardServiceClient, player string, score int64) error {
+func submitScore(ctx context.Context, client pb.LeaderboardServiceClient, board, player string, score int64) error {
 	if player == "" {
 		return fmt.Errorf("player name is required")
 	}
@@ -113,8 +290,9 @@ func submitScore(ctx context.Context, client pb.LeaderboardServiceClient, player
 	fmt.Printf("Submitting score: %s = %d\n", player, score)
 
 	resp, err := client.SubmitScore(ctx, &pb.SubmitScoreRequest{
-		PlayerName: player,
-		Score:      score,
+		PlayerName:    player,
+		Score:         score,
+		LeaderboardId: board,
 	})
 	if err != nil {
 		return fmt.Errorf("submit score: %w", err)
@@ -127,17 +305,21 @@ func submitScore(ctx context.Context, client pb.LeaderboardServiceClient, player
 		fmt.Printf("ℹ️  Score not applied. Current best: %d (updated: %s)\n",
 			resp.Entry.Score, resp.Entry.UpdatedAt)
 	}
+	if resp.Rank > 0 {
+		fmt.Printf("🏆 Rank #%d (%+d)\n", resp.Rank, resp.RankDelta)
+	}
 
 	return nil
 }
 
 // getTopScores demonstrates retrieving top scores
-func getTopScores(ctx context.Context, client pb.LeaderboardServiceClient, limit int32) error {
+func getTopScores(ctx context.Context, client pb.LeaderboardServiceClient, board string, limit int32) error {
 	fmt.Printf("Getting top %d scores...\n", limit)
 
 	resp, err := client.GetTopScores(ctx, &pb.GetTopScoresRequest{
-		Limit:  limit,
-		Offset: 0,
+		Limit:         limit,
+		Offset:        0,
+		LeaderboardId: board,
 	})
 	if err != nil {
 		return fmt.Errorf("get top scores: %w", err)
@@ -148,13 +330,13 @@ func getTopScores(ctx context.Context, client pb.LeaderboardServiceClient, limit
 		fmt.Printf("%d. %s: %d (updated: %s)\n",
 			i+1, entry.PlayerName, entry.Score, entry.UpdatedAt)
 	}
-	fmt.Println("==================\n")
+	fmt.Print("==================\n\n")
 
 	return nil
 }
 
 // getPlayerRank demonstrates getting a player's rank
-func getPlayerRank(ctx context.Context, client pb.LeaderboardServiceClient, player string) error {
+func getPlayerRank(ctx context.Context, client pb.LeaderboardServiceClient, board, player string) error {
 	if player == "" {
 		return fmt.Errorf("player name is required")
 	}
@@ -162,7 +344,8 @@ func getPlayerRank(ctx context.Context, client pb.LeaderboardServiceClient, play
 	fmt.Printf("Getting rank for: %s\n", player)
 
 	resp, err := client.GetPlayerRank(ctx, &pb.GetPlayerRankRequest{
-		PlayerName: player,
+		PlayerName:    player,
+		LeaderboardId: board,
 	})
 	if err != nil {
 		return fmt.Errorf("get player rank: %w", err)
@@ -179,3 +362,129 @@ func getPlayerRank(ctx context.Context, client pb.LeaderboardServiceClient, play
 
 	return nil
 }
+
+// getServerInfo demonstrates the startup handshake
+func getServerInfo(ctx context.Context, client pb.LeaderboardServiceClient) error {
+	resp, err := client.GetServerInfo(ctx, &pb.GetServerInfoRequest{})
+	if err != nil {
+		return fmt.Errorf("get server info: %w", err)
+	}
+
+	fmt.Printf("🖥️  Server %s (API %s), status %s\n", resp.Version, resp.ApiVersion, resp.Status)
+	fmt.Printf("   Capabilities: %s\n", strings.Join(resp.Capabilities, ", "))
+	fmt.Printf("   Page size: %d (max %d), player names: %d-%d characters\n",
+		resp.Limits.DefaultPageSize, resp.Limits.MaxPageSize, resp.Limits.MinPlayerNameLength, resp.Limits.MaxPlayerNameLength)
+	if resp.Limits.PlayerNamePattern != "" {
+		fmt.Printf("   Player names must match: %s\n", resp.Limits.PlayerNamePattern)
+	}
+	if resp.Limits.MaxScore > 0 {
+		fmt.Printf("   Max score: %d\n", resp.Limits.MaxScore)
+	}
+	fmt.Printf("   Signatures: %s, offline sync: %t, receipts: %t, tiers: %t\n",
+		resp.Features.SubmitSignatures, resp.Features.OfflineSync, resp.Features.Receipts, resp.Features.Tiers)
+	for _, b := range resp.Boards {
+		daily := ""
+		if b.Daily {
+			daily = " (daily)"
+		}
+		fmt.Printf("   📋 %s%s, %s\n", b.LeaderboardId, daily, b.SortOrder)
+	}
+	return nil
+}
+
+// exportPageSize is the GetTopScores page size of exports (the server's default MAX_LIMIT;
+// smaller limits are clamped and still paginate correctly)
+const exportPageSize = 100
+
+// exportEntry is one row of an export, with the fields of GET /scores/export
+type exportEntry struct {
+	Rank          int64  `json:"rank"`
+	LeaderboardID string `json:"leaderboard_id"`
+	PlayerName    string `json:"player_name"`
+	Score         int64  `json:"score"`
+	AchievedAt    string `json:"achieved_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// runExport writes a whole board in rank order, walking GetTopScores page tokens.
+// Progress goes to stderr so the export can be piped from stdout.
+func runExport(addr, board, format, out string) error {
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("format must be csv or json")
+	}
+
+	ctx := context.Background()
+	conn, err := dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := pb.NewLeaderboardServiceClient(conn)
+
+	w := io.Writer(os.Stdout)
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	buf := bufio.NewWriter(w)
+
+	// Rows are written as pages arrive: CSV with a header line, or a JSON array
+	csvw := csv.NewWriter(buf)
+	enc := json.NewEncoder(buf)
+	if format == "csv" {
+		csvw.Write([]string{"rank", "leaderboard_id", "player_name", "score", "achieved_at", "updated_at"})
+	} else {
+		buf.WriteString("[")
+	}
+
+	var rank int64
+	token := ""
+	for {
+		resp, err := client.GetTopScores(ctx, &pb.GetTopScoresRequest{
+			Limit:         exportPageSize,
+			PageToken:     token,
+			LeaderboardId: board,
+		})
+		if err != nil {
+			return fmt.Errorf("get top scores after %d entries: %w", rank, err)
+		}
+		for _, e := range resp.Entries {
+			rank++
+			row := exportEntry{rank, e.LeaderboardId, e.PlayerName, e.Score, e.AchievedAt, e.UpdatedAt}
+			if format == "csv" {
+				csvw.Write([]string{strconv.FormatInt(row.Rank, 10), row.LeaderboardID, row.PlayerName,
+					strconv.FormatInt(row.Score, 10), row.AchievedAt, row.UpdatedAt})
+				continue
+			}
+			if rank > 1 {
+				buf.WriteString(",")
+			}
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		token = resp.NextPageToken
+		if token == "" || len(resp.Entries) == 0 {
+			break
+		}
+	}
+
+	if format == "csv" {
+		csvw.Flush()
+		if err := csvw.Error(); err != nil {
+			return err
+		}
+	} else {
+		buf.WriteString("]\n")
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d entries\n", rank)
+	return nil
+}
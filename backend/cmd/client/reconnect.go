@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Reconnect delays of streams
+const (
+	reconnectInitialDelay = 500 * time.Millisecond
+	reconnectMaxDelay     = 30 * time.Second
+)
+
+// follow streams a board into handle until the stream fails for good. With
+// reconnect, a stream that fails with a transient error is reopened after an
+// exponential backoff (or the delay the server asked for), reported to retrying
+// first; the backoff resets once a new stream delivered its first update. A
+// reopened stream resumes from the highest seq received, so the server replays
+// the missed changes instead of sending a snapshot when it still has them.
+func follow(ctx context.Context, client pb.LeaderboardServiceClient, board string, limit int32, heartbeatTimeout time.Duration, reconnect bool,
+	handle func(*pb.LeaderboardUpdate), retrying func(err error, delay time.Duration, attempt int)) error {
+	retry := backoff{initial: reconnectInitialDelay, max: reconnectMaxDelay}
+	var seq int64
+	for {
+		delivered := false
+		err := streamOnce(ctx, client, board, limit, seq, heartbeatTimeout, func(update *pb.LeaderboardUpdate) {
+			if !delivered {
+				delivered = true
+				retry.reset()
+			}
+			seq = max(seq, update.Seq)
+			handle(update)
+		})
+		if !reconnect || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		delay := max(retry.next(), retryDelay(err))
+		retrying(err, delay, retry.attempt)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// streamOnce opens a stream, resuming from seq unless it is 0, and passes its
+// updates to handle until it fails. It returns io.EOF when the server closed the stream.
+func streamOnce(ctx context.Context, client pb.LeaderboardServiceClient, board string, limit int32, seq int64, heartbeatTimeout time.Duration, handle func(*pb.LeaderboardUpdate)) error {
+	ctx, alive, stop := watchStream(ctx, heartbeatTimeout)
+	defer stop()
+
+	stream, err := client.StreamLeaderboard(ctx, &pb.SubscribeRequest{
+		InitialLimit:  limit,
+		LeaderboardId: board,
+		ResumeFromSeq: seq,
+	})
+	if err != nil {
+		return fmt.Errorf("stream leaderboard: %w", err)
+	}
+
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			return err
+		}
+		if err != nil {
+			return recvError(ctx, err)
+		}
+		alive()
+		handle(update)
+	}
+}
+
+// backoff computes exponentially growing reconnect delays, with jitter so that
+// clients dropped together by a server restart do not all come back at once
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+	attempt int
+}
+
+// next returns the delay before the next attempt: between half and all of
+// initial*2^attempt, capped at max
+func (b *backoff) next() time.Duration {
+	d := b.initial << min(b.attempt, 16)
+	if d <= 0 || d > b.max {
+		d = b.max
+	}
+	b.attempt++
+	return d/2 + rand.N(d/2+1)
+}
+
+// reset starts over from the initial delay, once a connection proved healthy
+func (b *backoff) reset() {
+	b.attempt = 0
+}
+
+// retryable reports whether a stream that failed with err is worth reopening:
+// network failures, server restarts and overload are transient, while invalid
+// requests or missing permissions fail the same way on every attempt
+func retryable(err error) bool {
+	if errors.Is(err, errStreamSilent) || errors.Is(err, io.EOF) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns the delay the server asked for in a RetryInfo detail, or 0
+func retryDelay(err error) time.Duration {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			return info.RetryDelay.AsDuration()
+		}
+	}
+	return 0
+}
+
+// sleep waits for d, returning early with the context's error if it is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// view is the part of the leaderboard a stream showed so far. A SNAPSHOT sent
+// after a reconnect or a resync is compared to it, so only what changed while
+// the client was not listening is printed again.
+type view struct {
+	scores map[string]int64
+	synced bool // a snapshot was received
+}
+
+func newView() *view {
+	return &view{scores: make(map[string]int64)}
+}
+
+// apply tracks an incremental update
+func (v *view) apply(update *pb.LeaderboardUpdate) {
+	switch update.Kind {
+	case pb.LeaderboardUpdate_UPSERT:
+		v.scores[update.Changed.PlayerName] = update.Changed.Score
+	case pb.LeaderboardUpdate_DELETE:
+		delete(v.scores, update.Changed.PlayerName)
+	}
+}
+
+// resync replaces the view with a snapshot. It returns the entries that are new
+// or changed, in snapshot order, and the players no longer in view, sorted.
+func (v *view) resync(snapshot []*pb.ScoreEntry) (changed []*pb.ScoreEntry, gone []string) {
+	scores := make(map[string]int64, len(snapshot))
+	for _, e := range snapshot {
+		scores[e.PlayerName] = e.Score
+		if prev, ok := v.scores[e.PlayerName]; !ok || prev != e.Score {
+			changed = append(changed, e)
+		}
+	}
+	for player := range v.scores {
+		if _, ok := scores[player]; !ok {
+			gone = append(gone, player)
+		}
+	}
+	slices.Sort(gone)
+	v.scores = scores
+	v.synced = true
+	return changed, gone
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestBackoff(t *testing.T) {
+	b := backoff{initial: 100 * time.Millisecond, max: time.Second}
+	for i, ceiling := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		ceiling *= time.Millisecond
+		if d := b.next(); d < ceiling/2 || d > ceiling {
+			t.Errorf("attempt %d: delay %s, want within [%s, %s]", i, d, ceiling/2, ceiling)
+		}
+	}
+	b.reset()
+	if d := b.next(); d > 100*time.Millisecond {
+		t.Errorf("delay after reset = %s, want at most the initial delay", d)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{status.Error(codes.Unavailable, "connection refused"), true},
+		{fmt.Errorf("receive: %w", status.Error(codes.Internal, "stream reset")), true},
+		{status.Error(codes.ResourceExhausted, "overloaded"), true},
+		{errStreamSilent, true},
+		{io.EOF, true},
+		{status.Error(codes.InvalidArgument, "bad limit"), false},
+		{status.Error(codes.PermissionDenied, "invalid API key"), false},
+		{status.Error(codes.Canceled, "canceled"), false},
+	} {
+		if got := retryable(tc.err); got != tc.want {
+			t.Errorf("retryable(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	st, err := status.New(codes.ResourceExhausted, "overloaded").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(2 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := retryDelay(st.Err()); got != 2*time.Second {
+		t.Errorf("retryDelay = %s, want 2s", got)
+	}
+	if got := retryDelay(status.Error(codes.Unavailable, "down")); got != 0 {
+		t.Errorf("retryDelay without RetryInfo = %s, want 0", got)
+	}
+}
+
+func TestViewResync(t *testing.T) {
+	entry := func(player string, score int64) *pb.ScoreEntry {
+		return &pb.ScoreEntry{PlayerName: player, Score: score}
+	}
+	v := newView()
+	if changed, _ := v.resync([]*pb.ScoreEntry{entry("Alice", 100), entry("Bob", 80), entry("Carol", 60)}); len(changed) != 3 {
+		t.Fatalf("first snapshot: %d changed, want all 3", len(changed))
+	}
+	v.apply(&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_UPSERT, Changed: entry("Bob", 90)})
+	v.apply(&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_DELETE, Changed: entry("Carol", 0)})
+
+	// While disconnected: Alice improved, Dave entered, Bob left
+	changed, gone := v.resync([]*pb.ScoreEntry{entry("Alice", 150), entry("Dave", 70)})
+	var names []string
+	for _, e := range changed {
+		names = append(names, e.PlayerName)
+	}
+	if !slices.Equal(names, []string{"Alice", "Dave"}) || !slices.Equal(gone, []string{"Bob"}) {
+		t.Errorf("resync: changed %v, gone %v; want [Alice Dave], [Bob]", names, gone)
+	}
+
+	if changed, gone := v.resync([]*pb.ScoreEntry{entry("Alice", 150), entry("Dave", 70)}); len(changed)+len(gone) != 0 {
+		t.Errorf("identical snapshot: changed %v, gone %v; want nothing", changed, gone)
+	}
+}
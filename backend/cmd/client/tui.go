@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"google.golang.org/grpc/status"
+)
+
+// runTUI renders the live leaderboard as a table fed by the stream (reconnecting
+// like -cmd stream), with a form to submit scores, until Ctrl+C
+func runTUI(ctx context.Context, client pb.LeaderboardServiceClient, board string, limit int32, heartbeatTimeout time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	m := newTUIModel(ctx, client, board, limit)
+	// The stream does not tell how the board ranks; boards without a definition rank descending
+	if resp, err := client.GetLeaderboard(ctx, &pb.GetLeaderboardRequest{LeaderboardId: board}); err == nil {
+		m.asc = resp.Leaderboard.GetSortOrder() == pb.SortOrder_SORT_ORDER_ASC
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx))
+	go func() {
+		err := follow(ctx, client, board, limit, heartbeatTimeout, true, func(update *pb.LeaderboardUpdate) {
+			p.Send(updateMsg{update})
+		}, func(err error, delay time.Duration, attempt int) {
+			p.Send(retryMsg{err, delay, attempt})
+		})
+		p.Send(streamEndMsg{err})
+	}()
+
+	_, err := p.Run()
+	if errors.Is(err, tea.ErrProgramKilled) && ctx.Err() != nil {
+		return nil // stopped by the caller
+	}
+	return err
+}
+
+// Messages of the TUI besides key presses
+type (
+	updateMsg struct{ update *pb.LeaderboardUpdate }
+	retryMsg  struct {
+		err     error
+		delay   time.Duration
+		attempt int
+	}
+	streamEndMsg struct{ err error }
+	submittedMsg struct {
+		player string
+		score  int64
+		resp   *pb.SubmitScoreResponse
+		err    error
+	}
+)
+
+// Fields of the submission form
+const (
+	fieldPlayer = iota
+	fieldScore
+	fieldCount
+)
+
+var (
+	titleStyle   = lipgloss.NewStyle().Bold(true)
+	headerStyle  = lipgloss.NewStyle().Bold(true).Underline(true)
+	changedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)
+	focusStyle   = lipgloss.NewStyle().Reverse(true)
+	mutedStyle   = lipgloss.NewStyle().Faint(true)
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+// tuiModel is the state of the TUI: the board as last streamed and the form
+type tuiModel struct {
+	ctx    context.Context
+	client pb.LeaderboardServiceClient
+	board  string
+	limit  int32
+	asc    bool // lower scores rank first
+
+	entries []*pb.ScoreEntry // in rank order, at most limit
+	changed string           // player of the last update, highlighted
+	status  string           // connection state
+	result  string           // outcome of the last submission
+
+	fields [fieldCount]string
+	focus  int
+}
+
+func newTUIModel(ctx context.Context, client pb.LeaderboardServiceClient, board string, limit int32) *tuiModel {
+	return &tuiModel{ctx: ctx, client: client, board: board, limit: limit, status: "connecting..."}
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m, m.key(msg)
+	case updateMsg:
+		m.apply(msg.update)
+	case retryMsg:
+		m.status = fmt.Sprintf("reconnecting in %s (attempt %d): %s", msg.delay.Round(time.Millisecond), msg.attempt, errorMessage(msg.err))
+	case streamEndMsg:
+		m.status = "stream closed"
+		if msg.err != nil && !errors.Is(msg.err, io.EOF) && !errors.Is(msg.err, context.Canceled) {
+			m.status = "stream failed: " + errorMessage(msg.err)
+		}
+	case submittedMsg:
+		m.result = submissionResult(msg)
+	}
+	return m, nil
+}
+
+// key edits the form or submits it
+func (m *tuiModel) key(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return tea.Quit
+	case tea.KeyTab, tea.KeyDown:
+		m.focus = (m.focus + 1) % fieldCount
+	case tea.KeyShiftTab, tea.KeyUp:
+		m.focus = (m.focus + fieldCount - 1) % fieldCount
+	case tea.KeyEsc:
+		m.fields = [fieldCount]string{}
+		m.focus = fieldPlayer
+	case tea.KeyBackspace:
+		f := m.fields[m.focus]
+		_, size := utf8.DecodeLastRuneInString(f)
+		m.fields[m.focus] = f[:len(f)-size]
+	case tea.KeySpace:
+		if m.focus == fieldPlayer {
+			m.fields[m.focus] += " "
+		}
+	case tea.KeyRunes:
+		for _, r := range msg.Runes {
+			if m.focus == fieldScore && (r < '0' || r > '9') {
+				continue // scores are non-negative integers
+			}
+			m.fields[m.focus] += string(r)
+		}
+	case tea.KeyEnter:
+		return m.submit()
+	}
+	return nil
+}
+
+// submit sends the form's score, or explains why it cannot
+func (m *tuiModel) submit() tea.Cmd {
+	player := strings.TrimSpace(m.fields[fieldPlayer])
+	score, err := strconv.ParseInt(m.fields[fieldScore], 10, 64)
+	switch {
+	case player == "":
+		m.result = errorStyle.Render("player name is required")
+		m.focus = fieldPlayer
+		return nil
+	case err != nil:
+		m.result = errorStyle.Render("score must be a non-negative integer")
+		m.focus = fieldScore
+		return nil
+	}
+
+	m.result = fmt.Sprintf("submitting %s = %d...", player, score)
+	m.fields[fieldScore] = "" // keep the player for the next run
+	m.focus = fieldScore
+	ctx, client, board := m.ctx, m.client, m.board
+	return func() tea.Msg {
+		resp, err := client.SubmitScore(ctx, &pb.SubmitScoreRequest{PlayerName: player, Score: score, LeaderboardId: board})
+		return submittedMsg{player, score, resp, err}
+	}
+}
+
+// submissionResult describes the outcome of a submission
+func submissionResult(msg submittedMsg) string {
+	if msg.err != nil {
+		return errorStyle.Render(fmt.Sprintf("❌ %s = %d: %s", msg.player, msg.score, errorMessage(msg.err)))
+	}
+	out := fmt.Sprintf("ℹ️  %s = %d not applied, best is %d", msg.player, msg.score, msg.resp.Entry.GetScore())
+	if msg.resp.Applied {
+		out = fmt.Sprintf("✅ %s = %d applied", msg.player, msg.score)
+	}
+	if msg.resp.Rank > 0 {
+		out += fmt.Sprintf(", rank #%d (%+d)", msg.resp.Rank, msg.resp.RankDelta)
+	}
+	return out
+}
+
+// apply updates the table with a streamed update
+func (m *tuiModel) apply(update *pb.LeaderboardUpdate) {
+	switch update.Kind {
+	case pb.LeaderboardUpdate_SNAPSHOT:
+		m.entries = slices.Clone(update.Snapshot)
+		m.changed = ""
+		m.status = "live"
+		return
+	case pb.LeaderboardUpdate_HEARTBEAT:
+		m.status = "live, last heartbeat " + time.Now().Format(time.TimeOnly)
+		return
+	case pb.LeaderboardUpdate_DELETE:
+		m.entries = slices.DeleteFunc(m.entries, func(e *pb.ScoreEntry) bool { return e.PlayerName == update.Changed.PlayerName })
+		m.changed = ""
+		return
+	case pb.LeaderboardUpdate_UPSERT, pb.LeaderboardUpdate_TIER_CHANGE:
+	default:
+		return
+	}
+
+	m.changed = update.Changed.PlayerName
+	m.entries = slices.DeleteFunc(m.entries, func(e *pb.ScoreEntry) bool { return e.PlayerName == update.Changed.PlayerName })
+	m.entries = append(m.entries, update.Changed)
+	slices.SortStableFunc(m.entries, m.compare)
+	if m.limit > 0 && len(m.entries) > int(m.limit) {
+		m.entries = m.entries[:m.limit] // pushed out of the view
+	}
+}
+
+// compare orders entries by rank: by score in the board's order, then earlier
+// achievement first, like the server
+func (m *tuiModel) compare(a, b *pb.ScoreEntry) int {
+	if a.Score != b.Score {
+		if (a.Score > b.Score) != m.asc {
+			return -1
+		}
+		return 1
+	}
+	at, _ := time.Parse(time.RFC3339Nano, a.AchievedAt)
+	bt, _ := time.Parse(time.RFC3339Nano, b.AchievedAt)
+	return at.Compare(bt)
+}
+
+func (m *tuiModel) View() string {
+	var b strings.Builder
+	board := m.board
+	if board == "" {
+		board = "default board"
+	}
+	fmt.Fprintf(&b, "%s  %s\n\n", titleStyle.Render(fmt.Sprintf("🏆 %s, top %d", board, m.limit)), mutedStyle.Render(m.status))
+
+	b.WriteString(headerStyle.Render(fmt.Sprintf("%4s  %-20s %12s  %-10s", "#", "Player", "Score", "Tier")) + "\n")
+	if len(m.entries) == 0 {
+		b.WriteString(mutedStyle.Render("  no scores yet") + "\n")
+	}
+	for i, e := range m.entries {
+		row := fmt.Sprintf("%4d  %-20s %12d  %-10s", i+1, e.PlayerName, e.Score, e.Tier)
+		if e.PlayerName == m.changed {
+			row = changedStyle.Render(row + " ◀")
+		}
+		b.WriteString(row + "\n")
+	}
+
+	b.WriteString("\nSubmit  ")
+	for i, label := range [fieldCount]string{"player", "score"} {
+		value := m.fields[i] + " "
+		if i == m.focus {
+			value = focusStyle.Render(value)
+		}
+		fmt.Fprintf(&b, "%s: [%s]  ", label, value)
+	}
+	b.WriteString("\n")
+	if m.result != "" {
+		b.WriteString(m.result + "\n")
+	}
+	b.WriteString(mutedStyle.Render("\nenter submit · tab next field · esc clear · ctrl+c quit") + "\n")
+	return b.String()
+}
+
+// errorMessage returns the message of a gRPC status error, or the error text
+func errorMessage(err error) string {
+	if st, ok := status.FromError(err); ok {
+		return st.Message()
+	}
+	return err.Error()
+}
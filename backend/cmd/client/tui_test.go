@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+)
+
+func names(entries []*pb.ScoreEntry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.PlayerName)
+	}
+	return out
+}
+
+func TestTUIApply(t *testing.T) {
+	upsert := func(player string, score int64, achievedAt string) *pb.LeaderboardUpdate {
+		return &pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_UPSERT,
+			Changed: &pb.ScoreEntry{PlayerName: player, Score: score, AchievedAt: achievedAt}}
+	}
+	m := newTUIModel(context.Background(), nil, "", 3)
+	m.Update(updateMsg{&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_SNAPSHOT, Snapshot: []*pb.ScoreEntry{
+		{PlayerName: "Alice", Score: 300, AchievedAt: "2025-01-15T10:00:00Z"},
+		{PlayerName: "Bob", Score: 200, AchievedAt: "2025-01-15T10:00:00Z"},
+		{PlayerName: "Carol", Score: 100, AchievedAt: "2025-01-15T10:00:00Z"},
+	}}})
+	if m.status != "live" {
+		t.Errorf("status after snapshot = %q, want live", m.status)
+	}
+
+	// Dave ties Bob later, so ranks below him, and pushes Carol out of the top 3
+	m.Update(updateMsg{upsert("Dave", 200, "2025-01-15T11:00:00Z")})
+	if got := names(m.entries); !slices.Equal(got, []string{"Alice", "Bob", "Dave"}) {
+		t.Errorf("after Dave: %v, want [Alice Bob Dave]", got)
+	}
+	m.Update(updateMsg{upsert("Dave", 400, "2025-01-15T12:00:00Z")})
+	if got := names(m.entries); !slices.Equal(got, []string{"Dave", "Alice", "Bob"}) || m.changed != "Dave" {
+		t.Errorf("after Dave improved: %v (changed %q), want [Dave Alice Bob]", got, m.changed)
+	}
+	m.Update(updateMsg{&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_DELETE, Changed: &pb.ScoreEntry{PlayerName: "Alice"}}})
+	if got := names(m.entries); !slices.Equal(got, []string{"Dave", "Bob"}) {
+		t.Errorf("after deleting Alice: %v, want [Dave Bob]", got)
+	}
+
+	// Lower scores first on ascending boards
+	m.asc = true
+	m.Update(updateMsg{upsert("Erin", 50, "2025-01-15T13:00:00Z")})
+	if got := names(m.entries); !slices.Equal(got, []string{"Erin", "Bob", "Dave"}) {
+		t.Errorf("ascending: %v, want [Erin Bob Dave]", got)
+	}
+}
+
+func TestTUIForm(t *testing.T) {
+	m := newTUIModel(context.Background(), nil, "level-1", 10)
+	typeText := func(s string) {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+	}
+
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil || m.focus != fieldPlayer {
+		t.Error("empty form submitted")
+	}
+	typeText("Alicé")
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	typeText("12a3")
+	if m.fields[fieldPlayer] != "Alic" || m.fields[fieldScore] != "123" {
+		t.Fatalf("fields = %q, want [Alic 123]", m.fields)
+	}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("valid form not submitted")
+	}
+	if m.fields[fieldPlayer] != "Alic" || m.fields[fieldScore] != "" || m.focus != fieldScore {
+		t.Errorf("after submit: fields %q focus %d, want the player kept and the score cleared", m.fields, m.focus)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.fields != [fieldCount]string{} || m.focus != fieldPlayer {
+		t.Errorf("after esc: fields %q focus %d, want a cleared form", m.fields, m.focus)
+	}
+}
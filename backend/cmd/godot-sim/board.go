@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+)
+
+// board is a simulated client's copy of the top N, kept the way the Godot
+// client (front/leadboard-grpc/main.gd) keeps its list: a SNAPSHOT replaces
+// it, an UPSERT replaces the player's entry, re-sorts and trims the list to
+// the limit, and a DELETE removes the player.
+//
+// Equal scores are ordered like the server orders them (achieved_at, then
+// player name) instead of by the unstable sort of the script, so that two
+// converged lists compare equal entry by entry.
+type board struct {
+	limit   int
+	entries []*pb.ScoreEntry
+}
+
+func newBoard(limit int) *board {
+	return &board{limit: limit}
+}
+
+// apply updates the list with an update of the stream
+func (b *board) apply(update *pb.LeaderboardUpdate) {
+	switch update.Kind {
+	case pb.LeaderboardUpdate_SNAPSHOT:
+		b.entries = append(b.entries[:0], update.Snapshot...)
+	case pb.LeaderboardUpdate_UPSERT:
+		b.remove(update.Changed.PlayerName)
+		b.entries = append(b.entries, update.Changed)
+		slices.SortStableFunc(b.entries, compareEntries)
+		if len(b.entries) > b.limit {
+			b.entries = b.entries[:b.limit]
+		}
+	case pb.LeaderboardUpdate_DELETE:
+		b.remove(update.Changed.PlayerName)
+	}
+}
+
+// remove drops a player's entry
+func (b *board) remove(player string) {
+	b.entries = slices.DeleteFunc(b.entries, func(e *pb.ScoreEntry) bool {
+		return e.PlayerName == player
+	})
+}
+
+// diff describes how the list differs from want, the top N served by the
+// server, position by position; it is empty when they are the same
+func (b *board) diff(want []*pb.ScoreEntry) string {
+	var out []string
+	for i := range max(len(b.entries), len(want)) {
+		got, expected := "-", "-"
+		if i < len(b.entries) {
+			got = fmt.Sprintf("%s %d", b.entries[i].PlayerName, b.entries[i].Score)
+		}
+		if i < len(want) {
+			expected = fmt.Sprintf("%s %d", want[i].PlayerName, want[i].Score)
+		}
+		if got != expected {
+			out = append(out, fmt.Sprintf("#%d %s, want %s", i+1, got, expected))
+		}
+	}
+	return strings.Join(out, "; ")
+}
+
+// compareEntries orders entries best first: higher score, then earlier
+// achieved_at, then player name
+func compareEntries(a, b *pb.ScoreEntry) int {
+	if a.Score != b.Score {
+		if a.Score > b.Score {
+			return -1
+		}
+		return 1
+	}
+	aAt, _ := time.Parse(time.RFC3339Nano, a.AchievedAt)
+	bAt, _ := time.Parse(time.RFC3339Nano, b.AchievedAt)
+	if c := aAt.Compare(bAt); c != 0 {
+		return c
+	}
+	return strings.Compare(a.PlayerName, b.PlayerName)
+}
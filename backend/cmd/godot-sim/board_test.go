@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+)
+
+func entry(name string, score int64, achievedAt string) *pb.ScoreEntry {
+	return &pb.ScoreEntry{PlayerName: name, Score: score, AchievedAt: achievedAt}
+}
+
+func TestBoardApply(t *testing.T) {
+	b := newBoard(3)
+	b.apply(&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_SNAPSHOT, Snapshot: []*pb.ScoreEntry{
+		entry("Alice", 300, "2025-01-15T10:00:00Z"),
+		entry("Bob", 200, "2025-01-15T10:00:00Z"),
+	}})
+	b.apply(&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_UPSERT, Changed: entry("Carol", 200, "2025-01-15T09:00:00Z")})
+	b.apply(&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_UPSERT, Changed: entry("Dave", 100, "2025-01-15T10:00:00Z")}) // trimmed
+	b.apply(&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_HEARTBEAT})
+
+	want := []*pb.ScoreEntry{
+		entry("Alice", 300, "2025-01-15T10:00:00Z"),
+		entry("Carol", 200, "2025-01-15T09:00:00Z"), // tied with Bob, achieved first
+		entry("Bob", 200, "2025-01-15T10:00:00Z"),
+	}
+	if diff := b.diff(want); diff != "" {
+		t.Errorf("after upserts: %s", diff)
+	}
+
+	b.apply(&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_UPSERT, Changed: entry("Bob", 400, "2025-01-15T11:00:00Z")})
+	b.apply(&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_DELETE, Changed: entry("Alice", 300, "")})
+	want = []*pb.ScoreEntry{entry("Bob", 400, ""), entry("Carol", 200, "")}
+	if diff := b.diff(want); diff != "" {
+		t.Errorf("after upsert and delete: %s", diff)
+	}
+}
+
+func TestBoardDiff(t *testing.T) {
+	b := newBoard(2)
+	b.apply(&pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_SNAPSHOT, Snapshot: []*pb.ScoreEntry{entry("Alice", 300, "")}})
+
+	got := b.diff([]*pb.ScoreEntry{entry("Alice", 350, ""), entry("Bob", 200, "")})
+	want := "#1 Alice 300, want Alice 350; #2 -, want Bob 200"
+	if got != want {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+}
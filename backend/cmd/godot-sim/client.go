@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"google.golang.org/grpc/status"
+)
+
+// errDropped ends a stream cut by a simulated network drop
+var errDropped = errors.New("network dropped")
+
+// simClient is a simulated Godot client: one player playing runs, and a stream
+// of the board keeping the list the game displays
+type simClient struct {
+	id    int
+	skill float64 // mean score of a run
+
+	drops chan struct{} // network drops, cutting the current stream
+
+	mu      sync.Mutex
+	board   *board
+	seq     int64 // highest seq received, to resume from
+	retries int   // streams reopened after a drop
+}
+
+func newSimClient(id, limit int) *simClient {
+	return &simClient{
+		id:    id,
+		skill: 1000 + rand.Float64()*9000,
+		drops: make(chan struct{}, 1),
+		board: newBoard(limit),
+	}
+}
+
+// follow streams the board into the client's list until ctx is canceled,
+// reopening the stream after every network drop. ready is called once the
+// first snapshot is in.
+func (c *simClient) follow(ctx context.Context, client pb.LeaderboardServiceClient, opts options, ready func()) error {
+	defer ready()
+	for {
+		err := c.streamOnce(ctx, client, opts, ready)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !errors.Is(err, errDropped) {
+			return fmt.Errorf("%s: %w", playerName(c.id), err)
+		}
+
+		// Back online after an outage of up to opts.outage
+		outage := opts.outage/2 + rand.N(opts.outage/2+1)
+		select {
+		case <-time.After(outage):
+		case <-ctx.Done():
+			return nil
+		}
+		select {
+		case <-c.drops: // a drop while offline changes nothing
+		default:
+		}
+		c.mu.Lock()
+		c.retries++
+		c.mu.Unlock()
+	}
+}
+
+// streamOnce opens a stream and applies its updates until it fails or the
+// network drops
+func (c *simClient) streamOnce(ctx context.Context, client pb.LeaderboardServiceClient, opts options, ready func()) error {
+	ctx, cut := context.WithCancel(ctx)
+	defer cut()
+	dropped := make(chan struct{})
+	go func() {
+		select {
+		case <-c.drops:
+			close(dropped)
+			cut()
+		case <-ctx.Done():
+		}
+	}()
+
+	req := &pb.SubscribeRequest{LeaderboardId: opts.board, InitialLimit: int32(opts.limit)}
+	if opts.resume {
+		c.mu.Lock()
+		req.ResumeFromSeq = c.seq
+		c.mu.Unlock()
+	}
+	stream, err := client.StreamLeaderboard(ctx, req)
+	if err != nil {
+		return fmt.Errorf("stream leaderboard: %w", err)
+	}
+
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			select {
+			case <-dropped:
+				return errDropped
+			default:
+			}
+			if errors.Is(err, io.EOF) {
+				return errors.New("server closed the stream")
+			}
+			return errors.New(status.Convert(err).Message())
+		}
+
+		c.mu.Lock()
+		c.board.apply(update)
+		c.seq = max(c.seq, update.Seq)
+		c.mu.Unlock()
+		if update.Kind == pb.LeaderboardUpdate_SNAPSHOT {
+			ready()
+		}
+	}
+}
+
+// play submits the score of a run every opts.run on average until stop expires,
+// and sometimes drops the network after a run. Calls use ctx, so the last ones
+// complete instead of failing at the deadline.
+func (c *simClient) play(ctx, stop context.Context, client pb.LeaderboardServiceClient, opts options, res *result) {
+	for {
+		run := time.Duration(rand.ExpFloat64() * float64(opts.run))
+		select {
+		case <-time.After(run):
+		case <-stop.Done():
+			return
+		}
+
+		resp, err := client.SubmitScore(ctx, &pb.SubmitScoreRequest{
+			LeaderboardId: opts.board,
+			PlayerName:    playerName(c.id),
+			Score:         c.runScore(),
+		})
+		res.record(resp.GetApplied(), err)
+
+		if rand.Float64() < opts.drop {
+			select {
+			case c.drops <- struct{}{}:
+			default: // already dropping
+			}
+		}
+	}
+}
+
+// runScore returns the score of a run: normally distributed around the
+// player's skill, so personal bests get rarer as the player keeps playing
+func (c *simClient) runScore() int64 {
+	return int64(math.Max(0, c.skill*(1+0.2*rand.NormFloat64())))
+}
+
+// diff describes how the client's list differs from the server's top N
+func (c *simClient) diff(want []*pb.ScoreEntry) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.board.diff(want)
+}
+
+// reconnects returns how many times the stream was reopened
+func (c *simClient) reconnects() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.retries
+}
@@ -0,0 +1,232 @@
+// Command godot-sim is the end-to-end correctness test of leaderboard streams.
+// It simulates a fleet of Godot clients against a running server, each one
+// following the top N of a board while its player plays, and checks that every
+// client ends up showing the leaderboard the server serves.
+//
+// Each client plays runs of random length and submits the score of every run,
+// spread around a skill of its own, so most runs are not a personal best. Now
+// and then its network drops: the stream is cut, and it reconnects after a
+// short outage, resuming from the last seq it received (or with a fresh
+// snapshot with -resume=false). Once play stops, every client's list must equal
+// GetTopScores within -settle. It exits with status 1 when a client did not
+// converge.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// options holds the command-line configuration
+type options struct {
+	addr     string
+	apiKey   string
+	board    string
+	clients  int
+	limit    int
+	duration time.Duration
+	run      time.Duration
+	drop     float64
+	outage   time.Duration
+	resume   bool
+	settle   time.Duration
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.addr, "addr", "localhost:50051", "gRPC server address")
+	flag.StringVar(&opts.apiKey, "api-key", "", "API key sent as X-Api-Key")
+	flag.StringVar(&opts.board, "board", "", "leaderboard to play on (default a fresh godot-sim-<unix time> board)")
+	flag.IntVar(&opts.clients, "clients", 20, "simulated Godot clients, one player each")
+	flag.IntVar(&opts.limit, "limit", 10, "size of the top N every client follows")
+	flag.DurationVar(&opts.duration, "duration", 30*time.Second, "how long clients play")
+	flag.DurationVar(&opts.run, "run", time.Second, "mean length of a run, between two submissions of a client")
+	flag.Float64Var(&opts.drop, "drop", 0.05, "probability that a client's network drops after a run")
+	flag.DurationVar(&opts.outage, "outage", time.Second, "longest network outage after a drop")
+	flag.BoolVar(&opts.resume, "resume", true, "resume dropped streams from the last seq received instead of a fresh snapshot")
+	flag.DurationVar(&opts.settle, "settle", 5*time.Second, "how long clients may take to converge once play stops")
+	flag.Parse()
+
+	if opts.board == "" {
+		opts.board = fmt.Sprintf("godot-sim-%d", time.Now().Unix())
+	}
+	if opts.clients <= 0 || opts.limit <= 0 || opts.run <= 0 || opts.outage <= 0 || opts.drop < 0 || opts.drop > 1 {
+		fmt.Fprintln(os.Stderr, "error: -clients, -limit, -run and -outage must be positive, -drop within [0, 1]")
+		os.Exit(2)
+	}
+
+	res, err := simulate(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	res.print(os.Stdout, opts)
+	if len(res.diverged) > 0 {
+		os.Exit(1)
+	}
+}
+
+// result is the outcome of a simulation
+type result struct {
+	mu         sync.Mutex
+	submitted  int
+	applied    int
+	errors     map[string]int // failed SubmitScore calls by gRPC code
+	reconnects int
+	settled    time.Duration  // how long clients took to converge once play stopped
+	diverged   map[int]string // clients that did not converge, with how their list differs
+}
+
+// record counts a submission
+func (r *result) record(applied bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.submitted++
+	switch {
+	case err != nil:
+		r.errors[status.Code(err).String()]++
+	case applied:
+		r.applied++
+	}
+}
+
+func (r *result) print(w io.Writer, opts options) {
+	failed := 0
+	for _, n := range r.errors {
+		failed += n
+	}
+	fmt.Fprintf(w, "Runs: %d submitted, %d personal bests, %d failed, %d reconnects\n", r.submitted, r.applied, failed, r.reconnects)
+	for code, n := range r.errors {
+		fmt.Fprintf(w, "  %s: %d\n", code, n)
+	}
+	if len(r.diverged) == 0 {
+		fmt.Fprintf(w, "Converged: %d/%d clients show the server's top %d after %s\n", opts.clients, opts.clients, opts.limit, r.settled.Round(time.Millisecond))
+		return
+	}
+	fmt.Fprintf(w, "Diverged: %d/%d clients still differ from the server's top %d after %s\n", len(r.diverged), opts.clients, opts.limit, opts.settle)
+	for id := range opts.clients {
+		if diff, ok := r.diverged[id]; ok {
+			fmt.Fprintf(w, "  %s: %s\n", playerName(id), diff)
+		}
+	}
+}
+
+// simulate runs the fleet of clients and checks that they converge
+func simulate(opts options) (*result, error) {
+	conn, err := grpc.NewClient(opts.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+	client := pb.NewLeaderboardServiceClient(conn)
+
+	md := metadata.Pairs(strings.ToLower(requestctx.HeaderClientVersion), "godot-sim", strings.ToLower(requestctx.HeaderPlatform), "godot")
+	if opts.apiKey != "" {
+		md.Append(strings.ToLower(requestctx.HeaderAPIKey), opts.apiKey)
+	}
+	ctx := metadata.NewOutgoingContext(context.Background(), md)
+
+	fmt.Printf("Simulating %d Godot clients on %s at %s for %s, following the top %d\n",
+		opts.clients, opts.board, opts.addr, opts.duration, opts.limit)
+
+	clients := make([]*simClient, opts.clients)
+	for i := range clients {
+		clients[i] = newSimClient(i, opts.limit)
+	}
+
+	// Every client holds its first snapshot before anyone plays
+	streamCtx, stopStreams := context.WithCancel(ctx)
+	defer stopStreams()
+	streams, streamCtx := errgroup.WithContext(streamCtx)
+	var ready sync.WaitGroup
+	for _, c := range clients {
+		ready.Add(1)
+		streams.Go(func() error {
+			return c.follow(streamCtx, client, opts, sync.OnceFunc(ready.Done))
+		})
+	}
+	subscribed := make(chan struct{})
+	go func() {
+		ready.Wait()
+		close(subscribed)
+	}()
+	select {
+	case <-subscribed:
+	case <-streamCtx.Done():
+		return nil, streams.Wait()
+	}
+
+	res := &result{errors: make(map[string]int), diverged: make(map[int]string)}
+	stop, cancel := context.WithTimeout(streamCtx, opts.duration)
+	defer cancel()
+	var players sync.WaitGroup
+	for _, c := range clients {
+		players.Add(1)
+		go func() {
+			defer players.Done()
+			c.play(ctx, stop, client, opts, res)
+		}()
+	}
+	players.Wait()
+	if streamCtx.Err() != nil {
+		return nil, streams.Wait()
+	}
+
+	fmt.Printf("Play stopped, waiting up to %s for clients to converge...\n", opts.settle)
+	if err := converge(ctx, client, clients, opts, res); err != nil {
+		return nil, err
+	}
+	for _, c := range clients {
+		res.reconnects += c.reconnects()
+	}
+
+	stopStreams()
+	if err := streams.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		return nil, err
+	}
+	return res, nil
+}
+
+// converge compares the list of every client with the server's top N until they
+// all match or opts.settle elapses, and records the clients that still differ
+func converge(ctx context.Context, client pb.LeaderboardServiceClient, clients []*simClient, opts options, res *result) error {
+	start := time.Now()
+	deadline := start.Add(opts.settle)
+	for {
+		resp, err := client.GetTopScores(ctx, &pb.GetTopScoresRequest{LeaderboardId: opts.board, Limit: int32(opts.limit)})
+		if err != nil {
+			return fmt.Errorf("get top scores: %s", status.Convert(err).Message())
+		}
+		clear(res.diverged)
+		for _, c := range clients {
+			if diff := c.diff(resp.Entries); diff != "" {
+				res.diverged[c.id] = diff
+			}
+		}
+		res.settled = time.Since(start)
+		if len(res.diverged) == 0 || time.Now().After(deadline) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// playerName returns the name of the player of a client
+func playerName(id int) string {
+	return fmt.Sprintf("sim-%d", id)
+}
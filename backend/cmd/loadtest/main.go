@@ -0,0 +1,226 @@
+// Command loadtest drives a running server with concurrent gRPC clients
+// submitting scores and stream subscribers watching the board, then reports
+// SubmitScore latencies, how long applied scores took to reach subscribers and
+// how many never did.
+//
+// Each client owns its players and mostly improves their scores, so most
+// submissions are applied and broadcast. Every subscriber should see every
+// applied score (or a better one): keep -clients x -players within the
+// server's MAX_LIMIT so all players stay in the subscribers' view. It exits
+// with status 1 when updates were dropped.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// options holds the command-line configuration
+type options struct {
+	addr        string
+	apiKey      string
+	board       string
+	clients     int
+	players     int
+	subscribers int
+	duration    time.Duration
+	rate        float64
+	improve     float64
+	drain       time.Duration
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.addr, "addr", "localhost:50051", "gRPC server address")
+	flag.StringVar(&opts.apiKey, "api-key", "", "API key sent as X-Api-Key")
+	flag.StringVar(&opts.board, "board", "", "leaderboard to load (default a fresh loadtest-<unix time> board)")
+	flag.IntVar(&opts.clients, "clients", 10, "concurrent clients submitting scores")
+	flag.IntVar(&opts.players, "players", 5, "players per client")
+	flag.IntVar(&opts.subscribers, "subscribers", 5, "concurrent StreamLeaderboard subscribers")
+	flag.DurationVar(&opts.duration, "duration", 30*time.Second, "how long clients submit")
+	flag.Float64Var(&opts.rate, "rate", 20, "submissions per second per client (0 = as fast as possible)")
+	flag.Float64Var(&opts.improve, "improve", 0.8, "share of submissions that improve the player's best")
+	flag.DurationVar(&opts.drain, "drain", 2*time.Second, "how long subscribers keep receiving after the last submission")
+	flag.Parse()
+
+	if opts.board == "" {
+		opts.board = fmt.Sprintf("loadtest-%d", time.Now().Unix())
+	}
+	if opts.clients <= 0 || opts.players <= 0 || opts.subscribers < 0 || opts.rate < 0 || opts.improve < 0 || opts.improve > 1 {
+		fmt.Fprintln(os.Stderr, "error: -clients and -players must be positive, -subscribers and -rate non-negative, -improve within [0, 1]")
+		os.Exit(2)
+	}
+
+	rep, err := run(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	rep.print(os.Stdout)
+	if rep.dropped() > 0 {
+		os.Exit(1)
+	}
+}
+
+// run loads the server and collects the report
+func run(opts options) (*report, error) {
+	conn, err := grpc.NewClient(opts.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+	client := pb.NewLeaderboardServiceClient(conn)
+
+	md := metadata.Pairs(strings.ToLower(requestctx.HeaderClientVersion), "loadtest")
+	if opts.apiKey != "" {
+		md.Append(strings.ToLower(requestctx.HeaderAPIKey), opts.apiKey)
+	}
+	ctx := metadata.NewOutgoingContext(context.Background(), md)
+
+	rep := newReport(opts.subscribers)
+	fmt.Printf("Loading %s on %s: %d clients x %d players, %d subscribers, %s\n",
+		opts.board, opts.addr, opts.clients, opts.players, opts.subscribers, opts.duration)
+
+	// Subscribers first: every one of them must hold its snapshot before the first submission
+	streamCtx, stopStreams := context.WithCancel(ctx)
+	defer stopStreams()
+	streams, streamCtx := errgroup.WithContext(streamCtx)
+	var ready sync.WaitGroup
+	for i := range opts.subscribers {
+		ready.Add(1)
+		streams.Go(func() error {
+			return subscribe(streamCtx, client, opts.board, int32(opts.clients*opts.players), rep, i, ready.Done)
+		})
+	}
+	subscribed := make(chan struct{})
+	go func() {
+		ready.Wait()
+		close(subscribed)
+	}()
+	select {
+	case <-subscribed:
+	case <-streamCtx.Done():
+		return nil, streams.Wait()
+	}
+
+	stop, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+	start := time.Now()
+	var submitters sync.WaitGroup
+	for i := range opts.clients {
+		submitters.Add(1)
+		go func() {
+			defer submitters.Done()
+			submit(ctx, stop, client, opts, i, rep)
+		}()
+	}
+	submitters.Wait()
+	rep.elapsed = time.Since(start)
+
+	fmt.Printf("Submissions done, draining streams for %s...\n", opts.drain)
+	select {
+	case <-time.After(opts.drain):
+	case <-streamCtx.Done():
+	}
+	stopStreams()
+	if err := streams.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// submit sends the scores of one client's players until stop expires. Calls use
+// ctx, so the last ones complete instead of failing at the deadline.
+func submit(ctx, stop context.Context, client pb.LeaderboardServiceClient, opts options, id int, rep *report) {
+	players := make([]string, opts.players)
+	best := make([]int64, opts.players)
+	for j := range players {
+		players[j] = fmt.Sprintf("lt-%d-%d", id, j)
+	}
+
+	var tick <-chan time.Time
+	if opts.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for n := 0; ; n++ {
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-stop.Done():
+				return
+			}
+		} else if stop.Err() != nil {
+			return
+		}
+
+		j := n % len(players)
+		score := best[j] + 1 + rand.Int64N(100)
+		if best[j] > 0 && rand.Float64() >= opts.improve {
+			score = rand.Int64N(best[j]) // not an improvement
+		}
+
+		// Recorded before sending: the update may reach subscribers before the response
+		sent := rep.sent(players[j], score)
+		resp, err := client.SubmitScore(ctx, &pb.SubmitScoreRequest{LeaderboardId: opts.board, PlayerName: players[j], Score: score})
+		rep.submitted(sent, resp.GetApplied(), err)
+		if err == nil && resp.Applied {
+			best[j] = score
+		}
+	}
+}
+
+// subscribe streams the board into the report until ctx is canceled
+func subscribe(ctx context.Context, client pb.LeaderboardServiceClient, board string, limit int32, rep *report, id int, ready func()) error {
+	stream, err := client.StreamLeaderboard(ctx, &pb.SubscribeRequest{LeaderboardId: board, InitialLimit: limit})
+	if err != nil {
+		ready()
+		return fmt.Errorf("subscriber %d: %w", id, err)
+	}
+
+	snapshot := false
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			if !snapshot {
+				ready()
+			}
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("subscriber %d: %s", id, status.Convert(err).Message())
+		}
+
+		now := time.Now()
+		switch update.Kind {
+		case pb.LeaderboardUpdate_SNAPSHOT:
+			for _, e := range update.Snapshot {
+				rep.received(id, e.PlayerName, e.Score, now)
+			}
+			if !snapshot {
+				snapshot = true
+				ready()
+			}
+		case pb.LeaderboardUpdate_UPSERT:
+			rep.received(id, update.Changed.PlayerName, update.Changed.Score, now)
+		}
+	}
+}
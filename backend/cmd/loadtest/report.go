@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// submission is a score sent by a client, tracked until subscribers see it
+type submission struct {
+	player  string
+	score   int64
+	at      time.Time
+	applied bool            // the score became the player's best
+	lags    []time.Duration // from sending to each subscriber seeing it (or a better score)
+}
+
+// report collects the measurements of a run. Scores of a player only
+// increase when applied, so a subscriber that saw a score has seen every
+// submission of that player up to it, including the ones merged or skipped
+// by the server.
+type report struct {
+	mu          sync.Mutex
+	subscribers int
+	elapsed     time.Duration
+	latencies   []time.Duration // SubmitScore round trips that succeeded
+	errors      map[string]int  // failed SubmitScore calls by gRPC code
+	ledger      map[string][]*submission
+	cursors     []map[string]int // per subscriber and player: first submission not seen yet
+}
+
+func newReport(subscribers int) *report {
+	r := &report{
+		subscribers: subscribers,
+		errors:      make(map[string]int),
+		ledger:      make(map[string][]*submission),
+		cursors:     make([]map[string]int, subscribers),
+	}
+	for i := range r.cursors {
+		r.cursors[i] = make(map[string]int)
+	}
+	return r
+}
+
+// sent records a submission about to be sent
+func (r *report) sent(player string, score int64) *submission {
+	s := &submission{player: player, score: score, at: time.Now()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ledger[player] = append(r.ledger[player], s)
+	return s
+}
+
+// submitted records the outcome of a submission
+func (r *report) submitted(s *submission, applied bool, err error) {
+	latency := time.Since(s.at)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors[status.Code(err).String()]++
+		return
+	}
+	s.applied = applied
+	r.latencies = append(r.latencies, latency)
+}
+
+// received records that a subscriber saw a player at a score
+func (r *report) received(subscriber int, player string, score int64, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sent := r.ledger[player]
+	c := r.cursors[subscriber][player]
+	for ; c < len(sent) && sent[c].score <= score; c++ {
+		sent[c].lags = append(sent[c].lags, at.Sub(sent[c].at))
+	}
+	r.cursors[subscriber][player] = c
+}
+
+// broadcast sums up the delivery of applied submissions
+func (r *report) broadcast() (applied, delivered int, lags []time.Duration) {
+	for _, sent := range r.ledger {
+		for _, s := range sent {
+			if !s.applied {
+				continue
+			}
+			applied++
+			delivered += len(s.lags)
+			lags = append(lags, s.lags...)
+		}
+	}
+	return applied, delivered, lags
+}
+
+// dropped is the number of applied submissions subscribers never saw
+func (r *report) dropped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	applied, delivered, _ := r.broadcast()
+	return applied*r.subscribers - delivered
+}
+
+func (r *report) print(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := len(r.latencies)
+	failed := 0
+	for _, n := range r.errors {
+		failed += n
+	}
+	applied, delivered, lags := r.broadcast()
+	fmt.Fprintf(w, "\nSubmitScore: %d calls in %s (%.1f/s), %d applied, %d failed\n",
+		calls+failed, r.elapsed.Round(time.Millisecond), float64(calls+failed)/r.elapsed.Seconds(), applied, failed)
+	for _, code := range slices.Sorted(maps.Keys(r.errors)) {
+		fmt.Fprintf(w, "  %-20s %d\n", code, r.errors[code])
+	}
+	fmt.Fprintf(w, "  latency %s\n", percentiles(r.latencies))
+
+	if r.subscribers == 0 {
+		return
+	}
+	dropped := applied*r.subscribers - delivered
+	fmt.Fprintf(w, "Broadcast: %d applied scores x %d subscribers, %d delivered, %d dropped\n",
+		applied, r.subscribers, delivered, dropped)
+	fmt.Fprintf(w, "  lag     %s\n", percentiles(lags))
+	if dropped > 0 {
+		fmt.Fprintln(w, "❌ Updates were dropped")
+	}
+}
+
+// percentiles formats the p50, p95, p99 and maximum of durations
+func percentiles(d []time.Duration) string {
+	if len(d) == 0 {
+		return "n/a"
+	}
+	sorted := slices.Clone(d)
+	slices.Sort(sorted)
+	format := func(d time.Duration) time.Duration { return d.Round(time.Microsecond) }
+	return fmt.Sprintf("p50 %s  p95 %s  p99 %s  max %s",
+		format(percentile(sorted, 50)), format(percentile(sorted, 95)), format(percentile(sorted, 99)), format(sorted[len(sorted)-1]))
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
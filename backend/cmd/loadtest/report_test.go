@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPercentile(t *testing.T) {
+	var d []time.Duration
+	for i := 1; i <= 100; i++ {
+		d = append(d, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[float64]time.Duration{50: 50 * time.Millisecond, 95: 95 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := percentile(d, p); got != want {
+			t.Errorf("percentile(%v) = %s, want %s", p, got, want)
+		}
+	}
+	if got := percentile(d[:1], 99); got != time.Millisecond {
+		t.Errorf("percentile of one value = %s, want 1ms", got)
+	}
+}
+
+func TestReportDelivery(t *testing.T) {
+	r := newReport(2)
+	first := r.sent("Alice", 100)
+	r.submitted(first, true, nil)
+	lower := r.sent("Alice", 50)
+	r.submitted(lower, false, nil)
+	merged := r.sent("Alice", 120)
+	r.submitted(merged, true, nil)
+	last := r.sent("Alice", 150)
+	r.submitted(last, true, nil)
+	failed := r.sent("Bob", 10)
+	r.submitted(failed, false, status.Error(codes.ResourceExhausted, "overloaded"))
+
+	// Subscriber 0 sees every update, subscriber 1 only the last one (120 merged into it),
+	// which arrives before the response of its submission
+	r.received(0, "Alice", 100, time.Now())
+	r.received(0, "Alice", 120, time.Now())
+	r.received(0, "Alice", 150, time.Now())
+	pending := r.sent("Alice", 200)
+	r.received(1, "Alice", 200, time.Now())
+	r.submitted(pending, true, nil)
+
+	applied, delivered, lags := r.broadcast()
+	if applied != 4 || delivered != 7 || len(lags) != 7 {
+		t.Errorf("broadcast() = %d applied, %d delivered, %d lags; want 4, 7, 7", applied, delivered, len(lags))
+	}
+	if got := r.dropped(); got != 1 {
+		t.Errorf("dropped() = %d, want 1 (200 never reached subscriber 0)", got)
+	}
+	if r.errors[codes.ResourceExhausted.String()] != 1 || len(r.latencies) != 5 {
+		t.Errorf("errors = %v, %d latencies; want 1 RESOURCE_EXHAUSTED and 5 latencies", r.errors, len(r.latencies))
+	}
+}
@@ -2,23 +2,47 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net"
 	"os"
 	"os/signal"
+	"regexp"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // zone data for DAILY_TIMEZONE in minimal images
 
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
 	_ "github.com/yourorg/leaderboard/docs" // Import swagger docs
 	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	pbv2 "github.com/yourorg/leaderboard/gen/leaderboard/v2"
+	"github.com/yourorg/leaderboard/internal/bus"
 	"github.com/yourorg/leaderboard/internal/config"
+	"github.com/yourorg/leaderboard/internal/events"
+	"github.com/yourorg/leaderboard/internal/geoip"
+	"github.com/yourorg/leaderboard/internal/health"
+	"github.com/yourorg/leaderboard/internal/identity"
+	"github.com/yourorg/leaderboard/internal/integrations"
+	"github.com/yourorg/leaderboard/internal/kafka"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/listen"
 	"github.com/yourorg/leaderboard/internal/log"
+	"github.com/yourorg/leaderboard/internal/maintenance"
 	"github.com/yourorg/leaderboard/internal/notify"
 	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/status"
 	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
 	grpcTransport "github.com/yourorg/leaderboard/internal/transport/grpc"
 	restTransport "github.com/yourorg/leaderboard/internal/transport/rest"
+	"github.com/yourorg/leaderboard/internal/usage"
+	"github.com/yourorg/leaderboard/internal/webhook"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -30,11 +54,19 @@ func main() {
 }
 
 func run() error {
+	printConfig := flag.Bool("print-config", false, "print the effective configuration as YAML, secrets redacted, and exit")
+	flag.Parse()
+
+	startedAt := time.Now()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
+	if *printConfig {
+		return cfg.WriteYAML(os.Stdout)
+	}
 
 	// Initialize logger
 	logger := log.NewConsole(cfg.LogLevel)
@@ -44,72 +76,340 @@ func run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize database connection pool
-	logger.Info().Msg("connecting to database")
-	pool, err := store.NewPool(ctx, cfg.DatabaseURL)
+	// Initialize storage backend and its change source
+	st, source, maxWrites, err := openStorage(ctx, cfg, logger.Logger)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	// Lifecycle events (startup, shutdown, degraded mode, maintenance, rollovers)
+	// for the webhook, Redis and Kafka sinks
+	lifecycleEvents := lifecycle.NewBus(logger.Logger)
+
+	source, closeBus, err := broadcastBus(cfg, source, lifecycleEvents, logger.Logger)
 	if err != nil {
-		return fmt.Errorf("create database pool: %w", err)
+		return err
 	}
-	defer pool.Close()
-	logger.Info().Msg("database connection established")
+	defer closeBus()
+	// Closed before the broadcast bus publishing its events
+	defer lifecycleEvents.Close()
+	replayer, source := eventReplayer(st, source, logger.Logger)
+	source.Start(ctx)
 
-	// Initialize store
-	st := store.NewStore(pool)
+	// Wire formats of the events sent to webhooks, Kafka, chat and SSE clients
+	formats := events.NewRegistry("/leaderboard")
+	integrations.RegisterFormats(formats)
 
-	// Initialize notify listener
-	listener := notify.NewListener(pool, logger.Logger)
-	listener.Start(ctx)
+	// Fan out notifications to the gRPC broadcaster, the service cache, webhooks and chat
+	dispatcher := notify.NewDispatcher(source.Changes(), logger.Logger)
+	grpcChanges := dispatcher.Subscribe()
+	cacheChanges := dispatcher.Subscribe()
+	if err := startWebhooks(ctx, cfg, st, dispatcher, lifecycleEvents, formats, logger.Logger); err != nil {
+		return err
+	}
+	var announcer *integrations.Announcer
+	if cfg.ChatWebhookURL != "" {
+		announcer = integrations.NewAnnouncer(st, integrations.Config{
+			WebhookURL: cfg.ChatWebhookURL,
+			Platform:   cfg.ChatPlatform,
+			Boards:     cfg.ChatBoards,
+			Timeout:    cfg.ChatTimeout,
+			Formats:    formats,
+		}, logger.Logger)
+		go announcer.Run(dispatcher.Subscribe())
+	}
+	go dispatcher.Run()
 
 	// Log listener errors in background
 	go func() {
-		for err := range listener.Errors() {
+		for err := range source.Errors() {
 			logger.Error().Err(err).Msg("notify listener error")
 		}
 	}()
 
 	// Initialize service layer
-	svc := service.New(st, logger.Logger)
+	defaultTiers, err := service.ParseTierDefinitions(cfg.Tiers)
+	if err != nil {
+		return fmt.Errorf("parse TIERS: %w", err)
+	}
+	tiers, err := service.ParseBoardTierDefinitions(cfg.BoardTiers)
+	if err != nil {
+		return fmt.Errorf("parse BOARD_TIERS: %w", err)
+	}
+	if _, ok := tiers[service.DefaultLeaderboardID]; !ok && len(defaultTiers) > 0 {
+		tiers[service.DefaultLeaderboardID] = defaultTiers
+	}
+	receiptKeys, err := service.ParseReceiptKeys(cfg.ReceiptKeys)
+	if err != nil {
+		return fmt.Errorf("parse RECEIPT_KEYS: %w", err)
+	}
+
+	// Validated by config.Load; time/tzdata makes zone names work without system tzdata
+	dailyLocation, err := time.LoadLocation(cfg.DailyTimezone)
+	if err != nil {
+		return fmt.Errorf("load DAILY_TIMEZONE: %w", err)
+	}
+	geo, err := geoResolver(cfg, logger.Logger)
+	if err != nil {
+		return err
+	}
+
+	// Ship submission events to Kafka when brokers are configured
+	var submissionSink service.SubmissionSink
+	if len(cfg.KafkaBrokers) > 0 {
+		serializer, err := formats.Get(cfg.KafkaEventFormat)
+		if err != nil {
+			return fmt.Errorf("KAFKA_EVENT_FORMAT: %w", err)
+		}
+		sink := kafka.New(kafka.Config{
+			Brokers:      cfg.KafkaBrokers,
+			Topic:        cfg.KafkaTopic,
+			BatchSize:    int(cfg.KafkaBatchSize),
+			BatchTimeout: cfg.KafkaBatchTimeout,
+			BufferSize:   int(cfg.KafkaBufferSize),
+			Serializer:   serializer,
+		}, logger.Logger)
+		defer func() {
+			closeCtx, closeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer closeCancel()
+			if err := sink.Close(closeCtx); err != nil {
+				logger.Error().Err(err).Msg("error closing kafka sink")
+			}
+		}()
+		go sink.RunLifecycle(lifecycleEvents.Subscribe())
+		submissionSink = sink
+		logger.Info().Strs("brokers", cfg.KafkaBrokers).Str("topic", cfg.KafkaTopic).Msg("score submission events shipped to kafka")
+	}
+
+	// Validated by config.Load
+	var namePattern *regexp.Regexp
+	if cfg.PlayerNamePattern != "" {
+		namePattern = regexp.MustCompile(cfg.PlayerNamePattern)
+	}
+
+	// Size write admission from the backend so writes queue here, not on the database
+	writeConcurrency := int64(cfg.WriteConcurrency)
+	if writeConcurrency == 0 {
+		writeConcurrency = maxWrites
+	}
+	svc := service.New(st, logger.Logger, service.Options{
+		DeviceLimits: service.DeviceLimits{
+			Mode:                  cfg.DeviceLimitMode,
+			MaxAccounts:           cfg.DeviceMaxAccounts,
+			MaxSubmissionsPerHour: cfg.DeviceMaxSubmissionsPerHour,
+		},
+		Validation: service.Validation{
+			MinPlayerNameLength: int(cfg.MinPlayerNameLength),
+			MaxPlayerNameLength: int(cfg.MaxPlayerNameLength),
+			MaxScore:            cfg.MaxScore,
+			PlayerNamePattern:   namePattern,
+		},
+		PercentileBuckets:  cfg.PercentileBuckets,
+		PercentileCacheTTL: cfg.PercentileCacheTTL,
+		CountCacheTTL:      cfg.CountCacheTTL,
+		TopCacheSize:       int(cfg.TopCacheSize),
+		TopCacheBoards:     int(cfg.TopCacheBoards),
+		Tiers:              tiers,
+		Admission: service.Admission{
+			MaxConcurrent: writeConcurrency,
+			MaxWait:       cfg.AdmissionMaxWait,
+			RetryAfter:    cfg.AdmissionRetryAfter,
+		},
+		ClientTimestamps: service.ClientTimestamps{
+			SkewWindow: cfg.ClockSkewWindow,
+			MaxAge:     cfg.ClientTimestampMaxAge,
+		},
+		OfflineSync: service.OfflineSync{
+			SigningKey: []byte(cfg.OfflineSyncKey),
+			MaxRuns:    int(cfg.OfflineSyncMaxRuns),
+		},
+		SubmitSigning: service.SubmitSigning{
+			Mode:   cfg.SubmitSignatureMode,
+			Key:    []byte(cfg.SubmitSigningKey),
+			MaxAge: cfg.SubmitSignatureMaxAge,
+		},
+		ProfileCacheTTL: cfg.ProfileCacheTTL,
+		Import: service.Import{
+			MaxEntries: int(cfg.ImportMaxEntries),
+			ChunkSize:  int(cfg.ImportChunkSize),
+		},
+		Admin: service.Admin{
+			Token:             cfg.AdminToken,
+			ConfirmTTL:        cfg.AdminConfirmTTL,
+			RestoreMaxEntries: int(cfg.RestoreMaxEntries),
+		},
+		Identity: identityResolver(cfg, logger.Logger),
+		Geo:      geo,
+		Daily: service.Daily{
+			Prefix:    cfg.DailyPrefix,
+			Location:  dailyLocation,
+			SortOrder: service.SortOrder(cfg.DailySortOrder),
+			Grace:     cfg.DailyGrace,
+			SeedKey:   []byte(cfg.DailySeedKey),
+		},
+		Receipts:    service.Receipts{Keys: receiptKeys},
+		Events:      lifecycleEvents,
+		Submissions: submissionSink,
+		Replayer:    replayer,
+		Changes:     changeLog(cfg, st),
+		Usage: usage.New(usage.Config{
+			Window:  cfg.UsageWindow,
+			MaxKeys: int(cfg.UsageMaxKeys),
+		}),
+		RankingExperiment: service.RankingExperiment{
+			Variant:  cfg.RankingExperimentVariant,
+			Percent:  cfg.RankingExperimentPercent,
+			HalfLife: cfg.RankingRecencyHalfLife,
+		},
+		Cohorts: service.CohortMetrics{
+			Enabled:           cfg.CohortMetrics,
+			NewPlayerWindow:   cfg.CohortNewPlayerWindow,
+			Platforms:         cfg.CohortPlatforms,
+			MaxClientVersions: int(cfg.CohortMaxClientVersions),
+		},
+		SubmitRank:  cfg.SubmitRank,
+		SubmitQueue: submitQueue(cfg),
+	})
+	if cfg.RankingExperimentVariant != "" && cfg.RankingExperimentPercent > 0 {
+		logger.Info().Str("variant", cfg.RankingExperimentVariant).Float64("percent", cfg.RankingExperimentPercent).Msg("ranking experiment enabled")
+	}
+	go svc.RunTopCache(cacheChanges)
+	go svc.RunTierScheduler(ctx, cfg.TierRecomputeInterval)
+	go svc.RunDailyBoards(ctx)
+	go svc.RunSubmitQueue()
+	if cfg.SubmitMode == "async" {
+		logger.Info().Int32("queue_size", cfg.SubmitQueueSize).Int32("workers", cfg.SubmitQueueWorkers).Msg("score submissions queued for batched writes")
+	}
+
+	// Compress large responses for clients accepting it, e.g. snapshots sent to mobile clients
+	unaryInterceptors := []grpc.UnaryServerInterceptor{grpcTransport.UnaryRequestContext(), grpcTransport.UnaryUsage(svc)}
+	streamInterceptors := []grpc.StreamServerInterceptor{grpcTransport.StreamRequestContext(), grpcTransport.StreamUsage(svc)}
+	if cfg.GRPCCompression != "none" {
+		if cfg.GRPCCompression == grpcTransport.ZstdName {
+			grpcTransport.RegisterZstd()
+		}
+		unaryInterceptors = append(unaryInterceptors, grpcTransport.UnaryCompression(cfg.GRPCCompression, int(cfg.CompressionMinSize)))
+		streamInterceptors = append(streamInterceptors, grpcTransport.StreamCompression(cfg.GRPCCompression, int(cfg.CompressionMinSize)))
+		logger.Info().Str("compressor", cfg.GRPCCompression).Int32("min_size", cfg.CompressionMinSize).Msg("gRPC response compression enabled")
+	}
 
 	// Initialize gRPC server
 	grpcServer := grpc.NewServer(
-		grpc.MaxRecvMsgSize(1024*1024),     // 1MB
-		grpc.MaxSendMsgSize(10*1024*1024),  // 10MB
-		grpc.MaxConcurrentStreams(1000),
+		grpc.MaxRecvMsgSize(int(cfg.GRPCMaxRecvMsgSize)),
+		grpc.MaxSendMsgSize(int(cfg.GRPCMaxSendMsgSize)),
+		grpc.MaxConcurrentStreams(uint32(cfg.GRPCMaxConcurrentStreams)),
+		// Ping idle connections so NATs and proxies keep them, and drop dead peers
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    cfg.GRPCKeepaliveTime,
+			Timeout: cfg.GRPCKeepaliveTimeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             cfg.GRPCKeepaliveMinTime,
+			PermitWithoutStream: true,
+		}),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
 	)
 
-	grpcHandler := grpcTransport.NewServer(svc, listener, logger.Logger, cfg.DefaultLimit, cfg.MaxLimit)
+	grpcHandler := grpcTransport.NewServer(svc, grpcChanges, logger.Logger, cfg.DefaultLimit, cfg.MaxLimit, cfg.StreamHeartbeatInterval, cfg.StreamCoalesceWindow)
+	grpcHandler.SetResumeBuffer(int(cfg.StreamResumeBuffer))
+	grpcHandler.SetMaxSubscribers(int(cfg.StreamMaxSubscribers))
 	pb.RegisterLeaderboardServiceServer(grpcServer, grpcHandler)
+	pbv2.RegisterLeaderboardServiceServer(grpcServer, grpcHandler.V2())
+
+	// Health service follows readiness: database reachable and change source listening
+	checker := health.NewChecker(st, source, grpcHandler.SubscriberCount, cfg.HealthCheckTimeout, logger.Logger)
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	go checker.Run(ctx, cfg.HealthCheckInterval, healthServer, lifecycleEvents)
+
+	// Keep planner statistics fresh and watch table and index health
+	maintenanceJob := maintenance.NewJob(st, maintenance.DefaultThresholds, logger.Logger)
+	go maintenanceJob.Run(ctx, cfg.MaintenanceInterval)
+
+	// Move stale and low scores off the live boards into the archive
+	var archiveJob *maintenance.ArchiveJob
+	if cfg.PruneSchedule != "" {
+		schedule, err := maintenance.ParseSchedule(cfg.PruneSchedule)
+		if err != nil {
+			return fmt.Errorf("parse prune schedule: %w", err)
+		}
+		archiveJob = maintenance.NewArchiveJob(st, maintenance.ArchivePolicy{
+			Retention:  cfg.PruneRetention,
+			ScoreFloor: cfg.PruneScoreFloor,
+			BatchSize:  cfg.PruneBatchSize,
+			DryRun:     cfg.PruneDryRun,
+		}, logger.Logger)
+		archiveJob.SetLifecycle(lifecycleEvents)
+		go archiveJob.Run(ctx, schedule)
+	}
+	grpcHandler.SetArchiveJob(archiveJob)
 
 	// Enable gRPC reflection for grpcurl and similar tools
 	reflection.Register(grpcServer)
 
 	// Initialize REST server
-	restServer := restTransport.NewServer(svc, logger.Logger)
+	reporter := status.NewReporter(svc, checker, startedAt, cfg.StatusCacheTTL)
+	grpcHandler.SetStatusReporter(reporter)
+	restServer := restTransport.NewServer(svc, checker, reporter, maintenanceJob, logger.Logger, cfg.DefaultLimit, cfg.MaxLimit)
+	restServer.SetStreamer(grpcHandler)
+	restServer.SetEventRegistry(formats)
+	restServer.SetArchiveJob(archiveJob)
+	if cfg.HTTPCompression == "gzip" {
+		restServer.EnableGzip(int(cfg.CompressionMinSize))
+	}
 
-	// Start gRPC server in goroutine
-	grpcAddr := fmt.Sprintf(":%s", cfg.GRPCPort)
-	grpcListener, err := net.Listen("tcp", grpcAddr)
+	// Bind every configured address before serving, so a busy or invalid one fails startup
+	grpcListeners, err := listen.Listen(cfg.GRPCListen)
+	if err != nil {
+		return fmt.Errorf("create gRPC listeners: %w", err)
+	}
+	restListeners, err := listen.Listen(cfg.RESTListen)
 	if err != nil {
-		return fmt.Errorf("create gRPC listener: %w", err)
+		listen.Close(grpcListeners)
+		return fmt.Errorf("create REST listeners: %w", err)
 	}
 
-	grpcErrChan := make(chan error, 1)
-	go func() {
-		logger.Info().Str("addr", grpcAddr).Msg("starting gRPC server")
-		if err := grpcServer.Serve(grpcListener); err != nil {
-			grpcErrChan <- fmt.Errorf("gRPC server: %w", err)
-		}
-	}()
+	// Start gRPC server on each listener
+	grpcErrChan := make(chan error, len(grpcListeners))
+	for _, ln := range grpcListeners {
+		go func(ln net.Listener) {
+			logger.Info().Str("addr", ln.Addr().String()).Msg("starting gRPC server")
+			if err := grpcServer.Serve(ln); err != nil {
+				grpcErrChan <- fmt.Errorf("gRPC server on %s: %w", ln.Addr(), err)
+			}
+		}(ln)
+	}
 
-	// Start REST server in goroutine
-	restAddr := fmt.Sprintf(":%s", cfg.RESTPort)
-	restErrChan := make(chan error, 1)
-	go func() {
-		logger.Info().Str("addr", restAddr).Msg("starting REST server")
-		if err := restServer.Start(restAddr); err != nil {
-			restErrChan <- fmt.Errorf("REST server: %w", err)
-		}
-	}()
+	// Start REST server on each listener
+	restErrChan := make(chan error, len(restListeners))
+	for _, ln := range restListeners {
+		go func(ln net.Listener) {
+			logger.Info().Str("addr", ln.Addr().String()).Msg("starting REST server")
+			if err := restServer.Serve(ln); err != nil {
+				restErrChan <- fmt.Errorf("REST server on %s: %w", ln.Addr(), err)
+			}
+		}(ln)
+	}
+
+	lifecycleEvents.Publish(lifecycle.ServerStarted, map[string]string{
+		"grpc_listen": strings.Join(cfg.GRPCListen, ","),
+		"rest_listen": strings.Join(cfg.RESTListen, ","),
+		"db_driver":   cfg.DBDriver,
+	})
+
+	// Apply the reloadable settings on SIGHUP, from a copy the reloader owns
+	applied := *cfg
+	go (&reloader{
+		cfg:       &applied,
+		logger:    logger.Logger,
+		events:    lifecycleEvents,
+		svc:       svc,
+		grpc:      grpcHandler,
+		rest:      restServer,
+		announcer: announcer,
+	}).run(ctx)
 
 	// Wait for interrupt signal
 	sigChan := make(chan os.Signal, 1)
@@ -119,6 +419,7 @@ func run() error {
 	select {
 	case sig := <-sigChan:
 		logger.Info().Str("signal", sig.String()).Msg("received shutdown signal")
+		lifecycleEvents.Publish(lifecycle.ServerStopping, map[string]string{"signal": sig.String()})
 	case err := <-grpcErrChan:
 		return err
 	case err := <-restErrChan:
@@ -128,6 +429,9 @@ func run() error {
 	// Graceful shutdown
 	logger.Info().Msg("shutting down gracefully")
 
+	// Report NOT_SERVING so load balancers stop routing new calls
+	healthServer.Shutdown()
+
 	// Create shutdown context with timeout
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
@@ -152,9 +456,231 @@ func run() error {
 		logger.Info().Msg("gRPC server stopped gracefully")
 	}
 
-	// Cancel main context to stop notify listener
+	// Write out queued submissions while the store is open and changes still flow
+	if err := svc.StopSubmitQueue(shutdownCtx); err != nil {
+		logger.Warn().Err(err).Msg("submission queue not written out in time")
+	}
+
+	// Stop the change source before the store closes, so its connection is
+	// released and the dispatcher drains what is buffered
+	if err := source.Stop(shutdownCtx); err != nil {
+		logger.Warn().Err(err).Msg("change source did not stop in time")
+	} else {
+		logger.Info().Msg("change source stopped")
+	}
+
+	// Cancel main context to stop background jobs
 	cancel()
 
 	logger.Info().Msg("shutdown complete")
 	return nil
 }
+
+// openStorage opens the backend selected by DB_DRIVER and returns it with its change
+// source and the number of concurrent writes it can serve
+func openStorage(ctx context.Context, cfg *config.Config, logger *zerolog.Logger) (store.Repository, notify.Source, int64, error) {
+	switch cfg.DBDriver {
+	case config.DBDriverSQLite:
+		logger.Info().Str("path", cfg.SQLitePath).Msg("opening sqlite database")
+		st, err := sqlite.Open(ctx, cfg.SQLitePath)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("open sqlite database: %w", err)
+		}
+		if err := st.ApplyValidation(ctx, storeValidation(cfg)); err != nil {
+			st.Close()
+			return nil, nil, 0, fmt.Errorf("apply validation constraints: %w", err)
+		}
+		logger.Info().Msg("sqlite database ready")
+		return st, sqlite.NewPoller(st, cfg.SQLitePollInterval, logger), 1, nil
+
+	default:
+		logger.Info().Msg("connecting to database")
+		var poolOpts []store.PoolOption
+		if cfg.DBRequestTagging {
+			poolOpts = append(poolOpts, store.WithRequestTagging())
+		}
+		poolOpts = append(poolOpts,
+			store.WithStatementTimeout(cfg.DBStatementTimeout),
+			store.WithPoolSettings(store.PoolSettings{
+				MaxConns:          cfg.DBMaxConns,
+				MinConns:          cfg.DBMinConns,
+				MaxConnLifetime:   cfg.DBMaxConnLifetime,
+				MaxConnIdleTime:   cfg.DBMaxConnIdleTime,
+				HealthCheckPeriod: cfg.DBHealthCheckPeriod,
+			}),
+		)
+		pool, err := store.NewPool(ctx, cfg.DatabaseURL, poolOpts...)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("create database pool: %w", err)
+		}
+		logger.Info().Msg("database connection established")
+
+		st := store.NewStore(pool)
+		st.SetQueryTimeout(cfg.DBQueryTimeout)
+		st.SetCircuitBreaker(int(cfg.DBFailureThreshold), cfg.DBCooldown, logger)
+		if cfg.DatabaseReadURL != "" {
+			replica, err := store.NewReadPool(ctx, cfg.DatabaseReadURL, poolOpts...)
+			if err != nil {
+				pool.Close()
+				return nil, nil, 0, fmt.Errorf("create read replica pool: %w", err)
+			}
+			st.SetReadReplica(replica, logger)
+			logger.Info().Msg("top scores and ranks read from the replica")
+		}
+		if cfg.AutoMigrate {
+			if err := store.Migrate(pool, logger); err != nil {
+				st.Close()
+				return nil, nil, 0, fmt.Errorf("migrate database: %w", err)
+			}
+			if err := st.ApplyValidation(ctx, storeValidation(cfg)); err != nil {
+				st.Close()
+				return nil, nil, 0, fmt.Errorf("apply validation constraints: %w", err)
+			}
+		}
+		return st, changeSource(cfg, pool, logger), int64(pool.Config().MaxConns), nil
+	}
+}
+
+// storeValidation returns the bounds of the database constraints, the same as the service checks
+func storeValidation(cfg *config.Config) store.Validation {
+	return store.Validation{
+		MinPlayerNameLength: int(cfg.MinPlayerNameLength),
+		MaxPlayerNameLength: int(cfg.MaxPlayerNameLength),
+		MaxScore:            cfg.MaxScore,
+	}
+}
+
+// changeSource returns the PostgreSQL change source selected by NOTIFY_MODE
+func changeSource(cfg *config.Config, pool *pgxpool.Pool, logger *zerolog.Logger) notify.Source {
+	if cfg.NotifyMode == notify.ModeOutbox {
+		logger.Info().Str("consumer", cfg.NotifyConsumer).Msg("score changes read from the outbox")
+		return notify.NewOutboxPoller(pool, logger, notify.OutboxConfig{
+			Consumer:   cfg.NotifyConsumer,
+			Interval:   cfg.NotifyPollInterval,
+			Retention:  cfg.NotifyOutboxRetention,
+			GapTimeout: cfg.NotifyGapTimeout,
+		})
+	}
+	return notify.NewListener(pool, logger, cfg.NotifyOutboxRetention)
+}
+
+// broadcastBus wraps the change source in a broadcast bus relay when BUS_URL is set:
+// a publisher shares the changes it reads from the database with the other replicas,
+// a subscriber reads them from the bus instead of the database. Every replica
+// publishes its lifecycle events on the bus.
+func broadcastBus(cfg *config.Config, source notify.Source, lifecycleEvents *lifecycle.Bus, logger *zerolog.Logger) (notify.Source, func(), error) {
+	if cfg.BusURL == "" {
+		return source, func() {}, nil
+	}
+	b, err := bus.NewRedis(cfg.BusURL, cfg.BusChannel, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open broadcast bus: %w", err)
+	}
+	closeBus := func() {
+		if err := b.Close(); err != nil {
+			logger.Error().Err(err).Msg("error closing broadcast bus")
+		}
+	}
+
+	go b.RunLifecycle(lifecycleEvents.Subscribe(), cfg.BusLifecycleChannel, nil)
+
+	origin, _ := os.Hostname()
+	logger.Info().Str("channel", cfg.BusChannel).Str("role", cfg.BusRole).Msg("score changes shared on the broadcast bus")
+	if cfg.BusRole == notify.RoleSubscriber {
+		return notify.NewSubscriber(b, origin, logger), closeBus, nil
+	}
+	return notify.NewPublisher(source, b, origin, logger), closeBus, nil
+}
+
+// eventReplayer wraps the change source so admins can replay outbox events through
+// it; nil when the backend keeps no outbox (SQLite deletes changes once delivered)
+func eventReplayer(st store.Repository, source notify.Source, logger *zerolog.Logger) (service.EventReplayer, notify.Source) {
+	pg, ok := st.(*store.Store)
+	if !ok {
+		return nil, source
+	}
+	replayer := notify.NewReplayer(pg.Pool(), source, logger)
+	return replayer, replayer
+}
+
+// changeLog reads board changes from the outbox for polling clients; nil when the
+// backend keeps no outbox
+func changeLog(cfg *config.Config, st store.Repository) service.ChangeReader {
+	pg, ok := st.(*store.Store)
+	if !ok {
+		return nil
+	}
+	return notify.NewChangeLog(pg.Pool(), cfg.NotifyOutboxRetention)
+}
+
+// startWebhooks queues webhook events for the score changes of the dispatcher and
+// the lifecycle events of this replica, and delivers them; webhooks are stored in
+// PostgreSQL only. Bus subscribers queue no score events: their changes are queued
+// by the replica publishing them.
+func startWebhooks(ctx context.Context, cfg *config.Config, st store.Repository, dispatcher *notify.Dispatcher, lifecycleEvents *lifecycle.Bus, formats *events.Registry, logger *zerolog.Logger) error {
+	pg, ok := st.(*store.Store)
+	if !ok {
+		return nil
+	}
+	serializer, err := formats.Get(cfg.WebhookEventFormat)
+	if err != nil {
+		return fmt.Errorf("WEBHOOK_EVENT_FORMAT: %w", err)
+	}
+	notifier := webhook.NewNotifier(pg, serializer, logger)
+	if cfg.BusURL == "" || cfg.BusRole != notify.RoleSubscriber {
+		go notifier.Run(dispatcher.Subscribe())
+	}
+	go notifier.RunLifecycle(lifecycleEvents.Subscribe())
+	go webhook.NewDeliverer(pg, webhook.Config{
+		Timeout:     cfg.WebhookTimeout,
+		MaxAttempts: int(cfg.WebhookMaxAttempts),
+		RetryBase:   cfg.WebhookRetryBase,
+		RetryMax:    cfg.WebhookRetryMax,
+		Concurrency: int(cfg.WebhookConcurrency),
+		Retention:   cfg.WebhookDeliveryRetention,
+		ContentType: serializer.ContentType(),
+	}, logger).Run(ctx)
+	return nil
+}
+
+// geoResolver loads the GeoIP database, or returns nil when GEOIP_DB is unset
+func geoResolver(cfg *config.Config, logger *zerolog.Logger) (service.GeoResolver, error) {
+	if cfg.GeoIPDB == "" {
+		return nil, nil
+	}
+	db, err := geoip.Open(cfg.GeoIPDB)
+	if err != nil {
+		return nil, fmt.Errorf("load GEOIP_DB: %w", err)
+	}
+	logger.Info().Str("path", cfg.GeoIPDB).Int("ranges", db.Len()).Msg("score regions resolved from GeoIP database")
+	return db, nil
+}
+
+// identityResolver returns the external identity client, or nil when IDENTITY_URL is unset
+func identityResolver(cfg *config.Config, logger *zerolog.Logger) service.IdentityResolver {
+	if cfg.IdentityURL == "" {
+		return nil
+	}
+	logger.Info().Str("url", cfg.IdentityURL).Msg("player identities resolved by external service")
+	return identity.New(identity.Config{
+		URL:              cfg.IdentityURL,
+		Token:            cfg.IdentityToken,
+		Timeout:          cfg.IdentityTimeout,
+		CacheTTL:         cfg.IdentityCacheTTL,
+		FailureThreshold: int(cfg.IdentityFailureThreshold),
+		Cooldown:         cfg.IdentityCooldown,
+	}, logger)
+}
+
+// submitQueue returns the submission queue of async submit mode, zero in sync mode
+func submitQueue(cfg *config.Config) service.SubmitQueue {
+	if cfg.SubmitMode != "async" {
+		return service.SubmitQueue{}
+	}
+	return service.SubmitQueue{
+		Size:          int(cfg.SubmitQueueSize),
+		Workers:       int(cfg.SubmitQueueWorkers),
+		BatchSize:     int(cfg.SubmitQueueBatchSize),
+		FlushInterval: cfg.SubmitQueueFlushInterval,
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/config"
+	"github.com/yourorg/leaderboard/internal/integrations"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/log"
+	"github.com/yourorg/leaderboard/internal/service"
+	grpcTransport "github.com/yourorg/leaderboard/internal/transport/grpc"
+	restTransport "github.com/yourorg/leaderboard/internal/transport/rest"
+)
+
+// reloadable are the settings a SIGHUP applies to the running server; changes
+// of the others are reported and wait for a restart
+var reloadable = map[string]bool{
+	"log_level":                       true,
+	"default_limit":                   true,
+	"max_limit":                       true,
+	"device_limit_mode":               true,
+	"device_max_accounts":             true,
+	"device_max_submissions_per_hour": true,
+	"chat_webhook_url":                true,
+	"chat_platform":                   true,
+}
+
+// reloader applies the configuration again on SIGHUP, without closing listeners
+// or streams
+type reloader struct {
+	cfg       *config.Config // configuration in force
+	logger    *zerolog.Logger
+	events    *lifecycle.Bus
+	svc       *service.Service
+	grpc      *grpcTransport.Server
+	rest      *restTransport.Server
+	announcer *integrations.Announcer // nil when chat announcements are disabled
+}
+
+// run reloads the configuration on every SIGHUP until ctx is done
+func (r *reloader) run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reload()
+		}
+	}
+}
+
+// reload loads the configuration and applies its reloadable changes. An invalid
+// configuration is rejected as a whole and the current one stays in force.
+func (r *reloader) reload() {
+	cfg, err := config.Load()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("configuration reload failed, keeping the current configuration")
+		return
+	}
+
+	var applied, pending []string
+	for _, key := range r.cfg.Changed(cfg) {
+		switch {
+		case !reloadable[key]:
+			pending = append(pending, key)
+		case strings.HasPrefix(key, "chat_") && (r.announcer == nil || cfg.ChatWebhookURL == ""):
+			// Starting or stopping the announcer needs a restart
+			pending = append(pending, key)
+		default:
+			applied = append(applied, key)
+		}
+	}
+	if len(pending) > 0 {
+		r.logger.Warn().Strs("settings", pending).Msg("changed settings need a restart to apply")
+	}
+	if len(applied) == 0 {
+		r.logger.Info().Msg("configuration reloaded, nothing to apply")
+		return
+	}
+
+	log.SetLevel(cfg.LogLevel)
+	r.grpc.SetPageLimits(cfg.DefaultLimit, cfg.MaxLimit)
+	r.rest.SetPageLimits(cfg.DefaultLimit, cfg.MaxLimit)
+	r.svc.SetDeviceLimits(service.DeviceLimits{
+		Mode:                  cfg.DeviceLimitMode,
+		MaxAccounts:           cfg.DeviceMaxAccounts,
+		MaxSubmissionsPerHour: cfg.DeviceMaxSubmissionsPerHour,
+	})
+	if r.announcer != nil && cfg.ChatWebhookURL != "" {
+		r.announcer.SetWebhook(cfg.ChatWebhookURL, cfg.ChatPlatform)
+	}
+
+	// Settings waiting for a restart keep their current value, so they are
+	// reported again by the next reload
+	for _, key := range applied {
+		r.cfg.CopySetting(key, cfg)
+	}
+	r.logger.Info().Strs("settings", applied).Msg("🔄 configuration reloaded")
+	r.events.Publish(lifecycle.ConfigReloaded, map[string]string{"settings": strings.Join(applied, ",")})
+}
@@ -0,0 +1,247 @@
+// Command verify-ranks recomputes the leaderboard from the database with an
+// independent algorithm and compares it with what the gRPC API serves
+// (GetTopScores, including the in-memory top cache, and GetPlayerRank).
+//
+// It exits with status 1 when discrepancies are found. Run it while writes are
+// paused (e.g. after a tournament closes): scores changing mid-run show up as
+// discrepancies.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/config"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// options holds the command-line configuration
+type options struct {
+	addr        string
+	driver      string
+	databaseURL string
+	sqlitePath  string
+	board       string
+	top         int
+	pageSize    int
+	ranks       int
+	concurrency int
+	maxReport   int
+	timeout     time.Duration
+}
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: load config: %v\n", err)
+		os.Exit(2)
+	}
+
+	var opts options
+	flag.StringVar(&opts.addr, "addr", "localhost:50051", "gRPC server address")
+	flag.StringVar(&opts.driver, "driver", cfg.DBDriver, "storage backend: postgres or sqlite (default $DB_DRIVER)")
+	flag.StringVar(&opts.databaseURL, "db", cfg.DatabaseURL, "PostgreSQL connection string (default $DATABASE_URL)")
+	flag.StringVar(&opts.sqlitePath, "sqlite-path", cfg.SQLitePath, "SQLite database file (default $SQLITE_PATH)")
+	flag.StringVar(&opts.board, "board", store.DefaultLeaderboardID, "leaderboard to verify")
+	flag.IntVar(&opts.top, "top", 1000, "number of top positions to compare with GetTopScores (0 = all)")
+	flag.IntVar(&opts.pageSize, "page", int(cfg.MaxLimit), "GetTopScores page size (default $MAX_LIMIT)")
+	flag.IntVar(&opts.ranks, "ranks", 0, "number of best-ranked players to check with GetPlayerRank (0 = all)")
+	flag.IntVar(&opts.concurrency, "concurrency", 8, "concurrent GetPlayerRank calls")
+	flag.IntVar(&opts.maxReport, "max-report", 50, "maximum discrepancies printed")
+	flag.DurationVar(&opts.timeout, "timeout", 5*time.Minute, "overall timeout")
+	flag.Parse()
+
+	ok, err := run(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// run performs the verification and reports whether no discrepancy was found
+func run(opts options) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	fmt.Printf("Loading scores of %s from %s...\n", opts.board, opts.driver)
+	expected, ascending, err := loadScores(ctx, opts)
+	if err != nil {
+		return false, fmt.Errorf("load scores: %w", err)
+	}
+	rankEntries(expected, ascending)
+	order := "desc"
+	if ascending {
+		order = "asc"
+	}
+	fmt.Printf("Recomputed ranking of %d players (%s)\n", len(expected), order)
+
+	conn, err := grpc.NewClient(opts.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return false, fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+	client := pb.NewLeaderboardServiceClient(conn)
+
+	top := opts.top
+	if top <= 0 || top > len(expected) {
+		top = len(expected)
+	}
+	topIssues, err := verifyTop(ctx, client, opts.board, expected, top, opts.pageSize)
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("Checked top %d positions (GetTopScores): %d discrepancies\n", top, len(topIssues))
+
+	ranks := opts.ranks
+	if ranks <= 0 || ranks > len(expected) {
+		ranks = len(expected)
+	}
+	rankIssues, err := verifyRanks(ctx, client, opts.board, expected[:ranks], opts.concurrency)
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("Checked %d player ranks (GetPlayerRank): %d discrepancies\n", ranks, len(rankIssues))
+
+	issues := append(topIssues, rankIssues...)
+	if len(issues) == 0 {
+		fmt.Println("✅ Ranks verified: API matches the recomputed leaderboard")
+		return true, nil
+	}
+
+	fmt.Printf("\n❌ %d discrepancies found\n", len(issues))
+	for i, d := range issues {
+		if i == opts.maxReport {
+			fmt.Printf("... %d more\n", len(issues)-i)
+			break
+		}
+		fmt.Println(d)
+	}
+	return false, nil
+}
+
+// loadScores reads every score row without relying on the server's ordering
+// queries, along with whether the board ranks lower scores first
+func loadScores(ctx context.Context, opts options) ([]entry, bool, error) {
+	switch opts.driver {
+	case config.DBDriverSQLite:
+		st, err := sqlite.Open(ctx, opts.sqlitePath)
+		if err != nil {
+			return nil, false, err
+		}
+		defer st.Close()
+
+		var ascending bool
+		err = st.DB().QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM leaderboards WHERE leaderboard_id = ? AND sort_order = 'asc')`, opts.board).Scan(&ascending)
+		if err != nil {
+			return nil, false, err
+		}
+
+		rows, err := st.DB().QueryContext(ctx, `SELECT player_name, score, achieved_at FROM scores WHERE leaderboard_id = ?`, opts.board)
+		if err != nil {
+			return nil, false, err
+		}
+		defer rows.Close()
+
+		var entries []entry
+		for rows.Next() {
+			var e entry
+			var achievedAt int64
+			if err := rows.Scan(&e.PlayerName, &e.Score, &achievedAt); err != nil {
+				return nil, false, err
+			}
+			e.AchievedAt = time.UnixMicro(achievedAt)
+			entries = append(entries, e)
+		}
+		return entries, ascending, rows.Err()
+
+	case config.DBDriverPostgres:
+		conn, err := pgx.Connect(ctx, opts.databaseURL)
+		if err != nil {
+			return nil, false, err
+		}
+		defer conn.Close(ctx)
+
+		var ascending bool
+		err = conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM leaderboards WHERE leaderboard_id = $1 AND sort_order = 'asc')`, opts.board).Scan(&ascending)
+		if err != nil {
+			return nil, false, err
+		}
+
+		rows, err := conn.Query(ctx, `SELECT player_name, score, achieved_at FROM scores WHERE leaderboard_id = $1`, opts.board)
+		if err != nil {
+			return nil, false, err
+		}
+		entries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (entry, error) {
+			var e entry
+			err := row.Scan(&e.PlayerName, &e.Score, &e.AchievedAt)
+			return e, err
+		})
+		return entries, ascending, err
+
+	default:
+		return nil, false, fmt.Errorf("unknown driver %q", opts.driver)
+	}
+}
+
+// verifyTop pages through GetTopScores and compares every served position
+func verifyTop(ctx context.Context, client pb.LeaderboardServiceClient, board string, expected []entry, top, pageSize int) ([]discrepancy, error) {
+	var issues []discrepancy
+	offset := 0
+	for offset < top {
+		limit := min(pageSize, top-offset)
+		resp, err := client.GetTopScores(ctx, &pb.GetTopScoresRequest{LeaderboardId: board, Limit: int32(limit), Offset: int32(offset)})
+		if err != nil {
+			return nil, fmt.Errorf("get top scores at offset %d: %w", offset, err)
+		}
+		if len(resp.Entries) == 0 {
+			for _, e := range expected[offset:top] {
+				issues = append(issues, discrepancy{kindMissing, e.PlayerName, "not served by GetTopScores"})
+			}
+			break
+		}
+		// The server may clamp the page to its MAX_LIMIT: advance by what was served
+		served := resp.Entries[:min(len(resp.Entries), top-offset)]
+		issues = append(issues, compareTopPage(expected, offset, served)...)
+		offset += len(served)
+	}
+	return issues, nil
+}
+
+// verifyRanks compares GetPlayerRank with the recomputed rank of each player
+func verifyRanks(ctx context.Context, client pb.LeaderboardServiceClient, board string, expected []entry, concurrency int) ([]discrepancy, error) {
+	found := make([][]discrepancy, len(expected)) // per player, so the report stays in rank order
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(concurrency, 1))
+	for i, e := range expected {
+		g.Go(func() error {
+			resp, err := client.GetPlayerRank(ctx, &pb.GetPlayerRankRequest{LeaderboardId: board, PlayerName: e.PlayerName})
+			if err != nil {
+				return fmt.Errorf("get rank of %s: %w", e.PlayerName, err)
+			}
+			found[i] = compareRank(e, int64(i+1), resp)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var issues []discrepancy
+	for _, f := range found {
+		issues = append(issues, f...)
+	}
+	return issues, nil
+}
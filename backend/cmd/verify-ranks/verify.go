@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+)
+
+// entry is a score row as read straight from the database
+type entry struct {
+	PlayerName string
+	Score      int64
+	AchievedAt time.Time
+}
+
+// Discrepancy kinds
+const (
+	kindOrder   = "ORDER"   // GetTopScores serves a different player at a position
+	kindMissing = "MISSING" // a player or position the API does not serve
+	kindRank    = "RANK"    // GetPlayerRank disagrees with the recomputed rank
+	kindScore   = "SCORE"   // the API serves a different score than the database holds
+)
+
+// discrepancy is a difference between recomputed and served rankings
+type discrepancy struct {
+	Kind   string
+	Player string
+	Detail string
+}
+
+func (d discrepancy) String() string {
+	return fmt.Sprintf("%-7s %-20s %s", d.Kind, d.Player, d.Detail)
+}
+
+// rankEntries sorts entries into leaderboard order in place.
+// This is deliberately a plain sort rather than the COUNT(*)-based SQL the
+// server uses, so both implementations have to agree for verification to pass.
+// Ascending boards rank the lowest score first; the server's rank_score column
+// is not consulted.
+func rankEntries(entries []entry, ascending bool) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Score != b.Score {
+			return (a.Score > b.Score) != ascending
+		}
+		if !a.AchievedAt.Equal(b.AchievedAt) {
+			return a.AchievedAt.Before(b.AchievedAt)
+		}
+		return a.PlayerName < b.PlayerName
+	})
+}
+
+// compareTopPage checks a GetTopScores page served at offset against the recomputed order
+func compareTopPage(expected []entry, offset int, served []*pb.ScoreEntry) []discrepancy {
+	var out []discrepancy
+	for i, got := range served {
+		pos := offset + i
+		if pos >= len(expected) {
+			out = append(out, discrepancy{kindOrder, got.PlayerName,
+				fmt.Sprintf("served at position %d, but only %d players exist", pos+1, len(expected))})
+			continue
+		}
+		want := expected[pos]
+		if got.PlayerName != want.PlayerName {
+			out = append(out, discrepancy{kindOrder, want.PlayerName,
+				fmt.Sprintf("position %d: served %s (%d), want %s (%d)", pos+1, got.PlayerName, got.Score, want.PlayerName, want.Score)})
+			continue
+		}
+		if got.Score != want.Score {
+			out = append(out, discrepancy{kindScore, want.PlayerName,
+				fmt.Sprintf("position %d: served score %d, database has %d", pos+1, got.Score, want.Score)})
+		}
+	}
+	return out
+}
+
+// compareRank checks a GetPlayerRank response against the recomputed rank (1-based)
+func compareRank(want entry, wantRank int64, resp *pb.GetPlayerRankResponse) []discrepancy {
+	if resp.NotFound {
+		return []discrepancy{{kindMissing, want.PlayerName, fmt.Sprintf("rank %d in database, API reports not found", wantRank)}}
+	}
+	var out []discrepancy
+	if resp.Rank != wantRank {
+		out = append(out, discrepancy{kindRank, want.PlayerName, fmt.Sprintf("served rank %d, want %d", resp.Rank, wantRank)})
+	}
+	if resp.Entry.GetScore() != want.Score {
+		out = append(out, discrepancy{kindScore, want.PlayerName, fmt.Sprintf("served score %d, database has %d", resp.Entry.GetScore(), want.Score)})
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+)
+
+func TestRankEntries(t *testing.T) {
+	t0 := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	entries := []entry{
+		{PlayerName: "Dave", Score: 100, AchievedAt: t0},
+		{PlayerName: "Carol", Score: 200, AchievedAt: t0},
+		{PlayerName: "Bob", Score: 200, AchievedAt: t0},
+		{PlayerName: "Erin", Score: 200, AchievedAt: t0.Add(-time.Minute)},
+		{PlayerName: "Alice", Score: 300, AchievedAt: t0.Add(time.Hour)},
+	}
+	rankEntries(entries, false)
+
+	want := []string{"Alice", "Erin", "Bob", "Carol", "Dave"}
+	for i, e := range entries {
+		if e.PlayerName != want[i] {
+			t.Errorf("position %d = %s, want %s", i+1, e.PlayerName, want[i])
+		}
+	}
+
+	// Ascending: lowest score first, ties still broken by earliest achievement then name
+	rankEntries(entries, true)
+	want = []string{"Dave", "Erin", "Bob", "Carol", "Alice"}
+	for i, e := range entries {
+		if e.PlayerName != want[i] {
+			t.Errorf("ascending position %d = %s, want %s", i+1, e.PlayerName, want[i])
+		}
+	}
+}
+
+func TestCompareTopPage(t *testing.T) {
+	expected := []entry{
+		{PlayerName: "Alice", Score: 300},
+		{PlayerName: "Bob", Score: 200},
+		{PlayerName: "Carol", Score: 100},
+	}
+
+	tests := []struct {
+		name      string
+		offset    int
+		served    []*pb.ScoreEntry
+		wantKinds []string
+	}{
+		{
+			name:   "match",
+			served: []*pb.ScoreEntry{{PlayerName: "Alice", Score: 300}, {PlayerName: "Bob", Score: 200}},
+		},
+		{
+			name:      "swapped",
+			served:    []*pb.ScoreEntry{{PlayerName: "Bob", Score: 200}, {PlayerName: "Alice", Score: 300}},
+			wantKinds: []string{kindOrder, kindOrder},
+		},
+		{
+			name:      "stale score",
+			offset:    1,
+			served:    []*pb.ScoreEntry{{PlayerName: "Bob", Score: 150}},
+			wantKinds: []string{kindScore},
+		},
+		{
+			name:      "extra entry",
+			offset:    2,
+			served:    []*pb.ScoreEntry{{PlayerName: "Carol", Score: 100}, {PlayerName: "Ghost", Score: 50}},
+			wantKinds: []string{kindOrder},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareTopPage(expected, tt.offset, tt.served)
+			if len(got) != len(tt.wantKinds) {
+				t.Fatalf("got %d discrepancies %v, want %d", len(got), got, len(tt.wantKinds))
+			}
+			for i, d := range got {
+				if d.Kind != tt.wantKinds[i] {
+					t.Errorf("discrepancy %d kind = %s, want %s", i, d.Kind, tt.wantKinds[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompareRank(t *testing.T) {
+	want := entry{PlayerName: "Bob", Score: 200}
+
+	if got := compareRank(want, 2, &pb.GetPlayerRankResponse{Rank: 2, Entry: &pb.ScoreEntry{Score: 200}}); len(got) != 0 {
+		t.Errorf("matching rank: got %v, want none", got)
+	}
+	if got := compareRank(want, 2, &pb.GetPlayerRankResponse{Rank: 3, Entry: &pb.ScoreEntry{Score: 200}}); len(got) != 1 || got[0].Kind != kindRank {
+		t.Errorf("wrong rank: got %v, want one %s", got, kindRank)
+	}
+	if got := compareRank(want, 2, &pb.GetPlayerRankResponse{NotFound: true}); len(got) != 1 || got[0].Kind != kindMissing {
+		t.Errorf("not found: got %v, want one %s", got, kindMissing)
+	}
+}
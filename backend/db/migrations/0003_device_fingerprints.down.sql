@@ -0,0 +1,6 @@
+-- Drop the index
+DROP INDEX IF EXISTS idx_device_submissions_recent;
+
+-- Drop the tables
+DROP TABLE IF EXISTS device_submissions;
+DROP TABLE IF EXISTS device_players;
@@ -0,0 +1,22 @@
+-- Device fingerprints reported by game clients on score submission.
+-- The fingerprint is an opaque hash computed client-side; the server never sees raw device data.
+
+-- Tracks which player accounts have been seen on each device
+CREATE TABLE device_players (
+    device_hash TEXT NOT NULL,
+    player_name TEXT NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (device_hash, player_name),
+    CONSTRAINT device_hash_length CHECK (char_length(device_hash) <= 128 AND char_length(device_hash) > 0)
+);
+
+-- Log of submissions per device, used for per-device hourly rate limits
+CREATE TABLE device_submissions (
+    id BIGSERIAL PRIMARY KEY,
+    device_hash TEXT NOT NULL,
+    player_name TEXT NOT NULL,
+    submitted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Supports counting recent submissions for a device
+CREATE INDEX idx_device_submissions_recent ON device_submissions (device_hash, submitted_at DESC);
@@ -0,0 +1,56 @@
+-- Restore the notify function from 0002 (payload without achieved_at)
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    payload JSON;
+    operation TEXT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        operation := 'delete';
+        payload := json_build_object(
+            'player_name', OLD.player_name,
+            'score', OLD.score,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN OLD;
+    ELSIF TG_OP = 'INSERT' THEN
+        operation := 'insert';
+        payload := json_build_object(
+            'player_name', NEW.player_name,
+            'score', NEW.score,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        IF NEW.score <> OLD.score THEN
+            operation := 'update';
+            payload := json_build_object(
+                'player_name', NEW.player_name,
+                'score', NEW.score,
+                'op', operation
+            );
+            PERFORM pg_notify('scores_changes', payload::text);
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Restore the comment
+COMMENT ON FUNCTION notify_score_change() IS
+'Sends notifications on channel scores_changes with JSON payload: {"player_name":"...", "score":12345, "op":"insert|update|delete"}. Notifies on any score change (increase or decrease).';
+
+-- Restore the original leaderboard index
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX idx_scores_leaderboard ON scores (score DESC, player_name);
+
+-- Drop the timestamp columns
+ALTER TABLE scores
+    DROP COLUMN IF EXISTS client_achieved_at,
+    DROP COLUMN IF EXISTS achieved_at;
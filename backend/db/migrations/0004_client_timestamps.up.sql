@@ -0,0 +1,69 @@
+-- Client-reported completion timestamps for score submissions.
+-- achieved_at is the time the best score was achieved: the client timestamp when it
+-- passed the server's skew checks, the server time otherwise. It breaks ties between
+-- equal scores (earlier wins) and places offline runs in the right time window.
+-- client_achieved_at keeps the raw client value (NULL when none was sent) for auditing.
+ALTER TABLE scores
+    ADD COLUMN achieved_at TIMESTAMPTZ,
+    ADD COLUMN client_achieved_at TIMESTAMPTZ;
+
+-- Existing rows were achieved at their last update
+UPDATE scores SET achieved_at = updated_at;
+
+ALTER TABLE scores
+    ALTER COLUMN achieved_at SET NOT NULL,
+    ALTER COLUMN achieved_at SET DEFAULT now();
+
+-- Replace the leaderboard index to match the new tie-breaking order
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX idx_scores_leaderboard ON scores (score DESC, achieved_at ASC, player_name);
+
+-- Include achieved_at in notifications so in-memory views can order ties
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    payload JSON;
+    operation TEXT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        operation := 'delete';
+        payload := json_build_object(
+            'player_name', OLD.player_name,
+            'score', OLD.score,
+            'achieved_at', OLD.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN OLD;
+    ELSIF TG_OP = 'INSERT' THEN
+        operation := 'insert';
+        payload := json_build_object(
+            'player_name', NEW.player_name,
+            'score', NEW.score,
+            'achieved_at', NEW.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        IF NEW.score <> OLD.score THEN
+            operation := 'update';
+            payload := json_build_object(
+                'player_name', NEW.player_name,
+                'score', NEW.score,
+                'achieved_at', NEW.achieved_at,
+                'op', operation
+            );
+            PERFORM pg_notify('scores_changes', payload::text);
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Sends notifications on channel scores_changes with JSON payload: {"player_name":"...", "score":12345, "achieved_at":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease).';
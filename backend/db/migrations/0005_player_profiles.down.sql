@@ -0,0 +1,2 @@
+-- Drop the player profiles table
+DROP TABLE IF EXISTS players;
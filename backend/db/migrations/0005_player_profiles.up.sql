@@ -0,0 +1,16 @@
+-- Player profiles: optional presentation metadata shown next to scores in game UIs.
+-- Profiles are keyed by player_name but independent of scores: a profile may be
+-- created before the first score and survives score deletion.
+CREATE TABLE players (
+    player_name TEXT PRIMARY KEY,
+    display_name TEXT NOT NULL DEFAULT '',
+    -- ISO 3166-1 alpha-2 code (uppercase), empty when unknown
+    country_code TEXT NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT players_player_name_length CHECK (char_length(player_name) <= 20 AND char_length(player_name) > 0),
+    CONSTRAINT display_name_length CHECK (char_length(display_name) <= 32),
+    CONSTRAINT country_code_format CHECK (country_code = '' OR country_code ~ '^[A-Z]{2}$'),
+    CONSTRAINT avatar_url_length CHECK (char_length(avatar_url) <= 512)
+);
@@ -0,0 +1,62 @@
+-- Restore the notify function from 0004 (payload without leaderboard_id)
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    payload JSON;
+    operation TEXT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        operation := 'delete';
+        payload := json_build_object(
+            'player_name', OLD.player_name,
+            'score', OLD.score,
+            'achieved_at', OLD.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN OLD;
+    ELSIF TG_OP = 'INSERT' THEN
+        operation := 'insert';
+        payload := json_build_object(
+            'player_name', NEW.player_name,
+            'score', NEW.score,
+            'achieved_at', NEW.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        IF NEW.score <> OLD.score THEN
+            operation := 'update';
+            payload := json_build_object(
+                'player_name', NEW.player_name,
+                'score', NEW.score,
+                'achieved_at', NEW.achieved_at,
+                'op', operation
+            );
+            PERFORM pg_notify('scores_changes', payload::text);
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Sends notifications on channel scores_changes with JSON payload: {"player_name":"...", "score":12345, "achieved_at":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease).';
+
+-- Only the global board fits the single-board schema
+DELETE FROM scores WHERE leaderboard_id <> 'global';
+
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX idx_scores_leaderboard ON scores (score DESC, achieved_at ASC, player_name);
+
+ALTER TABLE scores DROP CONSTRAINT scores_pkey;
+ALTER TABLE scores ADD PRIMARY KEY (player_name);
+
+ALTER TABLE scores
+    DROP CONSTRAINT IF EXISTS leaderboard_id_format,
+    DROP COLUMN IF EXISTS leaderboard_id;
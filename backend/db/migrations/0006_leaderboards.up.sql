@@ -0,0 +1,69 @@
+-- Multiple leaderboards (e.g. one per game level) in the scores table.
+-- Every score belongs to a board; existing rows move to the default 'global' board.
+-- The primary key becomes (leaderboard_id, player_name): a player has one best score
+-- per board, and board-scoped lookups use the leading key column.
+ALTER TABLE scores
+    ADD COLUMN leaderboard_id TEXT NOT NULL DEFAULT 'global',
+    ADD CONSTRAINT leaderboard_id_format CHECK (leaderboard_id ~ '^[A-Za-z0-9_.:-]{1,64}$');
+
+ALTER TABLE scores DROP CONSTRAINT scores_pkey;
+ALTER TABLE scores ADD PRIMARY KEY (leaderboard_id, player_name);
+
+-- Board first, so every ranking query reads a contiguous range of a single board
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX idx_scores_leaderboard ON scores (leaderboard_id, score DESC, achieved_at ASC, player_name);
+
+-- One channel for all boards: the payload carries leaderboard_id and the server
+-- routes changes to the subscribers of that board. A channel per board would need
+-- one LISTEN per board on the listener connection.
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    payload JSON;
+    operation TEXT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        operation := 'delete';
+        payload := json_build_object(
+            'leaderboard_id', OLD.leaderboard_id,
+            'player_name', OLD.player_name,
+            'score', OLD.score,
+            'achieved_at', OLD.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN OLD;
+    ELSIF TG_OP = 'INSERT' THEN
+        operation := 'insert';
+        payload := json_build_object(
+            'leaderboard_id', NEW.leaderboard_id,
+            'player_name', NEW.player_name,
+            'score', NEW.score,
+            'achieved_at', NEW.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        IF NEW.score <> OLD.score THEN
+            operation := 'update';
+            payload := json_build_object(
+                'leaderboard_id', NEW.leaderboard_id,
+                'player_name', NEW.player_name,
+                'score', NEW.score,
+                'achieved_at', NEW.achieved_at,
+                'op', operation
+            );
+            PERFORM pg_notify('scores_changes', payload::text);
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Sends notifications on channel scores_changes with JSON payload: {"leaderboard_id":"...", "player_name":"...", "score":12345, "achieved_at":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease).';
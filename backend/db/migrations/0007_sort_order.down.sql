@@ -0,0 +1,66 @@
+-- Restore the notify function from 0006 (payload without rank_score)
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    payload JSON;
+    operation TEXT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        operation := 'delete';
+        payload := json_build_object(
+            'leaderboard_id', OLD.leaderboard_id,
+            'player_name', OLD.player_name,
+            'score', OLD.score,
+            'achieved_at', OLD.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN OLD;
+    ELSIF TG_OP = 'INSERT' THEN
+        operation := 'insert';
+        payload := json_build_object(
+            'leaderboard_id', NEW.leaderboard_id,
+            'player_name', NEW.player_name,
+            'score', NEW.score,
+            'achieved_at', NEW.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        IF NEW.score <> OLD.score THEN
+            operation := 'update';
+            payload := json_build_object(
+                'leaderboard_id', NEW.leaderboard_id,
+                'player_name', NEW.player_name,
+                'score', NEW.score,
+                'achieved_at', NEW.achieved_at,
+                'op', operation
+            );
+            PERFORM pg_notify('scores_changes', payload::text);
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Sends notifications on channel scores_changes with JSON payload: {"leaderboard_id":"...", "player_name":"...", "score":12345, "achieved_at":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease).';
+
+DROP TRIGGER IF EXISTS scores_rank_score_trigger ON scores;
+DROP FUNCTION IF EXISTS set_rank_score();
+
+-- Ascending boards cannot be represented without rank_score
+DELETE FROM scores
+WHERE leaderboard_id IN (SELECT leaderboard_id FROM leaderboards WHERE sort_order = 'asc');
+
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX idx_scores_leaderboard ON scores (leaderboard_id, score DESC, achieved_at ASC, player_name);
+
+ALTER TABLE scores DROP COLUMN IF EXISTS rank_score;
+
+DROP TABLE IF EXISTS leaderboards;
@@ -0,0 +1,97 @@
+-- Leaderboard definitions: per-board settings, starting with the sort order.
+-- Boards without a row are implicit and rank higher scores first ('desc'), so
+-- existing boards keep their behavior. 'asc' boards rank lower scores first,
+-- e.g. lap times in racing games.
+CREATE TABLE leaderboards (
+    leaderboard_id TEXT PRIMARY KEY,
+    sort_order TEXT NOT NULL DEFAULT 'desc',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT leaderboards_leaderboard_id_format CHECK (leaderboard_id ~ '^[A-Za-z0-9_.:-]{1,64}$'),
+    CONSTRAINT sort_order_valid CHECK (sort_order IN ('desc', 'asc'))
+);
+
+-- rank_score is the score in ranking space: equal to score on 'desc' boards and
+-- negated on 'asc' boards, so that higher is always better. Ranking queries, the
+-- best-score upsert and the index all use it and work the same for both orders.
+ALTER TABLE scores ADD COLUMN rank_score BIGINT;
+UPDATE scores SET rank_score = score;
+ALTER TABLE scores ALTER COLUMN rank_score SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX idx_scores_leaderboard ON scores (leaderboard_id, rank_score DESC, achieved_at ASC, player_name);
+
+-- Writes that only set score (manual corrections, scripts) must not leave rank_score
+-- stale: derive it from the board's order on every insert and score update
+CREATE OR REPLACE FUNCTION set_rank_score()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM leaderboards l WHERE l.leaderboard_id = NEW.leaderboard_id AND l.sort_order = 'asc') THEN
+        NEW.rank_score := -NEW.score;
+    ELSE
+        NEW.rank_score := NEW.score;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER scores_rank_score_trigger
+BEFORE INSERT OR UPDATE OF score, leaderboard_id ON scores
+FOR EACH ROW
+EXECUTE FUNCTION set_rank_score();
+
+-- Consumers order changes by rank_score, so the payload carries it
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    payload JSON;
+    operation TEXT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        operation := 'delete';
+        payload := json_build_object(
+            'leaderboard_id', OLD.leaderboard_id,
+            'player_name', OLD.player_name,
+            'score', OLD.score,
+            'rank_score', OLD.rank_score,
+            'achieved_at', OLD.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN OLD;
+    ELSIF TG_OP = 'INSERT' THEN
+        operation := 'insert';
+        payload := json_build_object(
+            'leaderboard_id', NEW.leaderboard_id,
+            'player_name', NEW.player_name,
+            'score', NEW.score,
+            'rank_score', NEW.rank_score,
+            'achieved_at', NEW.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        IF NEW.score <> OLD.score THEN
+            operation := 'update';
+            payload := json_build_object(
+                'leaderboard_id', NEW.leaderboard_id,
+                'player_name', NEW.player_name,
+                'score', NEW.score,
+                'rank_score', NEW.rank_score,
+                'achieved_at', NEW.achieved_at,
+                'op', operation
+            );
+            PERFORM pg_notify('scores_changes', payload::text);
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Sends notifications on channel scores_changes with JSON payload: {"leaderboard_id":"...", "player_name":"...", "score":12345, "rank_score":12345, "achieved_at":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease).';
@@ -0,0 +1,57 @@
+-- Restore the notify function from 0007 (full row in the payload)
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    payload JSON;
+    operation TEXT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        operation := 'delete';
+        payload := json_build_object(
+            'leaderboard_id', OLD.leaderboard_id,
+            'player_name', OLD.player_name,
+            'score', OLD.score,
+            'rank_score', OLD.rank_score,
+            'achieved_at', OLD.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN OLD;
+    ELSIF TG_OP = 'INSERT' THEN
+        operation := 'insert';
+        payload := json_build_object(
+            'leaderboard_id', NEW.leaderboard_id,
+            'player_name', NEW.player_name,
+            'score', NEW.score,
+            'rank_score', NEW.rank_score,
+            'achieved_at', NEW.achieved_at,
+            'op', operation
+        );
+        PERFORM pg_notify('scores_changes', payload::text);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        IF NEW.score <> OLD.score THEN
+            operation := 'update';
+            payload := json_build_object(
+                'leaderboard_id', NEW.leaderboard_id,
+                'player_name', NEW.player_name,
+                'score', NEW.score,
+                'rank_score', NEW.rank_score,
+                'achieved_at', NEW.achieved_at,
+                'op', operation
+            );
+            PERFORM pg_notify('scores_changes', payload::text);
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Sends notifications on channel scores_changes with JSON payload: {"leaderboard_id":"...", "player_name":"...", "score":12345, "rank_score":12345, "achieved_at":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease).';
+
+DROP TABLE IF EXISTS score_changes;
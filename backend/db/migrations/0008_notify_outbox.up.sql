@@ -0,0 +1,58 @@
+-- NOTIFY payloads are limited to 8000 bytes and the trigger fails the write above that.
+-- Changes are now written to the score_changes outbox and the notification only
+-- carries the outbox id and the row keys; listeners read the full change by id.
+-- Deleted rows are only available here, so the outbox keeps a copy of every field.
+CREATE TABLE score_changes (
+    id BIGSERIAL PRIMARY KEY,
+    leaderboard_id TEXT NOT NULL,
+    player_name TEXT NOT NULL,
+    score BIGINT NOT NULL,
+    rank_score BIGINT NOT NULL,
+    achieved_at TIMESTAMPTZ NOT NULL,
+    op TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Every server listens, so rows are not consumed on read: they are pruned by age
+CREATE INDEX idx_score_changes_created_at ON score_changes (created_at);
+
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed scores%ROWTYPE;
+    operation TEXT;
+    change_id BIGINT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+        operation := 'delete';
+    ELSIF TG_OP = 'INSERT' THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.score <> OLD.score THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        changed := NEW;
+        operation := 'update';
+    ELSE
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, op)
+    VALUES (changed.leaderboard_id, changed.player_name, changed.score, changed.rank_score, changed.achieved_at, operation)
+    RETURNING id INTO change_id;
+
+    -- Bounded size: player_name and leaderboard_id are length-checked
+    PERFORM pg_notify('scores_changes', json_build_object(
+        'id', change_id,
+        'leaderboard_id', changed.leaderboard_id,
+        'player_name', changed.player_name,
+        'op', operation
+    )::text);
+
+    RETURN changed;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Appends every score change to score_changes and sends its id on channel scores_changes with JSON payload: {"id":42, "leaderboard_id":"...", "player_name":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease).';
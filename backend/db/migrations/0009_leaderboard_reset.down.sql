@@ -0,0 +1,49 @@
+DROP FUNCTION IF EXISTS notify_leaderboard_resync(TEXT);
+
+-- Resync rows have no score row behind them and older listeners do not know the op
+DELETE FROM score_changes WHERE op = 'resync';
+
+-- Restore the notify function from 0008 (no suppression)
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed scores%ROWTYPE;
+    operation TEXT;
+    change_id BIGINT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+        operation := 'delete';
+    ELSIF TG_OP = 'INSERT' THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.score <> OLD.score THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        changed := NEW;
+        operation := 'update';
+    ELSE
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, op)
+    VALUES (changed.leaderboard_id, changed.player_name, changed.score, changed.rank_score, changed.achieved_at, operation)
+    RETURNING id INTO change_id;
+
+    -- Bounded size: player_name and leaderboard_id are length-checked
+    PERFORM pg_notify('scores_changes', json_build_object(
+        'id', change_id,
+        'leaderboard_id', changed.leaderboard_id,
+        'player_name', changed.player_name,
+        'op', operation
+    )::text);
+
+    RETURN changed;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Appends every score change to score_changes and sends its id on channel scores_changes with JSON payload: {"id":42, "leaderboard_id":"...", "player_name":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease).';
+
+DROP TABLE IF EXISTS leaderboard_snapshot_entries;
+DROP TABLE IF EXISTS leaderboard_snapshots;
@@ -0,0 +1,86 @@
+-- Admin resets of a whole board, optionally snapshotted first.
+-- A snapshot is a copy of every score of a board at the time of the reset.
+CREATE TABLE leaderboard_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    leaderboard_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_leaderboard_snapshots_board ON leaderboard_snapshots (leaderboard_id, created_at DESC);
+
+CREATE TABLE leaderboard_snapshot_entries (
+    snapshot_id BIGINT NOT NULL REFERENCES leaderboard_snapshots (id) ON DELETE CASCADE,
+    player_name TEXT NOT NULL,
+    score BIGINT NOT NULL,
+    rank_score BIGINT NOT NULL,
+    achieved_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (snapshot_id, player_name)
+);
+
+-- A reset deletes every row of a board: one outbox row and NOTIFY per deleted
+-- row would flood the listeners. The reset transaction sets
+-- leaderboard.suppress_notify and sends a single board-scoped 'resync' instead.
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed scores%ROWTYPE;
+    operation TEXT;
+    change_id BIGINT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+        operation := 'delete';
+    ELSIF TG_OP = 'INSERT' THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.score <> OLD.score THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        changed := NEW;
+        operation := 'update';
+    ELSE
+        RETURN NEW;
+    END IF;
+
+    IF current_setting('leaderboard.suppress_notify', true) = 'on' THEN
+        RETURN changed;
+    END IF;
+
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, op)
+    VALUES (changed.leaderboard_id, changed.player_name, changed.score, changed.rank_score, changed.achieved_at, operation)
+    RETURNING id INTO change_id;
+
+    -- Bounded size: player_name and leaderboard_id are length-checked
+    PERFORM pg_notify('scores_changes', json_build_object(
+        'id', change_id,
+        'leaderboard_id', changed.leaderboard_id,
+        'player_name', changed.player_name,
+        'op', operation
+    )::text);
+
+    RETURN changed;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Appends every score change to score_changes and sends its id on channel scores_changes with JSON payload: {"id":42, "leaderboard_id":"...", "player_name":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease), unless leaderboard.suppress_notify is on.';
+
+-- Asks every listener to reload one board, e.g. after a reset
+CREATE OR REPLACE FUNCTION notify_leaderboard_resync(board TEXT)
+RETURNS VOID AS $$
+DECLARE
+    change_id BIGINT;
+BEGIN
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, op)
+    VALUES (board, '', 0, 0, now(), 'resync')
+    RETURNING id INTO change_id;
+
+    PERFORM pg_notify('scores_changes', json_build_object(
+        'id', change_id,
+        'leaderboard_id', board,
+        'player_name', '',
+        'op', 'resync'
+    )::text);
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS score_change_cursors;
@@ -0,0 +1,8 @@
+-- Read positions of outbox pollers (NOTIFY_MODE=outbox). Each server tails
+-- score_changes by id and records the last id it delivered, so a restarted server
+-- resumes where it stopped instead of losing the changes made while it was down.
+CREATE TABLE score_change_cursors (
+    consumer TEXT PRIMARY KEY,
+    last_id BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,55 @@
+-- Soft-deleted scores would come back as live ones: delete them for good while
+-- the notify function still knows listeners already saw them deleted
+DELETE FROM scores WHERE deleted_at IS NOT NULL;
+
+-- Restore the notify function from 0009
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed scores%ROWTYPE;
+    operation TEXT;
+    change_id BIGINT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+        operation := 'delete';
+    ELSIF TG_OP = 'INSERT' THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.score <> OLD.score THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        changed := NEW;
+        operation := 'update';
+    ELSE
+        RETURN NEW;
+    END IF;
+
+    IF current_setting('leaderboard.suppress_notify', true) = 'on' THEN
+        RETURN changed;
+    END IF;
+
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, op)
+    VALUES (changed.leaderboard_id, changed.player_name, changed.score, changed.rank_score, changed.achieved_at, operation)
+    RETURNING id INTO change_id;
+
+    -- Bounded size: player_name and leaderboard_id are length-checked
+    PERFORM pg_notify('scores_changes', json_build_object(
+        'id', change_id,
+        'leaderboard_id', changed.leaderboard_id,
+        'player_name', changed.player_name,
+        'op', operation
+    )::text);
+
+    RETURN changed;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Appends every score change to score_changes and sends its id on channel scores_changes with JSON payload: {"id":42, "leaderboard_id":"...", "player_name":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease), unless leaderboard.suppress_notify is on.';
+
+DROP INDEX IF EXISTS idx_scores_deleted;
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX idx_scores_leaderboard ON scores (leaderboard_id, rank_score DESC, achieved_at ASC, player_name);
+
+ALTER TABLE scores DROP COLUMN IF EXISTS deleted_at;
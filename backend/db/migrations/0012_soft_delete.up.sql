@@ -0,0 +1,71 @@
+-- Deleting a score only sets deleted_at, so an admin can restore it. Every query
+-- of the scores table skips soft-deleted rows, and a new submission of the player
+-- replaces a soft-deleted row as if it were a first score.
+ALTER TABLE scores ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Ranking only reads live rows
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX idx_scores_leaderboard ON scores (leaderboard_id, rank_score DESC, achieved_at ASC, player_name) WHERE deleted_at IS NULL;
+
+-- Admin listing of deleted scores, most recent first
+CREATE INDEX idx_scores_deleted ON scores (leaderboard_id, deleted_at DESC) WHERE deleted_at IS NOT NULL;
+
+-- Soft deletes and restores are what listeners knew as deletes and inserts: the
+-- operation follows deleted_at. Changes of deleted rows are not notified, and
+-- neither is the hard delete of a row that was already soft-deleted.
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed scores%ROWTYPE;
+    operation TEXT;
+    change_id BIGINT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN OLD;
+        END IF;
+        changed := OLD;
+        operation := 'delete';
+    ELSIF TG_OP = 'INSERT' THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.deleted_at IS NOT NULL THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NEW;
+        END IF;
+        changed := OLD;
+        operation := 'delete';
+    ELSIF OLD.deleted_at IS NOT NULL THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.score <> OLD.score THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        changed := NEW;
+        operation := 'update';
+    ELSE
+        RETURN NEW;
+    END IF;
+
+    IF current_setting('leaderboard.suppress_notify', true) = 'on' THEN
+        RETURN changed;
+    END IF;
+
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, op)
+    VALUES (changed.leaderboard_id, changed.player_name, changed.score, changed.rank_score, changed.achieved_at, operation)
+    RETURNING id INTO change_id;
+
+    -- Bounded size: player_name and leaderboard_id are length-checked
+    PERFORM pg_notify('scores_changes', json_build_object(
+        'id', change_id,
+        'leaderboard_id', changed.leaderboard_id,
+        'player_name', changed.player_name,
+        'op', operation
+    )::text);
+
+    RETURN changed;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Appends every score change to score_changes and sends its id on channel scores_changes with JSON payload: {"id":42, "leaderboard_id":"...", "player_name":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease), soft deletes as delete and restores as insert, unless leaderboard.suppress_notify is on.';
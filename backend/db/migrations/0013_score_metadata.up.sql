@@ -0,0 +1,63 @@
+-- Game-defined attributes of a score (level, character, replay id...): a flat
+-- JSON object of strings that follows the best score. Its size is bounded by the
+-- service. Changes carry the metadata of the score to listeners.
+ALTER TABLE scores ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE score_changes ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+
+-- Same as 0012, also copying the metadata to the outbox
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed scores%ROWTYPE;
+    operation TEXT;
+    change_id BIGINT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN OLD;
+        END IF;
+        changed := OLD;
+        operation := 'delete';
+    ELSIF TG_OP = 'INSERT' THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.deleted_at IS NOT NULL THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NEW;
+        END IF;
+        changed := OLD;
+        operation := 'delete';
+    ELSIF OLD.deleted_at IS NOT NULL THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.score <> OLD.score THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        changed := NEW;
+        operation := 'update';
+    ELSE
+        RETURN NEW;
+    END IF;
+
+    IF current_setting('leaderboard.suppress_notify', true) = 'on' THEN
+        RETURN changed;
+    END IF;
+
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, metadata, op)
+    VALUES (changed.leaderboard_id, changed.player_name, changed.score, changed.rank_score, changed.achieved_at, changed.metadata, operation)
+    RETURNING id INTO change_id;
+
+    -- Bounded size: player_name and leaderboard_id are length-checked
+    PERFORM pg_notify('scores_changes', json_build_object(
+        'id', change_id,
+        'leaderboard_id', changed.leaderboard_id,
+        'player_name', changed.player_name,
+        'op', operation
+    )::text);
+
+    RETURN changed;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Appends every score change to score_changes and sends its id on channel scores_changes with JSON payload: {"id":42, "leaderboard_id":"...", "player_name":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease), soft deletes as delete and restores as insert, unless leaderboard.suppress_notify is on.';
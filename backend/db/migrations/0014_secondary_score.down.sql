@@ -0,0 +1,84 @@
+-- Restore the rank trigger from 0007
+CREATE OR REPLACE FUNCTION set_rank_score()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM leaderboards l WHERE l.leaderboard_id = NEW.leaderboard_id AND l.sort_order = 'asc') THEN
+        NEW.rank_score := -NEW.score;
+    ELSE
+        NEW.rank_score := NEW.score;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS scores_rank_score_trigger ON scores;
+CREATE TRIGGER scores_rank_score_trigger
+BEFORE INSERT OR UPDATE OF score, leaderboard_id ON scores
+FOR EACH ROW
+EXECUTE FUNCTION set_rank_score();
+
+-- Restore the notify function from 0013
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed scores%ROWTYPE;
+    operation TEXT;
+    change_id BIGINT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN OLD;
+        END IF;
+        changed := OLD;
+        operation := 'delete';
+    ELSIF TG_OP = 'INSERT' THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.deleted_at IS NOT NULL THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NEW;
+        END IF;
+        changed := OLD;
+        operation := 'delete';
+    ELSIF OLD.deleted_at IS NOT NULL THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.score <> OLD.score THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        changed := NEW;
+        operation := 'update';
+    ELSE
+        RETURN NEW;
+    END IF;
+
+    IF current_setting('leaderboard.suppress_notify', true) = 'on' THEN
+        RETURN changed;
+    END IF;
+
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, metadata, op)
+    VALUES (changed.leaderboard_id, changed.player_name, changed.score, changed.rank_score, changed.achieved_at, changed.metadata, operation)
+    RETURNING id INTO change_id;
+
+    -- Bounded size: player_name and leaderboard_id are length-checked
+    PERFORM pg_notify('scores_changes', json_build_object(
+        'id', change_id,
+        'leaderboard_id', changed.leaderboard_id,
+        'player_name', changed.player_name,
+        'op', operation
+    )::text);
+
+    RETURN changed;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Appends every score change to score_changes and sends its id on channel scores_changes with JSON payload: {"id":42, "leaderboard_id":"...", "player_name":"...", "op":"insert|update|delete"}. Notifies on any score change (increase or decrease), soft deletes as delete and restores as insert, unless leaderboard.suppress_notify is on.';
+
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX idx_scores_leaderboard ON scores (leaderboard_id, rank_score DESC, achieved_at ASC, player_name) WHERE deleted_at IS NULL;
+
+ALTER TABLE leaderboard_snapshot_entries DROP COLUMN IF EXISTS rank_secondary, DROP COLUMN IF EXISTS secondary_score;
+ALTER TABLE score_changes DROP COLUMN IF EXISTS rank_secondary, DROP COLUMN IF EXISTS secondary_score;
+ALTER TABLE scores DROP COLUMN IF EXISTS rank_secondary, DROP COLUMN IF EXISTS secondary_score;
+ALTER TABLE leaderboards DROP COLUMN IF EXISTS secondary_sort_order;
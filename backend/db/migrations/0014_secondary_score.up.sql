@@ -0,0 +1,96 @@
+-- A secondary score (e.g. a time or an accuracy) breaks ties between equal scores,
+-- before achieved_at. rank_secondary is the secondary score in ranking space like
+-- rank_score: negated on boards whose secondary_sort_order is 'asc'. A best score
+-- is replaced by a higher score, or by an equal one with a better secondary score.
+ALTER TABLE leaderboards ADD COLUMN secondary_sort_order TEXT NOT NULL DEFAULT 'desc',
+    ADD CONSTRAINT secondary_sort_order_valid CHECK (secondary_sort_order IN ('desc', 'asc'));
+
+ALTER TABLE scores ADD COLUMN secondary_score BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN rank_secondary BIGINT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT secondary_score_non_negative CHECK (secondary_score >= 0);
+ALTER TABLE score_changes ADD COLUMN secondary_score BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN rank_secondary BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE leaderboard_snapshot_entries ADD COLUMN secondary_score BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN rank_secondary BIGINT NOT NULL DEFAULT 0;
+
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX idx_scores_leaderboard ON scores (leaderboard_id, rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name) WHERE deleted_at IS NULL;
+
+-- Derive rank_secondary along with rank_score on every write
+CREATE OR REPLACE FUNCTION set_rank_score()
+RETURNS TRIGGER AS $$
+DECLARE
+    primary_order TEXT;
+    secondary_order TEXT;
+BEGIN
+    SELECT l.sort_order, l.secondary_sort_order INTO primary_order, secondary_order
+    FROM leaderboards l WHERE l.leaderboard_id = NEW.leaderboard_id;
+    NEW.rank_score := CASE WHEN primary_order = 'asc' THEN -NEW.score ELSE NEW.score END;
+    NEW.rank_secondary := CASE WHEN secondary_order = 'asc' THEN -NEW.secondary_score ELSE NEW.secondary_score END;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS scores_rank_score_trigger ON scores;
+CREATE TRIGGER scores_rank_score_trigger
+BEFORE INSERT OR UPDATE OF score, secondary_score, leaderboard_id ON scores
+FOR EACH ROW
+EXECUTE FUNCTION set_rank_score();
+
+-- Same as 0013, also notifying secondary score changes, which move players too
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed scores%ROWTYPE;
+    operation TEXT;
+    change_id BIGINT;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN OLD;
+        END IF;
+        changed := OLD;
+        operation := 'delete';
+    ELSIF TG_OP = 'INSERT' THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.deleted_at IS NOT NULL THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NEW;
+        END IF;
+        changed := OLD;
+        operation := 'delete';
+    ELSIF OLD.deleted_at IS NOT NULL THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.score <> OLD.score OR NEW.secondary_score <> OLD.secondary_score THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        changed := NEW;
+        operation := 'update';
+    ELSE
+        RETURN NEW;
+    END IF;
+
+    IF current_setting('leaderboard.suppress_notify', true) = 'on' THEN
+        RETURN changed;
+    END IF;
+
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, secondary_score, rank_secondary, achieved_at, metadata, op)
+    VALUES (changed.leaderboard_id, changed.player_name, changed.score, changed.rank_score, changed.secondary_score, changed.rank_secondary, changed.achieved_at, changed.metadata, operation)
+    RETURNING id INTO change_id;
+
+    -- Bounded size: player_name and leaderboard_id are length-checked
+    PERFORM pg_notify('scores_changes', json_build_object(
+        'id', change_id,
+        'leaderboard_id', changed.leaderboard_id,
+        'player_name', changed.player_name,
+        'op', operation
+    )::text);
+
+    RETURN changed;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Appends every score change to score_changes and sends its id on channel scores_changes with JSON payload: {"id":42, "leaderboard_id":"...", "player_name":"...", "op":"insert|update|delete"}. Notifies on any change of the score or the secondary score (increase or decrease), soft deletes as delete and restores as insert, unless leaderboard.suppress_notify is on.';
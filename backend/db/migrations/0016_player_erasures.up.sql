@@ -0,0 +1,13 @@
+-- Erasures of a player's data (DELETE /players/{player_name}/data), kept as the
+-- audit trail of erasure requests. The player name is erased with the rest: rows
+-- hold its hex SHA-256, which finds the erasures of a name the operator knows.
+CREATE TABLE player_erasures (
+    id BIGSERIAL PRIMARY KEY,
+    player_hash TEXT NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    deleted_scores BIGINT NOT NULL,
+    deleted_rows BIGINT NOT NULL, -- every table, scores included
+    erased_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_player_erasures_player ON player_erasures (player_hash);
@@ -0,0 +1,9 @@
+-- Players banned by an operator: SubmitScore rejects their names. A ban only
+-- names the player, so it survives the deletion of their scores and profile.
+CREATE TABLE banned_players (
+    player_name TEXT PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    banned_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT banned_players_player_name_length CHECK (char_length(player_name) <= 20 AND char_length(player_name) > 0),
+    CONSTRAINT ban_reason_length CHECK (char_length(reason) <= 256)
+);
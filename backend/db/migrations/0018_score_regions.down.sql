@@ -0,0 +1,66 @@
+-- Restore the notify function from 0015
+CREATE OR REPLACE FUNCTION notify_score_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed scores%ROWTYPE;
+    operation TEXT;
+    change_id BIGINT;
+    changed_at TIMESTAMPTZ;
+BEGIN
+    -- Determine the operation type
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN OLD;
+        END IF;
+        changed := OLD;
+        operation := 'delete';
+    ELSIF TG_OP = 'INSERT' THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.deleted_at IS NOT NULL THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NEW;
+        END IF;
+        changed := OLD;
+        operation := 'delete';
+    ELSIF OLD.deleted_at IS NOT NULL THEN
+        changed := NEW;
+        operation := 'insert';
+    ELSIF NEW.score <> OLD.score OR NEW.secondary_score <> OLD.secondary_score THEN
+        -- Notify if the score actually changed (any change, not just improvements)
+        changed := NEW;
+        operation := 'update';
+    ELSE
+        RETURN NEW;
+    END IF;
+
+    IF current_setting('leaderboard.suppress_notify', true) = 'on' THEN
+        RETURN changed;
+    END IF;
+
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, secondary_score, rank_secondary, achieved_at, metadata, op)
+    VALUES (changed.leaderboard_id, changed.player_name, changed.score, changed.rank_score, changed.secondary_score, changed.rank_secondary, changed.achieved_at, changed.metadata, operation)
+    RETURNING id, created_at INTO change_id, changed_at;
+
+    -- Bounded size: player_name and leaderboard_id are length-checked
+    PERFORM pg_notify('scores_changes', json_build_object(
+        'id', change_id,
+        'updated_at', changed_at,
+        'leaderboard_id', changed.leaderboard_id,
+        'player_name', changed.player_name,
+        'op', operation
+    )::text);
+
+    RETURN changed;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION notify_score_change() IS
+'Appends every score change to score_changes and sends its id (the change sequence number) and time on channel scores_changes with JSON payload: {"id":42, "updated_at":"...", "leaderboard_id":"...", "player_name":"...", "op":"insert|update|delete"}. Notifies on any change of the score or the secondary score (increase or decrease), soft deletes as delete and restores as insert, unless leaderboard.suppress_notify is on.';
+
+DROP INDEX IF EXISTS idx_scores_region;
+
+ALTER TABLE leaderboard_snapshot_entries DROP COLUMN IF EXISTS country_code;
+ALTER TABLE score_changes DROP COLUMN IF EXISTS country_code;
+ALTER TABLE scores DROP CONSTRAINT IF EXISTS scores_country_code_valid,
+    DROP COLUMN IF EXISTS country_code;
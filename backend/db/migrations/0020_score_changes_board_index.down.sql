@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_score_changes_board;
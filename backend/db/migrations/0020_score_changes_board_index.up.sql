@@ -0,0 +1,3 @@
+-- Polling clients read the changes of one board after a checkpoint id
+-- (GetChangesSince), so the outbox gets a per-board index in id order.
+CREATE INDEX idx_score_changes_board ON score_changes (leaderboard_id, id);
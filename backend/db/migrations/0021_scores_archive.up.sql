@@ -0,0 +1,26 @@
+-- Scores moved off the live boards by the archival job (internal/maintenance):
+-- entries not updated for the retention period or ranked worse than the score
+-- floor. Rows keep the columns of scores, soft-deleted ones included, with the
+-- reason and the time of their archival. Nothing reads them back automatically.
+CREATE TABLE scores_archive (
+    id BIGSERIAL PRIMARY KEY,
+    player_name TEXT NOT NULL,
+    score BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    achieved_at TIMESTAMPTZ NOT NULL,
+    client_achieved_at TIMESTAMPTZ,
+    leaderboard_id TEXT NOT NULL,
+    rank_score BIGINT NOT NULL,
+    deleted_at TIMESTAMPTZ,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    secondary_score BIGINT NOT NULL DEFAULT 0,
+    rank_secondary BIGINT NOT NULL DEFAULT 0,
+    country_code TEXT NOT NULL DEFAULT '',
+    platform TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT archive_reason_valid CHECK (reason IN ('retention', 'score_floor'))
+);
+
+CREATE INDEX idx_scores_archive_board ON scores_archive (leaderboard_id, archived_at);
+CREATE INDEX idx_scores_archive_player ON scores_archive (player_name);
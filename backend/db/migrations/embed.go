@@ -0,0 +1,10 @@
+// Package migrations embeds the PostgreSQL schema migrations so the server
+// binary can apply them at startup (see store.Migrate).
+package migrations
+
+import "embed"
+
+// FS holds the golang-migrate up/down SQL files of this directory
+//
+//go:embed *.sql
+var FS embed.FS
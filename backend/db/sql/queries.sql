@@ -1,62 +1,672 @@
 -- name: UpsertScore :one
--- Upserts a player's score, keeping only the best (highest) score.
--- Returns the current best score and a boolean indicating if it was improved.
+-- Upserts a player's score on a leaderboard, keeping only the best score: the highest
+-- rank_score, i.e. the highest score on 'desc' boards and the lowest on 'asc' boards,
+-- then the highest rank_secondary among equal scores.
+-- rank_score and rank_secondary are derived from the board's sort orders in the same statement.
 -- This query uses ON CONFLICT to handle the upsert logic efficiently.
--- Time complexity: O(log n) due to primary key lookup
-INSERT INTO scores (player_name, score, updated_at)
-VALUES ($1, $2, now())
-ON CONFLICT (player_name)
+-- The other columns follow the best score: they only change when it improves.
+-- A soft-deleted score is replaced, as if the player had none.
+-- Time complexity: O(log n) due to primary key lookups
+INSERT INTO scores (leaderboard_id, player_name, score, updated_at, achieved_at, client_achieved_at, metadata, secondary_score, country_code, platform, rank_score, rank_secondary)
+VALUES (
+    @leaderboard_id, @player_name, @score, now(),
+    COALESCE(sqlc.narg('achieved_at')::timestamptz, now()), sqlc.narg('client_achieved_at'),
+    COALESCE(sqlc.narg('metadata')::jsonb, '{}'), @secondary_score, @country_code, @platform,
+    CASE
+        WHEN EXISTS (SELECT 1 FROM leaderboards l WHERE l.leaderboard_id = @leaderboard_id AND l.sort_order = 'asc')
+        THEN -sqlc.arg(score)::bigint
+        ELSE @score::bigint
+    END,
+    CASE
+        WHEN EXISTS (SELECT 1 FROM leaderboards l WHERE l.leaderboard_id = @leaderboard_id AND l.secondary_sort_order = 'asc')
+        THEN -sqlc.arg(secondary_score)::bigint
+        ELSE @secondary_score::bigint
+    END
+)
+ON CONFLICT (leaderboard_id, player_name)
 DO UPDATE SET
-    score = GREATEST(EXCLUDED.score, scores.score),
+    score = CASE
+        WHEN scores.deleted_at IS NOT NULL OR (EXCLUDED.rank_score, EXCLUDED.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN EXCLUDED.score
+        ELSE scores.score
+    END,
+    secondary_score = CASE
+        WHEN scores.deleted_at IS NOT NULL OR (EXCLUDED.rank_score, EXCLUDED.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN EXCLUDED.secondary_score
+        ELSE scores.secondary_score
+    END,
+    rank_score = CASE
+        WHEN scores.deleted_at IS NOT NULL OR (EXCLUDED.rank_score, EXCLUDED.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN EXCLUDED.rank_score
+        ELSE scores.rank_score
+    END,
+    rank_secondary = CASE
+        WHEN scores.deleted_at IS NOT NULL OR (EXCLUDED.rank_score, EXCLUDED.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN EXCLUDED.rank_secondary
+        ELSE scores.rank_secondary
+    END,
     updated_at = CASE
-        WHEN EXCLUDED.score > scores.score THEN now()
+        WHEN scores.deleted_at IS NOT NULL OR (EXCLUDED.rank_score, EXCLUDED.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN now()
         ELSE scores.updated_at
+    END,
+    achieved_at = CASE
+        WHEN scores.deleted_at IS NOT NULL OR (EXCLUDED.rank_score, EXCLUDED.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN EXCLUDED.achieved_at
+        ELSE scores.achieved_at
+    END,
+    client_achieved_at = CASE
+        WHEN scores.deleted_at IS NOT NULL OR (EXCLUDED.rank_score, EXCLUDED.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN EXCLUDED.client_achieved_at
+        ELSE scores.client_achieved_at
+    END,
+    metadata = CASE
+        WHEN scores.deleted_at IS NOT NULL OR (EXCLUDED.rank_score, EXCLUDED.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN EXCLUDED.metadata
+        ELSE scores.metadata
+    END,
+    country_code = CASE
+        WHEN scores.deleted_at IS NOT NULL OR (EXCLUDED.rank_score, EXCLUDED.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN EXCLUDED.country_code
+        ELSE scores.country_code
+    END,
+    platform = CASE
+        WHEN scores.deleted_at IS NOT NULL OR (EXCLUDED.rank_score, EXCLUDED.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN EXCLUDED.platform
+        ELSE scores.platform
+    END,
+    deleted_at = NULL
+RETURNING player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform;
+
+-- name: InsertScore :one
+-- Inserts a player's first score on a leaderboard, like UpsertScore. Returns no row
+-- when the player already has one, including one inserted by a concurrent
+-- transaction: ON CONFLICT waits for that transaction to commit. A soft-deleted
+-- score does not count and is replaced.
+-- Time complexity: O(log n) - primary key insert
+INSERT INTO scores (leaderboard_id, player_name, score, updated_at, achieved_at, client_achieved_at, metadata, secondary_score, country_code, platform, rank_score, rank_secondary)
+VALUES (
+    @leaderboard_id, @player_name, @score, now(),
+    COALESCE(sqlc.narg('achieved_at')::timestamptz, now()), sqlc.narg('client_achieved_at'),
+    COALESCE(sqlc.narg('metadata')::jsonb, '{}'), @secondary_score, @country_code, @platform,
+    CASE
+        WHEN EXISTS (SELECT 1 FROM leaderboards l WHERE l.leaderboard_id = @leaderboard_id AND l.sort_order = 'asc')
+        THEN -sqlc.arg(score)::bigint
+        ELSE @score::bigint
+    END,
+    CASE
+        WHEN EXISTS (SELECT 1 FROM leaderboards l WHERE l.leaderboard_id = @leaderboard_id AND l.secondary_sort_order = 'asc')
+        THEN -sqlc.arg(secondary_score)::bigint
+        ELSE @secondary_score::bigint
     END
-RETURNING player_name, score, updated_at;
+)
+ON CONFLICT (leaderboard_id, player_name)
+DO UPDATE SET
+    score = EXCLUDED.score,
+    rank_score = EXCLUDED.rank_score,
+    updated_at = EXCLUDED.updated_at,
+    achieved_at = EXCLUDED.achieved_at,
+    client_achieved_at = EXCLUDED.client_achieved_at,
+    metadata = EXCLUDED.metadata,
+    secondary_score = EXCLUDED.secondary_score,
+    rank_secondary = EXCLUDED.rank_secondary,
+    country_code = EXCLUDED.country_code,
+    platform = EXCLUDED.platform,
+    deleted_at = NULL
+WHERE scores.deleted_at IS NOT NULL
+RETURNING player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform;
 
 -- name: GetTopScores :many
--- Retrieves the top N scores in descending order with pagination support.
+-- Retrieves the top N scores of a leaderboard, best first (rank_score descending),
+-- with pagination support. Ties are broken by rank_secondary (higher first), then
+-- achieved_at (earlier first), then player_name.
 -- Uses the idx_scores_leaderboard index for efficient sorting.
 -- Time complexity: O(limit + offset) with index scan
-SELECT player_name, score, updated_at
+SELECT player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL
+ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC
+LIMIT @page_size OFFSET @page_offset;
+
+-- name: GetTopScoresAfter :many
+-- Keyset pagination: retrieves the next page of the leaderboard after the given
+-- entry (rank_score, rank_secondary, achieved_at, player_name), in the same order as GetTopScores.
+-- Pages stay consistent when scores change between requests, unlike offsets.
+-- The leading rank_score bound lets the scan start at the cursor in idx_scores_leaderboard.
+-- Time complexity: O(log n + limit) with index scan
+SELECT player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL
+  AND rank_score <= @rank_score
+  AND (rank_score < @rank_score
+       OR rank_secondary < @rank_secondary
+       OR (rank_secondary = @rank_secondary AND achieved_at > @achieved_at)
+       OR (rank_secondary = @rank_secondary AND achieved_at = @achieved_at AND player_name > @player_name))
+ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC
+LIMIT @page_size;
+
+-- name: GetScoresAroundRank :many
+-- Retrieves the entries ranked within radius of a 1-based rank, in the order of
+-- GetTopScores: ranks max(rank - radius, 1) to rank + radius, fewer at the ends of
+-- the board. With the rank of GetPlayerRank, these are a player's neighbours.
+-- rank must be positive and radius non-negative.
+-- Time complexity: O(rank + radius) with index scan
+SELECT player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL
+ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC
+LIMIT sqlc.arg(rank)::int + sqlc.arg(radius)::int - GREATEST(sqlc.arg(rank)::int - sqlc.arg(radius)::int, 1) + 1
+OFFSET GREATEST(sqlc.arg(rank)::int - sqlc.arg(radius)::int, 1) - 1;
+
+-- name: GetSegmentTopScores :many
+-- Retrieves a page of a leaderboard restricted to a segment: the scores submitted
+-- from one country (ISO 3166-1 alpha-2 country_code) and/or one platform, '' matching
+-- any, in the order of GetTopScores.
+-- Uses the idx_scores_region or idx_scores_platform index.
+-- Time complexity: O(limit + offset) with index scan
+SELECT player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL
+  AND (@country_code::text = '' OR country_code = @country_code)
+  AND (@platform::text = '' OR platform = @platform)
+ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC
+LIMIT @page_size OFFSET @page_offset;
+
+-- name: GetSegmentTopScoresAfter :many
+-- Keyset pagination of GetSegmentTopScores, like GetTopScoresAfter.
+-- Time complexity: O(log n + limit) with index scan
+SELECT player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform
 FROM scores
-ORDER BY score DESC, player_name ASC
-LIMIT $1 OFFSET $2;
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL
+  AND (@country_code::text = '' OR country_code = @country_code)
+  AND (@platform::text = '' OR platform = @platform)
+  AND rank_score <= @rank_score
+  AND (rank_score < @rank_score
+       OR rank_secondary < @rank_secondary
+       OR (rank_secondary = @rank_secondary AND achieved_at > @achieved_at)
+       OR (rank_secondary = @rank_secondary AND achieved_at = @achieved_at AND player_name > @player_name))
+ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC
+LIMIT @page_size;
 
 -- name: GetPlayerScore :one
--- Retrieves a specific player's current best score.
+-- Retrieves a specific player's current best score on a leaderboard.
 -- Time complexity: O(1) - primary key lookup
-SELECT player_name, score, updated_at
+SELECT player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform
 FROM scores
-WHERE player_name = $1;
+WHERE leaderboard_id = @leaderboard_id AND player_name = @player_name AND deleted_at IS NULL;
+
+-- name: PlayerExists :one
+-- Reports whether a player has a score on a leaderboard, without reading it.
+-- Time complexity: O(log n) - primary key lookup
+SELECT EXISTS (
+    SELECT 1 FROM scores
+    WHERE leaderboard_id = @leaderboard_id AND player_name = @player_name AND deleted_at IS NULL
+) AS player_exists;
 
 -- name: GetPlayerRank :one
--- Calculates a player's rank in the leaderboard.
--- Rank is 1-based (1 = best). Uses deterministic tie-breaking by player_name.
--- Returns the count of players with strictly better scores plus 1.
+-- Calculates a player's rank in a leaderboard.
+-- Rank is 1-based (1 = best). Ties are broken deterministically by rank_secondary
+-- (higher first), then achieved_at (earlier first), then player_name.
+-- Returns the count of players ranked strictly better plus 1.
+-- Time complexity: O(n) worst case, but uses index for score comparison
+SELECT 1 + COUNT(*)::bigint AS rank
+FROM scores s1, (
+    SELECT s2.rank_score, s2.rank_secondary, s2.achieved_at, s2.player_name FROM scores s2
+    WHERE s2.leaderboard_id = @leaderboard_id AND s2.player_name = @player_name AND s2.deleted_at IS NULL
+) p
+WHERE s1.leaderboard_id = @leaderboard_id AND s1.deleted_at IS NULL
+  AND (s1.rank_score > p.rank_score
+       OR (s1.rank_score = p.rank_score AND s1.rank_secondary > p.rank_secondary)
+       OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at < p.achieved_at)
+       OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at = p.achieved_at AND s1.player_name < p.player_name));
+
+-- name: GetPlayerRanks :many
+-- Ranks several players of a leaderboard at once, with their best scores, each rank
+-- computed as in GetPlayerRank. Players without a score are omitted. Ordered by rank.
+-- Time complexity: O(k * n) worst case for k players, but uses index for score comparison
+SELECT sqlc.embed(p), (
+    SELECT 1 + COUNT(*) FROM scores s1
+    WHERE s1.leaderboard_id = p.leaderboard_id AND s1.deleted_at IS NULL
+      AND (s1.rank_score > p.rank_score
+           OR (s1.rank_score = p.rank_score AND s1.rank_secondary > p.rank_secondary)
+           OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at < p.achieved_at)
+           OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at = p.achieved_at AND s1.player_name < p.player_name))
+)::bigint AS rank
+FROM scores p
+WHERE p.leaderboard_id = @leaderboard_id AND p.player_name = ANY(@player_names::text[]) AND p.deleted_at IS NULL
+ORDER BY rank, p.player_name;
+
+-- name: GetSegmentPlayerRank :one
+-- Calculates a player's rank within a segment of a leaderboard (see GetSegmentTopScores),
+-- like GetPlayerRank. The player's best score must be in the segment.
 -- Time complexity: O(n) worst case, but uses index for score comparison
 SELECT 1 + COUNT(*)::bigint AS rank
-FROM scores s1
-WHERE s1.score > (SELECT s2.score FROM scores s2 WHERE s2.player_name = $1)
-   OR (s1.score = (SELECT s2.score FROM scores s2 WHERE s2.player_name = $1) AND s1.player_name < $1);
+FROM scores s1, (
+    SELECT s2.rank_score, s2.rank_secondary, s2.achieved_at, s2.player_name FROM scores s2
+    WHERE s2.leaderboard_id = @leaderboard_id AND s2.player_name = @player_name AND s2.deleted_at IS NULL
+) p
+WHERE s1.leaderboard_id = @leaderboard_id AND s1.deleted_at IS NULL
+  AND (@country_code::text = '' OR s1.country_code = @country_code)
+  AND (@platform::text = '' OR s1.platform = @platform)
+  AND (s1.rank_score > p.rank_score
+       OR (s1.rank_score = p.rank_score AND s1.rank_secondary > p.rank_secondary)
+       OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at < p.achieved_at)
+       OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at = p.achieved_at AND s1.player_name < p.player_name));
+
+-- name: GetTopScoresRecencyWeighted :many
+-- Ranking experiment: retrieves a page of the leaderboard ordered by recency-weighted
+-- rank score, which halves every half_life_seconds between achieved_at and now_unix
+-- (negative rank scores of 'asc' boards double instead, so older is always worse).
+-- Ties are broken as in GetTopScores.
+-- Time complexity: O(n log n) - sorts the whole board, no index applies
+SELECT player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL
+ORDER BY rank_score * power(2.0, -sign(rank_score) * LEAST(GREATEST(sqlc.arg(now_unix)::float8 - EXTRACT(EPOCH FROM achieved_at), 0) / sqlc.arg(half_life_seconds)::float8, 1000)) DESC,
+         rank_secondary DESC, achieved_at ASC, player_name ASC
+LIMIT @page_size OFFSET @page_offset;
+
+-- name: GetPlayerRankRecencyWeighted :one
+-- Ranking experiment: a player's 1-based rank in the order of GetTopScoresRecencyWeighted.
+-- Time complexity: O(n log n) - ranks the whole board
+SELECT r.player_rank::bigint AS rank
+FROM (
+    SELECT player_name,
+           ROW_NUMBER() OVER (
+               ORDER BY rank_score * power(2.0, -sign(rank_score) * LEAST(GREATEST(sqlc.arg(now_unix)::float8 - EXTRACT(EPOCH FROM achieved_at), 0) / sqlc.arg(half_life_seconds)::float8, 1000)) DESC,
+                        rank_secondary DESC, achieved_at ASC, player_name ASC
+           ) AS player_rank
+    FROM scores
+    WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL
+) r
+WHERE r.player_name = @player_name::text;
 
--- name: DeleteScore :exec
--- Deletes a player's score entry entirely.
+-- name: DeleteScore :execrows
+-- Soft-deletes a player's score entry from a leaderboard: it is hidden from every
+-- other query until RestoreScore. Affects no row when the player has no live score.
 -- Time complexity: O(log n) - primary key lookup
-DELETE FROM scores
-WHERE player_name = $1;
+UPDATE scores
+SET deleted_at = now()
+WHERE leaderboard_id = @leaderboard_id AND player_name = @player_name AND deleted_at IS NULL;
+
+-- name: RestoreScore :one
+-- Restores a soft-deleted score entry as it was when deleted.
+-- Time complexity: O(log n) - primary key lookup
+UPDATE scores
+SET deleted_at = NULL
+WHERE leaderboard_id = @leaderboard_id AND player_name = @player_name AND deleted_at IS NOT NULL
+RETURNING player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform;
+
+-- name: GetDeletedScores :many
+-- Lists the soft-deleted scores of a leaderboard, most recently deleted first.
+-- Uses the idx_scores_deleted index.
+-- Time complexity: O(log n + limit) with index scan
+SELECT player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC, player_name ASC
+LIMIT @page_size;
 
 -- name: CountScores :one
--- Returns the total number of players in the leaderboard.
--- Time complexity: O(1) - uses table statistics or fast count
+-- Returns the total number of players in a leaderboard.
+-- Time complexity: O(n) - index-only range scan of the board
+SELECT COUNT(*)::bigint AS total
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL;
+
+-- name: CountSegmentScores :one
+-- Returns the number of players in a segment of a leaderboard, as listed by
+-- GetSegmentTopScores.
+-- Time complexity: O(n) - range scan of the idx_scores_region or idx_scores_platform index
 SELECT COUNT(*)::bigint AS total
-FROM scores;
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL
+  AND (@country_code::text = '' OR country_code = @country_code)
+  AND (@platform::text = '' OR platform = @platform);
+
+-- name: CountPlayers :one
+-- Returns the number of distinct players with a score on any leaderboard.
+-- Time complexity: O(n) - scan of every board
+SELECT COUNT(DISTINCT player_name)::bigint AS total
+FROM scores
+WHERE deleted_at IS NULL;
 
 -- name: GetScoreForUpdate :one
 -- Retrieves a player's score with a row lock for transactional updates.
 -- Used when you need to ensure consistency during concurrent operations.
 -- Time complexity: O(1) - primary key lookup with lock
-SELECT player_name, score, updated_at
+SELECT player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform
 FROM scores
-WHERE player_name = $1
+WHERE leaderboard_id = @leaderboard_id AND player_name = @player_name AND deleted_at IS NULL
 FOR UPDATE;
+
+-- name: RecordDevicePlayer :exec
+-- Associates a player account with a device fingerprint (no-op if already known).
+-- Time complexity: O(log n) - primary key lookup
+INSERT INTO device_players (device_hash, player_name)
+VALUES ($1, $2)
+ON CONFLICT (device_hash, player_name) DO NOTHING;
+
+-- name: IsDevicePlayerKnown :one
+-- Reports whether a player account was already seen on a device.
+-- Time complexity: O(log n) - primary key lookup
+SELECT EXISTS (
+    SELECT 1 FROM device_players
+    WHERE device_hash = $1 AND player_name = $2
+) AS known;
+
+-- name: CountDevicePlayers :one
+-- Returns the number of distinct player accounts seen on a device.
+-- Time complexity: O(k) where k is the number of accounts on the device
+SELECT COUNT(*)::bigint AS total
+FROM device_players
+WHERE device_hash = $1;
+
+-- name: RecordDeviceSubmission :exec
+-- Logs a score submission made from a device.
+-- Time complexity: O(log n) - index insert
+INSERT INTO device_submissions (device_hash, player_name)
+VALUES ($1, $2);
+
+-- name: CountDeviceSubmissionsSince :one
+-- Returns the number of submissions made from a device since the given time.
+-- Uses the idx_device_submissions_recent index.
+-- Time complexity: O(k) where k is the number of recent submissions
+SELECT COUNT(*)::bigint AS total
+FROM device_submissions
+WHERE device_hash = $1 AND submitted_at >= $2;
+
+-- name: PruneDeviceSubmissions :execrows
+-- Deletes the submissions logged more than retention_seconds ago, which no device limit reads anymore.
+-- Time complexity: O(s) - sequential scan of a log kept short by this pruning
+DELETE FROM device_submissions
+WHERE submitted_at < now() - make_interval(secs => @retention_seconds::float8);
+
+-- name: GetScorePercentiles :one
+-- Computes continuous percentiles of a leaderboard's rank_score distribution for each requested fraction.
+-- Fractions are in [0, 1] ascending order of rank_score (0.99 = rank_score beating 99% of players).
+-- Thresholds are in ranking space: negate them on 'asc' boards to get scores.
+-- Only the scores of a segment count (see GetSegmentTopScores; '' matches any).
+-- Returns an empty array when the leaderboard is empty.
+-- Time complexity: O(n log n) - full sort of scores
+SELECT
+    COUNT(*)::bigint AS total,
+    COALESCE(percentile_cont(@fractions::float8[]) WITHIN GROUP (ORDER BY rank_score), '{}')::float8[] AS thresholds
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL
+  AND (@country_code::text = '' OR country_code = @country_code)
+  AND (@platform::text = '' OR platform = @platform);
+
+-- name: GetScoresInRange :many
+-- Retrieves all players of a leaderboard whose rank_score is in [min_rank_score, max_rank_score).
+-- Used to find players affected when tier thresholds move.
+-- Time complexity: O(log n + k) with index range scan
+SELECT player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL AND rank_score >= @min_rank_score AND rank_score < @max_rank_score
+ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC;
+
+-- name: GetScoreStats :one
+-- Returns the number of ranked players of a leaderboard and when a best score last changed.
+-- last_updated_at is NULL when the board is empty.
+-- Time complexity: O(n) - full scan, callers should cache the result
+SELECT COUNT(*)::bigint AS players, MAX(updated_at)::timestamptz AS last_updated_at
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL;
+
+-- name: GetScoreBounds :one
+-- Returns the number of players of a leaderboard and its lowest and highest scores
+-- (both 0 when the board is empty).
+-- Time complexity: O(n) - full scan of the board
+SELECT COUNT(*)::bigint AS players,
+       COALESCE(MIN(score), 0)::bigint AS min_score,
+       COALESCE(MAX(score), 0)::bigint AS max_score
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL;
+
+-- name: GetScoreHistogram :many
+-- Counts the players of a leaderboard per equal-width score bucket of [low, high).
+-- Buckets are numbered 1 to bucket_count; scores outside the range land in 0 or
+-- bucket_count + 1. Buckets without players are not returned.
+-- Time complexity: O(n) - full scan of the board, callers should cache the result
+SELECT width_bucket(score::float8, @low::float8, @high::float8, @bucket_count::int)::int AS bucket,
+       COUNT(*)::bigint AS players
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL
+GROUP BY bucket
+ORDER BY bucket;
+
+-- name: UpsertPlayerProfile :one
+-- Creates or replaces a player's profile. created_at is kept on update.
+-- Time complexity: O(log n) - primary key lookup
+INSERT INTO players (player_name, display_name, country_code, avatar_url)
+VALUES (@player_name, @display_name, @country_code, @avatar_url)
+ON CONFLICT (player_name)
+DO UPDATE SET
+    display_name = EXCLUDED.display_name,
+    country_code = EXCLUDED.country_code,
+    avatar_url = EXCLUDED.avatar_url,
+    updated_at = now()
+RETURNING player_name, display_name, country_code, avatar_url, created_at, updated_at;
+
+-- name: GetPlayerProfile :one
+-- Retrieves a player's profile.
+-- Time complexity: O(1) - primary key lookup
+SELECT player_name, display_name, country_code, avatar_url, created_at, updated_at
+FROM players
+WHERE player_name = $1;
+
+-- name: GetPlayerProfiles :many
+-- Retrieves the profiles of several players at once (players without one are omitted).
+-- Used to attach profiles to leaderboard pages.
+-- Time complexity: O(k log n) - one primary key lookup per name
+SELECT player_name, display_name, country_code, avatar_url, created_at, updated_at
+FROM players
+WHERE player_name = ANY(@player_names::text[]);
+
+-- name: SimulateRank :one
+-- Counts the players of a leaderboard a hypothetical score would rank behind, without writing anything.
+-- The hypothetical score is passed in ranking space (rank_score, negated on 'asc' boards).
+-- A new score ties after existing equal scores (it would be achieved later), so every
+-- rank_score >= the hypothetical one ranks ahead. The simulating player's own entry is
+-- excluded (pass an empty name for a new player). next_rank_score is the lowest
+-- rank_score ranked ahead (0 when none), total the number of other players.
+-- Only the players of a segment count (see GetSegmentTopScores; '' matches any).
+-- Time complexity: O(n) - full scan for the total
+SELECT
+    (COUNT(*) FILTER (WHERE s.rank_score >= @rank_score))::bigint AS ahead,
+    COALESCE(MIN(s.rank_score) FILTER (WHERE s.rank_score >= @rank_score), 0)::bigint AS next_rank_score,
+    COUNT(*)::bigint AS total
+FROM scores s
+WHERE s.leaderboard_id = @leaderboard_id AND s.player_name <> @player_name AND s.deleted_at IS NULL
+  AND (@country_code::text = '' OR s.country_code = @country_code)
+  AND (@platform::text = '' OR s.platform = @platform);
+
+-- name: UpsertLeaderboard :one
+-- Creates or updates a leaderboard definition. created_at is kept on update.
+-- Time complexity: O(log n) - primary key lookup
+INSERT INTO leaderboards (leaderboard_id, sort_order, secondary_sort_order)
+VALUES (@leaderboard_id, @sort_order, @secondary_sort_order)
+ON CONFLICT (leaderboard_id)
+DO UPDATE SET
+    sort_order = EXCLUDED.sort_order,
+    secondary_sort_order = EXCLUDED.secondary_sort_order,
+    updated_at = now()
+RETURNING leaderboard_id, sort_order, created_at, updated_at, secondary_sort_order;
+
+-- name: GetLeaderboard :one
+-- Retrieves a leaderboard definition. Boards without one use the defaults.
+-- Time complexity: O(1) - primary key lookup
+SELECT leaderboard_id, sort_order, created_at, updated_at, secondary_sort_order
+FROM leaderboards
+WHERE leaderboard_id = $1;
+
+-- name: ListLeaderboards :many
+-- Lists leaderboard definitions by id, leaving out the boards whose id starts with
+-- exclude_prefix (e.g. past daily boards), up to max_boards.
+-- Time complexity: O(n) over the definitions
+SELECT leaderboard_id, sort_order, created_at, updated_at, secondary_sort_order
+FROM leaderboards
+WHERE @exclude_prefix::text = '' OR left(leaderboard_id, length(@exclude_prefix::text)) <> @exclude_prefix::text
+ORDER BY leaderboard_id
+LIMIT @max_boards;
+
+-- name: HasScores :one
+-- Reports whether a leaderboard holds at least one score.
+-- Time complexity: O(log n) - first entry of the primary key range
+SELECT EXISTS (
+    SELECT 1 FROM scores WHERE leaderboard_id = $1 AND deleted_at IS NULL
+) AS has_scores;
+
+-- name: CreateLeaderboardSnapshot :one
+-- Creates an empty snapshot of a leaderboard, filled by SnapshotScores.
+-- Time complexity: O(1)
+INSERT INTO leaderboard_snapshots (leaderboard_id)
+VALUES ($1)
+RETURNING id;
+
+-- name: SnapshotScores :execrows
+-- Copies every live score of a leaderboard into a snapshot.
+-- Time complexity: O(n) - range scan of the board
+INSERT INTO leaderboard_snapshot_entries (snapshot_id, player_name, score, rank_score, achieved_at, updated_at, secondary_score, rank_secondary, country_code, platform)
+SELECT @snapshot_id, player_name, score, rank_score, achieved_at, updated_at, secondary_score, rank_secondary, country_code, platform
+FROM scores
+WHERE leaderboard_id = @leaderboard_id AND deleted_at IS NULL;
+
+-- name: GetLeaderboardSnapshot :one
+-- Retrieves a snapshot taken by a reset or a restore.
+-- Time complexity: O(1) - primary key lookup
+SELECT id, leaderboard_id, created_at
+FROM leaderboard_snapshots
+WHERE id = $1;
+
+-- name: GetSnapshotEntries :many
+-- Retrieves every entry of a snapshot, in rank order.
+-- Time complexity: O(n log n) - n entries of the snapshot
+SELECT snapshot_id, player_name, score, rank_score, achieved_at, updated_at, secondary_score, rank_secondary, country_code, platform
+FROM leaderboard_snapshot_entries
+WHERE snapshot_id = $1
+ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC;
+
+-- name: DeleteLeaderboardScores :one
+-- Deletes every score of a leaderboard for good, soft-deleted ones included, and
+-- returns the number of live scores deleted. The definition in leaderboards is kept.
+-- Time complexity: O(n) - range scan of the board
+WITH deleted AS (
+    DELETE FROM scores
+    WHERE leaderboard_id = $1
+    RETURNING deleted_at
+)
+SELECT (COUNT(*) FILTER (WHERE deleted_at IS NULL))::bigint AS deleted
+FROM deleted;
+
+-- name: CreateWebhook :one
+-- Registers a webhook.
+-- Time complexity: O(1)
+INSERT INTO webhooks (url, secret, events, leaderboard_id, enabled)
+VALUES (@url, @secret, @events::text[], sqlc.narg('leaderboard_id'), @enabled)
+RETURNING id, url, secret, events, leaderboard_id, enabled, created_at, updated_at;
+
+-- name: GetWebhook :one
+-- Retrieves a webhook.
+-- Time complexity: O(1) - primary key lookup
+SELECT id, url, secret, events, leaderboard_id, enabled, created_at, updated_at
+FROM webhooks
+WHERE id = $1;
+
+-- name: ListWebhooks :many
+-- Lists every webhook in registration order.
+-- Time complexity: O(w) - webhooks are few
+SELECT id, url, secret, events, leaderboard_id, enabled, created_at, updated_at
+FROM webhooks
+ORDER BY id;
+
+-- name: UpdateWebhook :one
+-- Replaces the settings of a webhook. The secret is kept.
+-- Time complexity: O(1) - primary key lookup
+UPDATE webhooks
+SET url = @url,
+    events = @events::text[],
+    leaderboard_id = sqlc.narg('leaderboard_id'),
+    enabled = @enabled,
+    updated_at = now()
+WHERE id = @id
+RETURNING id, url, secret, events, leaderboard_id, enabled, created_at, updated_at;
+
+-- name: DeleteWebhook :execrows
+-- Removes a webhook and its deliveries.
+-- Time complexity: O(d) - cascades to the webhook's deliveries
+DELETE FROM webhooks
+WHERE id = $1;
+
+-- name: ListWebhooksForEvent :many
+-- Lists the enabled webhooks subscribed to an event type of a board.
+-- Time complexity: O(w) - webhooks are few
+SELECT id, url, secret, events, leaderboard_id, enabled, created_at, updated_at
+FROM webhooks
+WHERE enabled
+  AND @event_type::text = ANY(events)
+  AND (leaderboard_id IS NULL OR leaderboard_id = @leaderboard_id::text)
+ORDER BY id;
+
+-- name: EnqueueWebhookDelivery :execrows
+-- Queues the delivery of an event to a webhook. Every server derives the same events
+-- from a score change: a delivery already queued for the change is not queued again,
+-- unless the change is replayed.
+-- Time complexity: O(log d) - unique index lookup
+INSERT INTO webhook_deliveries (webhook_id, event_type, change_id, replayed, payload)
+VALUES (@webhook_id, @event_type, @change_id, @replayed, @payload)
+ON CONFLICT (webhook_id, event_type, change_id) WHERE NOT replayed DO NOTHING;
+
+-- name: ClaimWebhookDeliveries :many
+-- Claims due deliveries for this worker by pushing their next attempt lease_seconds
+-- ahead, so a worker that dies mid-delivery only delays them. Concurrent workers
+-- skip each other's rows. Deliveries of disabled webhooks wait until they are enabled again.
+-- Time complexity: O(k log d) - partial index on due deliveries
+UPDATE webhook_deliveries d
+SET next_attempt_at = now() + make_interval(secs => @lease_seconds::float8),
+    updated_at = now()
+FROM webhooks w
+WHERE d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= now()
+      AND webhook_id IN (SELECT id FROM webhooks WHERE enabled)
+    ORDER BY next_attempt_at
+    LIMIT @max_deliveries
+    FOR UPDATE SKIP LOCKED
+)
+AND w.id = d.webhook_id
+RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.attempts, w.url, w.secret;
+
+-- name: CompleteWebhookDelivery :exec
+-- Records an attempt of a delivery: status 'pending' schedules another one at next_attempt_at.
+-- Time complexity: O(1) - primary key lookup
+UPDATE webhook_deliveries
+SET status = @status,
+    attempts = attempts + 1,
+    next_attempt_at = @next_attempt_at,
+    last_status_code = sqlc.narg('last_status_code'),
+    last_error = sqlc.narg('last_error'),
+    updated_at = now()
+WHERE id = @id;
+
+-- name: ListWebhookDeliveries :many
+-- Lists the most recent deliveries of a webhook.
+-- Time complexity: O(k log d) - index range scan
+SELECT id, webhook_id, event_type, change_id, replayed, payload, status, attempts, next_attempt_at,
+       last_status_code, last_error, created_at, updated_at
+FROM webhook_deliveries
+WHERE webhook_id = @webhook_id
+ORDER BY id DESC
+LIMIT @max_deliveries;
+
+-- name: PruneWebhookDeliveries :execrows
+-- Deletes finished deliveries older than retention_seconds.
+-- Time complexity: O(d) - sequential scan, run rarely
+DELETE FROM webhook_deliveries
+WHERE status <> 'pending'
+  AND updated_at < now() - make_interval(secs => @retention_seconds::float8);
+
+-- name: GetBannedPlayer :one
+-- Retrieves the ban of a player.
+-- Time complexity: O(log n) - primary key lookup
+SELECT player_name, reason, banned_at
+FROM banned_players
+WHERE player_name = @player_name;
+
+-- name: ListBannedPlayers :many
+-- Lists every ban, most recent first.
+-- Time complexity: O(n log n) - sorts the bans
+SELECT player_name, reason, banned_at
+FROM banned_players
+ORDER BY banned_at DESC, player_name;
@@ -0,0 +1,162 @@
+// Package bus implements the broadcast bus that shares score changes between
+// server replicas (notify.Bus) over Redis Pub/Sub. The server lifecycle events
+// of every replica are published on a channel of their own, for dashboards.
+//
+// Pub/Sub is fire-and-forget: a replica that is disconnected when a change is
+// published never receives it. The bus reports every reconnection, and the
+// notify.Relay resyncs its consumers then, as it does after a LISTEN reconnect.
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/events"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/notify"
+)
+
+// Default Pub/Sub channels
+const (
+	DefaultChannel          = "leaderboard:score-changes" // score changes
+	DefaultLifecycleChannel = "leaderboard:lifecycle"     // lifecycle events
+)
+
+const (
+	// pingInterval is how long the subscription may stay silent before it is
+	// pinged, so a dead connection is detected even when nothing is published
+	pingInterval = 15 * time.Second
+
+	// maxBackoff caps the wait between reconnection attempts
+	maxBackoff = 30 * time.Second
+)
+
+// Redis is a notify.Bus over Redis Pub/Sub
+type Redis struct {
+	client  *redis.Client
+	channel string
+	logger  *zerolog.Logger
+}
+
+var _ notify.Bus = (*Redis)(nil)
+
+// NewRedis returns a bus on the Redis server of url (redis:// or rediss://),
+// publishing on channel. It does not connect until used.
+func NewRedis(url, channel string, logger *zerolog.Logger) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	return &Redis{client: redis.NewClient(opts), channel: channel, logger: logger}, nil
+}
+
+// Publish sends a message to every subscribed replica
+func (r *Redis) Publish(ctx context.Context, payload []byte) error {
+	return r.client.Publish(ctx, r.channel, payload).Err()
+}
+
+// Subscribe receives the messages of the channel until ctx is cancelled,
+// reconnecting with backoff when the connection drops
+func (r *Redis) Subscribe(ctx context.Context) <-chan notify.BusMessage {
+	out := make(chan notify.BusMessage, 100)
+	go r.receive(ctx, out)
+	return out
+}
+
+func (r *Redis) receive(ctx context.Context, out chan<- notify.BusMessage) {
+	defer close(out)
+
+	ps := r.client.Subscribe(ctx, r.channel)
+	defer ps.Close()
+
+	send := func(msg notify.BusMessage) bool {
+		select {
+		case out <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	connected := false
+	backoff := time.Second
+	for {
+		msg, err := ps.ReceiveTimeout(ctx, pingInterval)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// Silent for a while: the Pong, or the failure, shows up on the next receive
+				if err := ps.Ping(ctx); err == nil {
+					continue
+				}
+			}
+			if connected {
+				r.logger.Error().Err(err).Str("channel", r.channel).Msg("broadcast bus subscription lost, will reconnect")
+				if !send(notify.BusMessage{Event: notify.BusDisconnected}) {
+					return
+				}
+				connected = false
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind != "subscribe" {
+				continue
+			}
+			r.logger.Info().Str("channel", r.channel).Msg("subscribed to broadcast bus")
+			connected = true
+			backoff = time.Second
+			if !send(notify.BusMessage{Event: notify.BusSubscribed}) {
+				return
+			}
+		case *redis.Message:
+			if !send(notify.BusMessage{Event: notify.BusPayload, Payload: []byte(m.Payload)}) {
+				return
+			}
+		}
+	}
+}
+
+// RunLifecycle publishes the lifecycle events of the bus subscription on channel,
+// encoded by serializer (nil is JSON), until the subscription is closed. Pub/Sub
+// keeps nothing: events published while no one listens are lost.
+func (r *Redis) RunLifecycle(sub <-chan lifecycle.Event, channel string, serializer events.Serializer) {
+	if channel == "" {
+		channel = DefaultLifecycleChannel
+	}
+	for event := range sub {
+		payload, err := events.MarshalLifecycle(serializer, event)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err = r.client.Publish(ctx, channel, payload).Err()
+			cancel()
+		}
+		if err != nil {
+			r.logger.Error().Err(err).Str("type", string(event.Type)).Str("channel", channel).Msg("❌ failed to publish lifecycle event")
+		}
+	}
+}
+
+// Close closes the connections to Redis
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, `
+db_driver: sqlite
+sqlite_path: /tmp/leaderboard-test.db
+max_limit: 200
+default_limit: 20
+notify_gap_timeout: 3s
+cohort_platforms: [iOS, android]
+chat_boards: global,level-1
+admin_token: from-file
+`))
+	t.Setenv("MAX_LIMIT", "150")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DBDriver != DBDriverSQLite || cfg.SQLitePath != "/tmp/leaderboard-test.db" || cfg.DefaultLimit != 20 {
+		t.Errorf("file settings not applied: %+v", cfg)
+	}
+	if cfg.MaxLimit != 150 {
+		t.Errorf("MaxLimit = %d, want 150 from the environment", cfg.MaxLimit)
+	}
+	if cfg.NotifyGapTimeout != 3*time.Second || cfg.WebhookTimeout != 5*time.Second {
+		t.Errorf("durations = %v, %v; want 3s from the file and the 5s default", cfg.NotifyGapTimeout, cfg.WebhookTimeout)
+	}
+	if !slices.Equal(cfg.CohortPlatforms, []string{"ios", "android"}) || !slices.Equal(cfg.ChatBoards, []string{"global", "level-1"}) {
+		t.Errorf("lists = %v, %v", cfg.CohortPlatforms, cfg.ChatBoards)
+	}
+
+	var out strings.Builder
+	if err := cfg.WriteYAML(&out); err != nil {
+		t.Fatalf("WriteYAML: %v", err)
+	}
+	printed := out.String()
+	for _, want := range []string{"max_limit: 150\n", "notify_gap_timeout: 3s\n", "admin_token: REDACTED\n", "leaderboard:xxxxx@localhost"} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("printed config lacks %q:\n%s", want, printed)
+		}
+	}
+	if strings.Contains(printed, "from-file") {
+		t.Errorf("printed config leaks the admin token:\n%s", printed)
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	cases := map[string]string{
+		"unknown setting": "max_limits: 10\n",
+		"nested mapping":  "bus_url:\n  host: redis\n",
+		"invalid value":   "max_limit: 5\ndefault_limit: 10\n",
+		"not a mapping":   "- db_driver\n",
+		"message size":    "grpc_max_recv_msg_size: 1024\n",
+		"streams":         "grpc_max_concurrent_streams: 0\n",
+		"compression":     "grpc_compression: brotli\n",
+		"query timeout":   "db_query_timeout: -1s\n",
+		"db cooldown":     "db_cooldown: 0s\n",
+		"pool size":       "db_max_conns: 10\ndb_min_conns: 20\n",
+		"conn lifetime":   "db_max_conn_lifetime: 0s\n",
+		"subscribers":     "stream_max_subscribers: -1\n",
+		"submit mode":     "submit_mode: later\n",
+		"submit queue":    "submit_mode: async\nsubmit_queue_size: 0\n",
+		"sqlite replica":  "db_driver: sqlite\ndatabase_read_url: postgres://replica/leaderboard\n",
+		"prune schedule":  "prune_schedule: every night\nprune_retention: 720h\n",
+		"prune policy":    "prune_schedule: '@daily'\n",
+		"prune batch":     "prune_batch_size: 0\n",
+		"webhook format":  "webhook_event_format: proto\n",
+		"bus lifecycle":   "bus_url: redis://localhost:6379\nbus_lifecycle_channel: leaderboard:score-changes\n",
+		"kafka format":    "kafka_event_format: json/v2\n",
+		"chat platform":   "chat_webhook_url: https://chat.example.com/hook\nchat_platform: teams\n",
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", writeConfigFile(t, content))
+			if _, err := Load(); err == nil {
+				t.Error("Load succeeded, want an error")
+			}
+		})
+	}
+
+	t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := Load(); err == nil {
+		t.Error("Load with a missing file succeeded, want an error")
+	}
+}
+
+func TestChangedSettings(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "log_level: info\nmax_limit: 100\n"))
+	current, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "log_level: debug\nmax_limit: 100\nchat_boards: [global]\n"))
+	reloaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if changed := current.Changed(reloaded); !slices.Equal(changed, []string{"log_level", "chat_boards"}) {
+		t.Fatalf("Changed = %v", changed)
+	}
+	current.CopySetting("log_level", reloaded)
+	if current.LogLevel != "debug" {
+		t.Errorf("LogLevel = %q after CopySetting", current.LogLevel)
+	}
+	if changed := current.Changed(reloaded); !slices.Equal(changed, []string{"chat_boards"}) {
+		t.Errorf("Changed after CopySetting = %v", changed)
+	}
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// source resolves settings: environment variables first, then the config file
+type source struct {
+	file map[string]string // settings of the config file by yaml key, lists comma-joined
+}
+
+// lookup returns the value of the environment variable key, or else of its
+// config file setting (the key in lower case), "" when neither is set
+func (src source) lookup(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return src.file[strings.ToLower(key)]
+}
+
+// newSource reads the YAML config file at path, with no file settings when path is empty.
+// The file is a flat mapping of the settings named like their environment variables,
+// in lower case; lists may be YAML sequences or comma-separated strings.
+func newSource(path string) (source, error) {
+	if path == "" {
+		return source{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return source{}, fmt.Errorf("CONFIG_FILE: %w", err)
+	}
+
+	var raw map[string]yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+		return source{}, fmt.Errorf("CONFIG_FILE %s: %w", path, err)
+	}
+
+	known := settingKeys()
+	file := make(map[string]string, len(raw))
+	for key, node := range raw {
+		if !known[key] {
+			return source{}, fmt.Errorf("CONFIG_FILE %s: unknown setting %q", path, key)
+		}
+		switch node.Kind {
+		case yaml.ScalarNode:
+			if node.Tag != "!!null" {
+				file[key] = node.Value
+			}
+		case yaml.SequenceNode:
+			values := make([]string, len(node.Content))
+			for i, item := range node.Content {
+				if item.Kind != yaml.ScalarNode {
+					return source{}, fmt.Errorf("CONFIG_FILE %s: %s must be a list of values", path, key)
+				}
+				values[i] = item.Value
+			}
+			file[key] = strings.Join(values, ",")
+		default:
+			return source{}, fmt.Errorf("CONFIG_FILE %s: %s must be a value or a list of values", path, key)
+		}
+	}
+	return source{file: file}, nil
+}
+
+// settingKeys returns the yaml keys of the Config fields
+func settingKeys() map[string]bool {
+	t := reflect.TypeOf(Config{})
+	keys := make(map[string]bool, t.NumField())
+	for i := range t.NumField() {
+		keys[t.Field(i).Tag.Get("yaml")] = true
+	}
+	return keys
+}
+
+// Changed returns the yaml keys of the settings that differ between c and other, in field order
+func (c *Config) Changed(other *Config) []string {
+	a, b := reflect.ValueOf(c).Elem(), reflect.ValueOf(other).Elem()
+	var keys []string
+	for i := range a.NumField() {
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			keys = append(keys, a.Type().Field(i).Tag.Get("yaml"))
+		}
+	}
+	return keys
+}
+
+// CopySetting sets the setting of yaml key key of c to its value in from
+func (c *Config) CopySetting(key string, from *Config) {
+	dst, src := reflect.ValueOf(c).Elem(), reflect.ValueOf(from).Elem()
+	for i := range dst.NumField() {
+		if dst.Type().Field(i).Tag.Get("yaml") == key {
+			dst.Field(i).Set(src.Field(i))
+			return
+		}
+	}
+}
+
+// secretSettings are redacted by WriteYAML
+var secretSettings = map[string]bool{
+	"admin_token":        true,
+	"offline_sync_key":   true,
+	"submit_signing_key": true,
+	"daily_seed_key":     true,
+	"receipt_keys":       true,
+	"identity_token":     true,
+	"chat_webhook_url":   true, // the URL path holds the webhook token
+}
+
+// WriteYAML writes the configuration as a config file, in field order, with
+// secrets and URL passwords redacted
+func (c *Config) WriteYAML(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	v := reflect.ValueOf(c).Elem()
+	for i := range v.NumField() {
+		key := v.Type().Field(i).Tag.Get("yaml")
+		var value any = v.Field(i).Interface()
+		switch x := value.(type) {
+		case time.Duration:
+			value = x.String()
+		case string:
+			switch {
+			case x == "":
+			case secretSettings[key]:
+				value = "REDACTED"
+			case key == "database_url" || key == "database_read_url" || key == "bus_url":
+				if u, err := url.Parse(x); err == nil {
+					value = u.Redacted()
+				}
+			}
+		}
+
+		var node yaml.Node
+		if err := node.Encode(value); err != nil {
+			return fmt.Errorf("encode %s: %w", key, err)
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &node)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,201 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// protoSerializer emits the internal proto message as-is (binary)
+type protoSerializer struct{}
+
+func (protoSerializer) Format() string      { return FormatProto }
+func (protoSerializer) ContentType() string { return "application/x-protobuf" }
+
+func (protoSerializer) Marshal(update *pb.LeaderboardUpdate) ([]byte, error) {
+	return proto.Marshal(update)
+}
+
+// MarshalEvent encodes events whose data is a proto message
+func (protoSerializer) MarshalEvent(event Event) ([]byte, error) {
+	m, ok := event.Data.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no proto message", ErrUnsupportedEvent, event.Type)
+	}
+	return proto.Marshal(m)
+}
+
+// EntryV1 is a leaderboard entry in the v1 JSON schema
+type EntryV1 struct {
+	PlayerName     string            `json:"player_name"`
+	Score          int64             `json:"score"`
+	UpdatedAt      string            `json:"updated_at,omitempty"`
+	Tier           string            `json:"tier,omitempty"`
+	AchievedAt     string            `json:"achieved_at,omitempty"`
+	Profile        *ProfileV1        `json:"profile,omitempty"`
+	LeaderboardID  string            `json:"leaderboard_id,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	SecondaryScore int64             `json:"secondary_score,omitempty"`
+}
+
+// ProfileV1 is a player profile in the v1 JSON schema
+type ProfileV1 struct {
+	DisplayName string `json:"display_name,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+// UpdateV1 is a stream update in the v1 JSON schema.
+// Field names and types are frozen; add optional fields only.
+type UpdateV1 struct {
+	SchemaVersion string    `json:"schema_version"`
+	Type          string    `json:"type"` // "snapshot", "upsert", "delete", "tier_change" or "heartbeat"
+	Entries       []EntryV1 `json:"entries,omitempty"`
+	Entry         *EntryV1  `json:"entry,omitempty"`
+	PreviousTier  string    `json:"previous_tier,omitempty"`
+	ServerTime    string    `json:"server_time,omitempty"` // heartbeats only
+}
+
+// ToV1 converts an internal update to the v1 JSON schema
+func ToV1(update *pb.LeaderboardUpdate) UpdateV1 {
+	v := UpdateV1{
+		SchemaVersion: SchemaVersion,
+		Type:          eventType(update.GetKind()),
+		PreviousTier:  update.GetPreviousTier(),
+		ServerTime:    update.GetServerTime(),
+	}
+	if update.GetKind() == pb.LeaderboardUpdate_SNAPSHOT {
+		v.Entries = make([]EntryV1, len(update.GetSnapshot()))
+		for i, e := range update.GetSnapshot() {
+			v.Entries[i] = entryV1(e)
+		}
+	}
+	if update.GetChanged() != nil {
+		e := entryV1(update.GetChanged())
+		v.Entry = &e
+	}
+	return v
+}
+
+func entryV1(e *pb.ScoreEntry) EntryV1 {
+	v := EntryV1{
+		PlayerName:     e.GetPlayerName(),
+		Score:          e.GetScore(),
+		UpdatedAt:      e.GetUpdatedAt(),
+		Tier:           e.GetTier(),
+		AchievedAt:     e.GetAchievedAt(),
+		LeaderboardID:  e.GetLeaderboardId(),
+		Metadata:       e.GetMetadata(),
+		SecondaryScore: e.GetSecondaryScore(),
+	}
+	if p := e.GetProfile(); p != nil {
+		v.Profile = &ProfileV1{
+			DisplayName: p.GetDisplayName(),
+			CountryCode: p.GetCountryCode(),
+			AvatarURL:   p.GetAvatarUrl(),
+		}
+	}
+	return v
+}
+
+// eventType maps the proto kind to the stable lowercase event type name
+func eventType(kind pb.LeaderboardUpdate_Kind) string {
+	if kind == pb.LeaderboardUpdate_KIND_UNSPECIFIED {
+		return "unknown"
+	}
+	return strings.ToLower(kind.String())
+}
+
+// jsonSerializer emits the versioned JSON schema
+type jsonSerializer struct{}
+
+func (jsonSerializer) Format() string      { return FormatJSON }
+func (jsonSerializer) ContentType() string { return "application/json" }
+
+func (jsonSerializer) Marshal(update *pb.LeaderboardUpdate) ([]byte, error) {
+	return json.Marshal(ToV1(update))
+}
+
+// MarshalEvent encodes the event data, the v1 schema of its sink
+func (jsonSerializer) MarshalEvent(event Event) ([]byte, error) {
+	return json.Marshal(event.Data)
+}
+
+// cloudEvent is a CloudEvents 1.0 structured-mode JSON envelope
+type cloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Time            string `json:"time"`
+	DataContentType string `json:"datacontenttype"`
+	Subject         string `json:"subject,omitempty"`
+	Data            any    `json:"data"`
+}
+
+// cloudEventsSerializer wraps the JSON schema in a CloudEvents envelope.
+// The schema version is part of the event type, e.g. "com.yourorg.leaderboard.upsert.v1".
+type cloudEventsSerializer struct {
+	source string
+}
+
+func (cloudEventsSerializer) Format() string      { return FormatCloudEvents }
+func (cloudEventsSerializer) ContentType() string { return "application/cloudevents+json" }
+
+func (c cloudEventsSerializer) Marshal(update *pb.LeaderboardUpdate) ([]byte, error) {
+	id, err := newEventID()
+	if err != nil {
+		return nil, err
+	}
+
+	data := ToV1(update)
+	event := cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              id,
+		Source:          c.source,
+		Type:            fmt.Sprintf("com.yourorg.leaderboard.%s.%s", data.Type, SchemaVersion),
+		Time:            time.Now().UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	if data.Entry != nil {
+		event.Subject = data.Entry.PlayerName
+	}
+	return json.Marshal(event)
+}
+
+// MarshalEvent wraps the event data, the v1 schema of its sink, in an envelope
+// of type "com.yourorg.leaderboard.<type>.v1"
+func (c cloudEventsSerializer) MarshalEvent(event Event) ([]byte, error) {
+	id := event.ID
+	if id == "" {
+		var err error
+		if id, err = newEventID(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              id,
+		Source:          c.source,
+		Type:            fmt.Sprintf("com.yourorg.leaderboard.%s.%s", event.Type, SchemaVersion),
+		Time:            event.Time.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Subject:         event.Subject,
+		Data:            event.Data,
+	})
+}
+
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate event id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+)
+
+// LifecycleSerializer is implemented by serializers that can also encode server
+// lifecycle events. The proto format cannot: lifecycle events have no proto message.
+type LifecycleSerializer interface {
+	MarshalLifecycle(event lifecycle.Event) ([]byte, error)
+}
+
+// MarshalLifecycle encodes a lifecycle event with s, or as JSON when s is nil.
+// Formats without a LifecycleSerializer fail with ErrUnsupportedEvent.
+func MarshalLifecycle(s Serializer, event lifecycle.Event) ([]byte, error) {
+	if s == nil {
+		return jsonSerializer{}.MarshalLifecycle(event)
+	}
+	ls, ok := s.(LifecycleSerializer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s cannot encode lifecycle events", ErrUnsupportedEvent, s.Format())
+	}
+	return ls.MarshalLifecycle(event)
+}
+
+// LifecycleV1 is a lifecycle event in the v1 JSON schema.
+// Field names and types are frozen; add optional fields only.
+type LifecycleV1 struct {
+	SchemaVersion string            `json:"schema_version"`
+	Type          string            `json:"type"` // e.g. "server_started", "daily_rollover"
+	Time          string            `json:"time"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+}
+
+// ToLifecycleV1 converts a lifecycle event to the v1 JSON schema
+func ToLifecycleV1(event lifecycle.Event) LifecycleV1 {
+	return LifecycleV1{
+		SchemaVersion: SchemaVersion,
+		Type:          string(event.Type),
+		Time:          event.Time.UTC().Format(time.RFC3339Nano),
+		Attributes:    event.Attributes,
+	}
+}
+
+func (jsonSerializer) MarshalLifecycle(event lifecycle.Event) ([]byte, error) {
+	return json.Marshal(ToLifecycleV1(event))
+}
+
+// lifecycleCloudEvent is the CloudEvents envelope of a lifecycle event
+type lifecycleCloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Time            string      `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Subject         string      `json:"subject,omitempty"`
+	Data            LifecycleV1 `json:"data"`
+}
+
+// MarshalLifecycle wraps the JSON schema in a CloudEvents envelope of type
+// "com.yourorg.leaderboard.lifecycle.<type>.v1"
+func (c cloudEventsSerializer) MarshalLifecycle(event lifecycle.Event) ([]byte, error) {
+	id, err := newEventID()
+	if err != nil {
+		return nil, err
+	}
+
+	data := ToLifecycleV1(event)
+	return json.Marshal(lifecycleCloudEvent{
+		SpecVersion:     "1.0",
+		ID:              id,
+		Source:          c.source,
+		Type:            fmt.Sprintf("com.yourorg.leaderboard.lifecycle.%s.%s", data.Type, SchemaVersion),
+		Time:            data.Time,
+		DataContentType: "application/json",
+		Subject:         event.Attributes["leaderboard_id"],
+		Data:            data,
+	})
+}
@@ -0,0 +1,145 @@
+// Package events serializes leaderboard stream updates and sink events for
+// consumers that do not speak the internal gRPC proto (SSE, webhooks, message
+// buses, chat).
+//
+// The proto messages evolve with the API; the JSON and CloudEvents formats are
+// versioned wire contracts so downstream consumers can pin a schema version.
+// Every sink picks its format by name from a Registry, e.g. "cloudevents/v1".
+package events
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+)
+
+// Format names accepted by the default registry
+const (
+	FormatProto       = "proto"
+	FormatJSON        = "json"
+	FormatCloudEvents = "cloudevents"
+)
+
+// SchemaVersion is the current version of the JSON event schema.
+// Bump it (and keep the previous serializer registered) on breaking changes.
+const SchemaVersion = "v1"
+
+var (
+	ErrUnknownFormat        = errors.New("unknown event format")
+	ErrUnknownSchemaVersion = errors.New("unknown event schema version")
+
+	// ErrUnsupportedEvent is returned by a serializer that cannot encode an event,
+	// e.g. the proto format for a sink event without a proto message
+	ErrUnsupportedEvent = errors.New("event not supported by the format")
+)
+
+// SinkFormats are the formats of the sink events of webhooks and message buses,
+// whose payloads have no proto message
+var SinkFormats = []string{FormatJSON, FormatCloudEvents}
+
+// Event is an event of a sink, e.g. a webhook event or a submission shipped to
+// a message bus. Data is the sink's own payload, which the JSON format encodes
+// as is: it is the sink's v1 schema.
+type Event struct {
+	ID      string    // stable across retries when the sink has one; CloudEvents generates one otherwise
+	Type    string    // dotted name, e.g. "score.high_score"
+	Time    time.Time // when the event occurred
+	Subject string    // what the event is about, e.g. a player name; may be empty
+	Data    any
+}
+
+// Serializer encodes leaderboard updates and sink events into a wire format
+type Serializer interface {
+	// Format is the name the serializer is registered under, e.g. "json"
+	Format() string
+	// ContentType is the MIME type of the encoded payload
+	ContentType() string
+	// Marshal encodes a single stream update
+	Marshal(update *pb.LeaderboardUpdate) ([]byte, error)
+	// MarshalEvent encodes a sink event, or returns ErrUnsupportedEvent
+	MarshalEvent(event Event) ([]byte, error)
+}
+
+// ParseFormat splits a format setting such as "cloudevents/v1" into the format
+// name and the schema version it pins, SchemaVersion when it pins none
+func ParseFormat(spec string) (format, version string, err error) {
+	format, version, pinned := strings.Cut(strings.TrimSpace(spec), "/")
+	if !pinned {
+		return format, SchemaVersion, nil
+	}
+	if version != SchemaVersion {
+		return "", "", fmt.Errorf("%w: %q (supported: %s)", ErrUnknownSchemaVersion, version, SchemaVersion)
+	}
+	return format, version, nil
+}
+
+// ValidateSinkFormat checks the format setting of a sink of events (see SinkFormats)
+func ValidateSinkFormat(spec string) error {
+	format, _, err := ParseFormat(spec)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(SinkFormats, format) {
+		return fmt.Errorf("%w: %q (want one of %s)", ErrUnknownFormat, format, strings.Join(SinkFormats, ", "))
+	}
+	return nil
+}
+
+// Registry maps format names to serializers. It is safe for concurrent use.
+type Registry struct {
+	mu          sync.RWMutex
+	serializers map[string]Serializer
+}
+
+// NewRegistry creates a registry with the built-in proto, JSON and CloudEvents formats
+func NewRegistry(source string) *Registry {
+	r := &Registry{serializers: make(map[string]Serializer)}
+	r.Register(protoSerializer{})
+	r.Register(jsonSerializer{})
+	r.Register(cloudEventsSerializer{source: source})
+	return r
+}
+
+// Register adds or replaces a serializer under its format name
+func (r *Registry) Register(s Serializer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.serializers[s.Format()] = s
+}
+
+// Get returns the serializer of a format setting: a format name, optionally
+// pinned to a schema version, e.g. "json" or "json/v1"
+func (r *Registry) Get(spec string) (Serializer, error) {
+	format, _, err := ParseFormat(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.serializers[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return s, nil
+}
+
+// Formats returns the registered format names, sorted
+func (r *Registry) Formats() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	formats := make([]string, 0, len(r.serializers))
+	for f := range r.serializers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
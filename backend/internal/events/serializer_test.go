@@ -0,0 +1,198 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRegistryFormats(t *testing.T) {
+	r := NewRegistry("/leaderboard")
+
+	for _, format := range []string{FormatProto, FormatJSON, FormatCloudEvents} {
+		if _, err := r.Get(format); err != nil {
+			t.Errorf("Get(%q) error = %v", format, err)
+		}
+	}
+	if _, err := r.Get("xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Get(xml) error = %v, want ErrUnknownFormat", err)
+	}
+	if s, err := r.Get("cloudevents/v1"); err != nil || s.Format() != FormatCloudEvents {
+		t.Errorf("Get(cloudevents/v1) = %v, %v", s, err)
+	}
+	if _, err := r.Get("json/v2"); !errors.Is(err, ErrUnknownSchemaVersion) {
+		t.Errorf("Get(json/v2) error = %v, want ErrUnknownSchemaVersion", err)
+	}
+
+	for spec, ok := range map[string]bool{"json": true, "cloudevents/v1": true, "proto": false, "json/v0": false, "xml": false} {
+		if err := ValidateSinkFormat(spec); (err == nil) != ok {
+			t.Errorf("ValidateSinkFormat(%q) = %v", spec, err)
+		}
+	}
+}
+
+func TestEventSerializers(t *testing.T) {
+	type payload struct {
+		Type  string `json:"type"`
+		Score int64  `json:"score"`
+	}
+	event := Event{
+		ID:      "42.score.high_score",
+		Type:    "score.high_score",
+		Time:    time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+		Subject: "Alice",
+		Data:    payload{Type: "score.high_score", Score: 500},
+	}
+	r := NewRegistry("/leaderboard")
+
+	// JSON is the sink's own payload
+	s, _ := r.Get(FormatJSON)
+	b, err := s.MarshalEvent(event)
+	if err != nil || string(b) != `{"type":"score.high_score","score":500}` {
+		t.Errorf("json = %s, %v", b, err)
+	}
+
+	s, _ = r.Get(FormatCloudEvents)
+	if b, err = s.MarshalEvent(event); err != nil {
+		t.Fatal(err)
+	}
+	var envelope struct {
+		cloudEvent
+		Data payload `json:"data"`
+	}
+	if err := json.Unmarshal(b, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.ID != event.ID || envelope.Type != "com.yourorg.leaderboard.score.high_score.v1" || envelope.Time != "2025-01-15T10:00:00Z" ||
+		envelope.Subject != "Alice" || envelope.Data.Score != 500 {
+		t.Errorf("unexpected envelope: %+v", envelope)
+	}
+	event.ID = ""
+	if b, err = s.MarshalEvent(event); err != nil || json.Unmarshal(b, &envelope) != nil || envelope.ID == "" {
+		t.Errorf("event without id: %s, %v; want a generated id", b, err)
+	}
+
+	// Only proto messages have a proto encoding
+	s, _ = r.Get(FormatProto)
+	if _, err := s.MarshalEvent(event); !errors.Is(err, ErrUnsupportedEvent) {
+		t.Errorf("proto of a JSON payload: error = %v, want ErrUnsupportedEvent", err)
+	}
+	update := &pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_UPSERT}
+	if b, err := s.MarshalEvent(Event{Type: "upsert", Data: update}); err != nil || len(b) == 0 {
+		t.Errorf("proto of a proto payload: %v", err)
+	}
+}
+
+func TestSerializers(t *testing.T) {
+	update := &pb.LeaderboardUpdate{
+		Kind:         pb.LeaderboardUpdate_TIER_CHANGE,
+		Changed:      &pb.ScoreEntry{PlayerName: "Alice", Score: 500, Tier: "Gold", LeaderboardId: "global"},
+		PreviousTier: "Silver",
+	}
+	r := NewRegistry("/leaderboard")
+
+	t.Run("proto round-trips", func(t *testing.T) {
+		s, _ := r.Get(FormatProto)
+		b, err := s.Marshal(update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got pb.LeaderboardUpdate
+		if err := proto.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(&got, update) {
+			t.Errorf("got %v, want %v", &got, update)
+		}
+	})
+
+	t.Run("json uses v1 schema", func(t *testing.T) {
+		s, _ := r.Get(FormatJSON)
+		b, err := s.Marshal(update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got UpdateV1
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.SchemaVersion != SchemaVersion || got.Type != "tier_change" || got.PreviousTier != "Silver" {
+			t.Errorf("unexpected envelope: %+v", got)
+		}
+		if got.Entry == nil || got.Entry.PlayerName != "Alice" || got.Entry.Tier != "Gold" || got.Entry.LeaderboardID != "global" {
+			t.Errorf("unexpected entry: %+v", got.Entry)
+		}
+	})
+
+	t.Run("cloudevents envelope", func(t *testing.T) {
+		s, _ := r.Get(FormatCloudEvents)
+		b, err := s.Marshal(update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			cloudEvent
+			Data UpdateV1 `json:"data"`
+		}
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.SpecVersion != "1.0" || got.ID == "" || got.Source != "/leaderboard" {
+			t.Errorf("unexpected envelope: %+v", got)
+		}
+		if got.Type != "com.yourorg.leaderboard.tier_change.v1" {
+			t.Errorf("type = %q", got.Type)
+		}
+		if got.Subject != "Alice" || got.Data.Entry == nil {
+			t.Errorf("unexpected subject/data: %+v", got)
+		}
+	})
+}
+
+func TestLifecycleSerializers(t *testing.T) {
+	event := lifecycle.Event{
+		Type:       lifecycle.DailyRollover,
+		Time:       time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+		Attributes: map[string]string{"leaderboard_id": "daily-2025-01-15"},
+	}
+	r := NewRegistry("/leaderboard")
+
+	s, _ := r.Get(FormatProto)
+	if _, err := MarshalLifecycle(s, event); !errors.Is(err, ErrUnsupportedEvent) {
+		t.Errorf("proto: %v, want ErrUnsupportedEvent", err)
+	}
+
+	s, _ = r.Get(FormatJSON)
+	b, err := MarshalLifecycle(s, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nilJSON, _ := MarshalLifecycle(nil, event); string(nilJSON) != string(b) {
+		t.Errorf("nil serializer: %s, want the JSON format %s", nilJSON, b)
+	}
+	var got LifecycleV1
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SchemaVersion != SchemaVersion || got.Type != "daily_rollover" || got.Time != "2025-01-15T00:00:00Z" || got.Attributes["leaderboard_id"] != "daily-2025-01-15" {
+		t.Errorf("unexpected event: %+v", got)
+	}
+
+	s, _ = r.Get(FormatCloudEvents)
+	b, err = MarshalLifecycle(s, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var envelope lifecycleCloudEvent
+	if err := json.Unmarshal(b, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Type != "com.yourorg.leaderboard.lifecycle.daily_rollover.v1" || envelope.Subject != "daily-2025-01-15" || envelope.Data.Type != "daily_rollover" {
+		t.Errorf("unexpected envelope: %+v", envelope)
+	}
+}
@@ -0,0 +1,146 @@
+// Package geoip resolves the country of client addresses from a local
+// IP-to-country database, so scores can be tagged with the region they were
+// submitted from without calling an external service on every write.
+//
+// The database is a CSV file of address ranges, one per line, optionally
+// gzip-compressed (a path ending in ".gz"):
+//
+//	1.0.0.0,1.0.0.255,AU
+//	2001:200::,2001:200:ffff:ffff:ffff:ffff:ffff:ffff,JP
+//
+// which is the format of the free DB-IP "IP to Country Lite" database. Ranges
+// are inclusive, IPv4 or IPv6, and must not overlap. Countries are ISO 3166-1
+// alpha-2 codes; ranges of "ZZ" (unassigned) are skipped. Blank lines and lines
+// starting with '#' are ignored.
+package geoip
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// unknownCountry is the code of unassigned ranges in DB-IP databases
+const unknownCountry = "ZZ"
+
+// DB is an in-memory IP-to-country database. It implements service.GeoResolver
+// and is safe for concurrent use.
+type DB struct {
+	ranges []ipRange // sorted by start, disjoint
+}
+
+type ipRange struct {
+	start, end netip.Addr
+	country    string
+}
+
+// Open loads the database file at path
+func Open(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	db, err := Load(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Load reads a database in the CSV format of the package documentation
+func Load(r io.Reader) (*DB, error) {
+	var ranges []ipRange
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rg, err := parseRange(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if rg.country != unknownCountry {
+			ranges = append(ranges, rg)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(ranges, func(a, b ipRange) int { return a.start.Compare(b.start) })
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].start.Compare(ranges[i-1].end) <= 0 {
+			return nil, fmt.Errorf("range %s-%s overlaps %s-%s",
+				ranges[i].start, ranges[i].end, ranges[i-1].start, ranges[i-1].end)
+		}
+	}
+	return &DB{ranges: ranges}, nil
+}
+
+// parseRange parses a "start_ip,end_ip,country_code" line
+func parseRange(text string) (ipRange, error) {
+	fields := strings.Split(text, ",")
+	if len(fields) != 3 {
+		return ipRange{}, fmt.Errorf("want 3 fields, got %d", len(fields))
+	}
+	for i := range fields {
+		fields[i] = strings.Trim(strings.TrimSpace(fields[i]), `"`)
+	}
+	start, err := netip.ParseAddr(fields[0])
+	if err != nil {
+		return ipRange{}, err
+	}
+	end, err := netip.ParseAddr(fields[1])
+	if err != nil {
+		return ipRange{}, err
+	}
+	start, end = start.Unmap(), end.Unmap()
+	if start.Is4() != end.Is4() || start.Compare(end) > 0 {
+		return ipRange{}, fmt.Errorf("invalid range %s-%s", start, end)
+	}
+	country := strings.ToUpper(fields[2])
+	if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+		return ipRange{}, fmt.Errorf("invalid country code %q", fields[2])
+	}
+	return ipRange{start: start, end: end, country: country}, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country of addr, or ""
+// when addr is in no range of the database
+func (db *DB) Country(addr netip.Addr) string {
+	if !addr.IsValid() {
+		return ""
+	}
+	addr = addr.Unmap()
+	// The last range starting at or before addr is the only one that can hold it
+	i, found := slices.BinarySearchFunc(db.ranges, addr, func(rg ipRange, a netip.Addr) int { return rg.start.Compare(a) })
+	if !found {
+		i--
+	}
+	if i < 0 || db.ranges[i].end.Compare(addr) < 0 {
+		return ""
+	}
+	return db.ranges[i].country
+}
+
+// Len returns the number of ranges of the database
+func (db *DB) Len() int {
+	return len(db.ranges)
+}
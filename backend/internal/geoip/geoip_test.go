@@ -0,0 +1,88 @@
+package geoip
+
+import (
+	"compress/gzip"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDB = `# start,end,country
+1.0.0.0,1.0.0.255,AU
+"2.16.0.0","2.16.0.255","fr"
+10.0.0.0,10.255.255.255,ZZ
+
+2001:200::,2001:200:ffff:ffff:ffff:ffff:ffff:ffff,JP
+1.0.1.0,1.0.3.255,CN
+`
+
+func TestCountry(t *testing.T) {
+	db, err := Load(strings.NewReader(testDB))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if db.Len() != 4 {
+		t.Errorf("Len() = %d, want 4 ranges (ZZ skipped)", db.Len())
+	}
+	tests := map[string]string{
+		"1.0.0.0":          "AU",
+		"1.0.0.255":        "AU",
+		"1.0.2.7":          "CN",
+		"1.0.4.0":          "",
+		"2.16.0.42":        "FR",
+		"::ffff:2.16.0.42": "FR",
+		"10.1.2.3":         "",
+		"0.0.0.1":          "",
+		"2001:200::1":      "JP",
+		"2001:201::":       "",
+		"::1":              "",
+	}
+	for ip, want := range tests {
+		if got := db.Country(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("Country(%s) = %q, want %q", ip, got, want)
+		}
+	}
+	if got := db.Country(netip.Addr{}); got != "" {
+		t.Errorf("Country(zero) = %q, want empty", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, data := range []string{
+		"1.0.0.0,1.0.0.255",
+		"1.0.0.0,1.0.0.255,AUS",
+		"1.0.0.255,1.0.0.0,AU",
+		"1.0.0.0,2001:200::,AU",
+		"not-an-ip,1.0.0.255,AU",
+		"1.0.0.0,1.0.0.255,AU\n1.0.0.128,1.0.1.0,NZ",
+	} {
+		if _, err := Load(strings.NewReader(data)); err == nil {
+			t.Errorf("Load(%q) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestOpenGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.csv.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(testDB))
+	gz.Close()
+	f.Close()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := db.Country(netip.MustParseAddr("1.0.0.1")); got != "AU" {
+		t.Errorf("Country() = %q, want AU", got)
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("Open(missing) succeeded, want an error")
+	}
+}
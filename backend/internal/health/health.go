@@ -0,0 +1,152 @@
+// Package health reports liveness and readiness of the server for
+// Kubernetes probes, over REST and the standard gRPC health service.
+package health
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Status values reported by checks
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// ServiceName is the gRPC service whose serving status follows readiness
+const ServiceName = "leaderboard.v1.LeaderboardService"
+
+// Pinger is implemented by storage backends (store.Repository)
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Check is the result of a single dependency check
+type Check struct {
+	Status string `json:"status" example:"ok"`
+	Error  string `json:"error,omitempty" example:"connection refused"`
+}
+
+// Report is the readiness state of the server
+type Report struct {
+	Status      string           `json:"status" example:"ok"` // ok when every check passes
+	Checks      map[string]Check `json:"checks"`
+	Subscribers int              `json:"subscribers" example:"3"` // connected stream subscribers
+}
+
+// Ready reports whether every check passed
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Checker runs the readiness checks
+type Checker struct {
+	db          Pinger
+	source      notify.Source
+	subscribers func() int
+	timeout     time.Duration
+	logger      *zerolog.Logger
+}
+
+// NewChecker creates a readiness checker. subscribers returns the current
+// number of stream subscribers; timeout bounds the database ping.
+func NewChecker(db Pinger, source notify.Source, subscribers func() int, timeout time.Duration, logger *zerolog.Logger) *Checker {
+	return &Checker{
+		db:          db,
+		source:      source,
+		subscribers: subscribers,
+		timeout:     timeout,
+		logger:      logger,
+	}
+}
+
+// Check pings the database and inspects the change source
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{
+		Status:      StatusOK,
+		Checks:      make(map[string]Check, 2),
+		Subscribers: c.subscribers(),
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	if err := c.db.Ping(pingCtx); err != nil {
+		report.Checks["database"] = Check{Status: StatusFail, Error: err.Error()}
+		report.Status = StatusFail
+	} else {
+		report.Checks["database"] = Check{Status: StatusOK}
+	}
+
+	if c.source.Listening() {
+		report.Checks["notify"] = Check{Status: StatusOK}
+	} else {
+		report.Checks["notify"] = Check{Status: StatusFail, Error: "change source not listening"}
+		report.Status = StatusFail
+	}
+
+	return report
+}
+
+// Run keeps the gRPC health server in sync with readiness every interval until ctx is done.
+// Losing and regaining readiness are published on events as degraded mode transitions.
+func (c *Checker) Run(ctx context.Context, interval time.Duration, srv *health.Server, events *lifecycle.Bus) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		report := c.Check(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		status := healthpb.HealthCheckResponse_NOT_SERVING
+		if report.Ready() {
+			status = healthpb.HealthCheckResponse_SERVING
+		}
+		if status != last {
+			if status == healthpb.HealthCheckResponse_SERVING {
+				c.logger.Info().Msg("💚 server ready")
+				if last != healthpb.HealthCheckResponse_UNKNOWN {
+					events.Publish(lifecycle.DegradedModeExited, nil)
+				}
+			} else {
+				c.logger.Warn().Interface("checks", report.Checks).Msg("server not ready")
+				events.Publish(lifecycle.DegradedModeEntered, failingChecks(report))
+			}
+			srv.SetServingStatus("", status)
+			srv.SetServingStatus(ServiceName, status)
+			last = status
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// failingChecks returns the errors of the failed checks by check name, with
+// "failing" listing their names
+func failingChecks(report Report) map[string]string {
+	attrs := make(map[string]string)
+	var names []string
+	for name, check := range report.Checks {
+		if check.Status != StatusOK {
+			names = append(names, name)
+			attrs[name] = check.Error
+		}
+	}
+	sort.Strings(names)
+	attrs["failing"] = strings.Join(names, ",")
+	return attrs
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/notify"
+)
+
+type fakePinger struct{ err error }
+
+func (p fakePinger) Ping(context.Context) error { return p.err }
+
+type fakeSource struct{ listening bool }
+
+func (s fakeSource) Start(context.Context)              {}
+func (s fakeSource) Stop(context.Context) error         { return nil }
+func (s fakeSource) Done() <-chan struct{}              { return nil }
+func (s fakeSource) Changes() <-chan notify.ScoreChange { return nil }
+func (s fakeSource) Errors() <-chan error               { return nil }
+func (s fakeSource) Listening() bool                    { return s.listening }
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		pingErr    error
+		listening  bool
+		wantReady  bool
+		wantFailed []string
+	}{
+		{name: "all healthy", listening: true, wantReady: true},
+		{name: "database down", pingErr: errors.New("connection refused"), listening: true, wantFailed: []string{"database"}},
+		{name: "listener down", listening: false, wantFailed: []string{"notify"}},
+		{name: "everything down", pingErr: errors.New("timeout"), wantFailed: []string{"database", "notify"}},
+	}
+
+	logger := zerolog.Nop()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(fakePinger{tt.pingErr}, fakeSource{tt.listening}, func() int { return 2 }, time.Second, &logger)
+			report := c.Check(context.Background())
+
+			if report.Ready() != tt.wantReady {
+				t.Errorf("Ready() = %v, want %v (report %+v)", report.Ready(), tt.wantReady, report)
+			}
+			if report.Subscribers != 2 {
+				t.Errorf("Subscribers = %d, want 2", report.Subscribers)
+			}
+			for _, name := range tt.wantFailed {
+				if report.Checks[name].Status != StatusFail {
+					t.Errorf("check %q = %+v, want failed", name, report.Checks[name])
+				}
+			}
+		})
+	}
+}
+
+func TestFailingChecks(t *testing.T) {
+	logger := zerolog.Nop()
+	c := NewChecker(fakePinger{errors.New("timeout")}, fakeSource{false}, func() int { return 0 }, time.Second, &logger)
+
+	attrs := failingChecks(c.Check(context.Background()))
+	if attrs["failing"] != "database,notify" || attrs["database"] != "timeout" || attrs["notify"] == "" {
+		t.Errorf("failingChecks() = %v", attrs)
+	}
+}
@@ -0,0 +1,292 @@
+// Package identity resolves player display data against an external identity
+// service, so the leaderboard can show platform display names and avatars
+// without owning profile data.
+//
+// The service is called over HTTP:
+//
+//	POST <url>
+//	Authorization: Bearer <token>   (when a token is configured)
+//	{"player_ids": ["alice", "bob"]}
+//
+// and answers 200 with the players it knows:
+//
+//	{"players": [{"player_id": "alice", "display_name": "Alice", "avatar_url": "https://..."}]}
+//
+// Results, including unknown players, are cached for a TTL. After consecutive
+// failures a circuit breaker stops calling the service for a cooldown, during
+// which lookups are answered from the cache only.
+package identity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/service"
+)
+
+// ErrCircuitOpen is returned while the circuit breaker skips the identity service
+var ErrCircuitOpen = errors.New("identity service circuit open")
+
+// Defaults of Config fields left zero
+const (
+	DefaultTimeout          = 500 * time.Millisecond
+	DefaultCacheTTL         = 5 * time.Minute
+	DefaultFailureThreshold = 5
+	DefaultCooldown         = 30 * time.Second
+)
+
+// maxResponseSize bounds the response body read from the identity service
+const maxResponseSize = 1 << 20
+
+// Config configures a Client
+type Config struct {
+	URL   string // lookup endpoint
+	Token string // bearer token sent to the service (optional)
+
+	Timeout          time.Duration // per lookup request
+	CacheTTL         time.Duration // how long resolved (and unknown) players are reused
+	FailureThreshold int           // consecutive failures that open the circuit
+	Cooldown         time.Duration // how long the circuit stays open
+}
+
+// Client resolves player identities over HTTP. It implements service.IdentityResolver.
+type Client struct {
+	cfg    Config
+	http   *http.Client
+	logger *zerolog.Logger
+
+	cache   cache
+	breaker breaker
+}
+
+// New returns a client for the identity service at cfg.URL
+func New(cfg Config, logger *zerolog.Logger) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = DefaultCacheTTL
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultFailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultCooldown
+	}
+	return &Client{
+		cfg:     cfg,
+		http:    &http.Client{Timeout: cfg.Timeout},
+		logger:  logger,
+		breaker: breaker{threshold: cfg.FailureThreshold, cooldown: cfg.Cooldown},
+	}
+}
+
+// Resolve returns the identities of the given players known to the service.
+// Cached players are not looked up again. On failure the cached identities are
+// returned along with the error.
+func (c *Client) Resolve(ctx context.Context, playerNames []string) (map[string]service.Identity, error) {
+	now := time.Now()
+	found, missing := c.cache.get(playerNames, now, c.cfg.CacheTTL)
+	if len(missing) == 0 {
+		metrics.IdentityLookups.WithLabelValues("cached").Inc()
+		return found, nil
+	}
+
+	if !c.breaker.allow(now) {
+		metrics.IdentityLookups.WithLabelValues("skipped").Inc()
+		return found, ErrCircuitOpen
+	}
+
+	fetched, err := c.fetch(ctx, missing)
+	if err != nil {
+		if c.breaker.failure(time.Now()) {
+			c.logger.Warn().Err(err).Dur("cooldown", c.cfg.Cooldown).Msg("🔌 identity service circuit opened")
+		}
+		metrics.IdentityLookups.WithLabelValues("error").Inc()
+		return found, err
+	}
+	if c.breaker.success() {
+		c.logger.Info().Msg("🔌 identity service circuit closed")
+	}
+	metrics.IdentityLookups.WithLabelValues("fetched").Inc()
+
+	c.cache.prune(now, c.cfg.CacheTTL)
+	for _, name := range missing {
+		id, ok := fetched[name]
+		if ok {
+			found[name] = id
+			c.cache.put(name, &id, now)
+		} else {
+			c.cache.put(name, nil, now)
+		}
+	}
+	return found, nil
+}
+
+type lookupRequest struct {
+	PlayerIDs []string `json:"player_ids"`
+}
+
+type lookupResponse struct {
+	Players []struct {
+		PlayerID    string `json:"player_id"`
+		DisplayName string `json:"display_name"`
+		AvatarURL   string `json:"avatar_url"`
+	} `json:"players"`
+}
+
+// fetch looks up players in the identity service
+func (c *Client) fetch(ctx context.Context, playerNames []string) (map[string]service.Identity, error) {
+	body, err := json.Marshal(lookupRequest{PlayerIDs: playerNames})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build identity request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("identity request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("identity service returned %s", resp.Status)
+	}
+
+	var out lookupResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode identity response: %w", err)
+	}
+	identities := make(map[string]service.Identity, len(out.Players))
+	for _, p := range out.Players {
+		identities[p.PlayerID] = service.Identity{DisplayName: p.DisplayName, AvatarURL: p.AvatarURL}
+	}
+	return identities, nil
+}
+
+// cache keeps resolved identities; players unknown to the service are cached as nil
+type cache struct {
+	mu        sync.Mutex
+	entries   map[string]cachedIdentity
+	lastPrune time.Time
+}
+
+type cachedIdentity struct {
+	identity  *service.Identity
+	fetchedAt time.Time
+}
+
+// get returns the fresh cached identities among names and the names that must be looked up
+func (c *cache) get(names []string, now time.Time, ttl time.Duration) (map[string]service.Identity, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	found := make(map[string]service.Identity, len(names))
+	var missing []string
+	for _, name := range names {
+		e, ok := c.entries[name]
+		if !ok || now.Sub(e.fetchedAt) >= ttl {
+			missing = append(missing, name)
+			continue
+		}
+		if e.identity != nil {
+			found[name] = *e.identity
+		}
+	}
+	return found, missing
+}
+
+func (c *cache) put(name string, identity *service.Identity, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]cachedIdentity)
+	}
+	c.entries[name] = cachedIdentity{identity: identity, fetchedAt: now}
+}
+
+// prune removes entries older than ttl, at most once per ttl
+func (c *cache) prune(now time.Time, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastPrune) < ttl {
+		return
+	}
+	for name, e := range c.entries {
+		if now.Sub(e.fetchedAt) >= ttl {
+			delete(c.entries, name)
+		}
+	}
+	c.lastPrune = now
+}
+
+// breaker is a consecutive-failure circuit breaker. Once open, it rejects calls
+// until the cooldown has passed, then lets a single probe through: its success
+// closes the circuit, its failure opens it for another cooldown.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a call may be made now
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// success records a successful call and reports whether it closed an open circuit
+func (b *breaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	closed := b.failures >= b.threshold
+	b.failures = 0
+	b.probing = false
+	return closed
+}
+
+// failure records a failed call and reports whether it opened the circuit
+func (b *breaker) failure(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	wasProbing := b.probing
+	b.probing = false
+	if b.failures < b.threshold {
+		return false
+	}
+	b.openUntil = now.Add(b.cooldown)
+	return b.failures == b.threshold || wasProbing
+}
@@ -0,0 +1,118 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestResolveCaches(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want bearer token", got)
+		}
+		var req lookupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		resp := map[string]any{"players": []map[string]string{}}
+		for _, id := range req.PlayerIDs {
+			if id == "alice" {
+				resp["players"] = []map[string]string{{"player_id": "alice", "display_name": "Alice", "avatar_url": "https://cdn.example.com/alice.png"}}
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	logger := zerolog.Nop()
+	c := New(Config{URL: srv.URL, Token: "secret", CacheTTL: time.Hour}, &logger)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		got, err := c.Resolve(ctx, []string{"alice", "bob"})
+		if err != nil {
+			t.Fatalf("Resolve() error = %v", err)
+		}
+		if len(got) != 1 || got["alice"].DisplayName != "Alice" {
+			t.Errorf("Resolve() = %+v, want only alice", got)
+		}
+	}
+	// bob is unknown to the service: the miss is cached too
+	if n := calls.Load(); n != 1 {
+		t.Errorf("identity service called %d times, want 1", n)
+	}
+}
+
+func TestResolveCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"players":[]}`))
+	}))
+	defer srv.Close()
+
+	logger := zerolog.Nop()
+	c := New(Config{URL: srv.URL, FailureThreshold: 2, Cooldown: 50 * time.Millisecond}, &logger)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Resolve(ctx, []string{"alice"}); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Resolve() #%d error = %v, want a service error", i, err)
+		}
+	}
+	if _, err := c.Resolve(ctx, []string{"alice"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Resolve() with open circuit error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("identity service called %d times, want 2", n)
+	}
+
+	// After the cooldown a probe goes through and closes the circuit
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := c.Resolve(ctx, []string{"alice"}); err != nil {
+		t.Fatalf("Resolve() after cooldown error = %v", err)
+	}
+	if _, err := c.Resolve(ctx, []string{"bob"}); err != nil {
+		t.Fatalf("Resolve() with closed circuit error = %v", err)
+	}
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	b := breaker{threshold: 1, cooldown: time.Minute}
+	now := time.Now()
+	if !b.failure(now) {
+		t.Fatal("failure() did not open the circuit")
+	}
+	if b.allow(now) {
+		t.Error("allow() during cooldown = true")
+	}
+	later := now.Add(2 * time.Minute)
+	if !b.allow(later) {
+		t.Fatal("allow() after cooldown = false, want a probe")
+	}
+	if b.allow(later) {
+		t.Error("allow() while probing = true, want a single probe")
+	}
+	if !b.failure(later) {
+		t.Error("failed probe did not reopen the circuit")
+	}
+	if b.allow(later.Add(time.Second)) {
+		t.Error("allow() after failed probe = true")
+	}
+}
@@ -0,0 +1,91 @@
+package integrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/events"
+)
+
+// EventLeaderChanged is the event type of announcements
+const EventLeaderChanged = "leaderboard.leader_changed"
+
+// RegisterFormats registers the Discord and Slack message formats in r, next to
+// the JSON and CloudEvents formats posting Announcement data to generic receivers
+func RegisterFormats(r *events.Registry) {
+	r.Register(chatSerializer{platform: PlatformDiscord})
+	r.Register(chatSerializer{platform: PlatformSlack})
+}
+
+// Announcement is the data of an announcement in the JSON and CloudEvents formats
+type Announcement struct {
+	Type           string           `json:"type"` // EventLeaderChanged
+	LeaderboardID  string           `json:"leaderboard_id"`
+	Leader         AnnouncedLeader  `json:"leader"`
+	PreviousLeader *AnnouncedLeader `json:"previous_leader"` // null when the board was empty
+	Reason         string           `json:"reason"`          // ReasonOvertaken or ReasonRemoved
+}
+
+// AnnouncedLeader is a first place in an Announcement
+type AnnouncedLeader struct {
+	PlayerName string    `json:"player_name"`
+	Score      int64     `json:"score"`
+	AchievedAt time.Time `json:"achieved_at"`
+}
+
+// announcement returns the event of a leader change, whose data is encoded by
+// the chat formats as a message and by the other formats as an Announcement
+func announcement(lc LeaderChange) events.Event {
+	data := Announcement{
+		Type:          EventLeaderChanged,
+		LeaderboardID: lc.LeaderboardID,
+		Leader:        announcedLeader(lc.Leader),
+		Reason:        lc.Reason,
+	}
+	if lc.Previous != nil {
+		previous := announcedLeader(*lc.Previous)
+		data.PreviousLeader = &previous
+	}
+	return events.Event{Type: EventLeaderChanged, Time: time.Now().UTC(), Subject: lc.Leader.PlayerName, Data: leaderChangeData{lc, data}}
+}
+
+func announcedLeader(l Leader) AnnouncedLeader {
+	return AnnouncedLeader{PlayerName: l.PlayerName, Score: l.Score, AchievedAt: l.AchievedAt.UTC()}
+}
+
+// leaderChangeData keeps the leader change for the chat formats; the other
+// formats encode its Announcement
+type leaderChangeData struct {
+	change LeaderChange
+	Announcement
+}
+
+// chatSerializer encodes leader changes as the incoming webhook message of a chat platform
+type chatSerializer struct {
+	platform string
+}
+
+func (c chatSerializer) Format() string    { return c.platform }
+func (chatSerializer) ContentType() string { return "application/json" }
+
+func (c chatSerializer) Marshal(*pb.LeaderboardUpdate) ([]byte, error) {
+	return nil, fmt.Errorf("%w: %s messages announce leader changes only", events.ErrUnsupportedEvent, c.platform)
+}
+
+func (c chatSerializer) MarshalEvent(event events.Event) ([]byte, error) {
+	data, ok := event.Data.(leaderChangeData)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s messages announce leader changes only", events.ErrUnsupportedEvent, c.platform)
+	}
+	text := FormatLeaderChange(data.change, c.platform)
+	if c.platform == PlatformSlack {
+		return json.Marshal(map[string]any{"text": text})
+	}
+	// Player names must not ping anyone
+	return json.Marshal(map[string]any{
+		"content":          text,
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	})
+}
@@ -0,0 +1,258 @@
+// Package integrations announces leaderboard events in chat services.
+//
+// The Announcer follows the score changes of the dispatcher with a Tracker and
+// posts a message to a Discord or Slack incoming webhook whenever a board gets a
+// new first place:
+//
+//	🏆 **Bob** took #1 on **level-42** with 1800, ahead of Alice (1500)
+//
+// Messages are encoded by the serializer of the platform in an events.Registry
+// (see RegisterFormats): the platform may also be a format of the events
+// package, e.g. "cloudevents", to post Announcement data to a generic receiver.
+// Messages are sent by a background goroutine, so a slow chat service never holds
+// up the change pipeline: when its queue is full, new messages are dropped and
+// counted. Every server running an Announcer posts the changes it sees, so it is
+// meant to be enabled on a single server.
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/events"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/notify"
+)
+
+// Chat platforms
+const (
+	PlatformDiscord = "discord"
+	PlatformSlack   = "slack"
+)
+
+// Defaults of Config fields left zero
+const (
+	DefaultTimeout   = 5 * time.Second
+	DefaultQueueSize = 32
+)
+
+// maxRetryAfter bounds how long a rate-limited message waits for its single retry
+const maxRetryAfter = 30 * time.Second
+
+// Config configures an Announcer
+type Config struct {
+	WebhookURL string        // Discord or Slack incoming webhook
+	Platform   string        // PlatformDiscord, PlatformSlack, or a format of Formats
+	Boards     []string      // boards announced; every board when empty
+	Timeout    time.Duration // per message
+	QueueSize  int           // messages waiting to be sent before new ones are dropped
+
+	// Formats encodes the messages of the platform; nil is a registry of the
+	// default formats and the chat platforms
+	Formats *events.Registry
+}
+
+// Announcer posts new board leaders to a chat webhook
+type Announcer struct {
+	cfg     Config
+	target  atomic.Pointer[webhook] // replaced by SetWebhook
+	tracker *Tracker
+	boards  map[string]bool // nil for every board
+	client  *http.Client
+	logger  *zerolog.Logger
+
+	messages chan message
+	done     chan struct{}
+}
+
+// message is a queued announcement
+type message struct {
+	body        []byte
+	contentType string
+}
+
+// NewAnnouncer creates an announcer of the leaders of the boards of st
+func NewAnnouncer(st Store, cfg Config, logger *zerolog.Logger) *Announcer {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.Formats == nil {
+		cfg.Formats = events.NewRegistry("/leaderboard")
+		RegisterFormats(cfg.Formats)
+	}
+	a := &Announcer{
+		cfg:      cfg,
+		tracker:  NewTracker(st),
+		client:   &http.Client{Timeout: cfg.Timeout},
+		logger:   logger,
+		messages: make(chan message, cfg.QueueSize),
+		done:     make(chan struct{}),
+	}
+	a.target.Store(&webhook{url: cfg.WebhookURL, platform: cfg.Platform})
+	if len(cfg.Boards) > 0 {
+		a.boards = make(map[string]bool, len(cfg.Boards))
+		for _, b := range cfg.Boards {
+			a.boards[b] = true
+		}
+	}
+	return a
+}
+
+// webhook is the chat webhook announcements are posted to
+type webhook struct {
+	url      string
+	platform string // format of the messages, see Config.Platform
+}
+
+// SetWebhook replaces the chat webhook, e.g. on a configuration reload.
+// Announcements already queued are posted to the new webhook, in the format
+// of the platform they were queued for.
+func (a *Announcer) SetWebhook(url, platform string) {
+	a.target.Store(&webhook{url: url, platform: platform})
+}
+
+// Run announces the leader changes of changes until the channel is closed, then
+// sends the queued messages
+func (a *Announcer) Run(changes <-chan notify.ScoreChange) {
+	go a.send()
+	a.logger.Info().Str("platform", a.cfg.Platform).Strs("boards", a.cfg.Boards).Msg("📣 chat announcer started")
+
+	// Listed boards are loaded upfront, so their first change can be announced
+	for _, b := range a.cfg.Boards {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := a.tracker.Load(ctx, b); err != nil {
+			a.logger.Error().Err(err).Msg("failed to load board leader")
+		}
+		cancel()
+	}
+
+	for change := range changes {
+		if a.boards != nil && change.LeaderboardID != "" && !a.boards[change.LeaderboardID] {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		lc, err := a.tracker.Apply(ctx, change)
+		cancel()
+		if err != nil {
+			a.logger.Error().Err(err).Msg("failed to track board leader")
+			continue
+		}
+		if lc != nil {
+			a.announce(*lc)
+		}
+	}
+	close(a.messages)
+	<-a.done
+	a.logger.Info().Msg("chat announcer stopped")
+}
+
+func (a *Announcer) announce(lc LeaderChange) {
+	msg, err := a.message(lc)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("failed to encode chat message")
+		return
+	}
+	select {
+	case a.messages <- msg:
+		a.logger.Debug().Str("leaderboard", lc.LeaderboardID).Str("leader", lc.Leader.PlayerName).Msg("🏆 new leader queued for announcement")
+	default:
+		metrics.ChatNotifications.WithLabelValues("dropped").Inc()
+		a.logger.Warn().Str("leaderboard", lc.LeaderboardID).Msg("⚠️  chat queue full, announcement dropped")
+	}
+}
+
+// message returns the webhook message announcing lc, in the format of the platform
+func (a *Announcer) message(lc LeaderChange) (message, error) {
+	serializer, err := a.cfg.Formats.Get(a.target.Load().platform)
+	if err != nil {
+		return message{}, err
+	}
+	body, err := serializer.MarshalEvent(announcement(lc))
+	if err != nil {
+		return message{}, err
+	}
+	return message{body: body, contentType: serializer.ContentType()}, nil
+}
+
+// FormatLeaderChange returns the chat text of a leader change, with the bold
+// markup of platform
+func FormatLeaderChange(lc LeaderChange, platform string) string {
+	bold := func(s string) string { return "**" + s + "**" }
+	if platform == PlatformSlack {
+		bold = func(s string) string { return "*" + s + "*" }
+	}
+	leader, board := bold(lc.Leader.PlayerName), bold(lc.LeaderboardID)
+	score := strconv.FormatInt(lc.Leader.Score, 10)
+
+	switch {
+	case lc.Previous == nil:
+		return fmt.Sprintf("🏆 %s is the first #1 on %s with %s", leader, board, score)
+	case lc.Reason == ReasonRemoved:
+		return fmt.Sprintf("🏆 %s is now #1 on %s with %s, after %s lost the first place", leader, board, score, lc.Previous.PlayerName)
+	}
+	return fmt.Sprintf("🏆 %s took #1 on %s with %s, ahead of %s (%d)", leader, board, score, lc.Previous.PlayerName, lc.Previous.Score)
+}
+
+// send posts queued messages until the queue is closed
+func (a *Announcer) send() {
+	defer close(a.done)
+	for msg := range a.messages {
+		err := a.post(msg)
+		var limited rateLimited
+		if errors.As(err, &limited) {
+			// Rate limits are per webhook: wait for the window once, then give up
+			time.Sleep(min(time.Duration(limited), maxRetryAfter))
+			err = a.post(msg)
+		}
+		if err != nil {
+			metrics.ChatNotifications.WithLabelValues("failed").Inc()
+			a.logger.Error().Err(err).Msg("❌ failed to post chat announcement")
+			continue
+		}
+		metrics.ChatNotifications.WithLabelValues("sent").Inc()
+	}
+}
+
+// rateLimited is the error of a 429 answer: how long to wait before retrying
+type rateLimited time.Duration
+
+func (r rateLimited) Error() string {
+	return "rate limited, retry after " + time.Duration(r).String()
+}
+
+func (a *Announcer) post(msg message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.target.Load().url, bytes.NewReader(msg.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", msg.contentType)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		secs, _ := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+		return rateLimited(max(time.Duration(secs*float64(time.Second)), time.Second))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("chat webhook answered %s", resp.Status)
+	}
+	return nil
+}
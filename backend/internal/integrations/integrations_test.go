@@ -0,0 +1,221 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/events"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// fakeStore holds one board, best first, and counts its reads
+type fakeStore struct {
+	top   []store.Score
+	reads int
+}
+
+func (f *fakeStore) GetTopScores(ctx context.Context, arg store.GetTopScoresParams) ([]store.Score, error) {
+	f.reads++
+	return f.top[:min(len(f.top), int(arg.PageSize))], nil
+}
+
+func (f *fakeStore) set(scores ...store.Score) { f.top = scores }
+
+func score(name string, s int64) store.Score {
+	return store.Score{PlayerName: name, Score: s, RankScore: s, AchievedAt: pgtype.Timestamptz{Time: time.Unix(0, 0), Valid: true}}
+}
+
+func change(name string, s int64, op string) notify.ScoreChange {
+	return notify.ScoreChange{LeaderboardID: "global", PlayerName: name, Score: s, RankScore: s, AchievedAt: time.Unix(100, 0), Op: op}
+}
+
+func TestTracker(t *testing.T) {
+	ctx := context.Background()
+	st := &fakeStore{}
+	tr := NewTracker(st)
+	apply := func(c notify.ScoreChange) *LeaderChange {
+		t.Helper()
+		lc, err := tr.Apply(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lc
+	}
+
+	// The first change of a board only loads its leader
+	st.set(score("Alice", 1500), score("Bob", 1200))
+	if lc := apply(change("Bob", 1200, "update")); lc != nil {
+		t.Fatalf("baseline announced %+v", lc)
+	}
+
+	// Changes below the first place, and the leader improving, are silent
+	if lc := apply(change("Carol", 900, "insert")); lc != nil {
+		t.Errorf("runner-up change announced %+v", lc)
+	}
+	if lc := apply(change("Alice", 1600, "update")); lc != nil {
+		t.Errorf("leader improving announced %+v", lc)
+	}
+	if lc := apply(change("Bob", 1600, "update")); lc != nil {
+		t.Errorf("tie with an earlier leader announced %+v", lc)
+	}
+	reads := st.reads
+
+	lc := apply(change("Bob", 1800, "update"))
+	if lc == nil || lc.Leader.PlayerName != "Bob" || lc.Previous.PlayerName != "Alice" || lc.Previous.Score != 1600 || lc.Reason != ReasonOvertaken {
+		t.Fatalf("overtake = %+v, want Bob ahead of Alice", lc)
+	}
+	if st.reads != reads {
+		t.Errorf("overtake read the store %d times", st.reads-reads)
+	}
+
+	// Deleting the leader reads the new one
+	st.set(score("Alice", 1600))
+	lc = apply(change("Bob", 1800, "delete"))
+	if lc == nil || lc.Leader.PlayerName != "Alice" || lc.Reason != ReasonRemoved {
+		t.Fatalf("leader deleted = %+v, want Alice back", lc)
+	}
+	st.set()
+	if lc := apply(change("Alice", 1600, "delete")); lc != nil {
+		t.Errorf("board emptied announced %+v", lc)
+	}
+	lc = apply(change("Dave", 10, "insert"))
+	if lc == nil || lc.Previous != nil {
+		t.Errorf("first score of an empty board = %+v, want no previous leader", lc)
+	}
+
+	// A resync makes the board unknown again; replays are ignored
+	apply(notify.ScoreChange{LeaderboardID: "global", Op: notify.OpResync})
+	st.set(score("Erin", 5000))
+	if lc := apply(change("Erin", 5000, "insert")); lc != nil {
+		t.Errorf("change after resync announced %+v", lc)
+	}
+	replayed := change("Frank", 9000, "insert")
+	replayed.Replayed = true
+	if lc := apply(replayed); lc != nil {
+		t.Errorf("replayed change announced %+v", lc)
+	}
+}
+
+func TestFormatLeaderChange(t *testing.T) {
+	lc := LeaderChange{
+		LeaderboardID: "level-42",
+		Leader:        Leader{PlayerName: "Bob", Score: 1800},
+		Previous:      &Leader{PlayerName: "Alice", Score: 1500},
+		Reason:        ReasonOvertaken,
+	}
+	for platform, want := range map[string]string{
+		PlatformDiscord: "🏆 **Bob** took #1 on **level-42** with 1800, ahead of Alice (1500)",
+		PlatformSlack:   "🏆 *Bob* took #1 on *level-42* with 1800, ahead of Alice (1500)",
+	} {
+		if got := FormatLeaderChange(lc, platform); got != want {
+			t.Errorf("%s: %q, want %q", platform, got, want)
+		}
+	}
+	lc.Reason = ReasonRemoved
+	if got, want := FormatLeaderChange(lc, PlatformDiscord), "🏆 **Bob** is now #1 on **level-42** with 1800, after Alice lost the first place"; got != want {
+		t.Errorf("removed: %q, want %q", got, want)
+	}
+}
+
+func TestAnnouncer(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []map[string]any
+		calls  int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			// Rate limited once: the message is retried after Retry-After
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		var body map[string]any
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	logger := zerolog.Nop()
+	st := &fakeStore{}
+	st.set(score("Alice", 1500))
+	a := NewAnnouncer(st, Config{WebhookURL: srv.URL, Platform: PlatformSlack, Boards: []string{"global"}}, &logger)
+
+	changes := make(chan notify.ScoreChange, 2)
+	changes <- notify.ScoreChange{LeaderboardID: "other", PlayerName: "Zed", Score: 1, Op: "insert"}
+	changes <- change("Bob", 1800, "update")
+	close(changes)
+	a.Run(changes)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 1 {
+		t.Fatalf("%d messages sent, want 1", len(bodies))
+	}
+	if text := bodies[0]["text"]; text != "🏆 *Bob* took #1 on *global* with 1800, ahead of Alice (1500)" {
+		t.Errorf("slack text = %v", text)
+	}
+}
+
+func TestAnnouncementFormats(t *testing.T) {
+	logger := zerolog.Nop()
+	a := NewAnnouncer(&fakeStore{}, Config{Platform: PlatformDiscord}, &logger)
+	lc := LeaderChange{LeaderboardID: "global", Leader: Leader{PlayerName: "Bob", Score: 1800}, Previous: &Leader{PlayerName: "Alice", Score: 1500}, Reason: ReasonOvertaken}
+
+	msg, err := a.message(lc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var discord struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(msg.body, &discord); err != nil || discord.Content != FormatLeaderChange(lc, PlatformDiscord) {
+		t.Errorf("discord message = %s, %v", msg.body, err)
+	}
+
+	// Generic receivers get the announcement as data
+	a.SetWebhook("", events.FormatCloudEvents+"/v1")
+	if msg, err = a.message(lc); err != nil {
+		t.Fatal(err)
+	}
+	var envelope struct {
+		Type    string       `json:"type"`
+		Subject string       `json:"subject"`
+		Data    Announcement `json:"data"`
+	}
+	if err := json.Unmarshal(msg.body, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if msg.contentType != "application/cloudevents+json" || envelope.Type != "com.yourorg.leaderboard."+EventLeaderChanged+".v1" || envelope.Subject != "Bob" ||
+		envelope.Data.Leader.Score != 1800 || envelope.Data.PreviousLeader == nil || envelope.Data.PreviousLeader.PlayerName != "Alice" || envelope.Data.Reason != ReasonOvertaken {
+		t.Errorf("cloudevents message (%s) = %s", msg.contentType, msg.body)
+	}
+
+	a.SetWebhook("", "xml")
+	if _, err := a.message(lc); !errors.Is(err, events.ErrUnknownFormat) {
+		t.Errorf("unknown platform: error = %v", err)
+	}
+
+	// Chat formats have no stream updates
+	r := events.NewRegistry("/leaderboard")
+	RegisterFormats(r)
+	slack, _ := r.Get(PlatformSlack)
+	if _, err := slack.Marshal(nil); !errors.Is(err, events.ErrUnsupportedEvent) {
+		t.Errorf("slack stream update: error = %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// Leader is the first place of a board
+type Leader struct {
+	PlayerName string
+	Score      int64
+	RankScore  int64 // higher is better on every board
+	AchievedAt time.Time
+
+	RankSecondary int64 // tiebreaker of equal rank scores, higher is better
+}
+
+// beats reports whether l ranks ahead of other: higher rank score, then higher
+// rank secondary, then earlier
+func (l Leader) beats(other Leader) bool {
+	if l.RankScore != other.RankScore {
+		return l.RankScore > other.RankScore
+	}
+	if l.RankSecondary != other.RankSecondary {
+		return l.RankSecondary > other.RankSecondary
+	}
+	return l.AchievedAt.Before(other.AchievedAt)
+}
+
+// Reasons of a LeaderChange
+const (
+	ReasonOvertaken = "overtaken" // the new leader beat the previous one
+	ReasonRemoved   = "removed"   // the previous leader was deleted or lowered
+)
+
+// LeaderChange is a new first place of a board
+type LeaderChange struct {
+	LeaderboardID string
+	Leader        Leader
+	Previous      *Leader // nil when the board was empty
+	Reason        string
+}
+
+// boardState is the state of a tracked board:
+//
+//	unknown --first change--> known (baseline loaded, nothing announced)
+//	known   --leader beaten--> known (announced)
+//	known   --leader deleted or lowered--> known (reloaded, announced)
+//	any     --resync--> unknown
+type boardState int
+
+const (
+	stateUnknown boardState = iota // leader not loaded yet, or stale after a resync
+	stateKnown                     // leader followed from the changes
+)
+
+type board struct {
+	state  boardState
+	leader *Leader // nil for an empty board
+}
+
+// Store is the storage read by the Tracker (store.Repository)
+type Store interface {
+	GetTopScores(ctx context.Context, arg store.GetTopScoresParams) ([]store.Score, error)
+}
+
+// Tracker follows the leader of each board from score changes. Leaders are kept
+// up to date from the changes alone; the store is only read when a board is first
+// seen, after a resync, and when its leader is deleted or lowered. A Tracker is not
+// safe for concurrent use.
+type Tracker struct {
+	store  Store
+	boards map[string]*board
+}
+
+// NewTracker creates a tracker reading leaders from st
+func NewTracker(st Store) *Tracker {
+	return &Tracker{store: st, boards: make(map[string]*board)}
+}
+
+// Load reads the leader of a board, so its next change can be announced
+func (t *Tracker) Load(ctx context.Context, leaderboardID string) error {
+	leader, err := t.readLeader(ctx, leaderboardID)
+	if err != nil {
+		return err
+	}
+	t.boards[leaderboardID] = &board{state: stateKnown, leader: leader}
+	return nil
+}
+
+// Apply updates the board of a change and returns its new leader, or nil when
+// the first place did not change hands. Replayed changes are history and ignored.
+func (t *Tracker) Apply(ctx context.Context, change notify.ScoreChange) (*LeaderChange, error) {
+	if change.Replayed {
+		return nil, nil
+	}
+	b := t.boards[change.LeaderboardID]
+	if change.Op == notify.OpResync {
+		if change.LeaderboardID == "" {
+			clear(t.boards)
+		} else if b != nil {
+			b.state = stateUnknown
+		}
+		return nil, nil
+	}
+	if b == nil || b.state == stateUnknown {
+		// The board already holds the change: it only sets the baseline
+		return nil, t.Load(ctx, change.LeaderboardID)
+	}
+
+	entry := Leader{
+		PlayerName: change.PlayerName,
+		Score:      change.Score,
+		RankScore:  change.RankScore,
+		AchievedAt: change.AchievedAt,
+
+		RankSecondary: change.RankSecondary,
+	}
+	switch change.Op {
+	case "insert", "update":
+		switch {
+		case b.leader == nil || (b.leader.PlayerName != entry.PlayerName && entry.beats(*b.leader)):
+			previous := b.leader
+			b.leader = &entry
+			return &LeaderChange{LeaderboardID: change.LeaderboardID, Leader: entry, Previous: previous, Reason: ReasonOvertaken}, nil
+		case b.leader.PlayerName == entry.PlayerName && !b.leader.beats(entry):
+			b.leader = &entry
+			return nil, nil
+		case b.leader.PlayerName == entry.PlayerName:
+			// The leader was lowered: the runner-up may be ahead now
+			return t.reload(ctx, change.LeaderboardID, b)
+		}
+	case "delete":
+		if b.leader != nil && b.leader.PlayerName == entry.PlayerName {
+			return t.reload(ctx, change.LeaderboardID, b)
+		}
+	}
+	return nil, nil
+}
+
+// reload reads the leader of a board whose leader lost its place
+func (t *Tracker) reload(ctx context.Context, leaderboardID string, b *board) (*LeaderChange, error) {
+	previous := b.leader
+	leader, err := t.readLeader(ctx, leaderboardID)
+	if err != nil {
+		b.state = stateUnknown
+		return nil, err
+	}
+	b.leader = leader
+	if leader == nil || (previous != nil && previous.PlayerName == leader.PlayerName) {
+		return nil, nil
+	}
+	return &LeaderChange{LeaderboardID: leaderboardID, Leader: *leader, Previous: previous, Reason: ReasonRemoved}, nil
+}
+
+func (t *Tracker) readLeader(ctx context.Context, leaderboardID string) (*Leader, error) {
+	// The primary: a replica may not have the change yet
+	top, err := t.store.GetTopScores(store.ReadPrimary(ctx), store.GetTopScoresParams{LeaderboardID: leaderboardID, PageSize: 1})
+	if err != nil {
+		return nil, fmt.Errorf("read leader of %s: %w", leaderboardID, err)
+	}
+	if len(top) == 0 {
+		return nil, nil
+	}
+	sc := top[0]
+	return &Leader{PlayerName: sc.PlayerName, Score: sc.Score, RankScore: sc.RankScore, AchievedAt: sc.AchievedAt.Time, RankSecondary: sc.RankSecondary}, nil
+}
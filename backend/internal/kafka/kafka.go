@@ -0,0 +1,335 @@
+// Package kafka ships score submission events to a Kafka topic for data
+// warehousing.
+//
+// Every submission becomes one JSON message keyed by "<leaderboard_id>/<player_name>",
+// so the submissions of a player stay ordered within a partition:
+//
+//	{"type": "score.submitted", "leaderboard_id": "global", "player_name": "alice",
+//	 "submitted_score": 1500, "old_score": 1200, "new_score": 1500, "applied": true,
+//	 "achieved_at": "...", "submitted_at": "...", "request_id": "..."}
+//
+// old_score is null for a player's first submission on a board. With the
+// CloudEvents format (Config.Serializer), that value is the data of a
+// CloudEvents envelope of type "com.yourorg.leaderboard.score.submitted.v1"; the
+// content-type header names the encoding. Events are
+// queued in memory and written in batches by a background goroutine, so a slow
+// or unreachable broker never holds up score submission: when the queue is full,
+// new events are dropped and counted.
+//
+// RunLifecycle writes the server lifecycle events to the same topic, keyed by
+// "lifecycle/<type>" with a type header of "lifecycle.<type>", in the v1
+// lifecycle schema of the events package. They are rare, so each is written on
+// its own, outside the batches.
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/yourorg/leaderboard/internal/events"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/service"
+)
+
+// Message types, in the type header
+const (
+	EventSubmitted       = "score.submitted" // score submission events
+	EventLifecyclePrefix = "lifecycle."      // prefixes the type of lifecycle events
+)
+
+// Defaults of Config fields left zero
+const (
+	DefaultTopic        = "leaderboard.score-submissions"
+	DefaultBatchSize    = 100
+	DefaultBatchTimeout = time.Second
+	DefaultBufferSize   = 10_000
+	DefaultWriteTimeout = 10 * time.Second
+)
+
+// Config configures a Sink
+type Config struct {
+	Brokers []string // bootstrap brokers, host:port
+	Topic   string
+
+	BatchSize    int           // events per write
+	BatchTimeout time.Duration // longest an event waits for its batch to fill
+	BufferSize   int           // events queued in memory before new ones are dropped
+	WriteTimeout time.Duration // per batch write, retries included
+
+	Serializer events.Serializer // encodes the messages (see events.SinkFormats); nil is JSON
+}
+
+// writer is the part of *kafkago.Writer used by Sink
+type writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafkago.Message) error
+	Close() error
+}
+
+// Sink batches score submission events to Kafka. It implements service.SubmissionSink.
+type Sink struct {
+	cfg    Config
+	w      writer
+	logger *zerolog.Logger
+
+	events chan service.SubmissionEvent
+	stop   chan struct{}
+	done   chan struct{}
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+}
+
+var _ service.SubmissionSink = (*Sink)(nil)
+
+// New returns a sink writing to cfg.Topic and starts its batching goroutine
+func New(cfg Config, logger *zerolog.Logger) *Sink {
+	cfg = withDefaults(cfg)
+	w := &kafkago.Writer{
+		Addr:         kafkago.TCP(cfg.Brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafkago.Hash{},
+		BatchSize:    cfg.BatchSize,
+		BatchTimeout: 10 * time.Millisecond, // batches are already assembled by the sink
+		RequiredAcks: kafkago.RequireOne,
+		Compression:  kafkago.Zstd,
+	}
+	return newSink(cfg, w, logger)
+}
+
+func withDefaults(cfg Config) Config {
+	if cfg.Topic == "" {
+		cfg.Topic = DefaultTopic
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = DefaultBatchTimeout
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultBufferSize
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = DefaultWriteTimeout
+	}
+	return cfg
+}
+
+func newSink(cfg Config, w writer, logger *zerolog.Logger) *Sink {
+	s := &Sink{
+		cfg:    cfg,
+		w:      w,
+		logger: logger,
+		events: make(chan service.SubmissionEvent, cfg.BufferSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Submitted queues an event without blocking; it is dropped when the queue is full
+// or the sink is closed
+func (s *Sink) Submitted(event service.SubmissionEvent) {
+	if s.closed.Load() {
+		metrics.SubmissionEvents.WithLabelValues("dropped").Inc()
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		metrics.SubmissionEvents.WithLabelValues("dropped").Inc()
+		s.logger.Warn().Str("player", event.PlayerName).Msg("⚠️  kafka event queue full, dropping submission event")
+	}
+}
+
+// Close writes the queued events, waiting at most until ctx is done, and closes
+// the producer
+func (s *Sink) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		s.closed.Store(true)
+		close(s.stop)
+	})
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		s.logger.Warn().Int("pending", len(s.events)).Msg("kafka sink shutdown timeout, dropping queued events")
+	}
+	return s.w.Close()
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+
+	batch := make([]kafkago.Message, 0, s.cfg.BatchSize)
+	timer := time.NewTimer(s.cfg.BatchTimeout)
+	timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		if len(batch) == 0 {
+			return
+		}
+		s.write(batch)
+		batch = batch[:0]
+	}
+	add := func(event service.SubmissionEvent) bool {
+		msg, err := s.message(event)
+		if err != nil {
+			metrics.SubmissionEvents.WithLabelValues("failed").Inc()
+			s.logger.Error().Err(err).Str("player", event.PlayerName).Msg("❌ failed to encode submission event")
+			return false
+		}
+		batch = append(batch, msg)
+		return true
+	}
+
+	for {
+		select {
+		case event := <-s.events:
+			if !add(event) {
+				continue
+			}
+			if len(batch) == 1 {
+				timer.Reset(s.cfg.BatchTimeout)
+			}
+			if len(batch) >= s.cfg.BatchSize {
+				flush()
+			}
+
+		case <-timer.C:
+			flush()
+
+		case <-s.stop:
+			for {
+				select {
+				case event := <-s.events:
+					if add(event) && len(batch) >= s.cfg.BatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// write sends a batch; the producer retries internally, so a failed batch is dropped
+func (s *Sink) write(batch []kafkago.Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.WriteTimeout)
+	defer cancel()
+
+	if err := s.w.WriteMessages(ctx, batch...); err != nil {
+		metrics.SubmissionEvents.WithLabelValues("failed").Add(float64(len(batch)))
+		s.logger.Error().Err(err).Int("events", len(batch)).Str("topic", s.cfg.Topic).Msg("❌ failed to write submission events to kafka")
+		return
+	}
+	metrics.SubmissionEvents.WithLabelValues("sent").Add(float64(len(batch)))
+	s.logger.Debug().Int("events", len(batch)).Str("topic", s.cfg.Topic).Msg("📤 submission events written to kafka")
+}
+
+// RunLifecycle writes the lifecycle events of the bus subscription until it is
+// closed. Events published after Close are dropped.
+func (s *Sink) RunLifecycle(sub <-chan lifecycle.Event) {
+	for event := range sub {
+		if s.closed.Load() {
+			continue
+		}
+		msg, err := s.lifecycleMessage(event)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.WriteTimeout)
+			err = s.w.WriteMessages(ctx, msg)
+			cancel()
+		}
+		if err != nil {
+			s.logger.Error().Err(err).Str("type", string(event.Type)).Str("topic", s.cfg.Topic).Msg("❌ failed to write lifecycle event to kafka")
+		}
+	}
+}
+
+func (s *Sink) lifecycleMessage(event lifecycle.Event) (kafkago.Message, error) {
+	value, err := events.MarshalLifecycle(s.cfg.Serializer, event)
+	if err != nil {
+		return kafkago.Message{}, err
+	}
+	contentType := "application/json"
+	if s.cfg.Serializer != nil {
+		contentType = s.cfg.Serializer.ContentType()
+	}
+	return kafkago.Message{
+		Key:   []byte("lifecycle/" + string(event.Type)),
+		Value: value,
+		Headers: []kafkago.Header{
+			{Key: "type", Value: []byte(EventLifecyclePrefix + string(event.Type))},
+			{Key: "content-type", Value: []byte(contentType)},
+		},
+	}, nil
+}
+
+// submittedEvent is the JSON value of a score.submitted message
+type submittedEvent struct {
+	Type           string    `json:"type"`
+	LeaderboardID  string    `json:"leaderboard_id"`
+	PlayerName     string    `json:"player_name"`
+	SubmittedScore int64     `json:"submitted_score"`
+	OldScore       *int64    `json:"old_score"` // null for a first submission
+	NewScore       int64     `json:"new_score"`
+	Applied        bool      `json:"applied"`
+	AchievedAt     time.Time `json:"achieved_at"`
+	SubmittedAt    time.Time `json:"submitted_at"`
+	RequestID      string    `json:"request_id,omitempty"`
+	Tenant         string    `json:"tenant,omitempty"`
+}
+
+func (s *Sink) message(e service.SubmissionEvent) (kafkago.Message, error) {
+	v := submittedEvent{
+		Type:           EventSubmitted,
+		LeaderboardID:  e.LeaderboardID,
+		PlayerName:     e.PlayerName,
+		SubmittedScore: e.SubmittedScore,
+		NewScore:       e.NewScore,
+		Applied:        e.Applied,
+		AchievedAt:     e.AchievedAt.UTC(),
+		SubmittedAt:    e.SubmittedAt,
+		RequestID:      e.RequestID,
+		Tenant:         e.Tenant,
+	}
+	if e.HadScore {
+		v.OldScore = &e.OldScore
+	}
+	value, contentType, err := s.encode(v)
+	if err != nil {
+		return kafkago.Message{}, err
+	}
+	return kafkago.Message{
+		Key:   []byte(e.LeaderboardID + "/" + e.PlayerName),
+		Value: value,
+		Headers: []kafkago.Header{
+			{Key: "type", Value: []byte(EventSubmitted)},
+			{Key: "content-type", Value: []byte(contentType)},
+		},
+	}, nil
+}
+
+// encode encodes the value of a message with the configured serializer
+func (s *Sink) encode(v submittedEvent) ([]byte, string, error) {
+	if s.cfg.Serializer == nil {
+		value, err := json.Marshal(v)
+		return value, "application/json", err
+	}
+	value, err := s.cfg.Serializer.MarshalEvent(events.Event{
+		Type:    v.Type,
+		Time:    v.SubmittedAt,
+		Subject: v.PlayerName,
+		Data:    v,
+	})
+	return value, s.cfg.Serializer.ContentType(), err
+}
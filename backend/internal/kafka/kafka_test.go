@@ -0,0 +1,226 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/yourorg/leaderboard/internal/events"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/service"
+)
+
+// fakeWriter records the batches written
+type fakeWriter struct {
+	mu      sync.Mutex
+	batches [][]kafkago.Message
+	block   chan struct{} // when set, writes wait for it to be closed
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafkago.Message) error {
+	if w.block != nil {
+		<-w.block
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batches = append(w.batches, append([]kafkago.Message(nil), msgs...))
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+func (w *fakeWriter) sizes() []int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sizes := make([]int, len(w.batches))
+	for i, b := range w.batches {
+		sizes[i] = len(b)
+	}
+	return sizes
+}
+
+func newTestSink(cfg Config, w writer) *Sink {
+	logger := zerolog.Nop()
+	return newSink(withDefaults(cfg), w, &logger)
+}
+
+func TestSinkBatches(t *testing.T) {
+	w := &fakeWriter{}
+	s := newTestSink(Config{BatchSize: 3, BatchTimeout: time.Hour}, w)
+
+	for i := range 7 {
+		s.Submitted(service.SubmissionEvent{PlayerName: "alice", NewScore: int64(i)})
+	}
+	// Two full batches go out without waiting for the timeout
+	deadline := time.Now().Add(time.Second)
+	for len(w.sizes()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Close flushes the partial batch
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.sizes(); len(got) != 3 || got[0] != 3 || got[1] != 3 || got[2] != 1 {
+		t.Errorf("batch sizes = %v, want [3 3 1]", got)
+	}
+
+	s.Submitted(service.SubmissionEvent{PlayerName: "late"})
+	if got := len(w.sizes()); got != 3 {
+		t.Errorf("event submitted after Close was written (%d batches)", got)
+	}
+}
+
+func TestSinkBatchTimeout(t *testing.T) {
+	w := &fakeWriter{}
+	s := newTestSink(Config{BatchSize: 100, BatchTimeout: 10 * time.Millisecond}, w)
+	defer s.Close(context.Background())
+
+	s.Submitted(service.SubmissionEvent{PlayerName: "alice"})
+	deadline := time.Now().Add(time.Second)
+	for len(w.sizes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := w.sizes(); len(got) != 1 || got[0] != 1 {
+		t.Errorf("batch sizes = %v, want [1] after the batch timeout", got)
+	}
+}
+
+func TestSinkDropsWhenFull(t *testing.T) {
+	w := &fakeWriter{block: make(chan struct{})}
+	s := newTestSink(Config{BatchSize: 1, BufferSize: 2}, w)
+
+	// The first event is picked up and blocks in WriteMessages, two fill the queue,
+	// the rest are dropped instead of blocking the caller
+	done := make(chan struct{})
+	go func() {
+		for range 10 {
+			s.Submitted(service.SubmissionEvent{PlayerName: "alice"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Submitted blocked on a full queue")
+	}
+
+	close(w.block)
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(w.sizes()); got < 1 || got > 3 {
+		t.Errorf("%d events written, want between 1 and 3", got)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	s := &Sink{cfg: withDefaults(Config{})}
+	achieved := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		event   service.SubmissionEvent
+		wantOld any
+	}{
+		{
+			name:    "improvement",
+			event:   service.SubmissionEvent{LeaderboardID: "global", PlayerName: "alice", SubmittedScore: 1500, OldScore: 1200, HadScore: true, NewScore: 1500, Applied: true, AchievedAt: achieved},
+			wantOld: float64(1200),
+		},
+		{
+			name:    "first submission",
+			event:   service.SubmissionEvent{LeaderboardID: "level-1", PlayerName: "bob", SubmittedScore: 10, NewScore: 10, Applied: true, AchievedAt: achieved},
+			wantOld: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := s.message(tt.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key := string(msg.Key); key != tt.event.LeaderboardID+"/"+tt.event.PlayerName {
+				t.Errorf("key = %q", key)
+			}
+			var v map[string]any
+			if err := json.Unmarshal(msg.Value, &v); err != nil {
+				t.Fatal(err)
+			}
+			old, ok := v["old_score"]
+			if v["type"] != EventSubmitted || !ok || old != tt.wantOld || v["new_score"] != float64(tt.event.NewScore) || v["applied"] != true {
+				t.Errorf("value = %s", msg.Value)
+			}
+		})
+	}
+}
+
+func TestMessageCloudEvents(t *testing.T) {
+	serializer, err := events.NewRegistry("/leaderboard").Get(events.FormatCloudEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Sink{cfg: withDefaults(Config{Serializer: serializer})}
+	submitted := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	msg, err := s.message(service.SubmissionEvent{LeaderboardID: "global", PlayerName: "alice", SubmittedScore: 1500, NewScore: 1500, Applied: true, SubmittedAt: submitted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := msg.Headers[1]; h.Key != "content-type" || string(h.Value) != "application/cloudevents+json" {
+		t.Errorf("header = %s: %s", h.Key, h.Value)
+	}
+	var envelope struct {
+		ID      string         `json:"id"`
+		Type    string         `json:"type"`
+		Time    string         `json:"time"`
+		Subject string         `json:"subject"`
+		Data    submittedEvent `json:"data"`
+	}
+	if err := json.Unmarshal(msg.Value, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.ID == "" || envelope.Type != "com.yourorg.leaderboard.score.submitted.v1" || envelope.Time != "2025-01-15T10:30:00Z" ||
+		envelope.Subject != "alice" || envelope.Data.Type != EventSubmitted || envelope.Data.NewScore != 1500 {
+		t.Errorf("value = %s", msg.Value)
+	}
+}
+
+func TestSinkLifecycle(t *testing.T) {
+	w := &fakeWriter{}
+	cloudEvents, _ := events.NewRegistry("/leaderboard").Get(events.FormatCloudEvents)
+	s := newTestSink(Config{Serializer: cloudEvents}, w)
+	defer s.Close(context.Background())
+
+	logger := zerolog.Nop()
+	bus := lifecycle.NewBus(&logger)
+	sub := bus.Subscribe()
+	bus.Publish(lifecycle.MaintenanceOn, map[string]string{"job": "archive"})
+	bus.Close()
+	s.RunLifecycle(sub)
+
+	if got := w.sizes(); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("batch sizes = %v, want one lifecycle message", got)
+	}
+	msg := w.batches[0][0]
+	var envelope struct {
+		Type string             `json:"type"`
+		Data events.LifecycleV1 `json:"data"`
+	}
+	if err := json.Unmarshal(msg.Value, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Key) != "lifecycle/maintenance_on" || envelope.Type != "com.yourorg.leaderboard.lifecycle.maintenance_on.v1" || envelope.Data.Attributes["job"] != "archive" {
+		t.Errorf("message %s = %s", msg.Key, msg.Value)
+	}
+	headers := map[string]string{}
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	if headers["type"] != "lifecycle.maintenance_on" || headers["content-type"] != cloudEvents.ContentType() {
+		t.Errorf("headers = %v", headers)
+	}
+}
@@ -0,0 +1,122 @@
+// Package lifecycle publishes structured server lifecycle events (startup,
+// shutdown, degraded mode, maintenance runs, daily and season rollovers, board
+// restores) to in-process consumers such as webhook and message bus sinks, so
+// downstream services and dashboards can react without scraping logs.
+package lifecycle
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+// Type names a lifecycle event. Names are part of the wire contract of the
+// event formats: add new types, never rename existing ones.
+type Type string
+
+// Lifecycle event types
+const (
+	ServerStarted       Type = "server_started"        // every listener is bound and serving
+	ServerStopping      Type = "server_stopping"       // graceful shutdown began
+	DegradedModeEntered Type = "degraded_mode_entered" // a readiness check started failing
+	DegradedModeExited  Type = "degraded_mode_exited"  // every readiness check passes again
+	DailyRollover       Type = "daily_rollover"        // a new daily challenge board opened
+	LeaderboardRestored Type = "leaderboard_restored"  // an admin replaced a board's scores with a saved state
+	ConfigReloaded      Type = "config_reloaded"       // settings were applied without a restart
+	MaintenanceOn       Type = "maintenance_on"        // an archival run started moving entries off the boards
+	MaintenanceOff      Type = "maintenance_off"       // the archival run ended
+	SeasonRollover      Type = "season_rollover"       // an admin reset a board, starting its next season
+)
+
+// Types lists the lifecycle event types
+var Types = []Type{
+	ServerStarted, ServerStopping, DegradedModeEntered, DegradedModeExited, DailyRollover,
+	LeaderboardRestored, ConfigReloaded, MaintenanceOn, MaintenanceOff, SeasonRollover,
+}
+
+// Event is a lifecycle event
+type Event struct {
+	Type       Type
+	Time       time.Time
+	Attributes map[string]string // type-specific details, e.g. "leaderboard_id"
+}
+
+// subscriberBuffer is the number of events a slow subscriber may lag behind
+// before further events are dropped for it
+const subscriberBuffer = 32
+
+// Bus fans out lifecycle events to subscribers. Unlike score changes, lifecycle
+// events must never hold up the code reporting them (shutdown in particular),
+// so a subscriber that falls behind misses events instead of blocking Publish.
+//
+// A nil *Bus is valid and discards every event.
+type Bus struct {
+	logger *zerolog.Logger
+
+	mu     sync.Mutex
+	subs   []chan Event
+	closed bool
+}
+
+// NewBus creates a lifecycle event bus
+func NewBus(logger *zerolog.Logger) *Bus {
+	return &Bus{logger: logger}
+}
+
+// Subscribe registers a consumer and returns its channel.
+// The channel is closed by Close.
+func (b *Bus) Subscribe() <-chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.subs = append(b.subs, ch)
+	return ch
+}
+
+// Publish stamps an event with the current time and offers it to every subscriber
+func (b *Bus) Publish(typ Type, attrs map[string]string) {
+	if b == nil {
+		return
+	}
+	event := Event{Type: typ, Time: time.Now().UTC(), Attributes: maps.Clone(attrs)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	b.logger.Info().Str("type", string(typ)).Interface("attributes", attrs).Msg("🛰️  lifecycle event")
+	for _, ch := range b.subs {
+		select {
+		case ch <- event:
+			metrics.LifecycleEvents.WithLabelValues(string(typ), "delivered").Inc()
+		default:
+			metrics.LifecycleEvents.WithLabelValues(string(typ), "dropped").Inc()
+			b.logger.Warn().Str("type", string(typ)).Msg("⚠️  lifecycle subscriber full, dropping event")
+		}
+	}
+}
+
+// Close closes every subscriber channel once the last events were published.
+// Later events are discarded.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, ch := range b.subs {
+		close(ch)
+	}
+	b.subs = nil
+}
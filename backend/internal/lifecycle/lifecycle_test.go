@@ -0,0 +1,50 @@
+package lifecycle
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestBus(t *testing.T) {
+	logger := zerolog.Nop()
+	b := NewBus(&logger)
+	first, second := b.Subscribe(), b.Subscribe()
+
+	attrs := map[string]string{"signal": "terminated"}
+	b.Publish(ServerStopping, attrs)
+	attrs["signal"] = "changed" // events keep their own copy
+
+	for _, ch := range []<-chan Event{first, second} {
+		event := <-ch
+		if event.Type != ServerStopping || event.Attributes["signal"] != "terminated" || event.Time.IsZero() {
+			t.Errorf("event = %+v", event)
+		}
+	}
+
+	// A lagging subscriber misses events instead of blocking Publish
+	for range subscriberBuffer + 1 {
+		b.Publish(DegradedModeEntered, nil)
+	}
+	if len(first) != subscriberBuffer {
+		t.Errorf("queued = %d, want %d", len(first), subscriberBuffer)
+	}
+
+	b.Close()
+	b.Publish(ServerStarted, nil) // discarded after Close
+	n := 0
+	for range first {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("drained %d events, want %d", n, subscriberBuffer)
+	}
+	if _, ok := <-b.Subscribe(); ok {
+		t.Error("subscribing to a closed bus returned an open channel")
+	}
+}
+
+func TestNilBus(t *testing.T) {
+	var b *Bus
+	b.Publish(ServerStarted, nil) // must not panic
+}
@@ -0,0 +1,56 @@
+// Package listen opens the TCP listeners of the servers from their configured
+// bind addresses, with an explicit IPv4/IPv6 behavior per address:
+//
+//   - an IPv4 literal ("0.0.0.0:50051", "10.0.0.5:50051") listens on IPv4 only
+//   - an IPv6 literal ("[::]:50051", "[::1]:50051") listens on IPv6 only, so it
+//     can be combined with the IPv4 wildcard on the same port
+//   - an empty host (":50051") is the dual-stack wildcard: IPv6 and IPv4 on
+//     platforms with IPv4-mapped addresses, IPv4 only elsewhere
+//   - a host name listens on the first address it resolves to
+package listen
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// Network returns the network addr is bound with: "tcp4" for IPv4 literals,
+// "tcp6" for IPv6 literals and "tcp" otherwise
+func Network(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return "tcp"
+	}
+	ip, err := netip.ParseAddr(host)
+	switch {
+	case err != nil:
+		return "tcp"
+	case ip.Is4() || ip.Is4In6():
+		return "tcp4"
+	default:
+		return "tcp6"
+	}
+}
+
+// Listen opens a listener per address. If one of them fails, those already
+// opened are closed.
+func Listen(addrs []string) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		ln, err := net.Listen(Network(addr), addr)
+		if err != nil {
+			Close(listeners)
+			return nil, fmt.Errorf("listen on %s: %w", addr, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
+// Close closes every listener, ignoring errors
+func Close(listeners []net.Listener) {
+	for _, ln := range listeners {
+		ln.Close()
+	}
+}
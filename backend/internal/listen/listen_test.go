@@ -0,0 +1,57 @@
+package listen
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestNetwork(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{":50051", "tcp"},
+		{"0.0.0.0:50051", "tcp4"},
+		{"127.0.0.1:50051", "tcp4"},
+		{"[::]:50051", "tcp6"},
+		{"[::1]:50051", "tcp6"},
+		{"[::ffff:10.0.0.1]:50051", "tcp4"},
+		{"localhost:50051", "tcp"},
+	}
+	for _, tt := range tests {
+		if got := Network(tt.addr); got != tt.want {
+			t.Errorf("Network(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestListen(t *testing.T) {
+	listeners, err := Listen([]string{"127.0.0.1:0", "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer Close(listeners)
+	if len(listeners) != 2 {
+		t.Fatalf("Listen() opened %d listeners, want 2", len(listeners))
+	}
+
+	// A busy address fails the whole call and releases the other listeners
+	busy := listeners[0].Addr().String()
+	if _, err := Listen([]string{"127.0.0.1:0", busy}); err == nil {
+		t.Fatalf("Listen() on busy %s succeeded", busy)
+	}
+
+	// IPv6-only and IPv4 listeners can share a port
+	v4, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen tcp4: %v", err)
+	}
+	defer v4.Close()
+	port := v4.Addr().(*net.TCPAddr).Port
+	v6, err := Listen([]string{net.JoinHostPort("::1", strconv.Itoa(port))})
+	if err != nil {
+		t.Skipf("no IPv6 loopback: %v", err)
+	}
+	Close(v6)
+}
@@ -39,6 +39,11 @@ func NewConsole(level string) *Logger {
 	return New(level, &output)
 }
 
+// SetLevel changes the level of every logger, e.g. on a configuration reload
+func SetLevel(level string) {
+	zerolog.SetGlobalLevel(parseLevel(level))
+}
+
 func parseLevel(level string) zerolog.Level {
 	switch strings.ToLower(level) {
 	case "debug":
@@ -0,0 +1,323 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// ArchivePolicy selects the scores the archival job moves to scores_archive
+type ArchivePolicy struct {
+	Retention  time.Duration // archive scores not updated for this long; 0 disables the rule
+	ScoreFloor int64         // archive scores ranked worse than this, in ranking space; 0 disables the rule
+	BatchSize  int32         // scores moved per transaction
+	DryRun     bool          // count the matching scores without moving them
+}
+
+// ArchivedBoard counts the scores of a board archived by a run, by reason
+type ArchivedBoard struct {
+	LeaderboardID string `json:"leaderboard_id" example:"global"`
+	Retention     int64  `json:"retention" example:"1200"`  // not updated within the retention
+	ScoreFloor    int64  `json:"score_floor" example:"300"` // ranked worse than the score floor
+}
+
+// ArchiveReport is the outcome of an archival run
+type ArchiveReport struct {
+	RanAt      string          `json:"ran_at" example:"2025-01-15T03:00:00Z"`
+	DurationMS int64           `json:"duration_ms" example:"1830"`
+	DryRun     bool            `json:"dry_run" example:"false"` // counts are the scores that would have been archived
+	Archived   int64           `json:"archived" example:"1500"`
+	Boards     []ArchivedBoard `json:"boards"`
+	// Error is set when a batch failed; the batches before it stay archived
+	Error string `json:"error,omitempty" example:""`
+}
+
+// ArchiveJob moves stale and low scores off the live boards into
+// scores_archive on a schedule, and keeps the last report
+type ArchiveJob struct {
+	db       store.Archiver
+	policy   ArchivePolicy
+	schedule atomic.Pointer[Schedule] // set by Run, for Preview
+	events   *lifecycle.Bus           // nil discards maintenance_on/off
+	logger   *zerolog.Logger
+
+	mu   sync.Mutex
+	last *ArchiveReport
+}
+
+// NewArchiveJob creates an archival job
+func NewArchiveJob(db store.Archiver, policy ArchivePolicy, logger *zerolog.Logger) *ArchiveJob {
+	return &ArchiveJob{
+		db:     db,
+		policy: policy,
+		logger: logger,
+	}
+}
+
+// SetLifecycle sets the bus receiving maintenance_on and maintenance_off around
+// the runs moving entries (dry runs move none). Call it before Run.
+func (j *ArchiveJob) SetLifecycle(events *lifecycle.Bus) {
+	j.events = events
+}
+
+// Run archives scores at each time of the schedule until ctx is done. A nil
+// schedule disables it.
+func (j *ArchiveJob) Run(ctx context.Context, schedule *Schedule) {
+	if schedule == nil {
+		return
+	}
+	j.schedule.Store(schedule)
+
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			j.logger.Warn().Str("schedule", schedule.String()).Msg("archive schedule never matches, archival disabled")
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		j.RunOnce(ctx)
+	}
+}
+
+// LastReport returns the report of the last run, or nil before the first one
+func (j *ArchiveJob) LastReport() *ArchiveReport {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.last
+}
+
+// RunOnce archives the matching scores in batches of BatchSize until none is
+// left, and records the report. A dry run counts them in a single query.
+func (j *ArchiveJob) RunOnce(ctx context.Context) *ArchiveReport {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	start := time.Now()
+	policy := store.ArchivePolicy{
+		ScoreFloor: j.policy.ScoreFloor,
+		Limit:      j.policy.BatchSize,
+		DryRun:     j.policy.DryRun,
+	}
+	// One cutoff for the whole run, so batches agree on what is stale
+	if j.policy.Retention > 0 {
+		policy.UpdatedBefore = start.Add(-j.policy.Retention)
+	}
+
+	if !policy.DryRun {
+		j.events.Publish(lifecycle.MaintenanceOn, map[string]string{"job": "archive"})
+	}
+
+	var total store.ArchiveResult
+	var err error
+	for {
+		var res store.ArchiveResult
+		if res, err = j.db.ArchiveScores(ctx, policy); err != nil {
+			break
+		}
+		for _, b := range res.Boards {
+			total.Add(b.LeaderboardID, store.ArchiveReasonRetention, b.Retention)
+			total.Add(b.LeaderboardID, store.ArchiveReasonScoreFloor, b.ScoreFloor)
+		}
+		if policy.DryRun || res.Total() == 0 || res.Total() < int64(policy.Limit) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	report := &ArchiveReport{
+		RanAt:      start.UTC().Format(time.RFC3339),
+		DurationMS: time.Since(start).Milliseconds(),
+		DryRun:     policy.DryRun,
+		Archived:   total.Total(),
+		Boards:     make([]ArchivedBoard, len(total.Boards)),
+	}
+	var retention, floor int64
+	for i, b := range total.Boards {
+		report.Boards[i] = ArchivedBoard{LeaderboardID: b.LeaderboardID, Retention: b.Retention, ScoreFloor: b.ScoreFloor}
+		retention += b.Retention
+		floor += b.ScoreFloor
+	}
+
+	if policy.DryRun {
+		metrics.ArchiveCandidates.WithLabelValues(store.ArchiveReasonRetention).Set(float64(retention))
+		metrics.ArchiveCandidates.WithLabelValues(store.ArchiveReasonScoreFloor).Set(float64(floor))
+	} else {
+		metrics.ArchivedScores.WithLabelValues(store.ArchiveReasonRetention).Add(float64(retention))
+		metrics.ArchivedScores.WithLabelValues(store.ArchiveReasonScoreFloor).Add(float64(floor))
+	}
+	if !policy.DryRun {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		j.events.Publish(lifecycle.MaintenanceOff, map[string]string{
+			"job":      "archive",
+			"result":   result,
+			"archived": strconv.FormatInt(report.Archived, 10),
+		})
+	}
+	switch {
+	case err != nil:
+		if ctx.Err() != nil {
+			return report
+		}
+		report.Error = err.Error()
+		metrics.ArchiveRuns.WithLabelValues("error").Inc()
+		j.logger.Error().Err(err).Int64("archived", report.Archived).Msg("archive run failed")
+	case policy.DryRun:
+		metrics.ArchiveRuns.WithLabelValues("dry_run").Inc()
+	default:
+		metrics.ArchiveRuns.WithLabelValues("ok").Inc()
+	}
+
+	j.logger.Info().
+		Bool("dry_run", report.DryRun).
+		Int64("archived", report.Archived).
+		Int64("retention", retention).
+		Int64("score_floor", floor).
+		Int("boards", len(report.Boards)).
+		Int64("duration_ms", report.DurationMS).
+		Msg("🧹 archive run complete")
+
+	j.last = report
+	return report
+}
+
+// Windows of Preview, in days
+const (
+	DefaultPreviewDays = 7 // used by the transports when a request sets none
+	MaxPreviewDays     = 366
+)
+
+// Errors of Preview requests
+var (
+	ErrInvalidPreviewDays = fmt.Errorf("days must be between 1 and %d", MaxPreviewDays)
+	ErrInvalidPageToken   = errors.New("invalid page token") // not issued by Preview
+)
+
+// ExpiringScore is a live score the archival job will move to the archive
+type ExpiringScore struct {
+	LeaderboardID string `json:"leaderboard_id" example:"global"`
+	PlayerName    string `json:"player_name" example:"Alice"`
+	Score         int64  `json:"score" example:"1500"`
+	UpdatedAt     string `json:"updated_at" example:"2024-12-20T18:00:00Z"`
+	Reason        string `json:"reason" example:"retention" enums:"retention,score_floor"`
+	// ExpiresAt is the first run of the schedule archiving the score, or when it
+	// becomes eligible when the job is not scheduled
+	ExpiresAt string `json:"expires_at" example:"2025-01-20T03:00:00Z"`
+}
+
+// ExpirationPreview is a page of the scores expiring within a window
+type ExpirationPreview struct {
+	Entries       []ExpiringScore `json:"entries"`
+	NextPageToken string          `json:"next_page_token,omitempty"` // set when the page is full
+	DryRun        bool            `json:"dry_run" example:"false"`   // the job only counts: nothing is archived
+}
+
+// previewCursor is the position of a page token: the last score of the previous page
+type previewCursor struct {
+	UpdatedAt     int64  `json:"u"` // unix micros
+	LeaderboardID string `json:"b"`
+	PlayerName    string `json:"p"`
+}
+
+// Preview lists the live scores the policy archives within the next days, oldest
+// update first. They are selected like the runs select them: by the retention
+// rule as of the end of the window, and by the score floor. A score improved
+// before it expires is archived later, or not at all.
+func (j *ArchiveJob) Preview(ctx context.Context, days int, limit int32, pageToken string) (*ExpirationPreview, error) {
+	if days < 1 || days > MaxPreviewDays {
+		return nil, ErrInvalidPreviewDays
+	}
+	arg := store.ListArchiveCandidatesParams{
+		Policy: store.ArchivePolicy{ScoreFloor: j.policy.ScoreFloor},
+		Limit:  limit,
+	}
+	now := time.Now()
+	if j.policy.Retention > 0 {
+		arg.Policy.UpdatedBefore = now.AddDate(0, 0, days).Add(-j.policy.Retention)
+	}
+	if pageToken != "" {
+		c, err := decodePreviewCursor(pageToken)
+		if err != nil {
+			return nil, err
+		}
+		arg.After = &store.ArchiveCandidate{UpdatedAt: time.UnixMicro(c.UpdatedAt), LeaderboardID: c.LeaderboardID, PlayerName: c.PlayerName}
+	}
+
+	candidates, err := j.db.ListArchiveCandidates(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	preview := &ExpirationPreview{Entries: make([]ExpiringScore, len(candidates)), DryRun: j.policy.DryRun}
+	for i, c := range candidates {
+		reason, eligible := j.expiry(c, now)
+		preview.Entries[i] = ExpiringScore{
+			LeaderboardID: c.LeaderboardID,
+			PlayerName:    c.PlayerName,
+			Score:         c.Score,
+			UpdatedAt:     c.UpdatedAt.UTC().Format(time.RFC3339),
+			Reason:        reason,
+			ExpiresAt:     j.runAfter(eligible).UTC().Format(time.RFC3339),
+		}
+	}
+	if limit > 0 && len(candidates) == int(limit) {
+		last := candidates[len(candidates)-1]
+		b, _ := json.Marshal(previewCursor{UpdatedAt: last.UpdatedAt.UnixMicro(), LeaderboardID: last.LeaderboardID, PlayerName: last.PlayerName})
+		preview.NextPageToken = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return preview, nil
+}
+
+// expiry returns the reason a candidate is archived for and when it becomes
+// eligible, as RunOnce decides it: retention first, then the score floor
+func (j *ArchiveJob) expiry(c store.ArchiveCandidate, now time.Time) (string, time.Time) {
+	if j.policy.Retention > 0 {
+		stale := c.UpdatedAt.Add(j.policy.Retention)
+		if !stale.After(now) {
+			return store.ArchiveReasonRetention, now
+		}
+		if !c.BelowFloor {
+			return store.ArchiveReasonRetention, stale
+		}
+	}
+	return store.ArchiveReasonScoreFloor, now
+}
+
+// runAfter returns the first run of the schedule strictly after eligible, or
+// eligible when the job is not scheduled
+func (j *ArchiveJob) runAfter(eligible time.Time) time.Time {
+	schedule := j.schedule.Load()
+	if schedule == nil {
+		return eligible
+	}
+	if next := schedule.Next(eligible); !next.IsZero() {
+		return next
+	}
+	return eligible
+}
+
+func decodePreviewCursor(token string) (previewCursor, error) {
+	var c previewCursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.PlayerName == "" {
+		return previewCursor{}, ErrInvalidPageToken
+	}
+	return c, nil
+}
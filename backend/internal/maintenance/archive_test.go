@@ -0,0 +1,202 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// fakeArchiver archives the given batches in turn, then nothing, and lists
+// candidates after the cursor
+type fakeArchiver struct {
+	batches  []store.ArchiveResult
+	err      error // returned once the batches are exhausted
+	policies []store.ArchivePolicy
+
+	candidates []store.ArchiveCandidate // in listing order
+	listed     []store.ListArchiveCandidatesParams
+}
+
+func (f *fakeArchiver) ListArchiveCandidates(ctx context.Context, arg store.ListArchiveCandidatesParams) ([]store.ArchiveCandidate, error) {
+	f.listed = append(f.listed, arg)
+	page := f.candidates
+	if arg.After != nil {
+		for i, c := range f.candidates {
+			if c.PlayerName == arg.After.PlayerName {
+				page = f.candidates[i+1:]
+			}
+		}
+	}
+	return page[:min(len(page), int(arg.Limit))], nil
+}
+
+func (f *fakeArchiver) ArchiveScores(ctx context.Context, policy store.ArchivePolicy) (store.ArchiveResult, error) {
+	f.policies = append(f.policies, policy)
+	if len(f.batches) == 0 {
+		return store.ArchiveResult{}, f.err
+	}
+	res := f.batches[0]
+	f.batches = f.batches[1:]
+	return res, nil
+}
+
+func batch(board string, retention, floor int64) store.ArchiveResult {
+	return store.ArchiveResult{Boards: []store.ArchivedBoard{{LeaderboardID: board, Retention: retention, ScoreFloor: floor}}}
+}
+
+func TestArchiveRunOnce(t *testing.T) {
+	db := &fakeArchiver{batches: []store.ArchiveResult{
+		batch("global", 2, 0),
+		batch("level-1", 1, 1),
+		batch("global", 0, 1), // short batch: nothing left
+		batch("global", 5, 0),
+	}}
+	logger := zerolog.Nop()
+	job := NewArchiveJob(db, ArchivePolicy{Retention: 24 * time.Hour, ScoreFloor: 10, BatchSize: 2}, &logger)
+	bus := lifecycle.NewBus(&logger)
+	sub := bus.Subscribe()
+	job.SetLifecycle(bus)
+
+	if job.LastReport() != nil {
+		t.Fatal("LastReport before the first run, want nil")
+	}
+	report := job.RunOnce(context.Background())
+	if report.Error != "" || report.DryRun || report.Archived != 5 {
+		t.Fatalf("report = %+v, want 5 archived", report)
+	}
+	if len(report.Boards) != 2 || report.Boards[0] != (ArchivedBoard{"global", 2, 1}) || report.Boards[1] != (ArchivedBoard{"level-1", 1, 1}) {
+		t.Errorf("boards = %+v", report.Boards)
+	}
+	if len(db.policies) != 3 {
+		t.Fatalf("%d batches, want 3: the run stops at the first short batch", len(db.policies))
+	}
+	p := db.policies[0]
+	if p.ScoreFloor != 10 || p.Limit != 2 || p.DryRun || time.Since(p.UpdatedBefore) < 24*time.Hour {
+		t.Errorf("policy = %+v", p)
+	}
+	if db.policies[2].UpdatedBefore != p.UpdatedBefore {
+		t.Error("batches of a run use different cutoffs")
+	}
+	if job.LastReport() != report {
+		t.Error("LastReport is not the report of the run")
+	}
+
+	// The run is a maintenance window
+	bus.Close()
+	var published []string
+	for event := range sub {
+		published = append(published, string(event.Type)+":"+event.Attributes["archived"])
+	}
+	if len(published) != 2 || published[0] != "maintenance_on:" || published[1] != "maintenance_off:5" {
+		t.Errorf("lifecycle events = %v, want maintenance_on then maintenance_off with 5 archived", published)
+	}
+}
+
+func TestArchiveRunOnceDryRunAndErrors(t *testing.T) {
+	logger := zerolog.Nop()
+
+	// A dry run counts once and never repeats, whatever the batch size
+	db := &fakeArchiver{batches: []store.ArchiveResult{batch("global", 40, 2), batch("global", 1, 0)}}
+	job := NewArchiveJob(db, ArchivePolicy{ScoreFloor: 10, BatchSize: 1, DryRun: true}, &logger)
+	bus := lifecycle.NewBus(&logger)
+	sub := bus.Subscribe()
+	job.SetLifecycle(bus)
+	report := job.RunOnce(context.Background())
+	if len(sub) != 0 {
+		t.Errorf("dry run published %d lifecycle events, want none: it moves nothing", len(sub))
+	}
+	if !report.DryRun || report.Archived != 42 || len(db.policies) != 1 {
+		t.Errorf("dry run = %+v after %d calls, want 42 counted in 1 call", report, len(db.policies))
+	}
+	if p := db.policies[0]; !p.DryRun || !p.UpdatedBefore.IsZero() {
+		t.Errorf("dry run policy = %+v, want a dry run without retention", p)
+	}
+
+	// A failed batch keeps the count of the batches before it
+	db = &fakeArchiver{batches: []store.ArchiveResult{batch("global", 1, 0)}, err: errors.New("connection reset")}
+	job = NewArchiveJob(db, ArchivePolicy{Retention: time.Hour, BatchSize: 1}, &logger)
+	report = job.RunOnce(context.Background())
+	if report.Error != "connection reset" || report.Archived != 1 {
+		t.Errorf("report = %+v, want 1 archived and the error", report)
+	}
+}
+
+func TestArchiveRunDisabled(t *testing.T) {
+	logger := zerolog.Nop()
+	job := NewArchiveJob(&fakeArchiver{}, ArchivePolicy{BatchSize: 1}, &logger)
+
+	done := make(chan struct{})
+	go func() {
+		job.Run(context.Background(), nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run without a schedule did not return")
+	}
+}
+
+func TestArchivePreview(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	db := &fakeArchiver{candidates: []store.ArchiveCandidate{
+		{LeaderboardID: "global", PlayerName: "Alice", Score: 100, UpdatedAt: now.Add(-40 * day)},                  // stale already
+		{LeaderboardID: "global", PlayerName: "Bob", Score: 5, UpdatedAt: now.Add(-25 * day), BelowFloor: true},    // below the floor
+		{LeaderboardID: "global", PlayerName: "Carol", Score: 50, UpdatedAt: now.Add(-25 * day)},                   // stale in 5 days
+		{LeaderboardID: "level-1", PlayerName: "Dave", Score: 8, UpdatedAt: now.Add(-time.Hour), BelowFloor: true}, // below the floor
+	}}
+	logger := zerolog.Nop()
+	job := NewArchiveJob(db, ArchivePolicy{Retention: 30 * day, ScoreFloor: 10, BatchSize: 100}, &logger)
+	schedule, _ := ParseSchedule("0 3 * * *")
+	job.schedule.Store(schedule)
+	ctx := context.Background()
+
+	page, err := job.Preview(ctx, 7, 3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The retention rule is applied as of the end of the window
+	if p := db.listed[0]; p.Policy.ScoreFloor != 10 || p.After != nil || p.Limit != 3 ||
+		p.Policy.UpdatedBefore.Sub(now.Add(-23*day)).Abs() > time.Minute {
+		t.Errorf("listed %+v, want the policy with a cutoff 23 days ago", p)
+	}
+	if len(page.Entries) != 3 || page.NextPageToken == "" {
+		t.Fatalf("page = %+v, want 3 entries and a next page", page)
+	}
+	nextRun := schedule.Next(now).Format(time.RFC3339)
+	for i, want := range []struct{ player, reason, expires string }{
+		{"Alice", store.ArchiveReasonRetention, nextRun},
+		{"Bob", store.ArchiveReasonScoreFloor, nextRun},
+		{"Carol", store.ArchiveReasonRetention, schedule.Next(now.Add(5 * day)).Format(time.RFC3339)},
+	} {
+		if e := page.Entries[i]; e.PlayerName != want.player || e.Reason != want.reason || e.ExpiresAt != want.expires {
+			t.Errorf("entry %d = %+v, want %s for %s at %s", i, e, want.player, want.reason, want.expires)
+		}
+	}
+
+	page, err = job.Preview(ctx, 7, 3, page.NextPageToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after := db.listed[1].After; after == nil || after.PlayerName != "Carol" || !after.UpdatedAt.Equal(now.Add(-25*day).Truncate(time.Microsecond)) {
+		t.Errorf("second page after %+v, want after Carol", after)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].PlayerName != "Dave" || page.NextPageToken != "" {
+		t.Errorf("last page = %+v, want Dave only", page)
+	}
+
+	if _, err := job.Preview(ctx, 7, 3, "not-a-token"); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("invalid token: %v, want ErrInvalidPageToken", err)
+	}
+	for _, days := range []int{0, MaxPreviewDays + 1} {
+		if _, err := job.Preview(ctx, days, 3, ""); !errors.Is(err, ErrInvalidPreviewDays) {
+			t.Errorf("%d days: %v, want ErrInvalidPreviewDays", days, err)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+// Package maintenance keeps planner statistics fresh and watches table and index
+// health, so query plan drift on a growing leaderboard shows up before it hurts.
+//
+// A Job periodically prunes the device submission log down to the window the
+// device limits read, runs ANALYZE on the application tables, then reads the
+// catalog counters (dead rows, sequential vs index scans, index sizes) and turns
+// them into recommendations surfaced by the admin stats endpoint.
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// Recommendation kinds
+const (
+	KindVacuum      = "vacuum"       // many dead rows: autovacuum is falling behind
+	KindSeqScans    = "seq_scans"    // most scans are sequential on a large table
+	KindIndexBloat  = "index_bloat"  // index much larger than its rows need
+	KindUnusedIndex = "unused_index" // index never scanned
+)
+
+// Thresholds decide when counters turn into recommendations
+type Thresholds struct {
+	MinRows         int64   // tables with fewer live rows are ignored
+	DeadRowRatio    float64 // dead / (live + dead) rows
+	SeqScanRatio    float64 // sequential / all scans since the previous run
+	IndexBloatRatio float64 // estimated wasted fraction of an index
+	MinIndexBytes   int64   // smaller indexes are ignored
+}
+
+// DefaultThresholds are conservative values for the leaderboard tables
+var DefaultThresholds = Thresholds{
+	MinRows:         10_000,
+	DeadRowRatio:    0.2,
+	SeqScanRatio:    0.5,
+	IndexBloatRatio: 0.3,
+	MinIndexBytes:   1 << 20,
+}
+
+// Table is the health of a table at the time of a run
+type Table struct {
+	Name         string  `json:"name" example:"scores"`
+	LiveRows     int64   `json:"live_rows" example:"1200000"`
+	DeadRows     int64   `json:"dead_rows" example:"3400"`
+	DeadRowRatio float64 `json:"dead_row_ratio" example:"0.003"`
+	// Scans since the previous run (since the statistics reset on the first run)
+	SeqScans       int64   `json:"seq_scans" example:"2"`
+	IndexScans     int64   `json:"index_scans" example:"48000"`
+	SeqScanRatio   float64 `json:"seq_scan_ratio" example:"0.00004"`
+	SizeBytes      int64   `json:"size_bytes" example:"183500800"`
+	LastAnalyzedAt string  `json:"last_analyzed_at,omitempty" example:"2025-01-15T10:30:00Z"`
+}
+
+// Index is the health of a btree index at the time of a run
+type Index struct {
+	Name      string `json:"name" example:"idx_scores_leaderboard"`
+	Table     string `json:"table" example:"scores"`
+	SizeBytes int64  `json:"size_bytes" example:"52428800"`
+	Scans     int64  `json:"scans" example:"48000"` // since the statistics reset
+	// EstimatedBloat is the fraction of the index estimated to be wasted space
+	EstimatedBloat float64 `json:"estimated_bloat" example:"0.12"`
+}
+
+// Recommendation is a suggested maintenance action
+type Recommendation struct {
+	Kind   string `json:"kind" example:"vacuum"`
+	Target string `json:"target" example:"scores"`
+	Reason string `json:"reason" example:"24% of rows are dead"`
+	Action string `json:"action" example:"VACUUM (ANALYZE) scores"`
+}
+
+// Report is the outcome of a maintenance run
+type Report struct {
+	RanAt      string `json:"ran_at" example:"2025-01-15T10:30:00Z"`
+	DurationMS int64  `json:"duration_ms" example:"420"`
+	// PrunedSubmissions is the number of device submissions older than the device limit window deleted
+	PrunedSubmissions int64            `json:"pruned_device_submissions" example:"5200"`
+	Analyzed          []string         `json:"analyzed"`
+	Tables            []Table          `json:"tables"`
+	Indexes           []Index          `json:"indexes"`
+	Recommendations   []Recommendation `json:"recommendations"`
+	// Error is set when the run failed or the backend does not expose statistics
+	Error string `json:"error,omitempty" example:""`
+}
+
+// Job runs maintenance and keeps the last report
+type Job struct {
+	db         store.Maintainer
+	thresholds Thresholds
+	logger     *zerolog.Logger
+
+	mu   sync.Mutex
+	prev map[string]store.TableStats // counters of the previous run, for scan deltas
+	last *Report
+}
+
+// NewJob creates a maintenance job
+func NewJob(db store.Maintainer, thresholds Thresholds, logger *zerolog.Logger) *Job {
+	return &Job{
+		db:         db,
+		thresholds: thresholds,
+		logger:     logger,
+	}
+}
+
+// Run runs maintenance every interval until ctx is done. A zero interval disables it.
+func (j *Job) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LastReport returns the report of the last run, or nil before the first one
+func (j *Job) LastReport() *Report {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.last
+}
+
+// RunOnce analyzes the tables, collects statistics and records the report
+func (j *Job) RunOnce(ctx context.Context) *Report {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	start := time.Now()
+	report, err := j.run(ctx)
+	report.RanAt = start.UTC().Format(time.RFC3339)
+	report.DurationMS = time.Since(start).Milliseconds()
+
+	switch {
+	case errors.Is(err, store.ErrNotSupported):
+		report.Error = "table statistics are not available on this storage backend"
+		metrics.MaintenanceRuns.WithLabelValues("partial").Inc()
+	case err != nil:
+		if ctx.Err() != nil {
+			return report
+		}
+		report.Error = err.Error()
+		metrics.MaintenanceRuns.WithLabelValues("error").Inc()
+		j.logger.Error().Err(err).Msg("maintenance run failed")
+	default:
+		metrics.MaintenanceRuns.WithLabelValues("ok").Inc()
+	}
+
+	counts := make(map[string]float64)
+	for _, rec := range report.Recommendations {
+		counts[rec.Kind]++
+		j.logger.Warn().
+			Str("kind", rec.Kind).
+			Str("target", rec.Target).
+			Str("action", rec.Action).
+			Msg(rec.Reason)
+	}
+	for _, kind := range []string{KindVacuum, KindSeqScans, KindIndexBloat, KindUnusedIndex} {
+		metrics.MaintenanceRecommendations.WithLabelValues(kind).Set(counts[kind])
+	}
+
+	j.logger.Info().
+		Strs("analyzed", report.Analyzed).
+		Int64("pruned_device_submissions", report.PrunedSubmissions).
+		Int("recommendations", len(report.Recommendations)).
+		Int64("duration_ms", report.DurationMS).
+		Msg("🧹 maintenance run complete")
+
+	j.last = report
+	return report
+}
+
+// run does the work of RunOnce; the report is never nil
+func (j *Job) run(ctx context.Context) (*Report, error) {
+	report := &Report{
+		Analyzed:        []string{},
+		Tables:          []Table{},
+		Indexes:         []Index{},
+		Recommendations: []Recommendation{},
+	}
+
+	// Before ANALYZE, so the statistics see the pruned log
+	pruned, err := j.db.PruneDeviceSubmissions(ctx, store.DeviceSubmissionWindow.Seconds())
+	if err != nil {
+		return report, fmt.Errorf("prune device submissions: %w", err)
+	}
+	report.PrunedSubmissions = pruned
+
+	analyzed, err := j.db.Analyze(ctx)
+	if err != nil {
+		return report, fmt.Errorf("analyze: %w", err)
+	}
+	if analyzed != nil {
+		report.Analyzed = analyzed
+	}
+
+	tables, err := j.db.TableStats(ctx)
+	if err != nil {
+		return report, err
+	}
+	indexes, err := j.db.IndexStats(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	liveRows := make(map[string]int64, len(tables))
+	prev := make(map[string]store.TableStats, len(tables))
+	for _, t := range tables {
+		table := j.tableReport(t)
+		report.Tables = append(report.Tables, table)
+		report.Recommendations = append(report.Recommendations, j.tableRecommendations(table)...)
+		liveRows[t.Table] = t.LiveRows
+		prev[t.Table] = t
+	}
+	j.prev = prev
+
+	for _, ix := range indexes {
+		index := Index{
+			Name:           ix.Index,
+			Table:          ix.Table,
+			SizeBytes:      ix.SizeBytes,
+			Scans:          ix.Scans,
+			EstimatedBloat: estimateBloat(ix.SizeBytes, ix.Rows, ix.KeyWidth),
+		}
+		report.Indexes = append(report.Indexes, index)
+		report.Recommendations = append(report.Recommendations,
+			j.indexRecommendations(index, ix.Unique, liveRows[ix.Table])...)
+	}
+
+	return report, nil
+}
+
+// tableReport converts table counters, with scan counts relative to the previous run
+func (j *Job) tableReport(t store.TableStats) Table {
+	table := Table{
+		Name:       t.Table,
+		LiveRows:   t.LiveRows,
+		DeadRows:   t.DeadRows,
+		SeqScans:   t.SeqScans,
+		IndexScans: t.IndexScans,
+		SizeBytes:  t.SizeBytes,
+	}
+	// Counters only go backwards when statistics were reset; keep the totals then
+	if p, ok := j.prev[t.Table]; ok && t.SeqScans >= p.SeqScans && t.IndexScans >= p.IndexScans {
+		table.SeqScans -= p.SeqScans
+		table.IndexScans -= p.IndexScans
+	}
+	table.DeadRowRatio = ratio(t.DeadRows, t.LiveRows+t.DeadRows)
+	table.SeqScanRatio = ratio(table.SeqScans, table.SeqScans+table.IndexScans)
+	if !t.LastAnalyzedAt.IsZero() {
+		table.LastAnalyzedAt = t.LastAnalyzedAt.UTC().Format(time.RFC3339)
+	}
+	return table
+}
+
+// tableRecommendations checks a table against the thresholds
+func (j *Job) tableRecommendations(t Table) []Recommendation {
+	if t.LiveRows < j.thresholds.MinRows {
+		return nil
+	}
+
+	var recs []Recommendation
+	if t.DeadRowRatio >= j.thresholds.DeadRowRatio {
+		recs = append(recs, Recommendation{
+			Kind:   KindVacuum,
+			Target: t.Name,
+			Reason: fmt.Sprintf("%.0f%% of rows are dead", t.DeadRowRatio*100),
+			Action: "VACUUM (ANALYZE) " + t.Name + "; consider a lower autovacuum_vacuum_scale_factor on this table",
+		})
+	}
+	if t.SeqScanRatio >= j.thresholds.SeqScanRatio {
+		recs = append(recs, Recommendation{
+			Kind:   KindSeqScans,
+			Target: t.Name,
+			Reason: fmt.Sprintf("%.0f%% of %d recent scans were sequential", t.SeqScanRatio*100, t.SeqScans+t.IndexScans),
+			Action: "check the plans of queries on " + t.Name + " with EXPLAIN ANALYZE and add or fix indexes",
+		})
+	}
+	return recs
+}
+
+// indexRecommendations checks an index against the thresholds
+func (j *Job) indexRecommendations(ix Index, unique bool, tableRows int64) []Recommendation {
+	if ix.SizeBytes < j.thresholds.MinIndexBytes {
+		return nil
+	}
+
+	var recs []Recommendation
+	if ix.EstimatedBloat >= j.thresholds.IndexBloatRatio {
+		recs = append(recs, Recommendation{
+			Kind:   KindIndexBloat,
+			Target: ix.Name,
+			Reason: fmt.Sprintf("an estimated %.0f%% of the index is wasted space", ix.EstimatedBloat*100),
+			Action: "REINDEX INDEX CONCURRENTLY " + ix.Name,
+		})
+	}
+	// Unique indexes enforce constraints even when never scanned
+	if ix.Scans == 0 && !unique && tableRows >= j.thresholds.MinRows {
+		recs = append(recs, Recommendation{
+			Kind:   KindUnusedIndex,
+			Target: ix.Name,
+			Reason: "index was never scanned since statistics were reset",
+			Action: "consider DROP INDEX CONCURRENTLY " + ix.Name,
+		})
+	}
+	return recs
+}
+
+// Btree layout constants used by estimateBloat
+const (
+	pageSize       = 8192
+	pageHeader     = 24 + 16 // page header + btree special space
+	tupleHeader    = 8       // IndexTupleData
+	itemPointer    = 4       // line pointer per tuple
+	leafFillFactor = 0.9     // default btree fillfactor
+)
+
+// estimateBloat estimates the wasted fraction of a btree index from its size, the
+// table row count and the average key width: the expected size is that of a freshly
+// built index with every leaf page filled to the default fillfactor.
+// It returns 0 when the inputs are unknown (no ANALYZE yet).
+func estimateBloat(sizeBytes, rows, keyWidth int64) float64 {
+	if sizeBytes <= 0 || rows <= 0 || keyWidth <= 0 {
+		return 0
+	}
+
+	tuple := tupleHeader + (keyWidth+7)/8*8 + itemPointer // keys are MAXALIGNed
+	perPage := math.Floor(float64(pageSize-pageHeader) * leafFillFactor / float64(tuple))
+	if perPage < 1 {
+		return 0
+	}
+	expected := (math.Ceil(float64(rows)/perPage) + 1) * pageSize // leaves + meta page
+
+	bloat := 1 - expected/float64(sizeBytes)
+	if bloat < 0 {
+		return 0
+	}
+	return math.Round(bloat*1000) / 1000
+}
+
+// ratio returns part/total rounded to 3 decimals, 0 when total is 0
+func ratio(part, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 1000
+}
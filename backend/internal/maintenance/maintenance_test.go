@@ -0,0 +1,165 @@
+package maintenance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+// fakeMaintainer returns canned statistics
+type fakeMaintainer struct {
+	tables  []store.TableStats
+	indexes []store.IndexStats
+
+	pruned       int64
+	pruneSeconds float64 // retention of the last prune
+}
+
+func (f *fakeMaintainer) Analyze(ctx context.Context) ([]string, error) {
+	names := make([]string, len(f.tables))
+	for i, t := range f.tables {
+		names[i] = t.Table
+	}
+	return names, nil
+}
+
+func (f *fakeMaintainer) TableStats(ctx context.Context) ([]store.TableStats, error) {
+	return f.tables, nil
+}
+
+func (f *fakeMaintainer) PruneDeviceSubmissions(ctx context.Context, retentionSeconds float64) (int64, error) {
+	f.pruneSeconds = retentionSeconds
+	return f.pruned, nil
+}
+
+func (f *fakeMaintainer) IndexStats(ctx context.Context) ([]store.IndexStats, error) {
+	return f.indexes, nil
+}
+
+func kinds(recs []Recommendation) map[string]string {
+	m := make(map[string]string, len(recs))
+	for _, r := range recs {
+		m[r.Target] = r.Kind
+	}
+	return m
+}
+
+func TestRunOnceRecommendations(t *testing.T) {
+	db := &fakeMaintainer{
+		tables: []store.TableStats{
+			{Table: "scores", LiveRows: 100_000, DeadRows: 50_000, SeqScans: 10, IndexScans: 1000},
+			{Table: "players", LiveRows: 50_000, SeqScans: 900, IndexScans: 100},
+			{Table: "device_players", LiveRows: 10, DeadRows: 90, SeqScans: 100}, // too small to matter
+		},
+		indexes: []store.IndexStats{
+			// 100k rows of 24-byte keys need ~3.5 MB: 40 MB is mostly bloat
+			{Index: "idx_scores_leaderboard", Table: "scores", SizeBytes: 40 << 20, Scans: 500, Rows: 100_000, KeyWidth: 24},
+			// 50k rows of 8-byte keys fit in 137 leaves + meta
+			{Index: "idx_players_unused", Table: "players", SizeBytes: 138 * 8192, Rows: 50_000, KeyWidth: 8},
+			{Index: "players_pkey", Table: "players", SizeBytes: 138 * 8192, Unique: true, Rows: 50_000, KeyWidth: 8},
+		},
+		pruned: 5200,
+	}
+	logger := zerolog.Nop()
+	job := NewJob(db, DefaultThresholds, &logger)
+
+	report := job.RunOnce(context.Background())
+	if report.Error != "" {
+		t.Fatalf("report error = %q", report.Error)
+	}
+	// The device limits only read the last hour of submissions
+	if report.PrunedSubmissions != 5200 || db.pruneSeconds != 3600 {
+		t.Errorf("pruned %d submissions older than %vs, want 5200 older than 3600s", report.PrunedSubmissions, db.pruneSeconds)
+	}
+	if len(report.Analyzed) != 3 || len(report.Tables) != 3 || len(report.Indexes) != 3 {
+		t.Fatalf("report has %d analyzed, %d tables, %d indexes, want 3 each",
+			len(report.Analyzed), len(report.Tables), len(report.Indexes))
+	}
+
+	want := map[string]string{
+		"scores":                 KindVacuum,
+		"players":                KindSeqScans,
+		"idx_scores_leaderboard": KindIndexBloat,
+		"idx_players_unused":     KindUnusedIndex,
+	}
+	got := kinds(report.Recommendations)
+	if len(got) != len(want) {
+		t.Errorf("recommendations = %+v, want %v", report.Recommendations, want)
+	}
+	for target, kind := range want {
+		if got[target] != kind {
+			t.Errorf("recommendation for %s = %q, want %q", target, got[target], kind)
+		}
+	}
+	if job.LastReport() != report {
+		t.Error("LastReport() is not the report of the last run")
+	}
+}
+
+func TestRunOnceScanDeltas(t *testing.T) {
+	db := &fakeMaintainer{
+		tables: []store.TableStats{{Table: "scores", LiveRows: 100_000, SeqScans: 5000, IndexScans: 100}},
+	}
+	logger := zerolog.Nop()
+	job := NewJob(db, DefaultThresholds, &logger)
+
+	if got := kinds(job.RunOnce(context.Background()).Recommendations); got["scores"] != KindSeqScans {
+		t.Fatalf("first run recommendations = %v, want seq_scans on scores", got)
+	}
+
+	// Since the first run, plans use the index again
+	db.tables[0].SeqScans += 1
+	db.tables[0].IndexScans += 999
+	report := job.RunOnce(context.Background())
+	if tbl := report.Tables[0]; tbl.SeqScans != 1 || tbl.IndexScans != 999 {
+		t.Errorf("scans since previous run = %d seq, %d index, want 1, 999", tbl.SeqScans, tbl.IndexScans)
+	}
+	if len(report.Recommendations) != 0 {
+		t.Errorf("second run recommendations = %+v, want none", report.Recommendations)
+	}
+}
+
+func TestRunOnceSQLite(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+
+	report := NewJob(st, DefaultThresholds, &logger).RunOnce(ctx)
+	if len(report.Analyzed) == 0 {
+		t.Error("no table was analyzed")
+	}
+	if report.Error == "" {
+		t.Error("report error is empty, want statistics reported as unavailable")
+	}
+}
+
+func TestEstimateBloat(t *testing.T) {
+	tests := []struct {
+		name                 string
+		size, rows, keyWidth int64
+		wantMin, wantMax     float64
+	}{
+		{name: "no statistics", size: 1 << 20, rows: 1000, keyWidth: 0, wantMin: 0, wantMax: 0},
+		{name: "empty index", size: 0, rows: 0, keyWidth: 8, wantMin: 0, wantMax: 0},
+		// 16-byte keys: 28 bytes per entry with its line pointer, 262 entries per page, 382 leaves + meta
+		{name: "fresh", size: 383 * 8192, rows: 100_000, keyWidth: 16, wantMin: 0, wantMax: 0.01},
+		{name: "double size", size: 766 * 8192, rows: 100_000, keyWidth: 16, wantMin: 0.49, wantMax: 0.51},
+		{name: "smaller than expected", size: 100 * 8192, rows: 100_000, keyWidth: 16, wantMin: 0, wantMax: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := estimateBloat(tt.size, tt.rows, tt.keyWidth)
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("estimateBloat() = %v, want in [%v, %v]", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
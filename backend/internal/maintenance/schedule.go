@@ -0,0 +1,163 @@
+package maintenance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression, evaluated in UTC
+type Schedule struct {
+	spec                     string
+	minute, hour, dom, month uint64 // bit i set when value i matches
+	dow                      uint64 // 0 is Sunday
+	// domStar and dowStar are set when the field is '*': as in cron, a day
+	// matches either restricted day field when both are restricted
+	domStar, dowStar bool
+}
+
+// scheduleMacros are the named schedules accepted besides five field expressions
+var scheduleMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// cronField is the range of values of a cron field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 7 is Sunday too
+}
+
+// ParseSchedule parses a cron expression: five fields (minute, hour, day of
+// month, month, day of week) of '*', values, ranges (1-5), steps (*/15, 0-30/5)
+// and comma separated lists of those, or one of @hourly, @daily, @midnight,
+// @weekly and @monthly
+func ParseSchedule(spec string) (*Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if macro, ok := scheduleMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("schedule %q: want 5 fields or a macro such as @daily, got %d fields", spec, len(fields))
+	}
+
+	var bits [5]uint64
+	for i, f := range fields {
+		var err error
+		if bits[i], err = parseCronField(f, cronFields[i]); err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", spec, err)
+		}
+	}
+	dow := bits[4]
+	if dow&(1<<7) != 0 {
+		dow = dow&^(1<<7) | 1
+	}
+	return &Schedule{
+		spec:    spec,
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     dow,
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses a comma separated list of a field into its bit set
+func parseCronField(field string, r cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		lo, hi, step := r.min, r.max, 1
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%s: invalid step %q", r.name, stepStr)
+			}
+			step = n
+		}
+		if rng != "*" {
+			first, last, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseCronValue(first, r); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseCronValue(last, r); err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("%s: invalid range %q", r.name, rng)
+				}
+			} else if hasStep {
+				hi = r.max // 5/15 is 5-max/15
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, r cronField) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < r.min || v > r.max {
+		return 0, fmt.Errorf("%s: %q is not between %d and %d", r.name, s, r.min, r.max)
+	}
+	return v, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first minute matching the schedule strictly after t, or the
+// zero time when none does within five years (e.g. 0 0 30 2 *)
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<t.Hour()) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the cron rule: when both day fields are restricted, a day
+// matching either one matches
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,65 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2025, 1, 15, 10, 30, 20, 0, time.UTC)
+
+	for _, tt := range []struct {
+		spec string
+		want string
+	}{
+		{"* * * * *", "2025-01-15T10:31:00Z"},
+		{"*/15 * * * *", "2025-01-15T10:45:00Z"},
+		{"0 3 * * *", "2025-01-16T03:00:00Z"},
+		{"@daily", "2025-01-16T00:00:00Z"},
+		{"@hourly", "2025-01-15T11:00:00Z"},
+		{"@weekly", "2025-01-19T00:00:00Z"},
+		{"@monthly", "2025-02-01T00:00:00Z"},
+		{"30 10 * * *", "2025-01-16T10:30:00Z"}, // strictly after
+		{"0 9-17/4 * * 1-5", "2025-01-15T13:00:00Z"},
+		{"0 0 * * 7", "2025-01-19T00:00:00Z"}, // 7 is Sunday
+		{"0 0 1,20 * *", "2025-01-20T00:00:00Z"},
+		{"0 0 13 * 5", "2025-01-17T00:00:00Z"}, // both day fields restricted: either matches
+		{"0 0 29 2 *", "2028-02-29T00:00:00Z"},
+		{"5/20 * * * *", "2025-01-15T10:45:00Z"},
+	} {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("%q: %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(from).Format(time.RFC3339); got != tt.want {
+			t.Errorf("%q: next = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+
+	s, _ := ParseSchedule("0 0 30 2 *")
+	if next := s.Next(from); !next.IsZero() {
+		t.Errorf("February 30th: next = %v, want none", next)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"@yearly",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("%q: want an error", spec)
+		}
+	}
+}
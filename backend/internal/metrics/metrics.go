@@ -0,0 +1,326 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "leaderboard"
+
+var (
+	// DeviceLimitViolations counts submissions exceeding a per-device limit.
+	// Labels: limit ("accounts" or "rate"), action ("flagged" or "rejected").
+	DeviceLimitViolations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "device_limit_violations_total",
+		Help:      "Score submissions that exceeded a per-device limit.",
+	}, []string{"limit", "action"})
+
+	// DeviceSubmissions counts submissions that carried a device fingerprint.
+	DeviceSubmissions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "device_submissions_total",
+		Help:      "Score submissions that included a device fingerprint.",
+	})
+
+	// TopCacheRequests counts GetTopScores lookups served by the in-memory cache.
+	// Labels: result ("hit" or "miss").
+	TopCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "top_cache_requests_total",
+		Help:      "Top scores lookups within the in-memory cache window.",
+	}, []string{"result"})
+
+	// StreamUpdates counts broadcast updates per stream subscriber.
+	// Labels: result ("sent", "filtered" when outside the subscriber's top-N,
+	// "replayed" when sent again to a resumed stream, or "dropped" when the
+	// subscriber's queue was full).
+	StreamUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_updates_total",
+		Help:      "Leaderboard updates delivered to or filtered out for stream subscribers.",
+	}, []string{"result"})
+
+	// StreamCoalesced counts score changes replaced by a later change of the same
+	// player within the broadcast coalescing window, and never broadcast.
+	StreamCoalesced = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_updates_coalesced_total",
+		Help:      "Score changes merged into a later change of the same player before broadcasting.",
+	})
+
+	// ClientTimestamps counts submissions carrying a client completion timestamp.
+	// Labels: result ("trusted", "future" beyond the skew window, or "expired").
+	ClientTimestamps = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "client_timestamps_total",
+		Help:      "Client-reported completion timestamps by trust decision.",
+	}, []string{"result"})
+
+	// OfflineRuns counts runs received through offline sync batches.
+	// Labels: outcome ("applied", "not_improved", "invalid", "out_of_window", "limited" or "banned").
+	OfflineRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "offline_runs_total",
+		Help:      "Offline runs processed by SyncOfflineScores, by outcome.",
+	}, []string{"outcome"})
+
+	// ImportedScores counts entries of bulk score imports.
+	// Labels: outcome ("applied", "not_improved", "invalid" or "failed").
+	ImportedScores = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "imported_scores_total",
+		Help:      "Entries processed by bulk score imports, by outcome.",
+	}, []string{"outcome"})
+
+	// LeaderboardResets counts confirmed admin resets of a board.
+	// Labels: snapshot ("true" or "false").
+	LeaderboardResets = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "leaderboard_resets_total",
+		Help:      "Leaderboards reset by an admin, by whether a snapshot was taken.",
+	}, []string{"snapshot"})
+
+	// LeaderboardRestores counts confirmed admin restores of a board.
+	// Labels: source ("export" or "snapshot").
+	LeaderboardRestores = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "leaderboard_restores_total",
+		Help:      "Leaderboards restored by an admin, by source.",
+	}, []string{"source"})
+
+	// PlayerErasures counts players whose data an admin erased.
+	PlayerErasures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "player_erasures_total",
+		Help:      "Player data erasures requested by an admin.",
+	})
+
+	// PlayerRenames counts player renames by an admin, by whether the new name
+	// already had scores or a profile to merge with.
+	PlayerRenames = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "player_renames_total",
+		Help:      "Player renames by an admin, by whether they merged into an existing player.",
+	}, []string{"merged"})
+
+	// BannedSubmissions counts submissions rejected because the player is banned,
+	// offline runs included.
+	BannedSubmissions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "banned_submissions_total",
+		Help:      "Score submissions rejected because the player is banned.",
+	})
+
+	// SubmissionSignatures counts signature checks on SubmitScore when signing is enabled.
+	// Labels: result ("valid", "missing", "invalid", "expired" or "replayed"), action ("accepted" or "rejected").
+	SubmissionSignatures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "submission_signatures_total",
+		Help:      "Score submission signature checks, by result and action.",
+	}, []string{"result", "action"})
+
+	// IdentityLookups counts player identity resolutions against the external identity service.
+	// Labels: result ("cached", "fetched", "error", or "skipped" while the circuit is open).
+	IdentityLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "identity_lookups_total",
+		Help:      "Player identity resolutions, by result.",
+	}, []string{"result"})
+
+	// NotifyDuplicates counts score changes delivered twice by the change source and
+	// dropped by the dispatcher.
+	NotifyDuplicates = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notify_duplicates_total",
+		Help:      "Duplicate score change deliveries dropped before reaching consumers.",
+	})
+
+	// BusMessages counts score changes relayed over the broadcast bus between replicas.
+	// Labels: result ("published", "publish_failed", "received" or "invalid").
+	BusMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bus_messages_total",
+		Help:      "Score changes published to or received from the broadcast bus, by result.",
+	}, []string{"result"})
+
+	// WebhookDeliveries counts webhook delivery attempts.
+	// Labels: result ("delivered", "retry" when another attempt is scheduled, or "failed" after the last one).
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts, by result.",
+	}, []string{"result"})
+
+	// ChatNotifications counts Discord/Slack announcements of new board leaders.
+	// Labels: result ("sent", "failed", or "dropped" when the send queue is full).
+	ChatNotifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chat_notifications_total",
+		Help:      "Chat announcements of new board leaders, by result.",
+	}, []string{"result"})
+
+	// UsageUntracked counts requests of API keys left out of usage statistics because
+	// the usage tracker already follows USAGE_MAX_KEYS keys.
+	UsageUntracked = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "usage_untracked_requests_total",
+		Help:      "Requests of API keys not tracked in usage statistics because too many keys are tracked.",
+	})
+
+	// RankReads counts rank reads (top pages and player ranks) by ranking variant.
+	// Labels: method ("top_scores", "player_rank" or "player_ranks"), variant ("control" or the experiment's variant).
+	RankReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rank_reads_total",
+		Help:      "Rank reads, by method and ranking variant.",
+	}, []string{"method", "variant"})
+
+	// RankReadDuration observes the time spent computing rank reads, by ranking variant.
+	// Labels: method ("top_scores", "player_rank" or "player_ranks"), variant.
+	RankReadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rank_read_duration_seconds",
+		Help:      "Time spent computing rank reads, by method and ranking variant.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "variant"})
+
+	// CohortSubmissions counts SubmitScore outcomes by player cohort, when cohort metrics are enabled.
+	// Labels: outcome ("applied", "not_improved", "rejected" or "failed"), tenure ("new", "returning"
+	// or "unknown"), platform, client_version ("unknown", or "other" beyond the tracked ones).
+	CohortSubmissions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cohort_submissions_total",
+		Help:      "Score submissions by outcome and player cohort.",
+	}, []string{"outcome", "tenure", "platform", "client_version"})
+
+	// EventsReplayed counts outbox changes re-dispatched by admin replays (dry runs excluded).
+	EventsReplayed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_replayed_total",
+		Help:      "Outbox score changes re-dispatched by admin replays.",
+	})
+
+	// SubmissionEvents counts score submission events shipped to Kafka.
+	// Labels: result ("sent", "failed" after the producer gave up, or "dropped" when the queue was full).
+	SubmissionEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "submission_events_total",
+		Help:      "Score submission events shipped to Kafka, by result.",
+	}, []string{"result"})
+
+	// MaintenanceRuns counts maintenance job runs.
+	// Labels: result ("ok", "partial" when the backend has no statistics, or "error").
+	MaintenanceRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "maintenance_runs_total",
+		Help:      "Maintenance job runs, by result.",
+	}, []string{"result"})
+
+	// MaintenanceRecommendations is the number of recommendations of the last maintenance run.
+	// Labels: kind ("vacuum", "seq_scans", "index_bloat" or "unused_index").
+	MaintenanceRecommendations = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "maintenance_recommendations",
+		Help:      "Maintenance recommendations from the last run, by kind.",
+	}, []string{"kind"})
+
+	// ArchiveRuns counts archival job runs.
+	// Labels: result ("ok", "dry_run" or "error").
+	ArchiveRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "archive_runs_total",
+		Help:      "Archival job runs, by result.",
+	}, []string{"result"})
+
+	// ArchivedScores counts the scores moved to scores_archive by the archival job.
+	// Labels: reason ("retention" or "score_floor").
+	ArchivedScores = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "archived_scores_total",
+		Help:      "Scores moved to the archive, by reason.",
+	}, []string{"reason"})
+
+	// ArchiveCandidates is the number of scores the last dry run of the archival job would have archived.
+	// Labels: reason ("retention" or "score_floor").
+	ArchiveCandidates = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "archive_candidates",
+		Help:      "Scores the last archival dry run would have archived, by reason.",
+	}, []string{"reason"})
+
+	// LifecycleEvents counts lifecycle events offered to bus subscribers.
+	// Labels: type (e.g. "server_started"), result ("delivered" or "dropped" when a subscriber lags).
+	LifecycleEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lifecycle_events_total",
+		Help:      "Lifecycle events offered to subscribers, by type and result.",
+	}, []string{"type", "result"})
+
+	// StoreCircuitOpen is 1 while the database circuit breaker rejects queries, 0 otherwise.
+	StoreCircuitOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "store_circuit_open",
+		Help:      "Whether the database circuit breaker is open.",
+	})
+
+	// StoreCircuitRejected counts queries rejected by the open database circuit breaker.
+	StoreCircuitRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_circuit_rejected_total",
+		Help:      "Database queries rejected by the open circuit breaker.",
+	})
+
+	// StoreReplicaReads counts reads routed to the read replica, by pool that
+	// served them: "replica", or "primary" when the replica was down.
+	StoreReplicaReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_replica_reads_total",
+		Help:      "Reads routed to the read replica, by pool that served them.",
+	}, []string{"pool"})
+
+	// AdmissionRejected counts write requests shed because write capacity was saturated.
+	AdmissionRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "admission_rejected_total",
+		Help:      "Write requests rejected by admission control.",
+	})
+
+	// WritesInFlight is the number of write operations currently holding an admission slot.
+	WritesInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "writes_in_flight",
+		Help:      "Write operations currently admitted.",
+	})
+
+	// SubmitQueueDepth is the number of submissions waiting in the async submission queue.
+	SubmitQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "submit_queue_depth",
+		Help:      "Submissions queued for a batched write.",
+	})
+
+	// QueuedSubmissions counts submissions of the async submission queue.
+	// Labels: outcome ("applied", "not_improved", "rejected", "failed" or "shed").
+	QueuedSubmissions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queued_submissions_total",
+		Help:      "Submissions processed by the async submission queue, by outcome.",
+	}, []string{"outcome"})
+
+	// ConditionalReads counts HTTP board reads carrying If-None-Match.
+	// Labels: result ("not_modified" when answered 304, or "modified").
+	ConditionalReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "conditional_reads_total",
+		Help:      "HTTP board reads revalidating an ETag, by result.",
+	}, []string{"result"})
+)
+
+// Handler returns the HTTP handler exposing metrics in Prometheus format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
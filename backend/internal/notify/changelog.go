@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ChangeSettle is how old a change must be before ChangesSince returns it. Ids are
+// allocated before commit, so a change of a transaction still in flight may show
+// up after changes with higher ids: a poller that moved its checkpoint past it
+// would never see it. Left for the next poll, it is read in order.
+const ChangeSettle = time.Second
+
+// boardChangesQuery reads the changes of a board after a checkpoint, in id order.
+// Board resyncs are read too: the changes before them are void.
+const boardChangesQuery = `
+	SELECT id, leaderboard_id, player_name, score, rank_score, achieved_at, metadata, op, secondary_score, rank_secondary, country_code, platform, created_at
+	FROM score_changes
+	WHERE leaderboard_id = $1 AND id > $2 AND created_at >= $3 AND created_at < now() - make_interval(secs => $4)
+	ORDER BY id
+	LIMIT $5`
+
+// changeBoundsQuery returns the oldest id still in the outbox, the latest settled
+// id and the last id allocated, which tells pruned changes apart when the outbox
+// is empty
+const changeBoundsQuery = `
+	SELECT COALESCE(min(id), 0),
+		COALESCE(max(id) FILTER (WHERE created_at < now() - make_interval(secs => $1)), 0),
+		(SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM score_changes_id_seq)
+	FROM score_changes`
+
+// ChangePage is a page of the changes of a board since a checkpoint
+type ChangePage struct {
+	// Changes holds the last change of each player changed in the page, in id order
+	Changes []ScoreChange
+
+	// LastID is the checkpoint of the next poll: the id of the last change read,
+	// or the latest settled change when the board had none
+	LastID int64
+
+	// More is set when the page is full: more changes may follow LastID
+	More bool
+
+	// Resync is set when changes since the checkpoint are missing, because they
+	// were pruned from the outbox or the board was reset. Changes is then empty:
+	// the client reloads the board and polls from LastID.
+	Resync bool
+}
+
+// ChangeLog reads the changes of a board from the score_changes outbox, for
+// clients that poll for what changed since their last call rather than hold a
+// stream open
+type ChangeLog struct {
+	pool      *pgxpool.Pool
+	retention time.Duration // how long outbox rows are kept
+}
+
+// NewChangeLog creates a change log over the outbox of pool, whose rows are pruned after retention
+func NewChangeLog(pool *pgxpool.Pool, retention time.Duration) *ChangeLog {
+	return &ChangeLog{pool: pool, retention: retention}
+}
+
+// ChangesSince returns up to limit changes of a board after a checkpoint: the id
+// of the last change the client applied, or when afterID is 0 the time of its
+// last poll. Without either, the page only carries the current checkpoint and
+// asks for a resync.
+func (l *ChangeLog) ChangesSince(ctx context.Context, board string, afterID int64, since time.Time, limit int) (*ChangePage, error) {
+	var oldest, settled, allocated int64
+	if err := l.pool.QueryRow(ctx, changeBoundsQuery, ChangeSettle.Seconds()).Scan(&oldest, &settled, &allocated); err != nil {
+		return nil, fmt.Errorf("read outbox bounds: %w", err)
+	}
+
+	page := &ChangePage{LastID: max(afterID, settled)}
+	switch {
+	case afterID == 0 && since.IsZero():
+		page.Resync = true
+		return page, nil
+	case afterID > 0 && (oldest > afterID || (oldest == 0 && allocated > afterID)):
+		// The checkpoint change was pruned, and the changes after it may have been
+		page.Resync = true
+		return page, nil
+	case afterID == 0 && since.Before(time.Now().Add(-l.retention)):
+		page.Resync = true
+		return page, nil
+	}
+
+	rows, err := l.pool.Query(ctx, boardChangesQuery, board, afterID, since, ChangeSettle.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("read board changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []ScoreChange
+	for rows.Next() {
+		var c ScoreChange
+		if err := rows.Scan(&c.ID, &c.LeaderboardID, &c.PlayerName, &c.Score, &c.RankScore, &c.AchievedAt, &c.Metadata, &c.Op, &c.SecondaryScore, &c.RankSecondary, &c.CountryCode, &c.Platform, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan board change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read board changes: %w", err)
+	}
+
+	if len(changes) > 0 {
+		page.More = len(changes) == limit
+		if lastRead := changes[len(changes)-1].ID; page.More || lastRead > page.LastID {
+			page.LastID = lastRead
+		}
+	}
+	page.Changes, page.Resync = coalesceChanges(changes)
+	return page, nil
+}
+
+// coalesceChanges keeps the last change of each player, in id order. After a
+// board resync it keeps nothing: the changes up to it are void and the client
+// reloads the board anyway.
+func coalesceChanges(changes []ScoreChange) ([]ScoreChange, bool) {
+	for _, c := range changes {
+		if c.Op == OpResync {
+			return nil, true
+		}
+	}
+
+	last := make(map[string]int, len(changes))
+	for i, c := range changes {
+		last[c.PlayerName] = i
+	}
+	out := make([]ScoreChange, 0, len(last))
+	for i, c := range changes {
+		if last[c.PlayerName] == i {
+			out = append(out, c)
+		}
+	}
+	return out, false
+}
@@ -0,0 +1,29 @@
+package notify
+
+import "testing"
+
+func TestCoalesceChanges(t *testing.T) {
+	changes := []ScoreChange{
+		{ID: 1, PlayerName: "Alice", Op: "insert", Score: 100},
+		{ID: 2, PlayerName: "Bob", Op: "insert", Score: 90},
+		{ID: 4, PlayerName: "Alice", Op: "update", Score: 120},
+		{ID: 5, PlayerName: "Carol", Op: "insert", Score: 80},
+		{ID: 7, PlayerName: "Bob", Op: "delete", Score: 90},
+	}
+	got, resync := coalesceChanges(changes)
+	if resync {
+		t.Fatal("resync without a resync change")
+	}
+	var ids []int64
+	for _, c := range got {
+		ids = append(ids, c.ID)
+	}
+	if len(ids) != 3 || ids[0] != 4 || ids[1] != 5 || ids[2] != 7 {
+		t.Errorf("coalesced ids = %v, want the last change of each player in id order [4 5 7]", ids)
+	}
+
+	got, resync = coalesceChanges(append(changes, ScoreChange{ID: 8, Op: OpResync}, ScoreChange{ID: 9, PlayerName: "Dave", Op: "insert"}))
+	if !resync || len(got) != 0 {
+		t.Errorf("after a resync: %d changes, resync %v, want none and a resync", len(got), resync)
+	}
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+// DedupeWindow is the number of most recent change ids the dispatcher remembers
+// to drop duplicates. Duplicates come from redeliveries around a reconnect or a
+// failover, so they are at most a few channel buffers behind the original.
+const DedupeWindow = 4096
+
+// Dispatcher fans out score changes from a single source (typically a Listener)
+// to multiple in-process consumers such as the gRPC broadcaster and service caches.
+// Changes of every board go through it; each consumer routes them by
+// LeaderboardID with a map lookup, so the number of boards does not matter here.
+//
+// A change delivered twice by the source (same outbox id, e.g. once from a buffer
+// filled before a disconnect and once more after it) reaches consumers only once;
+// changes replayed on purpose by a Replayer are always delivered.
+type Dispatcher struct {
+	source <-chan ScoreChange
+	logger *zerolog.Logger
+	recent *recentIDs
+
+	mu   sync.Mutex
+	subs []chan ScoreChange
+}
+
+// NewDispatcher creates a dispatcher reading from source
+func NewDispatcher(source <-chan ScoreChange, logger *zerolog.Logger) *Dispatcher {
+	return &Dispatcher{
+		source: source,
+		logger: logger,
+		recent: newRecentIDs(DedupeWindow),
+	}
+}
+
+// Subscribe registers a consumer and returns its channel.
+// The channel is closed when the source is closed.
+func (d *Dispatcher) Subscribe() <-chan ScoreChange {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ch := make(chan ScoreChange, 100)
+	d.subs = append(d.subs, ch)
+	return ch
+}
+
+// Run forwards changes to every subscriber until the source is closed.
+// Consumers are in-process and expected to drain their channel promptly,
+// so delivery blocks rather than dropping changes.
+func (d *Dispatcher) Run() {
+	for change := range d.source {
+		if change.ID != 0 && !change.Replayed && !d.recent.add(change.ID) {
+			metrics.NotifyDuplicates.Inc()
+			d.logger.Debug().
+				Int64("change_id", change.ID).
+				Str("leaderboard", change.LeaderboardID).
+				Str("player", change.PlayerName).
+				Msg("♻️  duplicate score change dropped")
+			continue
+		}
+
+		d.mu.Lock()
+		subs := d.subs
+		d.mu.Unlock()
+
+		for _, ch := range subs {
+			ch <- change
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, ch := range d.subs {
+		close(ch)
+	}
+	d.subs = nil
+	d.logger.Info().Msg("dispatcher stopped")
+}
+
+// recentIDs is a fixed-size set of the most recently added ids
+type recentIDs struct {
+	ids  map[int64]struct{}
+	ring []int64 // insertion order, oldest at next once full
+	next int
+}
+
+func newRecentIDs(size int) *recentIDs {
+	return &recentIDs{ids: make(map[int64]struct{}, size), ring: make([]int64, 0, size)}
+}
+
+// add records an id and reports whether it was new. Once the set is full the
+// oldest id is forgotten.
+func (r *recentIDs) add(id int64) bool {
+	if _, ok := r.ids[id]; ok {
+		return false
+	}
+	if len(r.ring) < cap(r.ring) {
+		r.ring = append(r.ring, id)
+	} else {
+		delete(r.ids, r.ring[r.next])
+		r.ring[r.next] = id
+		r.next = (r.next + 1) % len(r.ring)
+	}
+	r.ids[id] = struct{}{}
+	return true
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// TestDispatcherDropsDuplicates replays the reconnect race: changes 1 and 2 are
+// delivered before the connection drops, the source resyncs and then delivers
+// change 2 again alongside new ones
+func TestDispatcherDropsDuplicates(t *testing.T) {
+	source := make(chan ScoreChange, 10)
+	logger := zerolog.Nop()
+	d := NewDispatcher(source, &logger)
+	sub := d.Subscribe()
+
+	source <- ScoreChange{ID: 1, PlayerName: "Alice", Op: "insert"}
+	source <- ScoreChange{ID: 2, PlayerName: "Bob", Op: "insert"}
+	source <- ScoreChange{Op: OpResync}
+	source <- ScoreChange{ID: 2, PlayerName: "Bob", Op: "insert"}
+	source <- ScoreChange{ID: 3, PlayerName: "Carol", Op: "insert"}
+	source <- ScoreChange{Op: OpResync} // resyncs and changes without id are never deduplicated
+	close(source)
+	go d.Run()
+
+	var got []string
+	for change := range sub {
+		got = append(got, change.Op+":"+change.PlayerName)
+	}
+	want := []string{"insert:Alice", "insert:Bob", "resync:", "insert:Carol", "resync:"}
+	if len(got) != len(want) {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("delivered %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestRecentIDs(t *testing.T) {
+	r := newRecentIDs(3)
+	for _, id := range []int64{1, 2, 3} {
+		if !r.add(id) {
+			t.Errorf("add(%d) = false for a new id", id)
+		}
+	}
+	if r.add(2) {
+		t.Error("add(2) = true for a duplicate")
+	}
+
+	// 4 evicts the oldest id, which then counts as new again
+	r.add(4)
+	if !r.add(1) {
+		t.Error("add(1) = false after eviction")
+	}
+	if r.add(4) || r.add(3) {
+		t.Error("recent ids evicted out of order")
+	}
+	if len(r.ids) != 3 {
+		t.Errorf("%d ids remembered, want 3", len(r.ids))
+	}
+}
@@ -3,9 +3,13 @@ package notify
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog"
 )
@@ -15,34 +19,121 @@ const (
 	ScoresChangesChannel = "scores_changes"
 )
 
-// ScoreChange represents a notification payload from PostgreSQL
+// OpResync asks consumers to rebuild their state from the database rather than
+// trust their view. The listener emits it for every board after it reconnects
+// (notifications sent while the connection was down are lost), and the database
+// emits it for a single board after a reset.
+const OpResync = "resync"
+
+// ScoreChange is a change of a score row, read from the score_changes outbox.
+// All boards share one channel: consumers route changes by LeaderboardID
+// (empty for an OpResync that applies to every board).
 type ScoreChange struct {
-	PlayerName string `json:"player_name"`
-	Score      int64  `json:"score"`
-	Op         string `json:"op"` // "insert", "update", or "delete"
+	// ID is the outbox sequence of the change, unique per change and used to
+	// drop duplicate deliveries; 0 when unknown (resyncs, legacy payloads).
+	// Clients see it as seq: it increases with every change, so a gap tells a
+	// client it missed one (ids of rolled back writes and coalesced changes are
+	// skipped too). Changes of concurrent writes may arrive slightly out of order.
+	ID int64 `json:"-"`
+
+	// UpdatedAt is when the change was written; zero when unknown (resyncs, legacy payloads)
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+
+	LeaderboardID string            `json:"leaderboard_id"`
+	PlayerName    string            `json:"player_name"`
+	Score         int64             `json:"score"`
+	RankScore     int64             `json:"rank_score"` // score in ranking space: higher is better on every board
+	AchievedAt    time.Time         `json:"achieved_at"`
+	Metadata      map[string]string `json:"metadata,omitempty"` // attributes of the score, empty for resyncs
+	Op            string            `json:"op"`                 // "insert", "update", "delete", or OpResync
+
+	// SecondaryScore breaks ties between equal scores; RankSecondary is it in
+	// ranking space, like RankScore
+	SecondaryScore int64 `json:"secondary_score,omitempty"`
+	RankSecondary  int64 `json:"rank_secondary,omitempty"`
+
+	// CountryCode is the country the score was submitted from, '' when unknown
+	CountryCode string `json:"country_code,omitempty"`
+
+	// Platform is the platform family the score was submitted from, '' when unknown
+	Platform string `json:"platform,omitempty"`
+
+	// Replayed marks a historical change re-dispatched by a Replayer
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+// payload is the NOTIFY payload: the outbox id of the change and the row keys.
+// Payloads without an id come from a trigger older than migration 0008 and carry
+// the whole change.
+type payload struct {
+	ID int64 `json:"id"`
+	ScoreChange
 }
 
+// getChangeQuery reads a change from the outbox written by notify_score_change()
+const getChangeQuery = `
+	SELECT leaderboard_id, player_name, score, rank_score, achieved_at, metadata, op, secondary_score, rank_secondary, country_code, platform, created_at
+	FROM score_changes
+	WHERE id = $1`
+
+// pruneChangesQuery removes outbox rows every listener has had time to read
+const pruneChangesQuery = `DELETE FROM score_changes WHERE created_at < now() - make_interval(secs => $1)`
+
 // Listener handles PostgreSQL LISTEN/NOTIFY for score changes
 type Listener struct {
 	pool       *pgxpool.Pool
 	logger     *zerolog.Logger
+	retention  time.Duration // how long outbox rows are kept
 	changeChan chan ScoreChange
 	errChan    chan error
+	listening  atomic.Bool
+
+	cancel context.CancelFunc // set by Start
+	done   chan struct{}      // closed once stopped, after changeChan and errChan
 }
 
-// NewListener creates a new LISTEN/NOTIFY listener
-func NewListener(pool *pgxpool.Pool, logger *zerolog.Logger) *Listener {
+// NewListener creates a new LISTEN/NOTIFY listener that prunes score_changes
+// rows older than retention
+func NewListener(pool *pgxpool.Pool, logger *zerolog.Logger, retention time.Duration) *Listener {
 	return &Listener{
 		pool:       pool,
 		logger:     logger,
+		retention:  retention,
 		changeChan: make(chan ScoreChange, 100), // Buffered channel
 		errChan:    make(chan error, 10),
+		done:       make(chan struct{}),
 	}
 }
 
-// Start begins listening for notifications with automatic reconnection
+// Start begins listening for notifications with automatic reconnection, until
+// ctx is cancelled or Stop is called
 func (l *Listener) Start(ctx context.Context) {
-	go l.listen(ctx)
+	ctx, l.cancel = context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		l.listen(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		pruneOutbox(ctx, l.pool, l.retention, l.logger, l.sendError)
+	}()
+	// Both goroutines send on the channels: close them once neither can
+	go closeWhenDone(&wg, l.changeChan, l.errChan, l.done)
+}
+
+// Stop stops a started listener and waits until its LISTEN connection is back in
+// the pool and its channels are closed, or until ctx is done. Changes already
+// buffered stay readable from Changes.
+func (l *Listener) Stop(ctx context.Context) error {
+	return stopSource(ctx, l.cancel, l.done)
+}
+
+// Done returns a channel closed once the listener has stopped and closed its channels
+func (l *Listener) Done() <-chan struct{} {
+	return l.done
 }
 
 // Changes returns a channel that receives score change notifications
@@ -55,88 +146,172 @@ func (l *Listener) Errors() <-chan error {
 	return l.errChan
 }
 
+// Listening reports whether a LISTEN connection is currently established
+func (l *Listener) Listening() bool {
+	return l.listening.Load()
+}
+
 func (l *Listener) listen(ctx context.Context) {
 	backoff := time.Second
 	maxBackoff := time.Minute
+	listenedBefore := false
+	defer l.listening.Store(false)
+	defer func() { l.logger.Info().Msg("listener shutting down") }()
 
-	for {
-		select {
-		case <-ctx.Done():
-			l.logger.Info().Msg("listener shutting down")
-			close(l.changeChan)
-			close(l.errChan)
-			return
-		default:
-		}
-
+	for ctx.Err() == nil {
 		// Acquire a connection from the pool
 		conn, err := l.pool.Acquire(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
 			l.logger.Error().Err(err).Msg("failed to acquire connection for LISTEN")
 			l.sendError(fmt.Errorf("acquire connection: %w", err))
-			time.Sleep(backoff)
+			if !sleep(ctx, backoff) {
+				return
+			}
 			backoff = min(backoff*2, maxBackoff)
 			continue
 		}
 
-		// Issue LISTEN command
-		_, err = conn.Exec(ctx, fmt.Sprintf("LISTEN %s", ScoresChangesChannel))
-		if err != nil {
-			l.logger.Error().Err(err).Msg("failed to LISTEN")
-			conn.Release()
-			l.sendError(fmt.Errorf("LISTEN command: %w", err))
-			time.Sleep(backoff)
-			backoff = min(backoff*2, maxBackoff)
+		listened, err := l.receive(ctx, conn, listenedBefore)
+		conn.Release()
+		l.listening.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if listened {
+			// Reset backoff after a successful connection
+			listenedBefore, backoff = true, time.Second
+			l.logger.Error().Err(err).Msg("notification error, will reconnect")
+			l.sendError(fmt.Errorf("wait for notification: %w", err))
 			continue
 		}
 
-		l.logger.Info().Str("channel", ScoresChangesChannel).Msg("listening for notifications")
-		backoff = time.Second // Reset backoff on successful connection
-
-		// Wait for notifications
-		for {
-			notification, err := conn.Conn().WaitForNotification(ctx)
-			if err != nil {
-				l.logger.Error().Err(err).Msg("notification error, will reconnect")
-				conn.Release()
-				l.sendError(fmt.Errorf("wait for notification: %w", err))
-				break
-			}
+		l.logger.Error().Err(err).Msg("failed to LISTEN")
+		l.sendError(fmt.Errorf("LISTEN command: %w", err))
+		if !sleep(ctx, backoff) {
+			return
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
 
-			l.logger.Info().
-				Str("channel", notification.Channel).
+// receive issues LISTEN on conn and forwards its notifications until the
+// connection fails or ctx is done. It reports whether LISTEN succeeded, with the
+// error that ended it; the caller releases conn.
+func (l *Listener) receive(ctx context.Context, conn *pgxpool.Conn, listenedBefore bool) (bool, error) {
+	if _, err := conn.Exec(ctx, fmt.Sprintf("LISTEN %s", ScoresChangesChannel)); err != nil {
+		return false, err
+	}
+
+	l.logger.Info().Str("channel", ScoresChangesChannel).Msg("listening for notifications")
+	l.listening.Store(true)
+
+	// Changes may have been missed while disconnected: ask consumers to resync
+	if listenedBefore {
+		l.logger.Warn().Msg("🔄 LISTEN re-established, requesting subscriber resync")
+		select {
+		case l.changeChan <- ScoreChange{Op: OpResync}:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+
+	// Wait for notifications
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		l.logger.Info().
+			Str("channel", notification.Channel).
+			Str("payload", notification.Payload).
+			Msg("📨 DB NOTIFICATION received from PostgreSQL")
+
+		// Parse the notification payload
+		var n payload
+		if err := json.Unmarshal([]byte(notification.Payload), &n); err != nil {
+			l.logger.Error().
+				Err(err).
 				Str("payload", notification.Payload).
-				Msg("📨 DB NOTIFICATION received from PostgreSQL")
-
-			// Parse the notification payload
-			var change ScoreChange
-			if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
-				l.logger.Error().
-					Err(err).
-					Str("payload", notification.Payload).
-					Msg("❌ failed to parse notification payload")
-				continue
+				Msg("❌ failed to parse notification payload")
+			continue
+		}
+		change, err := l.resolve(ctx, n)
+		if err != nil {
+			if ctx.Err() != nil {
+				return true, ctx.Err()
 			}
+			l.logger.Error().Err(err).Int64("change_id", n.ID).Msg("❌ failed to read change from outbox")
+			l.sendError(fmt.Errorf("read change %d: %w", n.ID, err))
+			continue
+		}
+
+		l.logger.Info().
+			Str("leaderboard", change.LeaderboardID).
+			Str("player", change.PlayerName).
+			Int64("score", change.Score).
+			Str("op", change.Op).
+			Msg("✅ DB CHANGE detected - parsed successfully")
 
+		// Send to channel (non-blocking with timeout)
+		select {
+		case l.changeChan <- change:
 			l.logger.Info().
 				Str("player", change.PlayerName).
 				Int64("score", change.Score).
-				Str("op", change.Op).
-				Msg("✅ DB CHANGE detected - parsed successfully")
-
-			// Send to channel (non-blocking with timeout)
-			select {
-			case l.changeChan <- change:
-				l.logger.Info().
-					Str("player", change.PlayerName).
-					Int64("score", change.Score).
-					Msg("📤 Change forwarded to subscribers")
-			case <-time.After(time.Second):
-				l.logger.Warn().Msg("⚠️  change channel full, dropping notification")
-			case <-ctx.Done():
-				conn.Release()
-				return
+				Msg("📤 Change forwarded to subscribers")
+		case <-time.After(time.Second):
+			l.logger.Warn().Msg("⚠️  change channel full, dropping notification")
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
+
+// resolve reads the full change of a notification from the outbox.
+// A change pruned before it could be read is replaced by a resync.
+func (l *Listener) resolve(ctx context.Context, n payload) (ScoreChange, error) {
+	if n.ID == 0 {
+		return n.ScoreChange, nil
+	}
+
+	var c ScoreChange
+	err := l.pool.QueryRow(ctx, getChangeQuery, n.ID).
+		Scan(&c.LeaderboardID, &c.PlayerName, &c.Score, &c.RankScore, &c.AchievedAt, &c.Metadata, &c.Op, &c.SecondaryScore, &c.RankSecondary, &c.CountryCode, &c.Platform, &c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		l.logger.Warn().Int64("change_id", n.ID).Msg("🔄 change already pruned from outbox, requesting subscriber resync")
+		return ScoreChange{Op: OpResync}, nil
+	}
+	c.ID = n.ID
+	return c, err
+}
+
+// pruneOutbox deletes outbox rows older than the retention, at a tenth of the retention.
+// Every server prunes; the deletes are idempotent.
+func pruneOutbox(ctx context.Context, pool *pgxpool.Pool, retention time.Duration, logger *zerolog.Logger, sendError func(error)) {
+	ticker := time.NewTicker(retention / 10)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		tag, err := pool.Exec(ctx, pruneChangesQuery, retention.Seconds())
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error().Err(err).Msg("failed to prune score_changes outbox")
+				sendError(fmt.Errorf("prune outbox: %w", err))
 			}
+			continue
+		}
+		if n := tag.RowsAffected(); n > 0 {
+			logger.Debug().Int64("rows", n).Msg("pruned score_changes outbox")
 		}
 	}
 }
@@ -156,3 +331,13 @@ func min(a, b time.Duration) time.Duration {
 	}
 	return b
 }
+
+// sleep waits for d and reports whether ctx is still running
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,308 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// Notification delivery modes (NOTIFY_MODE)
+const (
+	ModeListen = "listen" // LISTEN/NOTIFY, lowest latency
+	ModeOutbox = "outbox" // poll the score_changes outbox, at-least-once
+)
+
+// Outbox poller defaults
+const (
+	DefaultOutboxBatchSize  = 500
+	DefaultOutboxGapTimeout = 5 * time.Second
+)
+
+// listChangesQuery reads the outbox after a cursor, in id order
+const listChangesQuery = `
+	SELECT id, leaderboard_id, player_name, score, rank_score, achieved_at, metadata, op, secondary_score, rank_secondary, country_code, platform, created_at
+	FROM score_changes
+	WHERE id > $1
+	ORDER BY id
+	LIMIT $2`
+
+const (
+	getCursorQuery = `SELECT last_id, updated_at FROM score_change_cursors WHERE consumer = $1`
+
+	saveCursorQuery = `
+	INSERT INTO score_change_cursors (consumer, last_id) VALUES ($1, $2)
+	ON CONFLICT (consumer) DO UPDATE SET last_id = EXCLUDED.last_id, updated_at = now()`
+
+	lastChangeQuery = `SELECT COALESCE(max(id), 0) FROM score_changes`
+)
+
+// OutboxConfig configures an OutboxPoller
+type OutboxConfig struct {
+	// Consumer names the cursor in score_change_cursors; each server needs its own
+	Consumer string
+
+	// Interval between polls when the outbox has no new change
+	Interval time.Duration
+
+	// Retention of outbox rows, as for Listener
+	Retention time.Duration
+
+	// BatchSize is the maximum number of changes read per query
+	BatchSize int
+
+	// GapTimeout is how long the poller waits for a missing id to show up before
+	// skipping it. Ids are allocated before commit, so a gap is usually a
+	// transaction still in flight; it may also be a rolled back one, which never fills.
+	GapTimeout time.Duration
+}
+
+// OutboxPoller tails the score_changes outbox instead of waiting for NOTIFY.
+// It delivers every change at least once, in id order, and persists its cursor
+// so a restart resumes after the last change it delivered.
+type OutboxPoller struct {
+	pool   *pgxpool.Pool
+	logger *zerolog.Logger
+	cfg    OutboxConfig
+
+	changeChan chan ScoreChange
+	errChan    chan error
+	healthy    atomic.Bool // last poll succeeded
+
+	cancel context.CancelFunc // set by Start
+	done   chan struct{}      // closed once stopped, after changeChan and errChan
+}
+
+var _ Source = (*OutboxPoller)(nil)
+
+// NewOutboxPoller creates an outbox poller; zero BatchSize and GapTimeout use the defaults
+func NewOutboxPoller(pool *pgxpool.Pool, logger *zerolog.Logger, cfg OutboxConfig) *OutboxPoller {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultOutboxBatchSize
+	}
+	if cfg.GapTimeout <= 0 {
+		cfg.GapTimeout = DefaultOutboxGapTimeout
+	}
+	return &OutboxPoller{
+		pool:       pool,
+		logger:     logger,
+		cfg:        cfg,
+		changeChan: make(chan ScoreChange, 100),
+		errChan:    make(chan error, 10),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start begins polling the outbox, until ctx is cancelled or Stop is called
+func (p *OutboxPoller) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		p.poll(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		pruneOutbox(ctx, p.pool, p.cfg.Retention, p.logger, p.sendError)
+	}()
+	go closeWhenDone(&wg, p.changeChan, p.errChan, p.done)
+}
+
+// Stop stops a started poller and waits until its channels are closed, or until ctx is done
+func (p *OutboxPoller) Stop(ctx context.Context) error {
+	return stopSource(ctx, p.cancel, p.done)
+}
+
+// Done returns a channel closed once the poller has stopped and closed its channels
+func (p *OutboxPoller) Done() <-chan struct{} {
+	return p.done
+}
+
+// Changes returns a channel that receives score changes
+func (p *OutboxPoller) Changes() <-chan ScoreChange {
+	return p.changeChan
+}
+
+// Errors returns a channel that receives poller errors
+func (p *OutboxPoller) Errors() <-chan error {
+	return p.errChan
+}
+
+// Listening reports whether the poller has its cursor and its last poll succeeded
+func (p *OutboxPoller) Listening() bool {
+	return p.healthy.Load()
+}
+
+// outboxRow is a change with its outbox id
+type outboxRow struct {
+	id     int64
+	change ScoreChange
+}
+
+func (p *OutboxPoller) poll(ctx context.Context) {
+	defer p.healthy.Store(false)
+
+	cursor, err := p.loadCursor(ctx)
+	for err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		p.logger.Error().Err(err).Msg("failed to load outbox cursor")
+		p.sendError(fmt.Errorf("load outbox cursor: %w", err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.cfg.Interval):
+		}
+		cursor, err = p.loadCursor(ctx)
+	}
+
+	p.logger.Info().
+		Str("consumer", p.cfg.Consumer).
+		Int64("cursor", cursor).
+		Dur("interval", p.cfg.Interval).
+		Msg("📬 tailing score_changes outbox")
+	p.healthy.Store(true)
+
+	var gapSince time.Time // when the poller started waiting at the current gap
+	for {
+		rows, err := p.read(ctx, cursor)
+		p.healthy.Store(err == nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				p.logger.Info().Msg("outbox poller shutting down")
+				return
+			}
+			p.sendError(fmt.Errorf("poll outbox: %w", err))
+		}
+
+		ready, waiting := contiguous(cursor, rows)
+		if waiting {
+			switch {
+			case gapSince.IsZero():
+				gapSince = time.Now()
+			case time.Since(gapSince) >= p.cfg.GapTimeout:
+				// The missing ids were rolled back: move past them
+				p.logger.Warn().
+					Int64("after", cursor).
+					Int64("next", rows[len(ready)].id).
+					Msg("⏭️  skipping outbox id gap")
+				ready, _ = contiguous(rows[len(ready)].id-1, rows[len(ready):])
+				gapSince = time.Time{}
+			}
+		}
+
+		for _, row := range ready {
+			select {
+			case p.changeChan <- row.change:
+			case <-ctx.Done():
+				p.logger.Info().Msg("outbox poller shutting down")
+				return
+			}
+			cursor = row.id
+		}
+		if len(ready) > 0 {
+			gapSince = time.Time{}
+			if err := p.saveCursor(ctx, cursor); err != nil && ctx.Err() == nil {
+				// Delivered changes are delivered again after a restart
+				p.sendError(fmt.Errorf("save outbox cursor: %w", err))
+			}
+		}
+
+		// A full batch means more changes are pending
+		if len(ready) == p.cfg.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			p.logger.Info().Msg("outbox poller shutting down")
+			return
+		case <-time.After(p.cfg.Interval):
+		}
+	}
+}
+
+// contiguous returns the leading rows whose ids follow cursor without a gap, and
+// whether a later row is held back by a missing id
+func contiguous(cursor int64, rows []outboxRow) (ready []outboxRow, waiting bool) {
+	for i, row := range rows {
+		if row.id != cursor+1 {
+			return rows[:i], true
+		}
+		cursor = row.id
+	}
+	return rows, false
+}
+
+// loadCursor returns the consumer's last delivered id. A new consumer starts at the
+// end of the outbox; a resumed one whose cursor is older than the retention may have
+// missed pruned changes, so it asks consumers to resync first.
+func (p *OutboxPoller) loadCursor(ctx context.Context) (int64, error) {
+	var cursor int64
+	var updatedAt time.Time
+	err := p.pool.QueryRow(ctx, getCursorQuery, p.cfg.Consumer).Scan(&cursor, &updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if err := p.pool.QueryRow(ctx, lastChangeQuery).Scan(&cursor); err != nil {
+			return 0, err
+		}
+		return cursor, p.saveCursor(ctx, cursor)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if time.Since(updatedAt) > p.cfg.Retention {
+		p.logger.Warn().
+			Str("consumer", p.cfg.Consumer).
+			Time("cursor_updated_at", updatedAt).
+			Msg("🔄 outbox cursor older than retention, requesting subscriber resync")
+		select {
+		case p.changeChan <- ScoreChange{Op: OpResync}:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	return cursor, nil
+}
+
+func (p *OutboxPoller) saveCursor(ctx context.Context, cursor int64) error {
+	_, err := p.pool.Exec(ctx, saveCursorQuery, p.cfg.Consumer, cursor)
+	return err
+}
+
+// read returns up to BatchSize changes after cursor
+func (p *OutboxPoller) read(ctx context.Context, cursor int64) ([]outboxRow, error) {
+	rows, err := p.pool.Query(ctx, listChangesQuery, cursor, p.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []outboxRow
+	for rows.Next() {
+		var r outboxRow
+		c := &r.change
+		if err := rows.Scan(&r.id, &c.LeaderboardID, &c.PlayerName, &c.Score, &c.RankScore, &c.AchievedAt, &c.Metadata, &c.Op, &c.SecondaryScore, &c.RankSecondary, &c.CountryCode, &c.Platform, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		c.ID = r.id
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (p *OutboxPoller) sendError(err error) {
+	select {
+	case p.errChan <- err:
+	default:
+		p.logger.Warn().Err(err).Msg("error channel full, dropping error")
+	}
+}
@@ -0,0 +1,34 @@
+package notify
+
+import "testing"
+
+func TestContiguous(t *testing.T) {
+	rows := func(ids ...int64) []outboxRow {
+		out := make([]outboxRow, len(ids))
+		for i, id := range ids {
+			out[i].id = id
+		}
+		return out
+	}
+
+	tests := []struct {
+		name        string
+		cursor      int64
+		rows        []outboxRow
+		wantReady   int
+		wantWaiting bool
+	}{
+		{name: "empty", cursor: 10},
+		{name: "contiguous", cursor: 10, rows: rows(11, 12, 13), wantReady: 3},
+		{name: "gap after cursor", cursor: 10, rows: rows(12, 13), wantWaiting: true},
+		{name: "gap in batch", cursor: 10, rows: rows(11, 12, 14), wantReady: 2, wantWaiting: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, waiting := contiguous(tt.cursor, tt.rows)
+			if len(ready) != tt.wantReady || waiting != tt.wantWaiting {
+				t.Errorf("contiguous() = %d ready, waiting %v, want %d, %v", len(ready), waiting, tt.wantReady, tt.wantWaiting)
+			}
+		})
+	}
+}
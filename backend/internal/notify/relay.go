@@ -0,0 +1,243 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+// Broadcast bus roles (BUS_ROLE)
+const (
+	RolePublisher  = "publisher"  // read changes from the database and publish them on the bus
+	RoleSubscriber = "subscriber" // read changes from the bus only
+)
+
+// busPublishTimeout bounds a publish, so a stalled bus delays local delivery by at most this much
+const busPublishTimeout = 2 * time.Second
+
+// Bus carries score changes between server replicas, e.g. Redis Pub/Sub
+type Bus interface {
+	// Publish sends a message to every subscribed replica
+	Publish(ctx context.Context, payload []byte) error
+
+	// Subscribe receives messages until ctx is cancelled, then closes the channel.
+	// The bus reconnects by itself and reports its connection state with
+	// BusSubscribed and BusDisconnected messages.
+	Subscribe(ctx context.Context) <-chan BusMessage
+}
+
+// BusEvent tells what a BusMessage reports
+type BusEvent int
+
+// Bus message events
+const (
+	BusPayload      BusEvent = iota // a published message
+	BusSubscribed                   // the subscription is (re-)established
+	BusDisconnected                 // the subscription dropped; the bus is reconnecting
+)
+
+// BusMessage is a message received from a Bus
+type BusMessage struct {
+	Event   BusEvent
+	Payload []byte // BusPayload only
+}
+
+// busEnvelope is the wire format of a change on the bus
+type busEnvelope struct {
+	Origin string `json:"origin"` // replica that read the change from the database
+	ID     int64  `json:"id"`
+	ScoreChange
+}
+
+// Relay shares score changes between replicas, so a stream subscriber gets every
+// update whatever replica it is connected to.
+//
+// A publisher relays the changes of its database source: each one is published
+// on the bus and delivered locally. A subscriber has no database source and
+// delivers what the publishers publish; it resyncs its consumers whenever its
+// bus subscription drops, as the Listener does after a LISTEN reconnect. Several
+// publishers may run side by side: consumers receive the same change once per
+// publisher, and the Dispatcher drops the copies by outbox id.
+type Relay struct {
+	local  Source // database source of a publisher, nil for a subscriber
+	bus    Bus
+	origin string
+	logger *zerolog.Logger
+
+	changeChan chan ScoreChange
+	errChan    chan error
+	subscribed atomic.Bool // subscriber: the bus subscription is up
+
+	cancel context.CancelFunc // set by Start
+	done   chan struct{}      // closed once stopped, after changeChan, errChan and the local source
+}
+
+var _ Source = (*Relay)(nil)
+
+// NewPublisher returns a relay publishing the changes of local on the bus.
+// origin names this replica in the published messages.
+func NewPublisher(local Source, bus Bus, origin string, logger *zerolog.Logger) *Relay {
+	return newRelay(local, bus, origin, logger)
+}
+
+// NewSubscriber returns a relay delivering the changes published on the bus
+func NewSubscriber(bus Bus, origin string, logger *zerolog.Logger) *Relay {
+	return newRelay(nil, bus, origin, logger)
+}
+
+func newRelay(local Source, bus Bus, origin string, logger *zerolog.Logger) *Relay {
+	return &Relay{
+		local:      local,
+		bus:        bus,
+		origin:     origin,
+		logger:     logger,
+		changeChan: make(chan ScoreChange, 100),
+		errChan:    make(chan error, 10),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start begins relaying changes, until ctx is cancelled or Stop is called
+func (r *Relay) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	if r.local != nil {
+		r.local.Start(ctx)
+		go r.publish(ctx)
+		return
+	}
+	go r.subscribe(ctx)
+}
+
+// Stop stops a started relay, and the database source of a publisher, and waits
+// until their channels are closed, or until ctx is done
+func (r *Relay) Stop(ctx context.Context) error {
+	return stopSource(ctx, r.cancel, r.done)
+}
+
+// Done returns a channel closed once the relay has stopped and closed its channels
+func (r *Relay) Done() <-chan struct{} {
+	return r.done
+}
+
+// Changes returns a channel that receives score changes
+func (r *Relay) Changes() <-chan ScoreChange {
+	return r.changeChan
+}
+
+// Errors returns a channel that receives relay and database source errors
+func (r *Relay) Errors() <-chan error {
+	return r.errChan
+}
+
+// Listening reports whether changes are coming in: the database source of a
+// publisher is listening, or the bus subscription of a subscriber is up
+func (r *Relay) Listening() bool {
+	if r.local != nil {
+		return r.local.Listening()
+	}
+	return r.subscribed.Load()
+}
+
+// publish forwards the changes of the local source to the bus and to local consumers
+func (r *Relay) publish(ctx context.Context) {
+	defer close(r.done)
+	defer func() { <-r.local.Done() }()
+	defer close(r.changeChan)
+	defer close(r.errChan)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for err := range r.local.Errors() {
+			r.sendError(err)
+		}
+	}()
+	defer wg.Wait()
+
+	r.logger.Info().Str("origin", r.origin).Msg("📢 publishing score changes on the broadcast bus")
+	for change := range r.local.Changes() {
+		if err := r.publishChange(ctx, change); err != nil && ctx.Err() == nil {
+			r.sendError(err)
+		}
+
+		// Local subscribers never depend on the bus
+		r.changeChan <- change
+	}
+}
+
+// publishChange publishes a change on the bus
+func (r *Relay) publishChange(ctx context.Context, change ScoreChange) error {
+	payload, err := json.Marshal(busEnvelope{Origin: r.origin, ID: change.ID, ScoreChange: change})
+	if err == nil {
+		pubCtx, cancel := context.WithTimeout(ctx, busPublishTimeout)
+		err = r.bus.Publish(pubCtx, payload)
+		cancel()
+	}
+	if err != nil {
+		metrics.BusMessages.WithLabelValues("publish_failed").Inc()
+		return fmt.Errorf("publish change %d: %w", change.ID, err)
+	}
+	metrics.BusMessages.WithLabelValues("published").Inc()
+	return nil
+}
+
+// subscribe delivers the changes published on the bus
+func (r *Relay) subscribe(ctx context.Context) {
+	defer close(r.done)
+	defer close(r.changeChan)
+	defer close(r.errChan)
+	defer r.subscribed.Store(false)
+
+	r.logger.Info().Str("origin", r.origin).Msg("📡 reading score changes from the broadcast bus")
+	subscribedBefore := false
+	for msg := range r.bus.Subscribe(ctx) {
+		switch msg.Event {
+		case BusSubscribed:
+			r.subscribed.Store(true)
+			// Changes may have been published while disconnected: ask consumers to resync
+			if subscribedBefore {
+				r.logger.Warn().Msg("🔄 broadcast bus resubscribed, requesting subscriber resync")
+				r.deliver(ctx, ScoreChange{Op: OpResync})
+			}
+			subscribedBefore = true
+			continue
+		case BusDisconnected:
+			r.subscribed.Store(false)
+			continue
+		}
+
+		var env busEnvelope
+		if err := json.Unmarshal(msg.Payload, &env); err != nil {
+			metrics.BusMessages.WithLabelValues("invalid").Inc()
+			r.logger.Error().Err(err).Bytes("payload", msg.Payload).Msg("❌ failed to parse broadcast bus message")
+			continue
+		}
+		metrics.BusMessages.WithLabelValues("received").Inc()
+		change := env.ScoreChange
+		change.ID = env.ID
+		r.deliver(ctx, change)
+	}
+	r.logger.Info().Msg("broadcast bus subscriber shutting down")
+}
+
+func (r *Relay) deliver(ctx context.Context, change ScoreChange) {
+	select {
+	case r.changeChan <- change:
+	case <-ctx.Done():
+	}
+}
+
+func (r *Relay) sendError(err error) {
+	select {
+	case r.errChan <- err:
+	default:
+		r.logger.Warn().Err(err).Msg("error channel full, dropping error")
+	}
+}
@@ -0,0 +1,181 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// fakeSource is a Source fed by the test, closed by the test or when its context is done
+type fakeSource struct {
+	changes chan ScoreChange
+	errs    chan error
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{changes: make(chan ScoreChange, 10), errs: make(chan error, 10), done: make(chan struct{})}
+}
+
+func (f *fakeSource) Start(ctx context.Context) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.close()
+		case <-f.done:
+		}
+	}()
+}
+func (f *fakeSource) Stop(ctx context.Context) error { f.close(); return nil }
+func (f *fakeSource) Done() <-chan struct{}          { return f.done }
+func (f *fakeSource) Changes() <-chan ScoreChange    { return f.changes }
+func (f *fakeSource) Errors() <-chan error           { return f.errs }
+func (f *fakeSource) Listening() bool                { return true }
+func (f *fakeSource) close() {
+	f.once.Do(func() { close(f.changes); close(f.errs); close(f.done) })
+}
+
+// fakeBus records publishes and replays messages to a subscriber
+type fakeBus struct {
+	mu        sync.Mutex
+	published [][]byte
+	fail      bool
+	messages  chan BusMessage
+}
+
+func (b *fakeBus) Publish(ctx context.Context, payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fail {
+		return errors.New("connection refused")
+	}
+	b.published = append(b.published, payload)
+	return nil
+}
+
+func (b *fakeBus) Subscribe(ctx context.Context) <-chan BusMessage { return b.messages }
+
+func TestRelayPublisher(t *testing.T) {
+	logger := zerolog.Nop()
+	local := newFakeSource()
+	b := &fakeBus{}
+	r := NewPublisher(local, b, "replica-1", &logger)
+	r.Start(context.Background())
+
+	local.changes <- ScoreChange{ID: 7, LeaderboardID: "global", PlayerName: "Alice", Score: 100, Op: "insert"}
+	if got := <-r.Changes(); got.ID != 7 || got.PlayerName != "Alice" {
+		t.Errorf("local delivery = %+v", got)
+	}
+
+	// Local consumers keep getting changes while the bus is down
+	b.mu.Lock()
+	b.fail = true
+	b.mu.Unlock()
+	local.changes <- ScoreChange{ID: 8, LeaderboardID: "global", PlayerName: "Bob", Op: "insert"}
+	if got := <-r.Changes(); got.ID != 8 {
+		t.Errorf("local delivery with the bus down = %+v", got)
+	}
+	if err := <-r.Errors(); err == nil {
+		t.Error("no error reported for the failed publish")
+	}
+	local.close()
+	for range r.Changes() {
+	}
+
+	if len(b.published) != 1 {
+		t.Fatalf("%d messages published, want 1", len(b.published))
+	}
+
+	// What a publisher sends is what a subscriber delivers
+	msgs := make(chan BusMessage, 2)
+	msgs <- BusMessage{Event: BusSubscribed}
+	msgs <- BusMessage{Payload: b.published[0]}
+	close(msgs)
+	sub := NewSubscriber(&fakeBus{messages: msgs}, "replica-2", &logger)
+	sub.Start(context.Background())
+	if got := <-sub.Changes(); got.ID != 7 || got.LeaderboardID != "global" || got.PlayerName != "Alice" || got.Score != 100 || got.Op != "insert" {
+		t.Errorf("change through the bus = %+v", got)
+	}
+}
+
+func TestRelaySubscriber(t *testing.T) {
+	logger := zerolog.Nop()
+	msgs := make(chan BusMessage)
+	r := NewSubscriber(&fakeBus{messages: msgs}, "replica-2", &logger)
+	r.Start(context.Background())
+
+	if r.Listening() {
+		t.Error("Listening() before the bus subscribed")
+	}
+	msgs <- BusMessage{Event: BusSubscribed}
+	msgs <- BusMessage{Payload: []byte(`{"origin":"replica-1","id":3,"leaderboard_id":"global","player_name":"Alice","op":"update"}`)}
+	if got := <-r.Changes(); got.ID != 3 || got.PlayerName != "Alice" {
+		t.Errorf("change = %+v", got)
+	}
+	if !r.Listening() {
+		t.Error("not Listening() while subscribed")
+	}
+
+	// Invalid payloads are skipped; a reconnection resyncs consumers
+	msgs <- BusMessage{Payload: []byte(`not json`)}
+	msgs <- BusMessage{Event: BusDisconnected}
+	msgs <- BusMessage{Event: BusSubscribed}
+	if got := <-r.Changes(); got.Op != OpResync || got.LeaderboardID != "" {
+		t.Errorf("after resubscribe got %+v, want a resync of every board", got)
+	}
+	close(msgs)
+	if _, ok := <-r.Changes(); ok {
+		t.Error("changes channel still open after the bus closed")
+	}
+}
+
+func TestRelayStop(t *testing.T) {
+	logger := zerolog.Nop()
+	local := newFakeSource()
+	r := NewPublisher(local, &fakeBus{}, "replica-1", &logger)
+	r.Start(context.Background())
+
+	local.changes <- ScoreChange{ID: 1, LeaderboardID: "global", PlayerName: "Alice", Op: "insert"}
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	// Stopping the relay stops its database source; buffered changes stay readable
+	select {
+	case <-local.Done():
+	default:
+		t.Error("local source still running after Stop")
+	}
+	var got []ScoreChange
+	for change := range r.Changes() {
+		got = append(got, change)
+	}
+	if len(got) > 1 || (len(got) == 1 && got[0].ID != 1) {
+		t.Errorf("changes after Stop = %+v", got)
+	}
+	if _, ok := <-r.Errors(); ok {
+		t.Error("errors channel still open after Stop")
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	logger := zerolog.Nop()
+	// The bus never closes its subscription, so the subscriber cannot stop
+	r := NewSubscriber(&fakeBus{messages: make(chan BusMessage)}, "replica-2", &logger)
+	r.Start(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Stop(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stop = %v, want context.Canceled", err)
+	}
+	select {
+	case <-r.Done():
+		t.Error("Done closed while the subscriber is still running")
+	default:
+	}
+}
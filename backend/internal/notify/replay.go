@@ -0,0 +1,236 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// ErrReplayStopped is returned by Replay when the change source has shut down
+var ErrReplayStopped = errors.New("change source stopped")
+
+// replayChangesQuery reads a range of the outbox, in id order
+const replayChangesQuery = `
+	SELECT id, leaderboard_id, player_name, score, rank_score, achieved_at, metadata, op, secondary_score, rank_secondary, country_code, platform, created_at
+	FROM score_changes
+	WHERE id > $1 AND id <= $2
+	ORDER BY id
+	LIMIT $3`
+
+// outboxBoundsQuery returns the oldest and latest ids still in the outbox
+const outboxBoundsQuery = `SELECT COALESCE(min(id), 0), COALESCE(max(id), 0) FROM score_changes`
+
+// ReplayResult reports the changes of a replayed outbox range
+type ReplayResult struct {
+	FromID int64 // first id of the range
+	ToID   int64 // last id of the range
+	DryRun bool  // nothing was dispatched
+
+	Events  int            // changes found in the range
+	FirstID int64          // id of the first change found, 0 when none
+	LastID  int64          // id of the last change found
+	Ops     map[string]int // changes found per op
+	Boards  []string       // boards resynced after the replay, sorted
+
+	// Outbox bounds when the replay started: changes older than OldestID were pruned
+	OldestID int64
+	LatestID int64
+}
+
+// Replayer re-dispatches historical changes of the score_changes outbox through
+// the change pipeline, for consumers downstream of the Dispatcher (and, on a bus
+// publisher, of the other replicas) that missed a window of events.
+//
+// It wraps the server's change source and merges replayed changes into it. They
+// keep their outbox id and are marked Replayed, so the Dispatcher does not drop
+// them as duplicates. Each replayed board is then resynced: the replayed changes
+// are history, and consumers holding state rebuild it from the database.
+type Replayer struct {
+	inner  Source
+	pool   *pgxpool.Pool
+	logger *zerolog.Logger
+
+	changeChan chan ScoreChange
+	replayChan chan ScoreChange
+	cancel     context.CancelFunc // set by Start
+	done       chan struct{}      // closed once the inner source has stopped, after changeChan
+
+	mu sync.Mutex // one replay at a time
+}
+
+var _ Source = (*Replayer)(nil)
+
+// NewReplayer wraps source so the changes of the outbox of pool can be replayed through it
+func NewReplayer(pool *pgxpool.Pool, source Source, logger *zerolog.Logger) *Replayer {
+	return &Replayer{
+		inner:      source,
+		pool:       pool,
+		logger:     logger,
+		changeChan: make(chan ScoreChange, 100),
+		replayChan: make(chan ScoreChange),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start starts the wrapped source
+func (r *Replayer) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.inner.Start(ctx)
+	go r.merge()
+}
+
+// Stop stops the wrapped source and waits until the channels are closed, or
+// until ctx is done. Replays in progress fail with ErrReplayStopped.
+func (r *Replayer) Stop(ctx context.Context) error {
+	return stopSource(ctx, r.cancel, r.done)
+}
+
+// Done returns a channel closed once the wrapped source has stopped and Changes is closed
+func (r *Replayer) Done() <-chan struct{} {
+	return r.done
+}
+
+// Changes returns a channel that receives the changes of the wrapped source and replayed changes
+func (r *Replayer) Changes() <-chan ScoreChange {
+	return r.changeChan
+}
+
+// Errors returns the errors of the wrapped source
+func (r *Replayer) Errors() <-chan error {
+	return r.inner.Errors()
+}
+
+// Listening reports whether the wrapped source is listening
+func (r *Replayer) Listening() bool {
+	return r.inner.Listening()
+}
+
+func (r *Replayer) merge() {
+	defer close(r.done)
+	defer func() { <-r.inner.Done() }()
+	defer close(r.changeChan)
+
+	changes := r.inner.Changes()
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			r.changeChan <- change
+		case change := <-r.replayChan:
+			r.changeChan <- change
+		}
+	}
+}
+
+// Replay dispatches the outbox changes with ids in [fromID, toID], followed by a
+// resync of every board they belong to. With dryRun it only reports what would be
+// dispatched. Replays run one at a time.
+func (r *Replayer) Replay(ctx context.Context, fromID, toID int64, dryRun bool) (*ReplayResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := &ReplayResult{FromID: fromID, ToID: toID, DryRun: dryRun, Ops: make(map[string]int)}
+	if err := r.pool.QueryRow(ctx, outboxBoundsQuery).Scan(&res.OldestID, &res.LatestID); err != nil {
+		return nil, fmt.Errorf("read outbox bounds: %w", err)
+	}
+
+	boards := make(map[string]bool)
+	for after := fromID - 1; after < toID; {
+		rows, err := r.read(ctx, after, toID)
+		if err != nil {
+			return nil, fmt.Errorf("read outbox: %w", err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		if !dryRun {
+			if err := r.dispatch(ctx, rows); err != nil {
+				return nil, err
+			}
+		}
+		for _, row := range rows {
+			res.count(row)
+			boards[row.change.LeaderboardID] = true
+		}
+		after = rows[len(rows)-1].id
+	}
+
+	for board := range boards {
+		res.Boards = append(res.Boards, board)
+	}
+	sort.Strings(res.Boards)
+	if !dryRun {
+		for _, board := range res.Boards {
+			if err := r.send(ctx, ScoreChange{LeaderboardID: board, Op: OpResync}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return res, nil
+}
+
+func (res *ReplayResult) count(row outboxRow) {
+	if res.Events == 0 {
+		res.FirstID = row.id
+	}
+	res.Events++
+	res.LastID = row.id
+	res.Ops[row.change.Op]++
+}
+
+// read returns up to DefaultOutboxBatchSize changes with ids in (after, to]
+func (r *Replayer) read(ctx context.Context, after, to int64) ([]outboxRow, error) {
+	rows, err := r.pool.Query(ctx, replayChangesQuery, after, to, DefaultOutboxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []outboxRow
+	for rows.Next() {
+		var row outboxRow
+		c := &row.change
+		if err := rows.Scan(&row.id, &c.LeaderboardID, &c.PlayerName, &c.Score, &c.RankScore, &c.AchievedAt, &c.Metadata, &c.Op, &c.SecondaryScore, &c.RankSecondary, &c.CountryCode, &c.Platform, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		c.ID = row.id
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// dispatch sends replayed changes down the pipeline, and on the broadcast bus
+// when the wrapped source is a bus publisher
+func (r *Replayer) dispatch(ctx context.Context, rows []outboxRow) error {
+	for _, row := range rows {
+		change := row.change
+		change.Replayed = true
+		if err := r.send(ctx, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Replayer) send(ctx context.Context, change ScoreChange) error {
+	if relay, ok := r.inner.(*Relay); ok && relay.local != nil {
+		if err := relay.publishChange(ctx, change); err != nil {
+			r.logger.Error().Err(err).Int64("change_id", change.ID).Msg("failed to publish replayed change on the broadcast bus")
+		}
+	}
+	select {
+	case r.replayChan <- change:
+		return nil
+	case <-r.done:
+		return ErrReplayStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
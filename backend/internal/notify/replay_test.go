@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestReplayerDispatch(t *testing.T) {
+	logger := zerolog.Nop()
+	local := newFakeSource()
+	b := &fakeBus{}
+	r := NewReplayer(nil, NewPublisher(local, b, "replica-1", &logger), &logger)
+	r.Start(context.Background())
+
+	d := NewDispatcher(r.Changes(), &logger)
+	sub := d.Subscribe()
+	go d.Run()
+
+	// Change 1 was delivered live; replaying it must get through the duplicate filter
+	local.changes <- ScoreChange{ID: 1, LeaderboardID: "global", PlayerName: "Alice", Op: "insert"}
+	if got := <-sub; got.ID != 1 || got.Replayed {
+		t.Fatalf("live change = %+v", got)
+	}
+	rows := []outboxRow{
+		{id: 1, change: ScoreChange{ID: 1, LeaderboardID: "global", PlayerName: "Alice", Op: "insert"}},
+		{id: 2, change: ScoreChange{ID: 2, LeaderboardID: "global", PlayerName: "Bob", Op: "insert"}},
+	}
+	if err := r.dispatch(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []int64{1, 2} {
+		if got := <-sub; got.ID != want || !got.Replayed {
+			t.Errorf("replayed change = %+v, want id %d marked replayed", got, want)
+		}
+	}
+
+	// A publisher also shares replayed changes with the other replicas
+	b.mu.Lock()
+	published := b.published
+	b.mu.Unlock()
+	if len(published) != 3 {
+		t.Fatalf("%d messages published, want the live change and 2 replayed ones", len(published))
+	}
+	var env busEnvelope
+	if err := json.Unmarshal(published[2], &env); err != nil || env.ID != 2 || !env.Replayed {
+		t.Errorf("published replay = %s (%v)", published[2], err)
+	}
+
+	local.close()
+	for range sub {
+	}
+	if err := r.send(context.Background(), ScoreChange{Op: OpResync}); err != ErrReplayStopped {
+		t.Errorf("replay after the source stopped: error = %v, want ErrReplayStopped", err)
+	}
+}
+
+func TestReplayerStop(t *testing.T) {
+	logger := zerolog.Nop()
+	local := newFakeSource()
+	r := NewReplayer(nil, local, &logger)
+	r.Start(context.Background())
+
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	select {
+	case <-local.Done():
+	default:
+		t.Error("wrapped source still running after Stop")
+	}
+	if _, ok := <-r.Changes(); ok {
+		t.Error("changes channel still open after Stop")
+	}
+	if err := r.send(context.Background(), ScoreChange{Op: OpResync}); err != ErrReplayStopped {
+		t.Errorf("replay after Stop: error = %v, want ErrReplayStopped", err)
+	}
+}
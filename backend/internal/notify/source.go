@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+// Source produces score changes from a storage backend.
+// Listener (PostgreSQL LISTEN/NOTIFY) is the primary implementation;
+// OutboxPoller tails the PostgreSQL outbox instead, and backends without
+// push notifications implement it by polling.
+type Source interface {
+	// Start begins producing changes until ctx is cancelled or Stop is called,
+	// then closes both channels
+	Start(ctx context.Context)
+
+	// Stop stops a started source and waits until it has released its resources
+	// and closed both channels, or until ctx is done (returning ctx.Err()).
+	// Changes already buffered stay readable.
+	Stop(ctx context.Context) error
+
+	// Done returns a channel closed once the source has stopped and closed both channels
+	Done() <-chan struct{}
+
+	// Changes returns the channel of score changes
+	Changes() <-chan ScoreChange
+
+	// Errors returns the channel of non-fatal source errors
+	Errors() <-chan error
+
+	// Listening reports whether the source is currently receiving changes
+	Listening() bool
+}
+
+var _ Source = (*Listener)(nil)
+
+// stopSource cancels a started source and waits until done is closed or ctx is done
+func stopSource(ctx context.Context, cancel context.CancelFunc, done <-chan struct{}) error {
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeWhenDone closes the channels of a source once all the goroutines sending
+// on them have returned, then done
+func closeWhenDone(wg *sync.WaitGroup, changes chan ScoreChange, errs chan error, done chan struct{}) {
+	wg.Wait()
+	close(changes)
+	close(errs)
+	close(done)
+}
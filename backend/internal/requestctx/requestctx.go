@@ -0,0 +1,190 @@
+// Package requestctx carries caller information (identity, API key, tenant,
+// locale, client version, platform and address) from the transports to the service layer.
+//
+// The REST middleware and the gRPC interceptors both fill an Info from the same
+// headers, so the service reads one value whatever transport the request used.
+package requestctx
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// Header names read by both transports (gRPC metadata keys are their lowercase form)
+const (
+	HeaderRequestID     = "X-Request-Id"
+	HeaderAPIKey        = "X-Api-Key"
+	HeaderTenant        = "X-Tenant-Id"
+	HeaderClientVersion = "X-Client-Version"
+	HeaderPlatform      = "X-Client-Platform"
+	HeaderLocale        = "Accept-Language"
+	HeaderAuthorization = "Authorization"
+	HeaderForwardedFor  = "X-Forwarded-For"
+	HeaderRealIP        = "X-Real-Ip"
+)
+
+// Transport names
+const (
+	TransportGRPC = "grpc"
+	TransportREST = "rest"
+)
+
+// maxValueLength bounds every header-derived value, so clients cannot blow up logs
+const maxValueLength = 128
+
+// Info describes the caller of a request. Every field is optional.
+type Info struct {
+	Transport     string // "grpc" or "rest"
+	RequestID     string
+	Principal     string // authenticated identity, set by authentication layers only
+	APIKey        string // raw API key as sent; never logged
+	Tenant        string
+	Locale        string // primary language tag of Accept-Language, e.g. "fr-FR"
+	ClientVersion string
+	Platform      string     // lowercased, e.g. "android", "ios", "windows"
+	ClientIP      netip.Addr // address of the client, zero when unknown; never logged
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying info
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// FromContext returns the request info of ctx, or a zero Info outside a request
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(contextKey{}).(Info)
+	return info
+}
+
+// WithPrincipal returns a copy of ctx whose request info has the given authenticated principal
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	info := FromContext(ctx)
+	info.Principal = principal
+	return NewContext(ctx, info)
+}
+
+// FromHeaders builds the request info of a transport from its headers.
+// get returns the first value of a header, or "" when absent.
+// A request id is generated when the client did not send one.
+func FromHeaders(transport string, get func(name string) string) Info {
+	info := Info{
+		Transport:     transport,
+		RequestID:     clean(get(HeaderRequestID)),
+		APIKey:        clean(get(HeaderAPIKey)),
+		Tenant:        clean(get(HeaderTenant)),
+		Locale:        primaryLocale(get(HeaderLocale)),
+		ClientVersion: clean(get(HeaderClientVersion)),
+		Platform:      strings.ToLower(clean(get(HeaderPlatform))),
+	}
+	if info.RequestID == "" {
+		info.RequestID = newRequestID()
+	}
+	return info
+}
+
+// ClientIP returns the address of the client of a request: the first address of
+// X-Forwarded-For, else X-Real-Ip, else the host of remoteAddr, the peer of the
+// connection ("host:port" or a bare address). The zero Addr when none parses.
+// Behind a proxy the headers name the client; without one a client can set them,
+// so the result only serves as a hint, e.g. for geolocation.
+func ClientIP(get func(name string) string, remoteAddr string) netip.Addr {
+	first, _, _ := strings.Cut(get(HeaderForwardedFor), ",")
+	for _, value := range []string{first, get(HeaderRealIP)} {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(value)); err == nil {
+			return addr.Unmap()
+		}
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, _ := netip.ParseAddr(host)
+	return addr.Unmap()
+}
+
+// KeyID returns the fingerprint of an API key, "k_" and 16 hex digits of its
+// SHA-256, which identifies the key in logs and usage statistics without revealing it
+func KeyID(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "k_" + hex.EncodeToString(sum[:8])
+}
+
+// MarshalZerologObject logs the request info. The API key is reduced to its KeyID.
+func (i Info) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("transport", i.Transport).Str("request_id", i.RequestID)
+	if i.Principal != "" {
+		e.Str("principal", i.Principal)
+	}
+	if i.APIKey != "" {
+		e.Str("api_key_id", KeyID(i.APIKey))
+	}
+	if i.Tenant != "" {
+		e.Str("tenant", i.Tenant)
+	}
+	if i.Locale != "" {
+		e.Str("locale", i.Locale)
+	}
+	if i.ClientVersion != "" {
+		e.Str("client_version", i.ClientVersion)
+	}
+	if i.Platform != "" {
+		e.Str("platform", i.Platform)
+	}
+}
+
+// Logger returns logger annotated with the request info of ctx under "request",
+// or logger itself outside a request, so every log line of a request carries its id
+func Logger(ctx context.Context, logger *zerolog.Logger) *zerolog.Logger {
+	info := FromContext(ctx)
+	if info.Transport == "" {
+		return logger
+	}
+	l := logger.With().Object("request", info).Logger()
+	return &l
+}
+
+// BearerToken returns the token of an "Authorization: Bearer <token>" value, or ""
+func BearerToken(value string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// primaryLocale returns the first language tag of an Accept-Language value
+// ("fr-FR,fr;q=0.9,en;q=0.8" -> "fr-FR"); "*" counts as no preference.
+func primaryLocale(value string) string {
+	tag, _, _ := strings.Cut(value, ",")
+	tag, _, _ = strings.Cut(tag, ";")
+	tag = clean(tag)
+	if tag == "*" {
+		return ""
+	}
+	return tag
+}
+
+// clean trims a header value and caps its length
+func clean(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) > maxValueLength {
+		value = value[:maxValueLength]
+	}
+	return value
+}
+
+// newRequestID returns a random 16-byte hex id
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
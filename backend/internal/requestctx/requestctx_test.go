@@ -0,0 +1,124 @@
+package requestctx
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestFromHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set(HeaderAPIKey, " key-123 ")
+	h.Set(HeaderTenant, "acme")
+	h.Set(HeaderLocale, "fr-FR,fr;q=0.9,en;q=0.8")
+	h.Set(HeaderClientVersion, strings.Repeat("v", 500))
+
+	info := FromHeaders(TransportREST, h.Get)
+	if info.Transport != TransportREST || info.APIKey != "key-123" || info.Tenant != "acme" || info.Locale != "fr-FR" {
+		t.Errorf("FromHeaders() = %+v", info)
+	}
+	if len(info.ClientVersion) != maxValueLength {
+		t.Errorf("client version length = %d, want capped at %d", len(info.ClientVersion), maxValueLength)
+	}
+	if info.RequestID == "" {
+		t.Error("request id not generated")
+	}
+
+	h.Set(HeaderRequestID, "req-1")
+	if got := FromHeaders(TransportREST, h.Get).RequestID; got != "req-1" {
+		t.Errorf("request id = %q, want client value req-1", got)
+	}
+}
+
+func TestPrimaryLocale(t *testing.T) {
+	tests := map[string]string{
+		"":                   "",
+		"*":                  "",
+		"en":                 "en",
+		"de-CH;q=0.9, de":    "de-CH",
+		" pt-BR , pt;q=0.5 ": "pt-BR",
+	}
+	for value, want := range tests {
+		if got := primaryLocale(value); got != want {
+			t.Errorf("primaryLocale(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := map[string]string{
+		"":               "",
+		"Bearer s3cret":  "s3cret",
+		"bearer  s3cret": "s3cret",
+		"Basic dXNlcjpw": "",
+		"s3cret":         "",
+	}
+	for value, want := range tests {
+		if got := BearerToken(value); got != want {
+			t.Errorf("BearerToken(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		forwardedFor, realIP, remoteAddr string
+		want                             string
+	}{
+		{"", "", "192.0.2.1:5000", "192.0.2.1"},
+		{"", "", "[2001:db8::1]:5000", "2001:db8::1"},
+		{"", "", "192.0.2.1", "192.0.2.1"},
+		{"203.0.113.7, 10.0.0.1", "198.51.100.2", "10.0.0.1:5000", "203.0.113.7"},
+		{"", "198.51.100.2", "10.0.0.1:5000", "198.51.100.2"},
+		{"garbage", "", "::ffff:192.0.2.9", "192.0.2.9"},
+		{"", "", "pipe", "invalid IP"},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set(HeaderForwardedFor, tt.forwardedFor)
+		h.Set(HeaderRealIP, tt.realIP)
+		if got := ClientIP(h.Get, tt.remoteAddr); got.String() != tt.want {
+			t.Errorf("ClientIP(%q, %q, %q) = %s, want %s", tt.forwardedFor, tt.realIP, tt.remoteAddr, got, tt.want)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if info := FromContext(ctx); info != (Info{}) {
+		t.Errorf("FromContext(empty) = %+v, want zero Info", info)
+	}
+
+	ctx = NewContext(ctx, Info{Transport: TransportGRPC, Tenant: "acme"})
+	ctx = WithPrincipal(ctx, "Alice")
+	if info := FromContext(ctx); info.Principal != "Alice" || info.Tenant != "acme" {
+		t.Errorf("FromContext() = %+v, want principal Alice and tenant kept", info)
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	id := KeyID("key-123")
+	if len(id) != 18 || !strings.HasPrefix(id, "k_") {
+		t.Errorf("KeyID = %q, want k_ and 16 hex digits", id)
+	}
+	if KeyID("key-123") != id || KeyID("key-124") == id {
+		t.Error("KeyID is not a stable fingerprint of the key")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var out strings.Builder
+	base := zerolog.New(&out)
+
+	Logger(context.Background(), &base).Info().Msg("background")
+	ctx := NewContext(context.Background(), Info{Transport: TransportREST, RequestID: "req-1"})
+	Logger(ctx, &base).Info().Msg("request")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || strings.Contains(lines[0], "request_id") || !strings.Contains(lines[1], `"request_id":"req-1"`) {
+		t.Errorf("log lines = %q", lines)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/requestctx"
+)
+
+var (
+	// ErrAdminDisabled is returned by admin operations when no admin token is configured
+	ErrAdminDisabled = errors.New("admin operations are disabled")
+
+	// ErrAdminUnauthorized is returned when the admin token is missing or wrong
+	ErrAdminUnauthorized = errors.New("admin token required")
+)
+
+// AdminPrincipal is the request principal of callers authenticated with the admin token
+const AdminPrincipal = "admin"
+
+// DefaultAdminConfirmTTL is how long a confirmation token stays valid by default
+const DefaultAdminConfirmTTL = 2 * time.Minute
+
+// Admin configures destructive operator operations
+type Admin struct {
+	// Token is the shared bearer token of operators (empty disables admin operations)
+	Token string
+
+	// ConfirmTTL is how long the confirmation token of a destructive operation stays valid
+	ConfirmTTL time.Duration
+
+	// RestoreMaxEntries is the maximum number of entries of a restore (0 uses DefaultRestoreMaxEntries)
+	RestoreMaxEntries int
+}
+
+// AuthenticateAdmin checks a bearer token against the admin token and returns a
+// copy of ctx whose principal is AdminPrincipal
+func (s *Service) AuthenticateAdmin(ctx context.Context, token string) (context.Context, error) {
+	if s.opts.Admin.Token == "" {
+		return ctx, ErrAdminDisabled
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Admin.Token)) != 1 {
+		return ctx, ErrAdminUnauthorized
+	}
+	return requestctx.WithPrincipal(ctx, AdminPrincipal), nil
+}
+
+// requireAdmin rejects requests that were not authenticated with AuthenticateAdmin
+func (s *Service) requireAdmin(ctx context.Context) error {
+	if s.opts.Admin.Token == "" {
+		return ErrAdminDisabled
+	}
+	if requestctx.FromContext(ctx).Principal != AdminPrincipal {
+		return ErrAdminUnauthorized
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+// ErrOverloaded is returned when write capacity is saturated and the request was shed
+var ErrOverloaded = errors.New("server overloaded, retry later")
+
+// Admission configures the global concurrency limit on write operations
+type Admission struct {
+	MaxConcurrent int64         // max in-flight writes (0 disables admission control)
+	MaxWait       time.Duration // how long a write may queue for a slot before being shed
+	RetryAfter    time.Duration // back-off hint returned to shed clients
+}
+
+// admit reserves a write slot. The returned release func must be called when the
+// write is done. When no slot frees up within MaxWait the request is shed with
+// ErrOverloaded instead of queueing indefinitely.
+func (s *Service) admit(ctx context.Context) (func(), error) {
+	if s.writes == nil {
+		return func() {}, nil
+	}
+
+	if !s.writes.TryAcquire(1) {
+		waitCtx, cancel := context.WithTimeout(ctx, s.opts.Admission.MaxWait)
+		defer cancel()
+		if err := s.writes.Acquire(waitCtx, 1); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			metrics.AdmissionRejected.Inc()
+			s.lastShed.Store(time.Now().UnixNano())
+			s.loggerFor(ctx).Warn().Int64("max_concurrent", s.opts.Admission.MaxConcurrent).Msg("write capacity saturated, shedding request")
+			return nil, ErrOverloaded
+		}
+	}
+
+	metrics.WritesInFlight.Inc()
+	return func() {
+		metrics.WritesInFlight.Dec()
+		s.writes.Release(1)
+	}, nil
+}
+
+// RetryAfter is the back-off hint clients should honor after ErrOverloaded
+func (s *Service) RetryAfter() time.Duration {
+	return s.opts.Admission.RetryAfter
+}
+
+// LastShed returns when a write was last shed by admission control (zero if never)
+func (s *Service) LastShed() time.Time {
+	if ns := s.lastShed.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
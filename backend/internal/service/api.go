@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/usage"
+)
+
+// Leaderboard is the service as the gRPC and REST transports use it. *Service
+// implements it; handler tests use a fake instead of a database.
+type Leaderboard interface {
+	// Scores
+	SubmitScore(ctx context.Context, sub ScoreSubmission) (*ScoreResult, error)
+	SyncOfflineScores(ctx context.Context, batch OfflineBatch) ([]OfflineRunResult, error)
+	VerifyReceipt(r Receipt) (*ReceiptVerification, error)
+	ImportScores(ctx context.Context, entries []ScoreImport) ([]ScoreImportResult, error)
+	ExportScores(ctx context.Context, board string, fn func(scores []store.Score) error) error
+	StreamChunkSize() int
+	DeleteScore(ctx context.Context, board, playerName string) error
+	RestoreScore(ctx context.Context, board, playerName string) (*store.Score, error)
+	GetDeletedScores(ctx context.Context, board string, limit int32) ([]store.Score, error)
+	GetChangesSince(ctx context.Context, board string, sinceSeq int64, since time.Time, limit int32) (*notify.ChangePage, error)
+
+	// Rankings
+	GetTopScores(ctx context.Context, board string, limit, offset int32) ([]store.Score, error)
+	GetTopScoresPage(ctx context.Context, board string, limit, offset int32, pageToken string) (*TopScoresPage, error)
+	GetSegmentTopScoresPage(ctx context.Context, board string, seg Segment, limit, offset int32, pageToken string) (*TopScoresPage, error)
+	GetPlayerRank(ctx context.Context, board, playerName string) (*PlayerRank, error)
+	GetSegmentPlayerRank(ctx context.Context, board, playerName string, seg Segment) (*PlayerRank, error)
+	GetPlayerRanks(ctx context.Context, board string, playerNames []string) (*PlayerRanks, error)
+	SimulateRank(ctx context.Context, board string, score int64, playerName string, seg Segment) (*RankSimulation, error)
+	GetPercentileBuckets(ctx context.Context, board string, seg Segment) (*PercentileSnapshot, error)
+	GetEntryCount(ctx context.Context, board string, seg Segment) (int64, error)
+	GetScoreDistribution(ctx context.Context, board string, buckets int32) (*ScoreDistribution, error)
+	GetBoardStats(ctx context.Context, board string) (BoardStats, error)
+	TierFor(board string, score int64) string
+	BoardVersion(board string) string
+	TierChanges() <-chan TierChange
+
+	// Players
+	UpsertPlayerProfile(ctx context.Context, update ProfileUpdate) (*store.Player, error)
+	GetPlayerProfile(ctx context.Context, playerName string) (*store.Player, error)
+	PlayerProfiles(ctx context.Context, playerNames []string) map[string]store.Player
+	ExportPlayerData(ctx context.Context, playerName string) (*store.PlayerData, error)
+	ErasePlayerData(ctx context.Context, playerName string) (*store.ErasureResult, error)
+	RenamePlayer(ctx context.Context, playerName, newPlayerName string, merge bool) (*store.RenameResult, error)
+	BanPlayer(ctx context.Context, playerName, reason string, purge bool) (*store.BanResult, error)
+	UnbanPlayer(ctx context.Context, playerName string) error
+	GetBan(ctx context.Context, playerName string) (*store.BannedPlayer, error)
+	ListBans(ctx context.Context) ([]store.BannedPlayer, error)
+
+	// Boards
+	UpsertLeaderboard(ctx context.Context, board string, order, secondaryOrder SortOrder) (*store.Leaderboard, error)
+	GetLeaderboard(ctx context.Context, board string) (*store.Leaderboard, error)
+	CurrentDailyBoard(ctx context.Context) (*DailyBoard, error)
+	ResetLeaderboard(ctx context.Context, leaderboardID string, snapshot bool, confirmation string) (*ResetResult, error)
+	RestoreLeaderboard(ctx context.Context, leaderboardID string, src RestoreSource, snapshot bool, confirmation string) (*RestoreResult, error)
+
+	// Operations
+	AuthenticateAdmin(ctx context.Context, token string) (context.Context, error)
+	ServerInfo(ctx context.Context) (*ServerInfo, error)
+	RetryAfter() time.Duration
+	ReplayEvents(ctx context.Context, fromID, toID int64, dryRun bool) (*notify.ReplayResult, error)
+	CreateWebhook(ctx context.Context, spec WebhookSpec) (*store.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]store.Webhook, error)
+	GetWebhook(ctx context.Context, id int64) (*store.Webhook, error)
+	UpdateWebhook(ctx context.Context, id int64, spec WebhookSpec) (*store.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	ListWebhookDeliveries(ctx context.Context, id int64, limit int32) ([]store.WebhookDelivery, error)
+	RecordUsage(ctx context.Context, method string, failed bool)
+	KeyUsage(ctx context.Context, keyID string) (*usage.Report, error)
+	TopKeyUsage(ctx context.Context, limit int32) ([]usage.Report, error)
+}
+
+var _ Leaderboard = (*Service)(nil)
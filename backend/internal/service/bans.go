@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+var (
+	// ErrPlayerBanned is returned when a banned player submits a score
+	ErrPlayerBanned = errors.New("player is banned")
+
+	// ErrBanNotFound is returned when looking up or lifting the ban of a player who is not banned
+	ErrBanNotFound = errors.New("player is not banned")
+
+	// ErrInvalidBan is returned when the reason of a ban is too long
+	ErrInvalidBan = errors.New("invalid ban")
+)
+
+// MaxBanReasonLength is the maximum length of the reason of a ban, in characters
+const MaxBanReasonLength = 256
+
+// BanPlayer bans a player: their submissions are rejected with ErrPlayerBanned
+// until UnbanPlayer. Banning a banned player replaces the reason. With purge,
+// the player's live scores on every board are deleted, which stream subscribers
+// receive as deletes; RestoreScore can bring them back. Admin only.
+func (s *Service) BanPlayer(ctx context.Context, playerName, reason string, purge bool) (*store.BanResult, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validatePlayerName(playerName); err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(reason) > MaxBanReasonLength {
+		return nil, fmt.Errorf("%w: reason must be at most %d characters", ErrInvalidBan, MaxBanReasonLength)
+	}
+
+	res, err := s.store.BanPlayer(ctx, playerName, reason, purge)
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("player", playerName).Msg("failed to ban player")
+		return nil, fmt.Errorf("ban player: %w", err)
+	}
+	slices.Sort(res.Purged)
+
+	s.loggerFor(ctx).Warn().
+		Str("player", playerName).
+		Str("reason", reason).
+		Strs("purged_leaderboards", res.Purged).
+		Msg("🚫 player banned")
+	return &res, nil
+}
+
+// UnbanPlayer lifts the ban of a player; scores purged by the ban stay deleted.
+// ErrBanNotFound when the player is not banned. Admin only.
+func (s *Service) UnbanPlayer(ctx context.Context, playerName string) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := s.validatePlayerName(playerName); err != nil {
+		return err
+	}
+	if err := s.store.UnbanPlayer(ctx, playerName); err != nil {
+		return banError("unban player", err)
+	}
+	s.loggerFor(ctx).Warn().Str("player", playerName).Msg("player unbanned")
+	return nil
+}
+
+// GetBan returns the ban of a player, ErrBanNotFound when not banned. Admin only.
+func (s *Service) GetBan(ctx context.Context, playerName string) (*store.BannedPlayer, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validatePlayerName(playerName); err != nil {
+		return nil, err
+	}
+	ban, err := s.store.GetBannedPlayer(ctx, playerName)
+	if err != nil {
+		return nil, banError("get ban", err)
+	}
+	return &ban, nil
+}
+
+// ListBans returns every banned player, most recently banned first. Admin only.
+func (s *Service) ListBans(ctx context.Context) ([]store.BannedPlayer, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	bans, err := s.store.ListBannedPlayers(ctx)
+	if err != nil {
+		return nil, banError("list bans", err)
+	}
+	return bans, nil
+}
+
+// checkBanned rejects the submissions of a banned player
+func (s *Service) checkBanned(ctx context.Context, playerName string) error {
+	_, err := s.store.GetBannedPlayer(ctx, playerName)
+	switch {
+	case err == nil:
+		metrics.BannedSubmissions.Inc()
+		return fmt.Errorf("%w: %s may not submit scores", ErrPlayerBanned, playerName)
+	case errors.Is(err, store.ErrNoRows):
+		return nil
+	}
+	s.loggerFor(ctx).Error().Err(err).Str("player", playerName).Msg("failed to check ban")
+	return fmt.Errorf("check ban: %w", err)
+}
+
+// banError maps the store errors of a ban operation
+func banError(op string, err error) error {
+	if errors.Is(err, store.ErrNoRows) {
+		return ErrBanNotFound
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
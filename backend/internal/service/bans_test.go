@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestBanPlayer(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	key := "test-key"
+	svc := New(st, &logger, Options{
+		Admin:            Admin{Token: "s3cret"},
+		ClientTimestamps: ClientTimestamps{SkewWindow: time.Minute, MaxAge: 24 * time.Hour},
+		OfflineSync:      OfflineSync{SigningKey: []byte(key), MaxRuns: 10},
+	})
+
+	for _, sub := range []ScoreSubmission{
+		{PlayerName: "Mallory", Score: 900},
+		{PlayerName: "Mallory", Score: 400, LeaderboardID: "level-1"},
+		{PlayerName: "Alice", Score: 300},
+	} {
+		if _, err := svc.SubmitScore(ctx, sub); err != nil {
+			t.Fatalf("seed score: %v", err)
+		}
+	}
+
+	if _, err := svc.BanPlayer(ctx, "Mallory", "", true); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated ban: error = %v, want ErrAdminUnauthorized", err)
+	}
+	admin, _ := svc.AuthenticateAdmin(ctx, "s3cret")
+
+	if _, err := svc.BanPlayer(admin, "", "", false); !errors.Is(err, ErrInvalidPlayerName) {
+		t.Errorf("ban without a name: error = %v, want ErrInvalidPlayerName", err)
+	}
+	if _, err := svc.BanPlayer(admin, "Mallory", strings.Repeat("x", MaxBanReasonLength+1), false); !errors.Is(err, ErrInvalidBan) {
+		t.Errorf("ban with a long reason: error = %v, want ErrInvalidBan", err)
+	}
+	if _, err := svc.GetBan(admin, "Mallory"); !errors.Is(err, ErrBanNotFound) {
+		t.Errorf("ban of a player not banned: error = %v, want ErrBanNotFound", err)
+	}
+
+	res, err := svc.BanPlayer(admin, "Mallory", "cheating", true)
+	if err != nil {
+		t.Fatalf("ban: %v", err)
+	}
+	if res.Ban.Reason != "cheating" || fmt.Sprint(res.Purged) != "[global level-1]" {
+		t.Errorf("ban = %+v, want Mallory's two boards purged", res)
+	}
+	if rank, err := svc.GetPlayerRank(ctx, "", "Alice"); err != nil || rank.Rank != 1 {
+		t.Errorf("Alice's rank after the purge = %v, %v, want 1", rank, err)
+	}
+
+	// Banned players cannot submit, live or offline
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Mallory", Score: 1000}); !errors.Is(err, ErrPlayerBanned) {
+		t.Errorf("submission of a banned player: error = %v, want ErrPlayerBanned", err)
+	}
+	runs := []OfflineRun{
+		{RunID: "r1", PlayerName: "Mallory", Score: 1000, AchievedAt: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)},
+		{RunID: "r2", PlayerName: "Bob", Score: 100, AchievedAt: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)},
+	}
+	results, err := svc.SyncOfflineScores(ctx, OfflineBatch{Runs: runs, Signature: SignOfflineBatch([]byte(key), "", runs)})
+	if err != nil {
+		t.Fatalf("SyncOfflineScores: %v", err)
+	}
+	if results[0].Outcome != OfflineBanned || results[1].Outcome != OfflineApplied {
+		t.Errorf("offline outcomes = %s, %s, want %s, %s", results[0].Outcome, results[1].Outcome, OfflineBanned, OfflineApplied)
+	}
+
+	if bans, err := svc.ListBans(admin); err != nil || len(bans) != 1 || bans[0].PlayerName != "Mallory" {
+		t.Errorf("ListBans = %+v, %v, want Mallory", bans, err)
+	}
+	if err := svc.UnbanPlayer(admin, "Mallory"); err != nil {
+		t.Fatalf("unban: %v", err)
+	}
+	if err := svc.UnbanPlayer(admin, "Mallory"); !errors.Is(err, ErrBanNotFound) {
+		t.Errorf("second unban: error = %v, want ErrBanNotFound", err)
+	}
+	if r, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Mallory", Score: 50}); err != nil || !r.Applied {
+		t.Errorf("submission after the unban = %+v, %v, want applied", r, err)
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/notify"
+)
+
+var (
+	// ErrChangesUnavailable is returned by GetChangesSince when the storage backend keeps no outbox
+	ErrChangesUnavailable = errors.New("change polling is not available with this storage backend")
+
+	// ErrInvalidCheckpoint is returned for a negative seq, or both a seq and a time, as a poll checkpoint
+	ErrInvalidCheckpoint = errors.New("invalid checkpoint")
+)
+
+// Limits of change polls
+const (
+	DefaultChanges = 100
+	MaxChanges     = 1000
+)
+
+// ChangeReader reads the changes of a board from the score_changes outbox (notify.ChangeLog)
+type ChangeReader interface {
+	ChangesSince(ctx context.Context, board string, afterID int64, since time.Time, limit int) (*notify.ChangePage, error)
+}
+
+// GetChangesSince returns the entries of a board changed since a checkpoint, for
+// clients that poll instead of holding a stream open: sinceSeq is the seq of the
+// last change the client applied, since the time of its last poll (a first poll
+// after a restart, say). Set one at most; without either the page asks for a
+// resync and carries the seq to poll from. limit 0 means DefaultChanges, larger
+// limits are capped at MaxChanges.
+func (s *Service) GetChangesSince(ctx context.Context, board string, sinceSeq int64, since time.Time, limit int32) (*notify.ChangePage, error) {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+	if sinceSeq < 0 {
+		return nil, fmt.Errorf("%w: seq must be non-negative", ErrInvalidCheckpoint)
+	}
+	if sinceSeq > 0 && !since.IsZero() {
+		return nil, fmt.Errorf("%w: set a seq or a time, not both", ErrInvalidCheckpoint)
+	}
+	if limit <= 0 {
+		limit = DefaultChanges
+	}
+	limit = min(limit, MaxChanges)
+	if s.opts.Changes == nil {
+		return nil, ErrChangesUnavailable
+	}
+
+	page, err := s.opts.Changes.ChangesSince(ctx, board, sinceSeq, since, int(limit))
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Int64("since_seq", sinceSeq).Msg("failed to read changes")
+		return nil, fmt.Errorf("read changes: %w", err)
+	}
+	return page, nil
+}
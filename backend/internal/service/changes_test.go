@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/notify"
+)
+
+// recordingChangeReader records the polls it is asked for
+type recordingChangeReader struct {
+	board  string
+	after  int64
+	since  time.Time
+	limits []int
+}
+
+func (r *recordingChangeReader) ChangesSince(_ context.Context, board string, afterID int64, since time.Time, limit int) (*notify.ChangePage, error) {
+	r.board, r.after, r.since = board, afterID, since
+	r.limits = append(r.limits, limit)
+	return &notify.ChangePage{LastID: afterID}, nil
+}
+
+func TestGetChangesSince(t *testing.T) {
+	logger := zerolog.Nop()
+	changes := &recordingChangeReader{}
+	svc := New(nil, &logger, Options{Changes: changes})
+	ctx := context.Background()
+	since := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	if _, err := svc.GetChangesSince(ctx, "", -1, time.Time{}, 0); !errors.Is(err, ErrInvalidCheckpoint) {
+		t.Errorf("negative seq: error = %v, want ErrInvalidCheckpoint", err)
+	}
+	if _, err := svc.GetChangesSince(ctx, "", 42, since, 0); !errors.Is(err, ErrInvalidCheckpoint) {
+		t.Errorf("seq and time: error = %v, want ErrInvalidCheckpoint", err)
+	}
+	if _, err := svc.GetChangesSince(ctx, "bad board!", 42, time.Time{}, 0); !errors.Is(err, ErrInvalidLeaderboardID) {
+		t.Errorf("invalid board: error = %v, want ErrInvalidLeaderboardID", err)
+	}
+
+	page, err := svc.GetChangesSince(ctx, "", 42, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("GetChangesSince: %v", err)
+	}
+	if page.LastID != 42 || changes.board != DefaultLeaderboardID || changes.after != 42 || !changes.since.IsZero() {
+		t.Errorf("poll of %s after %d since %v, want the default board after 42", changes.board, changes.after, changes.since)
+	}
+	if _, err := svc.GetChangesSince(ctx, "level-1", 0, since, MaxChanges+1); err != nil {
+		t.Fatalf("GetChangesSince a time: %v", err)
+	}
+	if changes.board != "level-1" || changes.after != 0 || !changes.since.Equal(since) {
+		t.Errorf("poll of %s after %d since %v, want level-1 since %v", changes.board, changes.after, changes.since, since)
+	}
+	if len(changes.limits) != 2 || changes.limits[0] != DefaultChanges || changes.limits[1] != MaxChanges {
+		t.Errorf("limits = %v, want the default then the cap", changes.limits)
+	}
+
+	noOutbox := New(nil, &logger, Options{})
+	if _, err := noOutbox.GetChangesSince(ctx, "", 42, time.Time{}, 0); !errors.Is(err, ErrChangesUnavailable) {
+		t.Errorf("without an outbox: error = %v, want ErrChangesUnavailable", err)
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+)
+
+// Submission outcomes, as labeled in cohort metrics
+const (
+	outcomeApplied     = "applied"
+	outcomeNotImproved = "not_improved"
+	outcomeRejected    = "rejected" // refused by validation, signature, device or board checks
+	outcomeFailed      = "failed"   // shed by admission control or storage error
+)
+
+// Cohort label values
+const (
+	TenureNew       = "new"       // profile created within CohortMetrics.NewPlayerWindow
+	TenureReturning = "returning" // profile older than CohortMetrics.NewPlayerWindow
+	CohortUnknown   = "unknown"   // no profile, platform or client version to go by
+	CohortOther     = "other"     // a platform or client version outside the tracked ones
+)
+
+// DefaultCohortPlatforms are the platforms labeled as such when none are configured
+var DefaultCohortPlatforms = []string{"android", "ios", "windows", "macos", "linux", "web"}
+
+// CohortMetrics labels SubmitScore outcomes with the cohort of the submitting player,
+// so rejection rates can be compared between new and returning players, platforms
+// and client builds. Tenure comes from the player's profile (through the profile
+// cache), platform and client version from the request headers.
+type CohortMetrics struct {
+	Enabled bool
+
+	// NewPlayerWindow is how long after its profile was created a player counts as new
+	NewPlayerWindow time.Duration
+
+	// Platforms are the platforms labeled by name (DefaultCohortPlatforms when empty);
+	// others are labeled CohortOther
+	Platforms []string
+
+	// MaxClientVersions bounds the client versions labeled by name: the first ones
+	// seen are kept, later ones are labeled CohortOther
+	MaxClientVersions int
+}
+
+// rejectionErrors are the SubmitScore errors caused by the submission itself
+var rejectionErrors = []error{
+	ErrInvalidLeaderboardID,
+	ErrLeaderboardClosed,
+	ErrInvalidPlayerName,
+	ErrInvalidScore,
+	ErrInvalidDeviceID,
+	ErrInvalidMetadata,
+	ErrInvalidCountry,
+	ErrInvalidPlatform,
+	ErrInvalidSignature,
+	ErrDeviceLimitExceeded,
+	ErrPlayerBanned,
+}
+
+// cohortVersions keeps the client versions labeled by name
+type cohortVersions struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+// observeSubmission counts a SubmitScore outcome under the cohort of its player
+func (s *Service) observeSubmission(ctx context.Context, playerName string, result *ScoreResult, err error) {
+	cfg := s.opts.Cohorts
+	if !cfg.Enabled {
+		return
+	}
+
+	outcome := submissionOutcome(result, err)
+	tenure := CohortUnknown
+	if !errors.Is(err, ErrInvalidPlayerName) {
+		tenure = s.playerTenure(ctx, playerName, time.Now())
+	}
+	info := requestctx.FromContext(ctx)
+	metrics.CohortSubmissions.WithLabelValues(outcome, tenure, cohortPlatform(info.Platform, cfg.Platforms), s.cohortClientVersion(info.ClientVersion)).Inc()
+}
+
+// submissionOutcome classifies the result of a SubmitScore call
+func submissionOutcome(result *ScoreResult, err error) string {
+	switch {
+	case err == nil && result.Applied:
+		return outcomeApplied
+	case err == nil:
+		return outcomeNotImproved
+	}
+	for _, target := range rejectionErrors {
+		if errors.Is(err, target) {
+			return outcomeRejected
+		}
+	}
+	return outcomeFailed
+}
+
+// playerTenure tells new players from returning ones by the age of their profile
+func (s *Service) playerTenure(ctx context.Context, playerName string, now time.Time) string {
+	profile, ok := s.localProfiles(ctx, []string{playerName})[playerName]
+	if !ok || !profile.CreatedAt.Valid {
+		return CohortUnknown
+	}
+	if now.Sub(profile.CreatedAt.Time) < s.opts.Cohorts.NewPlayerWindow {
+		return TenureNew
+	}
+	return TenureReturning
+}
+
+// cohortPlatform returns the platform label of a request
+func cohortPlatform(platform string, tracked []string) string {
+	if platform == "" {
+		return CohortUnknown
+	}
+	if len(tracked) == 0 {
+		tracked = DefaultCohortPlatforms
+	}
+	if slices.Contains(tracked, platform) {
+		return platform
+	}
+	return CohortOther
+}
+
+// cohortClientVersion returns the client version label of a request
+func (s *Service) cohortClientVersion(version string) string {
+	if version == "" {
+		return CohortUnknown
+	}
+
+	c := &s.cohortVersions
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.seen[version]; ok {
+		return version
+	}
+	if len(c.seen) >= s.opts.Cohorts.MaxClientVersions {
+		return CohortOther
+	}
+	if c.seen == nil {
+		c.seen = make(map[string]struct{})
+	}
+	c.seen[version] = struct{}{}
+	return version
+}
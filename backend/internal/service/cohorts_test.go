@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestSubmissionOutcome(t *testing.T) {
+	cases := []struct {
+		result *ScoreResult
+		err    error
+		want   string
+	}{
+		{&ScoreResult{Applied: true}, nil, outcomeApplied},
+		{&ScoreResult{}, nil, outcomeNotImproved},
+		{nil, fmt.Errorf("%w: too long", ErrInvalidPlayerName), outcomeRejected},
+		{nil, fmt.Errorf("%w: replayed nonce", ErrInvalidSignature), outcomeRejected},
+		{nil, ErrOverloaded, outcomeFailed},
+		{nil, fmt.Errorf("upsert score: %w", context.DeadlineExceeded), outcomeFailed},
+	}
+	for _, c := range cases {
+		if got := submissionOutcome(c.result, c.err); got != c.want {
+			t.Errorf("submissionOutcome(%+v, %v) = %q, want %q", c.result, c.err, got, c.want)
+		}
+	}
+}
+
+func TestCohortLabels(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{Cohorts: CohortMetrics{
+		Enabled:           true,
+		NewPlayerWindow:   time.Hour,
+		MaxClientVersions: 2,
+	}})
+
+	if _, err := svc.UpsertPlayerProfile(ctx, ProfileUpdate{PlayerName: "Alice"}); err != nil {
+		t.Fatalf("UpsertPlayerProfile: %v", err)
+	}
+	now := time.Now()
+	if got := svc.playerTenure(ctx, "Alice", now); got != TenureNew {
+		t.Errorf("tenure of a fresh profile = %q, want %q", got, TenureNew)
+	}
+	if got := svc.playerTenure(ctx, "Alice", now.Add(2*time.Hour)); got != TenureReturning {
+		t.Errorf("tenure after the window = %q, want %q", got, TenureReturning)
+	}
+	if got := svc.playerTenure(ctx, "Bob", now); got != CohortUnknown {
+		t.Errorf("tenure without a profile = %q, want %q", got, CohortUnknown)
+	}
+
+	for _, c := range []struct{ platform, want string }{
+		{"android", "android"},
+		{"", CohortUnknown},
+		{"dreamcast", CohortOther},
+	} {
+		if got := cohortPlatform(c.platform, nil); got != c.want {
+			t.Errorf("cohortPlatform(%q) = %q, want %q", c.platform, got, c.want)
+		}
+	}
+
+	for _, c := range []struct{ version, want string }{
+		{"1.0", "1.0"},
+		{"1.1", "1.1"},
+		{"1.2", CohortOther},
+		{"1.0", "1.0"},
+		{"", CohortUnknown},
+	} {
+		if got := svc.cohortClientVersion(c.version); got != c.want {
+			t.Errorf("cohortClientVersion(%q) = %q, want %q", c.version, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/store"
+	"golang.org/x/sync/singleflight"
+)
+
+// countCache caches the number of entries of each board segment. Whole-board
+// counts follow the board's insert and delete changes between recounts; segment
+// counts are only refreshed by recounts, as an update may move a player across
+// segments.
+type countCache struct {
+	mu     sync.Mutex
+	counts map[countKey]entryCount
+
+	// generations counts the changes of each board that move its whole-board
+	// count, and global the resyncs of every board. A count started before one
+	// of them may or may not include it, so it is not cached.
+	generations map[string]uint64
+	global      uint64
+
+	// flight shares a count in progress among the reads of the same segment
+	flight singleflight.Group
+}
+
+type countKey struct {
+	board string
+	seg   Segment
+}
+
+type entryCount struct {
+	total     int64
+	countedAt time.Time
+}
+
+// GetEntryCount returns the number of entries of a board, among the players of a
+// segment (the zero Segment for the whole board). Counts are reused for
+// Options.CountCacheTTL since counting scans the board, which would otherwise
+// happen on every page read.
+func (s *Service) GetEntryCount(ctx context.Context, board string, seg Segment) (int64, error) {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return 0, err
+	}
+	if seg, err = normalizeSegment(seg); err != nil {
+		return 0, err
+	}
+	return s.entryCount(ctx, board, seg)
+}
+
+// entryCount is GetEntryCount for a resolved board and a normalized segment
+func (s *Service) entryCount(ctx context.Context, board string, seg Segment) (int64, error) {
+	key := countKey{board: board, seg: seg}
+
+	s.counts.mu.Lock()
+	cached, ok := s.counts.counts[key]
+	s.counts.mu.Unlock()
+	if ok && time.Since(cached.countedAt) < s.opts.CountCacheTTL {
+		return cached.total, nil
+	}
+
+	// Count without the lock: a slow count must not hold up the other boards.
+	// Concurrent misses share one count, which outlives a caller that gives up.
+	ch := s.counts.flight.DoChan(board+"\x00"+seg.Region+"\x00"+seg.Platform, func() (any, error) {
+		return s.countEntries(context.WithoutCancel(ctx), key)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return 0, res.Err
+		}
+		return res.Val.(int64), nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// countEntries counts the entries of a segment and caches the count, unless a
+// change overtook it
+func (s *Service) countEntries(ctx context.Context, key countKey) (int64, error) {
+	s.counts.mu.Lock()
+	gen := s.counts.generation(key.board)
+	s.counts.mu.Unlock()
+
+	var (
+		total int64
+		err   error
+	)
+	if key.seg.IsZero() {
+		total, err = s.store.CountScores(ctx, key.board)
+	} else {
+		total, err = s.store.CountSegmentScores(ctx, store.CountSegmentScoresParams{
+			LeaderboardID: key.board,
+			CountryCode:   key.seg.Region,
+			Platform:      key.seg.Platform,
+		})
+	}
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", key.board).Str("region", key.seg.Region).Str("platform", key.seg.Platform).Msg("failed to count scores")
+		return 0, fmt.Errorf("count scores: %w", err)
+	}
+	return s.storeCount(key, total, gen), nil
+}
+
+// generation returns the current generation of a board's whole-board count.
+// Both counters only grow, so their sum moves whenever either does. c.mu must
+// be held.
+func (c *countCache) generation(board string) uint64 {
+	return c.global + c.generations[board]
+}
+
+// storeCount caches a fresh count started at generation gen and returns it.
+// Segment counts do not follow changes and are cached as is; a whole-board
+// count overtaken by a change is returned but not cached, as applying the
+// change to it could count it twice.
+func (s *Service) storeCount(key countKey, total int64, gen uint64) int64 {
+	s.counts.mu.Lock()
+	defer s.counts.mu.Unlock()
+
+	// Drop expired counts so boards that are no longer read do not accumulate
+	if s.counts.counts == nil {
+		s.counts.counts = make(map[countKey]entryCount)
+	}
+	for k, cached := range s.counts.counts {
+		if time.Since(cached.countedAt) >= s.opts.CountCacheTTL {
+			delete(s.counts.counts, k)
+		}
+	}
+	if key.seg.IsZero() && s.counts.generation(key.board) != gen {
+		return total
+	}
+	s.counts.counts[key] = entryCount{total: total, countedAt: time.Now()}
+	return total
+}
+
+// applyCounts applies a change to the cached whole-board count of its board
+func (s *Service) applyCounts(change notify.ScoreChange) {
+	s.counts.mu.Lock()
+	defer s.counts.mu.Unlock()
+
+	if change.Op == notify.OpResync {
+		// Events may have been lost, or the board was reset: recount on next read
+		if change.LeaderboardID == "" {
+			s.counts.global++
+		} else {
+			s.counts.bump(change.LeaderboardID)
+		}
+		for k := range s.counts.counts {
+			if change.LeaderboardID == "" || k.board == change.LeaderboardID {
+				delete(s.counts.counts, k)
+			}
+		}
+		return
+	}
+
+	board := change.LeaderboardID
+	if board == "" {
+		board = DefaultLeaderboardID
+	}
+	if change.Op != "insert" && change.Op != "delete" {
+		return
+	}
+	s.counts.bump(board)
+
+	key := countKey{board: board}
+	cached, ok := s.counts.counts[key]
+	if !ok {
+		return
+	}
+	if change.Op == "insert" {
+		cached.total++
+	} else {
+		cached.total = max(cached.total-1, 0)
+	}
+	s.counts.counts[key] = cached
+}
+
+// bump moves the generation of a board's whole-board count; c.mu must be held
+func (c *countCache) bump(board string) {
+	if c.generations == nil {
+		c.generations = make(map[string]uint64)
+	}
+	c.generations[board]++
+}
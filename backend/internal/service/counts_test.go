@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+func TestGetEntryCount(t *testing.T) {
+	ctx := context.Background()
+	svc := newSegmentService(t)
+
+	for _, tt := range []struct {
+		seg  Segment
+		want int64
+	}{
+		{Segment{}, 6},
+		{Segment{Region: "fr"}, 3},
+		{Segment{Platform: "PC"}, 3},
+		{Segment{Region: "DE", Platform: "mobile"}, 1},
+		{Segment{Platform: "web"}, 0},
+	} {
+		if got, err := svc.GetEntryCount(ctx, "", tt.seg); err != nil || got != tt.want {
+			t.Errorf("%+v: count = %d, %v; want %d", tt.seg, got, err, tt.want)
+		}
+	}
+	if _, err := svc.GetEntryCount(ctx, "", Segment{Region: "France"}); err == nil {
+		t.Error("invalid region: want an error")
+	}
+
+	// Pages carry the count of the board or segment they list
+	page, err := svc.GetTopScoresPage(ctx, "", 2, 0, "")
+	if err != nil {
+		t.Fatalf("GetTopScoresPage: %v", err)
+	}
+	if page.TotalCount != 6 {
+		t.Errorf("page total = %d, want 6", page.TotalCount)
+	}
+	page, err = svc.GetSegmentTopScoresPage(ctx, "", Segment{Region: "FR"}, 2, 0, "")
+	if err != nil {
+		t.Fatalf("GetSegmentTopScoresPage: %v", err)
+	}
+	if page.TotalCount != 3 {
+		t.Errorf("FR page total = %d, want 3", page.TotalCount)
+	}
+}
+
+func TestEntryCountCache(t *testing.T) {
+	ctx := context.Background()
+	svc := newSegmentService(t)
+	svc.opts.CountCacheTTL = time.Minute
+
+	count := func(seg Segment) int64 {
+		t.Helper()
+		n, err := svc.GetEntryCount(ctx, "", seg)
+		if err != nil {
+			t.Fatalf("%+v: GetEntryCount: %v", seg, err)
+		}
+		return n
+	}
+	if got := count(Segment{}); got != 6 {
+		t.Fatalf("count = %d, want 6", got)
+	}
+	if got := count(Segment{Region: "FR"}); got != 3 {
+		t.Fatalf("FR count = %d, want 3", got)
+	}
+
+	// Cached counts are not read again, but the whole board follows its changes
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "G", Score: 100, Country: "FR"}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	svc.applyCounts(notify.ScoreChange{Op: "insert", LeaderboardID: DefaultLeaderboardID, PlayerName: "G"})
+	svc.applyCounts(notify.ScoreChange{Op: "update", LeaderboardID: DefaultLeaderboardID, PlayerName: "A"})
+	if got := count(Segment{}); got != 7 {
+		t.Errorf("count after insert = %d, want 7", got)
+	}
+	if got := count(Segment{Region: "FR"}); got != 3 {
+		t.Errorf("FR count after insert = %d, want the cached 3", got)
+	}
+	svc.applyCounts(notify.ScoreChange{Op: "delete", LeaderboardID: DefaultLeaderboardID, PlayerName: "B"})
+	if got := count(Segment{}); got != 6 {
+		t.Errorf("count after delete = %d, want 6", got)
+	}
+
+	// A resync drops the board's counts: they are counted again
+	svc.applyCounts(notify.ScoreChange{Op: notify.OpResync, LeaderboardID: DefaultLeaderboardID})
+	if got := count(Segment{}); got != 7 {
+		t.Errorf("count after resync = %d, want 7", got)
+	}
+	if got := count(Segment{Region: "FR"}); got != 4 {
+		t.Errorf("FR count after resync = %d, want 4", got)
+	}
+}
+
+// blockingCounter holds whole-board counts until released, counting the calls
+type blockingCounter struct {
+	store.Repository
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingCounter) CountScores(ctx context.Context, board string) (int64, error) {
+	b.calls.Add(1)
+	b.started <- struct{}{}
+	<-b.release
+	return b.Repository.CountScores(ctx, board)
+}
+
+func TestEntryCountOvertaken(t *testing.T) {
+	ctx := context.Background()
+	svc := newSegmentService(t)
+	svc.opts.CountCacheTTL = time.Minute
+	counter := &blockingCounter{Repository: svc.store, started: make(chan struct{}, 1), release: make(chan struct{})}
+	svc.store = counter
+
+	result := make(chan int64, 1)
+	go func() {
+		n, err := svc.GetEntryCount(ctx, "", Segment{})
+		if err != nil {
+			t.Errorf("GetEntryCount: %v", err)
+		}
+		result <- n
+	}()
+	<-counter.started
+
+	// An insert landing during the count may be missing from it: applying it
+	// to the count would then be lost, so the count is not cached
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "G", Score: 100}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	svc.applyCounts(notify.ScoreChange{Op: "insert", LeaderboardID: DefaultLeaderboardID, PlayerName: "G"})
+	close(counter.release)
+	if got := <-result; got != 7 {
+		t.Errorf("count = %d, want 7", got)
+	}
+
+	if got, err := svc.GetEntryCount(ctx, "", Segment{}); err != nil || got != 7 {
+		t.Errorf("count after overtaken count = %d, %v; want 7", got, err)
+	}
+	if got := counter.calls.Load(); got != 2 {
+		t.Errorf("overtaken count was cached: %d counts, want 2", got)
+	}
+	if got, err := svc.GetEntryCount(ctx, "", Segment{}); err != nil || got != 7 || counter.calls.Load() != 2 {
+		t.Errorf("count = %d, %v after %d counts; want the cached 7", got, err, counter.calls.Load())
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// ErrLeaderboardClosed is returned when submitting to a daily board outside its day
+var ErrLeaderboardClosed = errors.New("leaderboard closed")
+
+// DefaultDailyPrefix is the id prefix of daily boards when none is configured
+const DefaultDailyPrefix = "daily-"
+
+const (
+	dailyDateLayout  = "2006-01-02"
+	dailySeedVersion = "leaderboard-daily-seed-v1"
+)
+
+// Daily configures the daily challenge boards: one board per day, whose id is the
+// prefix followed by the date ("daily-2025-01-15"), open from midnight to midnight
+// in Location. Past and future daily boards reject submissions but stay readable.
+type Daily struct {
+	// Prefix of daily board ids (empty uses DefaultDailyPrefix)
+	Prefix string
+
+	// Location is the timezone days roll over in (nil uses UTC)
+	Location *time.Location
+
+	// SortOrder is set on the definition of each daily board when it is created
+	SortOrder SortOrder
+
+	// Grace is how long after rollover the previous board still accepts scores,
+	// for runs started before midnight
+	Grace time.Duration
+
+	// SeedKey keys the daily seeds. Without it seeds only depend on the date, and
+	// players can compute the seeds of future days.
+	SeedKey []byte
+}
+
+// DailyBoard describes the daily challenge board of one day
+type DailyBoard struct {
+	LeaderboardID string
+	Date          string    // YYYY-MM-DD in the daily timezone
+	StartsAt      time.Time // midnight starting the day
+	EndsAt        time.Time // midnight ending it; submissions stop Grace later
+	Seed          int64     // same for every client and server on that day, e.g. to generate the level
+	SortOrder     SortOrder
+
+	// PreviousLeaderboardID is yesterday's board, frozen and still readable
+	PreviousLeaderboardID string
+}
+
+// dailyState remembers the last daily board whose definition was ensured
+type dailyState struct {
+	mu      sync.Mutex
+	ensured string
+}
+
+// CurrentDailyBoard returns today's daily board, creating its definition on first use
+func (s *Service) CurrentDailyBoard(ctx context.Context) (*DailyBoard, error) {
+	board := s.dailyBoard(time.Now())
+	if err := s.ensureDailyBoard(ctx, board); err != nil {
+		return nil, err
+	}
+	return &board, nil
+}
+
+// RunDailyBoards creates the definition of each day's board at rollover, so the
+// board exists with its sort order before the first score. It blocks until ctx is done.
+func (s *Service) RunDailyBoards(ctx context.Context) {
+	for {
+		board := s.dailyBoard(time.Now())
+		if err := s.ensureDailyBoard(ctx, board); err != nil {
+			s.logger.Warn().Err(err).Str("leaderboard", board.LeaderboardID).Msg("failed to create daily board")
+		}
+
+		// Wake up at the next rollover, or retry shortly after a failure
+		wait := time.Until(board.EndsAt)
+		if s.daily.current() != board.LeaderboardID {
+			wait = min(wait, time.Minute)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// dailyBoard returns the daily board of the day containing t
+func (s *Service) dailyBoard(t time.Time) DailyBoard {
+	loc := s.dailyLocation()
+	local := t.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 1)
+	date := start.Format(dailyDateLayout)
+
+	return DailyBoard{
+		LeaderboardID:         s.dailyPrefix() + date,
+		Date:                  date,
+		StartsAt:              start,
+		EndsAt:                end,
+		Seed:                  s.dailySeed(date),
+		SortOrder:             s.dailySortOrder(),
+		PreviousLeaderboardID: s.dailyPrefix() + start.AddDate(0, 0, -1).Format(dailyDateLayout),
+	}
+}
+
+// ensureDailyBoard creates the definition of a daily board unless it has one.
+// An existing definition is left as is, so operators can still change it.
+func (s *Service) ensureDailyBoard(ctx context.Context, board DailyBoard) error {
+	if s.daily.current() == board.LeaderboardID {
+		return nil
+	}
+
+	_, err := s.store.GetLeaderboard(ctx, board.LeaderboardID)
+	if errors.Is(err, store.ErrNoRows) {
+		_, err = s.store.UpsertLeaderboard(ctx, store.UpsertLeaderboardParams{
+			LeaderboardID:      board.LeaderboardID,
+			SortOrder:          string(board.SortOrder),
+			SecondarySortOrder: string(SortDescending),
+		})
+		if err == nil {
+			s.logger.Info().
+				Str("leaderboard", board.LeaderboardID).
+				Time("ends_at", board.EndsAt).
+				Msg("📅 daily board created")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("ensure daily board: %w", err)
+	}
+
+	s.daily.mu.Lock()
+	previous := s.daily.ensured
+	s.daily.ensured = board.LeaderboardID
+	s.daily.mu.Unlock()
+
+	// The first board ensured after startup is not a rollover
+	if previous != "" && previous != board.LeaderboardID {
+		s.opts.Events.Publish(lifecycle.DailyRollover, map[string]string{
+			"leaderboard_id":          board.LeaderboardID,
+			"previous_leaderboard_id": board.PreviousLeaderboardID,
+			"date":                    board.Date,
+			"ends_at":                 board.EndsAt.Format(time.RFC3339),
+		})
+	}
+	return nil
+}
+
+func (d *dailyState) current() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.ensured
+}
+
+// checkBoardOpen rejects submissions to a daily board outside its day (plus the
+// grace period). Boards that are not daily boards are always open.
+func (s *Service) checkBoardOpen(board string, now time.Time) error {
+	date, ok := strings.CutPrefix(board, s.dailyPrefix())
+	if !ok {
+		return nil
+	}
+	day, err := time.ParseInLocation(dailyDateLayout, date, s.dailyLocation())
+	if err != nil {
+		return nil
+	}
+	daily := s.dailyBoard(day)
+	switch {
+	case now.Before(daily.StartsAt):
+		return fmt.Errorf("%w: daily board %s opens at %s", ErrLeaderboardClosed, board, daily.StartsAt.Format(time.RFC3339))
+	case !now.Before(daily.EndsAt.Add(s.opts.Daily.Grace)):
+		return fmt.Errorf("%w: daily board %s closed at %s", ErrLeaderboardClosed, board, daily.EndsAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// maxDailySeed keeps seeds exact in JSON clients that parse numbers as doubles (Godot, JavaScript)
+const maxDailySeed = 1<<53 - 1
+
+// dailySeed derives the seed of a day from an HMAC (or a plain hash without seed
+// key) of the date, in [0, 2^53)
+func (s *Service) dailySeed(date string) int64 {
+	msg := []byte(dailySeedVersion + "\n" + date + "\n")
+	var sum []byte
+	if len(s.opts.Daily.SeedKey) > 0 {
+		mac := hmac.New(sha256.New, s.opts.Daily.SeedKey)
+		mac.Write(msg)
+		sum = mac.Sum(nil)
+	} else {
+		h := sha256.Sum256(msg)
+		sum = h[:]
+	}
+	return int64(binary.BigEndian.Uint64(sum[:8]) & maxDailySeed)
+}
+
+func (s *Service) dailyPrefix() string {
+	if s.opts.Daily.Prefix != "" {
+		return s.opts.Daily.Prefix
+	}
+	return DefaultDailyPrefix
+}
+
+func (s *Service) dailyLocation() *time.Location {
+	if s.opts.Daily.Location != nil {
+		return s.opts.Daily.Location
+	}
+	return time.UTC
+}
+
+func (s *Service) dailySortOrder() SortOrder {
+	if s.opts.Daily.SortOrder != "" {
+		return s.opts.Daily.SortOrder
+	}
+	return SortDescending
+}
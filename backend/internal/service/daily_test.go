@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestDailyBoard(t *testing.T) {
+	tokyo := time.FixedZone("UTC+9", 9*3600)
+	svc := &Service{opts: Options{Daily: Daily{Location: tokyo, Grace: time.Minute}}}
+
+	// 20:00 UTC on Jan 14 is already Jan 15 in UTC+9
+	now := time.Date(2025, 1, 14, 20, 0, 0, 0, time.UTC)
+	board := svc.dailyBoard(now)
+	if board.LeaderboardID != "daily-2025-01-15" || board.PreviousLeaderboardID != "daily-2025-01-14" {
+		t.Errorf("board = %s (previous %s), want daily-2025-01-15 (previous daily-2025-01-14)",
+			board.LeaderboardID, board.PreviousLeaderboardID)
+	}
+	if want := time.Date(2025, 1, 15, 0, 0, 0, 0, tokyo); !board.StartsAt.Equal(want) || !board.EndsAt.Equal(want.AddDate(0, 0, 1)) {
+		t.Errorf("board runs %v - %v, want midnight to midnight UTC+9", board.StartsAt, board.EndsAt)
+	}
+	if board.Seed != svc.dailyBoard(now.Add(time.Hour)).Seed || board.Seed == svc.dailyBoard(now.AddDate(0, 0, 1)).Seed {
+		t.Error("seed must be stable within a day and change across days")
+	}
+	keyed := &Service{opts: Options{Daily: Daily{Location: tokyo, SeedKey: []byte("secret")}}}
+	if keyed.dailyBoard(now).Seed == board.Seed {
+		t.Error("seed key does not change the seed")
+	}
+
+	tests := []struct {
+		board   string
+		now     time.Time
+		wantErr bool
+	}{
+		{"daily-2025-01-15", now, false},
+		{"daily-2025-01-14", now, true},                                   // yesterday
+		{"daily-2025-01-14", board.StartsAt.Add(30 * time.Second), false}, // within grace
+		{"daily-2025-01-16", now, true},                                   // tomorrow
+		{"daily-someday", now, false},                                     // not a daily board
+		{"level-1", now, false},
+	}
+	for _, tt := range tests {
+		err := svc.checkBoardOpen(tt.board, tt.now)
+		if tt.wantErr != errors.Is(err, ErrLeaderboardClosed) || !tt.wantErr && err != nil {
+			t.Errorf("checkBoardOpen(%s, %v) error = %v, wantErr %v", tt.board, tt.now, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCurrentDailyBoard(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{Daily: Daily{SortOrder: SortAscending}})
+
+	board, err := svc.CurrentDailyBoard(ctx)
+	if err != nil {
+		t.Fatalf("CurrentDailyBoard() error = %v", err)
+	}
+	def, err := svc.GetLeaderboard(ctx, board.LeaderboardID)
+	if err != nil || def.SortOrder != string(SortAscending) || !def.CreatedAt.Valid {
+		t.Errorf("definition = %+v, %v, want a created asc board", def, err)
+	}
+
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: board.LeaderboardID, PlayerName: "Alice", Score: 42}); err != nil {
+		t.Errorf("submit to today's board: %v", err)
+	}
+	_, err = svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: board.PreviousLeaderboardID, PlayerName: "Alice", Score: 42})
+	if !errors.Is(err, ErrLeaderboardClosed) {
+		t.Errorf("submit to yesterday's board error = %v, want %v", err, ErrLeaderboardClosed)
+	}
+}
+
+func TestDailyRolloverEvent(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+
+	logger := zerolog.Nop()
+	bus := lifecycle.NewBus(&logger)
+	events := bus.Subscribe()
+	svc := New(st, &logger, Options{Events: bus})
+
+	today := svc.dailyBoard(time.Now())
+	tomorrow := svc.dailyBoard(today.EndsAt)
+	for _, board := range []DailyBoard{today, today, tomorrow} {
+		if err := svc.ensureDailyBoard(ctx, board); err != nil {
+			t.Fatalf("ensureDailyBoard(%s) error = %v", board.LeaderboardID, err)
+		}
+	}
+	bus.Close()
+
+	var got []lifecycle.Event
+	for event := range events {
+		got = append(got, event)
+	}
+	if len(got) != 1 || got[0].Type != lifecycle.DailyRollover ||
+		got[0].Attributes["leaderboard_id"] != tomorrow.LeaderboardID || got[0].Attributes["previous_leaderboard_id"] != today.LeaderboardID {
+		t.Errorf("events = %+v, want one rollover to %s", got, tomorrow.LeaderboardID)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// ErrScoreNotDeleted is returned when restoring a player who has no deleted score on the board
+var ErrScoreNotDeleted = errors.New("no deleted score to restore")
+
+// Limits of deleted score listings
+const (
+	DefaultDeletedScores = 50
+	MaxDeletedScores     = 500
+)
+
+// RestoreScore puts back a player's score entry deleted by DeleteScore, as it was
+// when deleted. A player who submitted again since has a live entry and nothing
+// to restore. Admin only.
+func (s *Service) RestoreScore(ctx context.Context, board, playerName string) (*store.Score, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validatePlayerName(playerName); err != nil {
+		return nil, err
+	}
+
+	score, err := s.store.RestoreScore(ctx, store.RestoreScoreParams{
+		LeaderboardID: board,
+		PlayerName:    playerName,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNoRows) {
+			return nil, ErrScoreNotDeleted
+		}
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Str("player", playerName).Msg("failed to restore score")
+		return nil, fmt.Errorf("restore score: %w", err)
+	}
+
+	s.loggerFor(ctx).Info().Str("leaderboard", board).Str("player", playerName).Int64("score", score.Score).Msg("score restored")
+	return &score, nil
+}
+
+// GetDeletedScores lists the deleted score entries of a board that can still be
+// restored, most recently deleted first; limit 0 means DefaultDeletedScores. Admin only.
+func (s *Service) GetDeletedScores(ctx context.Context, board string, limit int32) ([]store.Score, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = DefaultDeletedScores
+	}
+	if limit < 0 || limit > MaxDeletedScores {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidLimit, MaxDeletedScores)
+	}
+
+	scores, err := s.store.GetDeletedScores(ctx, store.GetDeletedScoresParams{
+		LeaderboardID: board,
+		PageSize:      limit,
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to get deleted scores")
+		return nil, fmt.Errorf("get deleted scores: %w", err)
+	}
+	return scores, nil
+}
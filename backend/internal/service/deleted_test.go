@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestRestoreScore(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{Admin: Admin{Token: "s3cret"}})
+
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: "level-1", PlayerName: "Alice", Score: 100}); err != nil {
+		t.Fatalf("seed score: %v", err)
+	}
+	if err := svc.DeleteScore(ctx, "level-1", "Alice"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := svc.DeleteScore(ctx, "level-1", "Alice"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("deleting again: error = %v, want ErrPlayerNotFound", err)
+	}
+
+	if _, err := svc.RestoreScore(ctx, "level-1", "Alice"); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated restore: error = %v, want ErrAdminUnauthorized", err)
+	}
+	if _, err := svc.GetDeletedScores(ctx, "level-1", 0); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated listing: error = %v, want ErrAdminUnauthorized", err)
+	}
+	ctx, _ = svc.AuthenticateAdmin(ctx, "s3cret")
+
+	if _, err := svc.GetDeletedScores(ctx, "level-1", MaxDeletedScores+1); !errors.Is(err, ErrInvalidLimit) {
+		t.Errorf("listing over the limit: error = %v, want ErrInvalidLimit", err)
+	}
+	deleted, err := svc.GetDeletedScores(ctx, "level-1", 0)
+	if err != nil || len(deleted) != 1 || deleted[0].PlayerName != "Alice" {
+		t.Fatalf("deleted scores = %+v, %v; want Alice", deleted, err)
+	}
+
+	restored, err := svc.RestoreScore(ctx, "level-1", "Alice")
+	if err != nil || restored.Score != 100 {
+		t.Fatalf("restore = %+v, %v; want score 100", restored, err)
+	}
+	if rank, err := svc.GetPlayerRank(ctx, "level-1", "Alice"); err != nil || rank.Score.Score != 100 {
+		t.Errorf("rank after restore = %+v, %v; want score 100", rank, err)
+	}
+	if _, err := svc.RestoreScore(ctx, "level-1", "Alice"); !errors.Is(err, ErrScoreNotDeleted) {
+		t.Errorf("restoring a live score: error = %v, want ErrScoreNotDeleted", err)
+	}
+	if _, err := svc.RestoreScore(ctx, "level-1", "Bob"); !errors.Is(err, ErrScoreNotDeleted) {
+		t.Errorf("restoring an unknown player: error = %v, want ErrScoreNotDeleted", err)
+	}
+}
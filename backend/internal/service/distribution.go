@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// ErrInvalidBucketCount is returned when a histogram bucket count is out of range
+var ErrInvalidBucketCount = errors.New("invalid bucket count")
+
+// Bucket counts of GetScoreDistribution
+const (
+	DefaultDistributionBuckets = 20
+	MaxDistributionBuckets     = 100
+)
+
+// DistributionBucket counts the players whose score is in [MinScore, MaxScore]
+type DistributionBucket struct {
+	MinScore int64
+	MaxScore int64
+	Players  int64
+}
+
+// ScoreDistribution is a histogram of the scores of a board. Buckets have the
+// same integer width and cover every score from the lowest to the highest; there
+// may be fewer buckets than requested when the scores span a narrow range.
+type ScoreDistribution struct {
+	LeaderboardID string
+	Buckets       []DistributionBucket
+	BucketWidth   int64
+	MinScore      int64
+	MaxScore      int64
+	TotalPlayers  int64
+	ComputedAt    time.Time
+}
+
+// distributionCache caches the last computed histogram of each board and bucket count
+type distributionCache struct {
+	mu            sync.Mutex
+	distributions map[distributionKey]*ScoreDistribution
+}
+
+type distributionKey struct {
+	board   string
+	buckets int32
+}
+
+// GetScoreDistribution returns a histogram of the scores of a board in up to buckets
+// buckets (DefaultDistributionBuckets when 0). Results are cached for
+// Options.PercentileCacheTTL since computing them scans the whole board.
+func (s *Service) GetScoreDistribution(ctx context.Context, board string, buckets int32) (*ScoreDistribution, error) {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+	if buckets == 0 {
+		buckets = DefaultDistributionBuckets
+	}
+	if buckets < 1 || buckets > MaxDistributionBuckets {
+		return nil, fmt.Errorf("%w: buckets must be between 1 and %d", ErrInvalidBucketCount, MaxDistributionBuckets)
+	}
+	key := distributionKey{board: board, buckets: buckets}
+
+	s.distributions.mu.Lock()
+	defer s.distributions.mu.Unlock()
+
+	if cached := s.distributions.distributions[key]; cached != nil && time.Since(cached.ComputedAt) < s.opts.PercentileCacheTTL {
+		return cached, nil
+	}
+
+	dist, err := s.computeDistribution(ctx, board, buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop expired histograms so boards that are no longer read do not accumulate
+	if s.distributions.distributions == nil {
+		s.distributions.distributions = make(map[distributionKey]*ScoreDistribution)
+	}
+	for k, cached := range s.distributions.distributions {
+		if time.Since(cached.ComputedAt) >= s.opts.PercentileCacheTTL {
+			delete(s.distributions.distributions, k)
+		}
+	}
+	s.distributions.distributions[key] = dist
+	return dist, nil
+}
+
+func (s *Service) computeDistribution(ctx context.Context, board string, buckets int32) (*ScoreDistribution, error) {
+	bounds, err := s.store.GetScoreBounds(ctx, board)
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to read score bounds")
+		return nil, fmt.Errorf("get score bounds: %w", err)
+	}
+
+	dist := &ScoreDistribution{
+		LeaderboardID: board,
+		Buckets:       []DistributionBucket{},
+		MinScore:      bounds.MinScore,
+		MaxScore:      bounds.MaxScore,
+		TotalPlayers:  bounds.Players,
+		ComputedAt:    time.Now(),
+	}
+	if bounds.Players == 0 {
+		return dist, nil
+	}
+
+	// Integer-wide buckets from the lowest score, as few as needed to reach the highest
+	span := uint64(bounds.MaxScore-bounds.MinScore) + 1
+	width := (span + uint64(buckets) - 1) / uint64(buckets)
+	count := (span + width - 1) / width
+	dist.BucketWidth = int64(width)
+
+	dist.Buckets = make([]DistributionBucket, count)
+	for i := range dist.Buckets {
+		low := bounds.MinScore + int64(i)*dist.BucketWidth
+		dist.Buckets[i] = DistributionBucket{MinScore: low, MaxScore: low + dist.BucketWidth - 1}
+	}
+	dist.Buckets[count-1].MaxScore = bounds.MaxScore
+
+	rows, err := s.store.GetScoreHistogram(ctx, store.GetScoreHistogramParams{
+		LeaderboardID: board,
+		Low:           float64(bounds.MinScore),
+		High:          float64(bounds.MinScore) + float64(width*count),
+		BucketCount:   int32(count),
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to compute score histogram")
+		return nil, fmt.Errorf("get score histogram: %w", err)
+	}
+	for _, row := range rows {
+		// Scores changed since the bounds were read, or rounded at the edges of
+		// float8, land outside [1, count]: count them in the nearest bucket
+		i := min(max(int(row.Bucket), 1), int(count)) - 1
+		dist.Buckets[i].Players += row.Players
+	}
+	return dist, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestGetScoreDistribution(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{PercentileCacheTTL: time.Minute})
+
+	empty, err := svc.GetScoreDistribution(ctx, "", 0)
+	if err != nil || empty.TotalPlayers != 0 || len(empty.Buckets) != 0 {
+		t.Fatalf("empty board = %+v, %v; want no buckets", empty, err)
+	}
+
+	for name, score := range map[string]int64{"A": 100, "B": 105, "C": 150, "D": 199, "E": 200, "F": 130} {
+		if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: name, Score: score, LeaderboardID: "level-1"}); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+
+	dist, err := svc.GetScoreDistribution(ctx, "level-1", 4)
+	if err != nil {
+		t.Fatalf("GetScoreDistribution: %v", err)
+	}
+	// 101 distinct scores in 4 buckets of 26: [100,125] [126,151] [152,177] [178,200]
+	want := []DistributionBucket{
+		{MinScore: 100, MaxScore: 125, Players: 2},
+		{MinScore: 126, MaxScore: 151, Players: 2},
+		{MinScore: 152, MaxScore: 177, Players: 0},
+		{MinScore: 178, MaxScore: 200, Players: 2},
+	}
+	if dist.TotalPlayers != 6 || dist.BucketWidth != 26 || len(dist.Buckets) != len(want) {
+		t.Fatalf("distribution = %+v, want 6 players in 4 buckets of 26", dist)
+	}
+	for i, b := range dist.Buckets {
+		if b != want[i] {
+			t.Errorf("bucket %d = %+v, want %+v", i, b, want[i])
+		}
+	}
+
+	// Fewer buckets than requested when the range is narrow
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "A", Score: 3, LeaderboardID: "level-2"}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	narrow, err := svc.GetScoreDistribution(ctx, "level-2", 20)
+	if err != nil || len(narrow.Buckets) != 1 || narrow.Buckets[0] != (DistributionBucket{MinScore: 3, MaxScore: 3, Players: 1}) {
+		t.Errorf("single score = %+v, %v; want one bucket", narrow, err)
+	}
+
+	// Cached until the TTL expires
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "G", Score: 160, LeaderboardID: "level-1"}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if cached, _ := svc.GetScoreDistribution(ctx, "level-1", 4); cached != dist {
+		t.Error("distribution recomputed within the cache TTL")
+	}
+
+	for _, n := range []int32{-1, MaxDistributionBuckets + 1} {
+		if _, err := svc.GetScoreDistribution(ctx, "", n); !errors.Is(err, ErrInvalidBucketCount) {
+			t.Errorf("%d buckets: error = %v, want ErrInvalidBucketCount", n, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// exportPageSize is the number of entries ExportScores reads per query
+const exportPageSize = 1000
+
+// ExportScores walks every entry of a board in rank order and calls fn with each
+// page of entries; the first entry of the whole export has rank 1. It reads the
+// database directly with keyset pagination, so memory stays bounded whatever the
+// board size, and entries that do not move during the export are neither skipped
+// nor repeated. An error from fn stops the export and is returned as is.
+func (s *Service) ExportScores(ctx context.Context, board string, fn func(scores []store.Score) error) error {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return err
+	}
+
+	scores, err := s.store.GetTopScores(ctx, store.GetTopScoresParams{
+		LeaderboardID: board,
+		PageSize:      exportPageSize,
+	})
+	for {
+		if err != nil {
+			s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to read scores for export")
+			return fmt.Errorf("export scores: %w", err)
+		}
+		if len(scores) == 0 {
+			return nil
+		}
+		if err := fn(scores); err != nil {
+			return err
+		}
+		if len(scores) < exportPageSize {
+			return nil
+		}
+
+		last := scores[len(scores)-1]
+		scores, err = s.store.GetTopScoresAfter(ctx, store.GetTopScoresAfterParams{
+			LeaderboardID: board,
+			RankScore:     last.RankScore,
+			RankSecondary: last.RankSecondary,
+			AchievedAt:    last.AchievedAt,
+			PlayerName:    last.PlayerName,
+			PageSize:      exportPageSize,
+		})
+	}
+}
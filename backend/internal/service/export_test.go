@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestExportScores(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{})
+
+	// Two and a half pages, with ties on every score
+	const players = exportPageSize*2 + exportPageSize/2
+	entries := make([]ScoreImport, players)
+	for i := range entries {
+		entries[i] = ScoreImport{LeaderboardID: "level-1", PlayerName: fmt.Sprintf("p%04d", i), Score: int64(i / 2)}
+	}
+	if _, err := svc.ImportScores(ctx, entries); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	var pages int
+	var exported []store.Score
+	err = svc.ExportScores(ctx, "level-1", func(scores []store.Score) error {
+		pages++
+		exported = append(exported, scores...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportScores: %v", err)
+	}
+	if pages != 3 || len(exported) != players {
+		t.Fatalf("exported %d entries in %d pages, want %d in 3", len(exported), pages, players)
+	}
+
+	seen := make(map[string]bool, players)
+	for i, sc := range exported {
+		if seen[sc.PlayerName] {
+			t.Fatalf("%s exported twice", sc.PlayerName)
+		}
+		seen[sc.PlayerName] = true
+		if i > 0 && sc.Score > exported[i-1].Score {
+			t.Fatalf("entry %d (%d) ranks after a lower score (%d)", i, sc.Score, exported[i-1].Score)
+		}
+	}
+
+	// The first error from fn stops the export
+	stop := errors.New("stop")
+	pages = 0
+	err = svc.ExportScores(ctx, "level-1", func([]store.Score) error {
+		pages++
+		return stop
+	})
+	if !errors.Is(err, stop) || pages != 1 {
+		t.Errorf("ExportScores with failing fn = %v after %d pages, want %v after 1", err, pages, stop)
+	}
+
+	if err := svc.ExportScores(ctx, "bad id", func([]store.Score) error { return nil }); !errors.Is(err, ErrInvalidLeaderboardID) {
+		t.Errorf("export of a malformed board error = %v, want %v", err, ErrInvalidLeaderboardID)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidFieldMask is returned when a field mask names an unknown entry field
+var ErrInvalidFieldMask = errors.New("invalid field mask")
+
+// Leaderboard entry fields a field mask can select
+const (
+	FieldLeaderboardID = "leaderboard_id"
+	FieldPlayerName    = "player_name"
+	FieldScore         = "score"
+	FieldUpdatedAt     = "updated_at"
+	FieldTier          = "tier"
+	FieldAchievedAt    = "achieved_at"
+	FieldProfile       = "profile"
+	FieldMetadata      = "metadata"
+	FieldSecondary     = "secondary_score"
+	FieldRegion        = "region"
+	FieldPlatform      = "platform"
+)
+
+// entryFields lists the selectable fields in response order
+var entryFields = []string{FieldLeaderboardID, FieldPlayerName, FieldScore, FieldUpdatedAt, FieldTier, FieldAchievedAt, FieldProfile, FieldMetadata, FieldSecondary, FieldRegion, FieldPlatform}
+
+// FieldMask selects the fields of leaderboard entries to return, so
+// bandwidth-sensitive clients can leave out timestamps and profiles.
+// The zero value selects every field.
+type FieldMask struct {
+	fields map[string]bool
+}
+
+// ParseFieldMask builds a mask from field names; no names selects every field.
+// Names are matched after trimming spaces, and duplicates are allowed.
+func ParseFieldMask(paths []string) (FieldMask, error) {
+	var m FieldMask
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		known := false
+		for _, f := range entryFields {
+			known = known || f == p
+		}
+		if !known {
+			return FieldMask{}, fmt.Errorf("%w: unknown field %q, expected some of %s", ErrInvalidFieldMask, p, strings.Join(entryFields, ", "))
+		}
+		if m.fields == nil {
+			m.fields = make(map[string]bool)
+		}
+		m.fields[p] = true
+	}
+	return m, nil
+}
+
+// Has reports whether the mask selects a field
+func (m FieldMask) Has(field string) bool {
+	return m.fields == nil || m.fields[field]
+}
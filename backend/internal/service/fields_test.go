@@ -0,0 +1,33 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFieldMask(t *testing.T) {
+	all, err := ParseFieldMask(nil)
+	if err != nil {
+		t.Fatalf("ParseFieldMask(nil) error = %v", err)
+	}
+	for _, f := range entryFields {
+		if !all.Has(f) {
+			t.Errorf("empty mask does not select %s", f)
+		}
+	}
+
+	mask, err := ParseFieldMask([]string{" player_name", "score ", "", "score"})
+	if err != nil {
+		t.Fatalf("ParseFieldMask() error = %v", err)
+	}
+	for _, f := range entryFields {
+		want := f == FieldPlayerName || f == FieldScore
+		if mask.Has(f) != want {
+			t.Errorf("Has(%s) = %v, want %v", f, mask.Has(f), want)
+		}
+	}
+
+	if _, err := ParseFieldMask([]string{"score", "rank"}); !errors.Is(err, ErrInvalidFieldMask) {
+		t.Errorf("unknown field error = %v, want %v", err, ErrInvalidFieldMask)
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// Identity is the display data of a player known to an external account system
+type Identity struct {
+	DisplayName string
+	AvatarURL   string
+}
+
+// IdentityResolver looks up players in an external identity service. Resolve
+// returns the identities it knows, keyed by player name; players it does not know
+// are omitted. Implementations are expected to cache and to fail fast when the
+// service is down: resolution runs on every leaderboard read.
+type IdentityResolver interface {
+	Resolve(ctx context.Context, playerNames []string) (map[string]Identity, error)
+}
+
+// enrichProfiles fills the display name and avatar of profiles from the identity
+// resolver. Fields set in the local profile win, so players can still override
+// the platform data; players with no local profile get one built from their identity.
+func (s *Service) enrichProfiles(ctx context.Context, playerNames []string, profiles map[string]store.Player) {
+	if s.opts.Identity == nil || len(playerNames) == 0 {
+		return
+	}
+	identities, err := s.opts.Identity.Resolve(ctx, playerNames)
+	if err != nil {
+		s.loggerFor(ctx).Warn().Err(err).Int("players", len(playerNames)).Msg("failed to resolve player identities")
+	}
+	for name, id := range identities {
+		// External data is held to the same rules as profiles set through the API
+		if _, err := normalizeProfile(ProfileUpdate{DisplayName: id.DisplayName, AvatarURL: id.AvatarURL}); err != nil {
+			s.loggerFor(ctx).Debug().Err(err).Str("player", name).Msg("ignoring invalid player identity")
+			continue
+		}
+		p, ok := profiles[name]
+		if !ok {
+			p = store.Player{PlayerName: name}
+		}
+		if p.DisplayName == "" {
+			p.DisplayName = id.DisplayName
+		}
+		if p.AvatarUrl == "" {
+			p.AvatarUrl = id.AvatarURL
+		}
+		if p.DisplayName == "" && p.AvatarUrl == "" && !ok {
+			continue
+		}
+		profiles[name] = p
+	}
+}
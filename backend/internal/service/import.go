@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+var (
+	// ErrImportTooLarge is returned when an import has more entries than allowed
+	ErrImportTooLarge = errors.New("import too large")
+
+	// ErrInvalidImportOffset is returned when an import offset token cannot be decoded
+	ErrInvalidImportOffset = errors.New("invalid import offset")
+)
+
+// Import entry outcomes, also used as metric labels
+const (
+	ImportApplied     = "applied"
+	ImportNotImproved = "not_improved"
+	ImportInvalid     = "invalid"
+	ImportFailed      = "failed"
+)
+
+// DefaultImportMaxEntries is the maximum number of entries per import when none is configured
+const DefaultImportMaxEntries = 10_000
+
+// defaultStreamChunkSize is the number of entries of a streamed import written at a
+// time when Import.ChunkSize is unset: a stream has no size to write in one transaction
+const defaultStreamChunkSize = 1000
+
+// Import configures bulk score imports
+type Import struct {
+	// MaxEntries is the maximum number of entries per import (0 uses DefaultImportMaxEntries)
+	MaxEntries int
+
+	// ChunkSize is the number of entries written per transaction (0 writes an import in one)
+	ChunkSize int
+}
+
+// ScoreImport is one entry of a bulk import
+type ScoreImport struct {
+	LeaderboardID string // board of the score, empty for the default board
+	PlayerName    string
+	Score         int64
+	AchievedAt    time.Time // optional original completion time, server time when zero
+}
+
+// ScoreImportResult is the outcome of one entry of an import
+type ScoreImportResult struct {
+	Outcome string
+	Reason  string
+	Entry   *ScoreResult // player's best right after the entry (applied and not_improved)
+}
+
+// ImportScores writes operator-supplied scores, e.g. historical data or a migration
+// from another system, with the usual best-score logic. Unlike SubmitScore it skips
+// device limits and signatures, and trusts achieved_at as long as it is not in the future.
+//
+// Invalid entries are reported without stopping the import. Valid entries are written
+// in chunks of Import.ChunkSize, one transaction each: when a chunk fails, all of its
+// entries are reported as failed and the import goes on with the next chunk.
+func (s *Service) ImportScores(ctx context.Context, entries []ScoreImport) ([]ScoreImportResult, error) {
+	if len(entries) > s.opts.Import.MaxEntries {
+		return nil, fmt.Errorf("%w: %d entries, max %d", ErrImportTooLarge, len(entries), s.opts.Import.MaxEntries)
+	}
+
+	release, err := s.admit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	now := time.Now()
+	results := make([]ScoreImportResult, len(entries))
+	var valid []int // indexes of entries to write
+	var rows []store.UpsertScoreParams
+
+	for i, e := range entries {
+		board, err := ResolveLeaderboardID(e.LeaderboardID)
+		if err != nil {
+			results[i].Outcome, results[i].Reason = ImportInvalid, err.Error()
+			continue
+		}
+		if err := s.validatePlayerName(e.PlayerName); err != nil {
+			results[i].Outcome, results[i].Reason = ImportInvalid, err.Error()
+			continue
+		}
+		if err := s.validateScore(e.Score); err != nil {
+			results[i].Outcome, results[i].Reason = ImportInvalid, err.Error()
+			continue
+		}
+		achievedAt := e.AchievedAt
+		if achievedAt.IsZero() {
+			achievedAt = now
+		} else if achievedAt.After(now) {
+			results[i].Outcome, results[i].Reason = ImportInvalid, "achieved_at is in the future"
+			continue
+		}
+
+		valid = append(valid, i)
+		rows = append(rows, store.UpsertScoreParams{
+			LeaderboardID: board,
+			PlayerName:    e.PlayerName,
+			Score:         e.Score,
+			AchievedAt:    pgtype.Timestamptz{Time: achievedAt, Valid: true},
+		})
+	}
+
+	chunk := s.opts.Import.ChunkSize
+	if chunk <= 0 {
+		chunk = len(rows)
+	}
+	var applied, failed int
+	for start := 0; start < len(rows); start += chunk {
+		end := min(start+chunk, len(rows))
+
+		written, err := s.store.UpsertScores(ctx, rows[start:end])
+		if err != nil {
+			s.loggerFor(ctx).Error().Err(err).Int("first", valid[start]).Int("entries", end-start).Msg("failed to import score chunk")
+			for _, i := range valid[start:end] {
+				results[i].Outcome, results[i].Reason = ImportFailed, "storage error, chunk rolled back"
+			}
+			failed += end - start
+			continue
+		}
+
+		for k, w := range written {
+			i := valid[start+k]
+			results[i].Entry = toScoreResult(w.Score, w.Applied)
+			if w.Applied {
+				results[i].Outcome = ImportApplied
+				applied++
+			} else {
+				results[i].Outcome, results[i].Reason = ImportNotImproved, "current best score is better or equal"
+			}
+
+			if w.Applied && w.Previous != nil {
+				s.emitTierChange(w.LeaderboardID, w.PlayerName, w.Score.Score, s.TierFor(w.LeaderboardID, w.Previous.Score), s.TierFor(w.LeaderboardID, w.Score.Score))
+			}
+		}
+	}
+
+	for i := range results {
+		metrics.ImportedScores.WithLabelValues(results[i].Outcome).Inc()
+	}
+
+	s.loggerFor(ctx).Info().
+		Int("entries", len(entries)).
+		Int("applied", applied).
+		Int("invalid", len(entries)-len(rows)).
+		Int("failed", failed).
+		Msg("📥 scores imported")
+
+	return results, nil
+}
+
+// StreamChunkSize is the number of entries of a streamed import to pass to each
+// ImportScores call: Import.ChunkSize, or a default when imports are written in one
+// transaction, within Import.MaxEntries
+func (s *Service) StreamChunkSize() int {
+	chunk := s.opts.Import.ChunkSize
+	if chunk <= 0 {
+		chunk = defaultStreamChunkSize
+	}
+	return min(chunk, s.opts.Import.MaxEntries)
+}
+
+// ImportOffset is a position in a streamed import, after the last committed entry
+type ImportOffset struct {
+	Line  int64 `json:"l"` // lines consumed
+	Bytes int64 `json:"b"` // uncompressed bytes consumed
+}
+
+// Token returns the opaque offset token that resumes an import at this position
+func (o ImportOffset) Token() string {
+	b, _ := json.Marshal(o)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseImportOffset decodes an offset token; an empty token is the start of the stream
+func ParseImportOffset(token string) (ImportOffset, error) {
+	var o ImportOffset
+	if token == "" {
+		return o, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return o, fmt.Errorf("%w: %v", ErrInvalidImportOffset, err)
+	}
+	if err := json.Unmarshal(b, &o); err != nil || o.Line < 0 || o.Bytes < 0 {
+		return o, fmt.Errorf("%w: malformed token", ErrInvalidImportOffset)
+	}
+	return o, nil
+}
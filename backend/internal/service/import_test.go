@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+// failingBatcher fails the chunk starting with a given player
+type failingBatcher struct {
+	*sqlite.Store
+	failOn string
+}
+
+func (f failingBatcher) UpsertScores(ctx context.Context, rows []store.UpsertScoreParams) ([]store.UpsertedScore, error) {
+	if len(rows) > 0 && rows[0].PlayerName == f.failOn {
+		return nil, errors.New("disk full")
+	}
+	return f.Store.UpsertScores(ctx, rows)
+}
+
+func TestImportScores(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(failingBatcher{Store: st, failOn: "Erin"}, &logger, Options{Import: Import{MaxEntries: 10, ChunkSize: 2}})
+
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Bob", Score: 500}); err != nil {
+		t.Fatalf("seed score: %v", err)
+	}
+
+	past := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	entries := []ScoreImport{
+		{PlayerName: "Alice", Score: 100, AchievedAt: past},                    // chunk 1
+		{PlayerName: "Bob", Score: 200},                                        // chunk 1
+		{PlayerName: "", Score: 10},                                            // invalid
+		{PlayerName: "Carol", Score: -1},                                       // invalid
+		{PlayerName: "Dave", Score: 10, AchievedAt: time.Now().Add(time.Hour)}, // invalid
+		{PlayerName: "Alice", Score: 300, LeaderboardID: "level-1"},            // chunk 2
+		{PlayerName: "Alice", Score: 150},                                      // chunk 2
+		{PlayerName: "Erin", Score: 10},                                        // chunk 3, fails
+		{PlayerName: "Frank", Score: 10},                                       // chunk 3, fails
+	}
+	results, err := svc.ImportScores(ctx, entries)
+	if err != nil {
+		t.Fatalf("ImportScores: unexpected error %v", err)
+	}
+
+	want := []string{
+		ImportApplied,
+		ImportNotImproved, // Bob already has 500
+		ImportInvalid,
+		ImportInvalid,
+		ImportInvalid,
+		ImportApplied, // separate board
+		ImportApplied, // beats the 100 imported in chunk 1
+		ImportFailed,
+		ImportFailed,
+	}
+	for i, r := range results {
+		if r.Outcome != want[i] {
+			t.Errorf("entry %d outcome = %s (%s), want %s", i, r.Outcome, r.Reason, want[i])
+		}
+	}
+	if e := results[1].Entry; e == nil || e.Score != 500 {
+		t.Errorf("not improved entry = %+v, want Bob's best 500", e)
+	}
+	if e := results[0].Entry; e == nil || !e.AchievedAt.Equal(past) {
+		t.Errorf("imported entry = %+v, want achieved_at %s", e, past)
+	}
+
+	alice, err := svc.GetPlayerRank(ctx, "", "Alice")
+	if err != nil || alice.Score.Score != 150 {
+		t.Errorf("Alice = %+v (err %v), want 150", alice, err)
+	}
+	if _, err := svc.GetPlayerRank(ctx, "", "Erin"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("Erin of the failed chunk: error = %v, want %v", err, ErrPlayerNotFound)
+	}
+
+	if _, err := svc.ImportScores(ctx, make([]ScoreImport, 11)); !errors.Is(err, ErrImportTooLarge) {
+		t.Errorf("oversized import error = %v, want %v", err, ErrImportTooLarge)
+	}
+}
+
+func TestImportOffset(t *testing.T) {
+	want := ImportOffset{Line: 1000, Bytes: 67421}
+	got, err := ParseImportOffset(want.Token())
+	if err != nil || got != want {
+		t.Errorf("ParseImportOffset(Token()) = %+v, %v, want %+v", got, err, want)
+	}
+	if got, err := ParseImportOffset(""); err != nil || got != (ImportOffset{}) {
+		t.Errorf("empty token = %+v, %v, want the start", got, err)
+	}
+	for _, token := range []string{"not base64!", "bm90IGpzb24", ImportOffset{Line: -1}.Token()} {
+		if _, err := ParseImportOffset(token); !errors.Is(err, ErrInvalidImportOffset) {
+			t.Errorf("ParseImportOffset(%q) error = %v, want %v", token, err, ErrInvalidImportOffset)
+		}
+	}
+
+	logger := zerolog.Nop()
+	svc := New(nil, &logger, Options{Import: Import{MaxEntries: 500}})
+	if got := svc.StreamChunkSize(); got != 500 {
+		t.Errorf("StreamChunkSize() = %d, want the 500 entries limit", got)
+	}
+}
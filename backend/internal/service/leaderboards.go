@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+var (
+	// ErrInvalidLeaderboardID is returned when a leaderboard id is malformed
+	ErrInvalidLeaderboardID = errors.New("invalid leaderboard id")
+
+	// ErrInvalidSortOrder is returned when a sort order is neither "desc" nor "asc"
+	ErrInvalidSortOrder = errors.New("invalid sort order")
+
+	// ErrSortOrderLocked is returned when changing a sort order of a board that has scores:
+	// the stored bests were chosen under the old orders and would not be the bests under the new ones
+	ErrSortOrderLocked = errors.New("sort order cannot change once a leaderboard has scores")
+)
+
+// SortOrder is the direction a leaderboard ranks scores in
+type SortOrder string
+
+const (
+	SortDescending SortOrder = "desc" // higher scores rank first (default)
+	SortAscending  SortOrder = "asc"  // lower scores rank first, e.g. lap times
+)
+
+// RankScore converts a score to ranking space, where higher is better on every board.
+// It matches the rank_score column maintained by the database, and is its own inverse.
+func (o SortOrder) RankScore(score int64) int64 {
+	if o == SortAscending {
+		return -score
+	}
+	return score
+}
+
+// ParseSortOrder validates a sort order; empty means SortDescending
+func ParseSortOrder(value string) (SortOrder, error) {
+	switch SortOrder(value) {
+	case "", SortDescending:
+		return SortDescending, nil
+	case SortAscending:
+		return SortAscending, nil
+	}
+	return "", fmt.Errorf("%w: %q, expected %q or %q", ErrInvalidSortOrder, value, SortDescending, SortAscending)
+}
+
+// DefaultLeaderboardID is the board used when a request names none
+const DefaultLeaderboardID = store.DefaultLeaderboardID
+
+// MaxLeaderboardIDLength matches the leaderboard_id_format constraint of the scores table
+const MaxLeaderboardIDLength = 64
+
+// ResolveLeaderboardID returns the board a request targets: the default board when
+// id is empty, id itself when it is a valid board id.
+//
+// Boards do not need to be declared: any valid id (e.g. "level-42") is a board,
+// created by its first score. A definition (see UpsertLeaderboard) is only needed
+// to change its settings.
+func ResolveLeaderboardID(id string) (string, error) {
+	if id == "" {
+		return DefaultLeaderboardID, nil
+	}
+	if len(id) > MaxLeaderboardIDLength {
+		return "", fmt.Errorf("%w: leaderboard id must be at most %d characters",
+			ErrInvalidLeaderboardID, MaxLeaderboardIDLength)
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_', r == '-', r == '.', r == ':':
+		default:
+			return "", fmt.Errorf("%w: leaderboard id may only contain letters, digits and _ - . :",
+				ErrInvalidLeaderboardID)
+		}
+	}
+	return id, nil
+}
+
+// UpsertLeaderboard creates or updates a leaderboard definition. order ranks the
+// scores and secondaryOrder the secondary scores that break their ties. Neither can
+// change while the board has scores (ErrSortOrderLocked otherwise).
+func (s *Service) UpsertLeaderboard(ctx context.Context, board string, order, secondaryOrder SortOrder) (*store.Leaderboard, error) {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+	order, err = ParseSortOrder(string(order))
+	if err != nil {
+		return nil, err
+	}
+	secondaryOrder, err = ParseSortOrder(string(secondaryOrder))
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.admit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	current, err := s.GetLeaderboard(ctx, board)
+	if err != nil {
+		return nil, err
+	}
+	if SortOrder(current.SortOrder) != order || SortOrder(current.SecondarySortOrder) != secondaryOrder {
+		hasScores, err := s.store.HasScores(ctx, board)
+		if err != nil {
+			s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to check leaderboard scores")
+			return nil, fmt.Errorf("check leaderboard scores: %w", err)
+		}
+		if hasScores {
+			return nil, fmt.Errorf("%w: %s is %s, then %s", ErrSortOrderLocked, board, current.SortOrder, current.SecondarySortOrder)
+		}
+	}
+
+	def, err := s.store.UpsertLeaderboard(ctx, store.UpsertLeaderboardParams{
+		LeaderboardID:      board,
+		SortOrder:          string(order),
+		SecondarySortOrder: string(secondaryOrder),
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to upsert leaderboard")
+		return nil, fmt.Errorf("upsert leaderboard: %w", err)
+	}
+
+	s.loggerFor(ctx).Info().Str("leaderboard", board).Str("sort_order", def.SortOrder).Str("secondary_sort_order", def.SecondarySortOrder).Msg("leaderboard definition updated")
+	return &def, nil
+}
+
+// GetLeaderboard returns a board's definition. Boards without one get the defaults
+// (descending order) and zero timestamps.
+func (s *Service) GetLeaderboard(ctx context.Context, board string) (*store.Leaderboard, error) {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+
+	def, err := s.store.GetLeaderboard(ctx, board)
+	if errors.Is(err, store.ErrNoRows) {
+		return &store.Leaderboard{LeaderboardID: board, SortOrder: string(SortDescending), SecondarySortOrder: string(SortDescending)}, nil
+	}
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to get leaderboard")
+		return nil, fmt.Errorf("get leaderboard: %w", err)
+	}
+	return &def, nil
+}
+
+// sortOrder returns the sort order of an already resolved board
+func (s *Service) sortOrder(ctx context.Context, board string) (SortOrder, error) {
+	def, err := s.GetLeaderboard(ctx, board)
+	if err != nil {
+		return "", err
+	}
+	return SortOrder(def.SortOrder), nil
+}
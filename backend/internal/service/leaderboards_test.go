@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestResolveLeaderboardID(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "", want: DefaultLeaderboardID},
+		{input: "global", want: "global"},
+		{input: "level-042", want: "level-042"},
+		{input: "season:3.boss_rush", want: "season:3.boss_rush"},
+		{input: strings.Repeat("a", MaxLeaderboardIDLength), want: strings.Repeat("a", MaxLeaderboardIDLength)},
+		{input: strings.Repeat("a", MaxLeaderboardIDLength+1), wantErr: true},
+		{input: "level 42", wantErr: true},
+		{input: "level/42", wantErr: true},
+		{input: "niveau-é", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ResolveLeaderboardID(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidLeaderboardID) {
+					t.Errorf("ResolveLeaderboardID(%q) error = %v, want %v", tt.input, err, ErrInvalidLeaderboardID)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ResolveLeaderboardID(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaderboardsAreIndependent(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{TopCacheSize: 10, TopCacheBoards: 1})
+
+	for _, sub := range []ScoreSubmission{
+		{PlayerName: "Alice", Score: 500},
+		{LeaderboardID: "level-1", PlayerName: "Alice", Score: 10},
+		{LeaderboardID: "level-1", PlayerName: "Bob", Score: 20},
+		{LeaderboardID: "level-2", PlayerName: "Bob", Score: 5},
+	} {
+		if _, err := svc.SubmitScore(ctx, sub); err != nil {
+			t.Fatalf("submit %+v: %v", sub, err)
+		}
+	}
+
+	// Read every board twice: with room for a single cached board, each read evicts the previous one
+	for range 2 {
+		for board, want := range map[string][]string{
+			"":        {"Alice"},
+			"level-1": {"Bob", "Alice"},
+			"level-2": {"Bob"},
+			"level-3": {},
+		} {
+			scores, err := svc.GetTopScores(ctx, board, 10, 0)
+			if err != nil {
+				t.Fatalf("GetTopScores(%q): %v", board, err)
+			}
+			if got := names(scores); !slices.Equal(got, want) {
+				t.Errorf("GetTopScores(%q) = %v, want %v", board, got, want)
+			}
+		}
+	}
+
+	rank, err := svc.GetPlayerRank(ctx, "level-1", "Alice")
+	if err != nil || rank.Rank != 2 || rank.Score.Score != 10 {
+		t.Errorf("level-1 rank of Alice = %+v (err %v), want 2 with 10", rank, err)
+	}
+	if _, err := svc.GetPlayerRank(ctx, "level-2", "Alice"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("level-2 rank of Alice error = %v, want %v", err, ErrPlayerNotFound)
+	}
+
+	if err := svc.DeleteScore(ctx, "level-1", "Alice"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.GetPlayerRank(ctx, "", "Alice"); err != nil {
+		t.Errorf("default board entry deleted with level-1: %v", err)
+	}
+
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: "bad id", PlayerName: "Alice", Score: 1}); !errors.Is(err, ErrInvalidLeaderboardID) {
+		t.Errorf("submit to malformed board error = %v, want %v", err, ErrInvalidLeaderboardID)
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	for input, want := range map[string]SortOrder{"": SortDescending, "desc": SortDescending, "asc": SortAscending} {
+		if got, err := ParseSortOrder(input); err != nil || got != want {
+			t.Errorf("ParseSortOrder(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseSortOrder("up"); !errors.Is(err, ErrInvalidSortOrder) {
+		t.Errorf("ParseSortOrder(\"up\") error = %v, want %v", err, ErrInvalidSortOrder)
+	}
+}
+
+func TestAscendingLeaderboard(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{TopCacheSize: 10})
+
+	if _, err := svc.UpsertLeaderboard(ctx, "lap-1", SortAscending, SortDescending); err != nil {
+		t.Fatalf("UpsertLeaderboard: %v", err)
+	}
+
+	for _, tt := range []struct {
+		player      string
+		score       int64
+		wantApplied bool
+	}{
+		{"Alice", 9000, true},
+		{"Bob", 8000, true},
+		{"Alice", 9500, false}, // slower than her best
+		{"Alice", 8500, true},
+	} {
+		res, err := svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: "lap-1", PlayerName: tt.player, Score: tt.score})
+		if err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+		if res.Applied != tt.wantApplied {
+			t.Errorf("submit %s %d applied = %v, want %v", tt.player, tt.score, res.Applied, tt.wantApplied)
+		}
+	}
+
+	scores, err := svc.GetTopScores(ctx, "lap-1", 10, 0)
+	if err != nil {
+		t.Fatalf("GetTopScores: %v", err)
+	}
+	if got, want := names(scores), []string{"Bob", "Alice"}; !slices.Equal(got, want) {
+		t.Errorf("GetTopScores = %v, want %v", got, want)
+	}
+
+	// Alice needs 501 less than her 8500 to pass Bob's 8000
+	sim, err := svc.SimulateRank(ctx, "lap-1", 8500, "Alice", Segment{})
+	if err != nil || sim.Rank != 2 || sim.PointsToNextRank != 501 {
+		t.Errorf("SimulateRank = %+v (err %v), want rank 2 with 501 to next", sim, err)
+	}
+}
+
+func TestUpsertLeaderboardSortOrderLocked(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{})
+
+	def, err := svc.GetLeaderboard(ctx, "level-1")
+	if err != nil || def.SortOrder != string(SortDescending) {
+		t.Fatalf("GetLeaderboard(undefined) = %+v (err %v), want implicit desc", def, err)
+	}
+	if _, err := svc.UpsertLeaderboard(ctx, "level-1", SortAscending, SortDescending); err != nil {
+		t.Fatalf("UpsertLeaderboard(empty board): %v", err)
+	}
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: "level-1", PlayerName: "Alice", Score: 10}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	if _, err := svc.UpsertLeaderboard(ctx, "level-1", SortDescending, SortDescending); !errors.Is(err, ErrSortOrderLocked) {
+		t.Errorf("flipping a non-empty board error = %v, want %v", err, ErrSortOrderLocked)
+	}
+	if _, err := svc.UpsertLeaderboard(ctx, "level-1", SortAscending, SortDescending); err != nil {
+		t.Errorf("re-declaring the same order: %v", err)
+	}
+	if _, err := svc.UpsertLeaderboard(ctx, "level-1", SortOrder("up"), SortDescending); !errors.Is(err, ErrInvalidSortOrder) {
+		t.Errorf("invalid order error = %v, want %v", err, ErrInvalidSortOrder)
+	}
+	if _, err := svc.UpsertLeaderboard(ctx, "level-1", SortAscending, SortAscending); !errors.Is(err, ErrSortOrderLocked) {
+		t.Errorf("flipping the secondary order of a non-empty board error = %v, want %v", err, ErrSortOrderLocked)
+	}
+}
+
+func TestSubmitScoreSecondary(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{})
+
+	// Equal scores rank by the shortest time
+	if _, err := svc.UpsertLeaderboard(ctx, "level-1", SortDescending, SortAscending); err != nil {
+		t.Fatalf("UpsertLeaderboard: %v", err)
+	}
+	for _, sub := range []ScoreSubmission{
+		{PlayerName: "Alice", Score: 100, SecondaryScore: 95},
+		{PlayerName: "Bob", Score: 100, SecondaryScore: 80},
+	} {
+		sub.LeaderboardID = "level-1"
+		if _, err := svc.SubmitScore(ctx, sub); err != nil {
+			t.Fatalf("submit %s: %v", sub.PlayerName, err)
+		}
+	}
+	res, err := svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: "level-1", PlayerName: "Alice", Score: 100, SecondaryScore: 70})
+	if err != nil || !res.Applied || res.SecondaryScore != 70 {
+		t.Fatalf("faster run = %+v, %v; want applied with secondary score 70", res, err)
+	}
+	if rank, err := svc.GetPlayerRank(ctx, "level-1", "Alice"); err != nil || rank.Rank != 1 {
+		t.Errorf("rank of Alice = %+v, %v; want 1", rank, err)
+	}
+
+	_, err = svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: "level-1", PlayerName: "Bob", Score: 100, SecondaryScore: -1})
+	if !errors.Is(err, ErrInvalidScore) {
+		t.Errorf("negative secondary score: error = %v, want ErrInvalidScore", err)
+	}
+}
+
+// BenchmarkTopCachesApply routes score changes across 10k boards, 100 of them cached
+func BenchmarkTopCachesApply(b *testing.B) {
+	const boards = 10_000
+	caches := newTopCaches(100, DefaultTopCacheBoards)
+	for i := range DefaultTopCacheBoards {
+		caches.board(fmt.Sprintf("level-%d", i)).loaded = true
+	}
+	changes := make([]string, boards)
+	for i := range changes {
+		changes[i] = fmt.Sprintf("level-%d", i)
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		change := notifyChange(changes[i%boards], fmt.Sprintf("p%d", i%50), int64(i))
+		caches.apply(change)
+	}
+}
+
+// BenchmarkTopCachesBoard looks up board caches with 10k boards competing for 100 slots
+func BenchmarkTopCachesBoard(b *testing.B) {
+	const boards = 10_000
+	caches := newTopCaches(100, DefaultTopCacheBoards)
+	ids := make([]string, boards)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("level-%d", i)
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		caches.board(ids[i%boards])
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// ErrInvalidMetadata is returned when score metadata is too large or malformed
+var ErrInvalidMetadata = errors.New("invalid metadata")
+
+// Limits of the metadata attached to a score
+const (
+	MaxMetadataEntries     = 16
+	MaxMetadataKeyLength   = 32
+	MaxMetadataValueLength = 256
+	MaxMetadataSize        = 2048 // bytes of the JSON encoding
+)
+
+// metadataKeyPattern matches metadata keys: identifiers such as "level" or "replay_id"
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// validateMetadata checks the attributes of a submission against the metadata limits
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataEntries {
+		return fmt.Errorf("%w: at most %d entries", ErrInvalidMetadata, MaxMetadataEntries)
+	}
+	for k, v := range metadata {
+		if len(k) > MaxMetadataKeyLength || !metadataKeyPattern.MatchString(k) {
+			return fmt.Errorf("%w: key %q must be 1 to %d letters, digits, '_', '.' or '-'", ErrInvalidMetadata, k, MaxMetadataKeyLength)
+		}
+		if len(v) > MaxMetadataValueLength || !utf8.ValidString(v) {
+			return fmt.Errorf("%w: value of %q must be valid UTF-8 of at most %d bytes", ErrInvalidMetadata, k, MaxMetadataValueLength)
+		}
+	}
+	if size := len(encodeMetadata(metadata)); size > MaxMetadataSize {
+		return fmt.Errorf("%w: %d bytes encoded, at most %d", ErrInvalidMetadata, size, MaxMetadataSize)
+	}
+	return nil
+}
+
+// encodeMetadata returns the stored JSON object of metadata, nil when there is none
+// so the store keeps its empty default
+func encodeMetadata(metadata map[string]string) []byte {
+	if len(metadata) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(metadata) // a map of strings always encodes
+	return b
+}
+
+// DecodeMetadata returns the metadata of a stored score, nil when it has none.
+// The store only holds objects of strings written by the service.
+func DecodeMetadata(raw []byte) map[string]string {
+	var metadata map[string]string
+	if err := json.Unmarshal(raw, &metadata); err != nil || len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
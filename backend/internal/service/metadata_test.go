@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := 0; i <= MaxMetadataEntries; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+	tooLarge := map[string]string{}
+	for i := 0; i < MaxMetadataEntries; i++ {
+		tooLarge[strings.Repeat("k", i+1)] = strings.Repeat("v", MaxMetadataValueLength)
+	}
+
+	cases := []struct {
+		name     string
+		metadata map[string]string
+		valid    bool
+	}{
+		{"none", nil, true},
+		{"attributes", map[string]string{"level": "3", "character": "Zoë", "replay_id": "r-42"}, true},
+		{"empty value", map[string]string{"mode": ""}, true},
+		{"too many entries", tooMany, false},
+		{"empty key", map[string]string{"": "x"}, false},
+		{"key with spaces", map[string]string{"replay id": "x"}, false},
+		{"long key", map[string]string{strings.Repeat("k", MaxMetadataKeyLength+1): "x"}, false},
+		{"long value", map[string]string{"replay": strings.Repeat("v", MaxMetadataValueLength+1)}, false},
+		{"invalid UTF-8", map[string]string{"name": "\xff"}, false},
+		{"too large", tooLarge, false},
+	}
+	for _, c := range cases {
+		err := validateMetadata(c.metadata)
+		if c.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if !c.valid && !errors.Is(err, ErrInvalidMetadata) {
+			t.Errorf("%s: error = %v, want ErrInvalidMetadata", c.name, err)
+		}
+	}
+}
+
+func TestSubmitScoreMetadata(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{})
+
+	level3 := map[string]string{"level": "3", "character": "knight"}
+	res, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Alice", Score: 100, Metadata: level3})
+	if err != nil || !reflect.DeepEqual(res.Metadata, level3) {
+		t.Fatalf("first submission = %+v, %v; want metadata %v", res, err, level3)
+	}
+
+	// Metadata follows the best score
+	res, err = svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Alice", Score: 50, Metadata: map[string]string{"level": "1"}})
+	if err != nil || !reflect.DeepEqual(res.Metadata, level3) {
+		t.Errorf("lower submission = %+v, %v; want metadata %v kept", res, err, level3)
+	}
+	res, err = svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Alice", Score: 200})
+	if err != nil || res.Metadata != nil {
+		t.Errorf("better submission without metadata = %+v, %v; want no metadata", res, err)
+	}
+
+	_, err = svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Bob", Score: 10, Metadata: map[string]string{"bad key": "x"}})
+	if !errors.Is(err, ErrInvalidMetadata) {
+		t.Errorf("invalid metadata: error = %v, want ErrInvalidMetadata", err)
+	}
+	if _, err := svc.GetPlayerRank(ctx, "", "Bob"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("rank after rejected submission: error = %v, want ErrPlayerNotFound", err)
+	}
+
+	scores, err := svc.GetTopScores(ctx, "", 10, 0)
+	if err != nil || len(scores) != 1 || DecodeMetadata(scores[0].Metadata) != nil {
+		t.Errorf("top scores = %+v, %v; want Alice without metadata", scores, err)
+	}
+}
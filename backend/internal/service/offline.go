@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+var (
+	// ErrOfflineSyncDisabled is returned when no offline sync signing key is configured
+	ErrOfflineSyncDisabled = errors.New("offline sync disabled")
+
+	// ErrInvalidSignature is returned when a signed submission or offline batch fails verification
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrBatchTooLarge is returned when an offline batch has more runs than allowed
+	ErrBatchTooLarge = errors.New("offline batch too large")
+)
+
+// offlineSignatureVersion prefixes the canonical batch so the format can evolve
+const offlineSignatureVersion = "leaderboard-offline-v1"
+
+// Offline run outcomes, also used as metric labels
+const (
+	OfflineApplied     = "applied"
+	OfflineNotImproved = "not_improved"
+	OfflineInvalid     = "invalid"
+	OfflineOutOfWindow = "out_of_window"
+	OfflineLimited     = "limited"
+	OfflineBanned      = "banned"
+)
+
+// OfflineSync configures offline batch uploads
+type OfflineSync struct {
+	// SigningKey is the shared HMAC-SHA256 key batches are signed with (empty disables sync)
+	SigningKey []byte
+
+	// MaxRuns is the maximum number of runs per batch
+	MaxRuns int
+}
+
+// OfflineRun is a run recorded by the client while offline
+type OfflineRun struct {
+	RunID         string
+	LeaderboardID string // board of the run, empty for the default board
+	PlayerName    string
+	Score         int64
+	AchievedAt    string // RFC3339, signed as sent
+}
+
+// OfflineBatch is a signed upload of offline runs
+type OfflineBatch struct {
+	DeviceID  string
+	Runs      []OfflineRun
+	Signature string // hex HMAC-SHA256 of CanonicalOfflineBatch
+}
+
+// OfflineRunResult is the outcome of one run of a batch
+type OfflineRunResult struct {
+	RunID   string
+	Outcome string
+	Reason  string
+	Entry   *ScoreResult // player's best after the sync (applied and not_improved)
+}
+
+// CanonicalOfflineBatch returns the bytes a client signs for a batch:
+// a version line, the device id line, then one tab-separated line per run
+// (run_id, player_name, score, achieved_at) in upload order. Runs on a board other
+// than the default one have the leaderboard id as a fifth field.
+func CanonicalOfflineBatch(deviceID string, runs []OfflineRun) []byte {
+	var b strings.Builder
+	b.WriteString(offlineSignatureVersion)
+	b.WriteByte('\n')
+	b.WriteString(deviceID)
+	b.WriteByte('\n')
+	for _, r := range runs {
+		b.WriteString(r.RunID)
+		b.WriteByte('\t')
+		b.WriteString(r.PlayerName)
+		b.WriteByte('\t')
+		b.WriteString(strconv.FormatInt(r.Score, 10))
+		b.WriteByte('\t')
+		b.WriteString(r.AchievedAt)
+		if r.LeaderboardID != "" && r.LeaderboardID != DefaultLeaderboardID {
+			b.WriteByte('\t')
+			b.WriteString(r.LeaderboardID)
+		}
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+// SignOfflineBatch computes the signature of a batch with key
+func SignOfflineBatch(key []byte, deviceID string, runs []OfflineRun) string {
+	return signHMAC(key, CanonicalOfflineBatch(deviceID, runs))
+}
+
+// verifySignature checks the batch signature in constant time
+func (s *Service) verifySignature(batch OfflineBatch) error {
+	if !verifyHMAC(s.opts.OfflineSync.SigningKey, CanonicalOfflineBatch(batch.DeviceID, batch.Runs), batch.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// SyncOfflineScores validates a signed batch of offline runs and applies the best
+// valid run of each player on each board with its original completion time, so it ranks as of
+// when it was played. Each run gets its own outcome; batch-level problems
+// (signature, size, overload) fail the whole call. Re-sending a batch is safe:
+// only scores that improve a player's best are applied.
+func (s *Service) SyncOfflineScores(ctx context.Context, batch OfflineBatch) ([]OfflineRunResult, error) {
+	cfg := s.opts.OfflineSync
+	if len(cfg.SigningKey) == 0 {
+		return nil, ErrOfflineSyncDisabled
+	}
+	if len(batch.Runs) > cfg.MaxRuns {
+		return nil, fmt.Errorf("%w: %d runs, max %d", ErrBatchTooLarge, len(batch.Runs), cfg.MaxRuns)
+	}
+	if err := s.validateDeviceID(batch.DeviceID); err != nil {
+		return nil, err
+	}
+	if err := s.verifySignature(batch); err != nil {
+		return nil, err
+	}
+
+	release, err := s.admit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	now := time.Now()
+	results := make([]OfflineRunResult, len(batch.Runs))
+	achievedAt := make([]time.Time, len(batch.Runs))
+	clientAt := make([]time.Time, len(batch.Runs))
+	boards := make([]string, len(batch.Runs))
+	rankScores := make([]int64, len(batch.Runs))
+	orders := make(map[string]SortOrder) // sort order of each board in the batch
+	best := make(map[string]int)         // board and player name -> index of their best valid run
+
+	for i, run := range batch.Runs {
+		results[i] = OfflineRunResult{RunID: run.RunID}
+
+		board, err := ResolveLeaderboardID(run.LeaderboardID)
+		if err != nil {
+			results[i].Outcome, results[i].Reason = OfflineInvalid, err.Error()
+			continue
+		}
+		boards[i] = board
+		if err := s.checkBoardOpen(board, now); err != nil {
+			results[i].Outcome, results[i].Reason = OfflineOutOfWindow, err.Error()
+			continue
+		}
+		if err := s.validatePlayerName(run.PlayerName); err != nil {
+			results[i].Outcome, results[i].Reason = OfflineInvalid, err.Error()
+			continue
+		}
+		if err := s.validateScore(run.Score); err != nil {
+			results[i].Outcome, results[i].Reason = OfflineInvalid, err.Error()
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, run.AchievedAt)
+		if err != nil {
+			results[i].Outcome, results[i].Reason = OfflineInvalid, "achieved_at must be an RFC3339 timestamp"
+			continue
+		}
+		// Offline runs are only worth their timestamp: no fallback to server time
+		switch s.classifyTimestamp(t, now) {
+		case timestampFuture:
+			results[i].Outcome, results[i].Reason = OfflineOutOfWindow, "achieved_at is in the future"
+			continue
+		case timestampExpired:
+			results[i].Outcome, results[i].Reason = OfflineOutOfWindow, "achieved_at is older than the accepted window"
+			continue
+		}
+		clientAt[i], achievedAt[i] = t, t
+		if t.After(now) {
+			achievedAt[i] = now
+		}
+
+		order, ok := orders[board]
+		if !ok {
+			if order, err = s.sortOrder(ctx, board); err != nil {
+				return nil, err
+			}
+			orders[board] = order
+		}
+		rankScores[i] = order.RankScore(run.Score)
+
+		// The better score in the board's order wins; on a tie the earlier run ranks higher
+		key := offlineBestKey(board, run.PlayerName)
+		j, seen := best[key]
+		if !seen || rankScores[i] > rankScores[j] ||
+			(rankScores[i] == rankScores[j] && achievedAt[i].Before(achievedAt[j])) {
+			best[key] = i
+		}
+	}
+
+	// Valid runs beaten by another run of the same player on the same board in this batch
+	for i, run := range batch.Runs {
+		if results[i].Outcome == "" && best[offlineBestKey(boards[i], run.PlayerName)] != i {
+			results[i].Outcome, results[i].Reason = OfflineNotImproved, "superseded by a better run in this batch"
+		}
+	}
+
+	// Runs carry no country or platform: the batch is tagged with those of the client
+	tags := segmentTags{country: s.clientCountry(ctx), platform: clientPlatform(ctx)}
+	entries := make(map[string]*ScoreResult, len(best))
+	for i, run := range batch.Runs {
+		if results[i].Outcome != "" {
+			continue
+		}
+		player := run.PlayerName
+		key := offlineBestKey(boards[i], player)
+
+		if err := s.checkBanned(ctx, player); err != nil {
+			if errors.Is(err, ErrPlayerBanned) {
+				results[i].Outcome, results[i].Reason = OfflineBanned, err.Error()
+				continue
+			}
+			return nil, err
+		}
+		if err := s.checkDeviceLimits(ctx, batch.DeviceID, player); err != nil {
+			if errors.Is(err, ErrDeviceLimitExceeded) {
+				results[i].Outcome, results[i].Reason = OfflineLimited, err.Error()
+				continue
+			}
+			return nil, err
+		}
+
+		client := pgtype.Timestamptz{Time: clientAt[i], Valid: true}
+		entry, err := s.applyScore(ctx, boards[i], player, run.Score, 0, achievedAt[i], client, nil, tags)
+		if err != nil {
+			return nil, err
+		}
+		entries[key] = entry
+
+		if entry.Applied {
+			results[i].Outcome = OfflineApplied
+		} else {
+			results[i].Outcome, results[i].Reason = OfflineNotImproved, "current best score is higher or equal"
+		}
+	}
+
+	for i := range results {
+		if results[i].Outcome == OfflineApplied || results[i].Outcome == OfflineNotImproved {
+			results[i].Entry = entries[offlineBestKey(boards[i], batch.Runs[i].PlayerName)]
+		}
+		metrics.OfflineRuns.WithLabelValues(results[i].Outcome).Inc()
+	}
+
+	s.loggerFor(ctx).Info().
+		Str("device", batch.DeviceID).
+		Int("runs", len(batch.Runs)).
+		Int("players", len(entries)).
+		Msg("📦 offline batch synced")
+
+	return results, nil
+}
+
+// offlineBestKey identifies a player's entry on a board within a batch
+func offlineBestKey(board, playerName string) string {
+	return board + "\x00" + playerName
+}
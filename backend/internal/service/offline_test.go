@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func newOfflineTestService(t *testing.T, key string) *Service {
+	t.Helper()
+	st, err := sqlite.Open(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(st.Close)
+
+	logger := zerolog.Nop()
+	return New(st, &logger, Options{
+		ClientTimestamps: ClientTimestamps{SkewWindow: time.Minute, MaxAge: 24 * time.Hour},
+		OfflineSync:      OfflineSync{SigningKey: []byte(key), MaxRuns: 10},
+	})
+}
+
+func TestSyncOfflineScores(t *testing.T) {
+	ctx := context.Background()
+	key := "test-key"
+	svc := newOfflineTestService(t, key)
+
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Bob", Score: 500}); err != nil {
+		t.Fatalf("seed score: %v", err)
+	}
+
+	at := func(d time.Duration) string { return time.Now().Add(d).UTC().Format(time.RFC3339) }
+	runs := []OfflineRun{
+		{RunID: "r1", PlayerName: "Alice", Score: 100, AchievedAt: at(-3 * time.Hour)},
+		{RunID: "r2", PlayerName: "Alice", Score: 300, AchievedAt: at(-2 * time.Hour)},
+		{RunID: "r3", PlayerName: "Bob", Score: 200, AchievedAt: at(-time.Hour)},
+		{RunID: "r4", PlayerName: "Carol", Score: 50, AchievedAt: at(-48 * time.Hour)},
+		{RunID: "r5", PlayerName: "Carol", Score: 60, AchievedAt: at(time.Hour)},
+		{RunID: "r6", PlayerName: "", Score: 10, AchievedAt: at(-time.Hour)},
+		{RunID: "r7", PlayerName: "Dave", Score: 10, AchievedAt: "yesterday"},
+	}
+	batch := OfflineBatch{Runs: runs, Signature: SignOfflineBatch([]byte(key), "", runs)}
+
+	results, err := svc.SyncOfflineScores(ctx, batch)
+	if err != nil {
+		t.Fatalf("SyncOfflineScores: unexpected error %v", err)
+	}
+
+	want := []string{
+		OfflineNotImproved, // superseded by r2
+		OfflineApplied,
+		OfflineNotImproved, // Bob already has 500
+		OfflineOutOfWindow, // too old
+		OfflineOutOfWindow, // in the future
+		OfflineInvalid,
+		OfflineInvalid,
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, r := range results {
+		if r.RunID != runs[i].RunID {
+			t.Errorf("result %d: run id = %q, want %q", i, r.RunID, runs[i].RunID)
+		}
+		if r.Outcome != want[i] {
+			t.Errorf("run %s: outcome = %q (%s), want %q", r.RunID, r.Outcome, r.Reason, want[i])
+		}
+	}
+
+	alice := results[1].Entry
+	if alice == nil || alice.Score != 300 {
+		t.Fatalf("Alice entry = %+v, want score 300", alice)
+	}
+	if alice.AchievedAt.UTC().Format(time.RFC3339) != runs[1].AchievedAt {
+		t.Errorf("Alice achieved_at = %s, want %s", alice.AchievedAt, runs[1].AchievedAt)
+	}
+
+	// Re-sending the same batch applies nothing new
+	results, err = svc.SyncOfflineScores(ctx, batch)
+	if err != nil {
+		t.Fatalf("resend: unexpected error %v", err)
+	}
+	if results[1].Outcome != OfflineNotImproved {
+		t.Errorf("resend: run r2 outcome = %q, want %q", results[1].Outcome, OfflineNotImproved)
+	}
+}
+
+func TestSyncOfflineScoresRejectsBatch(t *testing.T) {
+	ctx := context.Background()
+	runs := []OfflineRun{{RunID: "r1", PlayerName: "Alice", Score: 100, AchievedAt: time.Now().UTC().Format(time.RFC3339)}}
+
+	tests := []struct {
+		name    string
+		key     string
+		batch   OfflineBatch
+		wantErr error
+	}{
+		{
+			name:    "sync disabled",
+			key:     "",
+			batch:   OfflineBatch{Runs: runs, Signature: SignOfflineBatch([]byte("k"), "", runs)},
+			wantErr: ErrOfflineSyncDisabled,
+		},
+		{
+			name:    "wrong key",
+			key:     "k",
+			batch:   OfflineBatch{Runs: runs, Signature: SignOfflineBatch([]byte("other"), "", runs)},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "tampered device",
+			key:     "k",
+			batch:   OfflineBatch{DeviceID: "abc", Runs: runs, Signature: SignOfflineBatch([]byte("k"), "", runs)},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "malformed signature",
+			key:     "k",
+			batch:   OfflineBatch{Runs: runs, Signature: "not-hex"},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "too many runs",
+			key:     "k",
+			batch:   OfflineBatch{Runs: make([]OfflineRun, 11)},
+			wantErr: ErrBatchTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newOfflineTestService(t, tt.key)
+			if _, err := svc.SyncOfflineScores(ctx, tt.batch); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SyncOfflineScores() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSyncOfflineScoresBoards(t *testing.T) {
+	ctx := context.Background()
+	key := "test-key"
+	svc := newOfflineTestService(t, key)
+
+	at := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	runs := []OfflineRun{
+		{RunID: "r1", LeaderboardID: "level-1", PlayerName: "Alice", Score: 100, AchievedAt: at},
+		{RunID: "r2", LeaderboardID: "level-2", PlayerName: "Alice", Score: 50, AchievedAt: at},
+		{RunID: "r3", LeaderboardID: "level-1", PlayerName: "Alice", Score: 80, AchievedAt: at},
+		{RunID: "r4", LeaderboardID: "bad id", PlayerName: "Alice", Score: 10, AchievedAt: at},
+	}
+	results, err := svc.SyncOfflineScores(ctx, OfflineBatch{Runs: runs, Signature: SignOfflineBatch([]byte(key), "", runs)})
+	if err != nil {
+		t.Fatalf("SyncOfflineScores() error = %v", err)
+	}
+
+	// Each board keeps its own best: r2 is not superseded by the higher r1
+	want := []string{OfflineApplied, OfflineApplied, OfflineNotImproved, OfflineInvalid}
+	for i, r := range results {
+		if r.Outcome != want[i] {
+			t.Errorf("run %s outcome = %s (%s), want %s", r.RunID, r.Outcome, r.Reason, want[i])
+		}
+	}
+	if e := results[1].Entry; e == nil || e.LeaderboardID != "level-2" || e.Score != 50 {
+		t.Errorf("level-2 entry = %+v, want score 50 on level-2", e)
+	}
+
+	// The board is part of the signed payload
+	moved := append([]OfflineRun(nil), runs...)
+	moved[1].LeaderboardID = "level-3"
+	batch := OfflineBatch{Runs: moved, Signature: SignOfflineBatch([]byte(key), "", runs)}
+	if _, err := svc.SyncOfflineScores(ctx, batch); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("batch with a moved run error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
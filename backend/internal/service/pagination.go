@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// ErrInvalidPageToken is returned when a page token cannot be decoded
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// TopScoresPage is a page of the leaderboard with the token of the next one
+type TopScoresPage struct {
+	Scores         []store.Score
+	NextPageToken  string // empty when the page is the last one
+	RankingVariant string // RankingControl, or the variant of the ranking experiment
+	TotalCount     int64  // entries of the board or segment listed, see GetEntryCount
+}
+
+// pageCursor is the keyset position a page token points after. Pages of a ranking
+// experiment variant are not keyset-paged: their tokens hold the variant and the
+// offset of the next page instead.
+type pageCursor struct {
+	Board      string `json:"b,omitempty"` // empty for the default board
+	RankScore  int64  `json:"s"`           // ranking space, so tokens of 'desc' boards hold the score
+	Secondary  int64  `json:"t,omitempty"` // rank_secondary, absent from tokens issued before secondary scores
+	AchievedAt int64  `json:"a"`           // Unix microseconds, the storage precision
+	PlayerName string `json:"p"`
+	Variant    string `json:"v,omitempty"`
+	Offset     int32  `json:"o,omitempty"`
+	Region     string `json:"r,omitempty"`  // country of a segment listing, see GetSegmentTopScoresPage
+	Platform   string `json:"pl,omitempty"` // platform family of a segment listing
+}
+
+// GetTopScoresPage retrieves a page of the leaderboard. With a page token, the page
+// starts right after the last entry of the previous page (keyset pagination) and
+// offset is ignored; without one it starts at offset. A next page token is returned
+// whenever the page is full. A token is only valid for the board it was issued for.
+// With a ranking experiment, the first page of a listing may be served by its
+// variant, and so are the pages that follow it.
+func (s *Service) GetTopScoresPage(ctx context.Context, board string, limit, offset int32, pageToken string) (*TopScoresPage, error) {
+	start := time.Now()
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		scores  []store.Score
+		variant = RankingControl
+	)
+	if pageToken == "" {
+		variant = s.pickRankingVariant()
+		scores, err = s.getTopScoresRanked(ctx, board, variant, limit, offset)
+	} else {
+		var cursor pageCursor
+		if cursor, err = decodeCursor(pageToken); err != nil {
+			return nil, err
+		}
+		if cursor.Board != board || cursor.Region != "" || cursor.Platform != "" {
+			return nil, fmt.Errorf("%w: token was issued for another leaderboard", ErrInvalidPageToken)
+		}
+		if cursor.Variant != "" {
+			variant, offset = cursor.Variant, cursor.Offset
+			scores, err = s.getTopScoresRanked(ctx, board, variant, limit, offset)
+		} else {
+			scores, err = s.getTopScoresAfter(ctx, cursor.score(), limit)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	observeRankRead(rankReadTopScores, variant, start)
+
+	total, err := s.entryCount(ctx, board, Segment{})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TopScoresPage{Scores: scores, RankingVariant: variant, TotalCount: total}
+	if len(scores) > 0 && len(scores) == int(limit) {
+		if variant == RankingControl {
+			page.NextPageToken = encodePageToken(board, scores[len(scores)-1])
+		} else {
+			page.NextPageToken = encodeVariantPageToken(board, variant, offset+limit)
+		}
+	}
+	return page, nil
+}
+
+// getTopScoresAfter serves the page following a keyset cursor, from the top cache when it can
+func (s *Service) getTopScoresAfter(ctx context.Context, after store.Score, limit int32) ([]store.Score, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidLimit)
+	}
+
+	if scores, ok, err := s.getTopScoresAfterCached(ctx, after, limit); err != nil {
+		return nil, err
+	} else if ok {
+		return scores, nil
+	}
+
+	board := after.LeaderboardID
+	scores, err := s.store.GetTopScoresAfter(ctx, store.GetTopScoresAfterParams{
+		LeaderboardID: board,
+		RankScore:     after.RankScore,
+		RankSecondary: after.RankSecondary,
+		AchievedAt:    after.AchievedAt,
+		PlayerName:    after.PlayerName,
+		PageSize:      limit,
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Int32("limit", limit).Msg("failed to get top scores after cursor")
+		return nil, fmt.Errorf("get top scores after cursor: %w", err)
+	}
+	return scores, nil
+}
+
+// getTopScoresAfterCached is getTopScoresCached for keyset pages
+func (s *Service) getTopScoresAfterCached(ctx context.Context, after store.Score, limit int32) ([]store.Score, bool, error) {
+	if s.top.size == 0 {
+		return nil, false, nil
+	}
+
+	cache := s.top.board(after.LeaderboardID)
+	if scores, ok := cache.getAfter(after, int(limit)); ok {
+		metrics.TopCacheRequests.WithLabelValues("hit").Inc()
+		return scores, true, nil
+	}
+	if cache.isLoaded() {
+		// Loaded but the page runs past the cached window
+		return nil, false, nil
+	}
+	metrics.TopCacheRequests.WithLabelValues("miss").Inc()
+
+	if err := s.loadTopCache(ctx, cache); err != nil {
+		return nil, false, err
+	}
+	scores, ok := cache.getAfter(after, int(limit))
+	return scores, ok, nil
+}
+
+// encodePageToken returns an opaque token pointing after the given entry of a board
+func encodePageToken(board string, last store.Score) string {
+	return encodeCursor(keysetCursor(board, last))
+}
+
+// keysetCursor returns the cursor pointing after the given entry of a board
+func keysetCursor(board string, last store.Score) pageCursor {
+	if board == DefaultLeaderboardID {
+		board = "" // keeps tokens of the default board as they were before boards existed
+	}
+	return pageCursor{
+		Board:      board,
+		RankScore:  last.RankScore,
+		Secondary:  last.RankSecondary,
+		AchievedAt: last.AchievedAt.Time.UnixMicro(),
+		PlayerName: last.PlayerName,
+	}
+}
+
+// encodeVariantPageToken returns an opaque token of the page of a ranking variant at offset
+func encodeVariantPageToken(board, variant string, offset int32) string {
+	if board == DefaultLeaderboardID {
+		board = ""
+	}
+	return encodeCursor(pageCursor{Board: board, Variant: variant, Offset: offset})
+}
+
+// encodeCursor returns the opaque page token of a cursor
+func encodeCursor(c pageCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns the cursor of a page token, with the default board filled in
+func decodeCursor(token string) (pageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+	switch c.Variant {
+	case "":
+		if c.PlayerName == "" {
+			return pageCursor{}, ErrInvalidPageToken
+		}
+	case RankingRecency:
+		if c.Offset < 0 {
+			return pageCursor{}, ErrInvalidPageToken
+		}
+	default:
+		return pageCursor{}, ErrInvalidPageToken
+	}
+	if c.Board == "" {
+		c.Board = DefaultLeaderboardID
+	}
+	return c, nil
+}
+
+// score returns the entry a keyset cursor points after
+func (c pageCursor) score() store.Score {
+	return store.Score{
+		LeaderboardID: c.Board,
+		PlayerName:    c.PlayerName,
+		RankScore:     c.RankScore,
+		RankSecondary: c.Secondary,
+		AchievedAt:    pgtype.Timestamptz{Time: time.UnixMicro(c.AchievedAt).UTC(), Valid: true},
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	for _, token := range []string{"not base64!", "bm90IGpzb24", "e30", "eyJ2IjoieCJ9"} { // "not json", "{}", `{"v":"x"}`
+		if _, err := decodeCursor(token); !errors.Is(err, ErrInvalidPageToken) {
+			t.Errorf("decodeCursor(%q) error = %v, want %v", token, err, ErrInvalidPageToken)
+		}
+	}
+}
+
+func TestGetTopScoresPage(t *testing.T) {
+	ctx := context.Background()
+	// B and C tie: B is submitted first so it ranks first, and the page boundary falls between them
+	players := []struct {
+		name  string
+		score int64
+	}{{"A", 500}, {"B", 400}, {"C", 400}, {"D", 300}, {"E", 200}}
+	want := []string{"A", "B", "C", "D", "E"}
+
+	for _, cacheSize := range []int{0, 3, 10} {
+		st, err := sqlite.Open(ctx, ":memory:")
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		defer st.Close()
+		logger := zerolog.Nop()
+		svc := New(st, &logger, Options{TopCacheSize: cacheSize})
+		for _, p := range players {
+			if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: p.name, Score: p.score}); err != nil {
+				t.Fatalf("submit: %v", err)
+			}
+		}
+
+		var got []string
+		token := ""
+		for range 10 {
+			page, err := svc.GetTopScoresPage(ctx, "", 2, 0, token)
+			if err != nil {
+				t.Fatalf("cache %d: GetTopScoresPage: %v", cacheSize, err)
+			}
+			got = append(got, names(page.Scores)...)
+			if page.NextPageToken == "" {
+				break
+			}
+			token = page.NextPageToken
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("cache %d: paged names = %v, want %v", cacheSize, got, want)
+		}
+	}
+}
+
+func TestGetTopScoresPageStableAcrossChanges(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{})
+
+	for name, score := range map[string]int64{"A": 500, "B": 400, "C": 300, "D": 200} {
+		if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: name, Score: score}); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+
+	first, err := svc.GetTopScoresPage(ctx, "", 2, 0, "")
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+
+	// D jumps to the top between pages: with offsets C would be served twice
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "D", Score: 900}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	second, err := svc.GetTopScoresPage(ctx, "", 2, 0, first.NextPageToken)
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	if got := names(second.Scores); !slices.Equal(got, []string{"C"}) {
+		t.Errorf("second page = %v, want [C]", got)
+	}
+	if second.NextPageToken != "" {
+		t.Errorf("short page has next token %q, want none", second.NextPageToken)
+	}
+}
+
+func TestGetTopScoresPageTokenBoundToBoard(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{})
+
+	for _, name := range []string{"A", "B", "C"} {
+		if _, err := svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: "level-1", PlayerName: name, Score: 100}); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+
+	first, err := svc.GetTopScoresPage(ctx, "level-1", 2, 0, "")
+	if err != nil || first.NextPageToken == "" {
+		t.Fatalf("first page = %+v, err %v, want a next token", first, err)
+	}
+	if _, err := svc.GetTopScoresPage(ctx, "", 2, 0, first.NextPageToken); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("token of level-1 on the default board: error = %v, want %v", err, ErrInvalidPageToken)
+	}
+	second, err := svc.GetTopScoresPage(ctx, "level-1", 2, 0, first.NextPageToken)
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	if got := names(second.Scores); !slices.Equal(got, []string{"C"}) {
+		t.Errorf("second page = %v, want [C]", got)
+	}
+}
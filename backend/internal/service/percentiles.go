@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// DefaultPercentileBuckets are the "top X%" buckets used when none are configured
+var DefaultPercentileBuckets = []float64{1, 5, 10, 25, 50}
+
+// PercentileBucket is the score required to be in the top X% of players: the minimum
+// score on descending boards, the maximum score on ascending boards
+type PercentileBucket struct {
+	TopPercent float64
+	MinScore   int64
+}
+
+// PercentileSnapshot is a computed set of percentile buckets
+type PercentileSnapshot struct {
+	Buckets      []PercentileBucket
+	SortOrder    SortOrder
+	TotalPlayers int64
+	ComputedAt   time.Time
+}
+
+// percentileCache caches the last computed percentile snapshot of each board segment
+type percentileCache struct {
+	mu        sync.Mutex
+	snapshots map[percentileKey]*PercentileSnapshot
+}
+
+type percentileKey struct {
+	board string
+	seg   Segment
+}
+
+// GetPercentileBuckets returns the score thresholds of the configured percentile buckets
+// of a board, among the players of a segment (the zero Segment for the whole board).
+// Results are cached for Options.PercentileCacheTTL since computing them sorts the
+// whole board.
+func (s *Service) GetPercentileBuckets(ctx context.Context, board string, seg Segment) (*PercentileSnapshot, error) {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+	if seg, err = normalizeSegment(seg); err != nil {
+		return nil, err
+	}
+	key := percentileKey{board: board, seg: seg}
+
+	s.percentiles.mu.Lock()
+	defer s.percentiles.mu.Unlock()
+
+	if cached := s.percentiles.snapshots[key]; cached != nil && time.Since(cached.ComputedAt) < s.opts.PercentileCacheTTL {
+		return cached, nil
+	}
+
+	snapshot, err := s.computePercentiles(ctx, board, seg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop expired snapshots so boards that are no longer read do not accumulate
+	if s.percentiles.snapshots == nil {
+		s.percentiles.snapshots = make(map[percentileKey]*PercentileSnapshot)
+	}
+	for k, cached := range s.percentiles.snapshots {
+		if time.Since(cached.ComputedAt) >= s.opts.PercentileCacheTTL {
+			delete(s.percentiles.snapshots, k)
+		}
+	}
+	s.percentiles.snapshots[key] = snapshot
+	return snapshot, nil
+}
+
+func (s *Service) computePercentiles(ctx context.Context, board string, seg Segment) (*PercentileSnapshot, error) {
+	buckets := s.opts.PercentileBuckets
+
+	order, err := s.sortOrder(ctx, board)
+	if err != nil {
+		return nil, err
+	}
+
+	// "top 1%" is the rank score at the 99th percentile of the ascending distribution
+	fractions := make([]float64, len(buckets))
+	for i, top := range buckets {
+		fractions[i] = 1 - top/100
+	}
+
+	row, err := s.store.GetScorePercentiles(ctx, store.GetScorePercentilesParams{
+		LeaderboardID: board,
+		Fractions:     fractions,
+		CountryCode:   seg.Region,
+		Platform:      seg.Platform,
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to compute score percentiles")
+		return nil, fmt.Errorf("get score percentiles: %w", err)
+	}
+
+	snapshot := &PercentileSnapshot{
+		Buckets:      make([]PercentileBucket, 0, len(buckets)),
+		SortOrder:    order,
+		TotalPlayers: row.Total,
+		ComputedAt:   time.Now(),
+	}
+	for i, top := range buckets {
+		if i >= len(row.Thresholds) {
+			break
+		}
+		snapshot.Buckets = append(snapshot.Buckets, PercentileBucket{
+			TopPercent: top,
+			MinScore:   order.RankScore(int64(math.Ceil(row.Thresholds[i]))),
+		})
+	}
+
+	return snapshot, nil
+}
+
+// normalizePercentileBuckets sorts buckets from the most exclusive and drops duplicates
+func normalizePercentileBuckets(buckets []float64) []float64 {
+	if len(buckets) == 0 {
+		buckets = DefaultPercentileBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	out := sorted[:0]
+	for i, b := range sorted {
+		if i > 0 && b == sorted[i-1] {
+			continue
+		}
+		out = append(out, b)
+	}
+	return out
+}
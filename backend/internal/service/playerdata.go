@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// ExportPlayerData returns everything stored about a player, for data subject
+// access requests. A player the server holds nothing about gets an empty export
+// rather than ErrPlayerNotFound: that is the answer to the request. Admin only.
+func (s *Service) ExportPlayerData(ctx context.Context, playerName string) (*store.PlayerData, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validatePlayerName(playerName); err != nil {
+		return nil, err
+	}
+
+	data, err := s.store.ExportPlayerData(ctx, playerName)
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("player", playerName).Msg("failed to export player data")
+		return nil, fmt.Errorf("export player data: %w", err)
+	}
+	s.loggerFor(ctx).Info().Str("player", playerName).Int("scores", len(data.Scores)).Msg("player data exported")
+	return &data, nil
+}
+
+// ErasePlayerData deletes everything stored about a player on every board, for
+// data subject erasure requests, and records the erasure under the SHA-256 of
+// the name. Unlike DeleteScore nothing can be restored; stream subscribers of
+// the boards the player was on receive a fresh snapshot. Erasing a player the
+// server holds nothing about is recorded as well. Admin only.
+func (s *Service) ErasePlayerData(ctx context.Context, playerName string) (*store.ErasureResult, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validatePlayerName(playerName); err != nil {
+		return nil, err
+	}
+
+	// Logs name the erasure by hash: the player name must not outlive it there either
+	hash := store.PlayerHash(playerName)
+	res, err := s.store.ErasePlayerData(ctx, playerName, requestctx.FromContext(ctx).RequestID)
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("player_hash", hash).Msg("failed to erase player data")
+		return nil, fmt.Errorf("erase player data: %w", err)
+	}
+
+	// Other servers reload on the resync notifications; this one need not wait for them
+	for _, board := range res.Boards {
+		if cache := s.top.lookup(board); cache != nil {
+			cache.invalidate()
+		}
+	}
+	metrics.PlayerErasures.Inc()
+	s.loggerFor(ctx).Warn().
+		Str("player_hash", hash).
+		Int64("erasure_id", res.Erasure.ID).
+		Int64("deleted_scores", res.Erasure.DeletedScores).
+		Int64("deleted_rows", res.Erasure.DeletedRows).
+		Strs("leaderboards", res.Boards).
+		Msg("🧹 player data erased")
+	return &res, nil
+}
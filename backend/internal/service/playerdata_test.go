@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestErasePlayerData(t *testing.T) {
+	ctx := requestctx.NewContext(context.Background(), requestctx.Info{RequestID: "req-1"})
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{Admin: Admin{Token: "s3cret"}})
+
+	for _, sub := range []ScoreSubmission{{PlayerName: "Alice", Score: 300}, {PlayerName: "Bob", Score: 200}, {LeaderboardID: "level-1", PlayerName: "Alice", Score: 10}} {
+		if _, err := svc.SubmitScore(ctx, sub); err != nil {
+			t.Fatalf("seed score: %v", err)
+		}
+	}
+	// Cached by the read, to be invalidated by the erasure
+	if top, err := svc.GetTopScores(ctx, "", 10, 0); err != nil || len(top) != 2 {
+		t.Fatalf("top before erasure = %v, %v", top, err)
+	}
+
+	if _, err := svc.ExportPlayerData(ctx, "Alice"); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated export: error = %v, want ErrAdminUnauthorized", err)
+	}
+	if _, err := svc.ErasePlayerData(ctx, "Alice"); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated erasure: error = %v, want ErrAdminUnauthorized", err)
+	}
+	ctx, _ = svc.AuthenticateAdmin(ctx, "s3cret")
+	if _, err := svc.ErasePlayerData(ctx, ""); !errors.Is(err, ErrInvalidPlayerName) {
+		t.Errorf("erasure without name: error = %v, want ErrInvalidPlayerName", err)
+	}
+
+	data, err := svc.ExportPlayerData(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if len(data.Scores) != 2 {
+		t.Errorf("export holds %d scores, want Alice's 2", len(data.Scores))
+	}
+
+	res, err := svc.ErasePlayerData(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("erasure: %v", err)
+	}
+	if res.Erasure.DeletedScores != 2 || res.Erasure.RequestID != "req-1" || len(res.Boards) != 2 {
+		t.Errorf("erasure = %+v, want 2 scores on 2 boards deleted by req-1", res)
+	}
+	if _, err := svc.GetPlayerRank(ctx, "", "Alice"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("rank after erasure: error = %v, want ErrPlayerNotFound", err)
+	}
+	if top, _ := svc.GetTopScores(ctx, "", 10, 0); len(top) != 1 || top[0].PlayerName != "Bob" {
+		t.Errorf("top after erasure = %v, want Bob only", top)
+	}
+
+	// Erasing a player the server knows nothing about is recorded as well
+	if res, err := svc.ErasePlayerData(ctx, "Alice"); err != nil || res.Erasure.DeletedRows != 0 {
+		t.Errorf("second erasure = %+v, %v, want nothing deleted", res, err)
+	}
+	if data, _ := svc.ExportPlayerData(ctx, "Alice"); len(data.Scores) != 0 || len(data.Erasures) != 2 {
+		t.Errorf("export after erasure = %+v, want no score and 2 erasures", data)
+	}
+}
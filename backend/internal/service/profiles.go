@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// ErrInvalidProfile is returned when profile fields fail validation
+var ErrInvalidProfile = errors.New("invalid profile")
+
+const (
+	MaxDisplayNameLength = 32
+	MaxAvatarURLLength   = 512
+)
+
+// ProfileUpdate holds the fields of a profile upsert. Empty fields clear the stored value.
+type ProfileUpdate struct {
+	PlayerName  string
+	DisplayName string
+	CountryCode string // ISO 3166-1 alpha-2, case-insensitive
+	AvatarURL   string // absolute http(s) URL
+}
+
+// UpsertPlayerProfile creates or replaces a player's profile
+func (s *Service) UpsertPlayerProfile(ctx context.Context, update ProfileUpdate) (*store.Player, error) {
+	if err := s.validatePlayerName(update.PlayerName); err != nil {
+		return nil, err
+	}
+	params, err := normalizeProfile(update)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.admit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	profile, err := s.store.UpsertPlayerProfile(ctx, params)
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("player", update.PlayerName).Msg("failed to upsert player profile")
+		return nil, fmt.Errorf("upsert player profile: %w", err)
+	}
+	if s.opts.ProfileCacheTTL > 0 {
+		s.profiles.put(profile.PlayerName, &profile, time.Now())
+	}
+	s.versions.bump("") // entries of every board carry the profile
+
+	s.loggerFor(ctx).Info().Str("player", profile.PlayerName).Msg("player profile updated")
+	return &profile, nil
+}
+
+// GetPlayerProfile returns a player's profile, or ErrPlayerNotFound if none was set
+func (s *Service) GetPlayerProfile(ctx context.Context, playerName string) (*store.Player, error) {
+	if err := s.validatePlayerName(playerName); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.store.GetPlayerProfile(ctx, playerName)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRows) {
+			return nil, ErrPlayerNotFound
+		}
+		s.loggerFor(ctx).Error().Err(err).Str("player", playerName).Msg("failed to get player profile")
+		return nil, fmt.Errorf("get player profile: %w", err)
+	}
+	return &profile, nil
+}
+
+// PlayerProfiles returns the profiles of the given players, keyed by player name,
+// completed with their external identity when an identity resolver is configured.
+// Profiles are decoration: players without one are omitted and lookup errors are
+// logged rather than returned, so leaderboard reads never fail because of them.
+func (s *Service) PlayerProfiles(ctx context.Context, playerNames []string) map[string]store.Player {
+	profiles := s.localProfiles(ctx, playerNames)
+	s.enrichProfiles(ctx, playerNames, profiles)
+	return profiles
+}
+
+// localProfiles returns the profiles stored in the players table
+func (s *Service) localProfiles(ctx context.Context, playerNames []string) map[string]store.Player {
+	now := time.Now()
+	profiles, missing := s.profiles.get(playerNames, now, s.opts.ProfileCacheTTL)
+	if len(missing) == 0 {
+		return profiles
+	}
+
+	rows, err := s.store.GetPlayerProfiles(ctx, missing)
+	if err != nil {
+		s.loggerFor(ctx).Warn().Err(err).Int("players", len(missing)).Msg("failed to get player profiles")
+		return profiles
+	}
+
+	found := make(map[string]*store.Player, len(rows))
+	for i := range rows {
+		found[rows[i].PlayerName] = &rows[i]
+		profiles[rows[i].PlayerName] = rows[i]
+	}
+	if s.opts.ProfileCacheTTL > 0 {
+		s.profiles.prune(now, s.opts.ProfileCacheTTL)
+		for _, name := range missing {
+			s.profiles.put(name, found[name], now)
+		}
+	}
+	return profiles
+}
+
+// normalizeProfile validates a profile update and converts it to store parameters
+func normalizeProfile(update ProfileUpdate) (store.UpsertPlayerProfileParams, error) {
+	params := store.UpsertPlayerProfileParams{
+		PlayerName:  update.PlayerName,
+		DisplayName: strings.TrimSpace(update.DisplayName),
+		CountryCode: strings.ToUpper(strings.TrimSpace(update.CountryCode)),
+		AvatarUrl:   strings.TrimSpace(update.AvatarURL),
+	}
+
+	if !utf8.ValidString(params.DisplayName) || utf8.RuneCountInString(params.DisplayName) > MaxDisplayNameLength {
+		return params, fmt.Errorf("%w: display name must be valid UTF-8 of at most %d characters",
+			ErrInvalidProfile, MaxDisplayNameLength)
+	}
+	if strings.IndexFunc(params.DisplayName, unicode.IsControl) >= 0 {
+		return params, fmt.Errorf("%w: display name must not contain control characters", ErrInvalidProfile)
+	}
+
+	if params.CountryCode != "" {
+		if len(params.CountryCode) != 2 || strings.IndexFunc(params.CountryCode, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0 {
+			return params, fmt.Errorf("%w: country code must be an ISO 3166-1 alpha-2 code", ErrInvalidProfile)
+		}
+	}
+
+	if params.AvatarUrl != "" {
+		if len(params.AvatarUrl) > MaxAvatarURLLength {
+			return params, fmt.Errorf("%w: avatar url must be at most %d characters", ErrInvalidProfile, MaxAvatarURLLength)
+		}
+		u, err := url.Parse(params.AvatarUrl)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return params, fmt.Errorf("%w: avatar url must be an absolute http(s) URL", ErrInvalidProfile)
+		}
+	}
+
+	return params, nil
+}
+
+// profileCache keeps recently read profiles so leaderboard pages served from the
+// in-memory top cache do not query the players table on every request.
+// Players known to have no profile are cached too (nil profile).
+type profileCache struct {
+	mu        sync.Mutex
+	entries   map[string]cachedProfile
+	lastPrune time.Time
+}
+
+type cachedProfile struct {
+	profile   *store.Player
+	fetchedAt time.Time
+}
+
+// get returns the fresh cached profiles among names and the names that must be loaded
+func (c *profileCache) get(names []string, now time.Time, ttl time.Duration) (map[string]store.Player, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	profiles := make(map[string]store.Player, len(names))
+	var missing []string
+	for _, name := range names {
+		e, ok := c.entries[name]
+		if !ok || now.Sub(e.fetchedAt) >= ttl {
+			missing = append(missing, name)
+			continue
+		}
+		if e.profile != nil {
+			profiles[name] = *e.profile
+		}
+	}
+	return profiles, missing
+}
+
+// put caches a player's profile (nil when the player has none)
+func (c *profileCache) put(name string, profile *store.Player, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]cachedProfile)
+	}
+	c.entries[name] = cachedProfile{profile: profile, fetchedAt: now}
+}
+
+// prune removes entries older than ttl, at most once per ttl
+func (c *profileCache) prune(now time.Time, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastPrune) < ttl {
+		return
+	}
+	for name, e := range c.entries {
+		if now.Sub(e.fetchedAt) >= ttl {
+			delete(c.entries, name)
+		}
+	}
+	c.lastPrune = now
+}
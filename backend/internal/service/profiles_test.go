@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestNormalizeProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		update  ProfileUpdate
+		wantErr bool
+	}{
+		{name: "empty profile", update: ProfileUpdate{}},
+		{name: "full profile", update: ProfileUpdate{DisplayName: "Zoë ⭐", CountryCode: "fr", AvatarURL: "https://cdn.example.com/a.png"}},
+		{name: "display name too long", update: ProfileUpdate{DisplayName: strings.Repeat("a", MaxDisplayNameLength+1)}, wantErr: true},
+		{name: "control character", update: ProfileUpdate{DisplayName: "a\nb"}, wantErr: true},
+		{name: "three letter country", update: ProfileUpdate{CountryCode: "FRA"}, wantErr: true},
+		{name: "numeric country", update: ProfileUpdate{CountryCode: "12"}, wantErr: true},
+		{name: "relative avatar", update: ProfileUpdate{AvatarURL: "/a.png"}, wantErr: true},
+		{name: "non-http avatar", update: ProfileUpdate{AvatarURL: "javascript:alert(1)"}, wantErr: true},
+		{name: "avatar too long", update: ProfileUpdate{AvatarURL: "https://example.com/" + strings.Repeat("a", MaxAvatarURLLength)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := normalizeProfile(tt.update)
+			if tt.wantErr && !errors.Is(err, ErrInvalidProfile) || !tt.wantErr && err != nil {
+				t.Errorf("normalizeProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlayerProfiles(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{ProfileCacheTTL: time.Hour})
+
+	// Cache that Alice has no profile, then create one: the upsert refreshes the cache
+	if got := svc.PlayerProfiles(ctx, []string{"Alice", "Bob"}); len(got) != 0 {
+		t.Errorf("profiles before upsert = %+v, want none", got)
+	}
+	if _, err := svc.UpsertPlayerProfile(ctx, ProfileUpdate{PlayerName: "Alice", CountryCode: "fr"}); err != nil {
+		t.Fatalf("upsert profile: %v", err)
+	}
+
+	got := svc.PlayerProfiles(ctx, []string{"Alice", "Bob"})
+	if len(got) != 1 || got["Alice"].CountryCode != "FR" {
+		t.Errorf("profiles = %+v, want Alice with country FR", got)
+	}
+
+	if _, err := svc.GetPlayerProfile(ctx, "Bob"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("GetPlayerProfile(Bob) error = %v, want %v", err, ErrPlayerNotFound)
+	}
+}
+
+type fakeIdentities map[string]Identity
+
+func (f fakeIdentities) Resolve(_ context.Context, names []string) (map[string]Identity, error) {
+	out := make(map[string]Identity)
+	for _, name := range names {
+		if id, ok := f[name]; ok {
+			out[name] = id
+		}
+	}
+	return out, errors.New("partial outage")
+}
+
+func TestPlayerProfilesIdentity(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{Identity: fakeIdentities{
+		"Alice": {DisplayName: "Alice (platform)", AvatarURL: "https://cdn.example.com/alice.png"},
+		"Bob":   {DisplayName: "Bobby"},
+		"Eve":   {AvatarURL: "javascript:alert(1)"},
+	}})
+	if _, err := svc.UpsertPlayerProfile(ctx, ProfileUpdate{PlayerName: "Alice", DisplayName: "Ally", CountryCode: "fr"}); err != nil {
+		t.Fatalf("upsert profile: %v", err)
+	}
+
+	got := svc.PlayerProfiles(ctx, []string{"Alice", "Bob", "Carol", "Eve"})
+	if len(got) != 2 {
+		t.Fatalf("profiles = %+v, want Alice and Bob", got)
+	}
+	// Local fields win, the identity fills the rest
+	if a := got["Alice"]; a.DisplayName != "Ally" || a.AvatarUrl != "https://cdn.example.com/alice.png" || a.CountryCode != "FR" {
+		t.Errorf("Alice = %+v, want local name and country with platform avatar", a)
+	}
+	if b := got["Bob"]; b.DisplayName != "Bobby" || b.CreatedAt.Valid {
+		t.Errorf("Bob = %+v, want platform name and no stored timestamps", b)
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// Ranking variants of rank reads
+const (
+	RankingControl = "control" // rank score order, what every board uses
+	RankingRecency = "recency" // rank score halved every RankingExperiment.HalfLife since the score was achieved
+)
+
+// DefaultRecencyHalfLife is the half-life of the recency variant when none is configured
+const DefaultRecencyHalfLife = 7 * 24 * time.Hour
+
+// Rank read methods, as labeled in metrics
+const (
+	rankReadTopScores   = "top_scores"
+	rankReadPlayerRank  = "player_rank"
+	rankReadPlayerRanks = "player_ranks"
+)
+
+// RankingExperiment serves a share of rank reads (top pages and player ranks) with an
+// alternative ranking, so a formula change can be evaluated on part of the traffic
+// before switching everyone. Reads are assigned at random; the pages following a
+// page of the experiment stay in its variant.
+type RankingExperiment struct {
+	Variant  string        // alternative ranking (RankingRecency); empty disables the experiment
+	Percent  float64       // share of rank reads served by Variant, from 0 to 100
+	HalfLife time.Duration // of the recency weight (DefaultRecencyHalfLife when 0)
+}
+
+// pickRankingVariant assigns a rank read to the experiment's variant or to control
+func (s *Service) pickRankingVariant() string {
+	exp := s.opts.RankingExperiment
+	if exp.Variant == "" || exp.Percent <= 0 {
+		return RankingControl
+	}
+	if rand.Float64()*100 < exp.Percent {
+		return exp.Variant
+	}
+	return RankingControl
+}
+
+// observeRankRead records a rank read served by a variant
+func observeRankRead(method, variant string, start time.Time) {
+	metrics.RankReads.WithLabelValues(method, variant).Inc()
+	metrics.RankReadDuration.WithLabelValues(method, variant).Observe(time.Since(start).Seconds())
+}
+
+// recencyParams returns the current time and half-life of the recency weight, in seconds
+func (s *Service) recencyParams() (nowUnix, halfLife float64) {
+	h := s.opts.RankingExperiment.HalfLife
+	if h <= 0 {
+		h = DefaultRecencyHalfLife
+	}
+	return float64(time.Now().UnixMicro()) / 1e6, h.Seconds()
+}
+
+// getTopScoresRanked retrieves a page of a board in the order of a ranking variant
+func (s *Service) getTopScoresRanked(ctx context.Context, board, variant string, limit, offset int32) ([]store.Score, error) {
+	if variant == RankingControl {
+		return s.GetTopScores(ctx, board, limit, offset)
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidLimit)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset must be non-negative", ErrInvalidLimit)
+	}
+
+	now, halfLife := s.recencyParams()
+	scores, err := s.store.GetTopScoresRecencyWeighted(ctx, store.GetTopScoresRecencyWeightedParams{
+		LeaderboardID:   board,
+		NowUnix:         now,
+		HalfLifeSeconds: halfLife,
+		PageSize:        limit,
+		PageOffset:      offset,
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Str("variant", variant).Msg("failed to get ranked top scores")
+		return nil, fmt.Errorf("get %s top scores: %w", variant, err)
+	}
+	return scores, nil
+}
+
+// getPlayerRankRanked calculates the rank of a player known to be on a board, in the
+// order of a ranking variant
+func (s *Service) getPlayerRankRanked(ctx context.Context, board, playerName, variant string) (int64, error) {
+	var (
+		rank int64
+		err  error
+	)
+	if variant == RankingControl {
+		var r int32
+		r, err = s.store.GetPlayerRank(ctx, store.GetPlayerRankParams{
+			LeaderboardID: board,
+			PlayerName:    playerName,
+		})
+		rank = int64(r)
+	} else {
+		now, halfLife := s.recencyParams()
+		rank, err = s.store.GetPlayerRankRecencyWeighted(ctx, store.GetPlayerRankRecencyWeightedParams{
+			LeaderboardID:   board,
+			PlayerName:      playerName,
+			NowUnix:         now,
+			HalfLifeSeconds: halfLife,
+		})
+	}
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("player", playerName).Str("variant", variant).Msg("failed to get player rank")
+		return 0, fmt.Errorf("get player rank: %w", err)
+	}
+	return rank, nil
+}
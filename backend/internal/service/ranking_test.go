@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestRankingExperiment(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	control := New(st, &logger, Options{})
+	experiment := New(st, &logger, Options{RankingExperiment: RankingExperiment{Variant: RankingRecency, Percent: 100}})
+
+	// Four half-lives old: Old's 1000 weighs about 60, and its 50s lap about 800s
+	monthAgo := time.Now().Add(-28 * 24 * time.Hour)
+	if _, err := control.UpsertLeaderboard(ctx, "lap-1", SortAscending, SortDescending); err != nil {
+		t.Fatalf("UpsertLeaderboard: %v", err)
+	}
+	if _, err := control.ImportScores(ctx, []ScoreImport{
+		{PlayerName: "Old", Score: 1000, AchievedAt: monthAgo},
+		{PlayerName: "New", Score: 600},
+		{PlayerName: "Mid", Score: 400},
+		{PlayerName: "Old", Score: 50, AchievedAt: monthAgo, LeaderboardID: "lap-1"},
+		{PlayerName: "New", Score: 60, LeaderboardID: "lap-1"},
+	}); err != nil {
+		t.Fatalf("ImportScores: %v", err)
+	}
+
+	page, err := control.GetTopScoresPage(ctx, "", 3, 0, "")
+	if err != nil || page.RankingVariant != RankingControl || !slices.Equal(names(page.Scores), []string{"Old", "New", "Mid"}) {
+		t.Fatalf("control page = %+v, %v; want Old, New, Mid", page, err)
+	}
+
+	// Pages following a page of the variant stay in the variant
+	page, err = experiment.GetTopScoresPage(ctx, "", 2, 0, "")
+	if err != nil || page.RankingVariant != RankingRecency || !slices.Equal(names(page.Scores), []string{"New", "Mid"}) {
+		t.Fatalf("recency page = %+v, %v; want New, Mid", page, err)
+	}
+	next, err := control.GetTopScoresPage(ctx, "", 2, 0, page.NextPageToken)
+	if err != nil || next.RankingVariant != RankingRecency || !slices.Equal(names(next.Scores), []string{"Old"}) {
+		t.Fatalf("page after a recency page = %+v, %v; want Old in recency order", next, err)
+	}
+	if _, err := control.GetTopScoresPage(ctx, "lap-1", 2, 0, page.NextPageToken); err == nil {
+		t.Error("recency token accepted for another board")
+	}
+
+	rank, err := experiment.GetPlayerRank(ctx, "", "Old")
+	if err != nil || rank.Rank != 3 || rank.RankingVariant != RankingRecency {
+		t.Errorf("recency rank of Old = %+v, %v; want 3", rank, err)
+	}
+	rank, err = control.GetPlayerRank(ctx, "", "Old")
+	if err != nil || rank.Rank != 1 || rank.RankingVariant != RankingControl {
+		t.Errorf("control rank of Old = %+v, %v; want 1", rank, err)
+	}
+
+	// On ascending boards old times get slower, not faster
+	rank, err = experiment.GetPlayerRank(ctx, "lap-1", "Old")
+	if err != nil || rank.Rank != 2 {
+		t.Errorf("recency rank of Old's lap = %+v, %v; want 2", rank, err)
+	}
+}
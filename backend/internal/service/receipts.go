@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrReceiptsDisabled is returned by VerifyReceipt when no receipt key is configured
+	ErrReceiptsDisabled = errors.New("score receipts are disabled")
+
+	// ErrInvalidReceipt is returned when a receipt to verify is incomplete
+	ErrInvalidReceipt = errors.New("invalid receipt")
+)
+
+// receiptSignatureVersion prefixes the canonical receipt so the format can evolve
+const receiptSignatureVersion = "leaderboard-receipt-v1"
+
+// maxReceiptKeyIDLength bounds key ids, which are copied into every receipt
+const maxReceiptKeyIDLength = 32
+
+// Receipt verification results
+const (
+	ReceiptValid             = "valid"
+	ReceiptUnknownKey        = "unknown_key"        // signed with a key the server no longer (or never) had
+	ReceiptSignatureMismatch = "signature_mismatch" // forged or altered
+)
+
+// ReceiptKey is an HMAC-SHA256 secret receipts are signed or verified with
+type ReceiptKey struct {
+	ID     string // copied into receipts so the verifying key can be found after rotation
+	Secret []byte
+}
+
+// Receipts configures signed score submission receipts
+type Receipts struct {
+	// Keys verify receipts; the first one also signs new receipts. Keep retired
+	// keys after it for as long as their receipts must remain verifiable.
+	// No keys disables receipts.
+	Keys []ReceiptKey
+}
+
+// Receipt is the server's signed acknowledgment of a score submission, which
+// players can store and later present, e.g. to settle a dispute
+type Receipt struct {
+	LeaderboardID string
+	PlayerName    string
+	Score         int64  // score submitted, not the player's best
+	IssuedAt      string // RFC3339 (nanoseconds), signed as is
+	Applied       bool   // whether the score became the player's best
+	KeyID         string
+	Signature     string // hex HMAC-SHA256 of CanonicalReceipt
+}
+
+// ReceiptVerification is the outcome of VerifyReceipt
+type ReceiptVerification struct {
+	Valid      bool
+	Result     string // ReceiptValid, ReceiptUnknownKey or ReceiptSignatureMismatch
+	CurrentKey bool   // the receipt is signed with the current signing key
+}
+
+// ParseReceiptKeys parses a key list such as "2025-06:secretB,2025-01:secretA",
+// current signing key first
+func ParseReceiptKeys(value string) ([]ReceiptKey, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var keys []ReceiptKey
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("invalid receipt key %q: expected key_id:secret", id)
+		}
+		if !validReceiptKeyID(id) {
+			return nil, fmt.Errorf("invalid receipt key id %q: use at most %d letters, digits, '-', '_' or '.'", id, maxReceiptKeyIDLength)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate receipt key id %q", id)
+		}
+		seen[id] = true
+		keys = append(keys, ReceiptKey{ID: id, Secret: []byte(secret)})
+	}
+	return keys, nil
+}
+
+// validReceiptKeyID reports whether id is a short token of letters, digits, '-', '_' or '.'
+func validReceiptKeyID(id string) bool {
+	if len(id) > maxReceiptKeyIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// CanonicalReceipt returns the bytes a receipt signature covers: a version line,
+// then key_id, leaderboard_id, player_name, score, issued_at and applied, each on its own line
+func CanonicalReceipt(r Receipt) []byte {
+	return []byte(strings.Join([]string{
+		receiptSignatureVersion,
+		r.KeyID,
+		r.LeaderboardID,
+		r.PlayerName,
+		strconv.FormatInt(r.Score, 10),
+		r.IssuedAt,
+		strconv.FormatBool(r.Applied),
+	}, "\n") + "\n")
+}
+
+// issueReceipt signs the receipt of a submission with the current key, or returns
+// nil when receipts are disabled
+func (s *Service) issueReceipt(sub ScoreSubmission, applied bool, now time.Time) *Receipt {
+	keys := s.opts.Receipts.Keys
+	if len(keys) == 0 {
+		return nil
+	}
+	r := &Receipt{
+		LeaderboardID: sub.LeaderboardID,
+		PlayerName:    sub.PlayerName,
+		Score:         sub.Score,
+		IssuedAt:      now.UTC().Format(time.RFC3339Nano),
+		Applied:       applied,
+		KeyID:         keys[0].ID,
+	}
+	r.Signature = signHMAC(keys[0].Secret, CanonicalReceipt(*r))
+	return r
+}
+
+// VerifyReceipt checks a receipt against the current and retired receipt keys
+func (s *Service) VerifyReceipt(r Receipt) (*ReceiptVerification, error) {
+	keys := s.opts.Receipts.Keys
+	if len(keys) == 0 {
+		return nil, ErrReceiptsDisabled
+	}
+	if r.KeyID == "" || r.Signature == "" || r.PlayerName == "" || r.IssuedAt == "" {
+		return nil, fmt.Errorf("%w: player_name, issued_at, key_id and signature are required", ErrInvalidReceipt)
+	}
+	board, err := ResolveLeaderboardID(r.LeaderboardID)
+	if err != nil {
+		return nil, err
+	}
+	r.LeaderboardID = board
+
+	for i, key := range keys {
+		if key.ID != r.KeyID {
+			continue
+		}
+		if !verifyHMAC(key.Secret, CanonicalReceipt(r), r.Signature) {
+			return &ReceiptVerification{Result: ReceiptSignatureMismatch}, nil
+		}
+		return &ReceiptVerification{Valid: true, Result: ReceiptValid, CurrentKey: i == 0}, nil
+	}
+	return &ReceiptVerification{Result: ReceiptUnknownKey}, nil
+}
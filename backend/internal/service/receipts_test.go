@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func newReceiptTestService(t *testing.T, keys ...ReceiptKey) *Service {
+	t.Helper()
+	st, err := sqlite.Open(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(st.Close)
+
+	logger := zerolog.Nop()
+	return New(st, &logger, Options{Receipts: Receipts{Keys: keys}})
+}
+
+func TestParseReceiptKeys(t *testing.T) {
+	keys, err := ParseReceiptKeys(" 2025-06:secretB , 2025-01:secretA")
+	if err != nil {
+		t.Fatalf("ParseReceiptKeys() error = %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != "2025-06" || string(keys[0].Secret) != "secretB" || keys[1].ID != "2025-01" {
+		t.Errorf("ParseReceiptKeys() = %+v", keys)
+	}
+	if keys, err := ParseReceiptKeys(""); err != nil || keys != nil {
+		t.Errorf("ParseReceiptKeys(\"\") = %v, %v, want no keys", keys, err)
+	}
+
+	for _, value := range []string{"nosecret", ":secret", "k1:", "k 1:secret", "k1:a,k1:b", "this-key-id-is-far-too-long-to-be-accepted:s"} {
+		if _, err := ParseReceiptKeys(value); err == nil {
+			t.Errorf("ParseReceiptKeys(%q) accepted", value)
+		}
+	}
+}
+
+func TestReceipts(t *testing.T) {
+	ctx := context.Background()
+	current := ReceiptKey{ID: "k2", Secret: []byte("new-secret")}
+	retired := ReceiptKey{ID: "k1", Secret: []byte("old-secret")}
+
+	// Receipt signed before rotation, when k1 was the signing key
+	before := newReceiptTestService(t, retired)
+	res, err := before.SubmitScore(ctx, ScoreSubmission{PlayerName: "Alice", Score: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := *res.Receipt
+
+	svc := newReceiptTestService(t, current, retired)
+	res, err = svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Alice", Score: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := *res.Receipt
+	if fresh.KeyID != "k2" || fresh.LeaderboardID != DefaultLeaderboardID || fresh.Score != 200 || !fresh.Applied || fresh.Signature == "" {
+		t.Errorf("receipt = %+v", fresh)
+	}
+
+	tampered := fresh
+	tampered.Score = 1_000_000
+	unknown := fresh
+	unknown.KeyID = "k0"
+
+	tests := []struct {
+		name        string
+		receipt     Receipt
+		wantResult  string
+		wantCurrent bool
+	}{
+		{name: "current key", receipt: fresh, wantResult: ReceiptValid, wantCurrent: true},
+		{name: "retired key", receipt: old, wantResult: ReceiptValid},
+		{name: "tampered score", receipt: tampered, wantResult: ReceiptSignatureMismatch},
+		{name: "unknown key", receipt: unknown, wantResult: ReceiptUnknownKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.VerifyReceipt(tt.receipt)
+			if err != nil {
+				t.Fatalf("VerifyReceipt() error = %v", err)
+			}
+			if got.Result != tt.wantResult || got.Valid != (tt.wantResult == ReceiptValid) || got.CurrentKey != tt.wantCurrent {
+				t.Errorf("VerifyReceipt() = %+v, want result %s, current key %v", got, tt.wantResult, tt.wantCurrent)
+			}
+		})
+	}
+
+	if _, err := svc.VerifyReceipt(Receipt{PlayerName: "Alice"}); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("incomplete receipt error = %v, want %v", err, ErrInvalidReceipt)
+	}
+}
+
+func TestReceiptsDisabled(t *testing.T) {
+	svc := newReceiptTestService(t)
+	res, err := svc.SubmitScore(context.Background(), ScoreSubmission{PlayerName: "Alice", Score: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Receipt != nil {
+		t.Errorf("receipt = %+v, want none", res.Receipt)
+	}
+	if _, err := svc.VerifyReceipt(Receipt{PlayerName: "Alice", IssuedAt: "x", KeyID: "k1", Signature: "00"}); !errors.Is(err, ErrReceiptsDisabled) {
+		t.Errorf("VerifyReceipt() error = %v, want %v", err, ErrReceiptsDisabled)
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/yourorg/leaderboard/internal/requestctx"
+)
+
+// ErrInvalidCountry is returned when a submitted country or a requested region
+// is not an ISO 3166-1 alpha-2 code
+var ErrInvalidCountry = errors.New("invalid country code")
+
+// GeoResolver resolves the country of client addresses, e.g. from a GeoIP
+// database. Country runs on every submission without an explicit country, so it
+// must answer from memory.
+type GeoResolver interface {
+	// Country returns the ISO 3166-1 alpha-2 code of the country of addr, or ""
+	// when unknown
+	Country(addr netip.Addr) string
+}
+
+// normalizeCountry uppercases a country code and checks that it is ISO 3166-1
+// alpha-2 shaped; "" stays "" (unknown)
+func normalizeCountry(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return "", nil
+	}
+	if len(code) != 2 || strings.IndexFunc(code, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0 {
+		return "", fmt.Errorf("%w: %q is not an ISO 3166-1 alpha-2 code", ErrInvalidCountry, code)
+	}
+	return code, nil
+}
+
+// submissionCountry returns the country a score is tagged with: the country of
+// the submission when the client sent one, else the country of the client
+// address when a GeoResolver is configured, else "" (unknown)
+func (s *Service) submissionCountry(ctx context.Context, country string) (string, error) {
+	country, err := normalizeCountry(country)
+	if err != nil || country != "" {
+		return country, err
+	}
+	return s.clientCountry(ctx), nil
+}
+
+// clientCountry resolves the country of the client address of the request in ctx
+func (s *Service) clientCountry(ctx context.Context) string {
+	if s.opts.Geo == nil {
+		return ""
+	}
+	country, err := normalizeCountry(s.opts.Geo.Country(requestctx.FromContext(ctx).ClientIP))
+	if err != nil {
+		s.loggerFor(ctx).Debug().Err(err).Msg("ignoring invalid resolved country")
+		return ""
+	}
+	return country
+}
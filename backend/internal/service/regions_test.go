@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+// fakeGeo resolves every address of 203.0.113.0/24 to its country
+type fakeGeo struct{ country string }
+
+func (g fakeGeo) Country(addr netip.Addr) string {
+	if netip.MustParsePrefix("203.0.113.0/24").Contains(addr) {
+		return g.country
+	}
+	return ""
+}
+
+func TestSubmissionCountry(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{Geo: fakeGeo{country: "de"}})
+	fromGermany := requestctx.NewContext(ctx, requestctx.Info{ClientIP: netip.MustParseAddr("203.0.113.7")})
+	fromElsewhere := requestctx.NewContext(ctx, requestctx.Info{ClientIP: netip.MustParseAddr("198.51.100.1")})
+
+	for _, tt := range []struct {
+		ctx     context.Context
+		player  string
+		country string
+		want    string
+	}{
+		{fromGermany, "Explicit", " fr ", "FR"},
+		{fromGermany, "Resolved", "", "DE"},
+		{fromElsewhere, "Unknown", "", ""},
+	} {
+		result, err := svc.SubmitScore(tt.ctx, ScoreSubmission{PlayerName: tt.player, Score: 100, Country: tt.country})
+		if err != nil {
+			t.Fatalf("%s: submit: %v", tt.player, err)
+		}
+		if result.Country != tt.want {
+			t.Errorf("%s: country = %q, want %q", tt.player, result.Country, tt.want)
+		}
+	}
+
+	for _, country := range []string{"FRA", "F", "F1", "é"} {
+		if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Bad", Score: 100, Country: country}); !errors.Is(err, ErrInvalidCountry) {
+			t.Errorf("country %q: error = %v, want %v", country, err, ErrInvalidCountry)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// ErrPlayerExists is returned when renaming a player to a name that already has
+// scores or a profile without asking for a merge
+var ErrPlayerExists = errors.New("player name already in use")
+
+// RenamePlayer moves a player's scores on every board, profile and devices to a
+// new name in one transaction. If the new name is in use the rename fails with
+// ErrPlayerExists unless merge is true: each board then keeps the better of the
+// two scores and the new name keeps its profile. Stream subscribers see the old
+// name deleted and the new one upserted. ErrPlayerNotFound when the player has
+// neither a score nor a profile. Admin only.
+func (s *Service) RenamePlayer(ctx context.Context, playerName, newPlayerName string, merge bool) (*store.RenameResult, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validatePlayerName(playerName); err != nil {
+		return nil, err
+	}
+	if err := s.validatePlayerName(newPlayerName); err != nil {
+		return nil, err
+	}
+	if newPlayerName == playerName {
+		return nil, fmt.Errorf("%w: the new name is the current name", ErrInvalidPlayerName)
+	}
+
+	res, err := s.store.RenamePlayer(ctx, playerName, newPlayerName, merge)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNoRows):
+			return nil, ErrPlayerNotFound
+		case errors.Is(err, store.ErrPlayerExists):
+			return nil, fmt.Errorf("%w: %s has scores or a profile, merge them to rename", ErrPlayerExists, newPlayerName)
+		}
+		s.loggerFor(ctx).Error().Err(err).Str("player", playerName).Msg("failed to rename player")
+		return nil, fmt.Errorf("rename player: %w", err)
+	}
+
+	// Scores reach the top caches through the change feed; profiles are not on it
+	if s.opts.ProfileCacheTTL > 0 {
+		now := time.Now()
+		s.profiles.put(playerName, nil, now)
+		s.profiles.put(newPlayerName, res.Profile, now)
+	}
+	metrics.PlayerRenames.WithLabelValues(strconv.FormatBool(len(res.Merged) > 0)).Inc()
+	s.loggerFor(ctx).Warn().
+		Str("player", playerName).
+		Str("new_player", newPlayerName).
+		Int("scores", len(res.Scores)).
+		Strs("merged_leaderboards", res.Merged).
+		Msg("player renamed")
+	return &res, nil
+}
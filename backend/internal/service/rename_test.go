@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestRenamePlayer(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{Admin: Admin{Token: "s3cret"}, ProfileCacheTTL: time.Minute})
+
+	for _, sub := range []ScoreSubmission{{PlayerName: "Alice", Score: 300}, {PlayerName: "Bob", Score: 200}} {
+		if _, err := svc.SubmitScore(ctx, sub); err != nil {
+			t.Fatalf("seed score: %v", err)
+		}
+	}
+	if _, err := svc.UpsertPlayerProfile(ctx, ProfileUpdate{PlayerName: "Alice", DisplayName: "Ally"}); err != nil {
+		t.Fatal(err)
+	}
+	// Cached, to be replaced by the rename
+	svc.PlayerProfiles(ctx, []string{"Alice", "Carol"})
+
+	if _, err := svc.RenamePlayer(ctx, "Alice", "Carol", false); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated rename: error = %v, want ErrAdminUnauthorized", err)
+	}
+	ctx, _ = svc.AuthenticateAdmin(ctx, "s3cret")
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     error
+	}{
+		{"same name", "Alice", "Alice", ErrInvalidPlayerName},
+		{"invalid new name", "Alice", "", ErrInvalidPlayerName},
+		{"unknown player", "Dave", "Carol", ErrPlayerNotFound},
+		{"name in use", "Alice", "Bob", ErrPlayerExists},
+	}
+	for _, tt := range tests {
+		if _, err := svc.RenamePlayer(ctx, tt.from, tt.to, false); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	res, err := svc.RenamePlayer(ctx, "Alice", "Carol", false)
+	if err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if len(res.Scores) != 1 || res.Scores[0].PlayerName != "Carol" || res.Scores[0].Score != 300 {
+		t.Errorf("rename = %+v, want Carol with Alice's 300", res)
+	}
+	if rank, err := svc.GetPlayerRank(ctx, "", "Carol"); err != nil || rank.Rank != 1 {
+		t.Errorf("Carol's rank = %v, %v, want 1", rank, err)
+	}
+	if _, err := svc.GetPlayerRank(ctx, "", "Alice"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("Alice's rank after the rename: error = %v, want ErrPlayerNotFound", err)
+	}
+	profiles := svc.PlayerProfiles(ctx, []string{"Alice", "Carol"})
+	if _, ok := profiles["Alice"]; ok || profiles["Carol"].DisplayName != "Ally" {
+		t.Errorf("profiles after the rename = %v, want Ally's profile on Carol only", profiles)
+	}
+
+	// Bob merges into Carol, who keeps her better score
+	res, err = svc.RenamePlayer(ctx, "Bob", "Carol", true)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if len(res.Merged) != 1 || res.Scores[0].Score != 300 {
+		t.Errorf("merge = %+v, want Carol's 300 kept", res)
+	}
+	if top, _ := svc.GetTopScores(ctx, "", 10, 0); len(top) != 1 || top[0].PlayerName != "Carol" {
+		t.Errorf("top after the merge = %v, want Carol only", top)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/notify"
+)
+
+var (
+	// ErrReplayUnavailable is returned by ReplayEvents when the storage backend keeps no outbox
+	ErrReplayUnavailable = errors.New("event replay is not available with this storage backend")
+
+	// ErrInvalidReplayRange is returned for an empty, reversed or oversized replay range
+	ErrInvalidReplayRange = errors.New("invalid replay range")
+)
+
+// MaxReplayEvents is the widest id range a single replay may cover
+const MaxReplayEvents = 100_000
+
+// EventReplayer re-dispatches a range of the score_changes outbox (notify.Replayer)
+type EventReplayer interface {
+	Replay(ctx context.Context, fromID, toID int64, dryRun bool) (*notify.ReplayResult, error)
+}
+
+// ReplayEvents re-dispatches the outbox changes with ids in [fromID, toID] to the
+// consumers of score changes (stream hub, caches, broadcast bus), then resyncs the
+// boards they belong to. With dryRun nothing is dispatched. Admin only.
+func (s *Service) ReplayEvents(ctx context.Context, fromID, toID int64, dryRun bool) (*notify.ReplayResult, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if fromID < 1 || toID < fromID {
+		return nil, fmt.Errorf("%w: from must be at least 1 and to at least from", ErrInvalidReplayRange)
+	}
+	if toID-fromID >= MaxReplayEvents {
+		return nil, fmt.Errorf("%w: at most %d events per replay", ErrInvalidReplayRange, MaxReplayEvents)
+	}
+	if s.opts.Replayer == nil {
+		return nil, ErrReplayUnavailable
+	}
+
+	res, err := s.opts.Replayer.Replay(ctx, fromID, toID, dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("replay events: %w", err)
+	}
+	if !dryRun {
+		metrics.EventsReplayed.Add(float64(res.Events))
+	}
+	s.loggerFor(ctx).Warn().
+		Int64("from_id", res.FromID).
+		Int64("to_id", res.ToID).
+		Int("events", res.Events).
+		Bool("dry_run", dryRun).
+		Msg("⏪ admin replayed outbox events")
+	return res, nil
+}
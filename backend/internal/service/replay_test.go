@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/notify"
+)
+
+// recordingReplayer records the ranges it is asked to replay
+type recordingReplayer struct {
+	calls [][2]int64
+}
+
+func (r *recordingReplayer) Replay(ctx context.Context, fromID, toID int64, dryRun bool) (*notify.ReplayResult, error) {
+	r.calls = append(r.calls, [2]int64{fromID, toID})
+	return &notify.ReplayResult{FromID: fromID, ToID: toID, DryRun: dryRun}, nil
+}
+
+func TestReplayEvents(t *testing.T) {
+	logger := zerolog.Nop()
+	replayer := &recordingReplayer{}
+	svc := New(nil, &logger, Options{Admin: Admin{Token: "s3cret"}, Replayer: replayer})
+	ctx := context.Background()
+
+	if _, err := svc.ReplayEvents(ctx, 1, 10, true); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated replay: error = %v, want ErrAdminUnauthorized", err)
+	}
+	ctx, _ = svc.AuthenticateAdmin(ctx, "s3cret")
+
+	for _, r := range [][2]int64{{0, 10}, {10, 9}, {1, MaxReplayEvents + 1}} {
+		if _, err := svc.ReplayEvents(ctx, r[0], r[1], false); !errors.Is(err, ErrInvalidReplayRange) {
+			t.Errorf("range %v: error = %v, want ErrInvalidReplayRange", r, err)
+		}
+	}
+	if _, err := svc.ReplayEvents(ctx, 5, 5, false); err != nil {
+		t.Fatalf("single event: %v", err)
+	}
+	if len(replayer.calls) != 1 || replayer.calls[0] != [2]int64{5, 5} {
+		t.Errorf("replayed ranges = %v, want [[5 5]]", replayer.calls)
+	}
+
+	noOutbox := New(nil, &logger, Options{Admin: Admin{Token: "s3cret"}})
+	if _, err := noOutbox.ReplayEvents(ctx, 1, 10, true); !errors.Is(err, ErrReplayUnavailable) {
+		t.Errorf("without an outbox: error = %v, want ErrReplayUnavailable", err)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+// ErrInvalidConfirmation is returned when a reset confirmation token is malformed,
+// for another board, or expired
+var ErrInvalidConfirmation = errors.New("invalid confirmation token")
+
+// resetConfirmVersion prefixes the signed content of reset confirmation tokens
+const resetConfirmVersion = "leaderboard-reset-v1"
+
+// ResetResult is the outcome of ResetLeaderboard. Without a confirmation token
+// nothing is deleted: Completed is false and the result carries the token to confirm with.
+type ResetResult struct {
+	LeaderboardID string
+	Completed     bool
+
+	// Preview (Completed false)
+	Entries           int64     // scores the board holds now
+	ConfirmationToken string    // pass back to ResetLeaderboard to delete them
+	ExpiresAt         time.Time // when the token stops being accepted
+
+	// Outcome (Completed true)
+	Deleted    int64
+	SnapshotID int64 // 0 when no snapshot was requested
+}
+
+// ResetLeaderboard deletes every score of a board. It is a two-step operation:
+// a call without confirmation returns a short-lived confirmation token bound to
+// the board, and a second call with that token performs the reset, copying the
+// board into a snapshot first when snapshot is true. Admin only.
+func (s *Service) ResetLeaderboard(ctx context.Context, leaderboardID string, snapshot bool, confirmation string) (*ResetResult, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	board, err := ResolveLeaderboardID(leaderboardID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	if confirmation == "" {
+		entries, err := s.store.CountScores(ctx, board)
+		if err != nil {
+			return nil, fmt.Errorf("count scores: %w", err)
+		}
+		expiresAt := now.Add(s.adminConfirmTTL()).Truncate(time.Second)
+		return &ResetResult{
+			LeaderboardID:     board,
+			Entries:           entries,
+			ConfirmationToken: s.resetConfirmation(board, expiresAt),
+			ExpiresAt:         expiresAt,
+		}, nil
+	}
+	if err := s.checkResetConfirmation(board, confirmation, now); err != nil {
+		return nil, err
+	}
+
+	res, err := s.store.ResetLeaderboard(ctx, board, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("reset leaderboard: %w", err)
+	}
+
+	// Other servers reload on the resync notification; this one need not wait for it
+	if cache := s.top.lookup(board); cache != nil {
+		cache.invalidate()
+	}
+	metrics.LeaderboardResets.WithLabelValues(strconv.FormatBool(snapshot)).Inc()
+	s.loggerFor(ctx).Warn().
+		Str("leaderboard", board).
+		Int64("deleted", res.Deleted).
+		Int64("snapshot_id", res.SnapshotID).
+		Msg("🧹 leaderboard reset")
+	// A reset ends the season of a board: the next one starts empty
+	s.opts.Events.Publish(lifecycle.SeasonRollover, map[string]string{
+		"leaderboard_id": board,
+		"entries":        strconv.FormatInt(res.Deleted, 10),
+		"snapshot_id":    strconv.FormatInt(res.SnapshotID, 10),
+	})
+
+	return &ResetResult{
+		LeaderboardID: board,
+		Completed:     true,
+		Deleted:       res.Deleted,
+		SnapshotID:    res.SnapshotID,
+	}, nil
+}
+
+func (s *Service) adminConfirmTTL() time.Duration {
+	if s.opts.Admin.ConfirmTTL > 0 {
+		return s.opts.Admin.ConfirmTTL
+	}
+	return DefaultAdminConfirmTTL
+}
+
+// resetConfirmation returns the token confirming a reset of board until expiresAt:
+// the expiry in Unix seconds and an HMAC of the board and expiry keyed by the admin
+// token, so any server of the deployment accepts it
+func (s *Service) resetConfirmation(board string, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + signHMAC([]byte(s.opts.Admin.Token), resetConfirmMessage(board, expiry))
+}
+
+func (s *Service) checkResetConfirmation(board, token string, now time.Time) error {
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("%w: malformed", ErrInvalidConfirmation)
+	}
+	if !verifyHMAC([]byte(s.opts.Admin.Token), resetConfirmMessage(board, expiry), signature) {
+		return fmt.Errorf("%w: not issued for leaderboard %q", ErrInvalidConfirmation, board)
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.After(time.Unix(unix, 0)) {
+		return fmt.Errorf("%w: expired, request a new one", ErrInvalidConfirmation)
+	}
+	return nil
+}
+
+func resetConfirmMessage(board, expiry string) []byte {
+	return []byte(resetConfirmVersion + "\n" + board + "\n" + expiry + "\n")
+}
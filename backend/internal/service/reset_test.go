@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestAuthenticateAdmin(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+
+	disabled := New(nil, &logger, Options{})
+	if _, err := disabled.AuthenticateAdmin(ctx, "anything"); !errors.Is(err, ErrAdminDisabled) {
+		t.Errorf("without ADMIN_TOKEN: error = %v, want ErrAdminDisabled", err)
+	}
+
+	svc := New(nil, &logger, Options{Admin: Admin{Token: "s3cret"}})
+	for _, token := range []string{"", "wrong", "s3cret "} {
+		if _, err := svc.AuthenticateAdmin(ctx, token); !errors.Is(err, ErrAdminUnauthorized) {
+			t.Errorf("token %q: error = %v, want ErrAdminUnauthorized", token, err)
+		}
+	}
+	adminCtx, err := svc.AuthenticateAdmin(ctx, "s3cret")
+	if err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+	if err := svc.requireAdmin(adminCtx); err != nil {
+		t.Errorf("requireAdmin after authentication: %v", err)
+	}
+	if err := svc.requireAdmin(ctx); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Errorf("requireAdmin without authentication: error = %v, want ErrAdminUnauthorized", err)
+	}
+}
+
+func TestResetLeaderboard(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	bus := lifecycle.NewBus(&logger)
+	events := bus.Subscribe()
+	svc := New(st, &logger, Options{Admin: Admin{Token: "s3cret", ConfirmTTL: time.Minute}, Events: bus})
+
+	for _, name := range []string{"Alice", "Bob"} {
+		if _, err := svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: "level-1", PlayerName: name, Score: 100}); err != nil {
+			t.Fatalf("seed score: %v", err)
+		}
+	}
+
+	if _, err := svc.ResetLeaderboard(ctx, "level-1", false, ""); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated reset: error = %v, want ErrAdminUnauthorized", err)
+	}
+	ctx, _ = svc.AuthenticateAdmin(ctx, "s3cret")
+
+	// Without confirmation nothing is deleted
+	preview, err := svc.ResetLeaderboard(ctx, "level-1", true, "")
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if preview.Completed || preview.Entries != 2 || preview.ConfirmationToken == "" {
+		t.Fatalf("preview = %+v, want 2 entries and a token", preview)
+	}
+	if n, _ := st.CountScores(ctx, "level-1"); n != 2 {
+		t.Fatalf("preview deleted scores: %d left", n)
+	}
+
+	// Tokens are bound to their board and expire
+	other, _ := svc.ResetLeaderboard(ctx, "level-2", false, "")
+	for name, token := range map[string]string{
+		"malformed":   "nope",
+		"other board": other.ConfirmationToken,
+		"expired":     svc.resetConfirmation("level-1", time.Now().Add(-time.Second)),
+	} {
+		if _, err := svc.ResetLeaderboard(ctx, "level-1", true, token); !errors.Is(err, ErrInvalidConfirmation) {
+			t.Errorf("%s token: error = %v, want ErrInvalidConfirmation", name, err)
+		}
+	}
+
+	res, err := svc.ResetLeaderboard(ctx, "level-1", true, preview.ConfirmationToken)
+	if err != nil {
+		t.Fatalf("confirmed reset: %v", err)
+	}
+	if !res.Completed || res.Deleted != 2 || res.SnapshotID == 0 {
+		t.Errorf("reset = %+v, want 2 deleted and a snapshot", res)
+	}
+	if n, _ := st.CountScores(ctx, "level-1"); n != 0 {
+		t.Errorf("level-1 holds %d scores after reset, want 0", n)
+	}
+
+	// Only the completed reset starts a season
+	bus.Close()
+	var got []lifecycle.Event
+	for event := range events {
+		got = append(got, event)
+	}
+	if len(got) != 1 || got[0].Type != lifecycle.SeasonRollover || got[0].Attributes["leaderboard_id"] != "level-1" || got[0].Attributes["entries"] != "2" {
+		t.Errorf("events = %+v, want one season rollover of level-1", got)
+	}
+}
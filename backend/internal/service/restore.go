@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+var (
+	// ErrInvalidRestore is returned when the entries of a restore fail validation
+	ErrInvalidRestore = errors.New("invalid restore")
+
+	// ErrSnapshotNotFound is returned when restoring from a snapshot that does not exist
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+)
+
+// DefaultRestoreMaxEntries is the maximum number of entries of a restore when none is configured
+const DefaultRestoreMaxEntries = 1_000_000
+
+// restoreConfirmVersion prefixes the signed content of restore confirmation tokens
+const restoreConfirmVersion = "leaderboard-restore-v1"
+
+// restoreSampleSize is the number of changed players listed in a restore diff
+const restoreSampleSize = 50
+
+// RestoreEntry is one score of the state a board is restored to, e.g. a row of
+// an export (GET /scores/export)
+type RestoreEntry struct {
+	LeaderboardID  string // board of the exported row; empty, or the restored board
+	PlayerName     string
+	Score          int64
+	SecondaryScore int64
+	AchievedAt     time.Time
+	UpdatedAt      time.Time // AchievedAt when zero
+}
+
+// RestoreSource is the state a board is restored to: an export, or a snapshot
+// taken by a previous reset or restore (SnapshotID set)
+type RestoreSource struct {
+	Entries    []RestoreEntry
+	SnapshotID int64
+}
+
+// RestoreChange is the score of a player before and after a restore
+type RestoreChange struct {
+	PlayerName string
+	Before     *int64 // nil when the player is added by the restore
+	After      *int64 // nil when the player is removed by the restore
+}
+
+// RestoreDiff compares a board with the state it would be restored to
+type RestoreDiff struct {
+	Added     int64 // players only in the restored state
+	Removed   int64 // players only on the board
+	Changed   int64 // players with another score
+	Unchanged int64
+	Samples   []RestoreChange // first changes by player name, at most restoreSampleSize
+}
+
+// RestoreResult is the outcome of RestoreLeaderboard. Without a confirmation token
+// nothing is written: Completed is false and the result carries the diff and the
+// token to confirm with.
+type RestoreResult struct {
+	LeaderboardID string
+	Completed     bool
+	Entries       int64 // entries of the restored state
+	Diff          RestoreDiff
+
+	// Preview (Completed false)
+	ConfirmationToken string    // pass back to RestoreLeaderboard with the same source
+	ExpiresAt         time.Time // when the token stops being accepted
+
+	// Outcome (Completed true)
+	Deleted    int64
+	Restored   int64
+	SnapshotID int64 // snapshot of the replaced scores, 0 when none was requested
+}
+
+// RestoreLeaderboard replaces every score of a board with a previously exported or
+// snapshotted state, e.g. to recover from corrupted scores. Unlike an import, entries
+// are written as they are, without best-score logic, and players missing from the
+// source are removed.
+//
+// Like ResetLeaderboard it takes two steps: a call without confirmation returns the
+// diff between the board and the source, and a token bound to the board and to the
+// exact source; a second call with the same source and that token replaces the board
+// in a single transaction, copying the current scores into a snapshot first when
+// snapshot is true. Stream subscribers of the board then receive a fresh snapshot.
+// Admin only.
+func (s *Service) RestoreLeaderboard(ctx context.Context, leaderboardID string, src RestoreSource, snapshot bool, confirmation string) (*RestoreResult, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	board, err := ResolveLeaderboardID(leaderboardID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.restoreEntries(ctx, board, src)
+	if err != nil {
+		return nil, err
+	}
+	digest := restoreDigest(board, entries)
+	now := time.Now()
+
+	if confirmation != "" {
+		if err := s.checkRestoreConfirmation(board, digest, confirmation, now); err != nil {
+			return nil, err
+		}
+	}
+	diff, err := s.restoreDiff(ctx, board, entries)
+	if err != nil {
+		return nil, err
+	}
+	result := &RestoreResult{LeaderboardID: board, Entries: int64(len(entries)), Diff: diff}
+
+	if confirmation == "" {
+		result.ExpiresAt = now.Add(s.adminConfirmTTL()).Truncate(time.Second)
+		result.ConfirmationToken = s.restoreConfirmation(board, digest, result.ExpiresAt)
+		return result, nil
+	}
+
+	release, err := s.admit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	res, err := s.store.RestoreLeaderboard(ctx, board, entries, snapshot)
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to restore leaderboard")
+		return nil, fmt.Errorf("restore leaderboard: %w", err)
+	}
+
+	// Other servers reload on the resync notification; this one need not wait for it
+	if cache := s.top.lookup(board); cache != nil {
+		cache.invalidate()
+	}
+	metrics.LeaderboardRestores.WithLabelValues(restoreSourceLabel(src)).Inc()
+	s.loggerFor(ctx).Warn().
+		Str("leaderboard", board).
+		Int64("source_snapshot_id", src.SnapshotID).
+		Int64("deleted", res.Deleted).
+		Int64("restored", res.Restored).
+		Int64("added", diff.Added).
+		Int64("removed", diff.Removed).
+		Int64("changed", diff.Changed).
+		Int64("snapshot_id", res.SnapshotID).
+		Msg("♻️ leaderboard restored")
+	s.opts.Events.Publish(lifecycle.LeaderboardRestored, map[string]string{
+		"leaderboard_id": board,
+		"entries":        strconv.FormatInt(res.Restored, 10),
+		"snapshot_id":    strconv.FormatInt(res.SnapshotID, 10),
+	})
+
+	result.Completed = true
+	result.Deleted, result.Restored, result.SnapshotID = res.Deleted, res.Restored, res.SnapshotID
+	return result, nil
+}
+
+// restoreEntries loads and validates the entries of a restore source, sorted by player name
+func (s *Service) restoreEntries(ctx context.Context, board string, src RestoreSource) ([]store.RestoreEntry, error) {
+	var entries []store.RestoreEntry
+	if src.SnapshotID != 0 {
+		if len(src.Entries) > 0 {
+			return nil, fmt.Errorf("%w: restore from entries or from a snapshot, not both", ErrInvalidRestore)
+		}
+		snap, err := s.store.GetLeaderboardSnapshot(ctx, src.SnapshotID)
+		if errors.Is(err, store.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %d", ErrSnapshotNotFound, src.SnapshotID)
+		} else if err != nil {
+			return nil, fmt.Errorf("get snapshot: %w", err)
+		}
+		if snap.LeaderboardID != board {
+			return nil, fmt.Errorf("%w: snapshot %d is of leaderboard %q", ErrInvalidRestore, snap.ID, snap.LeaderboardID)
+		}
+		rows, err := s.store.GetSnapshotEntries(ctx, snap.ID)
+		if err != nil {
+			return nil, fmt.Errorf("get snapshot entries: %w", err)
+		}
+		entries = make([]store.RestoreEntry, len(rows))
+		for i, r := range rows {
+			entries[i] = store.RestoreEntry{PlayerName: r.PlayerName, Score: r.Score, SecondaryScore: r.SecondaryScore, CountryCode: r.CountryCode, Platform: r.Platform, AchievedAt: r.AchievedAt.Time, UpdatedAt: r.UpdatedAt.Time}
+		}
+	} else {
+		entries = make([]store.RestoreEntry, len(src.Entries))
+		for i, e := range src.Entries {
+			entries[i] = store.RestoreEntry{PlayerName: e.PlayerName, Score: e.Score, SecondaryScore: e.SecondaryScore, AchievedAt: e.AchievedAt, UpdatedAt: e.UpdatedAt}
+			if e.LeaderboardID != "" && e.LeaderboardID != board {
+				return nil, fmt.Errorf("%w: entry %d is of leaderboard %q", ErrInvalidRestore, i, e.LeaderboardID)
+			}
+		}
+	}
+
+	if limit := s.restoreMaxEntries(); len(entries) > limit {
+		return nil, fmt.Errorf("%w: %d entries, max %d", ErrImportTooLarge, len(entries), limit)
+	}
+	now := time.Now()
+	for i := range entries {
+		e := &entries[i]
+		if err := s.validatePlayerName(e.PlayerName); err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrInvalidRestore, i, err)
+		}
+		if err := s.validateScore(e.Score); err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrInvalidRestore, i, err)
+		}
+		if e.SecondaryScore < 0 {
+			return nil, fmt.Errorf("%w: entry %d: secondary score must be non-negative", ErrInvalidRestore, i)
+		}
+		if e.AchievedAt.IsZero() || e.AchievedAt.After(now) {
+			return nil, fmt.Errorf("%w: entry %d: achieved_at must be set and not in the future", ErrInvalidRestore, i)
+		}
+		if e.UpdatedAt.IsZero() {
+			e.UpdatedAt = e.AchievedAt
+		}
+		// Timestamps are stored with microsecond precision: digest what will be written
+		e.AchievedAt, e.UpdatedAt = e.AchievedAt.UTC().Truncate(time.Microsecond), e.UpdatedAt.UTC().Truncate(time.Microsecond)
+	}
+
+	slices.SortFunc(entries, func(a, b store.RestoreEntry) int { return strings.Compare(a.PlayerName, b.PlayerName) })
+	for i := 1; i < len(entries); i++ {
+		if entries[i].PlayerName == entries[i-1].PlayerName {
+			return nil, fmt.Errorf("%w: player %q appears more than once", ErrInvalidRestore, entries[i].PlayerName)
+		}
+	}
+	return entries, nil
+}
+
+func (s *Service) restoreMaxEntries() int {
+	if s.opts.Admin.RestoreMaxEntries > 0 {
+		return s.opts.Admin.RestoreMaxEntries
+	}
+	return DefaultRestoreMaxEntries
+}
+
+// restoreDiff compares the current scores of a board with entries sorted by player name
+func (s *Service) restoreDiff(ctx context.Context, board string, entries []store.RestoreEntry) (RestoreDiff, error) {
+	current := make(map[string]int64)
+	if err := s.ExportScores(ctx, board, func(scores []store.Score) error {
+		for _, sc := range scores {
+			current[sc.PlayerName] = sc.Score
+		}
+		return nil
+	}); err != nil {
+		return RestoreDiff{}, err
+	}
+
+	var diff RestoreDiff
+	for _, e := range entries {
+		after := e.Score
+		before, ok := current[e.PlayerName]
+		switch {
+		case !ok:
+			diff.Added++
+			diff.Samples = append(diff.Samples, RestoreChange{PlayerName: e.PlayerName, After: &after})
+		case before != after:
+			diff.Changed++
+			diff.Samples = append(diff.Samples, RestoreChange{PlayerName: e.PlayerName, Before: &before, After: &after})
+		default:
+			diff.Unchanged++
+		}
+		delete(current, e.PlayerName)
+	}
+	for name, score := range current {
+		diff.Removed++
+		diff.Samples = append(diff.Samples, RestoreChange{PlayerName: name, Before: &score})
+	}
+
+	slices.SortFunc(diff.Samples, func(a, b RestoreChange) int { return strings.Compare(a.PlayerName, b.PlayerName) })
+	if len(diff.Samples) > restoreSampleSize {
+		diff.Samples = diff.Samples[:restoreSampleSize]
+	}
+	return diff, nil
+}
+
+// restoreDigest is the hex SHA-256 of a board and the entries restored to it,
+// which binds a confirmation token to the exact state previewed
+func restoreDigest(board string, entries []store.RestoreEntry) string {
+	h := sha256.New()
+	h.Write([]byte(board + "\n"))
+	var buf [8]byte
+	for _, e := range entries {
+		h.Write([]byte(e.PlayerName + "\n"))
+		for _, v := range []int64{e.Score, e.AchievedAt.UnixMicro(), e.UpdatedAt.UnixMicro(), e.SecondaryScore} {
+			binary.BigEndian.PutUint64(buf[:], uint64(v))
+			h.Write(buf[:])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// restoreConfirmation returns the token confirming a restore of board to the state
+// of digest until expiresAt, in the format of reset confirmations
+func (s *Service) restoreConfirmation(board, digest string, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + signHMAC([]byte(s.opts.Admin.Token), restoreConfirmMessage(board, digest, expiry))
+}
+
+func (s *Service) checkRestoreConfirmation(board, digest, token string, now time.Time) error {
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("%w: malformed", ErrInvalidConfirmation)
+	}
+	if !verifyHMAC([]byte(s.opts.Admin.Token), restoreConfirmMessage(board, digest, expiry), signature) {
+		return fmt.Errorf("%w: not issued for this leaderboard and source", ErrInvalidConfirmation)
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.After(time.Unix(unix, 0)) {
+		return fmt.Errorf("%w: expired, request a new one", ErrInvalidConfirmation)
+	}
+	return nil
+}
+
+func restoreConfirmMessage(board, digest, expiry string) []byte {
+	return []byte(restoreConfirmVersion + "\n" + board + "\n" + digest + "\n" + expiry + "\n")
+}
+
+// restoreSourceLabel labels restores in metrics
+func restoreSourceLabel(src RestoreSource) string {
+	if src.SnapshotID != 0 {
+		return "snapshot"
+	}
+	return "export"
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestRestoreLeaderboard(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{Admin: Admin{Token: "s3cret", ConfirmTTL: time.Minute}})
+
+	// The board as exported before a bad deploy...
+	hourAgo := time.Now().Add(-time.Hour).UTC()
+	export := []RestoreEntry{
+		{LeaderboardID: "level-1", PlayerName: "Alice", Score: 500, AchievedAt: hourAgo},
+		{LeaderboardID: "level-1", PlayerName: "Bob", Score: 300, AchievedAt: hourAgo},
+		{LeaderboardID: "level-1", PlayerName: "Carol", Score: 200, AchievedAt: hourAgo},
+	}
+	// ...and after it: Alice corrupted, Carol lost, Mallory added
+	if _, err := svc.ImportScores(ctx, []ScoreImport{
+		{LeaderboardID: "level-1", PlayerName: "Alice", Score: 999_999},
+		{LeaderboardID: "level-1", PlayerName: "Bob", Score: 300},
+		{LeaderboardID: "level-1", PlayerName: "Mallory", Score: 800},
+	}); err != nil {
+		t.Fatalf("seed scores: %v", err)
+	}
+
+	src := RestoreSource{Entries: export}
+	if _, err := svc.RestoreLeaderboard(ctx, "level-1", src, true, ""); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated restore: error = %v, want ErrAdminUnauthorized", err)
+	}
+	ctx, _ = svc.AuthenticateAdmin(ctx, "s3cret")
+
+	preview, err := svc.RestoreLeaderboard(ctx, "level-1", src, true, "")
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	d := preview.Diff
+	if preview.Completed || preview.ConfirmationToken == "" || d.Added != 1 || d.Removed != 1 || d.Changed != 1 || d.Unchanged != 1 {
+		t.Fatalf("preview = %+v, want Carol added, Mallory removed, Alice changed, Bob unchanged", preview)
+	}
+	var sampled []string
+	for _, ch := range d.Samples {
+		sampled = append(sampled, ch.PlayerName)
+	}
+	if !slices.Equal(sampled, []string{"Alice", "Carol", "Mallory"}) || *d.Samples[0].Before != 999_999 || *d.Samples[0].After != 500 {
+		t.Errorf("samples = %+v, want Alice, Carol and Mallory", d.Samples)
+	}
+
+	// The token only confirms the previewed state
+	tampered := RestoreSource{Entries: slices.Clone(export)}
+	tampered.Entries[0].Score = 1_000_000
+	if _, err := svc.RestoreLeaderboard(ctx, "level-1", tampered, true, preview.ConfirmationToken); !errors.Is(err, ErrInvalidConfirmation) {
+		t.Errorf("other state with the token: error = %v, want ErrInvalidConfirmation", err)
+	}
+
+	res, err := svc.RestoreLeaderboard(ctx, "level-1", src, true, preview.ConfirmationToken)
+	if err != nil {
+		t.Fatalf("confirmed restore: %v", err)
+	}
+	if !res.Completed || res.Deleted != 3 || res.Restored != 3 || res.SnapshotID == 0 {
+		t.Fatalf("restore = %+v, want 3 replaced by 3 and a snapshot", res)
+	}
+	top, err := svc.GetTopScores(ctx, "level-1", 10, 0)
+	if err != nil || !slices.Equal(names(top), []string{"Alice", "Bob", "Carol"}) || top[0].Score != 500 {
+		t.Fatalf("board after restore = %+v, %v; want the export", top, err)
+	}
+
+	// The snapshot taken by the restore undoes it
+	undo := RestoreSource{SnapshotID: res.SnapshotID}
+	preview, err = svc.RestoreLeaderboard(ctx, "level-1", undo, false, "")
+	if err != nil || preview.Diff.Added != 1 || preview.Diff.Removed != 1 {
+		t.Fatalf("undo preview = %+v, %v", preview, err)
+	}
+	if _, err := svc.RestoreLeaderboard(ctx, "level-1", undo, false, preview.ConfirmationToken); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if rank, err := svc.GetPlayerRank(ctx, "level-1", "Mallory"); err != nil || rank.Score.Score != 800 {
+		t.Errorf("Mallory after undo = %+v, %v; want 800", rank, err)
+	}
+	if _, err := svc.RestoreLeaderboard(ctx, "level-2", undo, false, ""); !errors.Is(err, ErrInvalidRestore) {
+		t.Errorf("snapshot of another board: error = %v, want ErrInvalidRestore", err)
+	}
+	if _, err := svc.RestoreLeaderboard(ctx, "level-1", RestoreSource{SnapshotID: 999}, false, ""); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("unknown snapshot: error = %v, want ErrSnapshotNotFound", err)
+	}
+
+	duplicate := RestoreSource{Entries: append(slices.Clone(export), export[0])}
+	if _, err := svc.RestoreLeaderboard(ctx, "level-1", duplicate, false, ""); !errors.Is(err, ErrInvalidRestore) {
+		t.Errorf("duplicate player: error = %v, want ErrInvalidRestore", err)
+	}
+}
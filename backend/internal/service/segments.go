@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// ErrInvalidPlatform is returned when a submitted or requested platform is not
+// one of the platform families
+var ErrInvalidPlatform = errors.New("invalid platform")
+
+// Platform families scores are segmented by. Cross-play games rank every family
+// on the same board and can list each one on its own.
+const (
+	PlatformPC      = "pc"
+	PlatformMobile  = "mobile"
+	PlatformConsole = "console"
+	PlatformWeb     = "web"
+)
+
+// platforms are the platform families, in the order of the CHECK constraint
+var platforms = []string{PlatformPC, PlatformMobile, PlatformConsole, PlatformWeb}
+
+// clientPlatforms maps the platforms clients report in X-Client-Platform to their
+// family. Godot reports OS names; the families map to themselves.
+var clientPlatforms = map[string]string{
+	"windows": PlatformPC,
+	"macos":   PlatformPC,
+	"linux":   PlatformPC,
+	"android": PlatformMobile,
+	"ios":     PlatformMobile,
+	"ps4":     PlatformConsole,
+	"ps5":     PlatformConsole,
+	"xbox":    PlatformConsole,
+	"switch":  PlatformConsole,
+	"html5":   PlatformWeb,
+	"web":     PlatformWeb,
+	"pc":      PlatformPC,
+	"mobile":  PlatformMobile,
+	"console": PlatformConsole,
+}
+
+// Segment restricts a read to the scores submitted from one region and/or one
+// platform family. The zero Segment is the whole board.
+type Segment struct {
+	Region   string // ISO 3166-1 alpha-2 country, case-insensitive; "" for any
+	Platform string // platform family, case-insensitive; "" for any
+}
+
+// IsZero reports whether the segment is the whole board
+func (seg Segment) IsZero() bool {
+	return seg == Segment{}
+}
+
+// normalizeSegment normalizes both filters of a segment
+func normalizeSegment(seg Segment) (Segment, error) {
+	region, err := normalizeCountry(seg.Region)
+	if err != nil {
+		return Segment{}, err
+	}
+	platform, err := normalizePlatform(seg.Platform)
+	if err != nil {
+		return Segment{}, err
+	}
+	return Segment{Region: region, Platform: platform}, nil
+}
+
+// contains reports whether a stored score is in the (normalized) segment
+func (seg Segment) contains(sc store.Score) bool {
+	return (seg.Region == "" || sc.CountryCode == seg.Region) && (seg.Platform == "" || sc.Platform == seg.Platform)
+}
+
+// segmentTags are the segments a score is tagged with, "" when unknown
+type segmentTags struct {
+	country  string
+	platform string
+}
+
+// normalizePlatform lowercases a platform and checks that it is a platform
+// family; "" stays "" (unknown)
+func normalizePlatform(platform string) (string, error) {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if platform == "" || slices.Contains(platforms, platform) {
+		return platform, nil
+	}
+	return "", fmt.Errorf("%w: %q is not one of %s", ErrInvalidPlatform, platform, strings.Join(platforms, ", "))
+}
+
+// submissionPlatform returns the platform family a score is tagged with: the
+// platform of the submission when the client sent one, else the family of the
+// platform the client reports in X-Client-Platform, else "" (unknown)
+func submissionPlatform(ctx context.Context, platform string) (string, error) {
+	platform, err := normalizePlatform(platform)
+	if err != nil || platform != "" {
+		return platform, err
+	}
+	return clientPlatform(ctx), nil
+}
+
+// clientPlatform resolves the platform family of the client of the request in ctx
+func clientPlatform(ctx context.Context) string {
+	return clientPlatforms[requestctx.FromContext(ctx).Platform]
+}
+
+// GetSegmentTopScoresPage retrieves a page of a board restricted to a segment,
+// paged like GetTopScoresPage; the zero Segment reads the whole board. Ranks in a
+// segment page are relative to the segment. Segment pages are always read from
+// the database in the control ranking: the top cache and the ranking experiment
+// cover whole boards only. A token is only valid for the board and the segment it
+// was issued for.
+func (s *Service) GetSegmentTopScoresPage(ctx context.Context, board string, seg Segment, limit, offset int32, pageToken string) (*TopScoresPage, error) {
+	start := time.Now()
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+	if seg, err = normalizeSegment(seg); err != nil {
+		return nil, err
+	}
+	if seg.IsZero() {
+		return s.GetTopScoresPage(ctx, board, limit, offset, pageToken)
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidLimit)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset must be non-negative", ErrInvalidLimit)
+	}
+
+	var scores []store.Score
+	if pageToken == "" {
+		scores, err = s.store.GetSegmentTopScores(ctx, store.GetSegmentTopScoresParams{
+			LeaderboardID: board,
+			CountryCode:   seg.Region,
+			Platform:      seg.Platform,
+			PageSize:      limit,
+			PageOffset:    offset,
+		})
+	} else {
+		var cursor pageCursor
+		if cursor, err = decodeCursor(pageToken); err != nil {
+			return nil, err
+		}
+		if cursor.Board != board || cursor.Region != seg.Region || cursor.Platform != seg.Platform || cursor.Variant != "" {
+			return nil, fmt.Errorf("%w: token was issued for another leaderboard or segment", ErrInvalidPageToken)
+		}
+		after := cursor.score()
+		scores, err = s.store.GetSegmentTopScoresAfter(ctx, store.GetSegmentTopScoresAfterParams{
+			LeaderboardID: board,
+			CountryCode:   seg.Region,
+			Platform:      seg.Platform,
+			RankScore:     after.RankScore,
+			RankSecondary: after.RankSecondary,
+			AchievedAt:    after.AchievedAt,
+			PlayerName:    after.PlayerName,
+			PageSize:      limit,
+		})
+	}
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Str("region", seg.Region).Str("platform", seg.Platform).Int32("limit", limit).Msg("failed to get segment top scores")
+		return nil, fmt.Errorf("get segment top scores: %w", err)
+	}
+	observeRankRead(rankReadTopScores, RankingControl, start)
+
+	total, err := s.entryCount(ctx, board, seg)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TopScoresPage{Scores: scores, RankingVariant: RankingControl, TotalCount: total}
+	if len(scores) > 0 && len(scores) == int(limit) {
+		cursor := keysetCursor(board, scores[len(scores)-1])
+		cursor.Region, cursor.Platform = seg.Region, seg.Platform
+		page.NextPageToken = encodeCursor(cursor)
+	}
+	return page, nil
+}
+
+// GetSegmentPlayerRank calculates a player's rank within a segment of a board, like
+// GetPlayerRank; the zero Segment ranks on the whole board. A player whose best
+// score was submitted outside the segment is not found. Segment ranks are always
+// computed in the control ranking.
+func (s *Service) GetSegmentPlayerRank(ctx context.Context, board, playerName string, seg Segment) (*PlayerRank, error) {
+	start := time.Now()
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+	if seg, err = normalizeSegment(seg); err != nil {
+		return nil, err
+	}
+	if seg.IsZero() {
+		return s.GetPlayerRank(ctx, board, playerName)
+	}
+	if err := s.validatePlayerName(playerName); err != nil {
+		return nil, err
+	}
+
+	score, err := s.store.GetPlayerScore(ctx, store.GetPlayerScoreParams{
+		LeaderboardID: board,
+		PlayerName:    playerName,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrNoRows) {
+			return nil, ErrPlayerNotFound
+		}
+		s.loggerFor(ctx).Error().Err(err).Str("player", playerName).Msg("failed to get player score")
+		return nil, fmt.Errorf("get player score: %w", err)
+	}
+	if !seg.contains(score) {
+		return nil, ErrPlayerNotFound
+	}
+
+	rank, err := s.store.GetSegmentPlayerRank(ctx, store.GetSegmentPlayerRankParams{
+		LeaderboardID: board,
+		PlayerName:    playerName,
+		CountryCode:   seg.Region,
+		Platform:      seg.Platform,
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Str("player", playerName).Msg("failed to get segment player rank")
+		return nil, fmt.Errorf("get segment player rank: %w", err)
+	}
+	observeRankRead(rankReadPlayerRank, RankingControl, start)
+
+	return &PlayerRank{Rank: int64(rank), Score: score, RankingVariant: RankingControl}, nil
+}
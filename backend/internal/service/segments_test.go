@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestSubmissionPlatform(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{})
+	fromAndroid := requestctx.NewContext(ctx, requestctx.Info{Platform: "android"})
+	fromToaster := requestctx.NewContext(ctx, requestctx.Info{Platform: "toaster"})
+
+	for _, tt := range []struct {
+		ctx      context.Context
+		player   string
+		platform string
+		want     string
+	}{
+		{fromAndroid, "Explicit", " Console ", "console"},
+		{fromAndroid, "Derived", "", "mobile"},
+		{fromToaster, "Unknown", "", ""},
+	} {
+		result, err := svc.SubmitScore(tt.ctx, ScoreSubmission{PlayerName: tt.player, Score: 100, Platform: tt.platform})
+		if err != nil {
+			t.Fatalf("%s: submit: %v", tt.player, err)
+		}
+		if result.Platform != tt.want {
+			t.Errorf("%s: platform = %q, want %q", tt.player, result.Platform, tt.want)
+		}
+	}
+
+	for _, platform := range []string{"android", "xbox", "p c"} {
+		if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Bad", Score: 100, Platform: platform}); !errors.Is(err, ErrInvalidPlatform) {
+			t.Errorf("platform %q: error = %v, want %v", platform, err, ErrInvalidPlatform)
+		}
+	}
+}
+
+// newSegmentService returns a service over a board of scores tagged with
+// countries and platforms:
+//
+//	A 500 FR pc, B 450 DE mobile, C 400 FR mobile, D 350 (unknown), E 300 FR pc, F 600 DE pc
+func newSegmentService(t *testing.T) *Service {
+	t.Helper()
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{TopCacheSize: 10})
+
+	for _, sub := range []ScoreSubmission{
+		{PlayerName: "A", Score: 500, Country: "FR", Platform: "pc"},
+		{PlayerName: "B", Score: 450, Country: "DE", Platform: "mobile"},
+		{PlayerName: "C", Score: 400, Country: "FR", Platform: "mobile"},
+		{PlayerName: "D", Score: 350},
+		{PlayerName: "E", Score: 300, Country: "FR", Platform: "pc"},
+		{PlayerName: "F", Score: 250, Country: "FR", Platform: "web"},
+		// A better score from another country and platform moves the player there
+		{PlayerName: "F", Score: 600, Country: "DE", Platform: "pc"},
+	} {
+		if _, err := svc.SubmitScore(ctx, sub); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+	return svc
+}
+
+func TestGetSegmentTopScoresPage(t *testing.T) {
+	ctx := context.Background()
+	svc := newSegmentService(t)
+
+	for _, tt := range []struct {
+		seg  Segment
+		want []string
+	}{
+		{Segment{Region: "fr"}, []string{"A", "C", "E"}},
+		{Segment{Platform: "PC"}, []string{"F", "A", "E"}},
+		{Segment{Region: "FR", Platform: "mobile"}, []string{"C"}},
+		{Segment{Platform: "web"}, nil},
+	} {
+		var got []string
+		token := ""
+		for range 10 {
+			page, err := svc.GetSegmentTopScoresPage(ctx, "", tt.seg, 2, 0, token)
+			if err != nil {
+				t.Fatalf("%+v: GetSegmentTopScoresPage: %v", tt.seg, err)
+			}
+			got = append(got, names(page.Scores)...)
+			if page.NextPageToken == "" {
+				break
+			}
+			token = page.NextPageToken
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%+v: names = %v, want %v", tt.seg, got, tt.want)
+		}
+	}
+
+	page, err := svc.GetSegmentTopScoresPage(ctx, "", Segment{Region: "DE"}, 10, 1, "")
+	if err != nil {
+		t.Fatalf("GetSegmentTopScoresPage with offset: %v", err)
+	}
+	if want := []string{"B"}; !slices.Equal(names(page.Scores), want) {
+		t.Errorf("DE names after offset 1 = %v, want %v", names(page.Scores), want)
+	}
+	page, err = svc.GetSegmentTopScoresPage(ctx, "", Segment{}, 2, 0, "")
+	if err != nil {
+		t.Fatalf("GetSegmentTopScoresPage of the whole board: %v", err)
+	}
+	if want := []string{"F", "A"}; !slices.Equal(names(page.Scores), want) {
+		t.Errorf("whole board names = %v, want %v", names(page.Scores), want)
+	}
+
+	// Tokens are bound to their segment, and whole-board tokens to whole boards
+	frPage, _ := svc.GetSegmentTopScoresPage(ctx, "", Segment{Region: "FR"}, 1, 0, "")
+	for _, seg := range []Segment{{Region: "DE"}, {Region: "FR", Platform: "pc"}, {Platform: "pc"}} {
+		if _, err := svc.GetSegmentTopScoresPage(ctx, "", seg, 1, 0, frPage.NextPageToken); !errors.Is(err, ErrInvalidPageToken) {
+			t.Errorf("FR token for %+v: error = %v, want %v", seg, err, ErrInvalidPageToken)
+		}
+	}
+	pcPage, _ := svc.GetSegmentTopScoresPage(ctx, "", Segment{Platform: "pc"}, 1, 0, "")
+	for _, token := range []string{frPage.NextPageToken, pcPage.NextPageToken} {
+		if _, err := svc.GetTopScoresPage(ctx, "", 1, 0, token); !errors.Is(err, ErrInvalidPageToken) {
+			t.Errorf("segment token for the whole board: error = %v, want %v", err, ErrInvalidPageToken)
+		}
+	}
+	boardPage, _ := svc.GetTopScoresPage(ctx, "", 1, 0, "")
+	if _, err := svc.GetSegmentTopScoresPage(ctx, "", Segment{Region: "FR"}, 1, 0, boardPage.NextPageToken); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("whole board token for FR: error = %v, want %v", err, ErrInvalidPageToken)
+	}
+
+	if _, err := svc.GetSegmentTopScoresPage(ctx, "", Segment{Region: "FRA"}, 10, 0, ""); !errors.Is(err, ErrInvalidCountry) {
+		t.Errorf("region FRA: error = %v, want %v", err, ErrInvalidCountry)
+	}
+	if _, err := svc.GetSegmentTopScoresPage(ctx, "", Segment{Platform: "android"}, 10, 0, ""); !errors.Is(err, ErrInvalidPlatform) {
+		t.Errorf("platform android: error = %v, want %v", err, ErrInvalidPlatform)
+	}
+}
+
+func TestGetSegmentPlayerRank(t *testing.T) {
+	ctx := context.Background()
+	svc := newSegmentService(t)
+
+	for _, tt := range []struct {
+		player string
+		seg    Segment
+		want   int64
+	}{
+		{"E", Segment{}, 6},
+		{"E", Segment{Region: "FR"}, 3},
+		{"E", Segment{Platform: "pc"}, 3},
+		{"E", Segment{Region: "fr", Platform: "pc"}, 2},
+		{"C", Segment{Platform: "mobile"}, 2},
+	} {
+		rank, err := svc.GetSegmentPlayerRank(ctx, "", tt.player, tt.seg)
+		if err != nil {
+			t.Fatalf("%s in %+v: %v", tt.player, tt.seg, err)
+		}
+		if rank.Rank != tt.want {
+			t.Errorf("%s in %+v: rank = %d, want %d", tt.player, tt.seg, rank.Rank, tt.want)
+		}
+	}
+
+	// F moved to DE and pc with its best score
+	for _, seg := range []Segment{{Region: "FR"}, {Platform: "web"}} {
+		if _, err := svc.GetSegmentPlayerRank(ctx, "", "F", seg); !errors.Is(err, ErrPlayerNotFound) {
+			t.Errorf("F in %+v: error = %v, want %v", seg, err, ErrPlayerNotFound)
+		}
+	}
+}
+
+func TestSegmentSimulateRankAndPercentiles(t *testing.T) {
+	ctx := context.Background()
+	svc := newSegmentService(t)
+
+	sim, err := svc.SimulateRank(ctx, "", 420, "", Segment{Platform: "mobile"})
+	if err != nil {
+		t.Fatalf("SimulateRank: %v", err)
+	}
+	if sim.Rank != 2 || sim.TotalPlayers != 3 || sim.PointsToNextRank != 31 {
+		t.Errorf("mobile simulation = %+v, want rank 2 of 3, 31 points to next rank", sim)
+	}
+	if _, err := svc.SimulateRank(ctx, "", 420, "", Segment{Platform: "ps5"}); !errors.Is(err, ErrInvalidPlatform) {
+		t.Errorf("SimulateRank on platform ps5: error = %v, want %v", err, ErrInvalidPlatform)
+	}
+
+	fr, err := svc.GetPercentileBuckets(ctx, "", Segment{Region: "FR"})
+	if err != nil {
+		t.Fatalf("GetPercentileBuckets FR: %v", err)
+	}
+	board, err := svc.GetPercentileBuckets(ctx, "", Segment{})
+	if err != nil {
+		t.Fatalf("GetPercentileBuckets: %v", err)
+	}
+	if fr.TotalPlayers != 3 || board.TotalPlayers != 6 {
+		t.Errorf("players = %d in FR and %d on the board, want 3 and 6: segments share a cached snapshot", fr.TotalPlayers, board.TotalPlayers)
+	}
+}
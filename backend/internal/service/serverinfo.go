@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// MaxServerInfoBoards is the maximum number of board definitions listed by ServerInfo
+const MaxServerInfoBoards = 100
+
+// serverInfoTTL is how long the board list of ServerInfo is reused: clients call it
+// at startup, so a launch spike must not turn into as many database reads
+const serverInfoTTL = 30 * time.Second
+
+// BoardInfo is a board a client can offer to players
+type BoardInfo struct {
+	LeaderboardID string
+	SortOrder     SortOrder
+	Daily         bool // today's daily challenge board
+}
+
+// ServerLimits are the input limits enforced by the service
+type ServerLimits struct {
+	MinPlayerNameLength    int
+	MaxPlayerNameLength    int
+	MaxLeaderboardIDLength int
+	MaxOfflineRuns         int    // per offline sync batch, 0 when offline sync is disabled
+	MaxScore               int64  // 0 when scores are unbounded
+	PlayerNamePattern      string // regular expression player names must match, "" when any is allowed
+}
+
+// ServerFeatures are the optional features enabled on the server
+type ServerFeatures struct {
+	SubmitSignatures  string // SigningModeOff, SigningModeMonitor or SigningModeEnforce
+	OfflineSync       bool
+	Receipts          bool
+	Tiers             bool
+	PlayerIdentities  bool // display names and avatars come from the platform identity service
+	RankingExperiment bool // some rank reads may be served by another ranking
+}
+
+// ServerInfo describes what the server offers a client, for its startup handshake
+type ServerInfo struct {
+	// Boards are the default board, today's daily board and the defined boards,
+	// past daily boards excluded
+	Boards   []BoardInfo
+	Limits   ServerLimits
+	Features ServerFeatures
+}
+
+// serverInfoCache caches the board list of ServerInfo
+type serverInfoCache struct {
+	mu       sync.Mutex
+	boards   []BoardInfo
+	loadedAt time.Time
+}
+
+// ServerInfo returns the boards, limits and features of the server
+func (s *Service) ServerInfo(ctx context.Context) (*ServerInfo, error) {
+	boards, err := s.serverBoards(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	signing := s.opts.SubmitSigning.Mode
+	if signing == "" {
+		signing = SigningModeOff
+	}
+	minName, maxName := s.opts.Validation.nameBounds()
+	info := &ServerInfo{
+		Boards: boards,
+		Limits: ServerLimits{
+			MinPlayerNameLength:    minName,
+			MaxPlayerNameLength:    maxName,
+			MaxLeaderboardIDLength: MaxLeaderboardIDLength,
+			MaxScore:               s.opts.Validation.MaxScore,
+		},
+		Features: ServerFeatures{
+			SubmitSignatures:  signing,
+			OfflineSync:       len(s.opts.OfflineSync.SigningKey) > 0,
+			Receipts:          len(s.opts.Receipts.Keys) > 0,
+			Tiers:             len(s.opts.Tiers) > 0,
+			PlayerIdentities:  s.opts.Identity != nil,
+			RankingExperiment: s.opts.RankingExperiment.Variant != "" && s.opts.RankingExperiment.Percent > 0,
+		},
+	}
+	if pattern := s.opts.Validation.PlayerNamePattern; pattern != nil {
+		info.Limits.PlayerNamePattern = pattern.String()
+	}
+	if info.Features.OfflineSync {
+		info.Limits.MaxOfflineRuns = s.opts.OfflineSync.MaxRuns
+	}
+	return info, nil
+}
+
+// serverBoards lists the boards of ServerInfo, from cache when fresh
+func (s *Service) serverBoards(ctx context.Context) ([]BoardInfo, error) {
+	s.serverInfo.mu.Lock()
+	defer s.serverInfo.mu.Unlock()
+	if s.serverInfo.boards != nil && time.Since(s.serverInfo.loadedAt) < serverInfoTTL {
+		return s.serverInfo.boards, nil
+	}
+
+	daily, err := s.CurrentDailyBoard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := s.store.ListLeaderboards(ctx, store.ListLeaderboardsParams{
+		ExcludePrefix: s.dailyPrefix(),
+		MaxBoards:     MaxServerInfoBoards,
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Msg("failed to list leaderboards")
+		return nil, fmt.Errorf("list leaderboards: %w", err)
+	}
+
+	boards := []BoardInfo{{LeaderboardID: DefaultLeaderboardID, SortOrder: SortDescending}}
+	for _, def := range defs {
+		if def.LeaderboardID == DefaultLeaderboardID {
+			boards[0].SortOrder = SortOrder(def.SortOrder)
+			continue
+		}
+		boards = append(boards, BoardInfo{LeaderboardID: def.LeaderboardID, SortOrder: SortOrder(def.SortOrder)})
+	}
+	boards = append(boards, BoardInfo{LeaderboardID: daily.LeaderboardID, SortOrder: daily.SortOrder, Daily: true})
+
+	s.serverInfo.boards, s.serverInfo.loadedAt = boards, time.Now()
+	return boards, nil
+}
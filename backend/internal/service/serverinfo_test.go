@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestServerInfo(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{
+		OfflineSync: OfflineSync{SigningKey: []byte("k"), MaxRuns: 50},
+		Daily:       Daily{SortOrder: SortAscending},
+		Validation:  Validation{MaxPlayerNameLength: 16, MaxScore: 9999},
+	})
+
+	if _, err := svc.UpsertLeaderboard(ctx, "lap-1", SortAscending, SortDescending); err != nil {
+		t.Fatalf("UpsertLeaderboard: %v", err)
+	}
+	if _, err := st.UpsertLeaderboard(ctx, store.UpsertLeaderboardParams{LeaderboardID: "daily-2020-01-01", SortOrder: "asc", SecondarySortOrder: "desc"}); err != nil {
+		t.Fatalf("past daily board: %v", err)
+	}
+
+	info, err := svc.ServerInfo(ctx)
+	if err != nil {
+		t.Fatalf("ServerInfo: %v", err)
+	}
+	today := "daily-" + time.Now().UTC().Format(dailyDateLayout)
+	want := []BoardInfo{
+		{LeaderboardID: DefaultLeaderboardID, SortOrder: SortDescending},
+		{LeaderboardID: "lap-1", SortOrder: SortAscending},
+		{LeaderboardID: today, SortOrder: SortAscending, Daily: true},
+	}
+	if len(info.Boards) != len(want) {
+		t.Fatalf("boards = %+v, want %+v", info.Boards, want)
+	}
+	for i, b := range info.Boards {
+		if b != want[i] {
+			t.Errorf("board %d = %+v, want %+v", i, b, want[i])
+		}
+	}
+
+	wantLimits := ServerLimits{
+		MinPlayerNameLength:    MinPlayerNameLength,
+		MaxPlayerNameLength:    16,
+		MaxLeaderboardIDLength: MaxLeaderboardIDLength,
+		MaxOfflineRuns:         50,
+		MaxScore:               9999,
+	}
+	if info.Limits != wantLimits {
+		t.Errorf("limits = %+v, want %+v", info.Limits, wantLimits)
+	}
+	wantFeatures := ServerFeatures{SubmitSignatures: SigningModeOff, OfflineSync: true}
+	if info.Features != wantFeatures {
+		t.Errorf("features = %+v, want %+v", info.Features, wantFeatures)
+	}
+}
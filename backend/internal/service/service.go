@@ -4,10 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"sync/atomic"
+	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/requestctx"
 	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/usage"
+	"golang.org/x/sync/semaphore"
 )
 
 var (
@@ -22,81 +30,466 @@ var (
 
 	// ErrInvalidLimit is returned when limit parameter is invalid
 	ErrInvalidLimit = errors.New("invalid limit")
+
+	// ErrInvalidDeviceID is returned when the device fingerprint is malformed
+	ErrInvalidDeviceID = errors.New("invalid device id")
+
+	// ErrDeviceLimitExceeded is returned when a device exceeds its account or rate limit
+	ErrDeviceLimitExceeded = errors.New("device limit exceeded")
+
+	// ErrTooManyPlayers is returned when GetPlayerRanks is asked for more than MaxPlayerRanks players
+	ErrTooManyPlayers = errors.New("too many players")
+
+	// ErrTimeout is returned when a database query runs past its timeout
+	ErrTimeout = store.ErrQueryTimeout
+
+	// ErrUnavailable is returned, as an *UnavailableError, while the database
+	// circuit breaker is open
+	ErrUnavailable = store.ErrUnavailable
 )
 
+// UnavailableError carries when to retry a call failed with ErrUnavailable
+type UnavailableError = store.UnavailableError
+
+// Default input limits; player name bounds can be changed with Options.Validation
 const (
-	MaxPlayerNameLength = 20
-	MinPlayerNameLength = 1
+	MaxPlayerNameLength = store.DefaultMaxPlayerNameLength
+	MinPlayerNameLength = store.DefaultMinPlayerNameLength
+
+	MaxDeviceIDLength = 128
+
+	// MaxPlayerRanks is the maximum number of players ranked by one GetPlayerRanks call
+	MaxPlayerRanks = 100
+)
+
+// Device limit enforcement modes
+const (
+	DeviceLimitModeOff     = "off"     // fingerprints are ignored
+	DeviceLimitModeMonitor = "monitor" // violations are logged and counted but allowed
+	DeviceLimitModeEnforce = "enforce" // violations are rejected
 )
 
+// DeviceLimits configures per-device submission limits
+type DeviceLimits struct {
+	Mode                  string
+	MaxAccounts           int32 // max distinct players per device
+	MaxSubmissionsPerHour int32 // max submissions per device per hour
+}
+
+// Validation configures the input checks of player names and scores. The
+// database enforces the same bounds with constraints (see store.Validation).
+type Validation struct {
+	MinPlayerNameLength int            // 0 uses MinPlayerNameLength
+	MaxPlayerNameLength int            // 0 uses MaxPlayerNameLength
+	MaxScore            int64          // 0 leaves scores unbounded
+	PlayerNamePattern   *regexp.Regexp // player names must match it (nil allows any)
+}
+
+// nameBounds returns the length bounds of player names
+func (v Validation) nameBounds() (int, int) {
+	minLen, maxLen := v.MinPlayerNameLength, v.MaxPlayerNameLength
+	if minLen <= 0 {
+		minLen = MinPlayerNameLength
+	}
+	if maxLen <= 0 {
+		maxLen = MaxPlayerNameLength
+	}
+	return minLen, maxLen
+}
+
+// Options holds optional service behavior
+type Options struct {
+	DeviceLimits DeviceLimits
+
+	// Validation bounds player names and scores
+	Validation Validation
+
+	// PercentileBuckets are the "top X%" buckets reported by GetPercentileBuckets
+	PercentileBuckets []float64
+
+	// PercentileCacheTTL is how long computed percentile thresholds and score
+	// distributions are reused
+	PercentileCacheTTL time.Duration
+
+	// CountCacheTTL is how long entry counts are reused before the board is
+	// counted again (0 counts on every read)
+	CountCacheTTL time.Duration
+
+	// TopCacheSize is the number of top entries kept in memory per board (0 disables the cache)
+	TopCacheSize int
+
+	// TopCacheBoards is the number of most recently read boards with a top cache
+	// (0 uses DefaultTopCacheBoards)
+	TopCacheBoards int
+
+	// Tiers are the tier/division definitions by board (boards without any have no tiers)
+	Tiers map[string][]TierDefinition
+
+	// Admission limits concurrent writes to shed load under peak traffic
+	Admission Admission
+
+	// ClientTimestamps controls when client-reported completion times are trusted
+	ClientTimestamps ClientTimestamps
+
+	// OfflineSync configures SyncOfflineScores (disabled without a signing key)
+	OfflineSync OfflineSync
+
+	// SubmitSigning controls HMAC verification of SubmitScore payloads
+	SubmitSigning SubmitSigning
+
+	// ProfileCacheTTL is how long player profiles attached to entries are reused (0 disables caching)
+	ProfileCacheTTL time.Duration
+
+	// Import limits bulk score imports
+	Import Import
+
+	// Admin configures admin authentication and destructive operations
+	Admin Admin
+
+	// Identity resolves display names and avatars from an external account system (nil disables it)
+	Identity IdentityResolver
+
+	// Geo resolves the country of submissions that do not name one from the
+	// client address (nil leaves their country unknown)
+	Geo GeoResolver
+
+	// Daily configures the daily challenge boards
+	Daily Daily
+
+	// Receipts configures signed submission receipts
+	Receipts Receipts
+
+	// Events receives lifecycle events such as daily rollovers (nil discards them)
+	Events *lifecycle.Bus
+
+	// Submissions receives an event per score submission, e.g. for a data warehouse (nil disables it)
+	Submissions SubmissionSink
+
+	// Replayer re-dispatches historical outbox events (nil when the backend keeps no outbox)
+	Replayer EventReplayer
+
+	// Changes reads the changes of a board for polling clients (nil when the backend keeps no outbox)
+	Changes ChangeReader
+
+	// Usage keeps per-API-key request statistics (nil disables them)
+	Usage *usage.Tracker
+
+	// RankingExperiment serves a share of rank reads with an alternative ranking
+	RankingExperiment RankingExperiment
+
+	// Cohorts labels submission outcome metrics with player cohorts
+	Cohorts CohortMetrics
+
+	// SubmitRank ranks players in the submission transaction and returns the
+	// rank in SubmitScore results, at the cost of up to two rank queries per write
+	SubmitRank bool
+
+	// SubmitQueue makes SubmitScore queue submissions for batched writes (zero writes synchronously)
+	SubmitQueue SubmitQueue
+}
+
 // Service implements the leaderboard business logic
 type Service struct {
-	store  *store.Store
+	store  store.Repository
 	logger *zerolog.Logger
+	opts   Options
+
+	deviceLimits   atomic.Pointer[DeviceLimits] // opts.DeviceLimits until SetDeviceLimits
+	percentiles    percentileCache
+	distributions  distributionCache
+	counts         countCache
+	top            topCaches
+	versions       boardVersions // versions of the boards' data, moved by their changes
+	tiers          tierState
+	writes         *semaphore.Weighted // nil when admission control is disabled
+	lastShed       atomic.Int64        // unix nanos of the last shed write
+	nonces         nonceCache          // recently used submission nonces
+	profiles       profileCache
+	cohortVersions cohortVersions // client versions labeled in cohort metrics
+	daily          dailyState
+	serverInfo     serverInfoCache
+	queue          submitQueue // async submissions, unused when SubmitScore writes synchronously
 }
 
 // New creates a new Service instance
-func New(s *store.Store, logger *zerolog.Logger) *Service {
-	return &Service{
-		store:  s,
-		logger: logger,
+func New(s store.Repository, logger *zerolog.Logger, opts Options) *Service {
+	if opts.DeviceLimits.Mode == "" {
+		opts.DeviceLimits.Mode = DeviceLimitModeOff
+	}
+	if opts.SubmitSigning.Mode == "" {
+		opts.SubmitSigning.Mode = SigningModeOff
+	}
+	opts.PercentileBuckets = normalizePercentileBuckets(opts.PercentileBuckets)
+	if opts.Import.MaxEntries <= 0 {
+		opts.Import.MaxEntries = DefaultImportMaxEntries
+	}
+
+	var writes *semaphore.Weighted
+	if opts.Admission.MaxConcurrent > 0 {
+		writes = semaphore.NewWeighted(opts.Admission.MaxConcurrent)
+	}
+
+	svc := &Service{
+		store:    s,
+		logger:   logger,
+		opts:     opts,
+		top:      newTopCaches(opts.TopCacheSize, opts.TopCacheBoards),
+		versions: newBoardVersions(),
+		tiers:    newTierState(opts.Tiers),
+		writes:   writes,
+	}
+	svc.deviceLimits.Store(&opts.DeviceLimits)
+	if opts.SubmitQueue.Size > 0 {
+		svc.queue.scores = make(chan queuedScore, opts.SubmitQueue.Size)
+		svc.queue.stopped = make(chan struct{})
+	}
+	return svc
+}
+
+// SetDeviceLimits replaces the device limits, e.g. on a configuration reload.
+// Submissions being checked finish with the previous limits.
+func (s *Service) SetDeviceLimits(limits DeviceLimits) {
+	if limits.Mode == "" {
+		limits.Mode = DeviceLimitModeOff
 	}
+	s.deviceLimits.Store(&limits)
+}
+
+// currentDeviceLimits returns the device limits in force
+func (s *Service) currentDeviceLimits() DeviceLimits {
+	if limits := s.deviceLimits.Load(); limits != nil {
+		return *limits
+	}
+	return DeviceLimits{Mode: DeviceLimitModeOff}
+}
+
+// ScoreSubmission holds the input of a score submission
+type ScoreSubmission struct {
+	LeaderboardID  string // board the score is for, empty for the default board
+	PlayerName     string
+	Score          int64
+	SecondaryScore int64             // optional tiebreaker among equal scores (time, accuracy...)
+	DeviceID       string            // optional client-computed device fingerprint hash
+	AchievedAt     time.Time         // optional client-reported completion time of the run
+	Metadata       map[string]string // optional attributes of the run (level, character, replay id...)
+	Country        string            // optional ISO 3166-1 alpha-2 country of the player, resolved from the client address when empty
+	Platform       string            // optional platform family (pc, mobile, console, web), derived from X-Client-Platform when empty
+	Nonce          string            // unique per attempt, required when submissions are signed
+	SignedAt       int64             // Unix seconds when the client signed the submission
+	Signature      string            // hex HMAC-SHA256 over the canonical submission
 }
 
 // ScoreResult represents the result of a score submission
 type ScoreResult struct {
-	PlayerName string
-	Score      int64
-	UpdatedAt  string
-	Applied    bool // true if the score was new or improved
+	LeaderboardID string
+	PlayerName    string
+	Score         int64
+	UpdatedAt     time.Time // zero for a queued submission
+	AchievedAt    time.Time // when the best score was achieved (trusted client time or server time)
+	Applied       bool      // true if the score was new or improved
+
+	// Metadata are the attributes of the best score, nil when it has none
+	Metadata map[string]string
+
+	// SecondaryScore is the tiebreaker of the best score
+	SecondaryScore int64
+
+	// Country is the country the best score was submitted from, "" when unknown
+	Country string
+
+	// Platform is the platform family the best score was submitted from, "" when unknown
+	Platform string
+
+	// Rank is the player's 1-based rank after the submission, 0 when ranking
+	// submissions is disabled. RankDelta is the number of places gained (0 for
+	// a first score).
+	Rank      int64
+	RankDelta int64
+
+	// Receipt is the signed receipt of a SubmitScore call, nil when receipts are disabled
+	Receipt *Receipt
+
+	// Accepted is true when the submission was queued rather than written: the
+	// result echoes the submission, Applied, Rank and Receipt are unset, and the
+	// board's streams deliver the authoritative best score once it is written
+	Accepted bool
 }
 
 // SubmitScore submits or updates a player's score
 // Returns true if the score was applied (new or improved)
-func (s *Service) SubmitScore(ctx context.Context, playerName string, score int64) (*ScoreResult, error) {
+func (s *Service) SubmitScore(ctx context.Context, sub ScoreSubmission) (*ScoreResult, error) {
+	result, err := s.submitScore(ctx, sub)
+	if err != nil || !result.Accepted {
+		// Queued submissions are observed once written
+		s.observeSubmission(ctx, sub.PlayerName, result, err)
+	}
+	return result, err
+}
+
+func (s *Service) submitScore(ctx context.Context, sub ScoreSubmission) (*ScoreResult, error) {
+	playerName, score := sub.PlayerName, sub.Score
+
 	// Validate input
+	board, err := ResolveLeaderboardID(sub.LeaderboardID)
+	if err != nil {
+		return nil, err
+	}
+	sub.LeaderboardID = board
+	if err := s.checkBoardOpen(board, time.Now()); err != nil {
+		return nil, err
+	}
 	if err := s.validatePlayerName(playerName); err != nil {
 		return nil, err
 	}
 	if err := s.validateScore(score); err != nil {
 		return nil, err
 	}
+	if sub.SecondaryScore < 0 {
+		return nil, fmt.Errorf("%w: secondary score must be non-negative", ErrInvalidScore)
+	}
+	if err := s.validateDeviceID(sub.DeviceID); err != nil {
+		return nil, err
+	}
+	if err := validateMetadata(sub.Metadata); err != nil {
+		return nil, err
+	}
+	country, err := s.submissionCountry(ctx, sub.Country)
+	if err != nil {
+		return nil, err
+	}
+	platform, err := submissionPlatform(ctx, sub.Platform)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSignature(ctx, sub, time.Now()); err != nil {
+		return nil, err
+	}
+	if s.queuing() {
+		return s.enqueueScore(ctx, sub, segmentTags{country: country, platform: platform})
+	}
 
-	// Get current score before upsert (if exists)
-	var oldScore int64
-	var hadScore bool
-	currentScore, err := s.store.GetPlayerScore(ctx, playerName)
-	if err == nil {
-		oldScore = currentScore.Score
-		hadScore = true
-	} else if !errors.Is(err, pgx.ErrNoRows) {
-		s.logger.Error().Err(err).Str("player", playerName).Msg("failed to get current score")
-		return nil, fmt.Errorf("get current score: %w", err)
-	}
-
-	// Perform upsert
-	result, err := s.store.UpsertScore(ctx, store.UpsertScoreParams{
-		PlayerName: playerName,
-		Score:      score,
+	release, err := s.admit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if err := s.checkBanned(ctx, playerName); err != nil {
+		return nil, err
+	}
+
+	// Apply per-device limits before touching the scores table
+	if err := s.checkDeviceLimits(ctx, sub.DeviceID, playerName); err != nil {
+		return nil, err
+	}
+
+	achievedAt, clientAchievedAt := s.resolveAchievedAt(ctx, sub.AchievedAt, time.Now())
+	result, err := s.applyScore(ctx, board, playerName, score, sub.SecondaryScore, achievedAt, clientAchievedAt, encodeMetadata(sub.Metadata), segmentTags{country: country, platform: platform})
+	if err != nil {
+		return nil, err
+	}
+	result.Receipt = s.issueReceipt(sub, result.Applied, time.Now())
+	return result, nil
+}
+
+// applyScore upserts a validated score and reports whether it became the player's best on the board.
+// metadata is the encoded metadata of the score, nil for none.
+func (s *Service) applyScore(ctx context.Context, board, playerName string, score, secondaryScore int64, achievedAt time.Time, clientAchievedAt pgtype.Timestamptz, metadata []byte, tags segmentTags) (*ScoreResult, error) {
+	upserted, err := s.upsertBest(ctx, store.UpsertScoreParams{
+		LeaderboardID:    board,
+		PlayerName:       playerName,
+		Score:            score,
+		SecondaryScore:   secondaryScore,
+		AchievedAt:       pgtype.Timestamptz{Time: achievedAt, Valid: true},
+		ClientAchievedAt: clientAchievedAt,
+		Metadata:         metadata,
+		CountryCode:      tags.country,
+		Platform:         tags.platform,
 	})
 	if err != nil {
-		s.logger.Error().Err(err).Str("player", playerName).Int64("score", score).Msg("failed to upsert score")
-		return nil, fmt.Errorf("upsert score: %w", err)
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Str("player", playerName).Int64("score", score).Msg("failed to upsert score")
+		return nil, err
+	}
+	return s.scoreWritten(ctx, score, upserted), nil
+}
+
+// scoreWritten announces a written score, submitted as score, and returns its result
+func (s *Service) scoreWritten(ctx context.Context, score int64, upserted store.RankedScore) *ScoreResult {
+	result, applied := upserted.Score, upserted.Applied
+	board := result.LeaderboardID
+	var oldScore int64
+	hadScore := upserted.Previous != nil
+	if hadScore {
+		oldScore = upserted.Previous.Score
+	}
+
+	// Announce promotions caused by this submission
+	if applied && hadScore {
+		s.emitTierChange(board, result.PlayerName, result.Score, s.TierFor(board, oldScore), s.TierFor(board, result.Score))
 	}
 
-	// Determine if the score was applied (improved or created)
-	applied := !hadScore || result.Score > oldScore
+	s.emitSubmitted(ctx, SubmissionEvent{
+		LeaderboardID:  board,
+		PlayerName:     result.PlayerName,
+		SubmittedScore: score,
+		OldScore:       oldScore,
+		HadScore:       hadScore,
+		NewScore:       result.Score,
+		Applied:        applied,
+		AchievedAt:     result.AchievedAt.Time,
+	})
 
+	res := toScoreResult(result, applied)
+	if upserted.Rank > 0 {
+		res.Rank = int64(upserted.Rank)
+		if upserted.PreviousRank > 0 {
+			res.RankDelta = int64(upserted.PreviousRank - upserted.Rank)
+		}
+	}
+	return res
+}
+
+// upsertBest writes a score in a transaction that tells authoritatively whether it
+// became the player's best, ranked in the same transaction when SubmitRank is enabled
+func (s *Service) upsertBest(ctx context.Context, row store.UpsertScoreParams) (store.RankedScore, error) {
+	if s.opts.SubmitRank {
+		return s.store.UpsertScoreRanked(ctx, row)
+	}
+	written, err := s.store.UpsertScores(ctx, []store.UpsertScoreParams{row})
+	if err != nil {
+		return store.RankedScore{}, err
+	}
+	return store.RankedScore{UpsertedScore: written[0]}, nil
+}
+
+// toScoreResult converts a stored best score into a submission result
+func toScoreResult(sc store.Score, applied bool) *ScoreResult {
 	return &ScoreResult{
-		PlayerName: result.PlayerName,
-		Score:      result.Score,
-		UpdatedAt:  result.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-		Applied:    applied,
-	}, nil
+		LeaderboardID:  sc.LeaderboardID,
+		PlayerName:     sc.PlayerName,
+		Score:          sc.Score,
+		UpdatedAt:      sc.UpdatedAt.Time,
+		AchievedAt:     sc.AchievedAt.Time,
+		Applied:        applied,
+		Metadata:       DecodeMetadata(sc.Metadata),
+		SecondaryScore: sc.SecondaryScore,
+		Country:        sc.CountryCode,
+		Platform:       sc.Platform,
+	}
+}
+
+// loggerFor returns the service logger annotated with the caller of the request in ctx,
+// so that service logs correlate with the transport logs of the same request id
+func (s *Service) loggerFor(ctx context.Context) *zerolog.Logger {
+	return requestctx.Logger(ctx, s.logger)
 }
 
-// GetTopScores retrieves the top N scores with pagination
-func (s *Service) GetTopScores(ctx context.Context, limit, offset int32) ([]store.Score, error) {
+// GetTopScores retrieves the top N scores of a board with pagination
+func (s *Service) GetTopScores(ctx context.Context, board string, limit, offset int32) ([]store.Score, error) {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
 	if limit <= 0 {
 		return nil, fmt.Errorf("%w: limit must be positive", ErrInvalidLimit)
 	}
@@ -104,65 +497,258 @@ func (s *Service) GetTopScores(ctx context.Context, limit, offset int32) ([]stor
 		return nil, fmt.Errorf("%w: offset must be non-negative", ErrInvalidLimit)
 	}
 
+	if scores, ok, err := s.getTopScoresCached(ctx, board, limit, offset); err != nil {
+		return nil, err
+	} else if ok {
+		return scores, nil
+	}
+
 	scores, err := s.store.GetTopScores(ctx, store.GetTopScoresParams{
-		Limit:  limit,
-		Offset: offset,
+		LeaderboardID: board,
+		PageSize:      limit,
+		PageOffset:    offset,
 	})
 	if err != nil {
-		s.logger.Error().Err(err).Int32("limit", limit).Int32("offset", offset).Msg("failed to get top scores")
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Int32("limit", limit).Int32("offset", offset).Msg("failed to get top scores")
 		return nil, fmt.Errorf("get top scores: %w", err)
 	}
 
 	return scores, nil
 }
 
-// GetPlayerRank calculates and returns a player's rank
-func (s *Service) GetPlayerRank(ctx context.Context, playerName string) (int64, *store.Score, error) {
+// BoardStats summarizes the leaderboard for status reporting
+type BoardStats struct {
+	Players       int64
+	LastUpdatedAt time.Time // zero when the board is empty
+}
+
+// GetBoardStats returns the player count of a board and the time of its last best-score change
+func (s *Service) GetBoardStats(ctx context.Context, board string) (BoardStats, error) {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return BoardStats{}, err
+	}
+	row, err := s.store.GetScoreStats(ctx, board)
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to get score stats")
+		return BoardStats{}, fmt.Errorf("get score stats: %w", err)
+	}
+	stats := BoardStats{Players: row.Players}
+	if row.LastUpdatedAt.Valid {
+		stats.LastUpdatedAt = row.LastUpdatedAt.Time
+	}
+	return stats, nil
+}
+
+// PlayerRank is the rank of a player on a board
+type PlayerRank struct {
+	Rank           int64 // 1-based
+	Score          store.Score
+	RankingVariant string // RankingControl, or the variant of the ranking experiment
+}
+
+// GetPlayerRank calculates and returns a player's rank on a board. With a ranking
+// experiment, the rank may be computed by its variant.
+func (s *Service) GetPlayerRank(ctx context.Context, board, playerName string) (*PlayerRank, error) {
+	start := time.Now()
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
 	if err := s.validatePlayerName(playerName); err != nil {
-		return 0, nil, err
+		return nil, err
 	}
 
 	// First, check if player exists and get their score
-	score, err := s.store.GetPlayerScore(ctx, playerName)
+	score, err := s.store.GetPlayerScore(ctx, store.GetPlayerScoreParams{
+		LeaderboardID: board,
+		PlayerName:    playerName,
+	})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return 0, nil, ErrPlayerNotFound
+		if errors.Is(err, store.ErrNoRows) {
+			return nil, ErrPlayerNotFound
 		}
-		s.logger.Error().Err(err).Str("player", playerName).Msg("failed to get player score")
-		return 0, nil, fmt.Errorf("get player score: %w", err)
+		s.loggerFor(ctx).Error().Err(err).Str("player", playerName).Msg("failed to get player score")
+		return nil, fmt.Errorf("get player score: %w", err)
 	}
 
 	// Calculate rank
-	rank, err := s.store.GetPlayerRank(ctx, playerName)
+	variant := s.pickRankingVariant()
+	rank, err := s.getPlayerRankRanked(ctx, board, playerName, variant)
+	if err != nil {
+		return nil, err
+	}
+	observeRankRead(rankReadPlayerRank, variant, start)
+
+	return &PlayerRank{Rank: rank, Score: score, RankingVariant: variant}, nil
+}
+
+// PlayerRanks are the ranks of several players on a board
+type PlayerRanks struct {
+	Ranks    []PlayerRank // players with a score on the board, best rank first
+	NotFound []string     // requested players without a score on the board, in request order
+}
+
+// GetPlayerRanks ranks up to MaxPlayerRanks players of a board in a single query,
+// e.g. the members of a lobby on a match results screen. A name requested twice is
+// ranked once. Ranks are always computed in the control ranking.
+func (s *Service) GetPlayerRanks(ctx context.Context, board string, playerNames []string) (*PlayerRanks, error) {
+	start := time.Now()
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+	if len(playerNames) == 0 {
+		return nil, fmt.Errorf("%w: at least one player name is required", ErrInvalidPlayerName)
+	}
+	names := make([]string, 0, len(playerNames))
+	seen := make(map[string]bool, len(playerNames))
+	for _, name := range playerNames {
+		if seen[name] {
+			continue
+		}
+		if err := s.validatePlayerName(name); err != nil {
+			return nil, err
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) > MaxPlayerRanks {
+		return nil, fmt.Errorf("%w: %d players, max %d", ErrTooManyPlayers, len(names), MaxPlayerRanks)
+	}
+
+	rows, err := s.store.GetPlayerRanks(ctx, store.GetPlayerRanksParams{
+		LeaderboardID: board,
+		PlayerNames:   names,
+	})
 	if err != nil {
-		s.logger.Error().Err(err).Str("player", playerName).Msg("failed to get player rank")
-		return 0, nil, fmt.Errorf("get player rank: %w", err)
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Int("players", len(names)).Msg("failed to get player ranks")
+		return nil, fmt.Errorf("get player ranks: %w", err)
 	}
+	observeRankRead(rankReadPlayerRanks, RankingControl, start)
 
-	return int64(rank), &score, nil
+	out := &PlayerRanks{Ranks: make([]PlayerRank, len(rows))}
+	found := make(map[string]bool, len(rows))
+	for i, row := range rows {
+		out.Ranks[i] = PlayerRank{Rank: row.Rank, Score: row.Score, RankingVariant: RankingControl}
+		found[row.Score.PlayerName] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			out.NotFound = append(out.NotFound, name)
+		}
+	}
+	return out, nil
 }
 
-// DeleteScore removes a player's score entry
-func (s *Service) DeleteScore(ctx context.Context, playerName string) error {
+// DeleteScore soft-deletes a player's score entry from a board: it leaves the
+// board until RestoreScore, or until the player submits a new score
+func (s *Service) DeleteScore(ctx context.Context, board, playerName string) error {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return err
+	}
 	if err := s.validatePlayerName(playerName); err != nil {
 		return err
 	}
 
-	if err := s.store.DeleteScore(ctx, playerName); err != nil {
-		s.logger.Error().Err(err).Str("player", playerName).Msg("failed to delete score")
+	release, err := s.admit(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	deleted, err := s.store.DeleteScore(ctx, store.DeleteScoreParams{
+		LeaderboardID: board,
+		PlayerName:    playerName,
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Str("player", playerName).Msg("failed to delete score")
 		return fmt.Errorf("delete score: %w", err)
 	}
+	if deleted == 0 {
+		return ErrPlayerNotFound
+	}
 
-	s.logger.Info().Str("player", playerName).Msg("score deleted")
+	s.loggerFor(ctx).Info().Str("leaderboard", board).Str("player", playerName).Msg("score deleted")
 	return nil
 }
 
 func (s *Service) validatePlayerName(name string) error {
-	if len(name) < MinPlayerNameLength || len(name) > MaxPlayerNameLength {
+	minLen, maxLen := s.opts.Validation.nameBounds()
+	if len(name) < minLen || len(name) > maxLen {
 		return fmt.Errorf("%w: player name must be between %d and %d characters",
-			ErrInvalidPlayerName, MinPlayerNameLength, MaxPlayerNameLength)
+			ErrInvalidPlayerName, minLen, maxLen)
+	}
+	if pattern := s.opts.Validation.PlayerNamePattern; pattern != nil && !pattern.MatchString(name) {
+		return fmt.Errorf("%w: player name must match %s", ErrInvalidPlayerName, pattern)
+	}
+	return nil
+}
+
+func (s *Service) validateDeviceID(deviceID string) error {
+	if len(deviceID) > MaxDeviceIDLength {
+		return fmt.Errorf("%w: device id must be at most %d characters",
+			ErrInvalidDeviceID, MaxDeviceIDLength)
+	}
+	return nil
+}
+
+// checkDeviceLimits enforces the max-accounts and hourly submission limits for a device.
+// Submissions without a fingerprint, or with limits disabled, are always allowed.
+// The store counts and records in one transaction per device, so concurrent
+// submissions cannot all pass a limit that only one of them fits under.
+func (s *Service) checkDeviceLimits(ctx context.Context, deviceID, playerName string) error {
+	limits := s.currentDeviceLimits()
+	if limits.Mode == DeviceLimitModeOff || deviceID == "" {
+		return nil
+	}
+	metrics.DeviceSubmissions.Inc()
+
+	usage, err := s.store.CheckDeviceSubmission(ctx, store.DeviceSubmissionCheck{
+		DeviceHash:     deviceID,
+		PlayerName:     playerName,
+		MaxAccounts:    limits.MaxAccounts,
+		MaxSubmissions: limits.MaxSubmissionsPerHour,
+		Enforce:        limits.Mode == DeviceLimitModeEnforce,
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("player", playerName).Msg("failed to check device limits")
+		return fmt.Errorf("check device limits: %w", err)
+	}
+	if usage.AccountsExceeded {
+		if err := s.deviceLimitViolation(ctx, limits.Mode, "accounts", deviceID, playerName,
+			fmt.Sprintf("device already used by %d accounts", usage.Accounts)); err != nil {
+			return err
+		}
+	}
+	if usage.SubmissionsExceeded {
+		if err := s.deviceLimitViolation(ctx, limits.Mode, "rate", deviceID, playerName,
+			fmt.Sprintf("%d submissions in the last hour", usage.Submissions)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deviceLimitViolation reports a violated device limit and returns an error only in enforce mode
+func (s *Service) deviceLimitViolation(ctx context.Context, mode, limit, deviceID, playerName, detail string) error {
+	action := "flagged"
+	if mode == DeviceLimitModeEnforce {
+		action = "rejected"
+	}
+	metrics.DeviceLimitViolations.WithLabelValues(limit, action).Inc()
+
+	s.loggerFor(ctx).Warn().
+		Str("limit", limit).
+		Str("action", action).
+		Str("device", deviceID).
+		Str("player", playerName).
+		Msg(detail)
+
+	if action == "rejected" {
+		return fmt.Errorf("%w: %s", ErrDeviceLimitExceeded, detail)
 	}
-	// Additional validation could be added here (e.g., character set restrictions)
 	return nil
 }
 
@@ -170,5 +756,8 @@ func (s *Service) validateScore(score int64) error {
 	if score < 0 {
 		return fmt.Errorf("%w: score must be non-negative", ErrInvalidScore)
 	}
+	if maxScore := s.opts.Validation.MaxScore; maxScore > 0 && score > maxScore {
+		return fmt.Errorf("%w: score must be at most %d", ErrInvalidScore, maxScore)
+	}
 	return nil
 }
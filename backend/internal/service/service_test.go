@@ -1,7 +1,17 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
 )
 
 func TestValidatePlayerName(t *testing.T) {
@@ -94,6 +104,49 @@ func TestValidateScore(t *testing.T) {
 	}
 }
 
+func TestValidationOptions(t *testing.T) {
+	s := &Service{opts: Options{Validation: Validation{
+		MinPlayerNameLength: 3,
+		MaxPlayerNameLength: 24,
+		MaxScore:            1_000_000,
+		PlayerNamePattern:   regexp.MustCompile(`^[A-Za-z0-9_]+$`),
+	}}}
+
+	names := []struct {
+		input     string
+		wantError bool
+	}{
+		{"Alice", false},
+		{"Al", true},
+		{"A_Name_Of_24_Characters_", false},
+		{"A_Name_Of_25_Characters__", true},
+		{"Bad Name", true},
+		{"Zoë", true},
+	}
+	for _, tt := range names {
+		err := s.validatePlayerName(tt.input)
+		if (err != nil) != tt.wantError || (err != nil && !errors.Is(err, ErrInvalidPlayerName)) {
+			t.Errorf("validatePlayerName(%q) error = %v, wantError %v", tt.input, err, tt.wantError)
+		}
+	}
+
+	scores := []struct {
+		input     int64
+		wantError bool
+	}{
+		{0, false},
+		{1_000_000, false},
+		{1_000_001, true},
+		{-1, true},
+	}
+	for _, tt := range scores {
+		err := s.validateScore(tt.input)
+		if (err != nil) != tt.wantError || (err != nil && !errors.Is(err, ErrInvalidScore)) {
+			t.Errorf("validateScore(%d) error = %v, wantError %v", tt.input, err, tt.wantError)
+		}
+	}
+}
+
 func TestMaxPlayerNameLength(t *testing.T) {
 	// Ensure the constant matches requirements
 	if MaxPlayerNameLength != 20 {
@@ -107,3 +160,476 @@ func TestMinPlayerNameLength(t *testing.T) {
 		t.Errorf("MinPlayerNameLength = %d, want 1", MinPlayerNameLength)
 	}
 }
+
+func TestNormalizePercentileBuckets(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []float64
+		want  []float64
+	}{
+		{
+			name:  "empty uses defaults",
+			input: nil,
+			want:  DefaultPercentileBuckets,
+		},
+		{
+			name:  "unsorted input is sorted",
+			input: []float64{25, 1, 10},
+			want:  []float64{1, 10, 25},
+		},
+		{
+			name:  "duplicates are removed",
+			input: []float64{5, 5, 1},
+			want:  []float64{1, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizePercentileBuckets(tt.input)
+			if len(got) != len(tt.want) {
+				t.Fatalf("normalizePercentileBuckets(%v) = %v, want %v", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("normalizePercentileBuckets(%v) = %v, want %v", tt.input, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	logger := zerolog.Nop()
+	s := New(nil, &logger, Options{
+		Admission: Admission{MaxConcurrent: 1, MaxWait: 10 * time.Millisecond, RetryAfter: time.Second},
+	})
+	ctx := context.Background()
+
+	release, err := s.admit(ctx)
+	if err != nil {
+		t.Fatalf("first admit: unexpected error %v", err)
+	}
+
+	if _, err := s.admit(ctx); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("admit while saturated: error = %v, want ErrOverloaded", err)
+	}
+
+	release()
+	release, err = s.admit(ctx)
+	if err != nil {
+		t.Fatalf("admit after release: unexpected error %v", err)
+	}
+	release()
+
+	unlimited := New(nil, &logger, Options{})
+	for i := 0; i < 3; i++ {
+		if _, err := unlimited.admit(ctx); err != nil {
+			t.Fatalf("admit with admission disabled: unexpected error %v", err)
+		}
+	}
+}
+
+func TestResolveAchievedAt(t *testing.T) {
+	logger := zerolog.Nop()
+	s := New(nil, &logger, Options{
+		ClientTimestamps: ClientTimestamps{SkewWindow: 2 * time.Minute, MaxAge: 24 * time.Hour},
+	})
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		clientAt   time.Time
+		want       time.Time
+		wantClient bool
+	}{
+		{name: "no client timestamp", clientAt: time.Time{}, want: now, wantClient: false},
+		{name: "recent past", clientAt: now.Add(-time.Hour), want: now.Add(-time.Hour), wantClient: true},
+		{name: "within skew window", clientAt: now.Add(time.Minute), want: now, wantClient: true},
+		{name: "beyond skew window", clientAt: now.Add(time.Hour), want: now, wantClient: true},
+		{name: "older than max age", clientAt: now.Add(-48 * time.Hour), want: now, wantClient: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, client := s.resolveAchievedAt(context.Background(), tt.clientAt, now)
+			if !got.Equal(tt.want) {
+				t.Errorf("resolveAchievedAt() = %v, want %v", got, tt.want)
+			}
+			if client.Valid != tt.wantClient {
+				t.Errorf("resolveAchievedAt() client.Valid = %v, want %v", client.Valid, tt.wantClient)
+			}
+		})
+	}
+}
+
+// fakeRepository keeps the best scores of every board in memory, ranked on
+// 'desc' boards. It is stateful rather than a generated mock so that a test
+// can submit, rank and delete across calls and assert on the outcome, not on
+// the call sequence. Queries a test does not use panic through the nil
+// embedded interface.
+type fakeRepository struct {
+	store.Repository
+
+	scores map[string]store.Score // by board and player name
+	err    error                  // returned by every query when set
+
+	devicePlayers     map[string]map[string]bool // players seen on each device
+	deviceSubmissions map[string]int64           // submissions recorded per device, all within the window
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		scores:            make(map[string]store.Score),
+		devicePlayers:     make(map[string]map[string]bool),
+		deviceSubmissions: make(map[string]int64),
+	}
+}
+
+func (r *fakeRepository) UpsertScores(_ context.Context, rows []store.UpsertScoreParams) ([]store.UpsertedScore, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	out := make([]store.UpsertedScore, len(rows))
+	for i, row := range rows {
+		key := row.LeaderboardID + "/" + row.PlayerName
+		sc := store.Score{
+			LeaderboardID:  row.LeaderboardID,
+			PlayerName:     row.PlayerName,
+			Score:          row.Score,
+			RankScore:      row.Score,
+			SecondaryScore: row.SecondaryScore,
+			RankSecondary:  row.SecondaryScore,
+			AchievedAt:     row.AchievedAt,
+			Metadata:       row.Metadata,
+		}
+		prev, ok := r.scores[key]
+		switch {
+		case !ok:
+			out[i] = store.UpsertedScore{Score: sc, Applied: true}
+		case store.Outranks(sc, prev):
+			out[i] = store.UpsertedScore{Score: sc, Previous: &prev, Applied: true}
+		default:
+			out[i] = store.UpsertedScore{Score: prev, Previous: &prev}
+			continue
+		}
+		r.scores[key] = sc
+	}
+	return out, nil
+}
+
+func (r *fakeRepository) UpsertScoreRanked(ctx context.Context, row store.UpsertScoreParams) (store.RankedScore, error) {
+	var out store.RankedScore
+	if out.Previous = r.score(row.LeaderboardID, row.PlayerName); out.Previous != nil {
+		out.PreviousRank = r.rank(row.LeaderboardID, *out.Previous)
+	}
+	upserted, err := r.UpsertScores(ctx, []store.UpsertScoreParams{row})
+	if err != nil {
+		return store.RankedScore{}, err
+	}
+	out.UpsertedScore = upserted[0]
+	out.Rank = r.rank(row.LeaderboardID, out.Score)
+	return out, nil
+}
+
+func (r *fakeRepository) GetPlayerScore(_ context.Context, arg store.GetPlayerScoreParams) (store.Score, error) {
+	if r.err != nil {
+		return store.Score{}, r.err
+	}
+	sc := r.score(arg.LeaderboardID, arg.PlayerName)
+	if sc == nil {
+		return store.Score{}, store.ErrNoRows
+	}
+	return *sc, nil
+}
+
+func (r *fakeRepository) GetPlayerRank(_ context.Context, arg store.GetPlayerRankParams) (int32, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	sc := r.score(arg.LeaderboardID, arg.PlayerName)
+	if sc == nil {
+		return 0, store.ErrNoRows
+	}
+	return r.rank(arg.LeaderboardID, *sc), nil
+}
+
+func (r *fakeRepository) DeleteScore(_ context.Context, arg store.DeleteScoreParams) (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	key := arg.LeaderboardID + "/" + arg.PlayerName
+	if _, ok := r.scores[key]; !ok {
+		return 0, nil
+	}
+	delete(r.scores, key)
+	return 1, nil
+}
+
+func (r *fakeRepository) CheckDeviceSubmission(_ context.Context, arg store.DeviceSubmissionCheck) (store.DeviceUsage, error) {
+	if r.err != nil {
+		return store.DeviceUsage{}, r.err
+	}
+	var usage store.DeviceUsage
+	players := r.devicePlayers[arg.DeviceHash]
+	if arg.MaxAccounts > 0 && !players[arg.PlayerName] {
+		usage.Accounts = int64(len(players))
+		usage.AccountsExceeded = usage.Accounts >= int64(arg.MaxAccounts)
+	}
+	if arg.MaxSubmissions > 0 {
+		usage.Submissions = r.deviceSubmissions[arg.DeviceHash]
+		usage.SubmissionsExceeded = usage.Submissions >= int64(arg.MaxSubmissions)
+	}
+	if arg.Enforce && usage.Exceeded() {
+		return usage, nil
+	}
+	if players == nil {
+		players = make(map[string]bool)
+		r.devicePlayers[arg.DeviceHash] = players
+	}
+	players[arg.PlayerName] = true
+	r.deviceSubmissions[arg.DeviceHash]++
+	usage.Recorded = true
+	return usage, nil
+}
+
+// GetBannedPlayer finds no ban: the fake has no banned players
+func (r *fakeRepository) GetBannedPlayer(_ context.Context, _ string) (store.BannedPlayer, error) {
+	if r.err != nil {
+		return store.BannedPlayer{}, r.err
+	}
+	return store.BannedPlayer{}, store.ErrNoRows
+}
+
+// score returns a player's best score on a board, nil when there is none
+func (r *fakeRepository) score(board, playerName string) *store.Score {
+	if sc, ok := r.scores[board+"/"+playerName]; ok {
+		return &sc
+	}
+	return nil
+}
+
+// rank returns the 1-based rank of sc among the scores of a board
+func (r *fakeRepository) rank(board string, sc store.Score) int32 {
+	rank := int32(1)
+	for _, other := range r.scores {
+		if other.LeaderboardID == board && store.Outranks(other, sc) {
+			rank++
+		}
+	}
+	return rank
+}
+
+func TestSubmitScoreApplied(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+
+	for _, submitRank := range []bool{false, true} {
+		repo := newFakeRepository()
+		s := New(repo, &logger, Options{SubmitRank: submitRank})
+		if _, err := s.SubmitScore(ctx, ScoreSubmission{PlayerName: "Bob", Score: 300}); err != nil {
+			t.Fatal(err)
+		}
+
+		steps := []struct {
+			score, secondary int64
+			wantApplied      bool
+			wantScore        int64
+			wantRank         int64
+			wantDelta        int64
+		}{
+			{100, 0, true, 100, 2, 0},  // first score
+			{50, 0, false, 100, 2, 0},  // lower
+			{100, 0, false, 100, 2, 0}, // equal
+			{100, 3, true, 100, 2, 0},  // better tiebreaker
+			{400, 0, true, 400, 1, 1},  // overtakes Bob
+		}
+		for i, step := range steps {
+			res, err := s.SubmitScore(ctx, ScoreSubmission{PlayerName: "Alice", Score: step.score, SecondaryScore: step.secondary})
+			if err != nil {
+				t.Fatalf("submit rank %v, step %d: %v", submitRank, i, err)
+			}
+			if res.Applied != step.wantApplied || res.Score != step.wantScore || res.LeaderboardID != DefaultLeaderboardID {
+				t.Errorf("submit rank %v, step %d: applied %v, score %d on %s, want %v, %d on %s",
+					submitRank, i, res.Applied, res.Score, res.LeaderboardID, step.wantApplied, step.wantScore, DefaultLeaderboardID)
+			}
+			wantRank, wantDelta := step.wantRank, step.wantDelta
+			if !submitRank {
+				wantRank, wantDelta = 0, 0
+			}
+			if res.Rank != wantRank || res.RankDelta != wantDelta {
+				t.Errorf("submit rank %v, step %d: rank %d (%+d), want %d (%+d)", submitRank, i, res.Rank, res.RankDelta, wantRank, wantDelta)
+			}
+		}
+	}
+}
+
+func TestSubmitScoreErrors(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	repo := newFakeRepository()
+	s := New(repo, &logger, Options{})
+
+	for _, sub := range []ScoreSubmission{
+		{PlayerName: "", Score: 100},
+		{PlayerName: "Alice", Score: -1},
+		{PlayerName: "Alice", Score: 100, LeaderboardID: "no spaces"},
+	} {
+		if _, err := s.SubmitScore(ctx, sub); err == nil {
+			t.Errorf("SubmitScore(%+v) succeeded", sub)
+		}
+	}
+	if len(repo.scores) != 0 {
+		t.Errorf("invalid submissions wrote %v", repo.scores)
+	}
+
+	repo.err = errors.New("connection reset")
+	if _, err := s.SubmitScore(ctx, ScoreSubmission{PlayerName: "Alice", Score: 100}); !errors.Is(err, repo.err) {
+		t.Errorf("store failure: error = %v, want %v", err, repo.err)
+	}
+}
+
+func TestDeviceLimits(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+
+	for _, mode := range []string{DeviceLimitModeEnforce, DeviceLimitModeMonitor} {
+		repo := newFakeRepository()
+		s := New(repo, &logger, Options{DeviceLimits: DeviceLimits{Mode: mode, MaxAccounts: 2, MaxSubmissionsPerHour: 4}})
+		submit := func(name string) error {
+			_, err := s.SubmitScore(ctx, ScoreSubmission{PlayerName: name, Score: 100, DeviceID: "device-1"})
+			return err
+		}
+		wantLimited := func(step string, err error) {
+			t.Helper()
+			if mode == DeviceLimitModeEnforce && !errors.Is(err, ErrDeviceLimitExceeded) {
+				t.Errorf("%s, %s: error = %v, want ErrDeviceLimitExceeded", mode, step, err)
+			}
+			if mode == DeviceLimitModeMonitor && err != nil {
+				t.Errorf("%s, %s: error = %v, want the submission allowed", mode, step, err)
+			}
+		}
+
+		for _, name := range []string{"Alice", "Bob"} {
+			if err := submit(name); err != nil {
+				t.Fatalf("%s, %s: %v", mode, name, err)
+			}
+		}
+		wantLimited("third account", submit("Carol"))
+		if _, ok := repo.scores[DefaultLeaderboardID+"/Carol"]; ok == (mode == DeviceLimitModeEnforce) {
+			t.Errorf("%s: third account written %v", mode, ok)
+		}
+
+		// Known accounts only count against the hourly limit
+		submitted := repo.deviceSubmissions["device-1"]
+		for ; submitted < 4; submitted++ {
+			if err := submit("Alice"); err != nil {
+				t.Fatalf("%s, submission %d: %v", mode, submitted+1, err)
+			}
+		}
+		wantLimited("fifth submission", submit("Bob"))
+
+		// Submissions without a device are not limited
+		if _, err := s.SubmitScore(ctx, ScoreSubmission{PlayerName: "Dave", Score: 100}); err != nil {
+			t.Errorf("%s, no device: %v", mode, err)
+		}
+	}
+}
+
+func TestGetPlayerRankNotFound(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	repo := newFakeRepository()
+	s := New(repo, &logger, Options{})
+	for name, score := range map[string]int64{"Alice": 300, "Bob": 200} {
+		if _, err := s.SubmitScore(ctx, ScoreSubmission{PlayerName: name, Score: score}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rank, err := s.GetPlayerRank(ctx, "", "Bob")
+	if err != nil || rank.Rank != 2 || rank.Score.Score != 200 || rank.RankingVariant != RankingControl {
+		t.Errorf("GetPlayerRank(Bob) = %+v, %v, want rank 2 with 200", rank, err)
+	}
+	if _, err := s.GetPlayerRank(ctx, "", "Carol"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("unknown player: error = %v, want ErrPlayerNotFound", err)
+	}
+	if _, err := s.GetPlayerRank(ctx, "level-1", "Alice"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("player of another board: error = %v, want ErrPlayerNotFound", err)
+	}
+
+	repo.err = errors.New("connection reset")
+	if _, err := s.GetPlayerRank(ctx, "", "Alice"); errors.Is(err, ErrPlayerNotFound) || !errors.Is(err, repo.err) {
+		t.Errorf("store failure: error = %v, want %v", err, repo.err)
+	}
+}
+
+func TestDeleteScoreNotFound(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	repo := newFakeRepository()
+	s := New(repo, &logger, Options{})
+	if _, err := s.SubmitScore(ctx, ScoreSubmission{PlayerName: "Alice", Score: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteScore(ctx, "", "Alice"); err != nil {
+		t.Fatalf("DeleteScore: %v", err)
+	}
+	if err := s.DeleteScore(ctx, "", "Alice"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("second delete: error = %v, want ErrPlayerNotFound", err)
+	}
+	if err := s.DeleteScore(ctx, "", ""); !errors.Is(err, ErrInvalidPlayerName) {
+		t.Errorf("no player: error = %v, want ErrInvalidPlayerName", err)
+	}
+
+	repo.err = errors.New("connection reset")
+	if err := s.DeleteScore(ctx, "", "Bob"); errors.Is(err, ErrPlayerNotFound) || !errors.Is(err, repo.err) {
+		t.Errorf("store failure: error = %v, want %v", err, repo.err)
+	}
+}
+
+func TestGetPlayerRanks(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	s := New(st, &logger, Options{})
+	for name, score := range map[string]int64{"Alice": 300, "Bob": 200, "Carol": 100} {
+		if _, err := s.SubmitScore(ctx, ScoreSubmission{PlayerName: name, Score: score}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ranks, err := s.GetPlayerRanks(ctx, "", []string{"Carol", "Nobody", "Alice", "Carol", "Newcomer"})
+	if err != nil {
+		t.Fatalf("GetPlayerRanks: %v", err)
+	}
+	if len(ranks.Ranks) != 2 || ranks.Ranks[0].Rank != 1 || ranks.Ranks[0].Score.PlayerName != "Alice" ||
+		ranks.Ranks[1].Rank != 3 || ranks.Ranks[1].Score.Score != 100 || ranks.Ranks[1].RankingVariant != RankingControl {
+		t.Errorf("ranks = %+v, want Alice 1 then Carol 3", ranks.Ranks)
+	}
+	if want := []string{"Nobody", "Newcomer"}; !slices.Equal(ranks.NotFound, want) {
+		t.Errorf("not found = %v, want %v", ranks.NotFound, want)
+	}
+
+	if _, err := s.GetPlayerRanks(ctx, "", nil); !errors.Is(err, ErrInvalidPlayerName) {
+		t.Errorf("no players: error = %v, want %v", err, ErrInvalidPlayerName)
+	}
+	if _, err := s.GetPlayerRanks(ctx, "", []string{"Alice", ""}); !errors.Is(err, ErrInvalidPlayerName) {
+		t.Errorf("empty name: error = %v, want %v", err, ErrInvalidPlayerName)
+	}
+	names := make([]string, MaxPlayerRanks+1)
+	for i := range names {
+		names[i] = fmt.Sprintf("P%d", i)
+	}
+	if _, err := s.GetPlayerRanks(ctx, "", names); !errors.Is(err, ErrTooManyPlayers) {
+		t.Errorf("%d players: error = %v, want %v", len(names), err, ErrTooManyPlayers)
+	}
+	if _, err := s.GetPlayerRanks(ctx, "", append(names[:MaxPlayerRanks], "P0")); err != nil {
+		t.Errorf("%d players with a repeated name: %v", MaxPlayerRanks, err)
+	}
+}
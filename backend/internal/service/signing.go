@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+// submitSignatureVersion prefixes the canonical submission so the format can evolve
+const submitSignatureVersion = "leaderboard-submit-v1"
+
+// boardSubmitSignatureVersion prefixes canonical submissions to boards other than
+// the default one, which also sign the leaderboard id
+const boardSubmitSignatureVersion = "leaderboard-submit-v2"
+
+// MaxNonceLength is the maximum length of a submission nonce
+const MaxNonceLength = 64
+
+// Submission signing modes
+const (
+	SigningModeOff     = "off"     // signatures are ignored
+	SigningModeMonitor = "monitor" // bad or missing signatures are logged and counted but allowed
+	SigningModeEnforce = "enforce" // bad or missing signatures are rejected
+)
+
+// SubmitSigning configures HMAC verification of score submissions
+type SubmitSigning struct {
+	Mode string
+
+	// Key is the shared HMAC-SHA256 secret embedded in the game client
+	Key []byte
+
+	// MaxAge is how far signed_at may be from server time, and how long nonces are remembered
+	MaxAge time.Duration
+}
+
+// CanonicalSubmission returns the bytes a client signs for SubmitScore:
+// a version line then player_name, score, nonce and signed_at (Unix seconds),
+// each on its own line. Submissions to a board other than the default one use
+// version 2, with the leaderboard id on the line after the version, so a signed
+// score cannot be replayed on another board.
+func CanonicalSubmission(board, playerName string, score int64, nonce string, signedAt int64) []byte {
+	lines := []string{submitSignatureVersion}
+	if board != "" && board != DefaultLeaderboardID {
+		lines = []string{boardSubmitSignatureVersion, board}
+	}
+	return []byte(strings.Join(append(lines,
+		playerName,
+		strconv.FormatInt(score, 10),
+		nonce,
+		strconv.FormatInt(signedAt, 10),
+	), "\n") + "\n")
+}
+
+// SignSubmission computes the signature of a score submission with key
+func SignSubmission(key []byte, board, playerName string, score int64, nonce string, signedAt int64) string {
+	return signHMAC(key, CanonicalSubmission(board, playerName, score, nonce, signedAt))
+}
+
+// signHMAC returns the hex HMAC-SHA256 of msg
+func signHMAC(key, msg []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyHMAC checks a hex HMAC-SHA256 signature of msg in constant time
+func verifyHMAC(key, msg []byte, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// checkSignature verifies the HMAC, freshness and nonce of a submission.
+// In monitor mode failures are only logged and counted.
+func (s *Service) checkSignature(ctx context.Context, sub ScoreSubmission, now time.Time) error {
+	cfg := s.opts.SubmitSigning
+	if cfg.Mode == SigningModeOff {
+		return nil
+	}
+
+	result, detail := s.verifySubmission(sub, now)
+	action := "accepted"
+	if result != "valid" && cfg.Mode == SigningModeEnforce {
+		action = "rejected"
+	}
+	metrics.SubmissionSignatures.WithLabelValues(result, action).Inc()
+
+	if result == "valid" {
+		return nil
+	}
+	s.loggerFor(ctx).Warn().
+		Str("result", result).
+		Str("action", action).
+		Str("player", sub.PlayerName).
+		Msg(detail)
+
+	if action == "rejected" {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, detail)
+	}
+	return nil
+}
+
+// verifySubmission classifies a submission signature as valid, missing, invalid, expired or replayed
+func (s *Service) verifySubmission(sub ScoreSubmission, now time.Time) (result, detail string) {
+	cfg := s.opts.SubmitSigning
+	if sub.Signature == "" || sub.Nonce == "" {
+		return "missing", "submission is not signed"
+	}
+	if len(sub.Nonce) > MaxNonceLength {
+		return "invalid", fmt.Sprintf("nonce exceeds %d characters", MaxNonceLength)
+	}
+	if !verifyHMAC(cfg.Key, CanonicalSubmission(sub.LeaderboardID, sub.PlayerName, sub.Score, sub.Nonce, sub.SignedAt), sub.Signature) {
+		return "invalid", "signature does not match submission"
+	}
+	signedAt := time.Unix(sub.SignedAt, 0)
+	if signedAt.Before(now.Add(-cfg.MaxAge)) || signedAt.After(now.Add(cfg.MaxAge)) {
+		return "expired", "signed_at is outside the accepted window"
+	}
+	if !s.nonces.add(sub.PlayerName+"\x00"+sub.Nonce, now, cfg.MaxAge) {
+		return "replayed", "nonce was already used"
+	}
+	return "valid", ""
+}
+
+// nonceCache remembers recently used nonces to reject replayed submissions.
+// Entries only need to outlive the signed_at window: older replays fail as expired.
+type nonceCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time // nonce -> expiry
+	lastPrune time.Time
+}
+
+// add records a nonce and reports false if it is already known
+func (c *nonceCache) add(nonce string, now time.Time, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.seen == nil {
+		c.seen = make(map[string]time.Time)
+	}
+	if now.Sub(c.lastPrune) > ttl {
+		for n, expiry := range c.seen {
+			if now.After(expiry) {
+				delete(c.seen, n)
+			}
+		}
+		c.lastPrune = now
+	}
+
+	if expiry, ok := c.seen[nonce]; ok && now.Before(expiry) {
+		return false
+	}
+	// signed_at may be up to ttl in the future, so keep the nonce for both sides of the window
+	c.seen[nonce] = now.Add(2 * ttl)
+	return true
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func newSigningTestService(t *testing.T, mode string) *Service {
+	t.Helper()
+	st, err := sqlite.Open(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(st.Close)
+
+	logger := zerolog.Nop()
+	return New(st, &logger, Options{
+		SubmitSigning: SubmitSigning{Mode: mode, Key: []byte("test-key"), MaxAge: time.Minute},
+	})
+}
+
+func TestSubmitScoreSignature(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	signed := func(player string, score int64, nonce string, signedAt int64) ScoreSubmission {
+		return ScoreSubmission{
+			PlayerName: player,
+			Score:      score,
+			Nonce:      nonce,
+			SignedAt:   signedAt,
+			Signature:  SignSubmission([]byte("test-key"), "", player, score, nonce, signedAt),
+		}
+	}
+	forged := signed("Alice", 100, "n-forged", now)
+	forged.Score = 1_000_000
+
+	tests := []struct {
+		name    string
+		sub     ScoreSubmission
+		wantErr bool // rejected in enforce mode; monitor mode accepts everything
+	}{
+		{name: "valid", sub: signed("Alice", 100, "n1", now)},
+		{name: "unsigned", sub: ScoreSubmission{PlayerName: "Alice", Score: 100}, wantErr: true},
+		{name: "tampered score", sub: forged, wantErr: true},
+		{name: "wrong key", sub: ScoreSubmission{PlayerName: "Alice", Score: 100, Nonce: "n2", SignedAt: now,
+			Signature: SignSubmission([]byte("other"), "", "Alice", 100, "n2", now)}, wantErr: true},
+		{name: "expired", sub: signed("Alice", 100, "n3", now-120), wantErr: true},
+		{name: "future", sub: signed("Alice", 100, "n4", now+120), wantErr: true},
+		{name: "nonce too long", sub: signed("Alice", 100, string(make([]byte, MaxNonceLength+1)), now), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enforce := newSigningTestService(t, SigningModeEnforce)
+			_, err := enforce.SubmitScore(ctx, tt.sub)
+			if tt.wantErr && !errors.Is(err, ErrInvalidSignature) || !tt.wantErr && err != nil {
+				t.Errorf("enforce: SubmitScore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			monitor := newSigningTestService(t, SigningModeMonitor)
+			if _, err := monitor.SubmitScore(ctx, tt.sub); err != nil {
+				t.Errorf("monitor: SubmitScore() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestSubmitScoreSignatureReplay(t *testing.T) {
+	ctx := context.Background()
+	svc := newSigningTestService(t, SigningModeEnforce)
+	now := time.Now().Unix()
+	sub := ScoreSubmission{
+		PlayerName: "Alice",
+		Score:      100,
+		Nonce:      "n1",
+		SignedAt:   now,
+		Signature:  SignSubmission([]byte("test-key"), "", "Alice", 100, "n1", now),
+	}
+
+	if _, err := svc.SubmitScore(ctx, sub); err != nil {
+		t.Fatalf("first submit: %v", err)
+	}
+	if _, err := svc.SubmitScore(ctx, sub); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("replayed submit error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	// The same nonce is independent per player
+	other := sub
+	other.PlayerName = "Bob"
+	other.Signature = SignSubmission([]byte("test-key"), "", "Bob", 100, "n1", now)
+	if _, err := svc.SubmitScore(ctx, other); err != nil {
+		t.Errorf("other player with same nonce: %v", err)
+	}
+}
+
+func TestSubmitScoreSignatureBoard(t *testing.T) {
+	ctx := context.Background()
+	svc := newSigningTestService(t, SigningModeEnforce)
+	now := time.Now().Unix()
+	sub := ScoreSubmission{
+		LeaderboardID: "level-1",
+		PlayerName:    "Alice",
+		Score:         100,
+		Nonce:         "n1",
+		SignedAt:      now,
+		Signature:     SignSubmission([]byte("test-key"), "level-1", "Alice", 100, "n1", now),
+	}
+
+	// Redirected to another board, the signature no longer matches
+	moved := sub
+	moved.LeaderboardID = "level-2"
+	if _, err := svc.SubmitScore(ctx, moved); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("submit to another board error = %v, want %v", err, ErrInvalidSignature)
+	}
+	if _, err := svc.SubmitScore(ctx, sub); err != nil {
+		t.Errorf("submit to signed board: %v", err)
+	}
+
+	// The default board keeps the v1 format, whether named or not
+	if string(CanonicalSubmission("", "Alice", 1, "n", 2)) != string(CanonicalSubmission(DefaultLeaderboardID, "Alice", 1, "n", 2)) {
+		t.Error("canonical submission differs between empty and default board")
+	}
+}
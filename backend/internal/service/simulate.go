@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// RankSimulation is where a hypothetical score would place on the leaderboard
+type RankSimulation struct {
+	Score        int64
+	Rank         int64 // 1-based rank the score would get
+	TotalPlayers int64 // board size including the simulating player
+
+	// PointsToNextRank is how many points better the score would need to be to gain
+	// one place (0 at rank 1): more points on descending boards, fewer on ascending ones
+	PointsToNextRank int64
+
+	Tier string // tier the score would fall in, empty if tiers are disabled
+}
+
+// SimulateRank computes the rank a score would achieve on a board without persisting
+// anything, as if it were the player's best. playerName is optional: when set, the
+// player's current entry is left out so they are not counted against themselves.
+// Within a segment, only the players of the segment are counted.
+func (s *Service) SimulateRank(ctx context.Context, board string, score int64, playerName string, seg Segment) (*RankSimulation, error) {
+	board, err := ResolveLeaderboardID(board)
+	if err != nil {
+		return nil, err
+	}
+	if seg, err = normalizeSegment(seg); err != nil {
+		return nil, err
+	}
+	if err := s.validateScore(score); err != nil {
+		return nil, err
+	}
+	if playerName != "" {
+		if err := s.validatePlayerName(playerName); err != nil {
+			return nil, err
+		}
+	}
+
+	order, err := s.sortOrder(ctx, board)
+	if err != nil {
+		return nil, err
+	}
+	rankScore := order.RankScore(score)
+
+	row, err := s.store.SimulateRank(ctx, store.SimulateRankParams{
+		LeaderboardID: board,
+		RankScore:     rankScore,
+		PlayerName:    playerName,
+		CountryCode:   seg.Region,
+		Platform:      seg.Platform,
+	})
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Int64("score", score).Msg("failed to simulate rank")
+		return nil, fmt.Errorf("simulate rank: %w", err)
+	}
+
+	sim := &RankSimulation{
+		Score:        score,
+		Rank:         row.Ahead + 1,
+		TotalPlayers: row.Total + 1,
+		Tier:         s.TierFor(board, score),
+	}
+	if row.Ahead > 0 {
+		// Beating the lowest score ahead (ties go to the earlier score) gains a place
+		sim.PointsToNextRank = row.NextRankScore - rankScore + 1
+	}
+	return sim, nil
+}
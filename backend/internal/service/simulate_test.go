@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestSimulateRank(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{})
+
+	for name, score := range map[string]int64{"A": 500, "B": 400, "C": 300} {
+		if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: name, Score: score}); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		score      int64
+		player     string
+		wantRank   int64
+		wantTotal  int64
+		wantToNext int64
+	}{
+		{name: "top", score: 600, wantRank: 1, wantTotal: 4},
+		{name: "between", score: 350, wantRank: 3, wantTotal: 4, wantToNext: 51},
+		{name: "tie ranks behind", score: 400, wantRank: 3, wantTotal: 4, wantToNext: 1},
+		{name: "last", score: 0, wantRank: 4, wantTotal: 4, wantToNext: 301},
+		{name: "own entry excluded", score: 350, player: "B", wantRank: 2, wantTotal: 3, wantToNext: 151},
+		{name: "existing player beating own best", score: 600, player: "A", wantRank: 1, wantTotal: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, err := svc.SimulateRank(ctx, "", tt.score, tt.player, Segment{})
+			if err != nil {
+				t.Fatalf("SimulateRank() error = %v", err)
+			}
+			if sim.Rank != tt.wantRank || sim.TotalPlayers != tt.wantTotal || sim.PointsToNextRank != tt.wantToNext {
+				t.Errorf("SimulateRank() = rank %d/%d, %d to next, want rank %d/%d, %d to next",
+					sim.Rank, sim.TotalPlayers, sim.PointsToNextRank, tt.wantRank, tt.wantTotal, tt.wantToNext)
+			}
+		})
+	}
+
+	if _, err := svc.SimulateRank(ctx, "", -1, "", Segment{}); !errors.Is(err, ErrInvalidScore) {
+		t.Errorf("negative score error = %v, want %v", err, ErrInvalidScore)
+	}
+
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/requestctx"
+)
+
+// SubmissionEvent describes one score written through SubmitScore or
+// SyncOfflineScores, applied or not, for downstream analytics
+type SubmissionEvent struct {
+	LeaderboardID  string
+	PlayerName     string
+	SubmittedScore int64 // score sent by the client
+	OldScore       int64 // best score before the submission, when HadScore
+	HadScore       bool
+	NewScore       int64 // best score after the submission
+	Applied        bool  // the submission created or improved the best score
+	AchievedAt     time.Time
+	SubmittedAt    time.Time
+	RequestID      string
+	Tenant         string
+}
+
+// SubmissionSink receives an event for every score submission. Submitted is
+// called on the write path after the score is stored, so implementations must
+// not block: they are expected to buffer and ship events in the background.
+type SubmissionSink interface {
+	Submitted(event SubmissionEvent)
+}
+
+// emitSubmitted hands a submission to the sink, if one is configured
+func (s *Service) emitSubmitted(ctx context.Context, event SubmissionEvent) {
+	if s.opts.Submissions == nil {
+		return
+	}
+	info := requestctx.FromContext(ctx)
+	event.RequestID, event.Tenant = info.RequestID, info.Tenant
+	event.SubmittedAt = time.Now().UTC()
+	s.opts.Submissions.Submitted(event)
+}
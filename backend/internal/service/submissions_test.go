@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+// recordingSink collects submission events
+type recordingSink []SubmissionEvent
+
+func (r *recordingSink) Submitted(event SubmissionEvent) { *r = append(*r, event) }
+
+func TestSubmissionEvents(t *testing.T) {
+	ctx := requestctx.NewContext(context.Background(), requestctx.Info{RequestID: "req-1"})
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+
+	logger := zerolog.Nop()
+	var sink recordingSink
+	svc := New(st, &logger, Options{Submissions: &sink})
+
+	for _, score := range []int64{100, 80, 150} {
+		if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Alice", Score: score}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []SubmissionEvent{
+		{SubmittedScore: 100, NewScore: 100, Applied: true},
+		{SubmittedScore: 80, OldScore: 100, HadScore: true, NewScore: 100},
+		{SubmittedScore: 150, OldScore: 100, HadScore: true, NewScore: 150, Applied: true},
+	}
+	if len(sink) != len(want) {
+		t.Fatalf("%d events, want %d", len(sink), len(want))
+	}
+	for i, got := range sink {
+		w := want[i]
+		if got.SubmittedScore != w.SubmittedScore || got.OldScore != w.OldScore || got.HadScore != w.HadScore ||
+			got.NewScore != w.NewScore || got.Applied != w.Applied {
+			t.Errorf("event %d = %+v, want %+v", i, got, w)
+		}
+		if got.LeaderboardID != DefaultLeaderboardID || got.PlayerName != "Alice" || got.RequestID != "req-1" || got.SubmittedAt.IsZero() {
+			t.Errorf("event %d = %+v, missing board, player or request", i, got)
+		}
+	}
+}
+
+func TestSubmitScoreRank(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{SubmitRank: true})
+
+	for _, c := range []struct {
+		player          string
+		score           int64
+		rank, rankDelta int64
+	}{
+		{"Alice", 100, 1, 0},
+		{"Bob", 200, 1, 0},  // first score: no delta
+		{"Carol", 50, 3, 0}, // behind Bob and Alice
+		{"Carol", 40, 3, 0}, // not an improvement
+		{"Carol", 300, 1, 2},
+		{"Alice", 250, 2, 1},
+	} {
+		res, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: c.player, Score: c.score})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Rank != c.rank || res.RankDelta != c.rankDelta {
+			t.Errorf("%s %d: rank %d (delta %d), want %d (delta %d)", c.player, c.score, res.Rank, res.RankDelta, c.rank, c.rankDelta)
+		}
+	}
+
+	svc = New(st, &logger, Options{})
+	if res, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Dave", Score: 10}); err != nil || res.Rank != 0 {
+		t.Errorf("without SubmitRank: rank %d, %v; want 0", res.Rank, err)
+	}
+}
+
+// BenchmarkSubmitScore submits improving scores for 1000 players, with and without
+// ranking in the write transaction
+func BenchmarkSubmitScore(b *testing.B) {
+	for _, rank := range []bool{false, true} {
+		b.Run(fmt.Sprintf("rank=%t", rank), func(b *testing.B) {
+			ctx := context.Background()
+			st, err := sqlite.Open(ctx, ":memory:")
+			if err != nil {
+				b.Fatalf("open sqlite: %v", err)
+			}
+			defer st.Close()
+			logger := zerolog.Nop()
+			svc := New(st, &logger, Options{SubmitRank: rank})
+
+			b.ResetTimer()
+			for i := range b.N {
+				if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: fmt.Sprintf("p%d", i%1000), Score: int64(i)}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// SubmitQueue configures write-behind score submissions: SubmitScore validates a
+// submission, queues it and returns it as accepted, and workers write the queue in
+// batches. Subscribers of the board's streams see the authoritative best score once
+// it is written. The queue is in memory: submissions still queued when the process
+// dies are lost.
+type SubmitQueue struct {
+	Size          int           // submissions queued at most before SubmitScore sheds (0 writes synchronously)
+	Workers       int           // workers writing the queue (0 uses 1)
+	BatchSize     int           // submissions written per transaction (0 uses 1)
+	FlushInterval time.Duration // how long a worker waits to fill a batch
+}
+
+// queuedScore is a validated submission waiting to be written
+type queuedScore struct {
+	ctx      context.Context // request context, detached from its cancellation
+	deviceID string
+	row      store.UpsertScoreParams
+}
+
+// submitQueue is the queue of SubmitScore in async mode
+type submitQueue struct {
+	mu      sync.RWMutex // held for reading to enqueue, for writing to close
+	closed  bool
+	scores  chan queuedScore
+	stopped chan struct{} // closed when the workers wrote the queue out
+}
+
+// queuing reports whether SubmitScore queues submissions
+func (s *Service) queuing() bool {
+	return s.queue.scores != nil
+}
+
+// enqueueScore queues a validated submission and returns it as accepted. When the
+// queue is full or closed the submission is shed with ErrOverloaded.
+func (s *Service) enqueueScore(ctx context.Context, sub ScoreSubmission, tags segmentTags) (*ScoreResult, error) {
+	achievedAt, clientAchievedAt := s.resolveAchievedAt(ctx, sub.AchievedAt, time.Now())
+	item := queuedScore{
+		ctx:      context.WithoutCancel(ctx),
+		deviceID: sub.DeviceID,
+		row: store.UpsertScoreParams{
+			LeaderboardID:    sub.LeaderboardID,
+			PlayerName:       sub.PlayerName,
+			Score:            sub.Score,
+			SecondaryScore:   sub.SecondaryScore,
+			AchievedAt:       pgtype.Timestamptz{Time: achievedAt, Valid: true},
+			ClientAchievedAt: clientAchievedAt,
+			Metadata:         encodeMetadata(sub.Metadata),
+			CountryCode:      tags.country,
+			Platform:         tags.platform,
+		},
+	}
+
+	q := &s.queue
+	q.mu.RLock()
+	queued := false
+	if !q.closed {
+		select {
+		case q.scores <- item:
+			queued = true
+		default:
+		}
+	}
+	q.mu.RUnlock()
+	if !queued {
+		metrics.QueuedSubmissions.WithLabelValues("shed").Inc()
+		s.lastShed.Store(time.Now().UnixNano())
+		s.loggerFor(ctx).Warn().Int("queue_size", cap(q.scores)).Msg("submission queue full, shedding request")
+		return nil, ErrOverloaded
+	}
+	metrics.SubmitQueueDepth.Inc()
+
+	return &ScoreResult{
+		LeaderboardID:  sub.LeaderboardID,
+		PlayerName:     sub.PlayerName,
+		Score:          sub.Score,
+		AchievedAt:     achievedAt,
+		Metadata:       sub.Metadata,
+		SecondaryScore: sub.SecondaryScore,
+		Country:        tags.country,
+		Platform:       tags.platform,
+		Accepted:       true,
+	}, nil
+}
+
+// RunSubmitQueue writes queued submissions until StopSubmitQueue, and returns once
+// the queue is written out. It returns at once when SubmitScore writes synchronously.
+func (s *Service) RunSubmitQueue() {
+	if !s.queuing() {
+		return
+	}
+	defer close(s.queue.stopped)
+
+	var wg sync.WaitGroup
+	for range max(s.opts.SubmitQueue.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.writeQueue()
+		}()
+	}
+	wg.Wait()
+}
+
+// StopSubmitQueue stops accepting submissions and waits until RunSubmitQueue wrote
+// out those already queued, or ctx is done
+func (s *Service) StopSubmitQueue(ctx context.Context) error {
+	if !s.queuing() {
+		return nil
+	}
+	q := &s.queue
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.scores)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeQueue is a queue worker: it takes a batch of up to BatchSize submissions,
+// waiting up to FlushInterval after the first one, and writes it
+func (s *Service) writeQueue() {
+	size := max(s.opts.SubmitQueue.BatchSize, 1)
+	batch := make([]queuedScore, 0, size)
+	for first := range s.queue.scores {
+		batch = append(batch[:0], first)
+		timer := time.NewTimer(s.opts.SubmitQueue.FlushInterval)
+	fill:
+		for len(batch) < size {
+			select {
+			case item, ok := <-s.queue.scores:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, item)
+			case <-timer.C:
+				break fill
+			}
+		}
+		timer.Stop()
+		metrics.SubmitQueueDepth.Sub(float64(len(batch)))
+		s.writeBatch(batch)
+	}
+}
+
+// writeBatch checks bans and device limits of queued submissions, now that it may
+// touch the database, and upserts the others in one transaction. When the
+// transaction fails each submission is retried on its own, so that one bad row
+// does not lose the batch.
+func (s *Service) writeBatch(batch []queuedScore) {
+	items := make([]queuedScore, 0, len(batch))
+	rows := make([]store.UpsertScoreParams, 0, len(batch))
+	for _, item := range batch {
+		if err := s.checkQueued(item); err != nil {
+			s.queuedWritten(item, nil, err)
+			continue
+		}
+		items = append(items, item)
+		rows = append(rows, item.row)
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	written, err := s.store.UpsertScores(context.Background(), rows)
+	if err == nil {
+		for i, w := range written {
+			s.queuedWritten(items[i], &w, nil)
+		}
+		return
+	}
+	if len(rows) > 1 {
+		s.logger.Warn().Err(err).Int("entries", len(rows)).Msg("failed to write submission batch, retrying one by one")
+	}
+	for _, item := range items {
+		written, err := s.store.UpsertScores(item.ctx, []store.UpsertScoreParams{item.row})
+		if err != nil {
+			s.loggerFor(item.ctx).Error().Err(err).Str("leaderboard", item.row.LeaderboardID).Str("player", item.row.PlayerName).Int64("score", item.row.Score).Msg("failed to upsert queued score")
+			s.queuedWritten(item, nil, err)
+			continue
+		}
+		s.queuedWritten(item, &written[0], nil)
+	}
+}
+
+// checkQueued runs the checks of a queued submission that read the database. The
+// workers bound the writes of the queue, so admission control does not apply.
+func (s *Service) checkQueued(item queuedScore) error {
+	if err := s.checkBanned(item.ctx, item.row.PlayerName); err != nil {
+		return err
+	}
+	return s.checkDeviceLimits(item.ctx, item.deviceID, item.row.PlayerName)
+}
+
+// queuedWritten reports the outcome of a queued submission: the written score, or
+// the error that dropped it
+func (s *Service) queuedWritten(item queuedScore, written *store.UpsertedScore, err error) {
+	var result *ScoreResult
+	if err == nil {
+		result = s.scoreWritten(item.ctx, item.row.Score, store.RankedScore{UpsertedScore: *written})
+	} else {
+		s.loggerFor(item.ctx).Warn().Err(err).Str("leaderboard", item.row.LeaderboardID).Str("player", item.row.PlayerName).Msg("queued submission dropped")
+	}
+	metrics.QueuedSubmissions.WithLabelValues(submissionOutcome(result, err)).Inc()
+	s.observeSubmission(item.ctx, item.row.PlayerName, result, err)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestSubmitQueue(t *testing.T) {
+	ctx := requestctx.NewContext(context.Background(), requestctx.Info{RequestID: "req-1"})
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+
+	logger := zerolog.Nop()
+	var sink recordingSink
+	svc := New(st, &logger, Options{
+		Admin:       Admin{Token: "s3cret"},
+		Submissions: &sink,
+		SubmitRank:  true,
+		SubmitQueue: SubmitQueue{Size: 3, Workers: 1, BatchSize: 10, FlushInterval: time.Millisecond},
+	})
+	admin, _ := svc.AuthenticateAdmin(ctx, "s3cret")
+	if _, err := svc.BanPlayer(admin, "Mallory", "cheating", false); err != nil {
+		t.Fatalf("ban: %v", err)
+	}
+
+	// Submissions are validated, then queued until the workers run
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Alice", Score: -1}); !errors.Is(err, ErrInvalidScore) {
+		t.Errorf("negative score: error = %v, want %v", err, ErrInvalidScore)
+	}
+	for _, sub := range []ScoreSubmission{
+		{PlayerName: "Alice", Score: 100},
+		{PlayerName: "Alice", Score: 150, Metadata: map[string]string{"level": "3"}},
+		{PlayerName: "Mallory", Score: 999}, // banned, dropped once checked by a worker
+	} {
+		result, err := svc.SubmitScore(ctx, sub)
+		if err != nil {
+			t.Fatalf("submit %+v: %v", sub, err)
+		}
+		if !result.Accepted || result.Applied || result.Rank != 0 || result.Score != sub.Score || result.LeaderboardID != DefaultLeaderboardID {
+			t.Errorf("queued result = %+v, want the submission echoed as accepted", result)
+		}
+	}
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Bob", Score: 50}); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("submit to a full queue: error = %v, want %v", err, ErrOverloaded)
+	}
+	if _, err := svc.GetPlayerRank(ctx, "", "Alice"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("rank before the queue is written: error = %v, want %v", err, ErrPlayerNotFound)
+	}
+
+	go svc.RunSubmitQueue()
+	stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := svc.StopSubmitQueue(stopCtx); err != nil {
+		t.Fatalf("StopSubmitQueue: %v", err)
+	}
+
+	rank, err := svc.GetPlayerRank(ctx, "", "Alice")
+	if err != nil {
+		t.Fatalf("rank after the queue is written: %v", err)
+	}
+	if rank.Score.Score != 150 || DecodeMetadata(rank.Score.Metadata)["level"] != "3" {
+		t.Errorf("best score = %d %s, want 150 with its metadata", rank.Score.Score, rank.Score.Metadata)
+	}
+	if len(sink) != 2 || !sink[0].Applied || !sink[1].Applied || sink[1].OldScore != 100 || sink[1].RequestID != "req-1" {
+		t.Errorf("events = %+v, want both Alice submissions applied in order", sink)
+	}
+
+	if _, err := svc.SubmitScore(ctx, ScoreSubmission{PlayerName: "Bob", Score: 50}); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("submit after stop: error = %v, want %v", err, ErrOverloaded)
+	}
+}
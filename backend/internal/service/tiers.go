@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// TierDefinition names the players in the top TopPercent of the board, e.g. {"Gold", 10}.
+// A player belongs to the most exclusive tier whose threshold they reach.
+//
+// Tiers are configured per board: only the boards given definitions rank their
+// players into tiers.
+type TierDefinition struct {
+	Name       string
+	TopPercent float64
+}
+
+// TierChange reports a player promoted or demoted to another tier of a board
+type TierChange struct {
+	LeaderboardID string
+	PlayerName    string
+	Score         int64
+	OldTier       string
+	NewTier       string
+}
+
+// tierState holds the tiers of every board configured with tiers. The set of
+// boards is fixed at startup, so the map is read without a lock.
+type tierState struct {
+	boards  map[string]*boardTiers
+	changes chan TierChange
+}
+
+func newTierState(defs map[string][]TierDefinition) tierState {
+	boards := make(map[string]*boardTiers, len(defs))
+	for board, d := range defs {
+		if len(d) > 0 {
+			boards[board] = &boardTiers{defs: d}
+		}
+	}
+	return tierState{boards: boards, changes: make(chan TierChange, 256)}
+}
+
+// boardTiers holds the tier thresholds of a board computed by the last recompute
+type boardTiers struct {
+	mu         sync.RWMutex
+	defs       []TierDefinition // ordered from the most exclusive tier
+	thresholds []int64          // min rank score per tier, parallel to defs; nil until computed
+	order      SortOrder        // sort order of the board at the last recompute
+	computedAt time.Time
+}
+
+// ParseTierDefinitions parses a tier list such as "Diamond:1,Gold:10,Silver:25,Bronze:100"
+func ParseTierDefinitions(value string) ([]TierDefinition, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var defs []TierDefinition
+	for _, part := range strings.Split(value, ",") {
+		name, pct, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid tier %q: expected name:top_percent", part)
+		}
+		top, err := strconv.ParseFloat(pct, 64)
+		if err != nil || top <= 0 || top > 100 {
+			return nil, fmt.Errorf("invalid tier %q: top_percent must be in (0, 100]", part)
+		}
+		defs = append(defs, TierDefinition{Name: name, TopPercent: top})
+	}
+
+	sort.Slice(defs, func(i, j int) bool { return defs[i].TopPercent < defs[j].TopPercent })
+	return defs, nil
+}
+
+// ParseBoardTierDefinitions parses the tier lists of several boards, separated by
+// semicolons, such as "global=Gold:10,Bronze:100;level-1=Gold:5,Silver:50"
+func ParseBoardTierDefinitions(value string) (map[string][]TierDefinition, error) {
+	boards := make(map[string][]TierDefinition)
+	for _, part := range strings.Split(value, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		id, list, ok := strings.Cut(part, "=")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid board tiers %q: expected board=name:top_percent,...", part)
+		}
+		board, err := ResolveLeaderboardID(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid board tiers %q: %w", part, err)
+		}
+		if _, dup := boards[board]; dup {
+			return nil, fmt.Errorf("tiers of board %q given twice", board)
+		}
+		defs, err := ParseTierDefinitions(list)
+		if err != nil {
+			return nil, fmt.Errorf("board %q: %w", board, err)
+		}
+		boards[board] = defs
+	}
+	return boards, nil
+}
+
+// TierChanges returns the feed of tier promotions and demotions
+func (s *Service) TierChanges() <-chan TierChange {
+	return s.tiers.changes
+}
+
+// TierFor returns the tier name for a score on a board, or "" when the board has
+// no tiers or they are not yet computed
+func (s *Service) TierFor(board string, score int64) string {
+	bt := s.tiers.boards[board]
+	if bt == nil {
+		return ""
+	}
+	bt.mu.RLock()
+	defer bt.mu.RUnlock()
+	return tierFor(bt.defs, bt.thresholds, bt.order.RankScore(score))
+}
+
+// tierFor returns the tier of a rank score
+func tierFor(defs []TierDefinition, thresholds []int64, rankScore int64) string {
+	for i, threshold := range thresholds {
+		if rankScore >= threshold {
+			return defs[i].Name
+		}
+	}
+	return ""
+}
+
+// RunTierScheduler recomputes tier thresholds every interval until ctx is cancelled
+func (s *Service) RunTierScheduler(ctx context.Context, interval time.Duration) {
+	if len(s.tiers.boards) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RecomputeTiers(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error().Err(err).Msg("failed to recompute tiers")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecomputeTiers recomputes the tier thresholds of every board with tiers from its
+// current score distribution and emits a TierChange for every player whose tier
+// moved with the thresholds. A failing board does not hold back the others.
+func (s *Service) RecomputeTiers(ctx context.Context) error {
+	boards := make([]string, 0, len(s.tiers.boards))
+	for board := range s.tiers.boards {
+		boards = append(boards, board)
+	}
+	slices.Sort(boards)
+
+	var errs []error
+	for _, board := range boards {
+		if err := s.recomputeBoardTiers(ctx, board, s.tiers.boards[board]); err != nil {
+			errs = append(errs, fmt.Errorf("board %s: %w", board, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recomputeBoardTiers recomputes the tier thresholds of a board
+func (s *Service) recomputeBoardTiers(ctx context.Context, board string, bt *boardTiers) error {
+	defs := bt.defs
+	order, err := s.sortOrder(ctx, board)
+	if err != nil {
+		return err
+	}
+
+	fractions := make([]float64, len(defs))
+	for i, d := range defs {
+		fractions[i] = 1 - d.TopPercent/100
+	}
+	row, err := s.store.GetScorePercentiles(ctx, store.GetScorePercentilesParams{
+		LeaderboardID: board,
+		Fractions:     fractions,
+	})
+	if err != nil {
+		return fmt.Errorf("get score percentiles: %w", err)
+	}
+
+	var thresholds []int64
+	if len(row.Thresholds) == len(defs) {
+		thresholds = make([]int64, len(defs))
+		for i, t := range row.Thresholds {
+			thresholds[i] = int64(math.Ceil(t))
+		}
+	}
+
+	bt.mu.Lock()
+	old := bt.thresholds
+	bt.thresholds = thresholds
+	bt.order = order
+	bt.computedAt = time.Now()
+	bt.mu.Unlock()
+
+	s.logger.Debug().Str("leaderboard", board).Interface("thresholds", thresholds).Msg("tiers recomputed")
+	if !slices.Equal(old, thresholds) {
+		s.versions.bump(board)
+	}
+
+	// No announcements on the first computation: nobody had a tier before
+	if old == nil || thresholds == nil {
+		return nil
+	}
+
+	// Players whose tier can change sit between an old and a new threshold
+	affected := make(map[string]int64)
+	for i := range defs {
+		low, high := min(old[i], thresholds[i]), max(old[i], thresholds[i])
+		if low == high {
+			continue
+		}
+
+		players, err := s.store.GetScoresInRange(ctx, store.GetScoresInRangeParams{
+			LeaderboardID: board,
+			MinRankScore:  low,
+			MaxRankScore:  high,
+		})
+		if err != nil {
+			return fmt.Errorf("get scores in range: %w", err)
+		}
+		for _, p := range players {
+			affected[p.PlayerName] = p.RankScore
+		}
+	}
+
+	for playerName, rankScore := range affected {
+		s.emitTierChange(board, playerName, order.RankScore(rankScore), tierFor(defs, old, rankScore), tierFor(defs, thresholds, rankScore))
+	}
+
+	return nil
+}
+
+// emitTierChange publishes a tier change if the tier actually differs
+func (s *Service) emitTierChange(board, playerName string, score int64, oldTier, newTier string) {
+	if oldTier == newTier {
+		return
+	}
+
+	select {
+	case s.tiers.changes <- TierChange{LeaderboardID: board, PlayerName: playerName, Score: score, OldTier: oldTier, NewTier: newTier}:
+	default:
+		s.logger.Warn().Str("leaderboard", board).Str("player", playerName).Msg("tier change channel full, dropping event")
+	}
+}
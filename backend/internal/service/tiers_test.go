@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestParseTierDefinitions(t *testing.T) {
+	defs, err := ParseTierDefinitions("Bronze:100, Gold:10,Diamond:1")
+	if err != nil {
+		t.Fatalf("ParseTierDefinitions failed: %v", err)
+	}
+	want := []string{"Diamond", "Gold", "Bronze"}
+	if len(defs) != len(want) {
+		t.Fatalf("got %d tiers, want %d", len(defs), len(want))
+	}
+	for i, name := range want {
+		if defs[i].Name != name {
+			t.Errorf("tier %d = %s, want %s", i, defs[i].Name, name)
+		}
+	}
+
+	for _, invalid := range []string{"Gold", "Gold:0", "Gold:101", ":10", "Gold:abc"} {
+		if _, err := ParseTierDefinitions(invalid); err == nil {
+			t.Errorf("ParseTierDefinitions(%q) expected error, got nil", invalid)
+		}
+	}
+}
+
+func TestTierFor(t *testing.T) {
+	defs := []TierDefinition{{"Gold", 10}, {"Silver", 50}, {"Bronze", 100}}
+	thresholds := []int64{900, 500, 0}
+
+	tests := []struct {
+		score int64
+		want  string
+	}{
+		{1000, "Gold"},
+		{900, "Gold"},
+		{899, "Silver"},
+		{500, "Silver"},
+		{10, "Bronze"},
+	}
+	for _, tt := range tests {
+		if got := tierFor(defs, thresholds, tt.score); got != tt.want {
+			t.Errorf("tierFor(%d) = %q, want %q", tt.score, got, tt.want)
+		}
+	}
+
+	if got := tierFor(defs, nil, 1000); got != "" {
+		t.Errorf("tierFor without thresholds = %q, want empty", got)
+	}
+}
+
+func TestParseBoardTierDefinitions(t *testing.T) {
+	boards, err := ParseBoardTierDefinitions(" global=Gold:10,Bronze:100; level-1=Gold:5 ;")
+	if err != nil {
+		t.Fatalf("ParseBoardTierDefinitions failed: %v", err)
+	}
+	if len(boards) != 2 || len(boards[DefaultLeaderboardID]) != 2 || len(boards["level-1"]) != 1 || boards["level-1"][0].TopPercent != 5 {
+		t.Errorf("boards = %v", boards)
+	}
+	if boards, err := ParseBoardTierDefinitions(""); err != nil || len(boards) != 0 {
+		t.Errorf("empty: %v, %v; want no boards", boards, err)
+	}
+
+	for _, invalid := range []string{"Gold:10", "=Gold:10", "no spaces=Gold:10", "level-1=Gold", "level-1=Gold:5;level-1=Gold:10"} {
+		if _, err := ParseBoardTierDefinitions(invalid); err == nil {
+			t.Errorf("ParseBoardTierDefinitions(%q) expected error, got nil", invalid)
+		}
+	}
+}
+
+// drainTierChanges returns the tier changes emitted so far by board and player
+func drainTierChanges(svc *Service) map[string]map[string]TierChange {
+	changes := make(map[string]map[string]TierChange)
+	for {
+		select {
+		case c := <-svc.TierChanges():
+			if changes[c.LeaderboardID] == nil {
+				changes[c.LeaderboardID] = make(map[string]TierChange)
+			}
+			changes[c.LeaderboardID][c.PlayerName] = c
+		default:
+			return changes
+		}
+	}
+}
+
+func TestRecomputeTiersByBoard(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{Tiers: map[string][]TierDefinition{
+		DefaultLeaderboardID: {{"Gold", 50}, {"Bronze", 100}},
+		"level-1":            {{"Gold", 25}, {"Bronze", 100}},
+	}})
+	submit := func(board, player string, score int64) {
+		t.Helper()
+		if _, err := svc.SubmitScore(ctx, ScoreSubmission{LeaderboardID: board, PlayerName: player, Score: score}); err != nil {
+			t.Fatalf("submit %s on %s: %v", player, board, err)
+		}
+	}
+
+	for i, player := range []string{"A", "B", "C", "D"} {
+		submit(DefaultLeaderboardID, player, int64(i+1)*100)
+		submit("level-1", player, int64(i+1)*100)
+		submit("level-2", player, int64(i+1)*100)
+	}
+	if err := svc.RecomputeTiers(ctx); err != nil {
+		t.Fatalf("RecomputeTiers: %v", err)
+	}
+	// global: Gold from the median, 250. level-1: Gold from the 75th percentile, 325
+	if got := svc.TierFor(DefaultLeaderboardID, 300); got != "Gold" {
+		t.Errorf("global tier of 300 = %q, want Gold", got)
+	}
+	if got := svc.TierFor("level-1", 300); got != "Bronze" {
+		t.Errorf("level-1 tier of 300 = %q, want Bronze", got)
+	}
+	if got := svc.TierFor("level-2", 400); got != "" {
+		t.Errorf("level-2 tier = %q, want none: the board has no tiers", got)
+	}
+	if changes := drainTierChanges(svc); len(changes) != 0 {
+		t.Errorf("first computation emitted %v, want nothing", changes)
+	}
+
+	// Better scores raise the global median to 350, demoting C. Lower scores
+	// drop the 75th percentile of level-1 to 275, promoting C there.
+	submit(DefaultLeaderboardID, "E", 500)
+	submit(DefaultLeaderboardID, "F", 600)
+	submit("level-1", "E", 10)
+	submit("level-1", "F", 20)
+	drainTierChanges(svc) // the submissions' own tier changes
+	if err := svc.RecomputeTiers(ctx); err != nil {
+		t.Fatalf("RecomputeTiers: %v", err)
+	}
+	changes := drainTierChanges(svc)
+
+	if c, ok := changes[DefaultLeaderboardID]["C"]; !ok || c.OldTier != "Gold" || c.NewTier != "Bronze" || c.Score != 300 {
+		t.Errorf("global change of C = %+v, want a demotion from Gold to Bronze", c)
+	}
+	if len(changes[DefaultLeaderboardID]) != 1 {
+		t.Errorf("global changes = %v, want C only", changes[DefaultLeaderboardID])
+	}
+	if c, ok := changes["level-1"]["C"]; !ok || c.OldTier != "Bronze" || c.NewTier != "Gold" {
+		t.Errorf("level-1 change of C = %+v, want a promotion from Bronze to Gold", c)
+	}
+	if _, ok := changes["level-2"]; ok {
+		t.Errorf("level-2 changes = %v, want none", changes["level-2"])
+	}
+}
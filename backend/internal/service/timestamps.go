@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+// Client timestamp trust decisions, also used as metric labels
+const (
+	timestampTrusted = "trusted"
+	timestampFuture  = "future"
+	timestampExpired = "expired"
+)
+
+// ClientTimestamps configures how client-reported completion times are trusted
+type ClientTimestamps struct {
+	// SkewWindow is how far ahead of server time a client clock may be
+	SkewWindow time.Duration
+
+	// MaxAge is how old a run may be (e.g. played offline) and still keep its timestamp
+	MaxAge time.Duration
+}
+
+// classifyTimestamp reports whether clientAt falls in [now-MaxAge, now+SkewWindow]
+// and records the decision in metrics
+func (s *Service) classifyTimestamp(clientAt, now time.Time) string {
+	cfg := s.opts.ClientTimestamps
+	result := timestampTrusted
+	switch {
+	case clientAt.After(now.Add(cfg.SkewWindow)):
+		result = timestampFuture
+	case clientAt.Before(now.Add(-cfg.MaxAge)):
+		result = timestampExpired
+	}
+	metrics.ClientTimestamps.WithLabelValues(result).Inc()
+	return result
+}
+
+// resolveAchievedAt decides when a submitted run was achieved.
+// The client timestamp is used when it falls in [now-MaxAge, now+SkewWindow]
+// (clamped to now if slightly ahead); otherwise the server time is used.
+// The raw client value is always returned for storage.
+func (s *Service) resolveAchievedAt(ctx context.Context, clientAt, now time.Time) (achievedAt time.Time, client pgtype.Timestamptz) {
+	if clientAt.IsZero() {
+		return now, pgtype.Timestamptz{}
+	}
+	client = pgtype.Timestamptz{Time: clientAt, Valid: true}
+
+	if result := s.classifyTimestamp(clientAt, now); result != timestampTrusted {
+		s.loggerFor(ctx).Debug().Time("client_at", clientAt).Str("result", result).Msg("untrusted client timestamp, using server time")
+		return now, client
+	}
+	if clientAt.After(now) {
+		return now, client
+	}
+	return clientAt, client
+}
@@ -0,0 +1,405 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// DefaultTopCacheBoards is the number of boards with a top cache when none is configured
+const DefaultTopCacheBoards = 100
+
+// maxPendingTopChanges bounds the changes kept for replay during a load. A load
+// overtaken by more changes than that is discarded, and the next read reloads.
+const maxPendingTopChanges = 10000
+
+// topCache is an in-process materialized view of the top N entries of one leaderboard.
+// It is loaded from the database once and then kept current from notify events,
+// so GetTopScores requests within the cached window need no database round-trip.
+type topCache struct {
+	board   string
+	mu      sync.RWMutex
+	size    int
+	entries []store.Score // ordered by score DESC, achieved_at ASC, player_name ASC
+
+	// loaded is false until the first load, and again whenever an event leaves
+	// the cache unable to tell which entry should fill a vacated slot.
+	loaded bool
+
+	// complete is true when entries hold every row of the board (fewer than size players)
+	complete bool
+
+	// loads counts the loads in flight. The changes delivered meanwhile are kept
+	// in pending and replayed over each loaded snapshot, which may predate them.
+	loads   int
+	pending []notify.ScoreChange
+
+	// generation is bumped whenever a load in flight must be discarded: its
+	// snapshot may predate a reset, or changes it can no longer replay
+	generation uint64
+}
+
+// topCaches holds the top caches of the most recently read boards.
+// With thousands of boards (one per game level) only the busy ones are worth
+// keeping in memory: a board is cached on its first read, and the least recently
+// read board is dropped once maxBoards are cached.
+type topCaches struct {
+	mu        sync.Mutex
+	size      int // entries per board, 0 disables caching
+	maxBoards int
+	boards    map[string]*list.Element // values are *topCache
+	lru       *list.List               // most recently read first
+}
+
+func newTopCaches(size, maxBoards int) topCaches {
+	if maxBoards <= 0 {
+		maxBoards = DefaultTopCacheBoards
+	}
+	return topCaches{
+		size:      size,
+		maxBoards: maxBoards,
+		boards:    make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// board returns the cache of a board, creating it (unloaded) if needed
+func (c *topCaches) board(id string) *topCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.boards[id]; ok {
+		c.lru.MoveToFront(el)
+		return el.Value.(*topCache)
+	}
+
+	cache := &topCache{board: id, size: c.size}
+	c.boards[id] = c.lru.PushFront(cache)
+	if c.lru.Len() > c.maxBoards {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.boards, oldest.Value.(*topCache).board)
+	}
+	return cache
+}
+
+// lookup returns the cache of a board, or nil when the board is not cached
+func (c *topCaches) lookup(id string) *topCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.boards[id]; ok {
+		return el.Value.(*topCache)
+	}
+	return nil
+}
+
+// invalidateAll forces the next read of every board to reload from the database
+func (c *topCaches) invalidateAll() {
+	c.mu.Lock()
+	caches := make([]*topCache, 0, len(c.boards))
+	for _, el := range c.boards {
+		caches = append(caches, el.Value.(*topCache))
+	}
+	c.mu.Unlock()
+
+	for _, cache := range caches {
+		cache.invalidate()
+	}
+}
+
+// apply routes a change to the cache of its board. Changes of boards that are
+// not cached are dropped: those boards are loaded from the database when read.
+func (c *topCaches) apply(change notify.ScoreChange) {
+	if cache := c.lookup(change.LeaderboardID); cache != nil {
+		cache.apply(change)
+	}
+}
+
+// RunTopCache keeps the top-N caches, the entry counts and the board versions
+// current from the given change feed. It returns when the channel is closed.
+func (s *Service) RunTopCache(changes <-chan notify.ScoreChange) {
+	for change := range changes {
+		s.applyTopCache(change)
+		s.applyCounts(change)
+		// After the cache: a version never tags data older than itself
+		board := change.LeaderboardID
+		if board == "" && change.Op != notify.OpResync {
+			board = DefaultLeaderboardID // payload of a server that predates boards
+		}
+		s.versions.bump(board)
+	}
+}
+
+// applyTopCache applies a change to the top-N cache of its board, if cached
+func (s *Service) applyTopCache(change notify.ScoreChange) {
+	if s.top.size == 0 {
+		return
+	}
+	if change.Op == notify.OpResync {
+		// Events may have been lost, or the board was reset: reload on next read
+		if change.LeaderboardID == "" {
+			s.top.invalidateAll()
+		} else if cache := s.top.lookup(change.LeaderboardID); cache != nil {
+			cache.invalidate()
+		}
+		return
+	}
+	s.top.apply(change)
+}
+
+// getTopScoresCached serves a page from the board's cache, loading it first if needed.
+// ok is false when the page falls outside the cached window, or when the load
+// was overtaken by a resync.
+func (s *Service) getTopScoresCached(ctx context.Context, board string, limit, offset int32) ([]store.Score, bool, error) {
+	if s.top.size == 0 || int(limit)+int(offset) > s.top.size {
+		return nil, false, nil
+	}
+
+	cache := s.top.board(board)
+	if scores, ok := cache.get(int(limit), int(offset)); ok {
+		metrics.TopCacheRequests.WithLabelValues("hit").Inc()
+		return scores, true, nil
+	}
+	metrics.TopCacheRequests.WithLabelValues("miss").Inc()
+
+	if err := s.loadTopCache(ctx, cache); err != nil {
+		return nil, false, err
+	}
+	scores, ok := cache.get(int(limit), int(offset))
+	return scores, ok, nil
+}
+
+// loadTopCache (re)loads a board's cache from the database. Changes delivered
+// while the query runs are replayed over its result, and a load overtaken by a
+// resync is discarded.
+func (s *Service) loadTopCache(ctx context.Context, cache *topCache) error {
+	generation := cache.beginLoad()
+
+	// Read from the primary: the cache is then kept current from score changes,
+	// and a lagging replica would miss those already delivered
+	scores, err := s.store.GetTopScores(store.ReadPrimary(ctx), store.GetTopScoresParams{
+		LeaderboardID: cache.board,
+		PageSize:      int32(cache.size),
+		PageOffset:    0,
+	})
+	if err != nil {
+		cache.endLoad()
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", cache.board).Int("size", cache.size).Msg("failed to load top scores cache")
+		return fmt.Errorf("load top scores cache: %w", err)
+	}
+
+	if !cache.finishLoad(generation, scores) {
+		s.loggerFor(ctx).Debug().Str("leaderboard", cache.board).Msg("top scores cache load overtaken, discarded")
+		return nil
+	}
+	s.loggerFor(ctx).Debug().Str("leaderboard", cache.board).Int("entries", len(scores)).Msg("top scores cache loaded")
+	return nil
+}
+
+// beginLoad registers a load in flight and returns the generation it loads
+func (c *topCache) beginLoad() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loads == 0 {
+		c.pending = nil
+	}
+	c.loads++
+	return c.generation
+}
+
+// endLoad unregisters a load in flight
+func (c *topCache) endLoad() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.endLoadLocked()
+}
+
+func (c *topCache) endLoadLocked() {
+	c.loads--
+	if c.loads == 0 {
+		c.pending = nil
+	}
+}
+
+// finishLoad installs a loaded snapshot and replays the changes delivered since
+// the load began. It reports false, leaving the cache as is, when the load of
+// the given generation was overtaken.
+func (c *topCache) finishLoad(generation uint64, scores []store.Score) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.endLoadLocked()
+
+	if generation != c.generation {
+		return false
+	}
+	c.entries = scores
+	c.complete = len(scores) < c.size
+	c.loaded = true
+	// Replaying a change the snapshot already holds is harmless: changes of a
+	// player are applied in order, so the latest one wins
+	for _, change := range c.pending {
+		c.applyLocked(change)
+	}
+	return true
+}
+
+// get returns a copy of the requested page if the cache can answer it
+func (c *topCache) get(limit, offset int) ([]store.Score, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.loaded {
+		return nil, false
+	}
+	if offset+limit > len(c.entries) && !c.complete {
+		return nil, false
+	}
+
+	start := min(offset, len(c.entries))
+	end := min(offset+limit, len(c.entries))
+	page := make([]store.Score, end-start)
+	copy(page, c.entries[start:end])
+	return page, true
+}
+
+// getAfter returns a copy of the page following the given entry if the cache can answer it
+func (c *topCache) getAfter(after store.Score, limit int) ([]store.Score, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.loaded {
+		return nil, false
+	}
+	start := sort.Search(len(c.entries), func(i int) bool {
+		return ranksBefore(after, c.entries[i])
+	})
+	if start+limit > len(c.entries) && !c.complete {
+		return nil, false
+	}
+
+	end := min(start+limit, len(c.entries))
+	page := make([]store.Score, end-start)
+	copy(page, c.entries[start:end])
+	return page, true
+}
+
+// isLoaded reports whether the cache currently holds data
+func (c *topCache) isLoaded() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.loaded
+}
+
+// invalidate forces the next read to reload from the database, discarding the
+// loads in flight
+func (c *topCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded = false
+	c.generation++
+	c.pending = nil
+}
+
+// apply updates the cache with a single change event, and keeps it for the
+// loads in flight
+func (c *topCache) apply(change notify.ScoreChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.loads > 0 {
+		if len(c.pending) < maxPendingTopChanges {
+			c.pending = append(c.pending, change)
+		} else {
+			c.generation++
+			c.pending = nil
+		}
+	}
+	c.applyLocked(change)
+}
+
+func (c *topCache) applyLocked(change notify.ScoreChange) {
+	if !c.loaded {
+		return
+	}
+
+	wasCached := c.remove(change.PlayerName)
+
+	switch change.Op {
+	case "insert", "update":
+		updatedAt := change.UpdatedAt
+		if updatedAt.IsZero() {
+			updatedAt = time.Now() // change of a trigger that predates migration 0015
+		}
+		entry := store.Score{
+			LeaderboardID:  change.LeaderboardID,
+			PlayerName:     change.PlayerName,
+			Score:          change.Score,
+			RankScore:      change.RankScore,
+			SecondaryScore: change.SecondaryScore,
+			RankSecondary:  change.RankSecondary,
+			CountryCode:    change.CountryCode,
+			Platform:       change.Platform,
+			Metadata:       encodeMetadata(change.Metadata),
+			UpdatedAt:      pgtype.Timestamptz{Time: updatedAt, Valid: true},
+			AchievedAt:     pgtype.Timestamptz{Time: change.AchievedAt, Valid: true},
+		}
+
+		pos := sort.Search(len(c.entries), func(i int) bool {
+			return ranksBefore(entry, c.entries[i])
+		})
+		if pos == len(c.entries) && !c.complete {
+			// Below the cached window. If the player was cached before, their slot
+			// is now vacant and we cannot know who fills it without a reload.
+			if wasCached {
+				c.loaded = false
+			}
+			return
+		}
+
+		c.entries = append(c.entries, store.Score{})
+		copy(c.entries[pos+1:], c.entries[pos:])
+		c.entries[pos] = entry
+
+		if len(c.entries) > c.size {
+			c.entries = c.entries[:c.size]
+			c.complete = false
+		}
+
+	case "delete":
+		if wasCached && !c.complete {
+			c.loaded = false
+		}
+	}
+}
+
+// remove deletes a player's entry and reports whether it was present
+func (c *topCache) remove(playerName string) bool {
+	for i, e := range c.entries {
+		if e.PlayerName == playerName {
+			c.entries = append(c.entries[:i], c.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ranksBefore reports whether a ranks strictly above b
+// (rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC)
+func ranksBefore(a, b store.Score) bool {
+	if store.Outranks(a, b) || store.Outranks(b, a) {
+		return store.Outranks(a, b)
+	}
+	if !a.AchievedAt.Time.Equal(b.AchievedAt.Time) {
+		return a.AchievedAt.Time.Before(b.AchievedAt.Time)
+	}
+	return a.PlayerName < b.PlayerName
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+func newLoadedCache(size int, complete bool, entries ...store.Score) *topCache {
+	return &topCache{size: size, entries: entries, loaded: true, complete: complete}
+}
+
+func names(entries []store.Score) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.PlayerName
+	}
+	return out
+}
+
+func TestTopCacheApply(t *testing.T) {
+	tests := []struct {
+		name       string
+		cache      *topCache
+		change     notify.ScoreChange
+		wantNames  []string
+		wantLoaded bool
+	}{
+		{
+			name:       "insert into middle",
+			cache:      newLoadedCache(3, false, store.Score{PlayerName: "A", Score: 300, RankScore: 300}, store.Score{PlayerName: "C", Score: 100, RankScore: 100}),
+			change:     notify.ScoreChange{PlayerName: "B", Score: 200, RankScore: 200, Op: "insert"},
+			wantNames:  []string{"A", "B", "C"},
+			wantLoaded: true,
+		},
+		{
+			name:       "insert beyond size truncates",
+			cache:      newLoadedCache(2, true, store.Score{PlayerName: "A", Score: 300, RankScore: 300}, store.Score{PlayerName: "C", Score: 100, RankScore: 100}),
+			change:     notify.ScoreChange{PlayerName: "B", Score: 200, RankScore: 200, Op: "insert"},
+			wantNames:  []string{"A", "B"},
+			wantLoaded: true,
+		},
+		{
+			name:       "tie broken by player name",
+			cache:      newLoadedCache(3, true, store.Score{PlayerName: "A", Score: 100, RankScore: 100}, store.Score{PlayerName: "C", Score: 100, RankScore: 100}),
+			change:     notify.ScoreChange{PlayerName: "B", Score: 100, RankScore: 100, Op: "insert"},
+			wantNames:  []string{"A", "B", "C"},
+			wantLoaded: true,
+		},
+		{
+			name:       "update moves entry up",
+			cache:      newLoadedCache(3, true, store.Score{PlayerName: "A", Score: 300, RankScore: 300}, store.Score{PlayerName: "B", Score: 200, RankScore: 200}),
+			change:     notify.ScoreChange{PlayerName: "B", Score: 400, RankScore: 400, Op: "update"},
+			wantNames:  []string{"B", "A"},
+			wantLoaded: true,
+		},
+		{
+			name:       "insert below window is ignored",
+			cache:      newLoadedCache(2, false, store.Score{PlayerName: "A", Score: 300, RankScore: 300}, store.Score{PlayerName: "B", Score: 200, RankScore: 200}),
+			change:     notify.ScoreChange{PlayerName: "C", Score: 100, RankScore: 100, Op: "insert"},
+			wantNames:  []string{"A", "B"},
+			wantLoaded: true,
+		},
+		{
+			name:       "cached entry dropping below window invalidates",
+			cache:      newLoadedCache(2, false, store.Score{PlayerName: "A", Score: 300, RankScore: 300}, store.Score{PlayerName: "B", Score: 200, RankScore: 200}),
+			change:     notify.ScoreChange{PlayerName: "B", Score: 10, RankScore: 10, Op: "update"},
+			wantNames:  []string{"A"},
+			wantLoaded: false,
+		},
+		{
+			name:       "delete from incomplete cache invalidates",
+			cache:      newLoadedCache(2, false, store.Score{PlayerName: "A", Score: 300, RankScore: 300}, store.Score{PlayerName: "B", Score: 200, RankScore: 200}),
+			change:     notify.ScoreChange{PlayerName: "A", Score: 300, RankScore: 300, Op: "delete"},
+			wantNames:  []string{"B"},
+			wantLoaded: false,
+		},
+		{
+			name:       "delete from complete cache stays loaded",
+			cache:      newLoadedCache(5, true, store.Score{PlayerName: "A", Score: 300, RankScore: 300}, store.Score{PlayerName: "B", Score: 200, RankScore: 200}),
+			change:     notify.ScoreChange{PlayerName: "A", Score: 300, RankScore: 300, Op: "delete"},
+			wantNames:  []string{"B"},
+			wantLoaded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cache.apply(tt.change)
+
+			got := names(tt.cache.entries)
+			if len(got) != len(tt.wantNames) {
+				t.Fatalf("entries = %v, want %v", got, tt.wantNames)
+			}
+			for i := range got {
+				if got[i] != tt.wantNames[i] {
+					t.Errorf("entries = %v, want %v", got, tt.wantNames)
+					break
+				}
+			}
+			if tt.cache.loaded != tt.wantLoaded {
+				t.Errorf("loaded = %v, want %v", tt.cache.loaded, tt.wantLoaded)
+			}
+		})
+	}
+}
+
+func TestTopCacheApplyUpdatedAt(t *testing.T) {
+	written := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	c := newLoadedCache(3, true)
+	c.apply(notify.ScoreChange{PlayerName: "A", Score: 300, RankScore: 300, Op: "insert", UpdatedAt: written})
+	if got := c.entries[0].UpdatedAt.Time; !got.Equal(written) {
+		t.Errorf("updated_at = %v, want the time of the change %v", got, written)
+	}
+
+	// Changes of a trigger predating updated_at in the payload fall back to now
+	before := time.Now()
+	c.apply(notify.ScoreChange{PlayerName: "A", Score: 400, RankScore: 400, Op: "update"})
+	if got := c.entries[0].UpdatedAt.Time; got.Before(before) {
+		t.Errorf("updated_at = %v, want now", got)
+	}
+}
+
+func TestTopCacheGet(t *testing.T) {
+	cache := newLoadedCache(3, false,
+		store.Score{PlayerName: "A", Score: 300, RankScore: 300},
+		store.Score{PlayerName: "B", Score: 200, RankScore: 200},
+		store.Score{PlayerName: "C", Score: 100, RankScore: 100},
+	)
+
+	page, ok := cache.get(2, 1)
+	if !ok {
+		t.Fatal("expected cache hit for page within window")
+	}
+	if got := names(page); len(got) != 2 || got[0] != "B" || got[1] != "C" {
+		t.Errorf("page = %v, want [B C]", got)
+	}
+
+	if _, ok := cache.get(3, 1); ok {
+		t.Error("expected cache miss for page beyond incomplete window")
+	}
+
+	cache.complete = true
+	page, ok = cache.get(3, 1)
+	if !ok || len(page) != 2 {
+		t.Errorf("expected short page from complete cache, got %v (ok=%v)", names(page), ok)
+	}
+}
+
+func notifyChange(board, player string, score int64) notify.ScoreChange {
+	return notify.ScoreChange{LeaderboardID: board, PlayerName: player, Score: score, RankScore: score, Op: "insert"}
+}
+
+func TestTopCachesRouting(t *testing.T) {
+	caches := newTopCaches(3, 2)
+	level1 := caches.board("level-1")
+	level1.loaded, level1.complete = true, true
+	level2 := caches.board("level-2")
+	level2.loaded, level2.complete = true, true
+
+	caches.apply(notifyChange("level-1", "A", 100))
+	caches.apply(notifyChange("level-3", "B", 200)) // not cached: dropped
+	if got := names(level1.entries); len(got) != 1 || got[0] != "A" {
+		t.Errorf("level-1 entries = %v, want [A]", got)
+	}
+	if len(level2.entries) != 0 {
+		t.Errorf("level-2 entries = %v, want none", names(level2.entries))
+	}
+
+	// level-3 evicts the least recently read board, level-1
+	caches.board("level-3")
+	if caches.lookup("level-1") != nil {
+		t.Error("level-1 still cached after eviction")
+	}
+	if caches.lookup("level-2") != level2 {
+		t.Error("level-2 evicted, want it kept")
+	}
+
+	caches.invalidateAll()
+	if level2.isLoaded() {
+		t.Error("level-2 still loaded after invalidateAll")
+	}
+}
+
+// snapshotRepository serves a fixed top scores snapshot, running midLoad once
+// the snapshot is taken, as a change delivered before the load returns would
+type snapshotRepository struct {
+	store.Repository
+	snapshot []store.Score
+	midLoad  func()
+}
+
+func (r *snapshotRepository) GetTopScores(context.Context, store.GetTopScoresParams) ([]store.Score, error) {
+	if r.midLoad != nil {
+		r.midLoad()
+	}
+	return slices.Clone(r.snapshot), nil
+}
+
+func TestTopCacheChangeDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	repo := &snapshotRepository{snapshot: []store.Score{
+		{LeaderboardID: DefaultLeaderboardID, PlayerName: "A", Score: 300, RankScore: 300},
+		{LeaderboardID: DefaultLeaderboardID, PlayerName: "C", Score: 100, RankScore: 100},
+	}}
+	svc := New(repo, &logger, Options{TopCacheSize: 10})
+
+	// A change the snapshot missed is replayed over it
+	repo.midLoad = func() { svc.applyTopCache(notifyChange(DefaultLeaderboardID, "B", 200)) }
+	page, ok, err := svc.getTopScoresCached(ctx, DefaultLeaderboardID, 10, 0)
+	if err != nil || !ok {
+		t.Fatalf("getTopScoresCached: ok %v, %v", ok, err)
+	}
+	if got := names(page); !slices.Equal(got, []string{"A", "B", "C"}) {
+		t.Errorf("page = %v, want [A B C]", got)
+	}
+	cache := svc.top.lookup(DefaultLeaderboardID)
+	if cache.loads != 0 || cache.pending != nil {
+		t.Errorf("%d loads in flight and %d pending changes after the load", cache.loads, len(cache.pending))
+	}
+
+	// A resync during the load discards its snapshot, which may predate the reset
+	cache.invalidate()
+	repo.midLoad = func() {
+		svc.applyTopCache(notify.ScoreChange{LeaderboardID: DefaultLeaderboardID, Op: notify.OpResync})
+	}
+	if _, ok, err := svc.getTopScoresCached(ctx, DefaultLeaderboardID, 10, 0); err != nil || ok {
+		t.Errorf("load overtaken by a resync: ok %v, %v; want a miss", ok, err)
+	}
+	if cache.isLoaded() {
+		t.Error("cache loaded from a snapshot taken before the resync")
+	}
+
+	// The next read loads again
+	repo.midLoad = nil
+	if page, ok, _ := svc.getTopScoresCached(ctx, DefaultLeaderboardID, 10, 0); !ok || len(page) != 2 {
+		t.Errorf("reload: %v (ok=%v), want the 2 entries of the snapshot", names(page), ok)
+	}
+}
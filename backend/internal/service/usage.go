@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/usage"
+)
+
+// ErrKeyUsageNotFound is returned when an API key made no request in the usage window
+var ErrKeyUsageNotFound = errors.New("no usage recorded for this API key")
+
+// Usage report limits
+const (
+	UsageTopMethods     = 10
+	DefaultUsageTopKeys = 10
+	MaxUsageTopKeys     = 100
+)
+
+// RecordUsage counts a request in the usage statistics of the API key of ctx;
+// requests without a key are not counted
+func (s *Service) RecordUsage(ctx context.Context, method string, failed bool) {
+	key := requestctx.FromContext(ctx).APIKey
+	if s.opts.Usage == nil || key == "" {
+		return
+	}
+	s.opts.Usage.Record(requestctx.KeyID(key), method, failed)
+}
+
+// KeyUsage returns the usage of an API key, by key id (requestctx.KeyID), over the
+// usage window of this server. Admin only.
+func (s *Service) KeyUsage(ctx context.Context, keyID string) (*usage.Report, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if s.opts.Usage == nil {
+		return nil, ErrKeyUsageNotFound
+	}
+	report, ok := s.opts.Usage.Usage(keyID, UsageTopMethods)
+	if !ok {
+		return nil, ErrKeyUsageNotFound
+	}
+	return report, nil
+}
+
+// TopKeyUsage returns the usage of the busiest API keys over the usage window of
+// this server, busiest first; limit 0 means DefaultUsageTopKeys. Admin only.
+func (s *Service) TopKeyUsage(ctx context.Context, limit int32) ([]usage.Report, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = DefaultUsageTopKeys
+	}
+	if limit < 0 || limit > MaxUsageTopKeys {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidLimit, MaxUsageTopKeys)
+	}
+	if s.opts.Usage == nil {
+		return nil, nil
+	}
+	return s.opts.Usage.Top(int(limit), UsageTopMethods), nil
+}
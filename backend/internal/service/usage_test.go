@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/usage"
+)
+
+func TestKeyUsage(t *testing.T) {
+	logger := zerolog.Nop()
+	svc := New(nil, &logger, Options{Admin: Admin{Token: "s3cret"}, Usage: usage.New(usage.Config{})})
+	ctx := context.Background()
+
+	// Requests without a key are not counted
+	svc.RecordUsage(ctx, "SubmitScore", false)
+	keyCtx := requestctx.NewContext(ctx, requestctx.Info{APIKey: "build-1.4"})
+	svc.RecordUsage(keyCtx, "SubmitScore", false)
+	svc.RecordUsage(keyCtx, "SubmitScore", true)
+
+	id := requestctx.KeyID("build-1.4")
+	if _, err := svc.KeyUsage(ctx, id); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated usage: error = %v, want ErrAdminUnauthorized", err)
+	}
+	ctx, _ = svc.AuthenticateAdmin(ctx, "s3cret")
+
+	r, err := svc.KeyUsage(ctx, id)
+	if err != nil {
+		t.Fatalf("KeyUsage: %v", err)
+	}
+	if r.Requests != 2 || r.Errors != 1 {
+		t.Errorf("usage = %+v, want 2 requests and 1 error", r)
+	}
+	if _, err := svc.KeyUsage(ctx, "k_0000000000000000"); !errors.Is(err, ErrKeyUsageNotFound) {
+		t.Errorf("unknown key: error = %v, want ErrKeyUsageNotFound", err)
+	}
+
+	top, err := svc.TopKeyUsage(ctx, 0)
+	if err != nil || len(top) != 1 || top[0].KeyID != id {
+		t.Errorf("TopKeyUsage = %+v, %v; want the one key", top, err)
+	}
+	if _, err := svc.TopKeyUsage(ctx, MaxUsageTopKeys+1); !errors.Is(err, ErrInvalidLimit) {
+		t.Errorf("limit over the max: error = %v, want ErrInvalidLimit", err)
+	}
+}
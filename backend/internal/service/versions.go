@@ -0,0 +1,55 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// boardVersions counts the changes of each board seen by this process, so reads
+// can be tagged with a version that moves whenever their result may have: HTTP
+// clients polling an unchanged board revalidate with an ETag instead of
+// downloading it again. A version is only meaningful within one process: it is
+// prefixed with an epoch drawn at startup, so that versions of a restarted
+// process or of another replica never match.
+type boardVersions struct {
+	epoch  string
+	global atomic.Uint64 // bumped by changes that may affect every board
+
+	mu     sync.Mutex
+	boards map[string]uint64
+}
+
+func newBoardVersions() boardVersions {
+	var b [8]byte
+	rand.Read(b[:])
+	return boardVersions{
+		epoch:  strconv.FormatUint(binary.BigEndian.Uint64(b[:]), 36),
+		boards: make(map[string]uint64),
+	}
+}
+
+// bump moves the version of a board, or of every board when board is empty
+func (v *boardVersions) bump(board string) {
+	if board == "" {
+		v.global.Add(1)
+		return
+	}
+	v.mu.Lock()
+	v.boards[board]++
+	v.mu.Unlock()
+}
+
+// BoardVersion returns the current version of a board's data: its scores, their
+// tiers and the profiles of its players. Read it before the data it tags: a change
+// landing in between then only costs the client a full response next time, where
+// reading it after could tag stale data with a current version.
+func (s *Service) BoardVersion(board string) string {
+	v := &s.versions
+	v.mu.Lock()
+	n := v.boards[board]
+	v.mu.Unlock()
+	return v.epoch + "." + strconv.FormatUint(v.global.Load(), 36) + "." + strconv.FormatUint(n, 36)
+}
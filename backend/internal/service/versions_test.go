@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+func TestBoardVersion(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{})
+
+	run := func(changes ...notify.ScoreChange) {
+		ch := make(chan notify.ScoreChange, len(changes))
+		for _, c := range changes {
+			ch <- c
+		}
+		close(ch)
+		svc.RunTopCache(ch)
+	}
+
+	global, level := svc.BoardVersion(DefaultLeaderboardID), svc.BoardVersion("level-1")
+	if svc.BoardVersion(DefaultLeaderboardID) != global {
+		t.Fatalf("version %q moved without a change", global)
+	}
+
+	// A change moves the version of its board only
+	run(notify.ScoreChange{LeaderboardID: "level-1", PlayerName: "Alice", Score: 100, Op: "insert"})
+	if v := svc.BoardVersion("level-1"); v == level {
+		t.Error("level-1 version unchanged by its change")
+	} else {
+		level = v
+	}
+	if svc.BoardVersion(DefaultLeaderboardID) != global {
+		t.Error("global version moved by a change of level-1")
+	}
+
+	// A resync of every board and a profile update move every version
+	run(notify.ScoreChange{Op: notify.OpResync})
+	if svc.BoardVersion(DefaultLeaderboardID) == global || svc.BoardVersion("level-1") == level {
+		t.Error("versions unchanged by a resync of every board")
+	}
+	global = svc.BoardVersion(DefaultLeaderboardID)
+	if _, err := svc.UpsertPlayerProfile(ctx, ProfileUpdate{PlayerName: "Alice", DisplayName: "Ally"}); err != nil {
+		t.Fatalf("UpsertPlayerProfile: %v", err)
+	}
+	if svc.BoardVersion(DefaultLeaderboardID) == global {
+		t.Error("version unchanged by a profile update")
+	}
+
+	// Another process never hands out the same versions
+	other := New(st, &logger, Options{})
+	if other.BoardVersion("level-2") == svc.BoardVersion("level-2") {
+		t.Error("two services share a version")
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/webhook"
+)
+
+var (
+	// ErrInvalidWebhook is returned when a webhook URL or event list is malformed
+	ErrInvalidWebhook = errors.New("invalid webhook")
+
+	// ErrWebhookNotFound is returned when no webhook has the requested id
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrWebhooksUnavailable is returned by webhook operations on a storage backend without webhooks
+	ErrWebhooksUnavailable = errors.New("webhooks are not available with this storage backend")
+)
+
+// Webhook limits
+const (
+	MaxWebhookURLLength      = 2048
+	DefaultWebhookDeliveries = 50
+	MaxWebhookDeliveries     = 500
+)
+
+// WebhookSpec is the settings of a webhook
+type WebhookSpec struct {
+	URL           string   // http or https endpoint receiving the POSTs
+	Events        []string // event types, see webhook.EventTypes
+	LeaderboardID string   // board the events are limited to; empty for every board
+	Enabled       bool
+}
+
+// CreateWebhook registers a webhook and returns it with its signing secret, which
+// is not returned again. Admin only.
+func (s *Service) CreateWebhook(ctx context.Context, spec WebhookSpec) (*store.Webhook, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	board, err := validateWebhook(&spec)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	hook, err := s.store.CreateWebhook(ctx, store.CreateWebhookParams{
+		Url:           spec.URL,
+		Secret:        "whsec_" + hex.EncodeToString(secret),
+		Events:        spec.Events,
+		LeaderboardID: board,
+		Enabled:       spec.Enabled,
+	})
+	if err != nil {
+		return nil, webhookError("create webhook", err)
+	}
+	s.loggerFor(ctx).Info().Int64("webhook_id", hook.ID).Str("url", hook.Url).Strs("events", hook.Events).Msg("📮 webhook registered")
+	return &hook, nil
+}
+
+// ListWebhooks returns every webhook, secrets cleared. Admin only.
+func (s *Service) ListWebhooks(ctx context.Context) ([]store.Webhook, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	hooks, err := s.store.ListWebhooks(ctx)
+	if err != nil {
+		return nil, webhookError("list webhooks", err)
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+// GetWebhook returns a webhook, secret cleared. Admin only.
+func (s *Service) GetWebhook(ctx context.Context, id int64) (*store.Webhook, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	hook, err := s.store.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, webhookError("get webhook", err)
+	}
+	hook.Secret = ""
+	return &hook, nil
+}
+
+// UpdateWebhook replaces the settings of a webhook; its secret is kept. Admin only.
+func (s *Service) UpdateWebhook(ctx context.Context, id int64, spec WebhookSpec) (*store.Webhook, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	board, err := validateWebhook(&spec)
+	if err != nil {
+		return nil, err
+	}
+	hook, err := s.store.UpdateWebhook(ctx, store.UpdateWebhookParams{
+		ID:            id,
+		Url:           spec.URL,
+		Events:        spec.Events,
+		LeaderboardID: board,
+		Enabled:       spec.Enabled,
+	})
+	if err != nil {
+		return nil, webhookError("update webhook", err)
+	}
+	hook.Secret = ""
+	return &hook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log. Admin only.
+func (s *Service) DeleteWebhook(ctx context.Context, id int64) error {
+	if err := s.requireAdmin(ctx); err != nil {
+		return err
+	}
+	deleted, err := s.store.DeleteWebhook(ctx, id)
+	if err != nil {
+		return webhookError("delete webhook", err)
+	}
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+	s.loggerFor(ctx).Info().Int64("webhook_id", id).Msg("webhook deleted")
+	return nil
+}
+
+// ListWebhookDeliveries returns the most recent deliveries of a webhook, newest
+// first; limit 0 means DefaultWebhookDeliveries. Admin only.
+func (s *Service) ListWebhookDeliveries(ctx context.Context, id int64, limit int32) ([]store.WebhookDelivery, error) {
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = DefaultWebhookDeliveries
+	}
+	if limit < 0 || limit > MaxWebhookDeliveries {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidLimit, MaxWebhookDeliveries)
+	}
+	if _, err := s.store.GetWebhook(ctx, id); err != nil {
+		return nil, webhookError("get webhook", err)
+	}
+	deliveries, err := s.store.ListWebhookDeliveries(ctx, store.ListWebhookDeliveriesParams{WebhookID: id, MaxDeliveries: limit})
+	if err != nil {
+		return nil, webhookError("list webhook deliveries", err)
+	}
+	return deliveries, nil
+}
+
+// validateWebhook checks a spec, normalizing its event list, and returns its board column
+func validateWebhook(spec *WebhookSpec) (pgtype.Text, error) {
+	if len(spec.URL) > MaxWebhookURLLength {
+		return pgtype.Text{}, fmt.Errorf("%w: url must be at most %d characters", ErrInvalidWebhook, MaxWebhookURLLength)
+	}
+	u, err := url.Parse(spec.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return pgtype.Text{}, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if len(spec.Events) == 0 {
+		return pgtype.Text{}, fmt.Errorf("%w: at least one event type is required", ErrInvalidWebhook)
+	}
+	for _, e := range spec.Events {
+		if !webhook.ValidEventType(e) {
+			return pgtype.Text{}, fmt.Errorf("%w: unknown event type %q, expected one of %v", ErrInvalidWebhook, e, webhook.EventTypes)
+		}
+	}
+	slices.Sort(spec.Events)
+	spec.Events = slices.Compact(spec.Events)
+
+	if spec.LeaderboardID == "" {
+		return pgtype.Text{}, nil
+	}
+	board, err := ResolveLeaderboardID(spec.LeaderboardID)
+	if err != nil {
+		return pgtype.Text{}, err
+	}
+	return pgtype.Text{String: board, Valid: true}, nil
+}
+
+// webhookError maps store errors of webhook operations to service errors
+func webhookError(op string, err error) error {
+	switch {
+	case errors.Is(err, store.ErrNoRows):
+		return ErrWebhookNotFound
+	case errors.Is(err, store.ErrNotSupported):
+		return ErrWebhooksUnavailable
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
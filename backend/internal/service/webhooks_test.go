@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+	"github.com/yourorg/leaderboard/internal/webhook"
+)
+
+func TestValidateWebhook(t *testing.T) {
+	spec := WebhookSpec{
+		URL:           "https://hooks.example.com/leaderboard",
+		Events:        []string{webhook.EventScoreDeleted, webhook.EventHighScore, webhook.EventScoreDeleted},
+		LeaderboardID: "level-42",
+	}
+	board, err := validateWebhook(&spec)
+	if err != nil {
+		t.Fatalf("valid spec: %v", err)
+	}
+	if !board.Valid || board.String != "level-42" {
+		t.Errorf("board = %+v, want level-42", board)
+	}
+	if want := []string{webhook.EventScoreDeleted, webhook.EventHighScore}; !slices.Equal(spec.Events, want) {
+		t.Errorf("events = %v, want sorted and deduplicated %v", spec.Events, want)
+	}
+
+	spec.LeaderboardID = ""
+	if board, _ := validateWebhook(&spec); board.Valid {
+		t.Errorf("no board: column = %+v, want NULL", board)
+	}
+
+	for name, bad := range map[string]WebhookSpec{
+		"relative url":  {URL: "/hooks", Events: []string{webhook.EventHighScore}},
+		"ftp url":       {URL: "ftp://hooks.example.com", Events: []string{webhook.EventHighScore}},
+		"long url":      {URL: "https://example.com/" + strings.Repeat("a", MaxWebhookURLLength), Events: []string{webhook.EventHighScore}},
+		"no events":     {URL: "https://hooks.example.com"},
+		"unknown event": {URL: "https://hooks.example.com", Events: []string{"score.lowered"}},
+		"invalid board": {URL: "https://hooks.example.com", Events: []string{webhook.EventHighScore}, LeaderboardID: "bad board!"},
+	} {
+		if _, err := validateWebhook(&bad); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestWebhooksWithoutSupport(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := New(st, &logger, Options{Admin: Admin{Token: "s3cret"}})
+	spec := WebhookSpec{URL: "https://hooks.example.com", Events: []string{webhook.EventHighScore}, Enabled: true}
+
+	if _, err := svc.CreateWebhook(ctx, spec); !errors.Is(err, ErrAdminUnauthorized) {
+		t.Fatalf("unauthenticated create: error = %v, want ErrAdminUnauthorized", err)
+	}
+	ctx, _ = svc.AuthenticateAdmin(ctx, "s3cret")
+
+	if _, err := svc.CreateWebhook(ctx, spec); !errors.Is(err, ErrWebhooksUnavailable) {
+		t.Errorf("create on sqlite: error = %v, want ErrWebhooksUnavailable", err)
+	}
+	if _, err := svc.ListWebhookDeliveries(ctx, 1, MaxWebhookDeliveries+1); !errors.Is(err, ErrInvalidLimit) {
+		t.Errorf("deliveries over the limit: error = %v, want ErrInvalidLimit", err)
+	}
+	if err := svc.DeleteWebhook(ctx, 1); !errors.Is(err, ErrWebhooksUnavailable) {
+		t.Errorf("delete on sqlite: error = %v, want ErrWebhooksUnavailable", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package status builds the public status page payload: a stable, player-facing
+// summary of the service, distinct from the internal health checks.
+package status
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/health"
+	"github.com/yourorg/leaderboard/internal/service"
+)
+
+// Overall status values
+const (
+	Operational = "operational" // everything works
+	Degraded    = "degraded"    // scores are accepted but something is slow or partially failing
+	Outage      = "outage"      // scores cannot be read or submitted
+)
+
+// GlobalBoardID identifies the global leaderboard, the only board reported on the status page
+const GlobalBoardID = service.DefaultLeaderboardID
+
+// shedWindow is how long after a shed write load shedding is reported
+const shedWindow = time.Minute
+
+// Board summarizes one leaderboard
+type Board struct {
+	ID            string `json:"id" example:"global"`
+	Players       int64  `json:"players" example:"1200"`
+	LastUpdatedAt string `json:"last_updated_at,omitempty" example:"2025-01-15T10:30:00Z"` // empty while the board has no scores
+}
+
+// DegradedFlags tells which parts of the service are impaired
+type DegradedFlags struct {
+	Storage         bool `json:"storage"`          // database unreachable: boards show the last known data
+	RealtimeUpdates bool `json:"realtime_updates"` // live leaderboard updates are delayed
+	LoadShedding    bool `json:"load_shedding"`    // some submissions were recently rejected, clients retry
+}
+
+// Status is the public status page payload. Fields are only ever added.
+type Status struct {
+	Status        string        `json:"status" example:"operational"`
+	StartedAt     string        `json:"started_at" example:"2025-01-15T08:00:00Z"`
+	UptimeSeconds int64         `json:"uptime_seconds" example:"9000"`
+	Boards        []Board       `json:"boards"`
+	Degraded      DegradedFlags `json:"degraded"`
+	GeneratedAt   string        `json:"generated_at" example:"2025-01-15T10:30:00Z"`
+}
+
+// Reporter builds and caches the status payload
+type Reporter struct {
+	svc       *service.Service
+	checker   *health.Checker
+	startedAt time.Time
+	ttl       time.Duration
+
+	mu       sync.Mutex
+	cached   *Status
+	cachedAt time.Time
+	boards   []Board // last successfully read boards
+}
+
+// NewReporter creates a status reporter. Results are cached for ttl so a busy
+// status page does not add database load.
+func NewReporter(svc *service.Service, checker *health.Checker, startedAt time.Time, ttl time.Duration) *Reporter {
+	return &Reporter{
+		svc:       svc,
+		checker:   checker,
+		startedAt: startedAt,
+		ttl:       ttl,
+		boards:    []Board{},
+	}
+}
+
+// Report returns the current status, from cache when fresh
+func (r *Reporter) Report(ctx context.Context) Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.cached != nil && now.Sub(r.cachedAt) < r.ttl {
+		return *r.cached
+	}
+
+	report := r.checker.Check(ctx)
+	flags := DegradedFlags{
+		Storage:         report.Checks["database"].Status != health.StatusOK,
+		RealtimeUpdates: report.Checks["notify"].Status != health.StatusOK,
+		LoadShedding:    now.Sub(r.svc.LastShed()) < shedWindow,
+	}
+
+	if !flags.Storage {
+		if stats, err := r.svc.GetBoardStats(ctx, GlobalBoardID); err != nil {
+			flags.Storage = true
+		} else {
+			board := Board{ID: GlobalBoardID, Players: stats.Players}
+			if !stats.LastUpdatedAt.IsZero() {
+				board.LastUpdatedAt = stats.LastUpdatedAt.UTC().Format(time.RFC3339)
+			}
+			r.boards = []Board{board}
+		}
+	}
+
+	overall := Operational
+	switch {
+	case flags.Storage:
+		overall = Outage
+	case flags.RealtimeUpdates || flags.LoadShedding:
+		overall = Degraded
+	}
+
+	st := &Status{
+		Status:        overall,
+		StartedAt:     r.startedAt.UTC().Format(time.RFC3339),
+		UptimeSeconds: int64(now.Sub(r.startedAt).Seconds()),
+		Boards:        r.boards,
+		Degraded:      flags,
+		GeneratedAt:   now.UTC().Format(time.RFC3339),
+	}
+	r.cached, r.cachedAt = st, now
+	return *st
+}
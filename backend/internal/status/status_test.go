@@ -0,0 +1,68 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/health"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+)
+
+type fakeSource struct{ listening bool }
+
+func (s *fakeSource) Start(context.Context)              {}
+func (s *fakeSource) Stop(context.Context) error         { return nil }
+func (s *fakeSource) Done() <-chan struct{}              { return nil }
+func (s *fakeSource) Changes() <-chan notify.ScoreChange { return nil }
+func (s *fakeSource) Errors() <-chan error               { return nil }
+func (s *fakeSource) Listening() bool                    { return s.listening }
+
+func TestReport(t *testing.T) {
+	ctx := context.Background()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+
+	logger := zerolog.Nop()
+	svc := service.New(st, &logger, service.Options{})
+	source := &fakeSource{listening: true}
+	checker := health.NewChecker(st, source, func() int { return 0 }, time.Second, &logger)
+	startedAt := time.Now().Add(-time.Hour)
+
+	empty := NewReporter(svc, checker, startedAt, 0).Report(ctx)
+	if empty.Status != Operational || len(empty.Boards) != 1 || empty.Boards[0].Players != 0 || empty.Boards[0].LastUpdatedAt != "" {
+		t.Errorf("empty board status = %+v, want operational with 0 players and no last update", empty)
+	}
+	if empty.UptimeSeconds < 3600 {
+		t.Errorf("uptime = %d, want >= 3600", empty.UptimeSeconds)
+	}
+
+	for _, name := range []string{"Alice", "Bob"} {
+		if _, err := svc.SubmitScore(ctx, service.ScoreSubmission{PlayerName: name, Score: 10}); err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+
+	r := NewReporter(svc, checker, startedAt, time.Hour)
+	got := r.Report(ctx)
+	if got.Boards[0].ID != GlobalBoardID || got.Boards[0].Players != 2 || got.Boards[0].LastUpdatedAt == "" {
+		t.Errorf("boards = %+v, want global board with 2 players and a last update", got.Boards)
+	}
+
+	// Cached within the TTL even though the listener went down
+	source.listening = false
+	if cached := r.Report(ctx); cached.Status != Operational {
+		t.Errorf("cached status = %s, want %s", cached.Status, Operational)
+	}
+
+	degraded := NewReporter(svc, checker, startedAt, 0).Report(ctx)
+	if degraded.Status != Degraded || !degraded.Degraded.RealtimeUpdates || degraded.Degraded.Storage {
+		t.Errorf("listener down: status = %+v, want degraded with realtime_updates", degraded)
+	}
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Archiver moves stale scores off the live boards into scores_archive, for the
+// archival job. It changes session settings and spans two tables, so it is
+// written by hand.
+type Archiver interface {
+	// ArchiveScores moves up to policy.Limit scores matching the policy into
+	// scores_archive in a single transaction. Change listeners receive one
+	// resync of each board instead of a delete per row. With policy.DryRun
+	// nothing is moved and every matching score is counted.
+	ArchiveScores(ctx context.Context, policy ArchivePolicy) (ArchiveResult, error)
+
+	// ListArchiveCandidates lists the scores matching a policy, oldest update
+	// first, without moving them: the same rules as ArchiveScores select them.
+	ListArchiveCandidates(ctx context.Context, arg ListArchiveCandidatesParams) ([]ArchiveCandidate, error)
+}
+
+// ArchivePolicy selects the scores to archive: a score matching either rule is
+// archived, soft-deleted scores included
+type ArchivePolicy struct {
+	UpdatedBefore time.Time // scores not updated since; zero disables the rule
+	// ScoreFloor archives the scores ranked worse than it: below it on 'desc'
+	// boards, above it on 'asc' boards; 0 disables the rule
+	ScoreFloor int64
+	Limit      int32 // scores moved per call, ignored by dry runs
+	DryRun     bool
+}
+
+// Reasons of archived scores, recorded in scores_archive
+const (
+	ArchiveReasonRetention  = "retention"   // not updated since UpdatedBefore
+	ArchiveReasonScoreFloor = "score_floor" // ranked worse than ScoreFloor, and updated since UpdatedBefore
+)
+
+// ArchiveResult reports the scores ArchiveScores moved, or would move on a dry run
+type ArchiveResult struct {
+	Boards []ArchivedBoard // by leaderboard id
+}
+
+// ArchivedBoard counts the archived scores of a board by reason
+type ArchivedBoard struct {
+	LeaderboardID string
+	Retention     int64
+	ScoreFloor    int64
+}
+
+// Total returns the number of archived scores
+func (r ArchiveResult) Total() int64 {
+	var n int64
+	for _, b := range r.Boards {
+		n += b.Retention + b.ScoreFloor
+	}
+	return n
+}
+
+// Add counts n scores of a board archived for reason
+func (r *ArchiveResult) Add(board, reason string, n int64) {
+	i, found := slices.BinarySearchFunc(r.Boards, board, func(b ArchivedBoard, id string) int {
+		return strings.Compare(b.LeaderboardID, id)
+	})
+	if !found {
+		r.Boards = slices.Insert(r.Boards, i, ArchivedBoard{LeaderboardID: board})
+	}
+	if reason == ArchiveReasonRetention {
+		r.Boards[i].Retention += n
+	} else {
+		r.Boards[i].ScoreFloor += n
+	}
+}
+
+// ListArchiveCandidatesParams selects a page of the scores matching Policy;
+// Policy.Limit and Policy.DryRun are ignored
+type ListArchiveCandidatesParams struct {
+	Policy ArchivePolicy
+	After  *ArchiveCandidate // last candidate of the previous page, nil for the first one
+	Limit  int32
+}
+
+// ArchiveCandidate is a score matching an archive policy
+type ArchiveCandidate struct {
+	LeaderboardID string
+	PlayerName    string
+	Score         int64
+	UpdatedAt     time.Time
+	BelowFloor    bool // matches the ScoreFloor rule, whatever its UpdatedAt
+}
+
+var _ Archiver = (*Store)(nil)
+
+// archiveFrom and archiveReason match scores s of boards l against the policy:
+// $1 is UpdatedBefore (NULL when disabled) and $2 the ScoreFloor, compared in
+// ranking space, where worse is always lower
+const (
+	archiveBelowFloor = `($2::bigint <> 0 AND s.rank_score < CASE WHEN l.sort_order = 'asc' THEN -$2::bigint ELSE $2::bigint END)`
+	archiveFrom       = `
+		FROM scores s LEFT JOIN leaderboards l ON l.leaderboard_id = s.leaderboard_id
+		WHERE s.updated_at < $1::timestamptz OR ` + archiveBelowFloor
+	archiveReason = `CASE WHEN s.updated_at < $1::timestamptz THEN 'retention' ELSE 'score_floor' END`
+
+	listArchiveCandidatesQuery = `
+		SELECT * FROM (
+			SELECT s.leaderboard_id, s.player_name, s.score, s.updated_at, ` + archiveBelowFloor + ` AS below_floor` + archiveFrom + `
+		) c
+		WHERE $3::timestamptz IS NULL OR (c.updated_at, c.leaderboard_id, c.player_name) > ($3, $4, $5)
+		ORDER BY c.updated_at, c.leaderboard_id, c.player_name
+		LIMIT $6`
+	countArchivableQuery = `
+		SELECT s.leaderboard_id, ` + archiveReason + `, COUNT(*)` + archiveFrom + `
+		GROUP BY 1, 2`
+	archiveScoresQuery = `
+		WITH doomed AS (
+			SELECT s.leaderboard_id, s.player_name, ` + archiveReason + ` AS reason` + archiveFrom + `
+			LIMIT $3
+			FOR UPDATE OF s SKIP LOCKED
+		), moved AS (
+			DELETE FROM scores s USING doomed d
+			WHERE s.leaderboard_id = d.leaderboard_id AND s.player_name = d.player_name
+			RETURNING s.player_name, s.score, s.updated_at, s.achieved_at, s.client_achieved_at, s.leaderboard_id,
+			          s.rank_score, s.deleted_at, s.metadata, s.secondary_score, s.rank_secondary, s.country_code,
+			          s.platform, d.reason
+		)
+		INSERT INTO scores_archive (` + scoreColumns + `, reason)
+		SELECT * FROM moved
+		RETURNING leaderboard_id, reason`
+)
+
+// ListArchiveCandidates pages with a keyset on (updated_at, leaderboard_id, player_name)
+func (s *Store) ListArchiveCandidates(ctx context.Context, arg ListArchiveCandidatesParams) ([]ArchiveCandidate, error) {
+	var (
+		afterAt                 pgtype.Timestamptz
+		afterBoard, afterPlayer string
+	)
+	if arg.After != nil {
+		afterAt = pgtype.Timestamptz{Time: arg.After.UpdatedAt, Valid: true}
+		afterBoard, afterPlayer = arg.After.LeaderboardID, arg.After.PlayerName
+	}
+	rows, err := s.db.Query(ctx, listArchiveCandidatesQuery,
+		archiveCutoff(arg.Policy), arg.Policy.ScoreFloor, afterAt, afterBoard, afterPlayer, arg.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list archive candidates: %w", err)
+	}
+	var (
+		candidates []ArchiveCandidate
+		c          ArchiveCandidate
+		updatedAt  pgtype.Timestamptz
+	)
+	if _, err := pgx.ForEachRow(rows, []any{&c.LeaderboardID, &c.PlayerName, &c.Score, &updatedAt, &c.BelowFloor}, func() error {
+		c.UpdatedAt = updatedAt.Time
+		candidates = append(candidates, c)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list archive candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+// archiveCutoff returns the UpdatedBefore parameter of a policy, NULL when disabled
+func archiveCutoff(policy ArchivePolicy) pgtype.Timestamptz {
+	if policy.UpdatedBefore.IsZero() {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: policy.UpdatedBefore, Valid: true}
+}
+
+// ArchiveScores skips the scores locked by a write in flight: they were just
+// updated, or are picked up by the next call
+func (s *Store) ArchiveScores(ctx context.Context, policy ArchivePolicy) (ArchiveResult, error) {
+	updatedBefore := archiveCutoff(policy)
+
+	var res ArchiveResult
+	if policy.DryRun {
+		rows, err := s.db.Query(ctx, countArchivableQuery, updatedBefore, policy.ScoreFloor)
+		if err != nil {
+			return ArchiveResult{}, fmt.Errorf("count scores: %w", err)
+		}
+		var (
+			board, reason string
+			n             int64
+		)
+		if _, err := pgx.ForEachRow(rows, []any{&board, &reason, &n}, func() error {
+			res.Add(board, reason, n)
+			return nil
+		}); err != nil {
+			return ArchiveResult{}, fmt.Errorf("count scores: %w", err)
+		}
+		return res, nil
+	}
+
+	tx, err := s.begin(ctx, pgx.TxOptions{})
+	if err != nil {
+		return ArchiveResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	if _, err := tx.Exec(ctx, suppressNotifyQuery); err != nil {
+		return ArchiveResult{}, fmt.Errorf("suppress notifications: %w", err)
+	}
+	rows, err := tx.Query(ctx, archiveScoresQuery, updatedBefore, policy.ScoreFloor, policy.Limit)
+	if err != nil {
+		return ArchiveResult{}, fmt.Errorf("archive scores: %w", err)
+	}
+	var board, reason string
+	if _, err := pgx.ForEachRow(rows, []any{&board, &reason}, func() error {
+		res.Add(board, reason, 1)
+		return nil
+	}); err != nil {
+		return ArchiveResult{}, fmt.Errorf("archive scores: %w", err)
+	}
+	for _, b := range res.Boards {
+		if _, err := tx.Exec(ctx, notifyResyncQuery, b.LeaderboardID); err != nil {
+			return ArchiveResult{}, fmt.Errorf("notify resync: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ArchiveResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
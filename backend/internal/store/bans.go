@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BanManager keeps the list of banned players. Its reads are sqlc queries, also
+// part of Querier; its writes are written by hand, BanPlayer for its transaction
+// and UnbanPlayer to report a missing ban.
+type BanManager interface {
+	// BanPlayer bans a player, or replaces the reason of an existing ban. With
+	// purge, the player's live scores are soft-deleted in the same transaction:
+	// change listeners receive a delete of each, and RestoreScore can still
+	// bring them back.
+	BanPlayer(ctx context.Context, playerName, reason string, purge bool) (BanResult, error)
+
+	// UnbanPlayer lifts a ban; ErrNoRows when the player is not banned.
+	// Purged scores stay deleted.
+	UnbanPlayer(ctx context.Context, playerName string) error
+
+	// GetBannedPlayer returns the ban of a player; ErrNoRows when not banned
+	GetBannedPlayer(ctx context.Context, playerName string) (BannedPlayer, error)
+
+	// ListBannedPlayers returns every ban, most recent first
+	ListBannedPlayers(ctx context.Context) ([]BannedPlayer, error)
+}
+
+// BanResult reports what BanPlayer changed
+type BanResult struct {
+	Ban    BannedPlayer
+	Purged []string // boards where a live score of the player was deleted
+}
+
+var _ BanManager = (*Store)(nil)
+
+const (
+	banColumns     = `player_name, reason, banned_at`
+	banPlayerQuery = `
+		INSERT INTO banned_players (player_name, reason) VALUES ($1, $2)
+		ON CONFLICT (player_name) DO UPDATE SET reason = excluded.reason
+		RETURNING ` + banColumns
+	purgeScoresQuery = `
+		UPDATE scores SET deleted_at = now()
+		WHERE player_name = $1 AND deleted_at IS NULL
+		RETURNING leaderboard_id`
+	unbanPlayerQuery = `DELETE FROM banned_players WHERE player_name = $1`
+)
+
+// BanPlayer writes the ban before purging, so a submission racing the ban
+// either lands before the purge deletes it or is rejected
+func (s *Store) BanPlayer(ctx context.Context, playerName, reason string, purge bool) (BanResult, error) {
+	tx, err := s.begin(ctx, pgx.TxOptions{})
+	if err != nil {
+		return BanResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	bans, err := collectRows[BannedPlayer](ctx, tx, banPlayerQuery, playerName, reason)
+	if err != nil {
+		return BanResult{}, fmt.Errorf("ban player: %w", err)
+	}
+	res := BanResult{Ban: bans[0]}
+	if purge {
+		rows, err := tx.Query(ctx, purgeScoresQuery, playerName)
+		if err != nil {
+			return BanResult{}, fmt.Errorf("purge scores: %w", err)
+		}
+		if res.Purged, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+			return BanResult{}, fmt.Errorf("purge scores: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return BanResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
+
+func (s *Store) UnbanPlayer(ctx context.Context, playerName string) error {
+	tag, err := s.db.Exec(ctx, unbanPlayerQuery, playerName)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ScoreBatcher writes many scores at once for bulk imports
+type ScoreBatcher interface {
+	// UpsertScores applies UpsertScore to every row, in order, in a single
+	// transaction: either every row is written or none is
+	UpsertScores(ctx context.Context, rows []UpsertScoreParams) ([]UpsertedScore, error)
+}
+
+// UpsertedScore is a player's best score after a batched upsert
+type UpsertedScore struct {
+	Score
+	Previous *Score // best before the upsert, nil for a new entry
+	Applied  bool   // the upserted score became the player's best
+}
+
+var _ ScoreBatcher = (*Store)(nil)
+
+// UpsertScores locks each row before upserting it so Previous and Applied are exact under concurrent writes
+func (s *Store) UpsertScores(ctx context.Context, rows []UpsertScoreParams) ([]UpsertedScore, error) {
+	tx, err := s.begin(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	q := s.Queries.WithTx(tx)
+	out := make([]UpsertedScore, len(rows))
+	for i, row := range rows {
+		if out[i], err = upsertBest(ctx, q, row, nil); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+// upsertBest upserts a score in a transaction, reading the previous best under a
+// row lock so that Previous and Applied describe this very write. A first score
+// is inserted with ON CONFLICT DO NOTHING: when a concurrent transaction inserted
+// the player first, its committed row is locked and upserted instead. locked,
+// when set, runs once the previous best is locked, before it changes. q is the
+// transaction's Queries, or a fake in tests.
+func upsertBest(ctx context.Context, q Querier, row UpsertScoreParams, locked func(prev Score) error) (UpsertedScore, error) {
+	key := GetScoreForUpdateParams{LeaderboardID: row.LeaderboardID, PlayerName: row.PlayerName}
+	prev, err := q.GetScoreForUpdate(ctx, key)
+	if errors.Is(err, ErrNoRows) {
+		inserted, insertErr := q.InsertScore(ctx, InsertScoreParams(row))
+		if insertErr == nil {
+			return UpsertedScore{Score: inserted, Applied: true}, nil
+		}
+		if !errors.Is(insertErr, ErrNoRows) {
+			return UpsertedScore{}, fmt.Errorf("insert score: %w", insertErr)
+		}
+		prev, err = q.GetScoreForUpdate(ctx, key)
+	}
+	if err != nil {
+		return UpsertedScore{}, fmt.Errorf("get current score: %w", err)
+	}
+	if locked != nil {
+		if err := locked(prev); err != nil {
+			return UpsertedScore{}, err
+		}
+	}
+
+	sc, err := q.UpsertScore(ctx, row)
+	if err != nil {
+		return UpsertedScore{}, fmt.Errorf("upsert score: %w", err)
+	}
+	return UpsertedScore{Score: sc, Previous: &prev, Applied: Outranks(sc, prev)}, nil
+}
+
+// Outranks reports whether score a is better than b in the board's order: a higher
+// rank_score, or an equal one with a higher rank_secondary. Ties on both are not.
+func Outranks(a, b Score) bool {
+	if a.RankScore != b.RankScore {
+		return a.RankScore > b.RankScore
+	}
+	return a.RankSecondary > b.RankSecondary
+}
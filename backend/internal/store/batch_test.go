@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeQuerier keeps the best scores of one board in memory. Queries upsertBest
+// does not use panic through the nil embedded interface.
+type fakeQuerier struct {
+	Querier
+
+	scores map[string]Score
+	raced  *Score // inserted by a concurrent transaction just before InsertScore
+}
+
+func (q *fakeQuerier) GetScoreForUpdate(_ context.Context, arg GetScoreForUpdateParams) (Score, error) {
+	sc, ok := q.scores[arg.PlayerName]
+	if !ok {
+		return Score{}, ErrNoRows
+	}
+	return sc, nil
+}
+
+func (q *fakeQuerier) InsertScore(_ context.Context, arg InsertScoreParams) (Score, error) {
+	if q.raced != nil {
+		q.scores[q.raced.PlayerName] = *q.raced
+		return Score{}, ErrNoRows
+	}
+	sc := Score{LeaderboardID: arg.LeaderboardID, PlayerName: arg.PlayerName, Score: arg.Score, RankScore: arg.Score, SecondaryScore: arg.SecondaryScore, RankSecondary: arg.SecondaryScore}
+	q.scores[arg.PlayerName] = sc
+	return sc, nil
+}
+
+// UpsertScore keeps the best score of a 'desc' board
+func (q *fakeQuerier) UpsertScore(_ context.Context, arg UpsertScoreParams) (Score, error) {
+	sc := Score{LeaderboardID: arg.LeaderboardID, PlayerName: arg.PlayerName, Score: arg.Score, RankScore: arg.Score, SecondaryScore: arg.SecondaryScore, RankSecondary: arg.SecondaryScore}
+	if prev, ok := q.scores[arg.PlayerName]; ok && !Outranks(sc, prev) {
+		return prev, nil
+	}
+	q.scores[arg.PlayerName] = sc
+	return sc, nil
+}
+
+func TestUpsertBest(t *testing.T) {
+	ctx := context.Background()
+	row := func(score, secondary int64) UpsertScoreParams {
+		return UpsertScoreParams{LeaderboardID: DefaultLeaderboardID, PlayerName: "Alice", Score: score, SecondaryScore: secondary}
+	}
+	held := Score{LeaderboardID: DefaultLeaderboardID, PlayerName: "Alice", Score: 100, RankScore: 100, SecondaryScore: 5, RankSecondary: 5}
+
+	tests := []struct {
+		name        string
+		held        *Score // Alice's best before the upsert
+		raced       bool   // a concurrent transaction inserts held first
+		row         UpsertScoreParams
+		wantApplied bool
+		wantScore   int64
+	}{
+		{"first score", nil, false, row(100, 0), true, 100},
+		{"improved", &held, false, row(150, 0), true, 150},
+		{"lower", &held, false, row(50, 9), false, 100},
+		{"equal", &held, false, row(100, 5), false, 100},
+		{"better tiebreaker", &held, false, row(100, 6), true, 100},
+		{"lost insert race", &held, true, row(90, 0), false, 100},
+		{"won over insert race", &held, true, row(120, 0), true, 120},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fakeQuerier{scores: make(map[string]Score)}
+			if tt.raced {
+				q.raced = tt.held
+			} else if tt.held != nil {
+				q.scores["Alice"] = *tt.held
+			}
+
+			var locked *Score
+			got, err := upsertBest(ctx, q, tt.row, func(prev Score) error {
+				locked = &prev
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("upsertBest: %v", err)
+			}
+			if got.Applied != tt.wantApplied || got.Score.Score != tt.wantScore {
+				t.Errorf("got applied %v, score %d, want %v, %d", got.Applied, got.Score.Score, tt.wantApplied, tt.wantScore)
+			}
+			if tt.held == nil {
+				if got.Previous != nil || locked != nil {
+					t.Errorf("first score has previous %v, locked %v", got.Previous, locked)
+				}
+				return
+			}
+			same := func(sc *Score) bool {
+				return sc != nil && sc.Score == tt.held.Score && sc.RankSecondary == tt.held.RankSecondary
+			}
+			if !same(got.Previous) || !same(locked) {
+				t.Errorf("previous = %v, locked %v, want %v", got.Previous, locked, *tt.held)
+			}
+		})
+	}
+}
+
+func TestUpsertBestLockedError(t *testing.T) {
+	q := &fakeQuerier{scores: map[string]Score{"Alice": {PlayerName: "Alice", Score: 100, RankScore: 100}}}
+	errRank := errors.New("rank failed")
+	_, err := upsertBest(context.Background(), q, UpsertScoreParams{PlayerName: "Alice", Score: 200}, func(Score) error { return errRank })
+	if !errors.Is(err, errRank) {
+		t.Errorf("upsertBest error = %v, want %v", err, errRank)
+	}
+	if q.scores["Alice"].Score != 100 {
+		t.Errorf("score written although locked failed: %v", q.scores["Alice"])
+	}
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+// ErrUnavailable is returned, as an *UnavailableError, by queries the circuit
+// breaker rejects without reaching the database
+var ErrUnavailable = errors.New("database unavailable")
+
+// UnavailableError is the error of a query rejected by the open circuit breaker
+type UnavailableError struct {
+	// RetryAfter is when the breaker lets a query through again, 0 when a probe
+	// query is already under way
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%v, retry in %s", ErrUnavailable, e.RetryAfter.Round(time.Second))
+}
+
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// SetCircuitBreaker makes the store fail fast once threshold queries in a row
+// could not reach the database (connection failures and timeouts, not errors
+// the database answered with): for cooldown, queries fail with ErrUnavailable
+// without waiting on a connection. Then a single probe query is let through; its
+// success closes the circuit, its failure opens it for another cooldown. A
+// threshold of 0, the default, disables the breaker. Call it before serving.
+func (s *Store) SetCircuitBreaker(threshold int, cooldown time.Duration, logger *zerolog.Logger) {
+	if threshold <= 0 {
+		s.db.breaker = nil
+		return
+	}
+	s.db.breaker = &breaker{threshold: threshold, cooldown: cooldown, logger: logger}
+}
+
+// breaker is a consecutive-failure circuit breaker. Its methods accept a nil
+// breaker, which lets every query through.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	logger    *zerolog.Logger
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow returns nil when a query may be sent now, or the *UnavailableError
+// rejecting it
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(b.openUntil) || b.probing {
+		metrics.StoreCircuitRejected.Inc()
+		return &UnavailableError{RetryAfter: max(b.openUntil.Sub(now), 0)}
+	}
+	b.probing = true
+	return nil
+}
+
+// record records the outcome of a query sent after allow
+func (b *breaker) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasProbing := b.probing
+	b.probing = false
+	switch {
+	case unreachable(err):
+		b.failures++
+		if b.failures < b.threshold {
+			return
+		}
+		b.openUntil = time.Now().Add(b.cooldown)
+		if b.failures == b.threshold || wasProbing {
+			metrics.StoreCircuitOpen.Set(1)
+			b.logger.Warn().Err(err).Dur("cooldown", b.cooldown).Msg("🔌 database circuit opened")
+		}
+	case errors.Is(err, context.Canceled):
+		// Abandoned by its caller: says nothing of the database
+	default:
+		if b.failures >= b.threshold {
+			metrics.StoreCircuitOpen.Set(0)
+			b.logger.Info().Msg("🔌 database circuit closed")
+		}
+		b.failures = 0
+	}
+}
+
+// unreachable reports whether err means the database could not serve the query:
+// it could not be reached, dropped the connection, is shutting down or starting,
+// has no connection slot left, or did not answer in time
+func unreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case strings.HasPrefix(pgErr.Code, "08"): // connection exception
+			return true
+		case pgErr.Code == "57P01", pgErr.Code == "57P02", pgErr.Code == "57P03": // admin shutdown, crash shutdown, cannot connect now
+			return true
+		case pgErr.Code == "53300": // too many connections
+			return true
+		}
+		return errors.Is(err, ErrQueryTimeout)
+	}
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.Is(err, ErrQueryTimeout) || errors.As(err, &connectErr) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
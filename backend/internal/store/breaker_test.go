@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+// failingDB is a DBTX answering every query with err, counting the queries it gets
+type failingDB struct {
+	err     error
+	queries int
+}
+
+func (f *failingDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	f.queries++
+	return pgconn.CommandTag{}, f.err
+}
+
+func (f *failingDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	f.queries++
+	return nil, f.err
+}
+
+func (f *failingDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	f.queries++
+	return errRow{f.err}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	down := &pgconn.ConnectError{Config: &pgconn.Config{}}
+	db := &failingDB{err: down}
+	g := &guardedDB{db: db, breaker: &breaker{threshold: 3, cooldown: time.Hour, logger: &logger}}
+
+	// Errors the database answered with do not count
+	db.err = &pgconn.PgError{Code: "23505"}
+	for range 5 {
+		g.Exec(ctx, "INSERT")
+	}
+	db.err = down
+	for range 3 {
+		if _, err := g.Exec(ctx, "INSERT"); errors.Is(err, ErrUnavailable) {
+			t.Fatalf("query rejected before the threshold: %v", err)
+		}
+	}
+
+	var n int
+	err := g.QueryRow(ctx, "SELECT").Scan(&n)
+	var u *UnavailableError
+	if !errors.As(err, &u) || !errors.Is(err, ErrUnavailable) || u.RetryAfter <= 59*time.Minute {
+		t.Fatalf("open circuit: got %v, want ErrUnavailable with an hour to wait", err)
+	}
+	if _, err := g.Query(ctx, "SELECT"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("open circuit Query: got %v", err)
+	}
+	if db.queries != 8 {
+		t.Errorf("database got %d queries, want 8: none while open", db.queries)
+	}
+
+	// After the cooldown, a single probe goes through: a failure opens the circuit again
+	g.breaker.openUntil = time.Now()
+	if _, err := g.Exec(ctx, "INSERT"); errors.Is(err, ErrUnavailable) || db.queries != 9 {
+		t.Fatalf("probe: got %v after %d queries, want the probe sent", err, db.queries)
+	}
+	if _, err := g.Exec(ctx, "INSERT"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("failed probe: got %v, want the circuit open again", err)
+	}
+
+	// A probe abandoned by its caller lets the next query probe; a success closes the circuit
+	g.breaker.openUntil = time.Now()
+	db.err = fmt.Errorf("acquire: %w", context.Canceled)
+	g.Exec(ctx, "INSERT")
+	db.err = ErrNoRows
+	if err := g.QueryRow(ctx, "SELECT").Scan(&n); !errors.Is(err, ErrNoRows) {
+		t.Fatalf("second probe: got %v, want the database's answer", err)
+	}
+	db.err = nil
+	for range 3 {
+		if _, err := g.Exec(ctx, "INSERT"); err != nil {
+			t.Errorf("closed circuit: got %v", err)
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"no rows", ErrNoRows, false},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"shutting down", fmt.Errorf("get score: %w", &pgconn.PgError{Code: "57P01"}), true},
+		{"too many connections", &pgconn.PgError{Code: "53300"}, true},
+		{"statement timeout", timeoutError(&pgconn.PgError{Code: queryCanceled}), true},
+		{"deadline", timeoutError(context.DeadlineExceeded), true},
+		{"cancelled", context.Canceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unreachable(tt.err); got != tt.want {
+				t.Errorf("unreachable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DeviceSubmissionWindow is the period the per-device submission limit counts
+// over. Older rows of device_submissions are pruned by the maintenance job.
+const DeviceSubmissionWindow = time.Hour
+
+// DeviceLimiter checks a submission against the limits of its device and
+// records it. Counting and recording are separate queries, so they are
+// serialized per device to keep concurrent submissions under the limits.
+type DeviceLimiter interface {
+	// CheckDeviceSubmission counts the accounts and recent submissions of the
+	// device and records the player and the submission, in one transaction.
+	// With Enforce, a submission exceeding a limit is not recorded.
+	CheckDeviceSubmission(ctx context.Context, arg DeviceSubmissionCheck) (DeviceUsage, error)
+}
+
+// DeviceSubmissionCheck is a submission from a device and the limits it is checked against
+type DeviceSubmissionCheck struct {
+	DeviceHash     string
+	PlayerName     string
+	MaxAccounts    int32 // accounts per device, 0 disables the limit
+	MaxSubmissions int32 // submissions per device within DeviceSubmissionWindow, 0 disables the limit
+	Enforce        bool
+}
+
+// DeviceUsage is the usage of a device before a submission, and what was recorded
+type DeviceUsage struct {
+	Accounts            int64 // accounts already seen on the device, counted for new accounts only
+	Submissions         int64 // submissions within DeviceSubmissionWindow, counted when limited
+	AccountsExceeded    bool
+	SubmissionsExceeded bool
+	Recorded            bool
+}
+
+// Exceeded reports whether the submission exceeds a limit
+func (u DeviceUsage) Exceeded() bool {
+	return u.AccountsExceeded || u.SubmissionsExceeded
+}
+
+var _ DeviceLimiter = (*Store)(nil)
+
+// lockDeviceQuery serializes the checks of a device until the end of the
+// transaction. Devices sharing a key hash only wait on each other.
+const lockDeviceQuery = `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`
+
+// CheckDeviceSubmission takes a transaction-level advisory lock on the device:
+// rows of device_submissions cannot be locked before they exist
+func (s *Store) CheckDeviceSubmission(ctx context.Context, arg DeviceSubmissionCheck) (DeviceUsage, error) {
+	tx, err := s.begin(ctx, pgx.TxOptions{})
+	if err != nil {
+		return DeviceUsage{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	if _, err := tx.Exec(ctx, lockDeviceQuery, arg.DeviceHash); err != nil {
+		return DeviceUsage{}, fmt.Errorf("lock device: %w", err)
+	}
+	q := s.Queries.WithTx(tx)
+
+	var usage DeviceUsage
+	if arg.MaxAccounts > 0 {
+		known, err := q.IsDevicePlayerKnown(ctx, IsDevicePlayerKnownParams{DeviceHash: arg.DeviceHash, PlayerName: arg.PlayerName})
+		if err != nil {
+			return DeviceUsage{}, fmt.Errorf("look up device player: %w", err)
+		}
+		if !known {
+			if usage.Accounts, err = q.CountDevicePlayers(ctx, arg.DeviceHash); err != nil {
+				return DeviceUsage{}, fmt.Errorf("count device players: %w", err)
+			}
+			usage.AccountsExceeded = usage.Accounts >= int64(arg.MaxAccounts)
+		}
+	}
+	if arg.MaxSubmissions > 0 {
+		if usage.Submissions, err = q.CountDeviceSubmissionsSince(ctx, CountDeviceSubmissionsSinceParams{
+			DeviceHash:  arg.DeviceHash,
+			SubmittedAt: pgtype.Timestamptz{Time: time.Now().Add(-DeviceSubmissionWindow), Valid: true},
+		}); err != nil {
+			return DeviceUsage{}, fmt.Errorf("count device submissions: %w", err)
+		}
+		usage.SubmissionsExceeded = usage.Submissions >= int64(arg.MaxSubmissions)
+	}
+	if arg.Enforce && usage.Exceeded() {
+		return usage, nil
+	}
+
+	if err := q.RecordDevicePlayer(ctx, RecordDevicePlayerParams{DeviceHash: arg.DeviceHash, PlayerName: arg.PlayerName}); err != nil {
+		return DeviceUsage{}, fmt.Errorf("record device player: %w", err)
+	}
+	if err := q.RecordDeviceSubmission(ctx, RecordDeviceSubmissionParams{DeviceHash: arg.DeviceHash, PlayerName: arg.PlayerName}); err != nil {
+		return DeviceUsage{}, fmt.Errorf("record device submission: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return DeviceUsage{}, fmt.Errorf("commit: %w", err)
+	}
+	usage.Recorded = true
+	return usage, nil
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrQueryTimeout is returned by queries cut short by the store's query timeout,
+// the caller's deadline or the server's statement_timeout
+var ErrQueryTimeout = errors.New("database query timed out")
+
+// queryCanceled is the SQLSTATE of a statement cancelled by statement_timeout
+// (or by a cancel request, which pgx only sends once the context is done)
+const queryCanceled = "57014"
+
+// WithStatementTimeout sets statement_timeout on every pooled connection, so the
+// server aborts statements running longer than d even when the client is gone.
+// It applies to transactions too, statement by statement. 0 leaves the server's
+// default in place.
+func WithStatementTimeout(d time.Duration) PoolOption {
+	return func(config *pgxpool.Config) {
+		if d > 0 {
+			config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(d.Milliseconds(), 10)
+		}
+	}
+}
+
+// SetQueryTimeout bounds each single-statement query of the store to d, reading
+// its rows included (0, the default, leaves queries bounded by their caller's
+// context only). Transactions are bounded by statement_timeout instead: see
+// WithStatementTimeout. Call it before serving.
+func (s *Store) SetQueryTimeout(d time.Duration) {
+	s.db.timeout = d
+	if s.replica != nil {
+		s.replica.db.timeout = d
+	}
+}
+
+// guardedDB runs queries under the query timeout and the circuit breaker, and
+// reports timeouts as ErrQueryTimeout
+type guardedDB struct {
+	db      DBTX
+	timeout time.Duration // 0 for none
+	breaker *breaker      // nil for none
+}
+
+var _ DBTX = (*guardedDB)(nil)
+
+func (g *guardedDB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, g.timeout)
+}
+
+// done classifies the error of a query and records its outcome in the breaker
+func (g *guardedDB) done(err error) error {
+	err = timeoutError(err)
+	g.breaker.record(err)
+	return err
+}
+
+func (g *guardedDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if err := g.breaker.allow(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	ctx, cancel := g.withTimeout(ctx)
+	defer cancel()
+	tag, err := g.db.Exec(ctx, sql, args...)
+	return tag, g.done(err)
+}
+
+func (g *guardedDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if err := g.breaker.allow(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := g.withTimeout(ctx)
+	rows, err := g.db.Query(ctx, sql, args...)
+	if err != nil {
+		cancel()
+		return nil, g.done(err)
+	}
+	return &guardedRows{Rows: rows, db: g, cancel: cancel}, nil
+}
+
+func (g *guardedDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if err := g.breaker.allow(); err != nil {
+		return errRow{err}
+	}
+	ctx, cancel := g.withTimeout(ctx)
+	return &guardedRow{row: g.db.QueryRow(ctx, sql, args...), db: g, cancel: cancel}
+}
+
+// guardedRows records the outcome of its query and releases its timeout once closed
+type guardedRows struct {
+	pgx.Rows
+	db     *guardedDB
+	cancel context.CancelFunc
+	closed bool
+}
+
+func (r *guardedRows) Close() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	r.Rows.Close()
+	r.db.done(r.Rows.Err())
+	r.cancel()
+}
+
+func (r *guardedRows) Err() error {
+	return timeoutError(r.Rows.Err())
+}
+
+// guardedRow records the outcome of its query and releases its timeout once scanned
+type guardedRow struct {
+	row    pgx.Row
+	db     *guardedDB
+	cancel context.CancelFunc
+}
+
+func (r *guardedRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.db.done(r.row.Scan(dest...))
+}
+
+// errRow is the row of a query that was not sent
+type errRow struct{ err error }
+
+func (r errRow) Scan(...any) error { return r.err }
+
+// guardedTx is a transaction whose statements run under the circuit breaker and
+// report timeouts as ErrQueryTimeout
+type guardedTx struct {
+	pgx.Tx
+	db *guardedDB
+}
+
+func (t *guardedTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return t.db.Exec(ctx, sql, args...)
+}
+
+func (t *guardedTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return t.db.Query(ctx, sql, args...)
+}
+
+func (t *guardedTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return t.db.QueryRow(ctx, sql, args...)
+}
+
+func (t *guardedTx) Commit(ctx context.Context) error {
+	return t.db.done(t.Tx.Commit(ctx))
+}
+
+// begin starts a transaction on the pool. Its statements are not bounded by the
+// query timeout, which is meant for single queries, but report timeouts alike.
+func (s *Store) begin(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	if err := s.db.breaker.allow(); err != nil {
+		return nil, err
+	}
+	tx, err := s.pool.BeginTx(ctx, opts)
+	if err = s.db.done(err); err != nil {
+		return nil, err
+	}
+	return &guardedTx{Tx: tx, db: &guardedDB{db: tx, breaker: s.db.breaker}}, nil
+}
+
+// timeoutError wraps err with ErrQueryTimeout when it reports a timeout
+func timeoutError(err error) error {
+	if err == nil || errors.Is(err, ErrQueryTimeout) {
+		return err
+	}
+	var pgErr *pgconn.PgError
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &pgErr) && pgErr.Code == queryCanceled) {
+		return fmt.Errorf("%w: %w", ErrQueryTimeout, err)
+	}
+	return err
+}
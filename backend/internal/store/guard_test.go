@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// slowDB is a DBTX whose queries only return once their context is done
+type slowDB struct{}
+
+func (slowDB) Exec(ctx context.Context, _ string, _ ...interface{}) (pgconn.CommandTag, error) {
+	<-ctx.Done()
+	return pgconn.CommandTag{}, ctx.Err()
+}
+
+func (slowDB) Query(ctx context.Context, _ string, _ ...interface{}) (pgx.Rows, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (slowDB) QueryRow(ctx context.Context, _ string, _ ...interface{}) pgx.Row {
+	return slowRow{ctx}
+}
+
+type slowRow struct{ ctx context.Context }
+
+func (r slowRow) Scan(...any) error {
+	<-r.ctx.Done()
+	return r.ctx.Err()
+}
+
+func TestQueryTimeout(t *testing.T) {
+	db := &guardedDB{db: slowDB{}, timeout: 10 * time.Millisecond}
+	ctx := context.Background()
+
+	if _, err := db.Exec(ctx, "UPDATE"); !errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("Exec: got %v, want ErrQueryTimeout", err)
+	}
+	if _, err := db.Query(ctx, "SELECT"); !errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("Query: got %v, want ErrQueryTimeout", err)
+	}
+	var n int
+	if err := db.QueryRow(ctx, "SELECT").Scan(&n); !errors.Is(err, ErrQueryTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("QueryRow: got %v, want ErrQueryTimeout wrapping the deadline", err)
+	}
+
+	// Without a timeout, the caller's context still bounds the query
+	db.timeout = 0
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := db.Exec(cancelled, "UPDATE"); !errors.Is(err, context.Canceled) || errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("cancelled Exec: got %v, want a cancellation, not a timeout", err)
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		timeout bool
+	}{
+		{"nil", nil, false},
+		{"no rows", ErrNoRows, false},
+		{"statement timeout", fmt.Errorf("get top scores: %w", &pgconn.PgError{Code: queryCanceled, Message: "canceling statement due to statement timeout"}), true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"deadline", context.DeadlineExceeded, true},
+		{"cancelled", context.Canceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := timeoutError(tt.err)
+			if errors.Is(err, ErrQueryTimeout) != tt.timeout || !errors.Is(err, tt.err) {
+				t.Errorf("timeoutError(%v) = %v, want timeout %v", tt.err, err, tt.timeout)
+			}
+		})
+	}
+}
@@ -1,23 +1,29 @@
+//go:build integration
 // +build integration
 
 package store_test
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
-	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/requestctx"
 	"github.com/yourorg/leaderboard/internal/store"
 )
 
+const board = store.DefaultLeaderboardID
+
 func setupTestDB(t *testing.T) (*store.Store, func()) {
 	ctx := context.Background()
 
@@ -42,19 +48,21 @@ func setupTestDB(t *testing.T) (*store.Store, func()) {
 		t.Fatalf("failed to get connection string: %s", err)
 	}
 
-	// Run migrations
-	if err := runMigrations(connStr); err != nil {
-		postgresContainer.Terminate(ctx)
-		t.Fatalf("failed to run migrations: %s", err)
-	}
-
 	// Create connection pool
-	pool, err := store.NewPool(ctx, connStr)
+	pool, err := store.NewPool(ctx, connStr, store.WithRequestTagging())
 	if err != nil {
 		postgresContainer.Terminate(ctx)
 		t.Fatalf("failed to create pool: %s", err)
 	}
 
+	// Run the embedded migrations, as the server does with AUTO_MIGRATE
+	logger := zerolog.Nop()
+	if err := store.Migrate(pool, &logger); err != nil {
+		pool.Close()
+		postgresContainer.Terminate(ctx)
+		t.Fatalf("failed to run migrations: %s", err)
+	}
+
 	st := store.NewStore(pool)
 
 	cleanup := func() {
@@ -67,84 +75,6 @@ func setupTestDB(t *testing.T) (*store.Store, func()) {
 	return st, cleanup
 }
 
-func runMigrations(connStr string) error {
-	// Open connection for migrations
-	db, err := sql.Open("pgx", connStr)
-	if err != nil {
-		return err
-	}
-	defer db.Close()
-
-	// Read and execute migration file
-	migrationPath := filepath.Join("..", "..", "db", "migrations", "0001_init.up.sql")
-
-	// Simple migration runner - in production, use golang-migrate
-	migrations := []string{
-		// Create table
-		`CREATE TABLE scores (
-			player_name TEXT PRIMARY KEY,
-			score BIGINT NOT NULL CHECK (score >= 0),
-			updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
-			CONSTRAINT player_name_length CHECK (char_length(player_name) <= 20 AND char_length(player_name) > 0)
-		)`,
-		// Create index
-		`CREATE INDEX idx_scores_leaderboard ON scores (score DESC, player_name)`,
-		// Create trigger function
-		`CREATE OR REPLACE FUNCTION notify_score_change()
-		RETURNS TRIGGER AS $$
-		DECLARE
-			payload JSON;
-			operation TEXT;
-		BEGIN
-			IF TG_OP = 'DELETE' THEN
-				operation := 'delete';
-				payload := json_build_object(
-					'player_name', OLD.player_name,
-					'score', OLD.score,
-					'op', operation
-				);
-				PERFORM pg_notify('scores_changes', payload::text);
-				RETURN OLD;
-			ELSIF TG_OP = 'INSERT' THEN
-				operation := 'insert';
-				payload := json_build_object(
-					'player_name', NEW.player_name,
-					'score', NEW.score,
-					'op', operation
-				);
-				PERFORM pg_notify('scores_changes', payload::text);
-				RETURN NEW;
-			ELSIF TG_OP = 'UPDATE' THEN
-				IF NEW.score > OLD.score THEN
-					operation := 'update';
-					payload := json_build_object(
-						'player_name', NEW.player_name,
-						'score', NEW.score,
-						'op', operation
-					);
-					PERFORM pg_notify('scores_changes', payload::text);
-				END IF;
-				RETURN NEW;
-			END IF;
-			RETURN NULL;
-		END;
-		$$ LANGUAGE plpgsql`,
-		// Create trigger
-		`CREATE TRIGGER scores_change_trigger
-		AFTER INSERT OR UPDATE OR DELETE ON scores
-		FOR EACH ROW
-		EXECUTE FUNCTION notify_score_change()`,
-	}
-
-	for _, migration := range migrations {
-		if _, err := db.Exec(migration); err != nil {
-			return fmt.Errorf("migration failed: %w", err)
-		}
-	}
-
-	return nil
-}
-
 func TestUpsertScore(t *testing.T) {
 	st, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -153,8 +83,9 @@ func TestUpsertScore(t *testing.T) {
 
 	// First insert
 	result1, err := st.UpsertScore(ctx, store.UpsertScoreParams{
-		PlayerName: "Alice",
-		Score:      100,
+		LeaderboardID: board,
+		PlayerName:    "Alice",
+		Score:         100,
 	})
 	if err != nil {
 		t.Fatalf("first upsert failed: %s", err)
@@ -165,8 +96,9 @@ func TestUpsertScore(t *testing.T) {
 
 	// Update with higher score - should succeed
 	result2, err := st.UpsertScore(ctx, store.UpsertScoreParams{
-		PlayerName: "Alice",
-		Score:      200,
+		LeaderboardID: board,
+		PlayerName:    "Alice",
+		Score:         200,
 	})
 	if err != nil {
 		t.Fatalf("second upsert failed: %s", err)
@@ -177,8 +109,9 @@ func TestUpsertScore(t *testing.T) {
 
 	// Update with lower score - should keep higher score
 	result3, err := st.UpsertScore(ctx, store.UpsertScoreParams{
-		PlayerName: "Alice",
-		Score:      150,
+		LeaderboardID: board,
+		PlayerName:    "Alice",
+		Score:         150,
 	})
 	if err != nil {
 		t.Fatalf("third upsert failed: %s", err)
@@ -208,8 +141,9 @@ func TestGetTopScores(t *testing.T) {
 
 	for _, p := range testPlayers {
 		_, err := st.UpsertScore(ctx, store.UpsertScoreParams{
-			PlayerName: p.name,
-			Score:      p.score,
+			LeaderboardID: board,
+			PlayerName:    p.name,
+			Score:         p.score,
 		})
 		if err != nil {
 			t.Fatalf("failed to insert %s: %s", p.name, err)
@@ -218,8 +152,9 @@ func TestGetTopScores(t *testing.T) {
 
 	// Get top 3
 	scores, err := st.GetTopScores(ctx, store.GetTopScoresParams{
-		Limit:  3,
-		Offset: 0,
+		LeaderboardID: board,
+		PageSize:      3,
+		PageOffset:    0,
 	})
 	if err != nil {
 		t.Fatalf("GetTopScores failed: %s", err)
@@ -238,6 +173,57 @@ func TestGetTopScores(t *testing.T) {
 	}
 }
 
+func TestGetTopScoresAfter(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Bob and Carol tie; pin achieved_at so Bob ranks first
+	base := time.Now().Add(-time.Hour)
+	for i, p := range []struct {
+		name  string
+		score int64
+	}{{"Alice", 1000}, {"Bob", 800}, {"Carol", 800}, {"Dave", 500}} {
+		_, err := st.UpsertScore(ctx, store.UpsertScoreParams{
+			LeaderboardID: board,
+			PlayerName:    p.name,
+			Score:         p.score,
+			AchievedAt:    pgtype.Timestamptz{Time: base.Add(time.Duration(i) * time.Second), Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("failed to insert %s: %s", p.name, err)
+		}
+	}
+
+	first, err := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: board, PageSize: 2, PageOffset: 0})
+	if err != nil {
+		t.Fatalf("GetTopScores failed: %s", err)
+	}
+	last := first[len(first)-1]
+
+	next, err := st.GetTopScoresAfter(ctx, store.GetTopScoresAfterParams{
+		LeaderboardID: board,
+		RankScore:     last.RankScore,
+		AchievedAt:    last.AchievedAt,
+		PlayerName:    last.PlayerName,
+		PageSize:      2,
+	})
+	if err != nil {
+		t.Fatalf("GetTopScoresAfter failed: %s", err)
+	}
+
+	expectedOrder := []string{"Carol", "Dave"}
+	if len(next) != len(expectedOrder) {
+		t.Fatalf("expected %d scores, got %d", len(expectedOrder), len(next))
+	}
+	for i, name := range expectedOrder {
+		if next[i].PlayerName != name {
+			t.Errorf("position %d: expected %s, got %s", i, name, next[i].PlayerName)
+		}
+	}
+}
+
 func TestGetPlayerRank(t *testing.T) {
 	st, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -256,8 +242,9 @@ func TestGetPlayerRank(t *testing.T) {
 
 	for _, p := range testPlayers {
 		_, err := st.UpsertScore(ctx, store.UpsertScoreParams{
-			PlayerName: p.name,
-			Score:      p.score,
+			LeaderboardID: board,
+			PlayerName:    p.name,
+			Score:         p.score,
 		})
 		if err != nil {
 			t.Fatalf("failed to insert %s: %s", p.name, err)
@@ -265,7 +252,7 @@ func TestGetPlayerRank(t *testing.T) {
 	}
 
 	// Check Charlie's rank (should be 1 - highest score)
-	rank, err := st.GetPlayerRank(ctx, "Charlie")
+	rank, err := st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: board, PlayerName: "Charlie"})
 	if err != nil {
 		t.Fatalf("GetPlayerRank failed: %s", err)
 	}
@@ -274,7 +261,7 @@ func TestGetPlayerRank(t *testing.T) {
 	}
 
 	// Check Alice's rank (should be 2)
-	rank, err = st.GetPlayerRank(ctx, "Alice")
+	rank, err = st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: board, PlayerName: "Alice"})
 	if err != nil {
 		t.Fatalf("GetPlayerRank failed: %s", err)
 	}
@@ -283,13 +270,77 @@ func TestGetPlayerRank(t *testing.T) {
 	}
 
 	// Check Bob's rank (should be 3)
-	rank, err = st.GetPlayerRank(ctx, "Bob")
+	rank, err = st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: board, PlayerName: "Bob"})
 	if err != nil {
 		t.Fatalf("GetPlayerRank failed: %s", err)
 	}
 	if rank != 3 {
 		t.Errorf("expected rank 3 for Bob, got %d", rank)
 	}
+
+	// Alice's neighbours, and the window clipped at the top of the board
+	around, err := st.GetScoresAroundRank(ctx, store.GetScoresAroundRankParams{LeaderboardID: board, Rank: 2, Radius: 1})
+	if err != nil {
+		t.Fatalf("GetScoresAroundRank failed: %s", err)
+	}
+	if len(around) != 3 || around[0].PlayerName != "Charlie" || around[2].PlayerName != "Bob" {
+		t.Errorf("around rank 2 = %+v, want Charlie, Alice, Bob", around)
+	}
+	if around, err := st.GetScoresAroundRank(ctx, store.GetScoresAroundRankParams{LeaderboardID: board, Rank: 1, Radius: 1}); err != nil || len(around) != 2 {
+		t.Errorf("around rank 1 = %+v, %v, want Charlie and Alice", around, err)
+	}
+
+	if exists, err := st.PlayerExists(ctx, store.PlayerExistsParams{LeaderboardID: board, PlayerName: "Bob"}); err != nil || !exists {
+		t.Errorf("PlayerExists(Bob) = %v, %v", exists, err)
+	}
+	if exists, _ := st.PlayerExists(ctx, store.PlayerExistsParams{LeaderboardID: "level-1", PlayerName: "Bob"}); exists {
+		t.Error("PlayerExists(Bob) on an empty board = true")
+	}
+	if players, err := st.CountPlayers(ctx); err != nil || players != 3 {
+		t.Errorf("CountPlayers = %d, %v, want 3", players, err)
+	}
+}
+
+func TestRecencyWeightedRanking(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Now()
+	// Four half-lives old: Alice's 1000 weighs 62.5
+	for name, p := range map[string]struct {
+		score int64
+		age   time.Duration
+	}{"Alice": {1000, 4 * time.Hour}, "Bob": {600, 0}, "Carol": {50, 0}} {
+		if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{
+			LeaderboardID: board,
+			PlayerName:    name,
+			Score:         p.score,
+			AchievedAt:    pgtype.Timestamptz{Time: now.Add(-p.age), Valid: true},
+		}); err != nil {
+			t.Fatalf("failed to insert %s: %s", name, err)
+		}
+	}
+
+	nowUnix := float64(now.UnixMicro()) / 1e6
+	top, err := st.GetTopScoresRecencyWeighted(ctx, store.GetTopScoresRecencyWeightedParams{
+		LeaderboardID: board, NowUnix: nowUnix, HalfLifeSeconds: 3600, PageSize: 10,
+	})
+	if err != nil {
+		t.Fatalf("GetTopScoresRecencyWeighted failed: %s", err)
+	}
+	for i, name := range []string{"Bob", "Alice", "Carol"} {
+		if i >= len(top) || top[i].PlayerName != name {
+			t.Fatalf("recency order = %v, want Bob, Alice, Carol", top)
+		}
+	}
+
+	rank, err := st.GetPlayerRankRecencyWeighted(ctx, store.GetPlayerRankRecencyWeightedParams{
+		LeaderboardID: board, PlayerName: "Alice", NowUnix: nowUnix, HalfLifeSeconds: 3600,
+	})
+	if err != nil || rank != 2 {
+		t.Errorf("recency rank of Alice = %d, %v; want 2", rank, err)
+	}
 }
 
 func TestDeleteScore(t *testing.T) {
@@ -300,15 +351,16 @@ func TestDeleteScore(t *testing.T) {
 
 	// Insert a score
 	_, err := st.UpsertScore(ctx, store.UpsertScoreParams{
-		PlayerName: "Alice",
-		Score:      100,
+		LeaderboardID: board,
+		PlayerName:    "Alice",
+		Score:         100,
 	})
 	if err != nil {
 		t.Fatalf("insert failed: %s", err)
 	}
 
 	// Verify it exists
-	score, err := st.GetPlayerScore(ctx, "Alice")
+	score, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: board, PlayerName: "Alice"})
 	if err != nil {
 		t.Fatalf("GetPlayerScore failed: %s", err)
 	}
@@ -317,39 +369,1189 @@ func TestDeleteScore(t *testing.T) {
 	}
 
 	// Delete it
-	err = st.DeleteScore(ctx, "Alice")
-	if err != nil {
-		t.Fatalf("DeleteScore failed: %s", err)
+	deleted, err := st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: board, PlayerName: "Alice"})
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteScore = %d, %v; want 1 row", deleted, err)
 	}
 
 	// Verify it's gone
-	_, err = st.GetPlayerScore(ctx, "Alice")
+	_, err = st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: board, PlayerName: "Alice"})
 	if err == nil {
 		t.Error("expected error for non-existent player, got nil")
 	}
+
+	// Soft-deleted: listed, and restored as it was
+	deletedScores, err := st.GetDeletedScores(ctx, store.GetDeletedScoresParams{LeaderboardID: board, PageSize: 10})
+	if err != nil || len(deletedScores) != 1 || deletedScores[0].PlayerName != "Alice" || !deletedScores[0].DeletedAt.Valid {
+		t.Fatalf("GetDeletedScores = %+v, %v; want Alice", deletedScores, err)
+	}
+	restored, err := st.RestoreScore(ctx, store.RestoreScoreParams{LeaderboardID: board, PlayerName: "Alice"})
+	if err != nil || restored.Score != 100 || restored.DeletedAt.Valid {
+		t.Fatalf("RestoreScore = %+v, %v; want live score 100", restored, err)
+	}
+	if _, err := st.RestoreScore(ctx, store.RestoreScoreParams{LeaderboardID: board, PlayerName: "Alice"}); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("restoring a live score: error = %v, want ErrNoRows", err)
+	}
+
+	// A new submission after a delete starts over, even below the deleted best
+	st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: board, PlayerName: "Alice"})
+	sc, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 40})
+	if err != nil || sc.Score != 40 || sc.DeletedAt.Valid {
+		t.Errorf("upsert after delete = %+v, %v; want live score 40", sc, err)
+	}
 }
 
-func TestPlayerNameLengthConstraint(t *testing.T) {
+func TestScoreMetadata(t *testing.T) {
 	st, cleanup := setupTestDB(t)
 	defer cleanup()
 
 	ctx := context.Background()
+	board := store.DefaultLeaderboardID
 
-	// Try to insert a name longer than 20 characters
-	_, err := st.UpsertScore(ctx, store.UpsertScoreParams{
-		PlayerName: "ThisNameIsWayTooLongAndShouldFail", // 34 characters
-		Score:      100,
+	sc, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 100})
+	if err != nil || string(sc.Metadata) != "{}" {
+		t.Fatalf("upsert without metadata = %s, %v; want {}", sc.Metadata, err)
+	}
+	sc, err = st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 200, Metadata: []byte(`{"level":"3"}`)})
+	if err != nil || string(sc.Metadata) != `{"level": "3"}` {
+		t.Fatalf("better score with metadata = %s, %v", sc.Metadata, err)
+	}
+
+	// Metadata follows the best score
+	sc, err = st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 150, Metadata: []byte(`{"level":"1"}`)})
+	if err != nil || string(sc.Metadata) != `{"level": "3"}` {
+		t.Errorf("lower score = %s, %v; want the metadata of the best score", sc.Metadata, err)
+	}
+}
+
+func TestSecondaryScores(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Equal scores rank by the fewest seconds left on the clock
+	if _, err := st.UpsertLeaderboard(ctx, store.UpsertLeaderboardParams{LeaderboardID: "level-1", SortOrder: "desc", SecondarySortOrder: "asc"}); err != nil {
+		t.Fatalf("failed to define level-1: %s", err)
+	}
+	for _, p := range []store.UpsertScoreParams{
+		{LeaderboardID: "level-1", PlayerName: "Alice", Score: 100, SecondaryScore: 40},
+		{LeaderboardID: "level-1", PlayerName: "Bob", Score: 100, SecondaryScore: 30},
+		{LeaderboardID: "level-1", PlayerName: "Carol", Score: 200, SecondaryScore: 90},
+	} {
+		if _, err := st.UpsertScore(ctx, p); err != nil {
+			t.Fatalf("failed to insert %s: %s", p.PlayerName, err)
+		}
+	}
+
+	// A better secondary score improves an equal score, a worse one does not
+	sc, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-1", PlayerName: "Alice", Score: 100, SecondaryScore: 20})
+	if err != nil || sc.SecondaryScore != 20 || sc.RankSecondary != -20 {
+		t.Errorf("faster Alice = %+v, %v; want secondary score 20", sc, err)
+	}
+	sc, err = st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-1", PlayerName: "Bob", Score: 100, SecondaryScore: 60})
+	if err != nil || sc.SecondaryScore != 30 {
+		t.Errorf("slower Bob = %+v, %v; want secondary score 30 kept", sc, err)
+	}
+
+	scores, err := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: "level-1", PageSize: 10})
+	if err != nil {
+		t.Fatalf("failed to get top scores: %s", err)
+	}
+	want := []string{"Carol", "Alice", "Bob"}
+	for i, s := range scores {
+		if s.PlayerName != want[i] {
+			t.Errorf("top[%d] = %s, want %s", i, s.PlayerName, want[i])
+		}
+	}
+	if rank, err := st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: "level-1", PlayerName: "Bob"}); err != nil || rank != 3 {
+		t.Errorf("rank of Bob = %d, %v; want 3", rank, err)
+	}
+}
+
+func TestSegmentTopScores(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for _, p := range []store.UpsertScoreParams{
+		{LeaderboardID: "global", PlayerName: "Alice", Score: 300, CountryCode: "FR", Platform: "pc"},
+		{LeaderboardID: "global", PlayerName: "Bob", Score: 250, CountryCode: "DE", Platform: "pc"},
+		{LeaderboardID: "global", PlayerName: "Carol", Score: 200, CountryCode: "FR", Platform: "mobile"},
+		{LeaderboardID: "global", PlayerName: "Dave", Score: 150},
+		{LeaderboardID: "global", PlayerName: "Erin", Score: 100, CountryCode: "FR", Platform: "pc"},
+	} {
+		if _, err := st.UpsertScore(ctx, p); err != nil {
+			t.Fatalf("failed to insert %s: %s", p.PlayerName, err)
+		}
+	}
+
+	// The region and platform follow the best score only
+	sc, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "global", PlayerName: "Carol", Score: 50, CountryCode: "DE", Platform: "web"})
+	if err != nil || sc.CountryCode != "FR" || sc.Platform != "mobile" {
+		t.Errorf("worse Carol = %+v, %v; want region FR and platform mobile kept", sc, err)
+	}
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "global", PlayerName: "Other", Score: 1, CountryCode: "fra"}); err == nil {
+		t.Error("upsert with an invalid country code succeeded")
+	}
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "global", PlayerName: "Other", Score: 1, Platform: "android"}); err == nil {
+		t.Error("upsert with an invalid platform succeeded")
+	}
+
+	scores, err := st.GetSegmentTopScores(ctx, store.GetSegmentTopScoresParams{LeaderboardID: "global", CountryCode: "FR", PageSize: 2})
+	if err != nil {
+		t.Fatalf("failed to get regional top scores: %s", err)
+	}
+	if len(scores) != 2 || scores[0].PlayerName != "Alice" || scores[1].PlayerName != "Carol" {
+		t.Fatalf("FR top 2 = %+v, want Alice, Carol", scores)
+	}
+	last := scores[1]
+	scores, err = st.GetSegmentTopScoresAfter(ctx, store.GetSegmentTopScoresAfterParams{
+		LeaderboardID: "global",
+		CountryCode:   "FR",
+		RankScore:     last.RankScore,
+		RankSecondary: last.RankSecondary,
+		AchievedAt:    last.AchievedAt,
+		PlayerName:    last.PlayerName,
+		PageSize:      10,
 	})
+	if err != nil {
+		t.Fatalf("failed to get regional top scores after Carol: %s", err)
+	}
+	if len(scores) != 1 || scores[0].PlayerName != "Erin" {
+		t.Errorf("FR after Carol = %+v, want Erin", scores)
+	}
+
+	scores, err = st.GetSegmentTopScores(ctx, store.GetSegmentTopScoresParams{LeaderboardID: "global", Platform: "pc", PageSize: 10})
+	if err != nil {
+		t.Fatalf("failed to get platform top scores: %s", err)
+	}
+	if len(scores) != 3 || scores[0].PlayerName != "Alice" || scores[1].PlayerName != "Bob" || scores[2].PlayerName != "Erin" {
+		t.Errorf("pc top = %+v, want Alice, Bob, Erin", scores)
+	}
+	rank, err := st.GetSegmentPlayerRank(ctx, store.GetSegmentPlayerRankParams{LeaderboardID: "global", PlayerName: "Erin", CountryCode: "FR", Platform: "pc"})
+	if err != nil || rank != 2 {
+		t.Errorf("Erin's FR pc rank = %d, %v; want 2", rank, err)
+	}
+	sim, err := st.SimulateRank(ctx, store.SimulateRankParams{LeaderboardID: "global", RankScore: 220, Platform: "pc"})
+	if err != nil || sim.Ahead != 2 || sim.Total != 3 {
+		t.Errorf("pc simulation of 220 = %+v, %v; want 2 ahead of 3", sim, err)
+	}
+	pct, err := st.GetScorePercentiles(ctx, store.GetScorePercentilesParams{LeaderboardID: "global", Fractions: []float64{0.5}, CountryCode: "FR", Platform: "pc"})
+	if err != nil || pct.Total != 2 {
+		t.Errorf("FR pc percentiles = %+v, %v; want 2 players", pct, err)
+	}
+}
+
+func TestLeaderboardsAreIndependent(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for _, p := range []store.UpsertScoreParams{
+		{LeaderboardID: "level-1", PlayerName: "Alice", Score: 100},
+		{LeaderboardID: "level-1", PlayerName: "Bob", Score: 200},
+		{LeaderboardID: "level-2", PlayerName: "Alice", Score: 300},
+	} {
+		if _, err := st.UpsertScore(ctx, p); err != nil {
+			t.Fatalf("failed to insert %s on %s: %s", p.PlayerName, p.LeaderboardID, err)
+		}
+	}
+
+	scores, err := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: "level-1", PageSize: 10})
+	if err != nil {
+		t.Fatalf("GetTopScores failed: %s", err)
+	}
+	if len(scores) != 2 || scores[0].PlayerName != "Bob" || scores[1].Score != 100 {
+		t.Errorf("level-1 top = %+v, want Bob then Alice with 100", scores)
+	}
+
+	rank, err := st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: "level-2", PlayerName: "Alice"})
+	if err != nil || rank != 1 {
+		t.Errorf("level-2 rank of Alice = %d (err %v), want 1", rank, err)
+	}
+
+	_, err = st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level 3", PlayerName: "Alice", Score: 1})
 	if err == nil {
-		t.Error("expected error for name > 20 chars, got nil")
+		t.Error("expected constraint violation for malformed leaderboard id")
 	}
+}
 
-	// Valid 20-character name should work
-	_, err = st.UpsertScore(ctx, store.UpsertScoreParams{
-		PlayerName: "12345678901234567890", // exactly 20 characters
-		Score:      100,
+func TestAscendingLeaderboard(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	if _, err := st.UpsertLeaderboard(ctx, store.UpsertLeaderboardParams{LeaderboardID: "time-trial", SortOrder: "asc", SecondarySortOrder: "desc"}); err != nil {
+		t.Fatalf("UpsertLeaderboard failed: %s", err)
+	}
+	for _, p := range []store.UpsertScoreParams{
+		{LeaderboardID: "time-trial", PlayerName: "Alice", Score: 9000},
+		{LeaderboardID: "time-trial", PlayerName: "Bob", Score: 8000},
+		{LeaderboardID: "time-trial", PlayerName: "Alice", Score: 9500}, // slower: not a best
+		{LeaderboardID: "time-trial", PlayerName: "Carol", Score: 8500},
+	} {
+		if _, err := st.UpsertScore(ctx, p); err != nil {
+			t.Fatalf("failed to insert %s: %s", p.PlayerName, err)
+		}
+	}
+
+	scores, err := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: "time-trial", PageSize: 10})
+	if err != nil {
+		t.Fatalf("GetTopScores failed: %s", err)
+	}
+	var got []string
+	for _, s := range scores {
+		got = append(got, fmt.Sprintf("%s:%d", s.PlayerName, s.Score))
+	}
+	if want := []string{"Bob:8000", "Carol:8500", "Alice:9000"}; !slices.Equal(got, want) {
+		t.Errorf("top = %v, want %v", got, want)
+	}
+
+	rank, err := st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: "time-trial", PlayerName: "Alice"})
+	if err != nil || rank != 3 {
+		t.Errorf("rank of Alice = %d (err %v), want 3", rank, err)
+	}
+}
+
+func TestUpsertScores(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	got, err := st.UpsertScores(ctx, []store.UpsertScoreParams{
+		{LeaderboardID: "global", PlayerName: "Alice", Score: 100},
+		{LeaderboardID: "global", PlayerName: "Alice", Score: 50},
 	})
 	if err != nil {
-		t.Errorf("expected success for 20-char name, got error: %s", err)
+		t.Fatalf("UpsertScores failed: %s", err)
+	}
+	if got[0].Previous != nil || got[1].Previous == nil || got[1].Score.Score != 100 {
+		t.Errorf("UpsertScores = %+v, want Alice's 100 kept over 50", got)
+	}
+
+	// A constraint violation rolls back the rows before it
+	_, err = st.UpsertScores(ctx, []store.UpsertScoreParams{
+		{LeaderboardID: "global", PlayerName: "Bob", Score: 10},
+		{LeaderboardID: "bad id", PlayerName: "Carol", Score: 10},
+	})
+	if err == nil {
+		t.Fatal("UpsertScores with an invalid row succeeded")
+	}
+	if _, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: "global", PlayerName: "Bob"}); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("Bob after rollback: error = %v, want store.ErrNoRows", err)
+	}
+}
+
+func TestConcurrentFirstSubmissions(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Every submission finds no score to lock: only the one that inserts applies
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	results := make([]store.UpsertedScore, 8)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var written []store.UpsertedScore
+			written, errs[i] = st.UpsertScores(ctx, []store.UpsertScoreParams{{LeaderboardID: board, PlayerName: "Alice", Score: 100}})
+			if errs[i] == nil {
+				results[i] = written[0]
+			}
+		}()
+	}
+	wg.Wait()
+
+	applied := 0
+	for i, r := range results {
+		if errs[i] != nil {
+			t.Fatalf("UpsertScores failed: %s", errs[i])
+		}
+		if r.Applied {
+			applied++
+		} else if r.Previous == nil || r.Previous.Score != 100 {
+			t.Errorf("not applied submission %d = %+v, want the concurrent insert as previous", i, r)
+		}
+	}
+	if applied != 1 {
+		t.Errorf("%d submissions applied, want 1", applied)
+	}
+}
+
+func TestUpsertScoreRanked(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, row := range []store.UpsertScoreParams{
+		{LeaderboardID: "global", PlayerName: "Alice", Score: 300},
+		{LeaderboardID: "global", PlayerName: "Bob", Score: 200},
+	} {
+		if _, err := st.UpsertScore(ctx, row); err != nil {
+			t.Fatalf("UpsertScore failed: %s", err)
+		}
+	}
+
+	got, err := st.UpsertScoreRanked(ctx, store.UpsertScoreParams{LeaderboardID: "global", PlayerName: "Carol", Score: 100})
+	if err != nil {
+		t.Fatalf("UpsertScoreRanked failed: %s", err)
+	}
+	if got.Previous != nil || got.PreviousRank != 0 || got.Rank != 3 {
+		t.Errorf("new entry = %+v, want rank 3 and no previous rank", got)
+	}
+
+	got, err = st.UpsertScoreRanked(ctx, store.UpsertScoreParams{LeaderboardID: "global", PlayerName: "Carol", Score: 250})
+	if err != nil {
+		t.Fatalf("UpsertScoreRanked failed: %s", err)
+	}
+	if got.Previous == nil || got.PreviousRank != 3 || got.Rank != 2 || got.Score.Score != 250 {
+		t.Errorf("improvement = %+v, want rank 3 -> 2", got)
+	}
+}
+
+func TestResetLeaderboard(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, name := range []string{"Alice", "Bob"} {
+		if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-1", PlayerName: name, Score: 100}); err != nil {
+			t.Fatalf("UpsertScore failed: %s", err)
+		}
+	}
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "global", PlayerName: "Alice", Score: 100}); err != nil {
+		t.Fatalf("UpsertScore failed: %s", err)
+	}
+	var before int64
+	st.Pool().QueryRow(ctx, "SELECT COALESCE(MAX(id), 0) FROM score_changes").Scan(&before)
+
+	res, err := st.ResetLeaderboard(ctx, "level-1", true)
+	if err != nil {
+		t.Fatalf("ResetLeaderboard failed: %s", err)
+	}
+	if res.Deleted != 2 || res.SnapshotID == 0 {
+		t.Errorf("result = %+v, want 2 deleted and a snapshot", res)
+	}
+	if n, _ := st.CountScores(ctx, "level-1"); n != 0 {
+		t.Errorf("level-1 holds %d scores after reset, want 0", n)
+	}
+	if n, _ := st.CountScores(ctx, "global"); n != 1 {
+		t.Errorf("global holds %d scores, want 1", n)
+	}
+
+	var snapshotted int64
+	st.Pool().QueryRow(ctx, "SELECT COUNT(*) FROM leaderboard_snapshot_entries WHERE snapshot_id = $1", res.SnapshotID).Scan(&snapshotted)
+	if snapshotted != 2 {
+		t.Errorf("snapshot holds %d entries, want 2", snapshotted)
+	}
+
+	// One resync in the outbox instead of a delete per row
+	var ops []string
+	rows, err := st.Pool().Query(ctx, "SELECT op || ':' || leaderboard_id FROM score_changes WHERE id > $1 ORDER BY id", before)
+	if err != nil {
+		t.Fatalf("read outbox: %s", err)
+	}
+	for rows.Next() {
+		var op string
+		rows.Scan(&op)
+		ops = append(ops, op)
+	}
+	rows.Close()
+	if len(ops) != 1 || ops[0] != "resync:level-1" {
+		t.Errorf("outbox = %v, want [resync:level-1]", ops)
+	}
+
+	// Notifications are only suppressed inside the reset transaction
+	if _, err := st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: "global", PlayerName: "Alice"}); err != nil {
+		t.Fatalf("DeleteScore failed: %s", err)
+	}
+	var deletes int64
+	st.Pool().QueryRow(ctx, "SELECT COUNT(*) FROM score_changes WHERE op = 'delete' AND id > $1", before).Scan(&deletes)
+	if deletes != 1 {
+		t.Errorf("got %d delete changes after the reset, want 1", deletes)
+	}
+}
+
+func TestPlayerData(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, b := range []string{"global", "level-1"} {
+		if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: b, PlayerName: "Alice", Score: 100}); err != nil {
+			t.Fatalf("UpsertScore failed: %s", err)
+		}
+	}
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-1", PlayerName: "Bob", Score: 200}); err != nil {
+		t.Fatalf("UpsertScore failed: %s", err)
+	}
+	if _, err := st.UpsertPlayerProfile(ctx, store.UpsertPlayerProfileParams{PlayerName: "Alice", DisplayName: "Alice the Great"}); err != nil {
+		t.Fatalf("UpsertPlayerProfile failed: %s", err)
+	}
+	hook, err := st.CreateWebhook(ctx, store.CreateWebhookParams{Url: "https://hooks.example.com", Secret: "whsec_test", Events: []string{"score.high_score"}, Enabled: true})
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %s", err)
+	}
+	for i, payload := range []string{
+		`{"type":"score.high_score","data":{"leaderboard_id":"global","player_name":"Alice","score":100}}`,
+		`{"type":"leaderboard.leader_changed","data":{"leaderboard_id":"level-1","leader":{"player_name":"Bob"},"previous_leader":{"player_name":"Alice"}}}`,
+		`{"type":"score.high_score","data":{"leaderboard_id":"level-1","player_name":"Bob","score":200}}`,
+	} {
+		enqueue := store.EnqueueWebhookDeliveryParams{WebhookID: hook.ID, EventType: "score.high_score", ChangeID: int64(i + 1), Payload: []byte(payload)}
+		if _, err := st.EnqueueWebhookDelivery(ctx, enqueue); err != nil {
+			t.Fatalf("EnqueueWebhookDelivery failed: %s", err)
+		}
+	}
+
+	data, err := st.ExportPlayerData(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("ExportPlayerData failed: %s", err)
+	}
+	if len(data.Scores) != 2 || data.Profile == nil || len(data.Changes) != 2 || len(data.WebhookDeliveries) != 2 || len(data.Erasures) != 0 {
+		t.Fatalf("export = %+v, want 2 scores, a profile, 2 changes and 2 deliveries", data)
+	}
+
+	var before int64
+	st.Pool().QueryRow(ctx, "SELECT COALESCE(MAX(id), 0) FROM score_changes").Scan(&before)
+	res, err := st.ErasePlayerData(ctx, "Alice", "req-1")
+	if err != nil {
+		t.Fatalf("ErasePlayerData failed: %s", err)
+	}
+	// 2 scores, the profile, 2 changes and 2 deliveries
+	if e := res.Erasure; e.PlayerHash != store.PlayerHash("Alice") || e.RequestID != "req-1" || e.DeletedScores != 2 || e.DeletedRows != 7 {
+		t.Errorf("erasure = %+v, want 2 scores and 7 rows deleted by req-1", e)
+	}
+	if !slices.Equal(res.Boards, []string{"global", "level-1"}) {
+		t.Errorf("boards = %v, want [global level-1]", res.Boards)
+	}
+
+	// One resync per board in the outbox instead of Alice's deletes
+	var ops []string
+	rows, err := st.Pool().Query(ctx, "SELECT op || ':' || leaderboard_id FROM score_changes WHERE id > $1 ORDER BY id", before)
+	if err != nil {
+		t.Fatalf("read outbox: %s", err)
+	}
+	for rows.Next() {
+		var op string
+		rows.Scan(&op)
+		ops = append(ops, op)
+	}
+	rows.Close()
+	if !slices.Equal(ops, []string{"resync:global", "resync:level-1"}) {
+		t.Errorf("outbox = %v, want a resync of global and level-1", ops)
+	}
+
+	data, err = st.ExportPlayerData(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("ExportPlayerData after erasure failed: %s", err)
+	}
+	if len(data.Scores)+len(data.Changes)+len(data.WebhookDeliveries) != 0 || data.Profile != nil || len(data.Erasures) != 1 {
+		t.Errorf("export after erasure = %+v, want nothing but the erasure", data)
+	}
+	if bob, _ := st.ExportPlayerData(ctx, "Bob"); len(bob.Scores) != 1 || len(bob.WebhookDeliveries) != 1 {
+		t.Errorf("Bob's data = %+v, want his score and his delivery: other players are kept", bob)
+	}
+}
+
+func TestRenamePlayer(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, row := range []store.UpsertScoreParams{
+		{LeaderboardID: "global", PlayerName: "Alice", Score: 100},
+		{LeaderboardID: "level-1", PlayerName: "Alice", Score: 300},
+		{LeaderboardID: "level-1", PlayerName: "Carol", Score: 200},
+		{LeaderboardID: "level-2", PlayerName: "Alice", Score: 10},
+		{LeaderboardID: "level-2", PlayerName: "Carol", Score: 50},
+	} {
+		if _, err := st.UpsertScore(ctx, row); err != nil {
+			t.Fatalf("UpsertScore failed: %s", err)
+		}
+	}
+	if _, err := st.UpsertPlayerProfile(ctx, store.UpsertPlayerProfileParams{PlayerName: "Alice", DisplayName: "Ally"}); err != nil {
+		t.Fatalf("UpsertPlayerProfile failed: %s", err)
+	}
+
+	if _, err := st.RenamePlayer(ctx, "Alice", "Carol", false); !errors.Is(err, store.ErrPlayerExists) {
+		t.Fatalf("rename to a player in use: %v, want ErrPlayerExists", err)
+	}
+	var before int64
+	st.Pool().QueryRow(ctx, "SELECT COALESCE(MAX(id), 0) FROM score_changes").Scan(&before)
+	res, err := st.RenamePlayer(ctx, "Alice", "Carol", true)
+	if err != nil {
+		t.Fatalf("RenamePlayer failed: %s", err)
+	}
+	if len(res.Scores) != 3 || !slices.Equal(res.Merged, []string{"level-1", "level-2"}) || res.Profile == nil || res.Profile.DisplayName != "Ally" {
+		t.Errorf("merge = %+v, want 3 scores, 2 merged boards and Alice's profile", res)
+	}
+
+	// Alice leaves each board; Carol enters global and improves on level-1 only
+	var ops []string
+	rows, err := st.Pool().Query(ctx, "SELECT op || ':' || leaderboard_id || ':' || player_name || ':' || score FROM score_changes WHERE id > $1 ORDER BY id", before)
+	if err != nil {
+		t.Fatalf("read outbox: %s", err)
+	}
+	for rows.Next() {
+		var op string
+		rows.Scan(&op)
+		ops = append(ops, op)
+	}
+	rows.Close()
+	want := []string{"delete:global:Alice:100", "insert:global:Carol:100", "delete:level-1:Alice:300", "update:level-1:Carol:300", "delete:level-2:Alice:10"}
+	if !slices.Equal(ops, want) {
+		t.Errorf("outbox = %v, want %v", ops, want)
+	}
+
+	if data, _ := st.ExportPlayerData(ctx, "Alice"); len(data.Scores) != 0 || data.Profile != nil {
+		t.Errorf("Alice after the rename = %+v, want nothing", data)
+	}
+	if sc, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: "level-2", PlayerName: "Carol"}); err != nil || sc.Score != 50 {
+		t.Errorf("Carol's level-2 score = %d, %v, want her own 50", sc.Score, err)
+	}
+}
+
+func TestRestoreLeaderboard(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if _, err := st.UpsertLeaderboard(ctx, store.UpsertLeaderboardParams{LeaderboardID: "lap-1", SortOrder: "asc", SecondarySortOrder: "desc"}); err != nil {
+		t.Fatalf("UpsertLeaderboard failed: %s", err)
+	}
+	for _, name := range []string{"Alice", "Mallory"} {
+		if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "lap-1", PlayerName: name, Score: 1}); err != nil {
+			t.Fatalf("UpsertScore failed: %s", err)
+		}
+	}
+	var before int64
+	st.Pool().QueryRow(ctx, "SELECT COALESCE(MAX(id), 0) FROM score_changes").Scan(&before)
+
+	achievedAt := time.Date(2025, 1, 15, 10, 29, 41, 123456000, time.UTC)
+	res, err := st.RestoreLeaderboard(ctx, "lap-1", []store.RestoreEntry{
+		{PlayerName: "Alice", Score: 52, AchievedAt: achievedAt, UpdatedAt: achievedAt},
+		{PlayerName: "Bob", Score: 48, AchievedAt: achievedAt, UpdatedAt: achievedAt},
+	}, true)
+	if err != nil {
+		t.Fatalf("RestoreLeaderboard failed: %s", err)
+	}
+	if res.Deleted != 2 || res.Restored != 2 || res.SnapshotID == 0 {
+		t.Errorf("result = %+v, want 2 replaced by 2 and a snapshot", res)
+	}
+
+	// Scores are written as they are, ranked by the board's order
+	top, err := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: "lap-1", PageSize: 10})
+	if err != nil {
+		t.Fatalf("GetTopScores failed: %s", err)
+	}
+	if len(top) != 2 || top[0].PlayerName != "Bob" || top[0].RankScore != -48 || !top[1].AchievedAt.Time.Equal(achievedAt) {
+		t.Errorf("board after restore = %+v, want Bob then Alice", top)
+	}
+	entries, err := st.GetSnapshotEntries(ctx, res.SnapshotID)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("snapshot entries = %+v, %v; want Alice and Mallory", entries, err)
+	}
+
+	var ops []string
+	rows, err := st.Pool().Query(ctx, "SELECT op || ':' || leaderboard_id FROM score_changes WHERE id > $1 ORDER BY id", before)
+	if err != nil {
+		t.Fatalf("read outbox: %s", err)
+	}
+	for rows.Next() {
+		var op string
+		rows.Scan(&op)
+		ops = append(ops, op)
+	}
+	rows.Close()
+	if len(ops) != 1 || ops[0] != "resync:lap-1" {
+		t.Errorf("outbox = %v, want [resync:lap-1]", ops)
+	}
+}
+
+func TestOutboxPollerResumes(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	logger := zerolog.Nop()
+	cfg := notify.OutboxConfig{Consumer: "test", Interval: 10 * time.Millisecond, Retention: time.Hour}
+	upsert := func(name string) {
+		t.Helper()
+		if _, err := st.UpsertScore(context.Background(), store.UpsertScoreParams{LeaderboardID: board, PlayerName: name, Score: 100}); err != nil {
+			t.Fatalf("UpsertScore failed: %s", err)
+		}
+	}
+	next := func(p *notify.OutboxPoller) notify.ScoreChange {
+		t.Helper()
+		select {
+		case c := <-p.Changes():
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change from the outbox poller")
+			return notify.ScoreChange{}
+		}
+	}
+
+	// A new consumer starts at the end of the outbox
+	upsert("Before")
+	ctx, stop := context.WithCancel(context.Background())
+	p := notify.NewOutboxPoller(st.Pool(), &logger, cfg)
+	p.Start(ctx)
+	for !p.Listening() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	upsert("Alice")
+	if c := next(p); c.PlayerName != "Alice" || c.Op != "insert" {
+		t.Errorf("first change = %+v, want Alice's insert", c)
+	}
+	stop()
+	for range p.Changes() {
+	}
+
+	// Changes made while stopped are delivered after a restart
+	upsert("Bob")
+	ctx, stop = context.WithCancel(context.Background())
+	defer stop()
+	p = notify.NewOutboxPoller(st.Pool(), &logger, cfg)
+	p.Start(ctx)
+	if c := next(p); c.PlayerName != "Bob" {
+		t.Errorf("resumed change = %+v, want Bob's insert", c)
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	var ids []int64
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: name, Score: 100}); err != nil {
+			t.Fatalf("UpsertScore failed: %s", err)
+		}
+		var id int64
+		if err := st.Pool().QueryRow(ctx, `SELECT max(id) FROM score_changes`).Scan(&id); err != nil {
+			t.Fatalf("read outbox: %s", err)
+		}
+		ids = append(ids, id)
+	}
+
+	logger := zerolog.Nop()
+	source := notify.NewOutboxPoller(st.Pool(), &logger, notify.OutboxConfig{Consumer: "test", Interval: time.Hour, Retention: time.Hour})
+	r := notify.NewReplayer(st.Pool(), source, &logger)
+	r.Start(ctx)
+
+	res, err := r.Replay(ctx, ids[1], ids[2], true)
+	if err != nil {
+		t.Fatalf("dry run: %s", err)
+	}
+	if res.Events != 2 || res.FirstID != ids[1] || res.LastID != ids[2] || res.Ops["insert"] != 2 || res.LatestID != ids[2] {
+		t.Errorf("dry run = %+v, want Bob's and Carol's inserts", res)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Replay(ctx, ids[1], ids[2], false)
+		done <- err
+	}()
+	var got []string
+	for len(got) < 3 {
+		select {
+		case c := <-r.Changes():
+			got = append(got, c.Op+":"+c.PlayerName)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("replayed %v, want 2 changes and a resync", got)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("replay: %s", err)
+	}
+	if got[0] != "insert:Bob" || got[1] != "insert:Carol" || got[2] != "resync:" {
+		t.Errorf("replayed %v, want Bob's and Carol's inserts then a resync", got)
+	}
+}
+
+func TestChangeLog(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var ids []int64
+	for _, sub := range []store.UpsertScoreParams{
+		{LeaderboardID: board, PlayerName: "Alice", Score: 100},
+		{LeaderboardID: board, PlayerName: "Bob", Score: 100},
+		{LeaderboardID: "level-1", PlayerName: "Carol", Score: 100},
+		{LeaderboardID: board, PlayerName: "Alice", Score: 150},
+	} {
+		if _, err := st.UpsertScore(ctx, sub); err != nil {
+			t.Fatalf("UpsertScore failed: %s", err)
+		}
+		var id int64
+		if err := st.Pool().QueryRow(ctx, `SELECT max(id) FROM score_changes`).Scan(&id); err != nil {
+			t.Fatalf("read outbox: %s", err)
+		}
+		ids = append(ids, id)
+	}
+	// Settle the changes rather than wait for them
+	settle := func() {
+		if _, err := st.Pool().Exec(ctx, `UPDATE score_changes SET created_at = created_at - interval '1 minute'`); err != nil {
+			t.Fatalf("settle changes: %s", err)
+		}
+	}
+	settle()
+
+	log := notify.NewChangeLog(st.Pool(), time.Hour)
+	page, err := log.ChangesSince(ctx, board, 0, time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("ChangesSince without checkpoint: %s", err)
+	}
+	if !page.Resync || page.LastID != ids[3] || len(page.Changes) != 0 {
+		t.Errorf("without checkpoint = %+v, want a resync from %d", page, ids[3])
+	}
+
+	page, err = log.ChangesSince(ctx, board, ids[0], time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("ChangesSince: %s", err)
+	}
+	if page.Resync || page.More || page.LastID != ids[3] || len(page.Changes) != 2 ||
+		page.Changes[0].PlayerName != "Bob" || page.Changes[1].PlayerName != "Alice" || page.Changes[1].Score != 150 {
+		t.Errorf("since %d = %+v, want Bob's insert then Alice's update", ids[0], page)
+	}
+	page, err = log.ChangesSince(ctx, board, 0, time.Now().Add(-5*time.Minute), 10)
+	if err != nil {
+		t.Fatalf("ChangesSince a time: %s", err)
+	}
+	if page.Resync || len(page.Changes) != 2 || page.Changes[0].ID != ids[1] || page.Changes[1].ID != ids[3] {
+		t.Errorf("since a time = %+v, want the last change of Bob and Alice", page)
+	}
+	if page, err = log.ChangesSince(ctx, board, 0, time.Now().Add(-2*time.Hour), 10); err != nil || !page.Resync {
+		t.Errorf("since before the retention = %+v (%v), want a resync", page, err)
+	}
+	if page, err = log.ChangesSince(ctx, board, ids[0], time.Time{}, 1); err != nil || !page.More || page.LastID != ids[1] {
+		t.Errorf("full page = %+v (%v), want more after Bob's insert", page, err)
+	}
+
+	// Unsettled changes wait for the next poll
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Bob", Score: 300}); err != nil {
+		t.Fatalf("UpsertScore failed: %s", err)
+	}
+	if page, err = log.ChangesSince(ctx, board, ids[3], time.Time{}, 10); err != nil || len(page.Changes) != 0 || page.LastID != ids[3] {
+		t.Errorf("unsettled change = %+v (%v), want none yet", page, err)
+	}
+
+	if _, err := st.ResetLeaderboard(ctx, board, false); err != nil {
+		t.Fatalf("ResetLeaderboard failed: %s", err)
+	}
+	settle()
+	if page, err = log.ChangesSince(ctx, board, ids[3], time.Time{}, 10); err != nil || !page.Resync || len(page.Changes) != 0 {
+		t.Errorf("after a reset = %+v (%v), want a resync", page, err)
+	}
+
+	// A checkpoint pruned from the outbox may have lost the changes after it
+	if _, err := st.Pool().Exec(ctx, `DELETE FROM score_changes WHERE id <= $1`, ids[1]); err != nil {
+		t.Fatalf("prune outbox: %s", err)
+	}
+	if page, err = log.ChangesSince(ctx, board, ids[0], time.Time{}, 10); err != nil || !page.Resync {
+		t.Errorf("pruned checkpoint = %+v (%v), want a resync", page, err)
+	}
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	hook, err := st.CreateWebhook(ctx, store.CreateWebhookParams{
+		Url:           "https://hooks.example.com",
+		Secret:        "whsec_test",
+		Events:        []string{"score.high_score"},
+		LeaderboardID: pgtype.Text{String: "level-1", Valid: true},
+		Enabled:       true,
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook failed: %s", err)
+	}
+	for board, want := range map[string]int{"level-1": 1, "level-2": 0} {
+		hooks, err := st.ListWebhooksForEvent(ctx, store.ListWebhooksForEventParams{EventType: "score.high_score", LeaderboardID: board})
+		if err != nil {
+			t.Fatalf("ListWebhooksForEvent failed: %s", err)
+		}
+		if len(hooks) != want {
+			t.Errorf("webhooks for %s: %d, want %d", board, len(hooks), want)
+		}
+	}
+
+	// Every server queues the change; the queue keeps one delivery
+	enqueue := store.EnqueueWebhookDeliveryParams{WebhookID: hook.ID, EventType: "score.high_score", ChangeID: 42, Payload: []byte(`{}`)}
+	for i, want := range []int64{1, 0} {
+		n, err := st.EnqueueWebhookDelivery(ctx, enqueue)
+		if err != nil {
+			t.Fatalf("EnqueueWebhookDelivery failed: %s", err)
+		}
+		if n != want {
+			t.Errorf("enqueue %d: %d rows, want %d", i, n, want)
+		}
+	}
+
+	claim := store.ClaimWebhookDeliveriesParams{LeaseSeconds: 60, MaxDeliveries: 10}
+	rows, err := st.ClaimWebhookDeliveries(ctx, claim)
+	if err != nil {
+		t.Fatalf("ClaimWebhookDeliveries failed: %s", err)
+	}
+	if len(rows) != 1 || rows[0].Url != hook.Url || rows[0].Secret != hook.Secret {
+		t.Fatalf("claimed %+v, want the queued delivery", rows)
+	}
+	if again, _ := st.ClaimWebhookDeliveries(ctx, claim); len(again) != 0 {
+		t.Errorf("leased delivery claimed again: %+v", again)
+	}
+
+	err = st.CompleteWebhookDelivery(ctx, store.CompleteWebhookDeliveryParams{
+		ID:             rows[0].ID,
+		Status:         "delivered",
+		NextAttemptAt:  pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		LastStatusCode: pgtype.Int4{Int32: 204, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CompleteWebhookDelivery failed: %s", err)
+	}
+	log, err := st.ListWebhookDeliveries(ctx, store.ListWebhookDeliveriesParams{WebhookID: hook.ID, MaxDeliveries: 10})
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries failed: %s", err)
+	}
+	if len(log) != 1 || log[0].Status != "delivered" || log[0].Attempts != 1 || log[0].LastStatusCode.Int32 != 204 {
+		t.Errorf("delivery log = %+v", log)
+	}
+
+	// Deleting the webhook deletes its deliveries
+	if n, err := st.DeleteWebhook(ctx, hook.ID); err != nil || n != 1 {
+		t.Fatalf("DeleteWebhook = %d, %v", n, err)
+	}
+	if log, _ := st.ListWebhookDeliveries(ctx, store.ListWebhookDeliveriesParams{WebhookID: hook.ID, MaxDeliveries: 10}); len(log) != 0 {
+		t.Errorf("deliveries left after delete: %+v", log)
+	}
+}
+
+func TestRequestTagging(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tagged := func(ctx context.Context) (appName, requestID string) {
+		t.Helper()
+		err := st.Pool().QueryRow(ctx,
+			"SELECT current_setting('application_name'), coalesce(current_setting('"+store.RequestIDSetting+"', true), '')",
+		).Scan(&appName, &requestID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return appName, requestID
+	}
+
+	ctx := requestctx.NewContext(context.Background(), requestctx.Info{RequestID: "req-42"})
+	if app, id := tagged(ctx); app != "leaderboard req-42" || id != "req-42" {
+		t.Errorf("in a request: application_name %q, request id %q", app, id)
+	}
+
+	// Connections go back to the base name outside a request
+	for range 10 {
+		if app, id := tagged(context.Background()); app != "leaderboard" || id != "" {
+			t.Errorf("outside a request: application_name %q, request id %q", app, id)
+		}
+	}
+}
+
+func TestPlayerNameLengthConstraint(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Try to insert a name longer than 20 characters
+	_, err := st.UpsertScore(ctx, store.UpsertScoreParams{
+		LeaderboardID: board,
+		PlayerName:    "ThisNameIsWayTooLongAndShouldFail", // 34 characters
+		Score:         100,
+	})
+	if err == nil {
+		t.Error("expected error for name > 20 chars, got nil")
+	}
+
+	// Valid 20-character name should work
+	_, err = st.UpsertScore(ctx, store.UpsertScoreParams{
+		LeaderboardID: board,
+		PlayerName:    "12345678901234567890", // exactly 20 characters
+		Score:         100,
+	})
+	if err != nil {
+		t.Errorf("expected success for 20-char name, got error: %s", err)
+	}
+}
+
+func TestApplyValidation(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Written under the migration bounds, kept by the NOT VALID constraints
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "TwelveLetter", Score: 5000}); err != nil {
+		t.Fatalf("failed to insert score: %s", err)
+	}
+
+	v := store.Validation{MinPlayerNameLength: 3, MaxPlayerNameLength: 30, MaxScore: 1000}
+	if err := st.ApplyValidation(ctx, v); err != nil {
+		t.Fatalf("ApplyValidation failed: %s", err)
+	}
+	// Same settings again: nothing to replace
+	if err := st.ApplyValidation(ctx, v); err != nil {
+		t.Fatalf("ApplyValidation again failed: %s", err)
+	}
+
+	cases := []struct {
+		name  string
+		score int64
+		ok    bool
+	}{
+		{"ThisNameIsLongerThanTwenty", 100, true},
+		{"Al", 100, false},
+		{"Bob", 1000, true},
+		{"Carol", 1001, false},
+	}
+	for _, c := range cases {
+		_, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: c.name, Score: c.score})
+		if (err == nil) != c.ok {
+			t.Errorf("upsert %s with %d: error = %v, want ok %t", c.name, c.score, err, c.ok)
+		}
+	}
+
+	// Back to the defaults: the score bound is dropped
+	if err := st.ApplyValidation(ctx, store.Validation{}); err != nil {
+		t.Fatalf("ApplyValidation with defaults failed: %s", err)
+	}
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Carol", Score: 1001}); err != nil {
+		t.Errorf("upsert above the dropped bound: %s", err)
+	}
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "ThisNameIsWayTooLongAndShouldFail", Score: 100}); err == nil {
+		t.Error("expected error for name > 20 chars after restoring the defaults, got nil")
+	}
+}
+
+func TestMaintenanceStats(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	for i := range 100 {
+		_, err := st.UpsertScore(ctx, store.UpsertScoreParams{
+			LeaderboardID: board,
+			PlayerName:    fmt.Sprintf("Player%d", i),
+			Score:         int64(i * 10),
+		})
+		if err != nil {
+			t.Fatalf("failed to insert score: %s", err)
+		}
+	}
+
+	analyzed, err := st.Analyze(ctx)
+	if err != nil {
+		t.Fatalf("Analyze failed: %s", err)
+	}
+	if !slices.Contains(analyzed, "scores") {
+		t.Errorf("analyzed tables = %v, want scores among them", analyzed)
+	}
+
+	tables, err := st.TableStats(ctx)
+	if err != nil {
+		t.Fatalf("TableStats failed: %s", err)
+	}
+	// Activity counters are flushed asynchronously, so only check the table is listed
+	if !slices.ContainsFunc(tables, func(tbl store.TableStats) bool { return tbl.Table == "scores" }) {
+		t.Errorf("TableStats = %+v, want scores among them", tables)
+	}
+
+	indexes, err := st.IndexStats(ctx)
+	if err != nil {
+		t.Fatalf("IndexStats failed: %s", err)
+	}
+	for _, ix := range indexes {
+		if ix.Index == "idx_scores_leaderboard" {
+			if ix.SizeBytes <= 0 || ix.KeyWidth <= 0 {
+				t.Errorf("idx_scores_leaderboard stats = %+v, want a size and key width", ix)
+			}
+			return
+		}
+	}
+	t.Errorf("IndexStats = %+v, want idx_scores_leaderboard among them", indexes)
+}
+
+func TestBanPlayer(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, row := range []store.UpsertScoreParams{
+		{LeaderboardID: "global", PlayerName: "Mallory", Score: 900},
+		{LeaderboardID: "level-1", PlayerName: "Mallory", Score: 500},
+		{LeaderboardID: "global", PlayerName: "Alice", Score: 100},
+	} {
+		if _, err := st.UpsertScore(ctx, row); err != nil {
+			t.Fatalf("UpsertScore failed: %s", err)
+		}
+	}
+
+	var before int64
+	st.Pool().QueryRow(ctx, "SELECT COALESCE(MAX(id), 0) FROM score_changes").Scan(&before)
+	res, err := st.BanPlayer(ctx, "Mallory", "cheating", true)
+	if err != nil {
+		t.Fatalf("BanPlayer failed: %s", err)
+	}
+	slices.Sort(res.Purged)
+	if res.Ban.Reason != "cheating" || !res.Ban.BannedAt.Valid || !slices.Equal(res.Purged, []string{"global", "level-1"}) {
+		t.Errorf("ban = %+v, want Mallory's two boards purged", res)
+	}
+	var deletes int
+	st.Pool().QueryRow(ctx, "SELECT COUNT(*) FROM score_changes WHERE id > $1 AND op = 'delete' AND player_name = 'Mallory'", before).Scan(&deletes)
+	if deletes != 2 {
+		t.Errorf("outbox has %d deletes of Mallory, want 2", deletes)
+	}
+
+	// Banning again replaces the reason and keeps the ban time
+	again, err := st.BanPlayer(ctx, "Mallory", "cheating again", false)
+	if err != nil {
+		t.Fatalf("BanPlayer failed: %s", err)
+	}
+	if again.Ban.Reason != "cheating again" || !again.Ban.BannedAt.Time.Equal(res.Ban.BannedAt.Time) || len(again.Purged) != 0 {
+		t.Errorf("second ban = %+v", again)
+	}
+	if bans, err := st.ListBannedPlayers(ctx); err != nil || len(bans) != 1 {
+		t.Errorf("ListBannedPlayers = %+v, %v, want 1 ban", bans, err)
+	}
+
+	if err := st.UnbanPlayer(ctx, "Mallory"); err != nil {
+		t.Fatalf("UnbanPlayer failed: %s", err)
+	}
+	if _, err := st.GetBannedPlayer(ctx, "Mallory"); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("ban after the unban: %v, want ErrNoRows", err)
+	}
+	if err := st.UnbanPlayer(ctx, "Mallory"); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("second unban: %v, want ErrNoRows", err)
+	}
+}
+
+func TestArchiveScores(t *testing.T) {
+	st, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if _, err := st.UpsertLeaderboard(ctx, store.UpsertLeaderboardParams{LeaderboardID: "time-trial", SortOrder: "asc", SecondarySortOrder: "desc"}); err != nil {
+		t.Fatalf("UpsertLeaderboard failed: %s", err)
+	}
+	for _, p := range []store.UpsertScoreParams{
+		{LeaderboardID: "global", PlayerName: "Alice", Score: 100},
+		{LeaderboardID: "global", PlayerName: "Bob", Score: 5},
+		{LeaderboardID: "global", PlayerName: "Carol", Score: 50},
+		{LeaderboardID: "time-trial", PlayerName: "Dave", Score: 30},
+		{LeaderboardID: "time-trial", PlayerName: "Eve", Score: 500},
+	} {
+		if _, err := st.UpsertScore(ctx, p); err != nil {
+			t.Fatalf("UpsertScore failed: %s", err)
+		}
+	}
+	if _, err := st.Pool().Exec(ctx, "UPDATE scores SET updated_at = now() - interval '2 days' WHERE player_name = 'Alice'"); err != nil {
+		t.Fatalf("age Alice's score: %s", err)
+	}
+	var before int64
+	st.Pool().QueryRow(ctx, "SELECT COALESCE(MAX(id), 0) FROM score_changes").Scan(&before)
+
+	// Alice is stale, Bob below the floor and Eve, on an ascending board, above it
+	policy := store.ArchivePolicy{UpdatedBefore: time.Now().Add(-24 * time.Hour), ScoreFloor: 40, Limit: 2, DryRun: true}
+
+	// The preview lists them oldest first, a page at a time
+	page, err := st.ListArchiveCandidates(ctx, store.ListArchiveCandidatesParams{Policy: policy, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListArchiveCandidates failed: %s", err)
+	}
+	if len(page) != 2 || page[0].PlayerName != "Alice" || page[0].BelowFloor || !page[1].BelowFloor {
+		t.Fatalf("first page = %+v, want Alice, then a score below the floor", page)
+	}
+	rest, err := st.ListArchiveCandidates(ctx, store.ListArchiveCandidatesParams{Policy: policy, After: &page[1], Limit: 2})
+	if err != nil {
+		t.Fatalf("ListArchiveCandidates failed: %s", err)
+	}
+	if len(rest) != 1 || !rest[0].BelowFloor || rest[0].PlayerName == page[1].PlayerName {
+		t.Errorf("second page = %+v, want the other score below the floor", rest)
+	}
+
+	res, err := st.ArchiveScores(ctx, policy)
+	if err != nil {
+		t.Fatalf("dry run failed: %s", err)
+	}
+	if fmt.Sprint(res.Boards) != "[{global 1 1} {time-trial 0 1}]" {
+		t.Errorf("dry run = %v, want Alice and Bob on global, Eve on time-trial", res.Boards)
+	}
+	if n, _ := st.CountScores(ctx, "global"); n != 3 {
+		t.Errorf("global holds %d scores after a dry run, want 3", n)
+	}
+
+	policy.DryRun = false
+	var archived int64
+	for _, want := range []int64{2, 1, 0} {
+		res, err := st.ArchiveScores(ctx, policy)
+		if err != nil {
+			t.Fatalf("ArchiveScores failed: %s", err)
+		}
+		if res.Total() != want {
+			t.Errorf("batch archived %d scores, want %d", res.Total(), want)
+		}
+		archived += res.Total()
+	}
+	if archived != 3 {
+		t.Fatalf("archived %d scores, want 3", archived)
+	}
+	if n, _ := st.CountScores(ctx, "global"); n != 1 {
+		t.Errorf("global holds %d scores, want Carol only", n)
+	}
+	var reasons []string
+	rows, err := st.Pool().Query(ctx, "SELECT player_name || ':' || reason FROM scores_archive ORDER BY player_name")
+	if err != nil {
+		t.Fatalf("read archive: %s", err)
+	}
+	for rows.Next() {
+		var r string
+		rows.Scan(&r)
+		reasons = append(reasons, r)
+	}
+	rows.Close()
+	if fmt.Sprint(reasons) != "[Alice:retention Bob:score_floor Eve:score_floor]" {
+		t.Errorf("archive = %v", reasons)
+	}
+
+	// Resyncs in the outbox instead of a delete per row
+	var deletes int64
+	st.Pool().QueryRow(ctx, "SELECT COUNT(*) FROM score_changes WHERE op = 'delete' AND id > $1", before).Scan(&deletes)
+	if deletes != 0 {
+		t.Errorf("got %d delete changes, want resyncs only", deletes)
+	}
+
+	// Archived scores belong to the player's data
+	data, err := st.ExportPlayerData(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("ExportPlayerData failed: %s", err)
+	}
+	if len(data.ArchivedScores) != 1 || data.ArchivedScores[0].Score != 100 {
+		t.Errorf("archived scores = %+v, want Alice's 100", data.ArchivedScores)
+	}
+	if _, err := st.ErasePlayerData(ctx, "Alice", "req-1"); err != nil {
+		t.Fatalf("ErasePlayerData failed: %s", err)
+	}
+	if data, _ := st.ExportPlayerData(ctx, "Alice"); len(data.ArchivedScores) != 0 {
+		t.Errorf("archived scores after erasure = %+v, want none", data.ArchivedScores)
 	}
 }
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Maintainer exposes planner statistics and catalog counters for the maintenance job.
+// These read the PostgreSQL catalog, which sqlc cannot type, so they are written by hand.
+type Maintainer interface {
+	// Analyze refreshes planner statistics of the application tables and returns their names
+	Analyze(ctx context.Context) ([]string, error)
+
+	// TableStats returns activity counters of the application tables
+	TableStats(ctx context.Context) ([]TableStats, error)
+
+	// IndexStats returns size and usage of the application btree indexes
+	IndexStats(ctx context.Context) ([]IndexStats, error)
+
+	// PruneDeviceSubmissions deletes the device submissions logged more than
+	// retentionSeconds ago (generated by sqlc)
+	PruneDeviceSubmissions(ctx context.Context, retentionSeconds float64) (int64, error)
+}
+
+// TableStats are the activity counters of a table (pg_stat_user_tables)
+type TableStats struct {
+	Table          string
+	LiveRows       int64
+	DeadRows       int64
+	SeqScans       int64
+	SeqRowsRead    int64
+	IndexScans     int64
+	SizeBytes      int64     // table, indexes and TOAST
+	LastAnalyzedAt time.Time // manual or automatic, zero if never
+}
+
+// IndexStats describe the size and usage of a btree index
+type IndexStats struct {
+	Index     string
+	Table     string
+	SizeBytes int64
+	Scans     int64
+	Unique    bool
+	Rows      int64 // planner row estimate of the table
+	KeyWidth  int64 // average width in bytes of the indexed columns, 0 before the first ANALYZE
+}
+
+var _ Maintainer = (*Store)(nil)
+
+// userTablesQuery lists the application tables, leaving out the migration bookkeeping
+const userTablesQuery = `
+	SELECT relname
+	FROM pg_stat_user_tables
+	WHERE schemaname = current_schema() AND relname <> 'schema_migrations'
+	ORDER BY relname`
+
+// Analyze runs ANALYZE on every application table
+func (s *Store) Analyze(ctx context.Context) ([]string, error) {
+	rows, err := s.pool.Query(ctx, userTablesQuery)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, table := range tables {
+		if _, err := s.pool.Exec(ctx, "ANALYZE "+pgx.Identifier{table}.Sanitize()); err != nil {
+			return nil, fmt.Errorf("analyze %s: %w", table, err)
+		}
+	}
+	return tables, nil
+}
+
+// TableStats returns pg_stat_user_tables counters of the application tables
+func (s *Store) TableStats(ctx context.Context) ([]TableStats, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT relname, n_live_tup, n_dead_tup, seq_scan, seq_tup_read,
+		       COALESCE(idx_scan, 0), pg_total_relation_size(relid),
+		       GREATEST(last_analyze, last_autoanalyze)
+		FROM pg_stat_user_tables
+		WHERE schemaname = current_schema() AND relname <> 'schema_migrations'
+		ORDER BY relname`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (TableStats, error) {
+		var t TableStats
+		var analyzedAt pgtype.Timestamptz
+		err := row.Scan(&t.Table, &t.LiveRows, &t.DeadRows, &t.SeqScans, &t.SeqRowsRead,
+			&t.IndexScans, &t.SizeBytes, &analyzedAt)
+		if analyzedAt.Valid {
+			t.LastAnalyzedAt = analyzedAt.Time
+		}
+		return t, err
+	})
+}
+
+// IndexStats returns size, usage and key width of the application btree indexes.
+// The key width comes from pg_stats, so it is only known once the table was analyzed.
+func (s *Store) IndexStats(ctx context.Context) ([]IndexStats, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT ui.indexrelname, ui.relname, pg_relation_size(ui.indexrelid), ui.idx_scan,
+		       i.indisunique, GREATEST(t.reltuples, 0)::bigint,
+		       COALESCE((
+		           SELECT SUM(st.avg_width)
+		           FROM pg_attribute a
+		           JOIN pg_stats st ON st.schemaname = ui.schemaname
+		                           AND st.tablename = ui.relname
+		                           AND st.attname = a.attname
+		           WHERE a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		       ), 0)::bigint
+		FROM pg_stat_user_indexes ui
+		JOIN pg_index i ON i.indexrelid = ui.indexrelid
+		JOIN pg_class ic ON ic.oid = ui.indexrelid
+		JOIN pg_am am ON am.oid = ic.relam
+		JOIN pg_class t ON t.oid = ui.relid
+		WHERE ui.schemaname = current_schema() AND ui.relname <> 'schema_migrations'
+		  AND am.amname = 'btree'
+		ORDER BY ui.relname, ui.indexrelname`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (IndexStats, error) {
+		var ix IndexStats
+		err := row.Scan(&ix.Index, &ix.Table, &ix.SizeBytes, &ix.Scans, &ix.Unique, &ix.Rows, &ix.KeyWidth)
+		return ix, err
+	})
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratepgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/db/migrations"
+)
+
+// Migrate applies pending embedded migrations (db/migrations) to the database behind pool.
+// It uses the same schema_migrations table as the migrate CLI, so both can be mixed.
+func Migrate(pool *pgxpool.Pool, logger *zerolog.Logger) error {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return fmt.Errorf("load embedded migrations: %w", err)
+	}
+
+	db := stdlib.OpenDBFromPool(pool)
+	driver, err := migratepgx.WithInstance(db, &migratepgx.Config{})
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("init migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "pgx5", driver)
+	if err != nil {
+		driver.Close()
+		return fmt.Errorf("init migrator: %w", err)
+	}
+	defer m.Close()
+
+	before, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("read migration version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("database is dirty at migration version %d, fix it and force the version with the migrate CLI", before)
+	}
+
+	if err := m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			logger.Info().Uint("version", before).Msg("database schema up to date")
+			return nil
+		}
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+
+	after, _, err := m.Version()
+	if err != nil {
+		return fmt.Errorf("read migration version: %w", err)
+	}
+	logger.Info().Uint("from", before).Uint("to", after).Msg("📦 database migrations applied")
+	return nil
+}
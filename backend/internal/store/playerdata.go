@@ -0,0 +1,212 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PlayerDataManager exports and erases everything stored about one player, for
+// data subject access and erasure requests
+type PlayerDataManager interface {
+	// ExportPlayerData reads every row about a player, from a single snapshot of the database
+	ExportPlayerData(ctx context.Context, playerName string) (PlayerData, error)
+
+	// ErasePlayerData deletes every row about a player in a single transaction
+	// and records the erasure in player_erasures. Change listeners receive a
+	// resync of each board where the player had a live score instead of a
+	// delete, which would write the player's score to the outbox again.
+	ErasePlayerData(ctx context.Context, playerName, requestID string) (ErasureResult, error)
+}
+
+// PlayerData is everything stored about a player
+type PlayerData struct {
+	Scores            []Score                    // every board, soft-deleted scores included
+	ArchivedScores    []ScoresArchive            // scores moved to scores_archive by the archival job
+	Profile           *Player                    // nil without a profile
+	Devices           []DevicePlayer             // devices the player submitted from
+	DeviceSubmissions []DeviceSubmission         // submissions counted by the device limits
+	SnapshotEntries   []LeaderboardSnapshotEntry // the player's entries in board snapshots
+	Changes           []ScoreChange              // score changes still in the outbox
+	WebhookDeliveries []WebhookDelivery          // deliveries whose event names the player, nil without webhook support
+	Erasures          []PlayerErasure            // earlier erasures of the player name
+}
+
+// ErasureResult reports what ErasePlayerData removed
+type ErasureResult struct {
+	Erasure PlayerErasure // the player_erasures row recorded
+	Boards  []string      // boards where the player had a live score, resynced
+}
+
+var _ PlayerDataManager = (*Store)(nil)
+
+// PlayerHash returns the player_hash of a player name: its hex SHA-256
+func PlayerHash(playerName string) string {
+	sum := sha256.Sum256([]byte(playerName))
+	return hex.EncodeToString(sum[:])
+}
+
+// deliveryNamesPlayer matches the webhook deliveries whose event data names the
+// player $1: score events and both entries of leader changes
+const deliveryNamesPlayer = `(payload->'data'->>'player_name' = $1
+	OR payload->'data'->'leader'->>'player_name' = $1
+	OR payload->'data'->'previous_leader'->>'player_name' = $1)`
+
+// Export queries, selecting the columns in the order of the models they fill
+const (
+	exportScoresQuery         = `SELECT ` + scoreColumns + ` FROM scores WHERE player_name = $1 ORDER BY leaderboard_id`
+	exportArchivedScoresQuery = `
+		SELECT id, ` + scoreColumns + `, reason, archived_at
+		FROM scores_archive WHERE player_name = $1 ORDER BY id`
+	exportProfileQuery = `
+		SELECT player_name, display_name, country_code, avatar_url, created_at, updated_at
+		FROM players WHERE player_name = $1`
+	exportDevicesQuery = `
+		SELECT device_hash, player_name, first_seen_at
+		FROM device_players WHERE player_name = $1 ORDER BY first_seen_at`
+	exportSubmissionsQuery = `
+		SELECT id, device_hash, player_name, submitted_at
+		FROM device_submissions WHERE player_name = $1 ORDER BY id`
+	exportSnapshotEntriesQuery = `
+		SELECT snapshot_id, player_name, score, rank_score, achieved_at, updated_at, secondary_score, rank_secondary, country_code, platform
+		FROM leaderboard_snapshot_entries WHERE player_name = $1 ORDER BY snapshot_id`
+	exportChangesQuery = `
+		SELECT id, leaderboard_id, player_name, score, rank_score, achieved_at, op, created_at,
+		       metadata, secondary_score, rank_secondary, country_code, platform
+		FROM score_changes WHERE player_name = $1 ORDER BY id`
+	exportDeliveriesQuery = `
+		SELECT id, webhook_id, event_type, change_id, replayed, payload, status, attempts, next_attempt_at,
+		       last_status_code, last_error, created_at, updated_at
+		FROM webhook_deliveries WHERE ` + deliveryNamesPlayer + ` ORDER BY id`
+	exportErasuresQuery = `
+		SELECT id, player_hash, request_id, deleted_scores, deleted_rows, erased_at
+		FROM player_erasures WHERE player_hash = $1 ORDER BY id`
+)
+
+// ExportPlayerData reads the player's rows in a read-only repeatable read
+// transaction, so the export is consistent across tables
+func (s *Store) ExportPlayerData(ctx context.Context, playerName string) (PlayerData, error) {
+	tx, err := s.begin(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return PlayerData{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // read-only, nothing to commit
+
+	var data PlayerData
+	if data.Scores, err = collectRows[Score](ctx, tx, exportScoresQuery, playerName); err != nil {
+		return PlayerData{}, fmt.Errorf("export scores: %w", err)
+	}
+	if data.ArchivedScores, err = collectRows[ScoresArchive](ctx, tx, exportArchivedScoresQuery, playerName); err != nil {
+		return PlayerData{}, fmt.Errorf("export archived scores: %w", err)
+	}
+	profiles, err := collectRows[Player](ctx, tx, exportProfileQuery, playerName)
+	if err != nil {
+		return PlayerData{}, fmt.Errorf("export profile: %w", err)
+	}
+	if len(profiles) > 0 {
+		data.Profile = &profiles[0]
+	}
+	if data.Devices, err = collectRows[DevicePlayer](ctx, tx, exportDevicesQuery, playerName); err != nil {
+		return PlayerData{}, fmt.Errorf("export devices: %w", err)
+	}
+	if data.DeviceSubmissions, err = collectRows[DeviceSubmission](ctx, tx, exportSubmissionsQuery, playerName); err != nil {
+		return PlayerData{}, fmt.Errorf("export device submissions: %w", err)
+	}
+	if data.SnapshotEntries, err = collectRows[LeaderboardSnapshotEntry](ctx, tx, exportSnapshotEntriesQuery, playerName); err != nil {
+		return PlayerData{}, fmt.Errorf("export snapshot entries: %w", err)
+	}
+	if data.Changes, err = collectRows[ScoreChange](ctx, tx, exportChangesQuery, playerName); err != nil {
+		return PlayerData{}, fmt.Errorf("export score changes: %w", err)
+	}
+	if data.WebhookDeliveries, err = collectRows[WebhookDelivery](ctx, tx, exportDeliveriesQuery, playerName); err != nil {
+		return PlayerData{}, fmt.Errorf("export webhook deliveries: %w", err)
+	}
+	if data.Erasures, err = collectRows[PlayerErasure](ctx, tx, exportErasuresQuery, PlayerHash(playerName)); err != nil {
+		return PlayerData{}, fmt.Errorf("export erasures: %w", err)
+	}
+	return data, nil
+}
+
+// collectRows runs a query and scans each row into a T, by column position
+func collectRows[T any](ctx context.Context, tx pgx.Tx, query string, args ...any) ([]T, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[T])
+}
+
+// erasePlayerQueries delete the player's rows other than scores, in the order of PlayerData
+var erasePlayerQueries = []struct{ what, query string }{
+	{"archived scores", `DELETE FROM scores_archive WHERE player_name = $1`},
+	{"profile", `DELETE FROM players WHERE player_name = $1`},
+	{"devices", `DELETE FROM device_players WHERE player_name = $1`},
+	{"device submissions", `DELETE FROM device_submissions WHERE player_name = $1`},
+	{"snapshot entries", `DELETE FROM leaderboard_snapshot_entries WHERE player_name = $1`},
+	{"score changes", `DELETE FROM score_changes WHERE player_name = $1`},
+	{"webhook deliveries", `DELETE FROM webhook_deliveries WHERE ` + deliveryNamesPlayer},
+}
+
+const recordErasureQuery = `
+	INSERT INTO player_erasures (player_hash, request_id, deleted_scores, deleted_rows)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, player_hash, request_id, deleted_scores, deleted_rows, erased_at`
+
+// ErasePlayerData suppresses the per-row notifications of the deletes and
+// notifies a resync of each board instead, like ResetLeaderboard
+func (s *Store) ErasePlayerData(ctx context.Context, playerName, requestID string) (ErasureResult, error) {
+	tx, err := s.begin(ctx, pgx.TxOptions{})
+	if err != nil {
+		return ErasureResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	var res ErasureResult
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT leaderboard_id FROM scores
+		WHERE player_name = $1 AND deleted_at IS NULL
+		ORDER BY leaderboard_id`, playerName)
+	if err != nil {
+		return ErasureResult{}, fmt.Errorf("list boards: %w", err)
+	}
+	if res.Boards, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+		return ErasureResult{}, fmt.Errorf("list boards: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, suppressNotifyQuery); err != nil {
+		return ErasureResult{}, fmt.Errorf("suppress notifications: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM scores WHERE player_name = $1`, playerName)
+	if err != nil {
+		return ErasureResult{}, fmt.Errorf("delete scores: %w", err)
+	}
+	scores, total := tag.RowsAffected(), tag.RowsAffected()
+	for _, q := range erasePlayerQueries {
+		tag, err := tx.Exec(ctx, q.query, playerName)
+		if err != nil {
+			return ErasureResult{}, fmt.Errorf("delete %s: %w", q.what, err)
+		}
+		total += tag.RowsAffected()
+	}
+	for _, board := range res.Boards {
+		if _, err := tx.Exec(ctx, notifyResyncQuery, board); err != nil {
+			return ErasureResult{}, fmt.Errorf("notify resync: %w", err)
+		}
+	}
+
+	row, err := tx.Query(ctx, recordErasureQuery, PlayerHash(playerName), requestID, scores, total)
+	if err != nil {
+		return ErasureResult{}, fmt.Errorf("record erasure: %w", err)
+	}
+	if res.Erasure, err = pgx.CollectExactlyOneRow(row, pgx.RowToStructByPos[PlayerErasure]); err != nil {
+		return ErasureResult{}, fmt.Errorf("record erasure: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ErasureResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
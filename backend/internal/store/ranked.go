@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RankedUpserter writes a submitted score and ranks it for the submission response
+type RankedUpserter interface {
+	// UpsertScoreRanked applies UpsertScore and reads the player's rank on the board
+	// before and after it in a single transaction, so both ranks describe the
+	// board as this write changed it
+	UpsertScoreRanked(ctx context.Context, row UpsertScoreParams) (RankedScore, error)
+}
+
+// RankedScore is a player's best score after an upsert, with its rank
+type RankedScore struct {
+	UpsertedScore
+	Rank         int32 // 1-based rank after the upsert
+	PreviousRank int32 // rank before the upsert, 0 for a new entry
+}
+
+var _ RankedUpserter = (*Store)(nil)
+
+// UpsertScoreRanked ranks the previous best while its row is locked, so Previous,
+// Applied and PreviousRank are exact under concurrent writes. The rank is only
+// read again when the best score changed: otherwise the write cannot have moved
+// the player.
+func (s *Store) UpsertScoreRanked(ctx context.Context, row UpsertScoreParams) (RankedScore, error) {
+	tx, err := s.begin(ctx, pgx.TxOptions{})
+	if err != nil {
+		return RankedScore{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	var out RankedScore
+	q := s.Queries.WithTx(tx)
+	rankParams := GetPlayerRankParams{LeaderboardID: row.LeaderboardID, PlayerName: row.PlayerName}
+	out.UpsertedScore, err = upsertBest(ctx, q, row, func(Score) error {
+		rank, err := q.GetPlayerRank(ctx, rankParams)
+		if err != nil {
+			return fmt.Errorf("get previous rank: %w", err)
+		}
+		out.PreviousRank = rank
+		return nil
+	})
+	if err != nil {
+		return RankedScore{}, err
+	}
+	out.Rank = out.PreviousRank
+	if out.Applied {
+		if out.Rank, err = q.GetPlayerRank(ctx, rankParams); err != nil {
+			return RankedScore{}, fmt.Errorf("get rank: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return RankedScore{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PlayerRenamer moves a player's data to another name
+type PlayerRenamer interface {
+	// RenamePlayer moves the scores of from on every board, its profile and its
+	// devices to to in a single transaction. When to already has a live score or
+	// a profile it fails with ErrPlayerExists unless merge is true: each board
+	// then keeps the better of the two scores, and to keeps its own profile.
+	// Change listeners receive a delete of each live score of from followed by
+	// an insert, or an update when merging improved the score of to. ErrNoRows
+	// when from has neither a score nor a profile.
+	RenamePlayer(ctx context.Context, from, to string, merge bool) (RenameResult, error)
+}
+
+// RenameResult reports what RenamePlayer moved
+type RenameResult struct {
+	Scores  []Score  // best of the new name on each board where the old one had a live score
+	Merged  []string // boards where the new name already had a live score
+	Profile *Player  // profile of the new name after the rename, nil without one
+}
+
+// ErrPlayerExists is returned by RenamePlayer when the new name is in use and merge is false
+var ErrPlayerExists = errors.New("player name already in use")
+
+var _ PlayerRenamer = (*Store)(nil)
+
+// scoreColumns are the scores columns in the order of Score
+const scoreColumns = `player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id,
+	rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform`
+
+// Rename queries. Moved scores are written again rather than renamed in place:
+// notify_score_change() only logs a change of score, and listeners must see the
+// old name leave and the new one enter.
+const (
+	lockPlayerScoresQuery   = `SELECT ` + scoreColumns + ` FROM scores WHERE player_name = $1 ORDER BY leaderboard_id FOR UPDATE`
+	lockProfileQuery        = exportProfileQuery + ` FOR UPDATE`
+	deleteScoreQuery        = `DELETE FROM scores WHERE leaderboard_id = $1 AND player_name = $2`
+	renameDeletedScoreQuery = `UPDATE scores SET player_name = $3 WHERE leaderboard_id = $1 AND player_name = $2`
+	insertRenamedScoreQuery = `
+		INSERT INTO scores (leaderboard_id, player_name, score, secondary_score, updated_at, achieved_at, client_achieved_at, metadata, country_code, platform)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + scoreColumns
+	mergeRenamedScoreQuery = `
+		UPDATE scores SET score = $3, secondary_score = $4, updated_at = $5, achieved_at = $6, client_achieved_at = $7, metadata = $8, country_code = $9, platform = $10
+		WHERE leaderboard_id = $1 AND player_name = $2
+		RETURNING ` + scoreColumns
+	renameProfileQuery = `
+		UPDATE players SET player_name = $2, updated_at = now() WHERE player_name = $1
+		RETURNING player_name, display_name, country_code, avatar_url, created_at, updated_at`
+	deleteProfileQuery = `DELETE FROM players WHERE player_name = $1`
+	renameDevicesQuery = `
+		INSERT INTO device_players (device_hash, player_name, first_seen_at)
+		SELECT device_hash, $2, first_seen_at FROM device_players WHERE player_name = $1
+		ON CONFLICT (device_hash, player_name)
+		DO UPDATE SET first_seen_at = LEAST(device_players.first_seen_at, excluded.first_seen_at)`
+	deleteDevicesQuery     = `DELETE FROM device_players WHERE player_name = $1`
+	renameSubmissionsQuery = `UPDATE device_submissions SET player_name = $2 WHERE player_name = $1`
+)
+
+// RenamePlayer locks the rows of both names before deciding anything, so a
+// concurrent submission of either player waits for the rename
+func (s *Store) RenamePlayer(ctx context.Context, from, to string, merge bool) (RenameResult, error) {
+	tx, err := s.begin(ctx, pgx.TxOptions{})
+	if err != nil {
+		return RenameResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	fromScores, err := collectRows[Score](ctx, tx, lockPlayerScoresQuery, from)
+	if err != nil {
+		return RenameResult{}, fmt.Errorf("lock scores: %w", err)
+	}
+	toScores, err := collectRows[Score](ctx, tx, lockPlayerScoresQuery, to)
+	if err != nil {
+		return RenameResult{}, fmt.Errorf("lock scores: %w", err)
+	}
+	fromProfiles, err := collectRows[Player](ctx, tx, lockProfileQuery, from)
+	if err != nil {
+		return RenameResult{}, fmt.Errorf("lock profile: %w", err)
+	}
+	toProfiles, err := collectRows[Player](ctx, tx, lockProfileQuery, to)
+	if err != nil {
+		return RenameResult{}, fmt.Errorf("lock profile: %w", err)
+	}
+	if len(fromScores) == 0 && len(fromProfiles) == 0 {
+		return RenameResult{}, ErrNoRows
+	}
+	held := make(map[string]Score, len(toScores))
+	for _, sc := range toScores {
+		held[sc.LeaderboardID] = sc
+	}
+	if !merge && (len(toProfiles) > 0 || hasLiveScore(toScores)) {
+		return RenameResult{}, ErrPlayerExists
+	}
+
+	var res RenameResult
+	for _, sc := range fromScores {
+		prev, ok := held[sc.LeaderboardID]
+		if sc.DeletedAt.Valid {
+			// A deleted score follows the player, unless the new name has a score
+			// of its own there: restoring it would then overwrite that one
+			if ok {
+				_, err = tx.Exec(ctx, deleteScoreQuery, sc.LeaderboardID, from)
+			} else {
+				_, err = tx.Exec(ctx, renameDeletedScoreQuery, sc.LeaderboardID, from, to)
+			}
+			if err != nil {
+				return RenameResult{}, fmt.Errorf("move deleted score: %w", err)
+			}
+			continue
+		}
+		if ok && prev.DeletedAt.Valid {
+			if _, err := tx.Exec(ctx, deleteScoreQuery, sc.LeaderboardID, to); err != nil {
+				return RenameResult{}, fmt.Errorf("drop deleted score: %w", err)
+			}
+			ok = false
+		}
+		if _, err := tx.Exec(ctx, deleteScoreQuery, sc.LeaderboardID, from); err != nil {
+			return RenameResult{}, fmt.Errorf("delete score: %w", err)
+		}
+		if ok {
+			res.Merged = append(res.Merged, sc.LeaderboardID)
+			if !Outranks(sc, prev) {
+				res.Scores = append(res.Scores, prev)
+				continue
+			}
+		}
+		query := insertRenamedScoreQuery
+		if ok {
+			query = mergeRenamedScoreQuery
+		}
+		rows, err := tx.Query(ctx, query, sc.LeaderboardID, to, sc.Score, sc.SecondaryScore,
+			sc.UpdatedAt, sc.AchievedAt, sc.ClientAchievedAt, sc.Metadata, sc.CountryCode, sc.Platform)
+		if err != nil {
+			return RenameResult{}, fmt.Errorf("write score: %w", err)
+		}
+		moved, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[Score])
+		if err != nil {
+			return RenameResult{}, fmt.Errorf("write score: %w", err)
+		}
+		res.Scores = append(res.Scores, moved)
+	}
+
+	switch {
+	case len(toProfiles) > 0:
+		res.Profile = &toProfiles[0]
+		if _, err := tx.Exec(ctx, deleteProfileQuery, from); err != nil {
+			return RenameResult{}, fmt.Errorf("delete profile: %w", err)
+		}
+	case len(fromProfiles) > 0:
+		profiles, err := collectRows[Player](ctx, tx, renameProfileQuery, from, to)
+		if err != nil {
+			return RenameResult{}, fmt.Errorf("move profile: %w", err)
+		}
+		res.Profile = &profiles[0]
+	}
+	for _, q := range []struct{ what, query string }{
+		{"move devices", renameDevicesQuery},
+		{"move device submissions", renameSubmissionsQuery},
+	} {
+		if _, err := tx.Exec(ctx, q.query, from, to); err != nil {
+			return RenameResult{}, fmt.Errorf("%s: %w", q.what, err)
+		}
+	}
+	if _, err := tx.Exec(ctx, deleteDevicesQuery, from); err != nil {
+		return RenameResult{}, fmt.Errorf("move devices: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return RenameResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
+
+// hasLiveScore reports whether any of scores is not soft-deleted
+func hasLiveScore(scores []Score) bool {
+	for _, sc := range scores {
+		if !sc.DeletedAt.Valid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+// replicaRetry is how long reads stay on the primary once the replica could not
+// be reached
+const replicaRetry = 10 * time.Second
+
+// SetReadReplica sends the reads behind top scores and ranks (GetTopScores,
+// GetTopScoresAfter, GetPlayerScore, GetPlayerRank and GetPlayerRanks) to a
+// read-only replica; writes, transactions and every other read stay on the
+// primary. When the replica cannot be reached, reads fall back to the primary for
+// replicaRetry. The store closes the replica pool. Call it before serving.
+func (s *Store) SetReadReplica(pool *pgxpool.Pool, logger *zerolog.Logger) {
+	db := &guardedDB{db: pool, timeout: s.db.timeout}
+	s.replicaPool = pool
+	s.replica = &replica{db: db, queries: New(db), logger: logger}
+}
+
+// ReadPrimary returns a context whose reads go to the primary even with a read
+// replica, for reads that must see every committed write, like a cache then kept
+// current from score changes
+func ReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPrimaryKey{}, true)
+}
+
+type readPrimaryKey struct{}
+
+// replica is a read replica, skipped for replicaRetry after a read could not reach it
+type replica struct {
+	db      *guardedDB // under the query timeout, not the circuit breaker
+	queries *Queries
+	logger  *zerolog.Logger
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+// use returns the replica's queries when a read made with ctx may go to it, nil
+// when it must go to the primary. Its methods accept a nil replica.
+func (r *replica) use(ctx context.Context) *Queries {
+	if r == nil || ctx.Value(readPrimaryKey{}) != nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Now().Before(r.downUntil) {
+		metrics.StoreReplicaReads.WithLabelValues("primary").Inc()
+		return nil
+	}
+	return r.queries
+}
+
+// failed reports whether the read that returned err could not reach the replica
+// and must be retried on the primary, which it then uses for replicaRetry
+func (r *replica) failed(err error) bool {
+	if !unreachable(err) {
+		metrics.StoreReplicaReads.WithLabelValues("replica").Inc()
+		return false
+	}
+	metrics.StoreReplicaReads.WithLabelValues("primary").Inc()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Now().Before(r.downUntil) {
+		return true
+	}
+	r.downUntil = time.Now().Add(replicaRetry)
+	r.logger.Warn().Err(err).Dur("retry", replicaRetry).Msg("read replica unreachable, reading from the primary")
+	return true
+}
+
+// readReplica runs query on the replica when there is one, on the primary when
+// there is none or it cannot be reached
+func readReplica[T any](ctx context.Context, s *Store, query func(*Queries) (T, error)) (T, error) {
+	if q := s.replica.use(ctx); q != nil {
+		v, err := query(q)
+		if !s.replica.failed(err) {
+			return v, err
+		}
+	}
+	return query(s.Queries)
+}
+
+func (s *Store) GetTopScores(ctx context.Context, arg GetTopScoresParams) ([]Score, error) {
+	return readReplica(ctx, s, func(q *Queries) ([]Score, error) { return q.GetTopScores(ctx, arg) })
+}
+
+func (s *Store) GetTopScoresAfter(ctx context.Context, arg GetTopScoresAfterParams) ([]Score, error) {
+	return readReplica(ctx, s, func(q *Queries) ([]Score, error) { return q.GetTopScoresAfter(ctx, arg) })
+}
+
+func (s *Store) GetPlayerScore(ctx context.Context, arg GetPlayerScoreParams) (Score, error) {
+	return readReplica(ctx, s, func(q *Queries) (Score, error) { return q.GetPlayerScore(ctx, arg) })
+}
+
+func (s *Store) GetPlayerRank(ctx context.Context, arg GetPlayerRankParams) (int32, error) {
+	return readReplica(ctx, s, func(q *Queries) (int32, error) { return q.GetPlayerRank(ctx, arg) })
+}
+
+func (s *Store) GetPlayerRanks(ctx context.Context, arg GetPlayerRanksParams) ([]GetPlayerRanksRow, error) {
+	return readReplica(ctx, s, func(q *Queries) ([]GetPlayerRanksRow, error) { return q.GetPlayerRanks(ctx, arg) })
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+func TestReadReplica(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	primary, replicaDB := &failingDB{}, &failingDB{}
+	s := &Store{db: &guardedDB{db: primary}, Queries: New(primary)}
+	arg := GetPlayerRankParams{LeaderboardID: "global", PlayerName: "Alice"}
+
+	// Without a replica, reads go to the primary
+	if _, err := s.GetPlayerRank(ctx, arg); err != nil || primary.queries != 1 {
+		t.Fatalf("no replica: got %v after %d primary queries", err, primary.queries)
+	}
+
+	s.replica = &replica{queries: New(replicaDB), logger: &logger}
+	if _, err := s.GetPlayerRank(ctx, arg); err != nil || replicaDB.queries != 1 || primary.queries != 1 {
+		t.Fatalf("replica: got %v after %d replica and %d primary queries", err, replicaDB.queries, primary.queries)
+	}
+	if _, err := s.GetPlayerRank(ReadPrimary(ctx), arg); err != nil || primary.queries != 2 {
+		t.Fatalf("ReadPrimary: got %v after %d primary queries", err, primary.queries)
+	}
+
+	// The replica's answers are returned as they are, errors included
+	replicaDB.err = ErrNoRows
+	if _, err := s.GetPlayerScore(ctx, GetPlayerScoreParams{LeaderboardID: "global", PlayerName: "Bob"}); !errors.Is(err, ErrNoRows) || primary.queries != 2 {
+		t.Fatalf("unknown player: got %v after %d primary queries, want ErrNoRows from the replica", err, primary.queries)
+	}
+
+	// An unreachable replica is skipped until replicaRetry has passed
+	replicaDB.err = &pgconn.ConnectError{Config: &pgconn.Config{}}
+	for range 3 {
+		if _, err := s.GetPlayerRank(ctx, arg); err != nil {
+			t.Fatalf("replica down: got %v, want the primary's answer", err)
+		}
+	}
+	if replicaDB.queries != 3 || primary.queries != 5 {
+		t.Errorf("replica down: %d replica and %d primary queries, want 3 and 5", replicaDB.queries, primary.queries)
+	}
+	s.replica.downUntil = time.Now()
+	replicaDB.err = nil
+	if _, err := s.GetPlayerRank(ctx, arg); err != nil || replicaDB.queries != 4 {
+		t.Errorf("replica back: got %v after %d replica queries, want it read again", err, replicaDB.queries)
+	}
+}
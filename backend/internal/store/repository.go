@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Repository is the storage backend used by the service layer.
+// Store (PostgreSQL via sqlc) is the primary implementation; the sqlite
+// subpackage provides a dependency-free backend for demos and tests.
+type Repository interface {
+	Querier
+	Maintainer
+	ScoreBatcher
+	RankedUpserter
+	Resetter
+	Restorer
+	PlayerDataManager
+	PlayerRenamer
+	BanManager
+	Archiver
+	DeviceLimiter
+
+	// Ping verifies the database connection is alive
+	Ping(ctx context.Context) error
+
+	// Close releases the underlying connections
+	Close()
+}
+
+var _ Repository = (*Store)(nil)
+
+// DefaultLeaderboardID is the board of scores submitted without a leaderboard id,
+// and the board every score belonged to before multiple boards existed
+const DefaultLeaderboardID = "global"
+
+// ErrNoRows is returned by single-row queries that match nothing, whatever the backend
+var ErrNoRows = pgx.ErrNoRows
+
+// ErrNotSupported is returned by backends that cannot implement a query
+var ErrNotSupported = errors.New("not supported by this storage backend")
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+)
+
+// RequestIDSetting is the session setting holding the request ID of the
+// connection's current caller, e.g. current_setting('leaderboard.request_id', true)
+const RequestIDSetting = "leaderboard.request_id"
+
+// defaultApplicationName is the application_name of connections when the
+// database URL does not set one
+const defaultApplicationName = "leaderboard"
+
+// maxApplicationName is the length PostgreSQL truncates application_name to (NAMEDATALEN - 1)
+const maxApplicationName = 63
+
+const setRequestTagQuery = `SELECT set_config('application_name', $1, false), set_config('` + RequestIDSetting + `', $2, false)`
+
+// PoolOption customizes the pool created by NewPool
+type PoolOption func(*pgxpool.Config)
+
+// WithRequestTagging tags every pooled connection with the request ID of the
+// context that acquires it, so pg_stat_activity, slow query logs (log_line_prefix
+// %a) and triggers can be correlated with the request logs. application_name
+// becomes "<name> <request id>" and the full ID is stored in RequestIDSetting;
+// acquisitions outside a request reset both to the base name and an empty ID.
+//
+// Tagging costs a round trip per acquisition whose tag differs from the
+// connection's current one, which is almost every request.
+func WithRequestTagging() PoolOption {
+	return func(config *pgxpool.Config) {
+		base := config.ConnConfig.RuntimeParams["application_name"]
+		if base == "" {
+			base = defaultApplicationName
+			config.ConnConfig.RuntimeParams["application_name"] = base
+		}
+		t := &requestTagger{base: base, tags: make(map[*pgx.Conn]string)}
+		config.PrepareConn = t.prepare
+		config.BeforeClose = t.forget
+	}
+}
+
+// requestTagger tracks the request ID each connection is tagged with, to skip
+// the round trip when it does not change
+type requestTagger struct {
+	base string
+
+	mu   sync.Mutex
+	tags map[*pgx.Conn]string
+}
+
+func (t *requestTagger) prepare(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	// Client-supplied IDs may hold any byte; PostgreSQL rejects invalid UTF-8 and NULs
+	id := strings.ReplaceAll(strings.ToValidUTF8(requestctx.FromContext(ctx).RequestID, ""), "\x00", "")
+
+	t.mu.Lock()
+	current := t.tags[conn] // "" for an untagged connection, which has the base name
+	t.mu.Unlock()
+	if current == id {
+		return true, nil
+	}
+
+	if _, err := conn.Exec(ctx, setRequestTagQuery, applicationName(t.base, id), id); err != nil {
+		// The connection state is unknown after a failed round trip: drop it
+		return false, fmt.Errorf("tag connection with request id: %w", err)
+	}
+	t.mu.Lock()
+	t.tags[conn] = id
+	t.mu.Unlock()
+	return true, nil
+}
+
+func (t *requestTagger) forget(conn *pgx.Conn) {
+	t.mu.Lock()
+	delete(t.tags, conn)
+	t.mu.Unlock()
+}
+
+// applicationName returns the application_name of a connection serving a
+// request: printable ASCII only, as PostgreSQL would show it, and truncated
+func applicationName(base, requestID string) string {
+	name := base
+	if requestID != "" {
+		name += " " + requestID
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '?'
+		}
+		return r
+	}, name)
+	if len(name) > maxApplicationName {
+		name = name[:maxApplicationName]
+	}
+	return name
+}
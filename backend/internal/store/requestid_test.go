@@ -0,0 +1,26 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplicationName(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		want      string
+	}{
+		{"no request", "", "leaderboard"},
+		{"request", "4f2c9e0a1b3d5f7e", "leaderboard 4f2c9e0a1b3d5f7e"},
+		{"non-ascii", "req-é\n1", "leaderboard req-??1"},
+		{"truncated", strings.Repeat("a", 100), "leaderboard " + strings.Repeat("a", maxApplicationName-len("leaderboard "))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applicationName("leaderboard", tt.requestID); got != tt.want {
+				t.Errorf("applicationName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
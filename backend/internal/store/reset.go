@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Resetter clears whole boards for admin resets
+type Resetter interface {
+	// ResetLeaderboard deletes every score of a board in a single transaction,
+	// after copying them into a new snapshot when snapshot is true. Change
+	// listeners receive one resync of the board instead of a delete per row.
+	ResetLeaderboard(ctx context.Context, leaderboardID string, snapshot bool) (ResetResult, error)
+}
+
+// ResetResult reports what a reset removed
+type ResetResult struct {
+	Deleted    int64
+	SnapshotID int64 // 0 when no snapshot was taken
+}
+
+var _ Resetter = (*Store)(nil)
+
+// suppressNotifyQuery silences notify_score_change() until the end of the transaction
+const suppressNotifyQuery = `SELECT set_config('leaderboard.suppress_notify', 'on', true)`
+
+// notifyResyncQuery appends a board resync to the score_changes outbox and notifies it
+const notifyResyncQuery = `SELECT notify_leaderboard_resync($1)`
+
+func (s *Store) ResetLeaderboard(ctx context.Context, leaderboardID string, snapshot bool) (ResetResult, error) {
+	tx, err := s.begin(ctx, pgx.TxOptions{})
+	if err != nil {
+		return ResetResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	var res ResetResult
+	q := s.Queries.WithTx(tx)
+	if snapshot {
+		if res.SnapshotID, err = q.CreateLeaderboardSnapshot(ctx, leaderboardID); err != nil {
+			return ResetResult{}, fmt.Errorf("create snapshot: %w", err)
+		}
+		if _, err := q.SnapshotScores(ctx, SnapshotScoresParams{SnapshotID: res.SnapshotID, LeaderboardID: leaderboardID}); err != nil {
+			return ResetResult{}, fmt.Errorf("snapshot scores: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, suppressNotifyQuery); err != nil {
+		return ResetResult{}, fmt.Errorf("suppress notifications: %w", err)
+	}
+	if res.Deleted, err = q.DeleteLeaderboardScores(ctx, leaderboardID); err != nil {
+		return ResetResult{}, fmt.Errorf("delete scores: %w", err)
+	}
+	if _, err := tx.Exec(ctx, notifyResyncQuery, leaderboardID); err != nil {
+		return ResetResult{}, fmt.Errorf("notify resync: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ResetResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Restorer replaces the scores of whole boards for admin restores
+type Restorer interface {
+	// RestoreLeaderboard replaces every score of a board with entries in a single
+	// transaction, after copying the current scores into a new snapshot when
+	// snapshot is true. Rank scores follow the board's current sort orders. Change
+	// listeners receive one resync of the board instead of a change per row.
+	RestoreLeaderboard(ctx context.Context, leaderboardID string, entries []RestoreEntry, snapshot bool) (RestoreResult, error)
+}
+
+// RestoreEntry is a score written by a restore as is, without best-score logic
+type RestoreEntry struct {
+	PlayerName     string
+	Score          int64
+	SecondaryScore int64
+	CountryCode    string // '' when unknown
+	Platform       string // '' when unknown
+	AchievedAt     time.Time
+	UpdatedAt      time.Time
+}
+
+// RestoreResult reports what a restore replaced
+type RestoreResult struct {
+	Deleted    int64 // scores of the board before the restore
+	Restored   int64
+	SnapshotID int64 // snapshot of the replaced scores, 0 when none was taken
+}
+
+var _ Restorer = (*Store)(nil)
+
+// RankScore returns the rank_score of a score on a board of the given sort order,
+// or the rank_secondary of a secondary score given the secondary sort order
+func RankScore(sortOrder string, score int64) int64 {
+	if sortOrder == "asc" {
+		return -score
+	}
+	return score
+}
+
+func (s *Store) RestoreLeaderboard(ctx context.Context, leaderboardID string, entries []RestoreEntry, snapshot bool) (RestoreResult, error) {
+	tx, err := s.begin(ctx, pgx.TxOptions{})
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	var res RestoreResult
+	q := s.Queries.WithTx(tx)
+	if snapshot {
+		if res.SnapshotID, err = q.CreateLeaderboardSnapshot(ctx, leaderboardID); err != nil {
+			return RestoreResult{}, fmt.Errorf("create snapshot: %w", err)
+		}
+		if _, err := q.SnapshotScores(ctx, SnapshotScoresParams{SnapshotID: res.SnapshotID, LeaderboardID: leaderboardID}); err != nil {
+			return RestoreResult{}, fmt.Errorf("snapshot scores: %w", err)
+		}
+	}
+
+	sortOrder, secondaryOrder := "desc", "desc"
+	if lb, err := q.GetLeaderboard(ctx, leaderboardID); err == nil {
+		sortOrder, secondaryOrder = lb.SortOrder, lb.SecondarySortOrder
+	} else if !errors.Is(err, ErrNoRows) {
+		return RestoreResult{}, fmt.Errorf("get leaderboard: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, suppressNotifyQuery); err != nil {
+		return RestoreResult{}, fmt.Errorf("suppress notifications: %w", err)
+	}
+	if res.Deleted, err = q.DeleteLeaderboardScores(ctx, leaderboardID); err != nil {
+		return RestoreResult{}, fmt.Errorf("delete scores: %w", err)
+	}
+
+	// COPY keeps large restores to a single round trip
+	res.Restored, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"scores"},
+		[]string{"leaderboard_id", "player_name", "score", "rank_score", "secondary_score", "rank_secondary", "country_code", "platform", "achieved_at", "updated_at"},
+		pgx.CopyFromSlice(len(entries), func(i int) ([]any, error) {
+			e := entries[i]
+			return []any{leaderboardID, e.PlayerName, e.Score, RankScore(sortOrder, e.Score),
+				e.SecondaryScore, RankScore(secondaryOrder, e.SecondaryScore), e.CountryCode, e.Platform, e.AchievedAt, e.UpdatedAt}, nil
+		}))
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("copy scores: %w", err)
+	}
+	if _, err := tx.Exec(ctx, notifyResyncQuery, leaderboardID); err != nil {
+		return RestoreResult{}, fmt.Errorf("notify resync: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return RestoreResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// archiveMatch and archiveReason match scores against the policy: ?1 is
+// UpdatedBefore in micros (0 when disabled, which no score is older than) and ?2
+// the ScoreFloor, compared in ranking space like PostgreSQL
+const (
+	archiveBelowFloor = `(?2 <> 0 AND rank_score < CASE WHEN COALESCE((
+				SELECT sort_order FROM leaderboards l WHERE l.leaderboard_id = scores.leaderboard_id), 'desc') = 'asc'
+				THEN -?2 ELSE ?2 END)`
+	archiveMatch = `
+		FROM scores
+		WHERE updated_at < ?1 OR ` + archiveBelowFloor
+	archiveReason = `CASE WHEN updated_at < ?1 THEN 'retention' ELSE 'score_floor' END`
+)
+
+// ListArchiveCandidates mirrors the PostgreSQL keyset on (updated_at, leaderboard_id, player_name)
+func (s *Store) ListArchiveCandidates(ctx context.Context, arg store.ListArchiveCandidatesParams) ([]store.ArchiveCandidate, error) {
+	var updatedBefore int64
+	if !arg.Policy.UpdatedBefore.IsZero() {
+		updatedBefore = toMicros(arg.Policy.UpdatedBefore)
+	}
+	var (
+		after                   sql.NullInt64
+		afterBoard, afterPlayer string
+	)
+	if arg.After != nil {
+		after = sql.NullInt64{Int64: toMicros(arg.After.UpdatedAt), Valid: true}
+		afterBoard, afterPlayer = arg.After.LeaderboardID, arg.After.PlayerName
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT * FROM (
+			SELECT leaderboard_id, player_name, score, updated_at, `+archiveBelowFloor+` AS below_floor`+archiveMatch+`
+		) c
+		WHERE ?3 IS NULL OR (c.updated_at, c.leaderboard_id, c.player_name) > (?3, ?4, ?5)
+		ORDER BY c.updated_at, c.leaderboard_id, c.player_name
+		LIMIT ?6`,
+		updatedBefore, arg.Policy.ScoreFloor, after, afterBoard, afterPlayer, arg.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list archive candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []store.ArchiveCandidate
+	for rows.Next() {
+		var c store.ArchiveCandidate
+		var updatedAt int64
+		if err := rows.Scan(&c.LeaderboardID, &c.PlayerName, &c.Score, &updatedAt, &c.BelowFloor); err != nil {
+			return nil, fmt.Errorf("list archive candidates: %w", err)
+		}
+		c.UpdatedAt = fromMicros(updatedAt).Time
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list archive candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+// ArchiveScores mirrors the PostgreSQL archival: the per-row deletes logged by
+// the triggers are replaced by a single 'resync' change of each board
+func (s *Store) ArchiveScores(ctx context.Context, policy store.ArchivePolicy) (store.ArchiveResult, error) {
+	var updatedBefore int64
+	if !policy.UpdatedBefore.IsZero() {
+		updatedBefore = toMicros(policy.UpdatedBefore)
+	}
+
+	if policy.DryRun {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT leaderboard_id, `+archiveReason+`, COUNT(*)`+archiveMatch+`
+			GROUP BY 1, 2`,
+			updatedBefore, policy.ScoreFloor)
+		if err != nil {
+			return store.ArchiveResult{}, fmt.Errorf("count scores: %w", err)
+		}
+		return scanArchived(rows)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return store.ArchiveResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() // no-op after Commit
+
+	var lastChange, lastArchived int64
+	if err := tx.QueryRowContext(ctx, `
+		SELECT (SELECT COALESCE(MAX(id), 0) FROM score_changes), (SELECT COALESCE(MAX(id), 0) FROM scores_archive)`).
+		Scan(&lastChange, &lastArchived); err != nil {
+		return store.ArchiveResult{}, fmt.Errorf("read change log: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO scores_archive (`+scoreColumns+`, reason, archived_at)
+		SELECT `+scoreColumns+`, `+archiveReason+`, ?4`+archiveMatch+`
+		LIMIT ?3`,
+		updatedBefore, policy.ScoreFloor, policy.Limit, toMicros(time.Now())); err != nil {
+		return store.ArchiveResult{}, fmt.Errorf("archive scores: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM scores
+		WHERE (leaderboard_id, player_name) IN (SELECT leaderboard_id, player_name FROM scores_archive WHERE id > ?1)`,
+		lastArchived); err != nil {
+		return store.ArchiveResult{}, fmt.Errorf("delete scores: %w", err)
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT leaderboard_id, reason, COUNT(*) FROM scores_archive WHERE id > ?1
+		GROUP BY 1, 2`,
+		lastArchived)
+	if err != nil {
+		return store.ArchiveResult{}, fmt.Errorf("count archived scores: %w", err)
+	}
+	res, err := scanArchived(rows)
+	if err != nil {
+		return store.ArchiveResult{}, fmt.Errorf("count archived scores: %w", err)
+	}
+
+	now := toMicros(time.Now())
+	for _, b := range res.Boards {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM score_changes WHERE id > ?1 AND leaderboard_id = ?2 AND op = 'delete'`,
+			lastChange, b.LeaderboardID); err != nil {
+			return store.ArchiveResult{}, fmt.Errorf("drop delete changes: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, created_at, op)
+			VALUES (?1, '', 0, 0, ?2, ?2, 'resync')`,
+			b.LeaderboardID, now); err != nil {
+			return store.ArchiveResult{}, fmt.Errorf("log resync: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.ArchiveResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
+
+// scanArchived reads (leaderboard_id, reason, count) rows into a result
+func scanArchived(rows *sql.Rows) (store.ArchiveResult, error) {
+	defer rows.Close()
+
+	var res store.ArchiveResult
+	for rows.Next() {
+		var board, reason string
+		var n int64
+		if err := rows.Scan(&board, &reason, &n); err != nil {
+			return store.ArchiveResult{}, err
+		}
+		res.Add(board, reason, n)
+	}
+	return res, rows.Err()
+}
+
+func scanArchivedScore(row rowScanner) (store.ScoresArchive, error) {
+	var a store.ScoresArchive
+	var updatedAt, achievedAt, archivedAt int64
+	var clientAchievedAt, deletedAt sql.NullInt64
+	if err := row.Scan(&a.ID, &a.PlayerName, &a.Score, &updatedAt, &achievedAt, &clientAchievedAt, &a.LeaderboardID, &a.RankScore, &deletedAt, &a.Metadata, &a.SecondaryScore, &a.RankSecondary, &a.CountryCode, &a.Platform, &a.Reason, &archivedAt); err != nil {
+		return a, err
+	}
+	a.UpdatedAt, a.AchievedAt, a.ArchivedAt = fromMicros(updatedAt), fromMicros(achievedAt), fromMicros(archivedAt)
+	if clientAchievedAt.Valid {
+		a.ClientAchievedAt = fromMicros(clientAchievedAt.Int64)
+	}
+	if deletedAt.Valid {
+		a.DeletedAt = fromMicros(deletedAt.Int64)
+	}
+	return a, nil
+}
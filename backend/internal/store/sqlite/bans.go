@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+const banColumns = `player_name, reason, banned_at`
+
+// BanPlayer mirrors the PostgreSQL ban; the purge is a soft delete the triggers
+// log like DeleteScore
+func (s *Store) BanPlayer(ctx context.Context, playerName, reason string, purge bool) (store.BanResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return store.BanResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() // no-op after Commit
+
+	now := toMicros(time.Now())
+	var res store.BanResult
+	if res.Ban, err = scanBan(tx.QueryRowContext(ctx, `
+		INSERT INTO banned_players (player_name, reason, banned_at) VALUES (?1, ?2, ?3)
+		ON CONFLICT (player_name) DO UPDATE SET reason = excluded.reason
+		RETURNING `+banColumns,
+		playerName, reason, now)); err != nil {
+		return store.BanResult{}, fmt.Errorf("ban player: %w", err)
+	}
+	if purge {
+		rows, err := tx.QueryContext(ctx, `
+			UPDATE scores SET deleted_at = ?2
+			WHERE player_name = ?1 AND deleted_at IS NULL
+			RETURNING leaderboard_id`,
+			playerName, now)
+		if err != nil {
+			return store.BanResult{}, fmt.Errorf("purge scores: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var board string
+			if err := rows.Scan(&board); err != nil {
+				return store.BanResult{}, fmt.Errorf("purge scores: %w", err)
+			}
+			res.Purged = append(res.Purged, board)
+		}
+		if err := rows.Err(); err != nil {
+			return store.BanResult{}, fmt.Errorf("purge scores: %w", err)
+		}
+		rows.Close()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.BanResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
+
+func (s *Store) UnbanPlayer(ctx context.Context, playerName string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM banned_players WHERE player_name = ?1`, playerName)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNoRows
+	}
+	return nil
+}
+
+func (s *Store) GetBannedPlayer(ctx context.Context, playerName string) (store.BannedPlayer, error) {
+	return scanBan(s.db.QueryRowContext(ctx, `SELECT `+banColumns+` FROM banned_players WHERE player_name = ?1`, playerName))
+}
+
+func (s *Store) ListBannedPlayers(ctx context.Context) ([]store.BannedPlayer, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+banColumns+` FROM banned_players ORDER BY banned_at DESC, player_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bans := []store.BannedPlayer{}
+	for rows.Next() {
+		b, err := scanBan(rows)
+		if err != nil {
+			return nil, err
+		}
+		bans = append(bans, b)
+	}
+	return bans, rows.Err()
+}
+
+func scanBan(row rowScanner) (store.BannedPlayer, error) {
+	var b store.BannedPlayer
+	var bannedAt int64
+	if err := row.Scan(&b.PlayerName, &b.Reason, &bannedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return b, store.ErrNoRows
+		}
+		return b, err
+	}
+	b.BannedAt = fromMicros(bannedAt)
+	return b, nil
+}
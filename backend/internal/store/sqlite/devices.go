@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// CheckDeviceSubmission mirrors the PostgreSQL check. The transaction holds the
+// only connection, which serializes concurrent checks without a lock.
+func (s *Store) CheckDeviceSubmission(ctx context.Context, arg store.DeviceSubmissionCheck) (store.DeviceUsage, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return store.DeviceUsage{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() // no-op after Commit
+
+	now := time.Now()
+	var usage store.DeviceUsage
+	if arg.MaxAccounts > 0 {
+		var known bool
+		if err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM device_players WHERE device_hash = ?1 AND player_name = ?2)`,
+			arg.DeviceHash, arg.PlayerName).Scan(&known); err != nil {
+			return store.DeviceUsage{}, fmt.Errorf("look up device player: %w", err)
+		}
+		if !known {
+			if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM device_players WHERE device_hash = ?1`,
+				arg.DeviceHash).Scan(&usage.Accounts); err != nil {
+				return store.DeviceUsage{}, fmt.Errorf("count device players: %w", err)
+			}
+			usage.AccountsExceeded = usage.Accounts >= int64(arg.MaxAccounts)
+		}
+	}
+	if arg.MaxSubmissions > 0 {
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM device_submissions WHERE device_hash = ?1 AND submitted_at >= ?2`,
+			arg.DeviceHash, toMicros(now.Add(-store.DeviceSubmissionWindow))).Scan(&usage.Submissions); err != nil {
+			return store.DeviceUsage{}, fmt.Errorf("count device submissions: %w", err)
+		}
+		usage.SubmissionsExceeded = usage.Submissions >= int64(arg.MaxSubmissions)
+	}
+	if arg.Enforce && usage.Exceeded() {
+		return usage, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO device_players (device_hash, player_name, first_seen_at)
+		VALUES (?1, ?2, ?3)
+		ON CONFLICT (device_hash, player_name) DO NOTHING`,
+		arg.DeviceHash, arg.PlayerName, toMicros(now)); err != nil {
+		return store.DeviceUsage{}, fmt.Errorf("record device player: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO device_submissions (device_hash, player_name, submitted_at)
+		VALUES (?1, ?2, ?3)`,
+		arg.DeviceHash, arg.PlayerName, toMicros(now)); err != nil {
+		return store.DeviceUsage{}, fmt.Errorf("record device submission: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return store.DeviceUsage{}, fmt.Errorf("commit: %w", err)
+	}
+	usage.Recorded = true
+	return usage, nil
+}
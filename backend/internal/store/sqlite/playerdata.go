@@ -0,0 +1,209 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// ExportPlayerData reads the player's rows in one transaction. Webhooks are not
+// supported, so the export has no deliveries.
+func (s *Store) ExportPlayerData(ctx context.Context, playerName string) (store.PlayerData, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return store.PlayerData{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() // read only, nothing to commit
+
+	var data store.PlayerData
+	if data.Scores, err = queryRows(ctx, tx, `
+		SELECT `+scoreColumns+` FROM scores WHERE player_name = ?1 ORDER BY leaderboard_id`,
+		playerName, scanScore); err != nil {
+		return store.PlayerData{}, fmt.Errorf("export scores: %w", err)
+	}
+	if data.ArchivedScores, err = queryRows(ctx, tx, `
+		SELECT id, `+scoreColumns+`, reason, archived_at FROM scores_archive WHERE player_name = ?1 ORDER BY id`,
+		playerName, scanArchivedScore); err != nil {
+		return store.PlayerData{}, fmt.Errorf("export archived scores: %w", err)
+	}
+	profiles, err := queryRows(ctx, tx, `SELECT `+playerColumns+` FROM players WHERE player_name = ?1`, playerName, scanPlayer)
+	if err != nil {
+		return store.PlayerData{}, fmt.Errorf("export profile: %w", err)
+	}
+	if len(profiles) > 0 {
+		data.Profile = &profiles[0]
+	}
+	if data.Devices, err = queryRows(ctx, tx, `
+		SELECT device_hash, player_name, first_seen_at FROM device_players WHERE player_name = ?1 ORDER BY first_seen_at`,
+		playerName, func(row rowScanner) (store.DevicePlayer, error) {
+			var d store.DevicePlayer
+			var firstSeenAt int64
+			err := row.Scan(&d.DeviceHash, &d.PlayerName, &firstSeenAt)
+			d.FirstSeenAt = fromMicros(firstSeenAt)
+			return d, err
+		}); err != nil {
+		return store.PlayerData{}, fmt.Errorf("export devices: %w", err)
+	}
+	if data.DeviceSubmissions, err = queryRows(ctx, tx, `
+		SELECT id, device_hash, player_name, submitted_at FROM device_submissions WHERE player_name = ?1 ORDER BY id`,
+		playerName, func(row rowScanner) (store.DeviceSubmission, error) {
+			var d store.DeviceSubmission
+			var submittedAt int64
+			err := row.Scan(&d.ID, &d.DeviceHash, &d.PlayerName, &submittedAt)
+			d.SubmittedAt = fromMicros(submittedAt)
+			return d, err
+		}); err != nil {
+		return store.PlayerData{}, fmt.Errorf("export device submissions: %w", err)
+	}
+	if data.SnapshotEntries, err = queryRows(ctx, tx, `
+		SELECT snapshot_id, player_name, score, rank_score, achieved_at, updated_at, secondary_score, rank_secondary, country_code, platform
+		FROM leaderboard_snapshot_entries WHERE player_name = ?1 ORDER BY snapshot_id`,
+		playerName, func(row rowScanner) (store.LeaderboardSnapshotEntry, error) {
+			var e store.LeaderboardSnapshotEntry
+			var achievedAt, updatedAt int64
+			err := row.Scan(&e.SnapshotID, &e.PlayerName, &e.Score, &e.RankScore, &achievedAt, &updatedAt, &e.SecondaryScore, &e.RankSecondary, &e.CountryCode, &e.Platform)
+			e.AchievedAt, e.UpdatedAt = fromMicros(achievedAt), fromMicros(updatedAt)
+			return e, err
+		}); err != nil {
+		return store.PlayerData{}, fmt.Errorf("export snapshot entries: %w", err)
+	}
+	if data.Changes, err = queryRows(ctx, tx, `
+		SELECT id, leaderboard_id, player_name, score, rank_score, achieved_at, op, created_at, metadata, secondary_score, rank_secondary, country_code, platform
+		FROM score_changes WHERE player_name = ?1 ORDER BY id`,
+		playerName, func(row rowScanner) (store.ScoreChange, error) {
+			var c store.ScoreChange
+			var achievedAt, createdAt int64
+			err := row.Scan(&c.ID, &c.LeaderboardID, &c.PlayerName, &c.Score, &c.RankScore, &achievedAt, &c.Op, &createdAt, &c.Metadata, &c.SecondaryScore, &c.RankSecondary, &c.CountryCode, &c.Platform)
+			c.AchievedAt, c.CreatedAt = fromMicros(achievedAt), fromMicros(createdAt)
+			return c, err
+		}); err != nil {
+		return store.PlayerData{}, fmt.Errorf("export score changes: %w", err)
+	}
+	if data.Erasures, err = queryRows(ctx, tx, `
+		SELECT id, player_hash, request_id, deleted_scores, deleted_rows, erased_at
+		FROM player_erasures WHERE player_hash = ?1 ORDER BY id`,
+		store.PlayerHash(playerName), scanErasure); err != nil {
+		return store.PlayerData{}, fmt.Errorf("export erasures: %w", err)
+	}
+	return data, nil
+}
+
+// ErasePlayerData mirrors the PostgreSQL erasure: the deletes logged by the
+// triggers go with the player's other changes, and each board where the player
+// had a live score gets a single 'resync' change
+func (s *Store) ErasePlayerData(ctx context.Context, playerName, requestID string) (store.ErasureResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return store.ErasureResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() // no-op after Commit
+
+	var res store.ErasureResult
+	if res.Boards, err = queryRows(ctx, tx, `
+		SELECT DISTINCT leaderboard_id FROM scores
+		WHERE player_name = ?1 AND deleted_at IS NULL
+		ORDER BY leaderboard_id`,
+		playerName, func(row rowScanner) (string, error) {
+			var board string
+			err := row.Scan(&board)
+			return board, err
+		}); err != nil {
+		return store.ErasureResult{}, fmt.Errorf("list boards: %w", err)
+	}
+
+	var lastChange int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM score_changes`).Scan(&lastChange); err != nil {
+		return store.ErasureResult{}, fmt.Errorf("read change log: %w", err)
+	}
+
+	var scores, total int64
+	for _, q := range []struct{ what, query string }{
+		{"scores", `DELETE FROM scores WHERE player_name = ?1`},
+		{"archived scores", `DELETE FROM scores_archive WHERE player_name = ?1`},
+		{"profile", `DELETE FROM players WHERE player_name = ?1`},
+		{"devices", `DELETE FROM device_players WHERE player_name = ?1`},
+		{"device submissions", `DELETE FROM device_submissions WHERE player_name = ?1`},
+		{"snapshot entries", `DELETE FROM leaderboard_snapshot_entries WHERE player_name = ?1`},
+	} {
+		r, err := tx.ExecContext(ctx, q.query, playerName)
+		if err != nil {
+			return store.ErasureResult{}, fmt.Errorf("delete %s: %w", q.what, err)
+		}
+		n, err := r.RowsAffected()
+		if err != nil {
+			return store.ErasureResult{}, fmt.Errorf("delete %s: %w", q.what, err)
+		}
+		if q.what == "scores" {
+			scores = n
+		}
+		total += n
+	}
+
+	// The deletes logged by the triggers above are dropped too, but not counted:
+	// they never existed outside this transaction
+	r, err := tx.ExecContext(ctx, `DELETE FROM score_changes WHERE player_name = ?1 AND id <= ?2`, playerName, lastChange)
+	if err != nil {
+		return store.ErasureResult{}, fmt.Errorf("delete score changes: %w", err)
+	}
+	changes, err := r.RowsAffected()
+	if err != nil {
+		return store.ErasureResult{}, fmt.Errorf("delete score changes: %w", err)
+	}
+	total += changes
+	if _, err := tx.ExecContext(ctx, `DELETE FROM score_changes WHERE id > ?1 AND player_name = ?2`, lastChange, playerName); err != nil {
+		return store.ErasureResult{}, fmt.Errorf("drop delete changes: %w", err)
+	}
+
+	now := toMicros(time.Now())
+	for _, board := range res.Boards {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, created_at, op)
+			VALUES (?1, '', 0, 0, ?2, ?2, 'resync')`,
+			board, now); err != nil {
+			return store.ErasureResult{}, fmt.Errorf("log resync: %w", err)
+		}
+	}
+
+	if res.Erasure, err = scanErasure(tx.QueryRowContext(ctx, `
+		INSERT INTO player_erasures (player_hash, request_id, deleted_scores, deleted_rows, erased_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)
+		RETURNING id, player_hash, request_id, deleted_scores, deleted_rows, erased_at`,
+		store.PlayerHash(playerName), requestID, scores, total, now)); err != nil {
+		return store.ErasureResult{}, fmt.Errorf("record erasure: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.ErasureResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
+
+func scanErasure(row rowScanner) (store.PlayerErasure, error) {
+	var e store.PlayerErasure
+	var erasedAt int64
+	err := row.Scan(&e.ID, &e.PlayerHash, &e.RequestID, &e.DeletedScores, &e.DeletedRows, &erasedAt)
+	e.ErasedAt = fromMicros(erasedAt)
+	return e, err
+}
+
+// queryRows runs a query with one argument and scans every row with scan
+func queryRows[T any](ctx context.Context, tx *sql.Tx, query string, arg any, scan func(rowScanner) (T, error)) ([]T, error) {
+	rows, err := tx.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []T{}
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
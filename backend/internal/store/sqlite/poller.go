@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/notify"
+)
+
+// Poller is the SQLite counterpart of notify.Listener: it drains the
+// trigger-maintained score_changes log at a fixed interval.
+type Poller struct {
+	store    *Store
+	interval time.Duration
+	logger   *zerolog.Logger
+
+	changeChan chan notify.ScoreChange
+	errChan    chan error
+	healthy    atomic.Bool // last poll succeeded
+
+	cancel context.CancelFunc // set by Start
+	done   chan struct{}      // closed once stopped, after changeChan and errChan
+}
+
+var _ notify.Source = (*Poller)(nil)
+
+// NewPoller creates a change poller for the given store
+func NewPoller(s *Store, interval time.Duration, logger *zerolog.Logger) *Poller {
+	return &Poller{
+		store:      s,
+		interval:   interval,
+		logger:     logger,
+		changeChan: make(chan notify.ScoreChange, 100),
+		errChan:    make(chan error, 10),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start begins polling for changes, until ctx is cancelled or Stop is called
+func (p *Poller) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	go p.poll(ctx)
+}
+
+// Stop stops a started poller and waits until its channels are closed, or until ctx is done
+func (p *Poller) Stop(ctx context.Context) error {
+	p.cancel()
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel closed once the poller has stopped and closed its channels
+func (p *Poller) Done() <-chan struct{} {
+	return p.done
+}
+
+// Changes returns a channel that receives score change notifications
+func (p *Poller) Changes() <-chan notify.ScoreChange {
+	return p.changeChan
+}
+
+// Errors returns a channel that receives poller errors
+func (p *Poller) Errors() <-chan error {
+	return p.errChan
+}
+
+// Listening reports whether the poller is running and its last poll succeeded
+func (p *Poller) Listening() bool {
+	return p.healthy.Load()
+}
+
+func (p *Poller) poll(ctx context.Context) {
+	defer close(p.done)
+	defer close(p.changeChan)
+	defer close(p.errChan)
+	defer p.healthy.Store(false)
+
+	// Changes logged before startup belong to a previous run: skip them
+	if _, err := p.store.db.ExecContext(ctx, `DELETE FROM score_changes`); err != nil && ctx.Err() == nil {
+		p.sendError(fmt.Errorf("clear change log: %w", err))
+	}
+
+	p.logger.Info().Dur("interval", p.interval).Msg("polling sqlite for score changes")
+	p.healthy.Store(true)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.logger.Info().Msg("poller shutting down")
+			return
+		case <-ticker.C:
+		}
+
+		changes, err := p.drain(ctx)
+		p.healthy.Store(err == nil)
+		if err != nil {
+			if ctx.Err() == nil {
+				p.sendError(fmt.Errorf("poll changes: %w", err))
+			}
+			continue
+		}
+
+		for _, change := range changes {
+			select {
+			case p.changeChan <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// drain reads and removes all pending changes in log order
+func (p *Poller) drain(ctx context.Context) ([]notify.ScoreChange, error) {
+	rows, err := p.store.db.QueryContext(ctx, `SELECT id, leaderboard_id, player_name, score, rank_score, achieved_at, metadata, op, secondary_score, rank_secondary, country_code, platform, created_at FROM score_changes ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []notify.ScoreChange
+	var lastID int64
+	for rows.Next() {
+		var c notify.ScoreChange
+		var achievedAt, createdAt int64
+		var metadata []byte
+		if err := rows.Scan(&lastID, &c.LeaderboardID, &c.PlayerName, &c.Score, &c.RankScore, &achievedAt, &metadata, &c.Op, &c.SecondaryScore, &c.RankSecondary, &c.CountryCode, &c.Platform, &createdAt); err != nil {
+			return nil, err
+		}
+		c.AchievedAt = time.UnixMicro(achievedAt).UTC()
+		if createdAt > 0 {
+			c.UpdatedAt = time.UnixMicro(createdAt).UTC()
+		}
+		if err := json.Unmarshal(metadata, &c.Metadata); err != nil {
+			return nil, fmt.Errorf("decode metadata of change %d: %w", lastID, err)
+		}
+		c.ID = lastID // AUTOINCREMENT: ids are never reused, even after the log is drained
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if len(changes) > 0 {
+		if _, err := p.store.db.ExecContext(ctx, `DELETE FROM score_changes WHERE id <= ?1`, lastID); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func (p *Poller) sendError(err error) {
+	select {
+	case p.errChan <- err:
+	default:
+		p.logger.Warn().Err(err).Msg("error channel full, dropping error")
+	}
+}
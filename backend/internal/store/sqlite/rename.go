@@ -0,0 +1,147 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// RenamePlayer mirrors the PostgreSQL rename: moved scores are deleted under the
+// old name and written again under the new one, so the triggers log the same
+// changes. The single connection serializes it with every other write.
+func (s *Store) RenamePlayer(ctx context.Context, from, to string, merge bool) (store.RenameResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return store.RenameResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() // no-op after Commit
+
+	playerScores := `SELECT ` + scoreColumns + ` FROM scores WHERE player_name = ?1 ORDER BY leaderboard_id`
+	fromScores, err := queryRows(ctx, tx, playerScores, from, scanScore)
+	if err != nil {
+		return store.RenameResult{}, fmt.Errorf("read scores: %w", err)
+	}
+	toScores, err := queryRows(ctx, tx, playerScores, to, scanScore)
+	if err != nil {
+		return store.RenameResult{}, fmt.Errorf("read scores: %w", err)
+	}
+	profile := `SELECT ` + playerColumns + ` FROM players WHERE player_name = ?1`
+	fromProfiles, err := queryRows(ctx, tx, profile, from, scanPlayer)
+	if err != nil {
+		return store.RenameResult{}, fmt.Errorf("read profile: %w", err)
+	}
+	toProfiles, err := queryRows(ctx, tx, profile, to, scanPlayer)
+	if err != nil {
+		return store.RenameResult{}, fmt.Errorf("read profile: %w", err)
+	}
+	if len(fromScores) == 0 && len(fromProfiles) == 0 {
+		return store.RenameResult{}, store.ErrNoRows
+	}
+	held := make(map[string]store.Score, len(toScores))
+	live := false
+	for _, sc := range toScores {
+		held[sc.LeaderboardID] = sc
+		live = live || !sc.DeletedAt.Valid
+	}
+	if !merge && (len(toProfiles) > 0 || live) {
+		return store.RenameResult{}, store.ErrPlayerExists
+	}
+
+	deleteScore := `DELETE FROM scores WHERE leaderboard_id = ?1 AND player_name = ?2`
+	var res store.RenameResult
+	for _, sc := range fromScores {
+		prev, ok := held[sc.LeaderboardID]
+		if sc.DeletedAt.Valid {
+			// A deleted score follows the player, unless the new name has a score
+			// of its own there: restoring it would then overwrite that one
+			if ok {
+				_, err = tx.ExecContext(ctx, deleteScore, sc.LeaderboardID, from)
+			} else {
+				_, err = tx.ExecContext(ctx, `UPDATE scores SET player_name = ?3 WHERE leaderboard_id = ?1 AND player_name = ?2`, sc.LeaderboardID, from, to)
+			}
+			if err != nil {
+				return store.RenameResult{}, fmt.Errorf("move deleted score: %w", err)
+			}
+			continue
+		}
+		if ok && prev.DeletedAt.Valid {
+			if _, err := tx.ExecContext(ctx, deleteScore, sc.LeaderboardID, to); err != nil {
+				return store.RenameResult{}, fmt.Errorf("drop deleted score: %w", err)
+			}
+			ok = false
+		}
+		if _, err := tx.ExecContext(ctx, deleteScore, sc.LeaderboardID, from); err != nil {
+			return store.RenameResult{}, fmt.Errorf("delete score: %w", err)
+		}
+		if ok {
+			res.Merged = append(res.Merged, sc.LeaderboardID)
+			if !store.Outranks(sc, prev) {
+				res.Scores = append(res.Scores, prev)
+				continue
+			}
+		}
+
+		var clientAchievedAt *int64
+		if sc.ClientAchievedAt.Valid {
+			us := toMicros(sc.ClientAchievedAt.Time)
+			clientAchievedAt = &us
+		}
+		args := []any{sc.LeaderboardID, to, sc.Score, sc.RankScore, sc.SecondaryScore, sc.RankSecondary,
+			toMicros(sc.UpdatedAt.Time), toMicros(sc.AchievedAt.Time), clientAchievedAt, string(sc.Metadata), sc.CountryCode, sc.Platform}
+		query := `
+			INSERT INTO scores (leaderboard_id, player_name, score, rank_score, secondary_score, rank_secondary, updated_at, achieved_at, client_achieved_at, metadata, country_code, platform)
+			VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12)
+			RETURNING ` + scoreColumns
+		if ok {
+			query = `
+				UPDATE scores SET score = ?3, rank_score = ?4, secondary_score = ?5, rank_secondary = ?6,
+					updated_at = ?7, achieved_at = ?8, client_achieved_at = ?9, metadata = ?10, country_code = ?11, platform = ?12
+				WHERE leaderboard_id = ?1 AND player_name = ?2
+				RETURNING ` + scoreColumns
+		}
+		moved, err := scanScore(tx.QueryRowContext(ctx, query, args...))
+		if err != nil {
+			return store.RenameResult{}, fmt.Errorf("write score: %w", err)
+		}
+		res.Scores = append(res.Scores, moved)
+	}
+
+	switch {
+	case len(toProfiles) > 0:
+		res.Profile = &toProfiles[0]
+		if _, err := tx.ExecContext(ctx, `DELETE FROM players WHERE player_name = ?1`, from); err != nil {
+			return store.RenameResult{}, fmt.Errorf("delete profile: %w", err)
+		}
+	case len(fromProfiles) > 0:
+		p, err := scanPlayer(tx.QueryRowContext(ctx, `
+			UPDATE players SET player_name = ?2, updated_at = ?3 WHERE player_name = ?1
+			RETURNING `+playerColumns,
+			from, to, toMicros(time.Now())))
+		if err != nil {
+			return store.RenameResult{}, fmt.Errorf("move profile: %w", err)
+		}
+		res.Profile = &p
+	}
+	for _, q := range []struct{ what, query string }{
+		{"move devices", `
+			INSERT INTO device_players (device_hash, player_name, first_seen_at)
+			SELECT device_hash, ?2, first_seen_at FROM device_players WHERE player_name = ?1
+			ON CONFLICT (device_hash, player_name)
+			DO UPDATE SET first_seen_at = min(device_players.first_seen_at, excluded.first_seen_at)`},
+		{"move device submissions", `UPDATE device_submissions SET player_name = ?2 WHERE player_name = ?1`},
+	} {
+		if _, err := tx.ExecContext(ctx, q.query, from, to); err != nil {
+			return store.RenameResult{}, fmt.Errorf("%s: %w", q.what, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM device_players WHERE player_name = ?1`, from); err != nil {
+		return store.RenameResult{}, fmt.Errorf("move devices: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.RenameResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
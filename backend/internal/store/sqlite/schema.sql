@@ -0,0 +1,200 @@
+-- SQLite schema mirroring db/migrations for the PostgreSQL backend.
+-- Applied on every open; all statements are idempotent.
+-- Timestamps are stored as Unix microseconds (UTC).
+
+CREATE TABLE IF NOT EXISTS scores (
+    leaderboard_id TEXT NOT NULL DEFAULT 'global',
+    player_name TEXT NOT NULL,
+    score INTEGER NOT NULL CHECK (score >= 0),
+    updated_at INTEGER NOT NULL,
+    achieved_at INTEGER NOT NULL,
+    client_achieved_at INTEGER,
+    -- score in ranking space (negated on 'asc' boards): higher is always better
+    rank_score INTEGER NOT NULL,
+    -- set by soft deletes: every query skips the row until it is restored
+    deleted_at INTEGER,
+    -- game-defined attributes of the best score, a JSON object of strings
+    metadata TEXT NOT NULL DEFAULT '{}',
+    -- tiebreaker among equal scores, ranked in the board's secondary sort order
+    secondary_score INTEGER NOT NULL DEFAULT 0 CHECK (secondary_score >= 0),
+    -- secondary_score in ranking space, like rank_score
+    rank_secondary INTEGER NOT NULL DEFAULT 0,
+    -- ISO 3166-1 alpha-2 country the best score was submitted from, '' when unknown
+    country_code TEXT NOT NULL DEFAULT '' CHECK (country_code = '' OR (length(country_code) = 2 AND country_code GLOB '[A-Z][A-Z]')),
+    -- platform family the best score was submitted from, '' when unknown
+    platform TEXT NOT NULL DEFAULT '' CHECK (platform IN ('', 'pc', 'mobile', 'console', 'web')),
+    PRIMARY KEY (leaderboard_id, player_name),
+    CONSTRAINT player_name_length CHECK (length(player_name) <= 20 AND length(player_name) > 0),
+    CONSTRAINT leaderboard_id_length CHECK (length(leaderboard_id) <= 64 AND length(leaderboard_id) > 0)
+);
+
+-- idx_scores_leaderboard predates secondary scores: it is replaced by idx_scores_ranking
+DROP INDEX IF EXISTS idx_scores_leaderboard;
+CREATE INDEX IF NOT EXISTS idx_scores_ranking ON scores (leaderboard_id, rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name);
+CREATE INDEX IF NOT EXISTS idx_scores_region ON scores (leaderboard_id, country_code, rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name);
+CREATE INDEX IF NOT EXISTS idx_scores_platform ON scores (leaderboard_id, platform, rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name);
+CREATE INDEX IF NOT EXISTS idx_scores_deleted ON scores (leaderboard_id, deleted_at DESC) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS leaderboards (
+    leaderboard_id TEXT PRIMARY KEY,
+    sort_order TEXT NOT NULL DEFAULT 'desc',
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    secondary_sort_order TEXT NOT NULL DEFAULT 'desc' CHECK (secondary_sort_order IN ('desc', 'asc')),
+    CONSTRAINT leaderboards_leaderboard_id_length CHECK (length(leaderboard_id) <= 64 AND length(leaderboard_id) > 0),
+    CONSTRAINT sort_order_valid CHECK (sort_order IN ('desc', 'asc'))
+);
+
+CREATE TABLE IF NOT EXISTS device_players (
+    device_hash TEXT NOT NULL,
+    player_name TEXT NOT NULL,
+    first_seen_at INTEGER NOT NULL,
+    PRIMARY KEY (device_hash, player_name),
+    CONSTRAINT device_hash_length CHECK (length(device_hash) <= 128 AND length(device_hash) > 0)
+);
+
+CREATE TABLE IF NOT EXISTS device_submissions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    device_hash TEXT NOT NULL,
+    player_name TEXT NOT NULL,
+    submitted_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_submissions_recent ON device_submissions (device_hash, submitted_at DESC);
+
+CREATE TABLE IF NOT EXISTS players (
+    player_name TEXT PRIMARY KEY,
+    display_name TEXT NOT NULL DEFAULT '',
+    country_code TEXT NOT NULL DEFAULT '',
+    avatar_url TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    CONSTRAINT players_player_name_length CHECK (length(player_name) <= 20 AND length(player_name) > 0),
+    CONSTRAINT display_name_length CHECK (length(display_name) <= 32),
+    CONSTRAINT country_code_format CHECK (country_code = '' OR (length(country_code) = 2 AND country_code GLOB '[A-Z][A-Z]')),
+    CONSTRAINT avatar_url_length CHECK (length(avatar_url) <= 512)
+);
+
+-- Players whose submissions are rejected
+CREATE TABLE IF NOT EXISTS banned_players (
+    player_name TEXT PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    banned_at INTEGER NOT NULL,
+    CONSTRAINT banned_players_player_name_length CHECK (length(player_name) <= 20 AND length(player_name) > 0),
+    CONSTRAINT ban_reason_length CHECK (length(reason) <= 256)
+);
+
+-- Copies of boards taken by admin resets
+CREATE TABLE IF NOT EXISTS leaderboard_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    leaderboard_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS leaderboard_snapshot_entries (
+    snapshot_id INTEGER NOT NULL REFERENCES leaderboard_snapshots (id) ON DELETE CASCADE,
+    player_name TEXT NOT NULL,
+    score INTEGER NOT NULL,
+    rank_score INTEGER NOT NULL,
+    achieved_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    secondary_score INTEGER NOT NULL DEFAULT 0,
+    rank_secondary INTEGER NOT NULL DEFAULT 0,
+    country_code TEXT NOT NULL DEFAULT '',
+    platform TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (snapshot_id, player_name)
+);
+
+-- Audit trail of player data erasures, keyed by the SHA-256 of the erased name
+CREATE TABLE IF NOT EXISTS player_erasures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    player_hash TEXT NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    deleted_scores INTEGER NOT NULL,
+    deleted_rows INTEGER NOT NULL,
+    erased_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_erasures_player ON player_erasures (player_hash);
+
+-- Scores moved off the live boards by the archival job, with the reason and time
+CREATE TABLE IF NOT EXISTS scores_archive (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    player_name TEXT NOT NULL,
+    score INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    achieved_at INTEGER NOT NULL,
+    client_achieved_at INTEGER,
+    leaderboard_id TEXT NOT NULL,
+    rank_score INTEGER NOT NULL,
+    deleted_at INTEGER,
+    metadata TEXT NOT NULL DEFAULT '{}',
+    secondary_score INTEGER NOT NULL DEFAULT 0,
+    rank_secondary INTEGER NOT NULL DEFAULT 0,
+    country_code TEXT NOT NULL DEFAULT '',
+    platform TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL CHECK (reason IN ('retention', 'score_floor')),
+    archived_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scores_archive_board ON scores_archive (leaderboard_id, archived_at);
+CREATE INDEX IF NOT EXISTS idx_scores_archive_player ON scores_archive (player_name);
+
+-- SQLite has no LISTEN/NOTIFY: triggers append to a change log that the Poller drains.
+-- Same semantics as the score_changes outbox in PostgreSQL, but with a single
+-- reader rows are deleted once read instead of pruned by age.
+CREATE TABLE IF NOT EXISTS score_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    leaderboard_id TEXT NOT NULL,
+    player_name TEXT NOT NULL,
+    score INTEGER NOT NULL,
+    rank_score INTEGER NOT NULL,
+    achieved_at INTEGER NOT NULL,
+    op TEXT NOT NULL,
+    metadata TEXT NOT NULL DEFAULT '{}',
+    secondary_score INTEGER NOT NULL DEFAULT 0,
+    rank_secondary INTEGER NOT NULL DEFAULT 0,
+    country_code TEXT NOT NULL DEFAULT '',
+    platform TEXT NOT NULL DEFAULT '',
+    -- when the change was written (the triggers set it, the column default only
+    -- serves rows logged before it existed)
+    created_at INTEGER NOT NULL DEFAULT 0
+);
+
+-- Soft deletes and restores are logged as deletes and inserts. Changes of deleted
+-- rows are not logged, and neither is the hard delete of a soft-deleted row.
+-- Triggers are dropped and created again, so databases created before soft
+-- deletes, score metadata, secondary scores, score regions and platforms get the current ones.
+DROP TRIGGER IF EXISTS scores_change_insert;
+CREATE TRIGGER scores_change_insert AFTER INSERT ON scores
+BEGIN
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, metadata, secondary_score, rank_secondary, country_code, platform, created_at, op) VALUES (NEW.leaderboard_id, NEW.player_name, NEW.score, NEW.rank_score, NEW.achieved_at, NEW.metadata, NEW.secondary_score, NEW.rank_secondary, NEW.country_code, NEW.platform, CAST((julianday('now') - 2440587.5) * 86400000000 AS INTEGER), 'insert');
+END;
+
+DROP TRIGGER IF EXISTS scores_change_update;
+CREATE TRIGGER scores_change_update AFTER UPDATE ON scores
+WHEN NEW.deleted_at IS NULL AND OLD.deleted_at IS NULL AND (NEW.score <> OLD.score OR NEW.secondary_score <> OLD.secondary_score)
+BEGIN
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, metadata, secondary_score, rank_secondary, country_code, platform, created_at, op) VALUES (NEW.leaderboard_id, NEW.player_name, NEW.score, NEW.rank_score, NEW.achieved_at, NEW.metadata, NEW.secondary_score, NEW.rank_secondary, NEW.country_code, NEW.platform, CAST((julianday('now') - 2440587.5) * 86400000000 AS INTEGER), 'update');
+END;
+
+DROP TRIGGER IF EXISTS scores_change_soft_delete;
+CREATE TRIGGER scores_change_soft_delete AFTER UPDATE ON scores
+WHEN NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL
+BEGIN
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, metadata, secondary_score, rank_secondary, country_code, platform, created_at, op) VALUES (OLD.leaderboard_id, OLD.player_name, OLD.score, OLD.rank_score, OLD.achieved_at, OLD.metadata, OLD.secondary_score, OLD.rank_secondary, OLD.country_code, OLD.platform, CAST((julianday('now') - 2440587.5) * 86400000000 AS INTEGER), 'delete');
+END;
+
+DROP TRIGGER IF EXISTS scores_change_restore;
+CREATE TRIGGER scores_change_restore AFTER UPDATE ON scores
+WHEN NEW.deleted_at IS NULL AND OLD.deleted_at IS NOT NULL
+BEGIN
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, metadata, secondary_score, rank_secondary, country_code, platform, created_at, op) VALUES (NEW.leaderboard_id, NEW.player_name, NEW.score, NEW.rank_score, NEW.achieved_at, NEW.metadata, NEW.secondary_score, NEW.rank_secondary, NEW.country_code, NEW.platform, CAST((julianday('now') - 2440587.5) * 86400000000 AS INTEGER), 'insert');
+END;
+
+DROP TRIGGER IF EXISTS scores_change_delete;
+CREATE TRIGGER scores_change_delete AFTER DELETE ON scores
+WHEN OLD.deleted_at IS NULL
+BEGIN
+    INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, metadata, secondary_score, rank_secondary, country_code, platform, created_at, op) VALUES (OLD.leaderboard_id, OLD.player_name, OLD.score, OLD.rank_score, OLD.achieved_at, OLD.metadata, OLD.secondary_score, OLD.rank_secondary, OLD.country_code, OLD.platform, CAST((julianday('now') - 2440587.5) * 86400000000 AS INTEGER), 'delete');
+END;
@@ -0,0 +1,1278 @@
+// Package sqlite implements store.Repository on top of an embedded SQLite database,
+// so the server can run without PostgreSQL for demos and tests.
+//
+// It is intended for a single server process: change notification is done by
+// polling a trigger-maintained change log (see Poller), and writes are serialized
+// through a single connection.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/yourorg/leaderboard/internal/store"
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+)
+
+//go:embed schema.sql
+var schema string
+
+// Store implements store.Repository with SQLite
+type Store struct {
+	db *sql.DB
+}
+
+var _ store.Repository = (*Store)(nil)
+
+// Open opens (creating if needed) the SQLite database at path and applies the schema.
+// Use ":memory:" for a throwaway in-memory database.
+func Open(ctx context.Context, path string) (*Store, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database: %w", err)
+	}
+
+	// SQLite allows a single writer; one connection also keeps ":memory:" databases shared
+	db.SetMaxOpenConns(1)
+
+	if err := upgrade(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to upgrade sqlite database: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to apply sqlite schema: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// addedColumns are the columns introduced after a database was created, in the
+// order they were added
+var addedColumns = []struct{ table, name, definition string }{
+	{"scores", "deleted_at", "INTEGER"},
+	{"scores", "metadata", "TEXT NOT NULL DEFAULT '{}'"},
+	{"score_changes", "metadata", "TEXT NOT NULL DEFAULT '{}'"},
+	{"scores", "secondary_score", "INTEGER NOT NULL DEFAULT 0 CHECK (secondary_score >= 0)"},
+	{"scores", "rank_secondary", "INTEGER NOT NULL DEFAULT 0"},
+	{"leaderboards", "secondary_sort_order", "TEXT NOT NULL DEFAULT 'desc' CHECK (secondary_sort_order IN ('desc', 'asc'))"},
+	{"score_changes", "secondary_score", "INTEGER NOT NULL DEFAULT 0"},
+	{"score_changes", "rank_secondary", "INTEGER NOT NULL DEFAULT 0"},
+	{"leaderboard_snapshot_entries", "secondary_score", "INTEGER NOT NULL DEFAULT 0"},
+	{"leaderboard_snapshot_entries", "rank_secondary", "INTEGER NOT NULL DEFAULT 0"},
+	{"score_changes", "created_at", "INTEGER NOT NULL DEFAULT 0"},
+	{"scores", "country_code", "TEXT NOT NULL DEFAULT '' CHECK (country_code = '' OR (length(country_code) = 2 AND country_code GLOB '[A-Z][A-Z]'))"},
+	{"score_changes", "country_code", "TEXT NOT NULL DEFAULT ''"},
+	{"leaderboard_snapshot_entries", "country_code", "TEXT NOT NULL DEFAULT ''"},
+	{"scores", "platform", "TEXT NOT NULL DEFAULT '' CHECK (platform IN ('', 'pc', 'mobile', 'console', 'web'))"},
+	{"score_changes", "platform", "TEXT NOT NULL DEFAULT ''"},
+	{"leaderboard_snapshot_entries", "platform", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// upgrade adds the columns introduced after a database was created, before the
+// schema refers to them: CREATE TABLE IF NOT EXISTS leaves existing tables as they are
+func upgrade(ctx context.Context, db *sql.DB) error {
+	for _, c := range addedColumns {
+		var missing bool
+		err := db.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM sqlite_schema WHERE type = 'table' AND name = ?1)
+			   AND NOT EXISTS (SELECT 1 FROM pragma_table_info(?1) WHERE name = ?2)`, c.table, c.name).Scan(&missing)
+		if err != nil {
+			return err
+		}
+		if !missing {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, `ALTER TABLE `+c.table+` ADD COLUMN `+c.name+` `+c.definition); err != nil {
+			return fmt.Errorf("add %s.%s: %w", c.table, c.name, err)
+		}
+	}
+	return nil
+}
+
+// ApplyValidation enforces v with triggers, created again on every call. SQLite
+// cannot alter the CHECK constraints of a table, so the player name bounds of
+// schema.sql stay in place: v can only narrow them.
+func (s *Store) ApplyValidation(ctx context.Context, v store.Validation) error {
+	for _, c := range v.Constraints() {
+		for _, event := range []string{"insert", "update"} {
+			trigger := "validate_" + c.Name + "_" + event
+			if _, err := s.db.ExecContext(ctx, `DROP TRIGGER IF EXISTS `+trigger); err != nil {
+				return fmt.Errorf("drop trigger %s: %w", trigger, err)
+			}
+			check := c.Expr("length", "NEW."+c.Column)
+			if check == "" {
+				continue
+			}
+			on := "INSERT ON " + c.Table
+			if event == "update" {
+				on = "UPDATE OF " + c.Column + " ON " + c.Table
+			}
+			create := fmt.Sprintf(`CREATE TRIGGER %s BEFORE %s WHEN NOT (%s)
+				BEGIN SELECT RAISE(ABORT, 'CHECK constraint failed: %s'); END`, trigger, on, check, c.Name)
+			if _, err := s.db.ExecContext(ctx, create); err != nil {
+				return fmt.Errorf("create trigger %s: %w", trigger, err)
+			}
+		}
+	}
+	return nil
+}
+
+// DB returns the underlying database handle
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// Ping verifies the database connection is alive
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close closes the database
+func (s *Store) Close() {
+	s.db.Close()
+}
+
+func (s *Store) UpsertScore(ctx context.Context, arg store.UpsertScoreParams) (store.Score, error) {
+	return upsertScore(ctx, s.db, arg)
+}
+
+// UpsertScores writes every row in one transaction. SQLite has a single writer
+// connection, so reading the previous score first is race-free.
+func (s *Store) UpsertScores(ctx context.Context, rows []store.UpsertScoreParams) ([]store.UpsertedScore, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() // no-op after Commit
+
+	out := make([]store.UpsertedScore, len(rows))
+	for i, row := range rows {
+		prev, err := scanScore(tx.QueryRowContext(ctx, `
+			SELECT `+scoreColumns+` FROM scores WHERE leaderboard_id = ?1 AND player_name = ?2 AND deleted_at IS NULL`,
+			row.LeaderboardID, row.PlayerName))
+		switch {
+		case err == nil:
+			out[i].Previous = &prev
+		case !errors.Is(err, store.ErrNoRows):
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		if out[i].Score, err = upsertScore(ctx, tx, row); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		out[i].Applied = out[i].Previous == nil || store.Outranks(out[i].Score, *out[i].Previous)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+// UpsertScoreRanked ranks the player before and after the upsert in one
+// transaction, read on the single writer connection like UpsertScores
+func (s *Store) UpsertScoreRanked(ctx context.Context, row store.UpsertScoreParams) (store.RankedScore, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return store.RankedScore{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() // no-op after Commit
+
+	var out store.RankedScore
+	rankParams := store.GetPlayerRankParams{LeaderboardID: row.LeaderboardID, PlayerName: row.PlayerName}
+	prev, err := scanScore(tx.QueryRowContext(ctx, `
+		SELECT `+scoreColumns+` FROM scores WHERE leaderboard_id = ?1 AND player_name = ?2 AND deleted_at IS NULL`,
+		row.LeaderboardID, row.PlayerName))
+	switch {
+	case err == nil:
+		out.Previous = &prev
+		if out.PreviousRank, err = getPlayerRank(ctx, tx, rankParams); err != nil {
+			return store.RankedScore{}, fmt.Errorf("get previous rank: %w", err)
+		}
+	case !errors.Is(err, store.ErrNoRows):
+		return store.RankedScore{}, fmt.Errorf("get current score: %w", err)
+	}
+
+	if out.Score, err = upsertScore(ctx, tx, row); err != nil {
+		return store.RankedScore{}, fmt.Errorf("upsert score: %w", err)
+	}
+	out.Applied = out.Previous == nil || store.Outranks(out.Score, *out.Previous)
+	out.Rank = out.PreviousRank
+	if out.Applied {
+		if out.Rank, err = getPlayerRank(ctx, tx, rankParams); err != nil {
+			return store.RankedScore{}, fmt.Errorf("get rank: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.RankedScore{}, fmt.Errorf("commit: %w", err)
+	}
+	return out, nil
+}
+
+// queryRower is satisfied by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scoreValues are the arguments ?1 to ?9 of the score inserts
+func scoreValues(arg store.UpsertScoreParams) []any {
+	now := time.Now()
+	achievedAt := now
+	if arg.AchievedAt.Valid {
+		achievedAt = arg.AchievedAt.Time
+	}
+	var clientAchievedAt *int64
+	if arg.ClientAchievedAt.Valid {
+		us := toMicros(arg.ClientAchievedAt.Time)
+		clientAchievedAt = &us
+	}
+	metadata := "{}"
+	if len(arg.Metadata) > 0 {
+		metadata = string(arg.Metadata)
+	}
+	return []any{arg.LeaderboardID, arg.PlayerName, arg.Score, toMicros(now), toMicros(achievedAt), clientAchievedAt, metadata, arg.SecondaryScore, arg.CountryCode, arg.Platform}
+}
+
+// InsertScore inserts a first score, store.ErrNoRows when the player already has
+// one. A soft-deleted score is replaced.
+func (s *Store) InsertScore(ctx context.Context, arg store.InsertScoreParams) (store.Score, error) {
+	return scanScore(s.db.QueryRowContext(ctx, `
+		INSERT INTO scores (leaderboard_id, player_name, score, updated_at, achieved_at, client_achieved_at, metadata, secondary_score, country_code, platform, rank_score, rank_secondary)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, CASE
+			WHEN EXISTS (SELECT 1 FROM leaderboards l WHERE l.leaderboard_id = ?1 AND l.sort_order = 'asc') THEN -?3
+			ELSE ?3
+		END, CASE
+			WHEN EXISTS (SELECT 1 FROM leaderboards l WHERE l.leaderboard_id = ?1 AND l.secondary_sort_order = 'asc') THEN -?8
+			ELSE ?8
+		END)
+		ON CONFLICT (leaderboard_id, player_name)
+		DO UPDATE SET
+			score = excluded.score,
+			rank_score = excluded.rank_score,
+			secondary_score = excluded.secondary_score,
+			rank_secondary = excluded.rank_secondary,
+			updated_at = excluded.updated_at,
+			achieved_at = excluded.achieved_at,
+			client_achieved_at = excluded.client_achieved_at,
+			metadata = excluded.metadata,
+			country_code = excluded.country_code,
+			platform = excluded.platform,
+			deleted_at = NULL
+		WHERE scores.deleted_at IS NOT NULL
+		RETURNING `+scoreColumns,
+		scoreValues(store.UpsertScoreParams(arg))...))
+}
+
+// upsertScore keeps the best score in the board's orders, like the PostgreSQL query:
+// the highest rank_score, then the highest rank_secondary. A soft-deleted score is replaced.
+func upsertScore(ctx context.Context, q queryRower, arg store.UpsertScoreParams) (store.Score, error) {
+	row := q.QueryRowContext(ctx, `
+		INSERT INTO scores (leaderboard_id, player_name, score, updated_at, achieved_at, client_achieved_at, metadata, secondary_score, country_code, platform, rank_score, rank_secondary)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, CASE
+			WHEN EXISTS (SELECT 1 FROM leaderboards l WHERE l.leaderboard_id = ?1 AND l.sort_order = 'asc') THEN -?3
+			ELSE ?3
+		END, CASE
+			WHEN EXISTS (SELECT 1 FROM leaderboards l WHERE l.leaderboard_id = ?1 AND l.secondary_sort_order = 'asc') THEN -?8
+			ELSE ?8
+		END)
+		ON CONFLICT (leaderboard_id, player_name)
+		DO UPDATE SET
+			score = CASE
+				WHEN scores.deleted_at IS NOT NULL OR (excluded.rank_score, excluded.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN excluded.score
+				ELSE scores.score
+			END,
+			secondary_score = CASE
+				WHEN scores.deleted_at IS NOT NULL OR (excluded.rank_score, excluded.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN excluded.secondary_score
+				ELSE scores.secondary_score
+			END,
+			rank_score = CASE
+				WHEN scores.deleted_at IS NOT NULL OR (excluded.rank_score, excluded.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN excluded.rank_score
+				ELSE scores.rank_score
+			END,
+			rank_secondary = CASE
+				WHEN scores.deleted_at IS NOT NULL OR (excluded.rank_score, excluded.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN excluded.rank_secondary
+				ELSE scores.rank_secondary
+			END,
+			updated_at = CASE
+				WHEN scores.deleted_at IS NOT NULL OR (excluded.rank_score, excluded.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN excluded.updated_at
+				ELSE scores.updated_at
+			END,
+			achieved_at = CASE
+				WHEN scores.deleted_at IS NOT NULL OR (excluded.rank_score, excluded.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN excluded.achieved_at
+				ELSE scores.achieved_at
+			END,
+			client_achieved_at = CASE
+				WHEN scores.deleted_at IS NOT NULL OR (excluded.rank_score, excluded.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN excluded.client_achieved_at
+				ELSE scores.client_achieved_at
+			END,
+			metadata = CASE
+				WHEN scores.deleted_at IS NOT NULL OR (excluded.rank_score, excluded.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN excluded.metadata
+				ELSE scores.metadata
+			END,
+			country_code = CASE
+				WHEN scores.deleted_at IS NOT NULL OR (excluded.rank_score, excluded.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN excluded.country_code
+				ELSE scores.country_code
+			END,
+			platform = CASE
+				WHEN scores.deleted_at IS NOT NULL OR (excluded.rank_score, excluded.rank_secondary) > (scores.rank_score, scores.rank_secondary) THEN excluded.platform
+				ELSE scores.platform
+			END,
+			deleted_at = NULL
+		RETURNING `+scoreColumns,
+		scoreValues(arg)...)
+	return scanScore(row)
+}
+
+func (s *Store) GetTopScores(ctx context.Context, arg store.GetTopScoresParams) ([]store.Score, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+scoreColumns+`
+		FROM scores
+		WHERE leaderboard_id = ?1 AND deleted_at IS NULL
+		ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC
+		LIMIT ?2 OFFSET ?3`,
+		arg.LeaderboardID, arg.PageSize, arg.PageOffset)
+	if err != nil {
+		return nil, err
+	}
+	return scanScores(rows)
+}
+
+// GetScoresAroundRank reads the page of GetTopScores covering the ranks around arg.Rank
+func (s *Store) GetScoresAroundRank(ctx context.Context, arg store.GetScoresAroundRankParams) ([]store.Score, error) {
+	first := max(arg.Rank-arg.Radius, 1)
+	return s.GetTopScores(ctx, store.GetTopScoresParams{
+		LeaderboardID: arg.LeaderboardID,
+		PageSize:      max(arg.Rank+arg.Radius-first+1, 0),
+		PageOffset:    first - 1,
+	})
+}
+
+// segmentFilter restricts a query to the scores of a segment, like the
+// PostgreSQL queries: parameter ?n is the country and ?n+1 the platform, an
+// empty one matching any. prefix qualifies the columns, e.g. "s1."
+func segmentFilter(prefix string, n int) string {
+	return fmt.Sprintf("(?%[2]d = '' OR %[1]scountry_code = ?%[2]d) AND (?%[3]d = '' OR %[1]splatform = ?%[3]d)", prefix, n, n+1)
+}
+
+func (s *Store) GetSegmentTopScores(ctx context.Context, arg store.GetSegmentTopScoresParams) ([]store.Score, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+scoreColumns+`
+		FROM scores
+		WHERE leaderboard_id = ?1 AND deleted_at IS NULL AND `+segmentFilter("", 4)+`
+		ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC
+		LIMIT ?2 OFFSET ?3`,
+		arg.LeaderboardID, arg.PageSize, arg.PageOffset, arg.CountryCode, arg.Platform)
+	if err != nil {
+		return nil, err
+	}
+	return scanScores(rows)
+}
+
+// recencyWeighted is the rank score weight of GetTopScoresRecencyWeighted; ?1 is the
+// current Unix time and ?2 the half-life, in seconds
+const recencyWeighted = `rank_score * power(2.0, -sign(rank_score) * min(max(?1 - achieved_at / 1e6, 0) / ?2, 1000))`
+
+func (s *Store) GetTopScoresRecencyWeighted(ctx context.Context, arg store.GetTopScoresRecencyWeightedParams) ([]store.Score, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+scoreColumns+`
+		FROM scores
+		WHERE leaderboard_id = ?3 AND deleted_at IS NULL
+		ORDER BY `+recencyWeighted+` DESC, rank_secondary DESC, achieved_at ASC, player_name ASC
+		LIMIT ?4 OFFSET ?5`,
+		arg.NowUnix, arg.HalfLifeSeconds, arg.LeaderboardID, arg.PageSize, arg.PageOffset)
+	if err != nil {
+		return nil, err
+	}
+	return scanScores(rows)
+}
+
+func (s *Store) GetPlayerRankRecencyWeighted(ctx context.Context, arg store.GetPlayerRankRecencyWeightedParams) (int64, error) {
+	var rank int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT player_rank FROM (
+			SELECT player_name,
+				ROW_NUMBER() OVER (ORDER BY `+recencyWeighted+` DESC, rank_secondary DESC, achieved_at ASC, player_name ASC) AS player_rank
+			FROM scores
+			WHERE leaderboard_id = ?3 AND deleted_at IS NULL
+		)
+		WHERE player_name = ?4`,
+		arg.NowUnix, arg.HalfLifeSeconds, arg.LeaderboardID, arg.PlayerName).Scan(&rank)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, store.ErrNoRows
+	}
+	return rank, err
+}
+
+func (s *Store) GetTopScoresAfter(ctx context.Context, arg store.GetTopScoresAfterParams) ([]store.Score, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+scoreColumns+`
+		FROM scores
+		WHERE leaderboard_id = ?1 AND deleted_at IS NULL
+		  AND rank_score <= ?2
+		  AND (rank_score < ?2
+		       OR rank_secondary < ?6
+		       OR (rank_secondary = ?6 AND achieved_at > ?3)
+		       OR (rank_secondary = ?6 AND achieved_at = ?3 AND player_name > ?4))
+		ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC
+		LIMIT ?5`,
+		arg.LeaderboardID, arg.RankScore, toMicros(arg.AchievedAt.Time), arg.PlayerName, arg.PageSize, arg.RankSecondary)
+	if err != nil {
+		return nil, err
+	}
+	return scanScores(rows)
+}
+
+func (s *Store) GetSegmentTopScoresAfter(ctx context.Context, arg store.GetSegmentTopScoresAfterParams) ([]store.Score, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+scoreColumns+`
+		FROM scores
+		WHERE leaderboard_id = ?1 AND deleted_at IS NULL AND `+segmentFilter("", 7)+`
+		  AND rank_score <= ?2
+		  AND (rank_score < ?2
+		       OR rank_secondary < ?6
+		       OR (rank_secondary = ?6 AND achieved_at > ?3)
+		       OR (rank_secondary = ?6 AND achieved_at = ?3 AND player_name > ?4))
+		ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC
+		LIMIT ?5`,
+		arg.LeaderboardID, arg.RankScore, toMicros(arg.AchievedAt.Time), arg.PlayerName, arg.PageSize, arg.RankSecondary, arg.CountryCode, arg.Platform)
+	if err != nil {
+		return nil, err
+	}
+	return scanScores(rows)
+}
+
+func (s *Store) GetPlayerScore(ctx context.Context, arg store.GetPlayerScoreParams) (store.Score, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+scoreColumns+`
+		FROM scores
+		WHERE leaderboard_id = ?1 AND player_name = ?2 AND deleted_at IS NULL`,
+		arg.LeaderboardID, arg.PlayerName)
+	return scanScore(row)
+}
+
+func (s *Store) PlayerExists(ctx context.Context, arg store.PlayerExistsParams) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM scores
+			WHERE leaderboard_id = ?1 AND player_name = ?2 AND deleted_at IS NULL
+		)`,
+		arg.LeaderboardID, arg.PlayerName).Scan(&exists)
+	return exists, err
+}
+
+func (s *Store) GetPlayerRank(ctx context.Context, arg store.GetPlayerRankParams) (int32, error) {
+	return getPlayerRank(ctx, s.db, arg)
+}
+
+func getPlayerRank(ctx context.Context, q queryRower, arg store.GetPlayerRankParams) (int32, error) {
+	var rank int32
+	err := q.QueryRowContext(ctx, `
+		SELECT 1 + COUNT(*)
+		FROM scores s1, (
+			SELECT rank_score, rank_secondary, achieved_at, player_name FROM scores
+			WHERE leaderboard_id = ?1 AND player_name = ?2 AND deleted_at IS NULL
+		) p
+		WHERE s1.leaderboard_id = ?1 AND s1.deleted_at IS NULL
+		  AND (s1.rank_score > p.rank_score
+		       OR (s1.rank_score = p.rank_score AND s1.rank_secondary > p.rank_secondary)
+		       OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at < p.achieved_at)
+		       OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at = p.achieved_at AND s1.player_name < p.player_name))`,
+		arg.LeaderboardID, arg.PlayerName).Scan(&rank)
+	return rank, err
+}
+
+func (s *Store) GetPlayerRanks(ctx context.Context, arg store.GetPlayerRanksParams) ([]store.GetPlayerRanksRow, error) {
+	ranks := []store.GetPlayerRanksRow{}
+	if len(arg.PlayerNames) == 0 {
+		return ranks, nil
+	}
+
+	// SQLite has no arrays: expand one placeholder per name after the board's ?1
+	args := []any{arg.LeaderboardID}
+	placeholders := make([]string, len(arg.PlayerNames))
+	for i, name := range arg.PlayerNames {
+		args = append(args, name)
+		placeholders[i] = fmt.Sprintf("?%d", i+2)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.player_name, p.score, p.updated_at, p.achieved_at, p.client_achieved_at, p.leaderboard_id, p.rank_score, p.deleted_at, p.metadata, p.secondary_score, p.rank_secondary, p.country_code, p.platform, (
+			SELECT 1 + COUNT(*) FROM scores s1
+			WHERE s1.leaderboard_id = p.leaderboard_id AND s1.deleted_at IS NULL
+			  AND (s1.rank_score > p.rank_score
+			       OR (s1.rank_score = p.rank_score AND s1.rank_secondary > p.rank_secondary)
+			       OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at < p.achieved_at)
+			       OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at = p.achieved_at AND s1.player_name < p.player_name))
+		) AS rank
+		FROM scores p
+		WHERE p.leaderboard_id = ?1 AND p.player_name IN (`+strings.Join(placeholders, ", ")+`) AND p.deleted_at IS NULL
+		ORDER BY rank, p.player_name`,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r store.GetPlayerRanksRow
+		if r.Score, err = scanScore(rankedRow{rows, &r.Rank}); err != nil {
+			return nil, err
+		}
+		ranks = append(ranks, r)
+	}
+	return ranks, rows.Err()
+}
+
+// rankedRow scans the score columns of a row followed by a rank
+type rankedRow struct {
+	rows *sql.Rows
+	rank *int64
+}
+
+func (r rankedRow) Scan(dest ...any) error {
+	return r.rows.Scan(append(dest, r.rank)...)
+}
+
+func (s *Store) GetSegmentPlayerRank(ctx context.Context, arg store.GetSegmentPlayerRankParams) (int32, error) {
+	var rank int32
+	err := s.db.QueryRowContext(ctx, `
+		SELECT 1 + COUNT(*)
+		FROM scores s1, (
+			SELECT rank_score, rank_secondary, achieved_at, player_name FROM scores
+			WHERE leaderboard_id = ?1 AND player_name = ?2 AND deleted_at IS NULL
+		) p
+		WHERE s1.leaderboard_id = ?1 AND s1.deleted_at IS NULL AND `+segmentFilter("s1.", 3)+`
+		  AND (s1.rank_score > p.rank_score
+		       OR (s1.rank_score = p.rank_score AND s1.rank_secondary > p.rank_secondary)
+		       OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at < p.achieved_at)
+		       OR (s1.rank_score = p.rank_score AND s1.rank_secondary = p.rank_secondary AND s1.achieved_at = p.achieved_at AND s1.player_name < p.player_name))`,
+		arg.LeaderboardID, arg.PlayerName, arg.CountryCode, arg.Platform).Scan(&rank)
+	return rank, err
+}
+
+func (s *Store) DeleteScore(ctx context.Context, arg store.DeleteScoreParams) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE scores SET deleted_at = ?3
+		WHERE leaderboard_id = ?1 AND player_name = ?2 AND deleted_at IS NULL`,
+		arg.LeaderboardID, arg.PlayerName, toMicros(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *Store) RestoreScore(ctx context.Context, arg store.RestoreScoreParams) (store.Score, error) {
+	return scanScore(s.db.QueryRowContext(ctx, `
+		UPDATE scores SET deleted_at = NULL
+		WHERE leaderboard_id = ?1 AND player_name = ?2 AND deleted_at IS NOT NULL
+		RETURNING `+scoreColumns,
+		arg.LeaderboardID, arg.PlayerName))
+}
+
+func (s *Store) GetDeletedScores(ctx context.Context, arg store.GetDeletedScoresParams) ([]store.Score, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+scoreColumns+`
+		FROM scores
+		WHERE leaderboard_id = ?1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, player_name ASC
+		LIMIT ?2`,
+		arg.LeaderboardID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return scanScores(rows)
+}
+
+func (s *Store) CountScores(ctx context.Context, leaderboardID string) (int64, error) {
+	var total int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM scores WHERE leaderboard_id = ?1 AND deleted_at IS NULL`, leaderboardID).Scan(&total)
+	return total, err
+}
+
+func (s *Store) CountSegmentScores(ctx context.Context, arg store.CountSegmentScoresParams) (int64, error) {
+	var total int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM scores
+		WHERE leaderboard_id = ?1 AND deleted_at IS NULL AND `+segmentFilter("", 2),
+		arg.LeaderboardID, arg.CountryCode, arg.Platform).Scan(&total)
+	return total, err
+}
+
+func (s *Store) CountPlayers(ctx context.Context) (int64, error) {
+	var total int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(DISTINCT player_name) FROM scores WHERE deleted_at IS NULL`).Scan(&total)
+	return total, err
+}
+
+// GetScoreForUpdate reads a player's score. SQLite has no row locks; writes are
+// already serialized by the single connection.
+func (s *Store) GetScoreForUpdate(ctx context.Context, arg store.GetScoreForUpdateParams) (store.Score, error) {
+	return s.GetPlayerScore(ctx, store.GetPlayerScoreParams(arg))
+}
+
+func (s *Store) RecordDevicePlayer(ctx context.Context, arg store.RecordDevicePlayerParams) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO device_players (device_hash, player_name, first_seen_at)
+		VALUES (?1, ?2, ?3)
+		ON CONFLICT (device_hash, player_name) DO NOTHING`,
+		arg.DeviceHash, arg.PlayerName, toMicros(time.Now()))
+	return err
+}
+
+func (s *Store) IsDevicePlayerKnown(ctx context.Context, arg store.IsDevicePlayerKnownParams) (bool, error) {
+	var known bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM device_players
+			WHERE device_hash = ?1 AND player_name = ?2
+		)`,
+		arg.DeviceHash, arg.PlayerName).Scan(&known)
+	return known, err
+}
+
+func (s *Store) CountDevicePlayers(ctx context.Context, deviceHash string) (int64, error) {
+	var total int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM device_players WHERE device_hash = ?1`, deviceHash).Scan(&total)
+	return total, err
+}
+
+func (s *Store) RecordDeviceSubmission(ctx context.Context, arg store.RecordDeviceSubmissionParams) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO device_submissions (device_hash, player_name, submitted_at)
+		VALUES (?1, ?2, ?3)`,
+		arg.DeviceHash, arg.PlayerName, toMicros(time.Now()))
+	return err
+}
+
+func (s *Store) PruneDeviceSubmissions(ctx context.Context, retentionSeconds float64) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM device_submissions WHERE submitted_at < ?1`,
+		toMicros(time.Now().Add(-time.Duration(retentionSeconds*float64(time.Second)))))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *Store) CountDeviceSubmissionsSince(ctx context.Context, arg store.CountDeviceSubmissionsSinceParams) (int64, error) {
+	var total int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM device_submissions
+		WHERE device_hash = ?1 AND submitted_at >= ?2`,
+		arg.DeviceHash, toMicros(arg.SubmittedAt.Time)).Scan(&total)
+	return total, err
+}
+
+// GetScorePercentiles computes continuous percentiles (like PostgreSQL's
+// percentile_cont) in Go, since SQLite has no ordered-set aggregates.
+func (s *Store) GetScorePercentiles(ctx context.Context, arg store.GetScorePercentilesParams) (store.GetScorePercentilesRow, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT rank_score FROM scores WHERE leaderboard_id = ?1 AND deleted_at IS NULL AND `+segmentFilter("", 2)+` ORDER BY rank_score ASC`,
+		arg.LeaderboardID, arg.CountryCode, arg.Platform)
+	if err != nil {
+		return store.GetScorePercentilesRow{}, err
+	}
+	defer rows.Close()
+
+	var scores []int64
+	for rows.Next() {
+		var score int64
+		if err := rows.Scan(&score); err != nil {
+			return store.GetScorePercentilesRow{}, err
+		}
+		scores = append(scores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return store.GetScorePercentilesRow{}, err
+	}
+
+	result := store.GetScorePercentilesRow{Total: int64(len(scores)), Thresholds: []float64{}}
+	if len(scores) == 0 {
+		return result, nil
+	}
+	for _, f := range arg.Fractions {
+		result.Thresholds = append(result.Thresholds, percentileCont(scores, f))
+	}
+	return result, nil
+}
+
+func (s *Store) GetScoresInRange(ctx context.Context, arg store.GetScoresInRangeParams) ([]store.Score, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+scoreColumns+`
+		FROM scores
+		WHERE leaderboard_id = ?1 AND deleted_at IS NULL AND rank_score >= ?2 AND rank_score < ?3
+		ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC`,
+		arg.LeaderboardID, arg.MinRankScore, arg.MaxRankScore)
+	if err != nil {
+		return nil, err
+	}
+	return scanScores(rows)
+}
+
+func (s *Store) GetScoreBounds(ctx context.Context, leaderboardID string) (store.GetScoreBoundsRow, error) {
+	var row store.GetScoreBoundsRow
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(MIN(score), 0), COALESCE(MAX(score), 0)
+		FROM scores
+		WHERE leaderboard_id = ?1 AND deleted_at IS NULL`,
+		leaderboardID).Scan(&row.Players, &row.MinScore, &row.MaxScore)
+	return row, err
+}
+
+// GetScoreHistogram computes PostgreSQL's width_bucket in SQL, which SQLite lacks
+func (s *Store) GetScoreHistogram(ctx context.Context, arg store.GetScoreHistogramParams) ([]store.GetScoreHistogramRow, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			CASE
+				WHEN score < ?1 THEN 0
+				WHEN score >= ?2 THEN ?3 + 1
+				ELSE CAST((score - ?1) * ?3 / (?2 - ?1) AS INTEGER) + 1
+			END AS bucket,
+			COUNT(*)
+		FROM scores
+		WHERE leaderboard_id = ?4 AND deleted_at IS NULL
+		GROUP BY bucket
+		ORDER BY bucket`,
+		arg.Low, arg.High, arg.BucketCount, arg.LeaderboardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var histogram []store.GetScoreHistogramRow
+	for rows.Next() {
+		var row store.GetScoreHistogramRow
+		if err := rows.Scan(&row.Bucket, &row.Players); err != nil {
+			return nil, err
+		}
+		histogram = append(histogram, row)
+	}
+	return histogram, rows.Err()
+}
+
+func (s *Store) GetScoreStats(ctx context.Context, leaderboardID string) (store.GetScoreStatsRow, error) {
+	var stats store.GetScoreStatsRow
+	var lastUpdatedAt sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*), MAX(updated_at) FROM scores WHERE leaderboard_id = ?1 AND deleted_at IS NULL`,
+		leaderboardID).Scan(&stats.Players, &lastUpdatedAt)
+	if lastUpdatedAt.Valid {
+		stats.LastUpdatedAt = fromMicros(lastUpdatedAt.Int64)
+	}
+	return stats, err
+}
+
+func (s *Store) SimulateRank(ctx context.Context, arg store.SimulateRankParams) (store.SimulateRankRow, error) {
+	var row store.SimulateRankRow
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE rank_score >= ?1),
+			COALESCE(MIN(rank_score) FILTER (WHERE rank_score >= ?1), 0),
+			COUNT(*)
+		FROM scores
+		WHERE leaderboard_id = ?3 AND player_name <> ?2 AND deleted_at IS NULL AND `+segmentFilter("", 4),
+		arg.RankScore, arg.PlayerName, arg.LeaderboardID, arg.CountryCode, arg.Platform).Scan(&row.Ahead, &row.NextRankScore, &row.Total)
+	return row, err
+}
+
+func (s *Store) UpsertPlayerProfile(ctx context.Context, arg store.UpsertPlayerProfileParams) (store.Player, error) {
+	now := toMicros(time.Now())
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO players (player_name, display_name, country_code, avatar_url, created_at, updated_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?5)
+		ON CONFLICT (player_name)
+		DO UPDATE SET
+			display_name = excluded.display_name,
+			country_code = excluded.country_code,
+			avatar_url = excluded.avatar_url,
+			updated_at = excluded.updated_at
+		RETURNING `+playerColumns,
+		arg.PlayerName, arg.DisplayName, arg.CountryCode, arg.AvatarUrl, now)
+	return scanPlayer(row)
+}
+
+func (s *Store) GetPlayerProfile(ctx context.Context, playerName string) (store.Player, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+playerColumns+`
+		FROM players
+		WHERE player_name = ?1`,
+		playerName)
+	return scanPlayer(row)
+}
+
+func (s *Store) GetPlayerProfiles(ctx context.Context, playerNames []string) ([]store.Player, error) {
+	players := []store.Player{}
+	if len(playerNames) == 0 {
+		return players, nil
+	}
+
+	// SQLite has no arrays: expand one placeholder per name
+	args := make([]any, len(playerNames))
+	placeholders := make([]string, len(playerNames))
+	for i, name := range playerNames {
+		args[i] = name
+		placeholders[i] = "?"
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+playerColumns+`
+		FROM players
+		WHERE player_name IN (`+strings.Join(placeholders, ", ")+`)`,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanPlayer(rows)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+func (s *Store) UpsertLeaderboard(ctx context.Context, arg store.UpsertLeaderboardParams) (store.Leaderboard, error) {
+	now := toMicros(time.Now())
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO leaderboards (leaderboard_id, sort_order, secondary_sort_order, created_at, updated_at)
+		VALUES (?1, ?2, ?4, ?3, ?3)
+		ON CONFLICT (leaderboard_id)
+		DO UPDATE SET
+			sort_order = excluded.sort_order,
+			secondary_sort_order = excluded.secondary_sort_order,
+			updated_at = excluded.updated_at
+		RETURNING `+leaderboardColumns,
+		arg.LeaderboardID, arg.SortOrder, now, arg.SecondarySortOrder)
+	return scanLeaderboard(row)
+}
+
+func (s *Store) GetLeaderboard(ctx context.Context, leaderboardID string) (store.Leaderboard, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+leaderboardColumns+`
+		FROM leaderboards
+		WHERE leaderboard_id = ?1`,
+		leaderboardID)
+	return scanLeaderboard(row)
+}
+
+func (s *Store) ListLeaderboards(ctx context.Context, arg store.ListLeaderboardsParams) ([]store.Leaderboard, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+leaderboardColumns+`
+		FROM leaderboards
+		WHERE ?1 = '' OR substr(leaderboard_id, 1, length(?1)) <> ?1
+		ORDER BY leaderboard_id
+		LIMIT ?2`,
+		arg.ExcludePrefix, arg.MaxBoards)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var boards []store.Leaderboard
+	for rows.Next() {
+		lb, err := scanLeaderboard(rows)
+		if err != nil {
+			return nil, err
+		}
+		boards = append(boards, lb)
+	}
+	return boards, rows.Err()
+}
+
+func (s *Store) HasScores(ctx context.Context, leaderboardID string) (bool, error) {
+	var has bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM scores WHERE leaderboard_id = ?1 AND deleted_at IS NULL)`, leaderboardID).Scan(&has)
+	return has, err
+}
+
+func (s *Store) CreateLeaderboardSnapshot(ctx context.Context, leaderboardID string) (int64, error) {
+	return createSnapshot(ctx, s.db, leaderboardID)
+}
+
+func (s *Store) SnapshotScores(ctx context.Context, arg store.SnapshotScoresParams) (int64, error) {
+	return snapshotScores(ctx, s.db, arg)
+}
+
+func (s *Store) DeleteLeaderboardScores(ctx context.Context, leaderboardID string) (int64, error) {
+	return deleteLeaderboardScores(ctx, s.db, leaderboardID)
+}
+
+// ResetLeaderboard mirrors the PostgreSQL reset: the per-row deletes logged by the
+// triggers are replaced by a single 'resync' change of the board
+func (s *Store) ResetLeaderboard(ctx context.Context, leaderboardID string, snapshot bool) (store.ResetResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return store.ResetResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() // no-op after Commit
+
+	var res store.ResetResult
+	if snapshot {
+		if res.SnapshotID, err = createSnapshot(ctx, tx, leaderboardID); err != nil {
+			return store.ResetResult{}, fmt.Errorf("create snapshot: %w", err)
+		}
+		if _, err := snapshotScores(ctx, tx, store.SnapshotScoresParams{SnapshotID: res.SnapshotID, LeaderboardID: leaderboardID}); err != nil {
+			return store.ResetResult{}, fmt.Errorf("snapshot scores: %w", err)
+		}
+	}
+
+	var lastChange int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM score_changes`).Scan(&lastChange); err != nil {
+		return store.ResetResult{}, fmt.Errorf("read change log: %w", err)
+	}
+	if res.Deleted, err = deleteLeaderboardScores(ctx, tx, leaderboardID); err != nil {
+		return store.ResetResult{}, fmt.Errorf("delete scores: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM score_changes WHERE id > ?1 AND leaderboard_id = ?2 AND op = 'delete'`,
+		lastChange, leaderboardID); err != nil {
+		return store.ResetResult{}, fmt.Errorf("drop delete changes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, created_at, op)
+		VALUES (?1, '', 0, 0, ?2, ?2, 'resync')`,
+		leaderboardID, toMicros(time.Now())); err != nil {
+		return store.ResetResult{}, fmt.Errorf("log resync: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.ResetResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
+
+func (s *Store) GetLeaderboardSnapshot(ctx context.Context, id int64) (store.LeaderboardSnapshot, error) {
+	var snap store.LeaderboardSnapshot
+	var createdAt int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, leaderboard_id, created_at FROM leaderboard_snapshots WHERE id = ?1`,
+		id).Scan(&snap.ID, &snap.LeaderboardID, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return snap, store.ErrNoRows
+	}
+	snap.CreatedAt = fromMicros(createdAt)
+	return snap, err
+}
+
+func (s *Store) GetSnapshotEntries(ctx context.Context, snapshotID int64) ([]store.LeaderboardSnapshotEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT snapshot_id, player_name, score, rank_score, achieved_at, updated_at, secondary_score, rank_secondary, country_code, platform
+		FROM leaderboard_snapshot_entries
+		WHERE snapshot_id = ?1
+		ORDER BY rank_score DESC, rank_secondary DESC, achieved_at ASC, player_name ASC`,
+		snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []store.LeaderboardSnapshotEntry{}
+	for rows.Next() {
+		var e store.LeaderboardSnapshotEntry
+		var achievedAt, updatedAt int64
+		if err := rows.Scan(&e.SnapshotID, &e.PlayerName, &e.Score, &e.RankScore, &achievedAt, &updatedAt, &e.SecondaryScore, &e.RankSecondary, &e.CountryCode, &e.Platform); err != nil {
+			return nil, err
+		}
+		e.AchievedAt, e.UpdatedAt = fromMicros(achievedAt), fromMicros(updatedAt)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// RestoreLeaderboard mirrors the PostgreSQL restore: the per-row changes logged by
+// the triggers are replaced by a single 'resync' change of the board
+func (s *Store) RestoreLeaderboard(ctx context.Context, leaderboardID string, entries []store.RestoreEntry, snapshot bool) (store.RestoreResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return store.RestoreResult{}, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback() // no-op after Commit
+
+	var res store.RestoreResult
+	if snapshot {
+		if res.SnapshotID, err = createSnapshot(ctx, tx, leaderboardID); err != nil {
+			return store.RestoreResult{}, fmt.Errorf("create snapshot: %w", err)
+		}
+		if _, err := snapshotScores(ctx, tx, store.SnapshotScoresParams{SnapshotID: res.SnapshotID, LeaderboardID: leaderboardID}); err != nil {
+			return store.RestoreResult{}, fmt.Errorf("snapshot scores: %w", err)
+		}
+	}
+
+	sortOrder, secondaryOrder := "desc", "desc"
+	if lb, err := scanLeaderboard(tx.QueryRowContext(ctx, `
+		SELECT `+leaderboardColumns+` FROM leaderboards WHERE leaderboard_id = ?1`, leaderboardID)); err == nil {
+		sortOrder, secondaryOrder = lb.SortOrder, lb.SecondarySortOrder
+	} else if !errors.Is(err, store.ErrNoRows) {
+		return store.RestoreResult{}, fmt.Errorf("get leaderboard: %w", err)
+	}
+
+	var lastChange int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM score_changes`).Scan(&lastChange); err != nil {
+		return store.RestoreResult{}, fmt.Errorf("read change log: %w", err)
+	}
+	if res.Deleted, err = deleteLeaderboardScores(ctx, tx, leaderboardID); err != nil {
+		return store.RestoreResult{}, fmt.Errorf("delete scores: %w", err)
+	}
+
+	insert, err := tx.PrepareContext(ctx, `
+		INSERT INTO scores (leaderboard_id, player_name, score, rank_score, achieved_at, updated_at, secondary_score, rank_secondary, country_code, platform)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)`)
+	if err != nil {
+		return store.RestoreResult{}, fmt.Errorf("prepare insert: %w", err)
+	}
+	defer insert.Close()
+	for i, e := range entries {
+		if _, err := insert.ExecContext(ctx, leaderboardID, e.PlayerName, e.Score, store.RankScore(sortOrder, e.Score),
+			toMicros(e.AchievedAt), toMicros(e.UpdatedAt), e.SecondaryScore, store.RankScore(secondaryOrder, e.SecondaryScore), e.CountryCode, e.Platform); err != nil {
+			return store.RestoreResult{}, fmt.Errorf("row %d: %w", i, err)
+		}
+		res.Restored++
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM score_changes WHERE id > ?1 AND leaderboard_id = ?2`,
+		lastChange, leaderboardID); err != nil {
+		return store.RestoreResult{}, fmt.Errorf("drop row changes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, created_at, op)
+		VALUES (?1, '', 0, 0, ?2, ?2, 'resync')`,
+		leaderboardID, toMicros(time.Now())); err != nil {
+		return store.RestoreResult{}, fmt.Errorf("log resync: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.RestoreResult{}, fmt.Errorf("commit: %w", err)
+	}
+	return res, nil
+}
+
+// execQuerier is satisfied by *sql.DB and *sql.Tx
+type execQuerier interface {
+	queryRower
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func createSnapshot(ctx context.Context, q queryRower, leaderboardID string) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO leaderboard_snapshots (leaderboard_id, created_at) VALUES (?1, ?2) RETURNING id`,
+		leaderboardID, toMicros(time.Now())).Scan(&id)
+	return id, err
+}
+
+func snapshotScores(ctx context.Context, q execQuerier, arg store.SnapshotScoresParams) (int64, error) {
+	res, err := q.ExecContext(ctx, `
+		INSERT INTO leaderboard_snapshot_entries (snapshot_id, player_name, score, rank_score, achieved_at, updated_at, secondary_score, rank_secondary, country_code, platform)
+		SELECT ?1, player_name, score, rank_score, achieved_at, updated_at, secondary_score, rank_secondary, country_code, platform
+		FROM scores
+		WHERE leaderboard_id = ?2 AND deleted_at IS NULL`,
+		arg.SnapshotID, arg.LeaderboardID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// deleteLeaderboardScores deletes every score of a board for good, soft-deleted
+// ones included, and returns the number of live scores deleted
+func deleteLeaderboardScores(ctx context.Context, q execQuerier, leaderboardID string) (int64, error) {
+	var live int64
+	if err := q.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM scores WHERE leaderboard_id = ?1 AND deleted_at IS NULL`,
+		leaderboardID).Scan(&live); err != nil {
+		return 0, err
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM scores WHERE leaderboard_id = ?1`, leaderboardID); err != nil {
+		return 0, err
+	}
+	return live, nil
+}
+
+// percentileCont interpolates linearly between the two closest ranks of sorted
+func percentileCont(sorted []int64, fraction float64) float64 {
+	pos := fraction * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(pos)), int(math.Ceil(pos))
+	return float64(sorted[lo]) + float64(sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+// scoreColumns are the scores columns in the order scanScore reads them
+const scoreColumns = "player_name, score, updated_at, achieved_at, client_achieved_at, leaderboard_id, rank_score, deleted_at, metadata, secondary_score, rank_secondary, country_code, platform"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanScore(row rowScanner) (store.Score, error) {
+	var sc store.Score
+	var updatedAt, achievedAt int64
+	var clientAchievedAt, deletedAt sql.NullInt64
+	if err := row.Scan(&sc.PlayerName, &sc.Score, &updatedAt, &achievedAt, &clientAchievedAt, &sc.LeaderboardID, &sc.RankScore, &deletedAt, &sc.Metadata, &sc.SecondaryScore, &sc.RankSecondary, &sc.CountryCode, &sc.Platform); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sc, store.ErrNoRows
+		}
+		return sc, err
+	}
+	sc.UpdatedAt = fromMicros(updatedAt)
+	sc.AchievedAt = fromMicros(achievedAt)
+	if clientAchievedAt.Valid {
+		sc.ClientAchievedAt = fromMicros(clientAchievedAt.Int64)
+	}
+	if deletedAt.Valid {
+		sc.DeletedAt = fromMicros(deletedAt.Int64)
+	}
+	return sc, nil
+}
+
+// playerColumns are the players columns in the order scanPlayer reads them
+const playerColumns = "player_name, display_name, country_code, avatar_url, created_at, updated_at"
+
+func scanPlayer(row rowScanner) (store.Player, error) {
+	var p store.Player
+	var createdAt, updatedAt int64
+	if err := row.Scan(&p.PlayerName, &p.DisplayName, &p.CountryCode, &p.AvatarUrl, &createdAt, &updatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return p, store.ErrNoRows
+		}
+		return p, err
+	}
+	p.CreatedAt = fromMicros(createdAt)
+	p.UpdatedAt = fromMicros(updatedAt)
+	return p, nil
+}
+
+// leaderboardColumns are the leaderboards columns in the order scanLeaderboard reads them
+const leaderboardColumns = "leaderboard_id, sort_order, created_at, updated_at, secondary_sort_order"
+
+func scanLeaderboard(row rowScanner) (store.Leaderboard, error) {
+	var l store.Leaderboard
+	var createdAt, updatedAt int64
+	if err := row.Scan(&l.LeaderboardID, &l.SortOrder, &createdAt, &updatedAt, &l.SecondarySortOrder); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return l, store.ErrNoRows
+		}
+		return l, err
+	}
+	l.CreatedAt = fromMicros(createdAt)
+	l.UpdatedAt = fromMicros(updatedAt)
+	return l, nil
+}
+
+func scanScores(rows *sql.Rows) ([]store.Score, error) {
+	defer rows.Close()
+
+	scores := []store.Score{}
+	for rows.Next() {
+		sc, err := scanScore(rows)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, sc)
+	}
+	return scores, rows.Err()
+}
+
+func toMicros(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+func fromMicros(us int64) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.UnixMicro(us).UTC(), Valid: true}
+}
+
+// Analyze runs ANALYZE on every table
+func (s *Store) Analyze(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name FROM sqlite_schema
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, table := range tables {
+		if _, err := s.db.ExecContext(ctx, `ANALYZE "`+table+`"`); err != nil {
+			return nil, fmt.Errorf("analyze %s: %w", table, err)
+		}
+	}
+	return tables, nil
+}
+
+// TableStats is not supported: SQLite keeps no scan counters
+func (s *Store) TableStats(ctx context.Context) ([]store.TableStats, error) {
+	return nil, store.ErrNotSupported
+}
+
+// IndexStats is not supported: SQLite keeps no index usage counters
+func (s *Store) IndexStats(ctx context.Context) ([]store.IndexStats, error) {
+	return nil, store.ErrNotSupported
+}
+
+// Webhooks are not supported: their delivery queue relies on row locks shared by
+// several servers, and a SQLite database has a single server
+
+func (s *Store) CreateWebhook(ctx context.Context, arg store.CreateWebhookParams) (store.Webhook, error) {
+	return store.Webhook{}, store.ErrNotSupported
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id int64) (store.Webhook, error) {
+	return store.Webhook{}, store.ErrNotSupported
+}
+
+func (s *Store) ListWebhooks(ctx context.Context) ([]store.Webhook, error) {
+	return nil, store.ErrNotSupported
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, arg store.UpdateWebhookParams) (store.Webhook, error) {
+	return store.Webhook{}, store.ErrNotSupported
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id int64) (int64, error) {
+	return 0, store.ErrNotSupported
+}
+
+func (s *Store) ListWebhooksForEvent(ctx context.Context, arg store.ListWebhooksForEventParams) ([]store.Webhook, error) {
+	return nil, store.ErrNotSupported
+}
+
+func (s *Store) EnqueueWebhookDelivery(ctx context.Context, arg store.EnqueueWebhookDeliveryParams) (int64, error) {
+	return 0, store.ErrNotSupported
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, arg store.ClaimWebhookDeliveriesParams) ([]store.ClaimWebhookDeliveriesRow, error) {
+	return nil, store.ErrNotSupported
+}
+
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, arg store.CompleteWebhookDeliveryParams) error {
+	return store.ErrNotSupported
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, arg store.ListWebhookDeliveriesParams) ([]store.WebhookDelivery, error) {
+	return nil, store.ErrNotSupported
+}
+
+func (s *Store) PruneWebhookDeliveries(ctx context.Context, retentionSeconds float64) (int64, error) {
+	return 0, store.ErrNotSupported
+}
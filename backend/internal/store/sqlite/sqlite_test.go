@@ -0,0 +1,1071 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+const board = store.DefaultLeaderboardID
+
+func openTestStore(t testing.TB) *Store {
+	t.Helper()
+	st, err := Open(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %s", err)
+	}
+	t.Cleanup(st.Close)
+	return st
+}
+
+func TestUpsertKeepsBestScore(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 100}); err != nil {
+		t.Fatalf("upsert failed: %s", err)
+	}
+	got, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 50})
+	if err != nil {
+		t.Fatalf("upsert failed: %s", err)
+	}
+	if got.Score != 100 {
+		t.Errorf("score = %d, want 100 (best kept)", got.Score)
+	}
+
+	got, err = st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 150})
+	if err != nil {
+		t.Fatalf("upsert failed: %s", err)
+	}
+	if got.Score != 150 || !got.UpdatedAt.Valid {
+		t.Errorf("got %+v, want score 150 with timestamp", got)
+	}
+
+	if _, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: board, PlayerName: "Nobody"}); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("GetPlayerScore(missing) error = %v, want store.ErrNoRows", err)
+	}
+}
+
+func TestTopScoresAndRank(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	// Same achieved_at for everyone so the Bob/Carol tie falls back to player_name
+	achievedAt := pgtype.Timestamptz{Time: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC), Valid: true}
+	for name, score := range map[string]int64{"Alice": 300, "Bob": 200, "Carol": 200, "Dave": 100} {
+		if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: name, Score: score, AchievedAt: achievedAt}); err != nil {
+			t.Fatalf("upsert failed: %s", err)
+		}
+	}
+
+	top, err := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: board, PageSize: 3, PageOffset: 0})
+	if err != nil {
+		t.Fatalf("GetTopScores failed: %s", err)
+	}
+	want := []string{"Alice", "Bob", "Carol"}
+	for i, s := range top {
+		if s.PlayerName != want[i] {
+			t.Errorf("top[%d] = %s, want %s", i, s.PlayerName, want[i])
+		}
+	}
+
+	rank, err := st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: board, PlayerName: "Carol"})
+	if err != nil {
+		t.Fatalf("GetPlayerRank failed: %s", err)
+	}
+	if rank != 3 {
+		t.Errorf("rank = %d, want 3", rank)
+	}
+
+	ranks, err := st.GetPlayerRanks(ctx, store.GetPlayerRanksParams{LeaderboardID: board, PlayerNames: []string{"Dave", "Nobody", "Carol", "Alice"}})
+	if err != nil {
+		t.Fatalf("GetPlayerRanks failed: %s", err)
+	}
+	if len(ranks) != 3 || ranks[0].Score.PlayerName != "Alice" || ranks[0].Rank != 1 || ranks[1].Score.PlayerName != "Carol" || ranks[1].Rank != 3 ||
+		ranks[2].Score.PlayerName != "Dave" || ranks[2].Rank != 4 || ranks[2].Score.Score != 100 {
+		t.Errorf("ranks = %+v, want Alice 1, Carol 3, Dave 4", ranks)
+	}
+
+	around, err := st.GetScoresAroundRank(ctx, store.GetScoresAroundRankParams{LeaderboardID: board, Rank: 3, Radius: 1})
+	if err != nil {
+		t.Fatalf("GetScoresAroundRank failed: %s", err)
+	}
+	if len(around) != 3 || around[0].PlayerName != "Bob" || around[2].PlayerName != "Dave" {
+		t.Errorf("around rank 3 = %+v, want Bob, Carol, Dave", around)
+	}
+	if around, _ := st.GetScoresAroundRank(ctx, store.GetScoresAroundRankParams{LeaderboardID: board, Rank: 1, Radius: 2}); len(around) != 3 || around[0].PlayerName != "Alice" {
+		t.Errorf("around rank 1 = %+v, want Alice, Bob, Carol", around)
+	}
+
+	if exists, err := st.PlayerExists(ctx, store.PlayerExistsParams{LeaderboardID: board, PlayerName: "Dave"}); err != nil || !exists {
+		t.Errorf("PlayerExists(Dave) = %v, %v", exists, err)
+	}
+	if exists, _ := st.PlayerExists(ctx, store.PlayerExistsParams{LeaderboardID: board, PlayerName: "Nobody"}); exists {
+		t.Error("PlayerExists(Nobody) = true")
+	}
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-1", PlayerName: "Alice", Score: 10, AchievedAt: achievedAt})
+	if players, err := st.CountPlayers(ctx); err != nil || players != 4 {
+		t.Errorf("CountPlayers = %d, %v, want 4", players, err)
+	}
+
+	percentiles, err := st.GetScorePercentiles(ctx, store.GetScorePercentilesParams{LeaderboardID: board, Fractions: []float64{0.5}})
+	if err != nil {
+		t.Fatalf("GetScorePercentiles failed: %s", err)
+	}
+	if percentiles.Total != 4 || len(percentiles.Thresholds) != 1 || percentiles.Thresholds[0] != 200 {
+		t.Errorf("percentiles = %+v, want total 4 and median 200", percentiles)
+	}
+}
+
+func TestBoardsAreIndependent(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	for _, p := range []store.UpsertScoreParams{
+		{LeaderboardID: "level-1", PlayerName: "Alice", Score: 100},
+		{LeaderboardID: "level-1", PlayerName: "Bob", Score: 200},
+		{LeaderboardID: "level-2", PlayerName: "Alice", Score: 300},
+	} {
+		if _, err := st.UpsertScore(ctx, p); err != nil {
+			t.Fatalf("upsert failed: %s", err)
+		}
+	}
+
+	// Same player, separate best scores
+	got, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: "level-1", PlayerName: "Alice"})
+	if err != nil || got.Score != 100 || got.LeaderboardID != "level-1" {
+		t.Errorf("level-1 Alice = %+v (err %v), want score 100", got, err)
+	}
+	rank, err := st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: "level-2", PlayerName: "Alice"})
+	if err != nil || rank != 1 {
+		t.Errorf("level-2 rank of Alice = %d (err %v), want 1", rank, err)
+	}
+	if total, err := st.CountScores(ctx, "level-1"); err != nil || total != 2 {
+		t.Errorf("level-1 count = %d (err %v), want 2", total, err)
+	}
+	if total, err := st.CountScores(ctx, board); err != nil || total != 0 {
+		t.Errorf("%s count = %d (err %v), want 0", board, total, err)
+	}
+
+	if n, err := st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: "level-1", PlayerName: "Alice"}); err != nil || n != 1 {
+		t.Fatalf("delete = %d, %v; want 1 row", n, err)
+	}
+	if _, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: "level-2", PlayerName: "Alice"}); err != nil {
+		t.Errorf("level-2 Alice deleted with level-1: %v", err)
+	}
+}
+
+func TestAscendingBoard(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	if has, err := st.HasScores(ctx, "lap-1"); err != nil || has {
+		t.Fatalf("HasScores(empty) = %v (err %v), want false", has, err)
+	}
+	def, err := st.UpsertLeaderboard(ctx, store.UpsertLeaderboardParams{LeaderboardID: "lap-1", SortOrder: "asc", SecondarySortOrder: "desc"})
+	if err != nil || def.SortOrder != "asc" {
+		t.Fatalf("UpsertLeaderboard = %+v (err %v), want asc", def, err)
+	}
+	if got, err := st.GetLeaderboard(ctx, "lap-1"); err != nil || got.SortOrder != "asc" {
+		t.Errorf("GetLeaderboard = %+v (err %v), want asc", got, err)
+	}
+	if _, err := st.GetLeaderboard(ctx, "lap-2"); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("GetLeaderboard(undefined) error = %v, want store.ErrNoRows", err)
+	}
+
+	for _, p := range []store.UpsertScoreParams{
+		{LeaderboardID: "lap-1", PlayerName: "Alice", Score: 100},
+		{LeaderboardID: "lap-1", PlayerName: "Alice", Score: 90},
+		{LeaderboardID: "lap-1", PlayerName: "Alice", Score: 95}, // slower, ignored
+		{LeaderboardID: "lap-1", PlayerName: "Bob", Score: 120},
+	} {
+		if _, err := st.UpsertScore(ctx, p); err != nil {
+			t.Fatalf("upsert failed: %s", err)
+		}
+	}
+
+	got, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: "lap-1", PlayerName: "Alice"})
+	if err != nil || got.Score != 90 || got.RankScore != -90 {
+		t.Errorf("Alice = %+v (err %v), want score 90, rank_score -90", got, err)
+	}
+	top, err := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: "lap-1", PageSize: 10})
+	if err != nil || len(top) != 2 || top[0].PlayerName != "Alice" {
+		t.Errorf("top = %+v (err %v), want Alice first", top, err)
+	}
+	if has, err := st.HasScores(ctx, "lap-1"); err != nil || !has {
+		t.Errorf("HasScores = %v (err %v), want true", has, err)
+	}
+}
+
+func TestSecondaryScores(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	// Equal scores rank by the fewest seconds left on the clock
+	if _, err := st.UpsertLeaderboard(ctx, store.UpsertLeaderboardParams{LeaderboardID: "level-1", SortOrder: "desc", SecondarySortOrder: "asc"}); err != nil {
+		t.Fatalf("UpsertLeaderboard failed: %s", err)
+	}
+	achievedAt := pgtype.Timestamptz{Time: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC), Valid: true}
+	for _, p := range []store.UpsertScoreParams{
+		{PlayerName: "Alice", Score: 100, SecondaryScore: 40},
+		{PlayerName: "Bob", Score: 100, SecondaryScore: 30},
+		{PlayerName: "Carol", Score: 200, SecondaryScore: 90},
+		{PlayerName: "Dave", Score: 100, SecondaryScore: 50},
+	} {
+		p.LeaderboardID, p.AchievedAt = "level-1", achievedAt
+		if _, err := st.UpsertScore(ctx, p); err != nil {
+			t.Fatalf("upsert failed: %s", err)
+		}
+	}
+
+	// A better secondary score improves an equal score, a worse one does not
+	got, err := st.UpsertScores(ctx, []store.UpsertScoreParams{
+		{LeaderboardID: "level-1", PlayerName: "Alice", Score: 100, SecondaryScore: 20, AchievedAt: achievedAt},
+		{LeaderboardID: "level-1", PlayerName: "Dave", Score: 100, SecondaryScore: 60, AchievedAt: achievedAt},
+	})
+	if err != nil {
+		t.Fatalf("UpsertScores failed: %s", err)
+	}
+	if !got[0].Applied || got[0].SecondaryScore != 20 || got[0].RankSecondary != -20 {
+		t.Errorf("Alice = %+v, want applied with secondary score 20", got[0])
+	}
+	if got[1].Applied || got[1].SecondaryScore != 50 {
+		t.Errorf("Dave = %+v, want not applied with secondary score 50 kept", got[1])
+	}
+
+	top, err := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: "level-1", PageSize: 10})
+	if err != nil {
+		t.Fatalf("GetTopScores failed: %s", err)
+	}
+	want := []string{"Carol", "Alice", "Bob", "Dave"}
+	for i, sc := range top {
+		if sc.PlayerName != want[i] {
+			t.Errorf("top[%d] = %s, want %s", i, sc.PlayerName, want[i])
+		}
+	}
+	if rank, err := st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: "level-1", PlayerName: "Bob"}); err != nil || rank != 3 {
+		t.Errorf("rank of Bob = %d (err %v), want 3", rank, err)
+	}
+	after, err := st.GetTopScoresAfter(ctx, store.GetTopScoresAfterParams{
+		LeaderboardID: "level-1",
+		RankScore:     top[1].RankScore,
+		RankSecondary: top[1].RankSecondary,
+		AchievedAt:    top[1].AchievedAt,
+		PlayerName:    top[1].PlayerName,
+		PageSize:      10,
+	})
+	if err != nil || len(after) != 2 || after[0].PlayerName != "Bob" {
+		t.Errorf("page after Alice = %+v (err %v), want Bob then Dave", after, err)
+	}
+}
+
+func TestApplyValidation(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	// Written before the bounds: kept, but no longer written to
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Al", Score: 10}); err != nil {
+		t.Fatalf("upsert failed: %s", err)
+	}
+
+	v := store.Validation{MinPlayerNameLength: 3, MaxPlayerNameLength: 12, MaxScore: 1000}
+	for range 2 {
+		if err := st.ApplyValidation(ctx, v); err != nil {
+			t.Fatalf("ApplyValidation failed: %s", err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		score int64
+		ok    bool
+	}{
+		{"Bob", 1000, true},
+		{"Al", 20, false},
+		{"Ed", 100, false},
+		{"ThirteenChars", 100, false},
+		{"Carol", 1001, false},
+	}
+	for _, c := range cases {
+		_, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: c.name, Score: c.score})
+		if (err == nil) != c.ok {
+			t.Errorf("upsert %s with %d: error = %v, want ok %t", c.name, c.score, err, c.ok)
+		}
+	}
+	if _, err := st.UpsertPlayerProfile(ctx, store.UpsertPlayerProfileParams{PlayerName: "Ed"}); err == nil {
+		t.Error("profile of a too short name: want an error")
+	}
+	if sc, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: board, PlayerName: "Al"}); err != nil || sc.Score != 10 {
+		t.Errorf("score of Al = %+v (err %v), want 10 kept", sc, err)
+	}
+
+	// Back to the defaults: the score bound is dropped
+	if err := st.ApplyValidation(ctx, store.Validation{}); err != nil {
+		t.Fatalf("ApplyValidation with defaults failed: %s", err)
+	}
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Carol", Score: 1001}); err != nil {
+		t.Errorf("upsert above the dropped bound: %s", err)
+	}
+}
+
+func TestUpsertScoresBatch(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	if _, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 100}); err != nil {
+		t.Fatalf("upsert failed: %s", err)
+	}
+
+	got, err := st.UpsertScores(ctx, []store.UpsertScoreParams{
+		{LeaderboardID: board, PlayerName: "Alice", Score: 50},
+		{LeaderboardID: board, PlayerName: "Bob", Score: 200},
+		{LeaderboardID: board, PlayerName: "Bob", Score: 300},
+	})
+	if err != nil {
+		t.Fatalf("UpsertScores failed: %s", err)
+	}
+	if got[0].Score.Score != 100 || got[0].Previous == nil || got[0].Previous.Score != 100 {
+		t.Errorf("Alice = %+v, want best 100 kept", got[0])
+	}
+	if got[1].Previous != nil {
+		t.Errorf("Bob's first row previous = %+v, want nil", got[1].Previous)
+	}
+	if got[2].Score.Score != 300 || got[2].Previous == nil || got[2].Previous.Score != 200 {
+		t.Errorf("Bob's second row = %+v, want 300 over 200", got[2])
+	}
+
+	// A failing row rolls back the whole batch
+	_, err = st.UpsertScores(ctx, []store.UpsertScoreParams{
+		{LeaderboardID: board, PlayerName: "Carol", Score: 10},
+		{LeaderboardID: board, PlayerName: "Dave", Score: -1},
+	})
+	if err == nil {
+		t.Fatal("UpsertScores with an invalid row succeeded")
+	}
+	if _, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: board, PlayerName: "Carol"}); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("Carol after rollback: error = %v, want store.ErrNoRows", err)
+	}
+}
+
+// BenchmarkGetTopScoresManyBoards reads the top 10 of one board among 10k boards of 20 players
+func BenchmarkGetTopScoresManyBoards(b *testing.B) {
+	const boards, players = 10_000, 20
+	st := openTestStore(b)
+	ctx := context.Background()
+
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := range boards {
+		for j := range players {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO scores (leaderboard_id, player_name, score, rank_score, updated_at, achieved_at)
+				VALUES (?1, ?2, ?3, ?3, 0, 0)`,
+				fmt.Sprintf("level-%d", i), fmt.Sprintf("p%d", j), int64(i*j)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := range b.N {
+		scores, err := st.GetTopScores(ctx, store.GetTopScoresParams{
+			LeaderboardID: fmt.Sprintf("level-%d", i%boards),
+			PageSize:      10,
+		})
+		if err != nil || len(scores) != 10 {
+			b.Fatalf("GetTopScores = %d scores, err %v", len(scores), err)
+		}
+	}
+}
+
+func TestPollerEmitsChanges(t *testing.T) {
+	st := openTestStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := zerolog.Nop()
+	poller := NewPoller(st, 10*time.Millisecond, &logger)
+	poller.Start(ctx)
+
+	// Let the poller clear the log before writing
+	time.Sleep(50 * time.Millisecond)
+
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 100})
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 50}) // no change, no event
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 200, Metadata: []byte(`{"level":"2"}`)})
+	st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: board, PlayerName: "Alice"})
+	st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: board, PlayerName: "Alice"}) // already deleted, no event
+	st.RestoreScore(ctx, store.RestoreScoreParams{LeaderboardID: board, PlayerName: "Alice"})
+
+	want := []struct{ op, level string }{{"insert", ""}, {"update", "2"}, {"delete", "2"}, {"insert", "2"}}
+	var lastSeq int64
+	for _, w := range want {
+		select {
+		case change := <-poller.Changes():
+			if change.Op != w.op || change.PlayerName != "Alice" || change.LeaderboardID != board || change.Metadata["level"] != w.level {
+				t.Fatalf("got %+v, want op %s for Alice on %s with level %q", change, w.op, board, w.level)
+			}
+			if change.ID <= lastSeq {
+				t.Errorf("%s change has seq %d after %d", w.op, change.ID, lastSeq)
+			}
+			if time.Since(change.UpdatedAt) > time.Minute {
+				t.Errorf("%s change has updated_at %v", w.op, change.UpdatedAt)
+			}
+			lastSeq = change.ID
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s change", w.op)
+		}
+	}
+}
+
+func TestPollerStop(t *testing.T) {
+	st := openTestStore(t)
+	logger := zerolog.Nop()
+	poller := NewPoller(st, 10*time.Millisecond, &logger)
+	poller.Start(context.Background())
+
+	if err := poller.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if _, ok := <-poller.Changes(); ok {
+		t.Error("changes channel still open after Stop")
+	}
+	if _, ok := <-poller.Errors(); ok {
+		t.Error("errors channel still open after Stop")
+	}
+	if poller.Listening() {
+		t.Error("Listening() after Stop")
+	}
+}
+
+func TestResetLeaderboard(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	for i, name := range []string{"Alice", "Bob", "Carol"} {
+		st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-1", PlayerName: name, Score: int64(100 * (i + 1))})
+	}
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 50})
+	st.db.ExecContext(ctx, `DELETE FROM score_changes`)
+
+	res, err := st.ResetLeaderboard(ctx, "level-1", true)
+	if err != nil {
+		t.Fatalf("ResetLeaderboard failed: %s", err)
+	}
+	if res.Deleted != 3 || res.SnapshotID == 0 {
+		t.Errorf("result = %+v, want 3 deleted and a snapshot", res)
+	}
+	if n, _ := st.CountScores(ctx, "level-1"); n != 0 {
+		t.Errorf("level-1 holds %d scores after reset, want 0", n)
+	}
+	if n, _ := st.CountScores(ctx, board); n != 1 {
+		t.Errorf("%s holds %d scores, want 1: other boards are kept", board, n)
+	}
+
+	var snapshotted int64
+	st.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM leaderboard_snapshot_entries WHERE snapshot_id = ?1`, res.SnapshotID).Scan(&snapshotted)
+	if snapshotted != 3 {
+		t.Errorf("snapshot holds %d entries, want 3", snapshotted)
+	}
+
+	// The per-row deletes are replaced by one resync of the board
+	var ops []string
+	rows, _ := st.db.QueryContext(ctx, `SELECT op || ':' || leaderboard_id FROM score_changes ORDER BY id`)
+	for rows.Next() {
+		var op string
+		rows.Scan(&op)
+		ops = append(ops, op)
+	}
+	rows.Close()
+	if len(ops) != 1 || ops[0] != "resync:level-1" {
+		t.Errorf("change log = %v, want [resync:level-1]", ops)
+	}
+
+	if res, err := st.ResetLeaderboard(ctx, board, false); err != nil || res.Deleted != 1 || res.SnapshotID != 0 {
+		t.Errorf("reset without snapshot = %+v, %v, want 1 deleted and no snapshot", res, err)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	for i, name := range []string{"Alice", "Bob", "Carol"} {
+		st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: name, Score: int64(100 * (i + 1))})
+	}
+	if n, err := st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: board, PlayerName: "Carol"}); err != nil || n != 1 {
+		t.Fatalf("DeleteScore = %d, %v; want 1 row", n, err)
+	}
+	if n, _ := st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: board, PlayerName: "Carol"}); n != 0 {
+		t.Errorf("deleting again affected %d rows, want 0", n)
+	}
+
+	// Gone from every read
+	if _, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: board, PlayerName: "Carol"}); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("GetPlayerScore of a deleted score: error = %v, want ErrNoRows", err)
+	}
+	if n, _ := st.CountScores(ctx, board); n != 2 {
+		t.Errorf("count = %d, want 2", n)
+	}
+	if rank, _ := st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: board, PlayerName: "Bob"}); rank != 1 {
+		t.Errorf("rank of Bob = %d, want 1", rank)
+	}
+	deleted, err := st.GetDeletedScores(ctx, store.GetDeletedScoresParams{LeaderboardID: board, PageSize: 10})
+	if err != nil || len(deleted) != 1 || deleted[0].PlayerName != "Carol" || !deleted[0].DeletedAt.Valid {
+		t.Fatalf("GetDeletedScores = %+v, %v; want Carol", deleted, err)
+	}
+
+	restored, err := st.RestoreScore(ctx, store.RestoreScoreParams{LeaderboardID: board, PlayerName: "Carol"})
+	if err != nil || restored.Score != 300 || restored.DeletedAt.Valid {
+		t.Fatalf("RestoreScore = %+v, %v; want live score 300", restored, err)
+	}
+	if rank, _ := st.GetPlayerRank(ctx, store.GetPlayerRankParams{LeaderboardID: board, PlayerName: "Carol"}); rank != 1 {
+		t.Errorf("rank of restored Carol = %d, want 1", rank)
+	}
+	if _, err := st.RestoreScore(ctx, store.RestoreScoreParams{LeaderboardID: board, PlayerName: "Carol"}); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("restoring a live score: error = %v, want ErrNoRows", err)
+	}
+
+	// A new submission after a delete starts over, even below the deleted best
+	st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: board, PlayerName: "Carol"})
+	if sc, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Carol", Score: 50}); err != nil || sc.Score != 50 {
+		t.Errorf("upsert after delete = %+v, %v; want score 50", sc, err)
+	}
+	st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: board, PlayerName: "Carol"})
+	if sc, err := st.InsertScore(ctx, store.InsertScoreParams{LeaderboardID: board, PlayerName: "Carol", Score: 20}); err != nil || sc.Score != 20 {
+		t.Errorf("insert after delete = %+v, %v; want score 20", sc, err)
+	}
+	if deleted, _ := st.GetDeletedScores(ctx, store.GetDeletedScoresParams{LeaderboardID: board, PageSize: 10}); len(deleted) != 0 {
+		t.Errorf("deleted scores after resubmission = %+v, want none", deleted)
+	}
+
+	// Resets only count live scores
+	st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: board, PlayerName: "Alice"})
+	if res, err := st.ResetLeaderboard(ctx, board, true); err != nil || res.Deleted != 2 {
+		t.Errorf("reset = %+v, %v; want 2 deleted", res, err)
+	}
+	if deleted, _ := st.GetDeletedScores(ctx, store.GetDeletedScoresParams{LeaderboardID: board, PageSize: 10}); len(deleted) != 0 {
+		t.Errorf("deleted scores after reset = %+v, want none", deleted)
+	}
+}
+
+func TestOpenUpgradesSchema(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/old.db"
+
+	// A database created before soft deletes, score metadata and secondary scores
+	st, err := Open(ctx, path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 100})
+	for _, stmt := range []string{
+		`DROP INDEX idx_scores_deleted`,
+		`DROP INDEX idx_scores_ranking`,
+		`DROP INDEX idx_scores_region`,
+		`DROP INDEX idx_scores_platform`,
+		`CREATE INDEX idx_scores_leaderboard ON scores (leaderboard_id, rank_score DESC, achieved_at ASC, player_name)`,
+		`DROP TRIGGER scores_change_insert`,
+		`DROP TRIGGER scores_change_soft_delete`,
+		`DROP TRIGGER scores_change_restore`,
+		`DROP TRIGGER scores_change_update`,
+		`DROP TRIGGER scores_change_delete`,
+		`ALTER TABLE scores DROP COLUMN platform`,
+		`ALTER TABLE score_changes DROP COLUMN platform`,
+		`ALTER TABLE leaderboard_snapshot_entries DROP COLUMN platform`,
+		`ALTER TABLE scores DROP COLUMN country_code`,
+		`ALTER TABLE score_changes DROP COLUMN country_code`,
+		`ALTER TABLE leaderboard_snapshot_entries DROP COLUMN country_code`,
+		`ALTER TABLE scores DROP COLUMN deleted_at`,
+		`ALTER TABLE scores DROP COLUMN metadata`,
+		`ALTER TABLE score_changes DROP COLUMN metadata`,
+		`ALTER TABLE scores DROP COLUMN secondary_score`,
+		`ALTER TABLE scores DROP COLUMN rank_secondary`,
+		`ALTER TABLE score_changes DROP COLUMN secondary_score`,
+		`ALTER TABLE score_changes DROP COLUMN rank_secondary`,
+		`ALTER TABLE leaderboards DROP COLUMN secondary_sort_order`,
+		`ALTER TABLE leaderboard_snapshot_entries DROP COLUMN secondary_score`,
+		`ALTER TABLE leaderboard_snapshot_entries DROP COLUMN rank_secondary`,
+		`CREATE TRIGGER scores_change_insert AFTER INSERT ON scores
+		BEGIN
+			INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, op) VALUES (NEW.leaderboard_id, NEW.player_name, NEW.score, NEW.rank_score, NEW.achieved_at, 'insert');
+		END`,
+		`CREATE TRIGGER scores_change_update AFTER UPDATE ON scores WHEN NEW.score <> OLD.score
+		BEGIN
+			INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, op) VALUES (NEW.leaderboard_id, NEW.player_name, NEW.score, NEW.rank_score, NEW.achieved_at, 'update');
+		END`,
+		`CREATE TRIGGER scores_change_delete AFTER DELETE ON scores
+		BEGIN
+			INSERT INTO score_changes (leaderboard_id, player_name, score, rank_score, achieved_at, op) VALUES (OLD.leaderboard_id, OLD.player_name, OLD.score, OLD.rank_score, OLD.achieved_at, 'delete');
+		END`,
+	} {
+		if _, err := st.db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	st.Close()
+
+	st, err = Open(ctx, path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer st.Close()
+	if n, err := st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: board, PlayerName: "Alice"}); err != nil || n != 1 {
+		t.Errorf("DeleteScore after upgrade = %d, %v; want 1 row", n, err)
+	}
+	if n, _ := st.CountScores(ctx, board); n != 0 {
+		t.Errorf("count after delete = %d, want 0", n)
+	}
+	var deletes int64
+	st.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM score_changes WHERE op = 'delete'`).Scan(&deletes)
+	if deletes != 1 {
+		t.Errorf("logged %d delete changes, want 1: the triggers were not upgraded", deletes)
+	}
+	if sc, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Bob", Score: 50, Metadata: []byte(`{"level":"1"}`)}); err != nil || string(sc.Metadata) != `{"level":"1"}` {
+		t.Errorf("UpsertScore with metadata after upgrade = %s, %v", sc.Metadata, err)
+	}
+	if sc, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Bob", Score: 50, SecondaryScore: 7}); err != nil || sc.SecondaryScore != 7 {
+		t.Errorf("UpsertScore with a secondary score after upgrade = %+v, %v", sc, err)
+	}
+	if sc, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Carol", Score: 70, CountryCode: "FR"}); err != nil || sc.CountryCode != "FR" {
+		t.Errorf("UpsertScore with a country after upgrade = %+v, %v", sc, err)
+	}
+	if sc, err := st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Dave", Score: 60, Platform: "mobile"}); err != nil || sc.Platform != "mobile" {
+		t.Errorf("UpsertScore with a platform after upgrade = %+v, %v", sc, err)
+	}
+	var oldIndex bool
+	st.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM sqlite_schema WHERE type = 'index' AND name = 'idx_scores_leaderboard')`).Scan(&oldIndex)
+	if oldIndex {
+		t.Error("idx_scores_leaderboard was not replaced by idx_scores_ranking")
+	}
+}
+
+func TestPlayerProfiles(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	created, err := st.UpsertPlayerProfile(ctx, store.UpsertPlayerProfileParams{PlayerName: "Alice", DisplayName: "Alice", CountryCode: "FR"})
+	if err != nil {
+		t.Fatalf("upsert profile failed: %s", err)
+	}
+	updated, err := st.UpsertPlayerProfile(ctx, store.UpsertPlayerProfileParams{PlayerName: "Alice", AvatarUrl: "https://example.com/a.png"})
+	if err != nil {
+		t.Fatalf("upsert profile failed: %s", err)
+	}
+	if updated.CountryCode != "" || updated.AvatarUrl != "https://example.com/a.png" || !updated.CreatedAt.Time.Equal(created.CreatedAt.Time) {
+		t.Errorf("updated = %+v, want fields replaced and created_at kept from %+v", updated, created)
+	}
+
+	if _, err := st.UpsertPlayerProfile(ctx, store.UpsertPlayerProfileParams{PlayerName: "Bob", CountryCode: "fr"}); err == nil {
+		t.Error("lowercase country code accepted, want constraint error")
+	}
+	if _, err := st.GetPlayerProfile(ctx, "Nobody"); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("GetPlayerProfile(missing) error = %v, want store.ErrNoRows", err)
+	}
+
+	profiles, err := st.GetPlayerProfiles(ctx, []string{"Alice", "Nobody"})
+	if err != nil {
+		t.Fatalf("get profiles failed: %s", err)
+	}
+	if len(profiles) != 1 || profiles[0].PlayerName != "Alice" {
+		t.Errorf("profiles = %+v, want only Alice", profiles)
+	}
+}
+
+func TestPlayerData(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	for _, b := range []string{board, "level-1", "level-2", "level-3"} {
+		st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: b, PlayerName: "Alice", Score: 100})
+	}
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-1", PlayerName: "Bob", Score: 200})
+	st.UpsertPlayerProfile(ctx, store.UpsertPlayerProfileParams{PlayerName: "Alice", DisplayName: "Alice the Great"})
+	st.RecordDevicePlayer(ctx, store.RecordDevicePlayerParams{DeviceHash: "d1", PlayerName: "Alice"})
+	st.RecordDeviceSubmission(ctx, store.RecordDeviceSubmissionParams{DeviceHash: "d1", PlayerName: "Alice"})
+	if _, err := st.ResetLeaderboard(ctx, "level-2", true); err != nil {
+		t.Fatal(err)
+	}
+	st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: "level-3", PlayerName: "Alice"})
+
+	data, err := st.ExportPlayerData(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("ExportPlayerData failed: %s", err)
+	}
+	// global, level-1 and the deleted level-3; level-2 was reset into a snapshot
+	if len(data.Scores) != 3 || data.Profile == nil || len(data.Devices) != 1 || len(data.DeviceSubmissions) != 1 ||
+		len(data.SnapshotEntries) != 1 || len(data.Changes) != 5 || len(data.Erasures) != 0 {
+		t.Fatalf("export = %+v, want 3 scores, a profile, 1 device, 1 submission, 1 snapshot entry and 5 changes", data)
+	}
+
+	st.db.ExecContext(ctx, `DELETE FROM score_changes WHERE player_name <> 'Alice'`)
+	res, err := st.ErasePlayerData(ctx, "Alice", "req-1")
+	if err != nil {
+		t.Fatalf("ErasePlayerData failed: %s", err)
+	}
+	e := res.Erasure
+	if e.PlayerHash != store.PlayerHash("Alice") || e.RequestID != "req-1" || e.DeletedScores != 3 || e.DeletedRows != 12 {
+		t.Errorf("erasure = %+v, want 3 scores and 12 rows deleted by req-1", e)
+	}
+	if fmt.Sprint(res.Boards) != "[global level-1]" {
+		t.Errorf("boards = %v, want the boards of Alice's live scores", res.Boards)
+	}
+
+	// No change names Alice anymore: her boards are resynced instead
+	var ops []string
+	rows, _ := st.db.QueryContext(ctx, `SELECT op || ':' || leaderboard_id || ':' || player_name FROM score_changes ORDER BY id`)
+	for rows.Next() {
+		var op string
+		rows.Scan(&op)
+		ops = append(ops, op)
+	}
+	rows.Close()
+	if fmt.Sprint(ops) != "[resync:global: resync:level-1:]" {
+		t.Errorf("change log = %v, want a resync of global and level-1", ops)
+	}
+
+	if sc, err := st.GetPlayerScore(ctx, store.GetPlayerScoreParams{LeaderboardID: "level-1", PlayerName: "Bob"}); err != nil || sc.Score != 200 {
+		t.Errorf("Bob's score = %v, %v, want 200: other players are kept", sc.Score, err)
+	}
+	data, err = st.ExportPlayerData(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("ExportPlayerData after erasure failed: %s", err)
+	}
+	if len(data.Scores)+len(data.Devices)+len(data.DeviceSubmissions)+len(data.SnapshotEntries)+len(data.Changes) != 0 || data.Profile != nil {
+		t.Errorf("export after erasure = %+v, want nothing but the erasure", data)
+	}
+	if len(data.Erasures) != 1 || data.Erasures[0].ID != e.ID {
+		t.Errorf("erasures = %+v, want the erasure %d", data.Erasures, e.ID)
+	}
+}
+
+func TestCheckDeviceSubmission(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	check := store.DeviceSubmissionCheck{DeviceHash: "d1", MaxAccounts: 2, MaxSubmissions: 5, Enforce: true}
+	for i, tt := range []struct {
+		player   string
+		exceeded bool
+	}{
+		{"Alice", false},
+		{"Bob", false},
+		{"Carol", true}, // third account
+		{"Alice", false},
+	} {
+		check.PlayerName = tt.player
+		usage, err := st.CheckDeviceSubmission(ctx, check)
+		if err != nil {
+			t.Fatalf("check %d: %s", i, err)
+		}
+		if usage.AccountsExceeded != tt.exceeded || usage.Recorded == tt.exceeded {
+			t.Errorf("check %d (%s) = %+v, want exceeded %v", i, tt.player, usage, tt.exceeded)
+		}
+	}
+
+	// Concurrent submissions cannot all pass the check: 3 were recorded, 2 fit
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	recorded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			usage, err := st.CheckDeviceSubmission(ctx, store.DeviceSubmissionCheck{DeviceHash: "d1", PlayerName: "Alice", MaxSubmissions: 5, Enforce: true})
+			if err != nil {
+				t.Errorf("concurrent check: %s", err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if usage.Recorded {
+				recorded++
+			}
+		}()
+	}
+	wg.Wait()
+	if recorded != 2 {
+		t.Errorf("%d concurrent submissions recorded, want 2", recorded)
+	}
+
+	// Monitor mode records over the limit
+	usage, err := st.CheckDeviceSubmission(ctx, store.DeviceSubmissionCheck{DeviceHash: "d1", PlayerName: "Dave", MaxAccounts: 2, MaxSubmissions: 5})
+	if err != nil || !usage.AccountsExceeded || !usage.SubmissionsExceeded || !usage.Recorded {
+		t.Errorf("monitored check = %+v, %v, want both limits exceeded and recorded", usage, err)
+	}
+
+	// Submissions older than the window are pruned
+	st.db.ExecContext(ctx, `UPDATE device_submissions SET submitted_at = ?1 WHERE player_name = 'Bob'`, toMicros(time.Now().Add(-2*time.Hour)))
+	if n, err := st.PruneDeviceSubmissions(ctx, store.DeviceSubmissionWindow.Seconds()); err != nil || n != 1 {
+		t.Errorf("PruneDeviceSubmissions = %d, %v, want Bob's submission", n, err)
+	}
+	if n, _ := st.CountDeviceSubmissionsSince(ctx, store.CountDeviceSubmissionsSinceParams{DeviceHash: "d1", SubmittedAt: pgtype.Timestamptz{Valid: true}}); n != 5 {
+		t.Errorf("%d submissions left, want 5", n)
+	}
+}
+
+func TestRenamePlayer(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	for b, score := range map[string]int64{board: 100, "level-1": 300, "level-2": 50} {
+		st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: b, PlayerName: "Alice", Score: score})
+	}
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-3", PlayerName: "Alice", Score: 10})
+	st.DeleteScore(ctx, store.DeleteScoreParams{LeaderboardID: "level-3", PlayerName: "Alice"})
+	st.UpsertPlayerProfile(ctx, store.UpsertPlayerProfileParams{PlayerName: "Alice", DisplayName: "Alice the Great"})
+	st.RecordDevicePlayer(ctx, store.RecordDevicePlayerParams{DeviceHash: "d1", PlayerName: "Alice"})
+
+	// changes returns the change log written since the last call
+	changes := func() string {
+		t.Helper()
+		var ops []string
+		rows, err := st.db.QueryContext(ctx, `SELECT op || ':' || leaderboard_id || ':' || player_name || ':' || score FROM score_changes ORDER BY id`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var op string
+			rows.Scan(&op)
+			ops = append(ops, op)
+		}
+		rows.Close()
+		st.db.ExecContext(ctx, `DELETE FROM score_changes`)
+		return fmt.Sprint(ops)
+	}
+	changes()
+
+	if _, err := st.RenamePlayer(ctx, "Nobody", "Carol", false); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("rename of an unknown player: %v, want ErrNoRows", err)
+	}
+
+	res, err := st.RenamePlayer(ctx, "Alice", "Carol", false)
+	if err != nil {
+		t.Fatalf("RenamePlayer failed: %s", err)
+	}
+	if len(res.Scores) != 3 || len(res.Merged) != 0 || res.Profile == nil || res.Profile.DisplayName != "Alice the Great" {
+		t.Errorf("rename = %+v, want 3 scores and Alice's profile moved", res)
+	}
+	if got := changes(); got != "[delete:global:Alice:100 insert:global:Carol:100 delete:level-1:Alice:300 insert:level-1:Carol:300 delete:level-2:Alice:50 insert:level-2:Carol:50]" {
+		t.Errorf("change log = %v, want a delete of Alice and an insert of Carol per board", got)
+	}
+	if _, err := st.RestoreScore(ctx, store.RestoreScoreParams{LeaderboardID: "level-3", PlayerName: "Carol"}); err != nil {
+		t.Errorf("Alice's deleted score was not moved to Carol: %v", err)
+	}
+	if known, _ := st.IsDevicePlayerKnown(ctx, store.IsDevicePlayerKnownParams{DeviceHash: "d1", PlayerName: "Carol"}); !known {
+		t.Error("Alice's device was not moved to Carol")
+	}
+	if data, _ := st.ExportPlayerData(ctx, "Alice"); len(data.Scores)+len(data.Devices) != 0 || data.Profile != nil {
+		t.Errorf("Alice after the rename = %+v, want nothing", data)
+	}
+	changes()
+
+	// Dave merges into Carol: his better score wins on level-1 only
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-1", PlayerName: "Dave", Score: 500})
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-2", PlayerName: "Dave", Score: 20})
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-4", PlayerName: "Dave", Score: 70})
+	changes()
+	if _, err := st.RenamePlayer(ctx, "Dave", "Carol", false); !errors.Is(err, store.ErrPlayerExists) {
+		t.Fatalf("rename to a player in use: %v, want ErrPlayerExists", err)
+	}
+	res, err = st.RenamePlayer(ctx, "Dave", "Carol", true)
+	if err != nil {
+		t.Fatalf("RenamePlayer with merge failed: %s", err)
+	}
+	best := map[string]int64{}
+	for _, sc := range res.Scores {
+		best[sc.LeaderboardID] = sc.Score
+	}
+	if fmt.Sprint(best) != "map[level-1:500 level-2:50 level-4:70]" || fmt.Sprint(res.Merged) != "[level-1 level-2]" {
+		t.Errorf("merge = %v, merged %v, want the best of both names per board", best, res.Merged)
+	}
+	if got := changes(); got != "[delete:level-1:Dave:500 update:level-1:Carol:500 delete:level-2:Dave:20 delete:level-4:Dave:70 insert:level-4:Carol:70]" {
+		t.Errorf("change log = %v, want Dave deleted and Carol updated only where he was better", got)
+	}
+	top, _ := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: "level-1", PageSize: 10})
+	if len(top) != 1 || top[0].PlayerName != "Carol" || top[0].Score != 500 {
+		t.Errorf("level-1 = %+v, want Carol alone with 500", top)
+	}
+}
+
+func TestBanPlayer(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Mallory", Score: 900})
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: "level-1", PlayerName: "Mallory", Score: 500})
+	st.UpsertScore(ctx, store.UpsertScoreParams{LeaderboardID: board, PlayerName: "Alice", Score: 100})
+	st.db.ExecContext(ctx, `DELETE FROM score_changes`)
+
+	if _, err := st.GetBannedPlayer(ctx, "Mallory"); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("ban of a player not banned: %v, want ErrNoRows", err)
+	}
+	res, err := st.BanPlayer(ctx, "Mallory", "cheating", false)
+	if err != nil {
+		t.Fatalf("BanPlayer failed: %s", err)
+	}
+	if res.Ban.PlayerName != "Mallory" || res.Ban.Reason != "cheating" || !res.Ban.BannedAt.Valid || len(res.Purged) != 0 {
+		t.Errorf("ban = %+v, want Mallory banned for cheating without purge", res)
+	}
+	if top, _ := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: board, PageSize: 10}); len(top) != 2 {
+		t.Errorf("a ban without purge deleted scores: %+v", top)
+	}
+
+	// Banning again replaces the reason, and purges this time
+	res, err = st.BanPlayer(ctx, "Mallory", "cheating again", true)
+	if err != nil {
+		t.Fatalf("BanPlayer with purge failed: %s", err)
+	}
+	slices.Sort(res.Purged)
+	if fmt.Sprint(res.Purged) != "[global level-1]" {
+		t.Errorf("purged = %v, want both of Mallory's boards", res.Purged)
+	}
+	var deletes int
+	st.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM score_changes WHERE op = 'delete' AND player_name = 'Mallory'`).Scan(&deletes)
+	if deletes != 2 {
+		t.Errorf("change log has %d deletes of Mallory, want 2", deletes)
+	}
+	if top, _ := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: board, PageSize: 10}); len(top) != 1 || top[0].PlayerName != "Alice" {
+		t.Errorf("top after the purge = %+v, want Alice alone", top)
+	}
+
+	st.BanPlayer(ctx, "Trudy", "", false)
+	bans, err := st.ListBannedPlayers(ctx)
+	if err != nil || len(bans) != 2 {
+		t.Fatalf("ListBannedPlayers = %+v, %v, want 2 bans", bans, err)
+	}
+	if ban, err := st.GetBannedPlayer(ctx, "Mallory"); err != nil || ban.Reason != "cheating again" {
+		t.Errorf("Mallory's ban = %+v, %v, want the new reason", ban, err)
+	}
+
+	if err := st.UnbanPlayer(ctx, "Mallory"); err != nil {
+		t.Fatalf("UnbanPlayer failed: %s", err)
+	}
+	if err := st.UnbanPlayer(ctx, "Mallory"); !errors.Is(err, store.ErrNoRows) {
+		t.Errorf("second unban: %v, want ErrNoRows", err)
+	}
+	if _, err := st.RestoreScore(ctx, store.RestoreScoreParams{LeaderboardID: board, PlayerName: "Mallory"}); err != nil {
+		t.Errorf("purged score cannot be restored: %v", err)
+	}
+}
+
+func TestArchiveScores(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	st.UpsertLeaderboard(ctx, store.UpsertLeaderboardParams{LeaderboardID: "lap-1", SortOrder: "asc", SecondarySortOrder: "desc"})
+	for _, p := range []store.UpsertScoreParams{
+		{LeaderboardID: board, PlayerName: "Alice", Score: 100},
+		{LeaderboardID: board, PlayerName: "Bob", Score: 5},
+		{LeaderboardID: board, PlayerName: "Carol", Score: 50},
+		{LeaderboardID: "lap-1", PlayerName: "Dave", Score: 30},
+		{LeaderboardID: "lap-1", PlayerName: "Eve", Score: 500},
+	} {
+		if _, err := st.UpsertScore(ctx, p); err != nil {
+			t.Fatalf("upsert failed: %s", err)
+		}
+	}
+	st.db.ExecContext(ctx, `UPDATE scores SET updated_at = ?1 WHERE player_name = 'Alice'`, toMicros(time.Now().Add(-48*time.Hour)))
+	st.db.ExecContext(ctx, `DELETE FROM score_changes`)
+
+	// Alice is stale, Bob below the floor and Eve, on an ascending board, above it
+	policy := store.ArchivePolicy{UpdatedBefore: time.Now().Add(-24 * time.Hour), ScoreFloor: 40, Limit: 2, DryRun: true}
+
+	// The preview lists them oldest first, a page at a time
+	var listed []string
+	var after *store.ArchiveCandidate
+	for {
+		page, err := st.ListArchiveCandidates(ctx, store.ListArchiveCandidatesParams{Policy: policy, After: after, Limit: 2})
+		if err != nil {
+			t.Fatalf("ListArchiveCandidates failed: %s", err)
+		}
+		for _, c := range page {
+			listed = append(listed, fmt.Sprintf("%s:%t", c.PlayerName, c.BelowFloor))
+		}
+		if len(page) < 2 {
+			break
+		}
+		after = &page[len(page)-1]
+	}
+	if len(listed) != 3 || listed[0] != "Alice:false" || !slices.Contains(listed, "Bob:true") || !slices.Contains(listed, "Eve:true") {
+		t.Errorf("candidates = %v, want Alice first, then Bob and Eve below the floor", listed)
+	}
+
+	res, err := st.ArchiveScores(ctx, policy)
+	if err != nil {
+		t.Fatalf("dry run failed: %s", err)
+	}
+	if fmt.Sprint(res.Boards) != "[{global 1 1} {lap-1 0 1}]" {
+		t.Errorf("dry run = %v, want Alice and Bob on global, Eve on lap-1", res.Boards)
+	}
+	if n, _ := st.CountScores(ctx, board); n != 3 {
+		t.Errorf("global holds %d scores after a dry run, want 3", n)
+	}
+
+	policy.DryRun = false
+	var archived int64
+	for _, want := range []int64{2, 1, 0} {
+		res, err := st.ArchiveScores(ctx, policy)
+		if err != nil {
+			t.Fatalf("ArchiveScores failed: %s", err)
+		}
+		if res.Total() != want {
+			t.Errorf("batch archived %d scores, want %d", res.Total(), want)
+		}
+		archived += res.Total()
+	}
+	if archived != 3 {
+		t.Fatalf("archived %d scores, want 3", archived)
+	}
+	top, _ := st.GetTopScores(ctx, store.GetTopScoresParams{LeaderboardID: board, PageSize: 10})
+	if len(top) != 1 || top[0].PlayerName != "Carol" {
+		t.Errorf("global top = %+v, want Carol only", top)
+	}
+	if n, _ := st.CountScores(ctx, "lap-1"); n != 1 {
+		t.Errorf("lap-1 holds %d scores, want Dave only", n)
+	}
+
+	// Listeners see resyncs instead of the deletes
+	var ops []string
+	rows, _ := st.db.QueryContext(ctx, `SELECT DISTINCT op FROM score_changes`)
+	for rows.Next() {
+		var op string
+		rows.Scan(&op)
+		ops = append(ops, op)
+	}
+	rows.Close()
+	if fmt.Sprint(ops) != "[resync]" {
+		t.Errorf("change ops = %v, want resyncs only", ops)
+	}
+
+	// Archived scores belong to the player's data
+	data, err := st.ExportPlayerData(ctx, "Alice")
+	if err != nil {
+		t.Fatalf("ExportPlayerData failed: %s", err)
+	}
+	if len(data.Scores) != 0 || len(data.ArchivedScores) != 1 {
+		t.Fatalf("export = %+v, want Alice's score in the archive", data)
+	}
+	if a := data.ArchivedScores[0]; a.Score != 100 || a.Reason != store.ArchiveReasonRetention || !a.ArchivedAt.Valid {
+		t.Errorf("archived score = %+v, want 100 archived for retention", a)
+	}
+	if res, err := st.ErasePlayerData(ctx, "Alice", "req-1"); err != nil || res.Erasure.DeletedRows != 1 {
+		t.Errorf("erasure = %+v, %v, want the archived score deleted", res.Erasure, err)
+	}
+	if data, _ := st.ExportPlayerData(ctx, "Alice"); len(data.ArchivedScores) != 0 {
+		t.Errorf("archived scores after erasure = %+v, want none", data.ArchivedScores)
+	}
+}
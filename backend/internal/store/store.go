@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -10,14 +11,20 @@ import (
 // Store wraps the database connection pool and provides query methods
 type Store struct {
 	pool *pgxpool.Pool
+	db   *guardedDB // the pool, under the query timeout
 	*Queries
+
+	replicaPool *pgxpool.Pool // nil without a read replica
+	replica     *replica
 }
 
 // NewStore creates a new Store instance
 func NewStore(pool *pgxpool.Pool) *Store {
+	db := &guardedDB{db: pool}
 	return &Store{
 		pool:    pool,
-		Queries: New(pool),
+		db:      db,
+		Queries: New(db),
 	}
 }
 
@@ -26,9 +33,12 @@ func (s *Store) Pool() *pgxpool.Pool {
 	return s.pool
 }
 
-// Close closes the database connection pool
+// Close closes the database connection pools
 func (s *Store) Close() {
 	s.pool.Close()
+	if s.replicaPool != nil {
+		s.replicaPool.Close()
+	}
 }
 
 // Ping verifies the database connection is alive
@@ -37,7 +47,50 @@ func (s *Store) Ping(ctx context.Context) error {
 }
 
 // NewPool creates a new PostgreSQL connection pool
-func NewPool(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
+func NewPool(ctx context.Context, databaseURL string, opts ...PoolOption) (*pgxpool.Pool, error) {
+	pool, err := newPool(ctx, databaseURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify connection
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("unable to ping database: %w", err)
+	}
+
+	return pool, nil
+}
+
+// NewReadPool creates the connection pool of a read replica. Unlike NewPool it
+// does not wait for the database: a replica that is down at startup is only
+// skipped by reads until it comes up.
+func NewReadPool(ctx context.Context, databaseURL string, opts ...PoolOption) (*pgxpool.Pool, error) {
+	return newPool(ctx, databaseURL, opts)
+}
+
+// PoolSettings size a connection pool and bound the life of its connections
+type PoolSettings struct {
+	MaxConns          int32
+	MinConns          int32         // kept open even when idle
+	MaxConnLifetime   time.Duration // connections older than this are closed once released
+	MaxConnIdleTime   time.Duration // idle connections older than this are closed, down to MinConns
+	HealthCheckPeriod time.Duration // how often idle connections are checked
+}
+
+// WithPoolSettings replaces the default pool settings (25 connections at most,
+// 5 at least, and the pgx defaults otherwise)
+func WithPoolSettings(p PoolSettings) PoolOption {
+	return func(config *pgxpool.Config) {
+		config.MaxConns = p.MaxConns
+		config.MinConns = p.MinConns
+		config.MaxConnLifetime = p.MaxConnLifetime
+		config.MaxConnIdleTime = p.MaxConnIdleTime
+		config.HealthCheckPeriod = p.HealthCheckPeriod
+	}
+}
+
+func newPool(ctx context.Context, databaseURL string, opts []PoolOption) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database URL: %w", err)
@@ -46,17 +99,13 @@ func NewPool(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
 	// Configure connection pool settings
 	config.MaxConns = 25
 	config.MinConns = 5
+	for _, opt := range opts {
+		opt(config)
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
-
-	// Verify connection
-	if err := pool.Ping(ctx); err != nil {
-		pool.Close()
-		return nil, fmt.Errorf("unable to ping database: %w", err)
-	}
-
 	return pool, nil
 }
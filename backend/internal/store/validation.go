@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Default bounds of player names, as created by the migrations
+const (
+	DefaultMinPlayerNameLength = 1
+	DefaultMaxPlayerNameLength = 20
+)
+
+// Validation are the input bounds the database enforces with CHECK constraints,
+// next to the service checks, so rows written around the service obey them too
+type Validation struct {
+	MinPlayerNameLength int   // 0 uses DefaultMinPlayerNameLength
+	MaxPlayerNameLength int   // 0 uses DefaultMaxPlayerNameLength
+	MaxScore            int64 // 0 leaves scores unbounded
+}
+
+// WithDefaults returns v with its zero bounds replaced by the defaults
+func (v Validation) WithDefaults() Validation {
+	if v.MinPlayerNameLength <= 0 {
+		v.MinPlayerNameLength = DefaultMinPlayerNameLength
+	}
+	if v.MaxPlayerNameLength <= 0 {
+		v.MaxPlayerNameLength = DefaultMaxPlayerNameLength
+	}
+	return v
+}
+
+// ValidationConstraint is a CHECK constraint derived from Validation
+type ValidationConstraint struct {
+	Table    string
+	Name     string
+	Column   string
+	Length   bool  // the bounds apply to the length of Column rather than its value
+	Min, Max int64 // Max 0 when the constraint is dropped
+}
+
+// Expr returns the check expression of c, "" when it is dropped. length is the
+// length function of the SQL dialect and column the reference to Column.
+func (c ValidationConstraint) Expr(length, column string) string {
+	if c.Max == 0 {
+		return ""
+	}
+	if c.Length {
+		column = length + "(" + column + ")"
+	}
+	if c.Min == 0 {
+		return fmt.Sprintf("%s <= %d", column, c.Max)
+	}
+	return fmt.Sprintf("%s >= %d AND %s <= %d", column, c.Min, column, c.Max)
+}
+
+// Constraints returns the CHECK constraints enforcing v
+func (v Validation) Constraints() []ValidationConstraint {
+	v = v.WithDefaults()
+	minName, maxName := int64(v.MinPlayerNameLength), int64(v.MaxPlayerNameLength)
+	return []ValidationConstraint{
+		{Table: "scores", Name: "player_name_length", Column: "player_name", Length: true, Min: minName, Max: maxName},
+		{Table: "players", Name: "players_player_name_length", Column: "player_name", Length: true, Min: minName, Max: maxName},
+		{Table: "scores", Name: "score_max", Column: "score", Max: v.MaxScore},
+	}
+}
+
+// constraintCommentQuery reads the comment of a constraint, which holds the
+// expression it was created from by ApplyValidation
+const constraintCommentQuery = `
+	SELECT obj_description(oid, 'pg_constraint')
+	FROM pg_constraint
+	WHERE conrelid = $1::regclass AND conname = $2`
+
+// ApplyValidation recreates the validation constraints of the database from v.
+// A constraint keeps its expression in its comment and is only replaced when
+// that changes, so restarts with the same settings take no table lock.
+// Constraints are added NOT VALID: rows written under looser bounds are kept,
+// only new writes are checked.
+func (s *Store) ApplyValidation(ctx context.Context, v Validation) error {
+	tx, err := s.begin(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx) // no-op after Commit
+
+	// Fail instead of queueing writes behind the ALTER while long reads hold the tables
+	if _, err := tx.Exec(ctx, `SET LOCAL lock_timeout = '5s'`); err != nil {
+		return fmt.Errorf("set lock timeout: %w", err)
+	}
+	for _, c := range v.Constraints() {
+		check := c.Expr("char_length", c.Column)
+		var comment *string
+		err := tx.QueryRow(ctx, constraintCommentQuery, c.Table, c.Name).Scan(&comment)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("read constraint %s: %w", c.Name, err)
+		}
+		exists := err == nil
+		// Constraints created by the migrations have no comment and are replaced once
+		if (check == "" && !exists) || (check != "" && comment != nil && *comment == check) {
+			continue
+		}
+
+		alter := fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s`, c.Table, c.Name)
+		if check != "" {
+			alter += fmt.Sprintf(`, ADD CONSTRAINT %s CHECK (%s) NOT VALID`, c.Name, check)
+		}
+		if _, err := tx.Exec(ctx, alter); err != nil {
+			return fmt.Errorf("replace constraint %s: %w", c.Name, err)
+		}
+		if check != "" {
+			if _, err := tx.Exec(ctx, fmt.Sprintf(`COMMENT ON CONSTRAINT %s ON %s IS '%s'`, c.Name, c.Table, check)); err != nil {
+				return fmt.Errorf("comment constraint %s: %w", c.Name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package grpc
+
+import "github.com/yourorg/leaderboard/internal/notify"
+
+// coalesceKey identifies the player a change is about
+type coalesceKey struct {
+	board  string
+	player string
+}
+
+// coalescer merges the score changes of a player received within a window into
+// the latest one. Changes are released in the order their player first changed
+// in the window, so a burst of one player does not delay the others.
+type coalescer struct {
+	order  []coalesceKey
+	latest map[coalesceKey]notify.ScoreChange
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{latest: make(map[coalesceKey]notify.ScoreChange)}
+}
+
+// add queues a change, replacing a pending change of the same player on the same
+// board. It reports whether a pending change was replaced.
+func (c *coalescer) add(change notify.ScoreChange) bool {
+	key := coalesceKey{change.LeaderboardID, change.PlayerName}
+	_, merged := c.latest[key]
+	if !merged {
+		c.order = append(c.order, key)
+	}
+	c.latest[key] = change
+	return merged
+}
+
+// drop discards the pending changes of board, or of every board when board is
+// empty, e.g. when its subscribers are about to be resynced from the database
+func (c *coalescer) drop(board string) int {
+	kept := c.order[:0]
+	for _, key := range c.order {
+		if board == "" || key.board == board {
+			delete(c.latest, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	dropped := len(c.order) - len(kept)
+	c.order = kept
+	return dropped
+}
+
+// take returns the pending changes and empties the coalescer
+func (c *coalescer) take() []notify.ScoreChange {
+	changes := make([]notify.ScoreChange, len(c.order))
+	for i, key := range c.order {
+		changes[i] = c.latest[key]
+	}
+	c.order = c.order[:0]
+	clear(c.latest)
+	return changes
+}
+
+// len returns the number of pending changes
+func (c *coalescer) len() int {
+	return len(c.order)
+}
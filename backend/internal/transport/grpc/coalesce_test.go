@@ -0,0 +1,99 @@
+package grpc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/notify"
+)
+
+func change(board, player string, score int64, op string) notify.ScoreChange {
+	return notify.ScoreChange{LeaderboardID: board, PlayerName: player, Score: score, Op: op}
+}
+
+func TestCoalescer(t *testing.T) {
+	c := newCoalescer()
+	for i, ch := range []notify.ScoreChange{
+		change("global", "Alice", 100, "insert"),
+		change("global", "Bob", 50, "insert"),
+		change("global", "Alice", 120, "update"),
+		change("level-1", "Alice", 10, "insert"), // another board, another player
+		change("global", "Bob", 0, "delete"),
+		change("global", "Alice", 150, "update"),
+	} {
+		merged := c.add(ch)
+		if want := i == 2 || i == 4 || i == 5; merged != want {
+			t.Errorf("add #%d merged = %t, want %t", i, merged, want)
+		}
+	}
+
+	got := c.take()
+	want := []notify.ScoreChange{
+		change("global", "Alice", 150, "update"),
+		change("global", "Bob", 0, "delete"),
+		change("level-1", "Alice", 10, "insert"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("take() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("take()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if c.len() != 0 || len(c.take()) != 0 {
+		t.Error("coalescer not empty after take")
+	}
+
+	c.add(change("global", "Alice", 1, "insert"))
+	c.add(change("level-1", "Bob", 2, "insert"))
+	c.add(change("level-2", "Carol", 3, "insert"))
+	if n := c.drop("level-1"); n != 1 || c.len() != 2 {
+		t.Errorf("drop(level-1) = %d leaving %d, want 1 leaving 2", n, c.len())
+	}
+	c.add(change("level-1", "Bob", 4, "insert"))
+	if n := c.drop(""); n != 3 || c.len() != 0 {
+		t.Errorf("drop(all) = %d leaving %d, want 3 leaving 0", n, c.len())
+	}
+}
+
+func TestBroadcastCoalescesChanges(t *testing.T) {
+	changes := make(chan notify.ScoreChange)
+	s := newHub()
+	s.changes = changes
+	s.coalesce = 50 * time.Millisecond
+	sub := make(chan *pb.LeaderboardUpdate, 10)
+	s.addSubscriber("level-1", sub, &subscriber{})
+	done := make(chan struct{})
+	go func() {
+		s.broadcastNotifications()
+		close(done)
+	}()
+
+	// Deletions need no service to enrich them
+	for score := range int64(5) {
+		changes <- change("level-1", "Alice", score, "delete")
+	}
+	changes <- change("level-1", "Bob", 7, "delete")
+	select {
+	case u := <-sub:
+		if u.Changed.PlayerName != "Alice" || u.Changed.Score != 4 {
+			t.Errorf("first update = %v, want Alice's latest score", u)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no update after the window")
+	}
+	if u := <-sub; u.Changed.PlayerName != "Bob" {
+		t.Errorf("second update = %v, want Bob", u)
+	}
+
+	// Pending changes are flushed when the source closes
+	changes <- change("level-1", "Carol", 1, "delete")
+	close(changes)
+	<-done
+	if len(sub) != 1 {
+		t.Errorf("%d updates after close, want Carol's", len(sub))
+	}
+}
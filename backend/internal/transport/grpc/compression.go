@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip" // registers the gzip compressor
+	"google.golang.org/protobuf/proto"
+)
+
+// ZstdName is the name of the zstd compressor in grpc-encoding headers
+const ZstdName = "zstd"
+
+// RegisterZstd registers the zstd compressor, for requests and responses. Like
+// every compressor registration, it must happen before the server starts.
+func RegisterZstd() {
+	encoding.RegisterCompressor(&zstdCompressor{})
+}
+
+// UnaryCompression compresses the responses of unary RPCs of at least minSize
+// bytes with the named compressor, when the client accepts it. gRPC otherwise
+// answers with the compressor of the request, and most clients send requests
+// uncompressed.
+func UnaryCompression(name string, minSize int) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if msg, ok := resp.(proto.Message); ok && err == nil {
+			setSendCompressor(ctx, name, minSize, msg)
+		}
+		return resp, err
+	}
+}
+
+// StreamCompression compresses the messages of a server stream with the named
+// compressor, when the client accepts it and the first message, such as the
+// snapshot of a leaderboard stream, holds at least minSize bytes. A stream has a
+// single compressor: the decision made on the first message holds for the rest.
+func StreamCompression(name string, minSize int) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &compressingStream{ServerStream: ss, name: name, minSize: minSize})
+	}
+}
+
+// compressingStream picks the compressor of a stream when its first message is sent
+type compressingStream struct {
+	grpc.ServerStream
+	name    string
+	minSize int
+	sent    bool // SendMsg serializes calls, so no lock is needed
+}
+
+func (s *compressingStream) SendMsg(m any) error {
+	if !s.sent {
+		s.sent = true
+		if msg, ok := m.(proto.Message); ok {
+			setSendCompressor(s.Context(), s.name, s.minSize, msg)
+		}
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// setSendCompressor compresses the response of a call carrying msg when msg is
+// large enough and the client accepts a suitable compressor
+func setSendCompressor(ctx context.Context, name string, minSize int, msg proto.Message) {
+	if proto.Size(msg) < minSize {
+		return
+	}
+	accepted, err := grpc.ClientSupportedCompressors(ctx)
+	if err != nil {
+		return
+	}
+	if name = pickCompressor(name, accepted); name != "" {
+		// Fails only once headers are sent, leaving the response as it was
+		_ = grpc.SetSendCompressor(ctx, name)
+	}
+}
+
+// pickCompressor returns the compressor to answer with: the preferred one when
+// the client accepts it, gzip for a client preferring zstd that only accepts
+// gzip, or "" to leave the response uncompressed
+func pickCompressor(preferred string, accepted []string) string {
+	switch {
+	case slices.Contains(accepted, preferred):
+		return preferred
+	case preferred == ZstdName && slices.Contains(accepted, gzip.Name):
+		return gzip.Name
+	}
+	return ""
+}
+
+// zstdCompressor implements the zstd grpc-encoding. Encoders and decoders are
+// pooled: creating them costs far more than the messages they handle.
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+var _ encoding.Compressor = (*zstdCompressor)(nil)
+
+func (c *zstdCompressor) Name() string {
+	return ZstdName
+}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	enc, ok := c.encoders.Get().(*zstd.Encoder)
+	if !ok {
+		var err error
+		if enc, err = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1)); err != nil {
+			return nil, err
+		}
+	}
+	enc.Reset(w)
+	return &zstdWriter{Encoder: enc, pool: &c.encoders}, nil
+}
+
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	dec, ok := c.decoders.Get().(*zstd.Decoder)
+	if !ok {
+		// gRPC bounds the decompressed message; the window is bounded here,
+		// with the largest GRPC_MAX_RECV_MSG_SIZE allowed
+		var err error
+		if dec, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(64<<20)); err != nil {
+			return nil, err
+		}
+	}
+	if err := dec.Reset(r); err != nil {
+		c.decoders.Put(dec)
+		return nil, err
+	}
+	return &zstdReader{dec: dec, pool: &c.decoders}, nil
+}
+
+// zstdWriter returns its encoder to the pool when closed
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (w *zstdWriter) Close() error {
+	err := w.Encoder.Close()
+	w.pool.Put(w.Encoder)
+	return err
+}
+
+// zstdReader returns its decoder to the pool once the message is read
+type zstdReader struct {
+	dec  *zstd.Decoder
+	pool *sync.Pool
+}
+
+func (r *zstdReader) Read(p []byte) (int, error) {
+	if r.dec == nil {
+		return 0, io.EOF
+	}
+	n, err := r.dec.Read(p)
+	if err == io.EOF {
+		r.pool.Put(r.dec)
+		r.dec = nil
+	}
+	return n, err
+}
@@ -0,0 +1,131 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestPickCompressor(t *testing.T) {
+	tests := []struct {
+		preferred string
+		accepted  []string
+		want      string
+	}{
+		{"gzip", []string{"gzip"}, "gzip"},
+		{"gzip", []string{"zstd"}, ""},
+		{"zstd", []string{"gzip", "zstd"}, "zstd"},
+		{"zstd", []string{"gzip"}, "gzip"},
+		{"zstd", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := pickCompressor(tt.preferred, tt.accepted); got != tt.want {
+			t.Errorf("pickCompressor(%q, %v) = %q, want %q", tt.preferred, tt.accepted, got, tt.want)
+		}
+	}
+}
+
+// encodingRecorder records the grpc-encoding of the responses a client receives
+type encodingRecorder struct {
+	mu        sync.Mutex
+	encodings []string
+}
+
+func (r *encodingRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (r *encodingRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if h, ok := s.(*stats.InHeader); ok {
+		r.mu.Lock()
+		r.encodings = append(r.encodings, h.Compression)
+		r.mu.Unlock()
+	}
+}
+
+func (r *encodingRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (r *encodingRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+func (r *encodingRecorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.encodings) == 0 {
+		return ""
+	}
+	return r.encodings[len(r.encodings)-1]
+}
+
+func TestCompression(t *testing.T) {
+	RegisterZstd()
+
+	// A serving status is a few bytes: 1 compresses it, 1024 does not
+	for _, tt := range []struct {
+		name    string
+		minSize int
+		want    string
+	}{
+		{"zstd", 1, ZstdName},
+		{"gzip", 1, gzip.Name},
+		{"zstd", 1024, ""},
+	} {
+		lis := bufconn.Listen(1 << 20)
+		srv := grpc.NewServer(
+			grpc.UnaryInterceptor(UnaryCompression(tt.name, tt.minSize)),
+			grpc.StreamInterceptor(StreamCompression(tt.name, tt.minSize)),
+		)
+		healthpb.RegisterHealthServer(srv, health.NewServer())
+		go srv.Serve(lis)
+
+		rec := &encodingRecorder{}
+		conn, err := grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithStatsHandler(rec),
+		)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		client := healthpb.NewHealthClient(conn)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// An uncompressed request, then one compressed with the chosen compressor
+		if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatalf("%s/%d: check: %v", tt.name, tt.minSize, err)
+		}
+		if got := rec.last(); got != tt.want {
+			t.Errorf("%s/%d: check answered with %q, want %q", tt.name, tt.minSize, got, tt.want)
+		}
+		if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.UseCompressor(tt.name)); err != nil {
+			t.Fatalf("%s/%d: compressed check: %v", tt.name, tt.minSize, err)
+		}
+
+		stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("%s/%d: watch: %v", tt.name, tt.minSize, err)
+		}
+		if _, err := stream.Recv(); err != nil {
+			t.Fatalf("%s/%d: watch: %v", tt.name, tt.minSize, err)
+		}
+		if got := rec.last(); got != tt.want {
+			t.Errorf("%s/%d: watch answered with %q, want %q", tt.name, tt.minSize, got, tt.want)
+		}
+
+		cancel()
+		conn.Close()
+		srv.Stop()
+	}
+}
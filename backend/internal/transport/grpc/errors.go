@@ -0,0 +1,213 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorDomain is the domain of the ErrorInfo details attached to every error status
+const ErrorDomain = "leaderboard.v1"
+
+// Reasons of the ErrorInfo details: stable, machine-readable error codes clients
+// can map to localized messages instead of parsing status messages
+const (
+	ReasonInvalidPlayerName    = "INVALID_PLAYER_NAME"
+	ReasonInvalidScore         = "INVALID_SCORE"
+	ReasonInvalidLeaderboardID = "INVALID_LEADERBOARD_ID"
+	ReasonInvalidTimestamp     = "INVALID_TIMESTAMP"
+	ReasonInvalidDeviceID      = "INVALID_DEVICE_ID"
+	ReasonInvalidMetadata      = "INVALID_METADATA"
+	ReasonInvalidCountry       = "INVALID_COUNTRY"
+	ReasonInvalidPlatform      = "INVALID_PLATFORM"
+	ReasonInvalidPageToken     = "INVALID_PAGE_TOKEN"
+	ReasonInvalidProfile       = "INVALID_PROFILE"
+	ReasonInvalidSortOrder     = "INVALID_SORT_ORDER"
+	ReasonInvalidControl       = "INVALID_CONTROL_ACTION"
+	ReasonInvalidReceipt       = "INVALID_RECEIPT"
+	ReasonInvalidFieldMask     = "INVALID_FIELD_MASK"
+	ReasonMissingField         = "MISSING_FIELD"
+	ReasonBatchTooLarge        = "BATCH_TOO_LARGE"
+	ReasonTooManyPlayers       = "TOO_MANY_PLAYERS"
+	ReasonDeviceLimitExceeded  = "DEVICE_LIMIT_EXCEEDED"
+	ReasonInvalidSignature     = "INVALID_SIGNATURE"
+	ReasonSortOrderLocked      = "SORT_ORDER_LOCKED"
+	ReasonLeaderboardClosed    = "LEADERBOARD_CLOSED"
+	ReasonOfflineSyncDisabled  = "OFFLINE_SYNC_DISABLED"
+	ReasonReceiptsDisabled     = "RECEIPTS_DISABLED"
+	ReasonInvalidConfirmation  = "INVALID_CONFIRMATION"
+	ReasonAdminDisabled        = "ADMIN_DISABLED"
+	ReasonAdminUnauthorized    = "ADMIN_UNAUTHORIZED"
+	ReasonPlayerNotFound       = "PLAYER_NOT_FOUND"
+	ReasonPlayerExists         = "PLAYER_EXISTS"
+	ReasonPlayerBanned         = "PLAYER_BANNED"
+	ReasonOverloaded           = "OVERLOADED"
+	ReasonTooManySubscribers   = "TOO_MANY_SUBSCRIBERS"
+	ReasonStreamQuotaExceeded  = "STREAM_QUOTA_EXCEEDED"
+	ReasonStreamDisconnected   = "STREAM_DISCONNECTED"
+	ReasonSubscriberNotFound   = "SUBSCRIBER_NOT_FOUND"
+	ReasonInvalidPeerAddress   = "INVALID_PEER_ADDRESS"
+	ReasonInvalidStreamQuota   = "INVALID_STREAM_QUOTA"
+	ReasonInvalidCheckpoint    = "INVALID_CHECKPOINT"
+	ReasonInvalidPreviewDays   = "INVALID_PREVIEW_DAYS"
+	ReasonArchiveDisabled      = "ARCHIVE_DISABLED"
+	ReasonChangesUnavailable   = "CHANGES_UNAVAILABLE"
+	ReasonTimeout              = "TIMEOUT"
+	ReasonUnavailable          = "UNAVAILABLE"
+	ReasonInternal             = "INTERNAL"
+)
+
+// serviceError describes how a service error is reported: its status code,
+// reason and, for invalid arguments, the request field at fault
+type serviceError struct {
+	err    error
+	code   codes.Code
+	reason string
+	field  string
+}
+
+// serviceErrors maps the service's sentinel errors to their status.
+// ErrOverloaded is handled by overloaded, which also sets retry hints.
+var serviceErrors = []serviceError{
+	{service.ErrInvalidPlayerName, codes.InvalidArgument, ReasonInvalidPlayerName, "player_name"},
+	{service.ErrInvalidScore, codes.InvalidArgument, ReasonInvalidScore, "score"},
+	{service.ErrInvalidLeaderboardID, codes.InvalidArgument, ReasonInvalidLeaderboardID, "leaderboard_id"},
+	{service.ErrInvalidDeviceID, codes.InvalidArgument, ReasonInvalidDeviceID, "device_id"},
+	{service.ErrInvalidMetadata, codes.InvalidArgument, ReasonInvalidMetadata, "metadata"},
+	{service.ErrInvalidCountry, codes.InvalidArgument, ReasonInvalidCountry, ""},
+	{service.ErrInvalidPlatform, codes.InvalidArgument, ReasonInvalidPlatform, "platform"},
+	{service.ErrInvalidPageToken, codes.InvalidArgument, ReasonInvalidPageToken, "page_token"},
+	{service.ErrInvalidProfile, codes.InvalidArgument, ReasonInvalidProfile, ""},
+	{service.ErrInvalidSortOrder, codes.InvalidArgument, ReasonInvalidSortOrder, "leaderboard.sort_order"},
+	{service.ErrBatchTooLarge, codes.InvalidArgument, ReasonBatchTooLarge, "runs"},
+	{service.ErrTooManyPlayers, codes.InvalidArgument, ReasonTooManyPlayers, "player_names"},
+	{service.ErrInvalidReceipt, codes.InvalidArgument, ReasonInvalidReceipt, "receipt"},
+	{service.ErrInvalidFieldMask, codes.InvalidArgument, ReasonInvalidFieldMask, "read_mask"},
+	{service.ErrInvalidCheckpoint, codes.InvalidArgument, ReasonInvalidCheckpoint, "since_seq"},
+	{service.ErrDeviceLimitExceeded, codes.ResourceExhausted, ReasonDeviceLimitExceeded, ""},
+	{service.ErrInvalidSignature, codes.Unauthenticated, ReasonInvalidSignature, "signature"},
+	{service.ErrSortOrderLocked, codes.FailedPrecondition, ReasonSortOrderLocked, ""},
+	{service.ErrLeaderboardClosed, codes.FailedPrecondition, ReasonLeaderboardClosed, "leaderboard_id"},
+	{service.ErrOfflineSyncDisabled, codes.FailedPrecondition, ReasonOfflineSyncDisabled, ""},
+	{service.ErrReceiptsDisabled, codes.FailedPrecondition, ReasonReceiptsDisabled, ""},
+	{service.ErrChangesUnavailable, codes.FailedPrecondition, ReasonChangesUnavailable, ""},
+	{service.ErrInvalidConfirmation, codes.FailedPrecondition, ReasonInvalidConfirmation, "confirmation_token"},
+	{service.ErrAdminDisabled, codes.PermissionDenied, ReasonAdminDisabled, ""},
+	{service.ErrAdminUnauthorized, codes.Unauthenticated, ReasonAdminUnauthorized, ""},
+	{service.ErrPlayerNotFound, codes.NotFound, ReasonPlayerNotFound, ""},
+	{service.ErrPlayerExists, codes.AlreadyExists, ReasonPlayerExists, "new_player_name"},
+	{service.ErrPlayerBanned, codes.PermissionDenied, ReasonPlayerBanned, ""},
+}
+
+// fromServiceError converts an error returned by the service to a status error.
+// Unexpected errors are logged and reported as Internal with a generic message
+// naming the failed operation ("failed to <op>"); timeouts are logged too and
+// reported as DeadlineExceeded.
+func (s *Server) fromServiceError(ctx context.Context, err error, op string) error {
+	if errors.Is(err, service.ErrOverloaded) {
+		return s.overloaded(ctx, err)
+	}
+	if errors.Is(err, service.ErrUnavailable) {
+		return s.unavailable(ctx, err)
+	}
+	if errors.Is(err, service.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		// A slow database, not a bad request: logged, but without the cause in the status
+		s.loggerFor(ctx).Warn().Err(err).Msg(op + " timed out")
+		return statusError(codes.DeadlineExceeded, ReasonTimeout, "failed to "+op+": database query timed out")
+	}
+	for _, e := range serviceErrors {
+		if errors.Is(err, e.err) {
+			if e.field != "" {
+				return fieldError(e.code, e.reason, e.field, err.Error())
+			}
+			return statusError(e.code, e.reason, err.Error())
+		}
+	}
+	s.loggerFor(ctx).Error().Err(err).Msg("failed to " + op)
+	return internalError("failed to " + op)
+}
+
+// statusError returns a status error whose details are an ErrorInfo with the
+// given reason followed by any extra details
+func statusError(code codes.Code, reason, msg string, details ...protoadapt.MessageV1) error {
+	return withDetails(status.New(code, msg), append([]protoadapt.MessageV1{errorInfo(reason, nil)}, details...))
+}
+
+// fieldError returns a status error for an invalid request field, with a
+// BadRequest field violation next to the ErrorInfo
+func fieldError(code codes.Code, reason, field, msg string) error {
+	return statusError(code, reason, msg, &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{
+			Field:       field,
+			Description: msg,
+			Reason:      reason,
+		}},
+	})
+}
+
+// invalidArgument returns an InvalidArgument status for a request field rejected by the handler itself
+func invalidArgument(reason, field, msg string) error {
+	return fieldError(codes.InvalidArgument, reason, field, msg)
+}
+
+// internalError returns an Internal status; msg must not leak internal details
+func internalError(msg string) error {
+	return statusError(codes.Internal, ReasonInternal, msg)
+}
+
+// overloadedError returns the ResourceExhausted status of a shed request, telling
+// the client when to retry both in the ErrorInfo metadata and as a RetryInfo
+func overloadedError(err error, retryAfter int) error {
+	return retryError(codes.ResourceExhausted, ReasonOverloaded, err, retryAfter)
+}
+
+// unavailableError returns the Unavailable status of a call failed fast while the
+// database circuit is open, with the same retry hints as overloadedError
+func unavailableError(err error, retryAfter int) error {
+	return retryError(codes.Unavailable, ReasonUnavailable, err, retryAfter)
+}
+
+func retryError(code codes.Code, reason string, err error, retryAfter int) error {
+	return withDetails(status.New(code, err.Error()), []protoadapt.MessageV1{
+		errorInfo(reason, map[string]string{"retry_after_seconds": strconv.Itoa(retryAfter)}),
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Duration(retryAfter) * time.Second)},
+	})
+}
+
+// subscriberLimitError returns the ResourceExhausted status of a stream refused
+// because its board already has max subscribers
+func subscriberLimitError(board string, max int) error {
+	return withDetails(status.New(codes.ResourceExhausted, fmt.Sprintf("leaderboard %q already has the maximum of %d subscribers", board, max)), []protoadapt.MessageV1{
+		errorInfo(ReasonTooManySubscribers, map[string]string{"max_subscribers": strconv.Itoa(max)}),
+	})
+}
+
+// streamQuotaError returns the ResourceExhausted status of a stream refused
+// because its client address already holds its quota of streams
+func streamQuotaError(quota int) error {
+	return withDetails(status.New(codes.ResourceExhausted, fmt.Sprintf("stream quota of %d reached for this client address", quota)), []protoadapt.MessageV1{
+		errorInfo(ReasonStreamQuotaExceeded, map[string]string{"max_streams": strconv.Itoa(quota)}),
+	})
+}
+
+func errorInfo(reason string, metadata map[string]string) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain, Metadata: metadata}
+}
+
+// withDetails attaches details to st, falling back to the bare status if they cannot be encoded
+func withDetails(st *status.Status, details []protoadapt.MessageV1) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// details returns the ErrorInfo and BadRequest details of a status error
+func details(t *testing.T, err error) (*status.Status, *errdetails.ErrorInfo, *errdetails.BadRequest) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("%v is not a status error", err)
+	}
+	var info *errdetails.ErrorInfo
+	var bad *errdetails.BadRequest
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.BadRequest:
+			bad = d
+		}
+	}
+	if info == nil || info.Domain != ErrorDomain {
+		t.Fatalf("status %v has no ErrorInfo of domain %s", st, ErrorDomain)
+	}
+	return st, info, bad
+}
+
+func TestFromServiceError(t *testing.T) {
+	s := newHub()
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		err    error
+		code   codes.Code
+		reason string
+		field  string
+	}{
+		{"player name", fmt.Errorf("%w: too long", service.ErrInvalidPlayerName), codes.InvalidArgument, ReasonInvalidPlayerName, "player_name"},
+		{"score", fmt.Errorf("%w: negative", service.ErrInvalidScore), codes.InvalidArgument, ReasonInvalidScore, "score"},
+		{"field mask", fmt.Errorf("%w: unknown field", service.ErrInvalidFieldMask), codes.InvalidArgument, ReasonInvalidFieldMask, "read_mask"},
+		{"locked sort order", service.ErrSortOrderLocked, codes.FailedPrecondition, ReasonSortOrderLocked, ""},
+		{"admin token", service.ErrAdminUnauthorized, codes.Unauthenticated, ReasonAdminUnauthorized, ""},
+		{"unexpected", errors.New("connection reset"), codes.Internal, ReasonInternal, ""},
+		{"query timeout", fmt.Errorf("get top scores: %w", service.ErrTimeout), codes.DeadlineExceeded, ReasonTimeout, ""},
+		{"caller deadline", fmt.Errorf("get top scores: %w", context.DeadlineExceeded), codes.DeadlineExceeded, ReasonTimeout, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, info, bad := details(t, s.fromServiceError(ctx, tt.err, "submit score"))
+			if st.Code() != tt.code || info.Reason != tt.reason {
+				t.Errorf("status = %v/%s, want %v/%s", st.Code(), info.Reason, tt.code, tt.reason)
+			}
+			switch {
+			case tt.field == "" && bad != nil:
+				t.Errorf("unexpected field violations %v", bad.FieldViolations)
+			case tt.field != "" && (bad == nil || len(bad.FieldViolations) != 1 || bad.FieldViolations[0].Field != tt.field):
+				t.Errorf("field violations = %v, want one on %s", bad, tt.field)
+			}
+			if tt.code == codes.Internal && st.Message() != "failed to submit score" {
+				t.Errorf("internal message = %q, leaks the cause", st.Message())
+			}
+			if tt.code == codes.DeadlineExceeded && st.Message() != "failed to submit score: database query timed out" {
+				t.Errorf("timeout message = %q", st.Message())
+			}
+		})
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	s := newHub()
+	err := s.fromServiceError(context.Background(), fmt.Errorf("get top scores: %w", &service.UnavailableError{RetryAfter: 1500 * time.Millisecond}), "get top scores")
+	st, info, _ := details(t, err)
+	if st.Code() != codes.Unavailable || info.Reason != ReasonUnavailable || info.Metadata["retry_after_seconds"] != "2" {
+		t.Errorf("status = %v, info = %v, want Unavailable retrying after 2s", st.Code(), info)
+	}
+}
+
+func TestOverloadedError(t *testing.T) {
+	st, info, _ := details(t, overloadedError(service.ErrOverloaded, 2))
+	if st.Code() != codes.ResourceExhausted || info.Reason != ReasonOverloaded || info.Metadata["retry_after_seconds"] != "2" {
+		t.Errorf("status = %v, info = %v", st.Code(), info)
+	}
+	for _, d := range st.Details() {
+		if retry, ok := d.(*errdetails.RetryInfo); ok {
+			if got := retry.RetryDelay.AsDuration().Seconds(); got != 2 {
+				t.Errorf("retry delay = %vs, want 2s", got)
+			}
+			return
+		}
+	}
+	t.Error("no RetryInfo detail")
+}
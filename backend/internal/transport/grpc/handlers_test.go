@@ -0,0 +1,563 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/maintenance"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// fakeService is a service.Leaderboard answering from canned results. Methods
+// a test does not set up panic through the nil embedded interface.
+type fakeService struct {
+	service.Leaderboard
+
+	submitted []service.ScoreSubmission
+	result    *service.ScoreResult
+
+	page        *service.TopScoresPage
+	pageArgs    []any // board, limit, offset and page token of the last call
+	segmentArgs []any // board, segment, limit, offset and page token of the last segment call
+	count       int64
+	countArgs   []any // board and segment of the last GetEntryCount call
+
+	rank        *service.PlayerRank
+	rankSegment *service.Segment // segment of the last GetSegmentPlayerRank call
+	ranks       *service.PlayerRanks
+	changes     *notify.ChangePage
+	changesArgs []any // board, seq, time and limit of the last GetChangesSince call
+
+	adminToken string // accepted bearer token
+	resets     int
+	renamed    *store.RenameResult
+
+	err          error // returned by every call set up above
+	profiles     map[string]store.Player
+	profileCalls int
+}
+
+func (f *fakeService) SubmitScore(_ context.Context, sub service.ScoreSubmission) (*service.ScoreResult, error) {
+	f.submitted = append(f.submitted, sub)
+	return f.result, f.err
+}
+
+func (f *fakeService) GetTopScoresPage(_ context.Context, board string, limit, offset int32, pageToken string) (*service.TopScoresPage, error) {
+	f.pageArgs = []any{board, limit, offset, pageToken}
+	return f.page, f.err
+}
+
+func (f *fakeService) GetSegmentTopScoresPage(_ context.Context, board string, seg service.Segment, limit, offset int32, pageToken string) (*service.TopScoresPage, error) {
+	f.segmentArgs = []any{board, seg, limit, offset, pageToken}
+	return f.page, f.err
+}
+
+func (f *fakeService) GetEntryCount(_ context.Context, board string, seg service.Segment) (int64, error) {
+	f.countArgs = []any{board, seg}
+	return f.count, f.err
+}
+
+func (f *fakeService) GetPlayerRank(_ context.Context, _, _ string) (*service.PlayerRank, error) {
+	return f.rank, f.err
+}
+
+func (f *fakeService) GetSegmentPlayerRank(_ context.Context, _, _ string, seg service.Segment) (*service.PlayerRank, error) {
+	f.rankSegment = &seg
+	return f.rank, f.err
+}
+
+func (f *fakeService) GetPlayerRanks(_ context.Context, _ string, _ []string) (*service.PlayerRanks, error) {
+	return f.ranks, f.err
+}
+
+func (f *fakeService) GetChangesSince(_ context.Context, board string, sinceSeq int64, since time.Time, limit int32) (*notify.ChangePage, error) {
+	f.changesArgs = []any{board, sinceSeq, since, limit}
+	return f.changes, f.err
+}
+
+func (f *fakeService) AuthenticateAdmin(ctx context.Context, token string) (context.Context, error) {
+	if token == "" || token != f.adminToken {
+		return nil, service.ErrAdminUnauthorized
+	}
+	return ctx, nil
+}
+
+func (f *fakeService) ResetLeaderboard(_ context.Context, board string, _ bool, _ string) (*service.ResetResult, error) {
+	f.resets++
+	return &service.ResetResult{LeaderboardID: board, Completed: true, Deleted: 2}, f.err
+}
+
+func (f *fakeService) RenamePlayer(_ context.Context, _, _ string, _ bool) (*store.RenameResult, error) {
+	return f.renamed, f.err
+}
+
+func (f *fakeService) PlayerProfiles(_ context.Context, _ []string) map[string]store.Player {
+	f.profileCalls++
+	return f.profiles
+}
+
+func (f *fakeService) TierFor(_ string, score int64) string {
+	if score >= 1000 {
+		return "Gold"
+	}
+	return ""
+}
+
+func (f *fakeService) TierChanges() <-chan service.TierChange {
+	ch := make(chan service.TierChange)
+	close(ch)
+	return ch
+}
+
+func (f *fakeService) RetryAfter() time.Duration { return 2 * time.Second }
+
+// newFakeServer returns a Server over svc with a default page of 10 and a maximum of 50
+func newFakeServer(svc service.Leaderboard) *Server {
+	logger := zerolog.Nop()
+	changes := make(chan notify.ScoreChange)
+	close(changes)
+	return NewServer(svc, changes, &logger, 10, 50, 0, 0)
+}
+
+func TestSubmitScoreHandler(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rejected requests", func(t *testing.T) {
+		svc := &fakeService{}
+		s := newFakeServer(svc)
+		for _, tt := range []struct {
+			req    *pb.SubmitScoreRequest
+			reason string
+			field  string
+		}{
+			{&pb.SubmitScoreRequest{Score: 100}, ReasonMissingField, "player_name"},
+			{&pb.SubmitScoreRequest{PlayerName: "Alice", Score: -1}, ReasonInvalidScore, "score"},
+			{&pb.SubmitScoreRequest{PlayerName: "Alice", AchievedAt: "yesterday"}, ReasonInvalidTimestamp, "achieved_at"},
+		} {
+			_, err := s.SubmitScore(ctx, tt.req)
+			st, info, bad := details(t, err)
+			if st.Code() != codes.InvalidArgument || info.Reason != tt.reason || bad.GetFieldViolations()[0].GetField() != tt.field {
+				t.Errorf("SubmitScore(%v) = %v %s on %v, want InvalidArgument %s on %s", tt.req, st.Code(), info.Reason, bad, tt.reason, tt.field)
+			}
+		}
+		if len(svc.submitted) != 0 {
+			t.Errorf("service got %d submissions, want none", len(svc.submitted))
+		}
+	})
+
+	t.Run("applied", func(t *testing.T) {
+		svc := &fakeService{
+			result: &service.ScoreResult{
+				LeaderboardID: "level-1",
+				PlayerName:    "Alice",
+				Score:         1500,
+				UpdatedAt:     time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
+				AchievedAt:    time.Date(2025, 1, 15, 10, 29, 41, 0, time.UTC),
+				Applied:       true,
+				Rank:          3,
+				RankDelta:     2,
+			},
+			profiles: map[string]store.Player{"Alice": {PlayerName: "Alice", DisplayName: "Alice the Great"}},
+		}
+		s := newFakeServer(svc)
+		resp, err := s.SubmitScore(ctx, &pb.SubmitScoreRequest{
+			LeaderboardId: "level-1",
+			PlayerName:    "Alice",
+			Score:         1500,
+			AchievedAt:    "2025-01-15T10:29:41Z",
+			Metadata:      map[string]string{"map": "dust"},
+		})
+		if err != nil {
+			t.Fatalf("SubmitScore: %v", err)
+		}
+
+		sub := svc.submitted[0]
+		if sub.LeaderboardID != "level-1" || sub.PlayerName != "Alice" || sub.Score != 1500 || sub.Metadata["map"] != "dust" ||
+			!sub.AchievedAt.Equal(time.Date(2025, 1, 15, 10, 29, 41, 0, time.UTC)) {
+			t.Errorf("service got %+v", sub)
+		}
+		e := resp.Entry
+		if !resp.Applied || resp.Rank != 3 || resp.RankDelta != 2 || resp.Receipt != nil {
+			t.Errorf("response = %v, want applied at rank 3 (+2) without receipt", resp)
+		}
+		if e.PlayerName != "Alice" || e.Score != 1500 || e.LeaderboardId != "level-1" || e.Tier != "Gold" || e.GetProfile().GetDisplayName() != "Alice the Great" {
+			t.Errorf("entry = %v", e)
+		}
+		if e.UpdatedAt != "2025-01-15T10:30:00Z" || e.GetUpdatedTime().AsTime() != svc.result.UpdatedAt ||
+			e.AchievedAt != "2025-01-15T10:29:41Z" || e.GetAchievedTime().AsTime() != svc.result.AchievedAt {
+			t.Errorf("entry times = %q %v, %q %v", e.UpdatedAt, e.UpdatedTime, e.AchievedAt, e.AchievedTime)
+		}
+	})
+
+	t.Run("service errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			err    error
+			code   codes.Code
+			reason string
+		}{
+			{fmt.Errorf("%w: too long", service.ErrInvalidPlayerName), codes.InvalidArgument, ReasonInvalidPlayerName},
+			{service.ErrLeaderboardClosed, codes.FailedPrecondition, ReasonLeaderboardClosed},
+			{service.ErrPlayerBanned, codes.PermissionDenied, ReasonPlayerBanned},
+			{service.ErrOverloaded, codes.ResourceExhausted, ReasonOverloaded},
+			{errors.New("connection reset"), codes.Internal, ReasonInternal},
+		} {
+			s := newFakeServer(&fakeService{err: tt.err})
+			_, err := s.SubmitScore(ctx, &pb.SubmitScoreRequest{PlayerName: "Alice", Score: 100})
+			st, info, _ := details(t, err)
+			if st.Code() != tt.code || info.Reason != tt.reason {
+				t.Errorf("%v: got %v %s, want %v %s", tt.err, st.Code(), info.Reason, tt.code, tt.reason)
+			}
+			if tt.code == codes.Internal && st.Message() != "failed to submit score" {
+				t.Errorf("internal error message = %q leaks the cause", st.Message())
+			}
+		}
+	})
+}
+
+func TestGetTopScoresHandler(t *testing.T) {
+	ctx := context.Background()
+	page := &service.TopScoresPage{
+		Scores: []store.Score{
+			{LeaderboardID: "global", PlayerName: "Alice", Score: 1200},
+			{LeaderboardID: "global", PlayerName: "Bob", Score: 900},
+		},
+		NextPageToken:  "next",
+		RankingVariant: service.RankingControl,
+		TotalCount:     2,
+	}
+
+	for _, tt := range []struct {
+		limit, want int32
+	}{{0, 10}, {25, 25}, {500, 50}} {
+		svc := &fakeService{page: page}
+		s := newFakeServer(svc)
+		if _, err := s.GetTopScores(ctx, &pb.GetTopScoresRequest{Limit: tt.limit, Offset: -3, PageToken: "tok"}); err != nil {
+			t.Fatalf("GetTopScores: %v", err)
+		}
+		if got := svc.pageArgs; got[1] != tt.want || got[2] != int32(0) || got[3] != "tok" {
+			t.Errorf("limit %d: service got limit, offset, token %v, want %d, 0, tok", tt.limit, got[1:], tt.want)
+		}
+	}
+
+	svc := &fakeService{page: page}
+	s := newFakeServer(svc)
+	resp, err := s.GetTopScores(ctx, &pb.GetTopScoresRequest{ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"player_name", "score", "tier"}}})
+	if err != nil {
+		t.Fatalf("GetTopScores with mask: %v", err)
+	}
+	if len(resp.Entries) != 2 || resp.NextPageToken != "next" || resp.RankingVariant != service.RankingControl || resp.TotalCount != 2 {
+		t.Fatalf("response = %v", resp)
+	}
+	if e := resp.Entries[0]; e.PlayerName != "Alice" || e.Score != 1200 || e.Tier != "Gold" || e.LeaderboardId != "" {
+		t.Errorf("masked entry = %v, want only player_name, score and tier", e)
+	}
+	if svc.profileCalls != 0 {
+		t.Error("profiles loaded although the mask leaves them out")
+	}
+
+	_, err = s.GetTopScores(ctx, &pb.GetTopScoresRequest{ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"password"}}})
+	if st, info, _ := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonInvalidFieldMask {
+		t.Errorf("unknown mask path: got %v %s, want InvalidArgument %s", st.Code(), info.Reason, ReasonInvalidFieldMask)
+	}
+
+	regional := &service.TopScoresPage{Scores: []store.Score{{LeaderboardID: "global", PlayerName: "Chloe", Score: 800, CountryCode: "FR", Platform: "mobile"}}}
+	svc = &fakeService{page: regional}
+	s = newFakeServer(svc)
+	resp, err = s.GetTopScores(ctx, &pb.GetTopScoresRequest{Region: "fr", Platform: "mobile", PageToken: "tok"})
+	if err != nil {
+		t.Fatalf("GetTopScores with region and platform: %v", err)
+	}
+	if got := svc.segmentArgs; got == nil || got[1] != (service.Segment{Region: "fr", Platform: "mobile"}) || got[4] != "tok" || svc.pageArgs != nil {
+		t.Errorf("segment call: service got %v, whole board call %v", got, svc.pageArgs)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Region != "FR" || resp.Entries[0].Platform != "mobile" {
+		t.Errorf("segment entries = %v, want Chloe with region FR and platform mobile", resp.Entries)
+	}
+
+	s = newFakeServer(&fakeService{err: fmt.Errorf("%w: \"FRA\"", service.ErrInvalidCountry)})
+	_, err = s.GetTopScores(ctx, &pb.GetTopScoresRequest{Region: "FRA"})
+	if st, info, _ := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonInvalidCountry {
+		t.Errorf("bad region: got %v %s, want InvalidArgument %s", st.Code(), info.Reason, ReasonInvalidCountry)
+	}
+	s = newFakeServer(&fakeService{err: fmt.Errorf("%w: \"android\"", service.ErrInvalidPlatform)})
+	_, err = s.GetTopScores(ctx, &pb.GetTopScoresRequest{Platform: "android"})
+	if st, info, bad := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonInvalidPlatform || bad.GetFieldViolations()[0].GetField() != "platform" {
+		t.Errorf("bad platform: got %v %s, want InvalidArgument %s on platform", st.Code(), info.Reason, ReasonInvalidPlatform)
+	}
+
+	s = newFakeServer(&fakeService{err: fmt.Errorf("%w: garbled", service.ErrInvalidPageToken)})
+	_, err = s.GetTopScores(ctx, &pb.GetTopScoresRequest{PageToken: "garbled"})
+	if st, info, _ := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonInvalidPageToken {
+		t.Errorf("bad page token: got %v %s, want InvalidArgument %s", st.Code(), info.Reason, ReasonInvalidPageToken)
+	}
+}
+
+func TestGetEntryCountHandler(t *testing.T) {
+	ctx := context.Background()
+	svc := &fakeService{count: 1200}
+	resp, err := newFakeServer(svc).GetEntryCount(ctx, &pb.GetEntryCountRequest{LeaderboardId: "level-1", Region: "fr", Platform: "pc"})
+	if err != nil || resp.TotalCount != 1200 {
+		t.Fatalf("GetEntryCount = %v, %v; want 1200", resp, err)
+	}
+	if got := svc.countArgs; got[0] != "level-1" || got[1] != (service.Segment{Region: "fr", Platform: "pc"}) {
+		t.Errorf("service got board, segment %v", got)
+	}
+
+	_, err = newFakeServer(&fakeService{err: fmt.Errorf("%w: \"FRA\"", service.ErrInvalidCountry)}).GetEntryCount(ctx, &pb.GetEntryCountRequest{Region: "FRA"})
+	if st, info, _ := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonInvalidCountry {
+		t.Errorf("bad region: got %v %s, want InvalidArgument %s", st.Code(), info.Reason, ReasonInvalidCountry)
+	}
+}
+
+func TestGetPlayerRankHandler(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := newFakeServer(&fakeService{}).GetPlayerRank(ctx, &pb.GetPlayerRankRequest{})
+	if st, info, _ := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonMissingField {
+		t.Errorf("no player: got %v %s, want InvalidArgument %s", st.Code(), info.Reason, ReasonMissingField)
+	}
+
+	resp, err := newFakeServer(&fakeService{err: service.ErrPlayerNotFound}).GetPlayerRank(ctx, &pb.GetPlayerRankRequest{PlayerName: "Nobody"})
+	if err != nil || !resp.NotFound {
+		t.Errorf("unknown player: got %v, %v, want not_found", resp, err)
+	}
+
+	svc := &fakeService{
+		rank:     &service.PlayerRank{Rank: 4, Score: store.Score{LeaderboardID: "global", PlayerName: "Bob", Score: 900}, RankingVariant: service.RankingControl},
+		profiles: map[string]store.Player{"Bob": {PlayerName: "Bob", CountryCode: "FR"}},
+	}
+	resp, err = newFakeServer(svc).GetPlayerRank(ctx, &pb.GetPlayerRankRequest{PlayerName: "Bob"})
+	if err != nil {
+		t.Fatalf("GetPlayerRank: %v", err)
+	}
+	if resp.NotFound || resp.Rank != 4 || resp.Entry.PlayerName != "Bob" || resp.Entry.Score != 900 || resp.Entry.GetProfile().GetCountryCode() != "FR" {
+		t.Errorf("response = %v", resp)
+	}
+	if svc.rankSegment != nil {
+		t.Errorf("whole board rank computed in segment %+v", *svc.rankSegment)
+	}
+	if _, err = newFakeServer(svc).GetPlayerRank(ctx, &pb.GetPlayerRankRequest{PlayerName: "Bob", Platform: "pc"}); err != nil {
+		t.Fatalf("GetPlayerRank with platform: %v", err)
+	}
+	if svc.rankSegment == nil || *svc.rankSegment != (service.Segment{Platform: "pc"}) {
+		t.Errorf("platform rank: service got segment %v, want platform pc", svc.rankSegment)
+	}
+
+	_, err = newFakeServer(&fakeService{err: errors.New("timeout")}).GetPlayerRank(ctx, &pb.GetPlayerRankRequest{PlayerName: "Bob"})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("store failure: got %v, want Internal", err)
+	}
+}
+
+func TestGetPlayerRanksHandler(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := newFakeServer(&fakeService{}).GetPlayerRanks(ctx, &pb.GetPlayerRanksRequest{})
+	if st, info, _ := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonMissingField {
+		t.Errorf("no players: got %v %s, want InvalidArgument %s", st.Code(), info.Reason, ReasonMissingField)
+	}
+	_, err = newFakeServer(&fakeService{err: service.ErrTooManyPlayers}).GetPlayerRanks(ctx, &pb.GetPlayerRanksRequest{PlayerNames: []string{"Bob"}})
+	if st, info, br := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonTooManyPlayers || br.GetFieldViolations()[0].GetField() != "player_names" {
+		t.Errorf("too many players: got %v %s, want InvalidArgument %s on player_names", st.Code(), info.Reason, ReasonTooManyPlayers)
+	}
+
+	svc := &fakeService{
+		ranks: &service.PlayerRanks{
+			Ranks: []service.PlayerRank{
+				{Rank: 2, Score: store.Score{LeaderboardID: "global", PlayerName: "Alice", Score: 1200}},
+				{Rank: 4, Score: store.Score{LeaderboardID: "global", PlayerName: "Bob", Score: 900}},
+			},
+			NotFound: []string{"Newcomer"},
+		},
+		profiles: map[string]store.Player{"Bob": {PlayerName: "Bob", CountryCode: "FR"}},
+	}
+	resp, err := newFakeServer(svc).GetPlayerRanks(ctx, &pb.GetPlayerRanksRequest{PlayerNames: []string{"Bob", "Newcomer", "Alice"}})
+	if err != nil {
+		t.Fatalf("GetPlayerRanks: %v", err)
+	}
+	if len(resp.Ranks) != 2 || resp.Ranks[0].Rank != 2 || resp.Ranks[0].Entry.PlayerName != "Alice" ||
+		resp.Ranks[1].Rank != 4 || resp.Ranks[1].Entry.GetProfile().GetCountryCode() != "FR" ||
+		len(resp.NotFound) != 1 || resp.NotFound[0] != "Newcomer" {
+		t.Errorf("response = %v", resp)
+	}
+	if svc.profileCalls != 1 {
+		t.Errorf("profiles looked up %d times, want once for all players", svc.profileCalls)
+	}
+}
+
+func TestGetChangesSinceHandler(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := newFakeServer(&fakeService{}).GetChangesSince(ctx, &pb.GetChangesSinceRequest{SinceTime: "yesterday"})
+	if st, info, _ := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonInvalidTimestamp {
+		t.Errorf("malformed time: got %v %s, want InvalidArgument %s", st.Code(), info.Reason, ReasonInvalidTimestamp)
+	}
+	_, err = newFakeServer(&fakeService{err: service.ErrChangesUnavailable}).GetChangesSince(ctx, &pb.GetChangesSinceRequest{SinceSeq: 42})
+	if st, info, _ := details(t, err); st.Code() != codes.FailedPrecondition || info.Reason != ReasonChangesUnavailable {
+		t.Errorf("no outbox: got %v %s, want FailedPrecondition %s", st.Code(), info.Reason, ReasonChangesUnavailable)
+	}
+
+	at := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	svc := &fakeService{
+		changes: &notify.ChangePage{
+			Changes: []notify.ScoreChange{
+				{ID: 43, LeaderboardID: "level-1", PlayerName: "Alice", Score: 1500, Op: "update", UpdatedAt: at, AchievedAt: at},
+				{ID: 45, LeaderboardID: "level-1", PlayerName: "Bob", Score: 900, Op: "delete", UpdatedAt: at, AchievedAt: at},
+			},
+			LastID: 46,
+			More:   true,
+		},
+		profiles: map[string]store.Player{"Alice": {PlayerName: "Alice", CountryCode: "FR"}},
+	}
+	resp, err := newFakeServer(svc).GetChangesSince(ctx, &pb.GetChangesSinceRequest{LeaderboardId: "level-1", SinceTime: "2025-01-15T10:00:00Z", Limit: 50})
+	if err != nil {
+		t.Fatalf("GetChangesSince: %v", err)
+	}
+	if want := []any{"level-1", int64(0), at.Add(-30 * time.Minute), int32(50)}; fmt.Sprint(svc.changesArgs) != fmt.Sprint(want) {
+		t.Errorf("service got %v, want %v", svc.changesArgs, want)
+	}
+	if len(resp.Changes) != 2 || resp.NextSeq != 46 || !resp.HasMore || resp.Resync {
+		t.Fatalf("response = %v", resp)
+	}
+	upsert, del := resp.Changes[0], resp.Changes[1]
+	if upsert.Kind != pb.LeaderboardUpdate_UPSERT || upsert.Seq != 43 || upsert.Changed.Score != 1500 || upsert.Changed.GetProfile().GetCountryCode() != "FR" ||
+		upsert.Changed.UpdatedAt != "2025-01-15T10:30:00Z" || upsert.Changed.LeaderboardId != "level-1" {
+		t.Errorf("upsert = %v", upsert)
+	}
+	if del.Kind != pb.LeaderboardUpdate_DELETE || del.Seq != 45 || del.Changed.PlayerName != "Bob" {
+		t.Errorf("delete = %v", del)
+	}
+}
+
+func TestResetLeaderboardHandlerRequiresAdmin(t *testing.T) {
+	svc := &fakeService{adminToken: "secret"}
+	s := newFakeServer(svc)
+
+	for _, header := range []string{"", "Bearer wrong"} {
+		ctx := context.Background()
+		if header != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", header))
+		}
+		_, err := s.ResetLeaderboard(ctx, &pb.ResetLeaderboardRequest{LeaderboardId: "level-1"})
+		if st, info, _ := details(t, err); st.Code() != codes.Unauthenticated || info.Reason != ReasonAdminUnauthorized {
+			t.Errorf("authorization %q: got %v %s, want Unauthenticated", header, st.Code(), info.Reason)
+		}
+	}
+	if svc.resets != 0 {
+		t.Fatalf("board reset %d times without a valid token", svc.resets)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	resp, err := s.ResetLeaderboard(ctx, &pb.ResetLeaderboardRequest{LeaderboardId: "level-1"})
+	if err != nil {
+		t.Fatalf("ResetLeaderboard: %v", err)
+	}
+	if svc.resets != 1 || !resp.Completed || resp.LeaderboardId != "level-1" || resp.Deleted != 2 {
+		t.Errorf("response = %v after %d resets", resp, svc.resets)
+	}
+}
+
+// fakeArchiver lists the candidates it holds, and records the requests
+type fakeArchiver struct {
+	store.Archiver
+	candidates []store.ArchiveCandidate
+	listed     []store.ListArchiveCandidatesParams
+}
+
+func (f *fakeArchiver) ListArchiveCandidates(_ context.Context, arg store.ListArchiveCandidatesParams) ([]store.ArchiveCandidate, error) {
+	f.listed = append(f.listed, arg)
+	return f.candidates[:min(len(f.candidates), int(arg.Limit))], nil
+}
+
+func TestPreviewExpirationsHandler(t *testing.T) {
+	admin := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	s := newFakeServer(&fakeService{adminToken: "secret"})
+
+	_, err := s.PreviewExpirations(admin, &pb.PreviewExpirationsRequest{})
+	if st, info, _ := details(t, err); st.Code() != codes.FailedPrecondition || info.Reason != ReasonArchiveDisabled {
+		t.Errorf("without an archival job: got %v %s, want FailedPrecondition", st.Code(), info.Reason)
+	}
+
+	logger := zerolog.Nop()
+	db := &fakeArchiver{candidates: []store.ArchiveCandidate{
+		{LeaderboardID: "global", PlayerName: "Alice", Score: 5, UpdatedAt: time.Now(), BelowFloor: true},
+		{LeaderboardID: "global", PlayerName: "Bob", Score: 3, UpdatedAt: time.Now(), BelowFloor: true},
+	}}
+	s.SetArchiveJob(maintenance.NewArchiveJob(db, maintenance.ArchivePolicy{ScoreFloor: 10, DryRun: true}, &logger))
+
+	resp, err := s.PreviewExpirations(admin, &pb.PreviewExpirationsRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("PreviewExpirations: %v", err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].PlayerName != "Alice" || resp.Entries[0].Reason != store.ArchiveReasonScoreFloor ||
+		resp.NextPageToken == "" || !resp.DryRun {
+		t.Errorf("response = %v, want Alice below the floor and a next page", resp)
+	}
+	if _, err := s.PreviewExpirations(admin, &pb.PreviewExpirationsRequest{Limit: 1, PageToken: resp.NextPageToken}); err != nil || db.listed[1].After == nil || db.listed[1].After.PlayerName != "Alice" {
+		t.Errorf("second page: %v, after %+v", err, db.listed[1].After)
+	}
+
+	for _, tt := range []struct {
+		req    *pb.PreviewExpirationsRequest
+		reason string
+	}{
+		{&pb.PreviewExpirationsRequest{Days: 400}, ReasonInvalidPreviewDays},
+		{&pb.PreviewExpirationsRequest{PageToken: "garbled"}, ReasonInvalidPageToken},
+	} {
+		_, err := s.PreviewExpirations(admin, tt.req)
+		if st, info, _ := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != tt.reason {
+			t.Errorf("%v: got %v %s, want InvalidArgument %s", tt.req, st.Code(), info.Reason, tt.reason)
+		}
+	}
+	if _, err := s.PreviewExpirations(context.Background(), &pb.PreviewExpirationsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without token: got %v, want Unauthenticated", err)
+	}
+}
+
+func TestRenamePlayerHandler(t *testing.T) {
+	admin := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	req := &pb.RenamePlayerRequest{PlayerName: "Alice", NewPlayerName: "Carol"}
+
+	svc := &fakeService{adminToken: "secret", renamed: &store.RenameResult{
+		Scores:  []store.Score{{LeaderboardID: "global", PlayerName: "Carol", Score: 1500}, {LeaderboardID: "level-1", PlayerName: "Carol", Score: 20}},
+		Merged:  []string{"level-1"},
+		Profile: &store.Player{PlayerName: "Carol", DisplayName: "Ally"},
+	}}
+	resp, err := newFakeServer(svc).RenamePlayer(admin, req)
+	if err != nil {
+		t.Fatalf("RenamePlayer: %v", err)
+	}
+	if resp.PlayerName != "Carol" || len(resp.Entries) != 2 || resp.Entries[0].Tier != "Gold" ||
+		resp.Entries[1].Profile.GetDisplayName() != "Ally" || len(resp.MergedLeaderboardIds) != 1 || resp.Profile.GetDisplayName() != "Ally" {
+		t.Errorf("response = %v", resp)
+	}
+
+	if _, err := newFakeServer(svc).RenamePlayer(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without token: got %v, want Unauthenticated", err)
+	}
+
+	for _, tt := range []struct {
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{service.ErrPlayerExists, codes.AlreadyExists, ReasonPlayerExists},
+		{service.ErrPlayerNotFound, codes.NotFound, ReasonPlayerNotFound},
+	} {
+		_, err := newFakeServer(&fakeService{adminToken: "secret", err: tt.err}).RenamePlayer(admin, req)
+		if st, info, _ := details(t, err); st.Code() != tt.code || info.Reason != tt.reason {
+			t.Errorf("%v: got %v %s, want %v %s", tt.err, st.Code(), info.Reason, tt.code, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"sync"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+)
+
+// history retains the last score updates broadcast to each board, so that a
+// client reconnecting after a drop can resume its stream from the seq of the
+// last update it received instead of starting over with a snapshot.
+//
+// Only updates carrying a seq (UPSERT and DELETE) are retained: a replayed
+// upsert carries the player's tier, so missed tier changes need no replay.
+// A resume is possible when no change of the board after the client's seq can
+// be missing: none evicted from the board's buffer, none dropped by a resync,
+// and none received before the server's first change.
+type history struct {
+	mu     sync.Mutex
+	size   int                      // updates retained per board, 0 disables resuming
+	floor  int64                    // boards without updates may miss changes up to this seq
+	last   int64                    // highest seq recorded
+	boards map[string]*boardHistory // by leaderboard id
+}
+
+// boardHistory is the retained updates of a board
+type boardHistory struct {
+	updates []*pb.LeaderboardUpdate // in broadcast order, oldest first
+	floor   int64                   // changes of the board up to this seq may be missing
+}
+
+func newHistory(size int) *history {
+	return &history{size: size, boards: make(map[string]*boardHistory)}
+}
+
+// resize changes the number of updates retained per board. Shrinking evicts
+// the oldest updates; 0 drops them all and disables resuming.
+func (h *history) resize(size int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.size = size
+	if size == 0 {
+		h.floor = h.last
+		clear(h.boards)
+		return
+	}
+	for _, b := range h.boards {
+		b.trim(size)
+	}
+}
+
+// record retains an update broadcast to a board. A resync marker drops the
+// board's updates, or those of every board when board is empty: the
+// subscribers get a snapshot, so the changes before it cannot be replayed.
+func (h *history) record(board string, update *pb.LeaderboardUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.size == 0 {
+		return
+	}
+
+	if update == resyncMarker {
+		if board == "" {
+			h.floor = h.last
+			clear(h.boards)
+			return
+		}
+		h.boards[board] = &boardHistory{floor: h.last}
+		return
+	}
+	if update.Seq == 0 {
+		return
+	}
+
+	if h.last == 0 {
+		// Changes before the first one received are unknown
+		h.floor = update.Seq
+	}
+	h.last = max(h.last, update.Seq)
+
+	b, ok := h.boards[board]
+	if !ok {
+		b = &boardHistory{floor: h.floor}
+		h.boards[board] = b
+	}
+	b.updates = append(b.updates, update)
+	b.trim(h.size)
+}
+
+// since returns the updates of a board recorded after the one with seq, in
+// broadcast order, and whether they are all the changes of the board since
+// then. Changes are broadcast in about seq order, but not exactly (concurrent
+// writes, coalescing), so the updates after the one with seq are preferred to
+// those with a greater seq when it is still retained.
+func (h *history) since(board string, seq int64) ([]*pb.LeaderboardUpdate, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.size == 0 || h.last == 0 || seq <= 0 {
+		return nil, false
+	}
+
+	b, ok := h.boards[board]
+	if !ok {
+		return nil, seq >= h.floor
+	}
+	if seq < b.floor {
+		return nil, false
+	}
+	for i := len(b.updates) - 1; i >= 0; i-- {
+		if b.updates[i].Seq == seq {
+			return append([]*pb.LeaderboardUpdate(nil), b.updates[i+1:]...), true
+		}
+	}
+	var missed []*pb.LeaderboardUpdate
+	for _, update := range b.updates {
+		if update.Seq > seq {
+			missed = append(missed, update)
+		}
+	}
+	return missed, true
+}
+
+// trim evicts the oldest updates beyond size
+func (b *boardHistory) trim(size int) {
+	if len(b.updates) <= size {
+		return
+	}
+	evicted := len(b.updates) - size
+	for _, update := range b.updates[:evicted] {
+		b.floor = max(b.floor, update.Seq)
+	}
+	b.updates = append(b.updates[:0], b.updates[evicted:]...)
+}
@@ -0,0 +1,109 @@
+package grpc
+
+import (
+	"slices"
+	"testing"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+)
+
+// seqUpdate returns an upsert of a player carrying seq
+func seqUpdate(player string, seq int64) *pb.LeaderboardUpdate {
+	u := update(pb.LeaderboardUpdate_UPSERT, player, 100)
+	u.Seq = seq
+	return u
+}
+
+// seqs returns the seq of each update
+func seqs(updates []*pb.LeaderboardUpdate) []int64 {
+	var out []int64
+	for _, u := range updates {
+		out = append(out, u.Seq)
+	}
+	return out
+}
+
+func TestHistorySince(t *testing.T) {
+	h := newHistory(3)
+	if _, ok := h.since("level-1", 1); ok {
+		t.Error("resumable before any change was received")
+	}
+
+	h.record("level-1", seqUpdate("Alice", 10))
+	h.record("level-2", seqUpdate("Bob", 11))
+	h.record("level-1", update(pb.LeaderboardUpdate_TIER_CHANGE, "Alice", 100)) // no seq, not retained
+	h.record("level-1", seqUpdate("Carol", 13))
+	h.record("level-1", seqUpdate("Dave", 12)) // broadcast out of order
+
+	tests := []struct {
+		name  string
+		board string
+		seq   int64
+		want  []int64
+		ok    bool
+	}{
+		{"after a retained update", "level-1", 10, []int64{13, 12}, true},
+		{"in broadcast order", "level-1", 13, []int64{12}, true},
+		{"up to date", "level-1", 12, nil, true},
+		{"seq of another board", "level-1", 11, []int64{13, 12}, true},
+		{"before the first change", "level-1", 9, nil, false},
+		{"board without updates since", "level-3", 11, nil, true},
+		{"board without updates before the first change", "level-3", 5, nil, false},
+		{"no seq", "level-1", 0, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := h.since(tt.board, tt.seq)
+			if ok != tt.ok || !slices.Equal(seqs(got), tt.want) {
+				t.Errorf("since(%s, %d) = %v, %v, want %v, %v", tt.board, tt.seq, seqs(got), ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHistoryEviction(t *testing.T) {
+	h := newHistory(2)
+	for seq := int64(1); seq <= 4; seq++ {
+		h.record("level-1", seqUpdate("Alice", seq))
+	}
+	if _, ok := h.since("level-1", 1); ok {
+		t.Error("resumable from an evicted update")
+	}
+	if got, ok := h.since("level-1", 2); !ok || !slices.Equal(seqs(got), []int64{3, 4}) {
+		t.Errorf("since(2) = %v, %v, want [3 4], true", seqs(got), ok)
+	}
+
+	h.resize(1)
+	if _, ok := h.since("level-1", 2); ok {
+		t.Error("resumable from an update evicted by resize")
+	}
+	h.resize(0)
+	if _, ok := h.since("level-1", 4); ok {
+		t.Error("resumable with resuming disabled")
+	}
+}
+
+func TestHistoryResync(t *testing.T) {
+	h := newHistory(10)
+	h.record("level-1", seqUpdate("Alice", 1))
+	h.record("level-2", seqUpdate("Bob", 2))
+
+	h.record("level-1", resyncMarker)
+	if _, ok := h.since("level-1", 1); ok {
+		t.Error("level-1 resumable from before its resync")
+	}
+	if got, ok := h.since("level-1", 2); !ok || len(got) != 0 {
+		t.Errorf("since(2) after resync = %v, %v, want none, true", seqs(got), ok)
+	}
+	if _, ok := h.since("level-2", 1); !ok {
+		t.Error("resync of level-1 dropped level-2")
+	}
+
+	h.record("", resyncMarker)
+	if _, ok := h.since("level-2", 1); ok {
+		t.Error("level-2 resumable from before the resync of every board")
+	}
+	if _, ok := h.since("level-3", 2); !ok {
+		t.Error("not resumable from the last change before the resync of every board")
+	}
+}
@@ -0,0 +1,511 @@
+//go:build integration
+// +build integration
+
+package grpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/store"
+	grpcTransport "github.com/yourorg/leaderboard/internal/transport/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// pipeline is the full notify path wired the same way as cmd/server:
+// store -> trigger -> LISTEN -> dispatcher -> gRPC hub -> bufconn client
+type pipeline struct {
+	pool     *pgxpool.Pool
+	listener *notify.Listener
+	svc      *service.Service
+	client   pb.LeaderboardServiceClient
+}
+
+func setupPipeline(t *testing.T) *pipeline {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	postgresContainer, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage("postgres:18-alpine"),
+		postgres.WithDatabase("leaderboard_test"),
+		postgres.WithUsername("test"),
+		postgres.WithPassword("test"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second)),
+	)
+	if err != nil {
+		t.Fatalf("failed to start postgres container: %s", err)
+	}
+
+	connStr, err := postgresContainer.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("failed to get connection string: %s", err)
+	}
+
+	pool, err := store.NewPool(ctx, connStr)
+	if err != nil {
+		t.Fatalf("failed to create pool: %s", err)
+	}
+	logger := zerolog.New(zerolog.NewTestWriter(t)).Level(zerolog.WarnLevel)
+	if err := store.Migrate(pool, &logger); err != nil {
+		t.Fatalf("failed to run migrations: %s", err)
+	}
+
+	listener := notify.NewListener(pool, &logger, time.Hour)
+	listener.Start(ctx)
+	go func() {
+		for range listener.Errors() {
+		}
+	}()
+
+	dispatcher := notify.NewDispatcher(listener.Changes(), &logger)
+	grpcChanges := dispatcher.Subscribe()
+	go dispatcher.Run()
+
+	svc := service.New(store.NewStore(pool), &logger, service.Options{})
+
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	pb.RegisterLeaderboardServiceServer(grpcServer, grpcTransport.NewServer(svc, grpcChanges, &logger, 10, 100, 0, 0))
+	go grpcServer.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufconn: %s", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+		cancel()
+		pool.Close()
+		if err := postgresContainer.Terminate(context.Background()); err != nil {
+			t.Logf("failed to terminate container: %s", err)
+		}
+	})
+
+	p := &pipeline{pool: pool, listener: listener, svc: svc, client: pb.NewLeaderboardServiceClient(conn)}
+	p.waitForListen(t, 0)
+	return p
+}
+
+// listenerPID returns the backend PID holding the LISTEN, or 0 if none
+func (p *pipeline) listenerPID(t *testing.T) int32 {
+	t.Helper()
+	var pid int32
+	err := p.pool.QueryRow(context.Background(),
+		`SELECT COALESCE(MAX(pid), 0) FROM pg_stat_activity WHERE query ILIKE 'LISTEN %'`).Scan(&pid)
+	if err != nil {
+		t.Fatalf("failed to query listener pid: %s", err)
+	}
+	return pid
+}
+
+// waitForListen waits until a LISTEN session other than oldPID is active
+func (p *pipeline) waitForListen(t *testing.T, oldPID int32) int32 {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		if pid := p.listenerPID(t); pid != 0 && pid != oldPID {
+			return pid
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for notify listener to LISTEN")
+	return 0
+}
+
+// change returns the outbox row behind an update's seq
+func (p *pipeline) change(t *testing.T, seq int64) (player string, score int64, createdAt time.Time) {
+	t.Helper()
+	err := p.pool.QueryRow(context.Background(),
+		`SELECT player_name, score, created_at FROM score_changes WHERE id = $1`, seq).Scan(&player, &score, &createdAt)
+	if err != nil {
+		t.Fatalf("failed to read change %d: %s", seq, err)
+	}
+	return player, score, createdAt
+}
+
+// updatedAt returns the updated_at of a player's row on the default board
+func (p *pipeline) updatedAt(t *testing.T, player string) time.Time {
+	t.Helper()
+	var updatedAt time.Time
+	err := p.pool.QueryRow(context.Background(),
+		`SELECT updated_at FROM scores WHERE leaderboard_id = $1 AND player_name = $2`, service.DefaultLeaderboardID, player).Scan(&updatedAt)
+	if err != nil {
+		t.Fatalf("failed to read updated_at of %s: %s", player, err)
+	}
+	return updatedAt
+}
+
+func (p *pipeline) submit(t *testing.T, player string, score int64) {
+	t.Helper()
+	if _, err := p.svc.SubmitScore(context.Background(), service.ScoreSubmission{PlayerName: player, Score: score}); err != nil {
+		t.Fatalf("submit %s=%d failed: %s", player, score, err)
+	}
+}
+
+// recv waits for the next update of the given kind, failing after a timeout
+func recv(t *testing.T, updates <-chan *pb.LeaderboardUpdate, kind pb.LeaderboardUpdate_Kind) *pb.LeaderboardUpdate {
+	t.Helper()
+	select {
+	case u, ok := <-updates:
+		if !ok {
+			t.Fatal("stream closed unexpectedly")
+		}
+		if u.Kind != kind {
+			t.Fatalf("expected %s update, got %s", kind, u.Kind)
+		}
+		return u
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for %s update", kind)
+		return nil
+	}
+}
+
+func subscribe(t *testing.T, client pb.LeaderboardServiceClient, board string) <-chan *pb.LeaderboardUpdate {
+	t.Helper()
+	return subscribeTop(t, client, board, 10)
+}
+
+// subscribeTop subscribes to the top limit entries of a board. The stream joins
+// the hub before sending its snapshot: once the snapshot is received, every
+// later change reaches it.
+func subscribeTop(t *testing.T, client pb.LeaderboardServiceClient, board string, limit int32) <-chan *pb.LeaderboardUpdate {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	stream, err := client.StreamLeaderboard(ctx, &pb.SubscribeRequest{InitialLimit: limit, LeaderboardId: board})
+	if err != nil {
+		t.Fatalf("StreamLeaderboard failed: %s", err)
+	}
+
+	updates := make(chan *pb.LeaderboardUpdate, 100)
+	go func() {
+		defer close(updates)
+		for {
+			u, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			updates <- u
+		}
+	}()
+	return updates
+}
+
+func TestNotifyPipelineOrdering(t *testing.T) {
+	p := setupPipeline(t)
+	updates := subscribe(t, p.client, "")
+
+	snapshot := recv(t, updates, pb.LeaderboardUpdate_SNAPSHOT)
+	if len(snapshot.Snapshot) != 0 {
+		t.Fatalf("expected empty snapshot, got %d entries", len(snapshot.Snapshot))
+	}
+
+	submissions := []struct {
+		player string
+		score  int64
+	}{
+		{"Alice", 100},
+		{"Bob", 200},
+		{"Alice", 300},
+		{"Alice", 250}, // not an improvement: must not produce an update
+		{"Charlie", 150},
+	}
+	for _, s := range submissions {
+		p.submit(t, s.player, s.score)
+	}
+
+	want := []struct {
+		player string
+		score  int64
+	}{
+		{"Alice", 100},
+		{"Bob", 200},
+		{"Alice", 300},
+		{"Charlie", 150},
+	}
+
+	var lastSeq int64
+	var last time.Time
+	latest := make(map[string]time.Time) // updated_at of each player's last update
+	for i, w := range want {
+		u := recv(t, updates, pb.LeaderboardUpdate_UPSERT)
+		if u.Changed.PlayerName != w.player || u.Changed.Score != w.score {
+			t.Fatalf("update %d: got %s=%d, want %s=%d", i, u.Changed.PlayerName, u.Changed.Score, w.player, w.score)
+		}
+
+		// Seq is the id of the outbox row of the change, in commit order
+		if u.Seq <= lastSeq {
+			t.Errorf("update %d: seq %d after %d, want increasing", i, u.Seq, lastSeq)
+		}
+		lastSeq = u.Seq
+		player, score, createdAt := p.change(t, u.Seq)
+		if player != w.player || score != w.score {
+			t.Errorf("update %d: seq %d is the change %s=%d in the outbox", i, u.Seq, player, score)
+		}
+
+		// updated_at is when the change was written, not when it was delivered
+		ts := u.Changed.UpdatedTime.AsTime()
+		if !ts.Equal(createdAt) {
+			t.Errorf("update %d: updated_at %s, want %s from the outbox", i, ts, createdAt)
+		}
+		if ts.Before(last) {
+			t.Errorf("update %d: updated_at %s is before previous %s", i, ts, last)
+		}
+		last = ts
+		latest[w.player] = ts
+	}
+	for player, ts := range latest {
+		if stored := p.updatedAt(t, player); !stored.Equal(ts) {
+			t.Errorf("%s: last update at %s, row updated at %s", player, ts, stored)
+		}
+	}
+
+	// Delete flows through the same path
+	if err := p.svc.DeleteScore(context.Background(), "", "Bob"); err != nil {
+		t.Fatalf("delete failed: %s", err)
+	}
+	u := recv(t, updates, pb.LeaderboardUpdate_DELETE)
+	if u.Changed.PlayerName != "Bob" {
+		t.Errorf("expected DELETE for Bob, got %s", u.Changed.PlayerName)
+	}
+	if u.Seq <= lastSeq {
+		t.Errorf("delete: seq %d after %d, want increasing", u.Seq, lastSeq)
+	}
+
+	// Updates arrive in order: an update between the delete and a later
+	// submission would show up first
+	p.submit(t, "Dave", 50)
+	if u := recv(t, updates, pb.LeaderboardUpdate_UPSERT); u.Changed.PlayerName != "Dave" {
+		t.Errorf("unexpected extra update: %v", u)
+	}
+}
+
+func TestNotifyPipelineListenerReconnect(t *testing.T) {
+	p := setupPipeline(t)
+	updates := subscribe(t, p.client, "")
+	recv(t, updates, pb.LeaderboardUpdate_SNAPSHOT)
+
+	p.submit(t, "Alice", 100)
+	if u := recv(t, updates, pb.LeaderboardUpdate_UPSERT); u.Changed.PlayerName != "Alice" {
+		t.Fatalf("expected UPSERT for Alice, got %s", u.Changed.PlayerName)
+	}
+
+	// Forcibly kill the LISTEN session from another connection
+	oldPID := p.listenerPID(t)
+	if _, err := p.pool.Exec(context.Background(), "SELECT pg_terminate_backend($1)", oldPID); err != nil {
+		t.Fatalf("failed to terminate listener backend: %s", err)
+	}
+
+	// The listener must reconnect on a new backend without restarting the server
+	newPID := p.waitForListen(t, oldPID)
+	if newPID == oldPID {
+		t.Fatalf("listener did not reconnect (pid %d)", newPID)
+	}
+
+	// Subscribers get a fresh snapshot since notifications may have been missed
+	snapshot := recv(t, updates, pb.LeaderboardUpdate_SNAPSHOT)
+	if len(snapshot.Snapshot) != 1 || snapshot.Snapshot[0].PlayerName != "Alice" {
+		t.Fatalf("unexpected resync snapshot: %v", snapshot.Snapshot)
+	}
+
+	// The existing stream must keep working after the reconnect
+	p.submit(t, "Bob", 200)
+	if u := recv(t, updates, pb.LeaderboardUpdate_UPSERT); u.Changed.PlayerName != "Bob" || u.Changed.Score != 200 {
+		t.Fatalf("expected UPSERT Bob=200 after reconnect, got %s=%d", u.Changed.PlayerName, u.Changed.Score)
+	}
+}
+
+func TestNotifyPipelineListenerStop(t *testing.T) {
+	p := setupPipeline(t)
+	updates := subscribe(t, p.client, "")
+	recv(t, updates, pb.LeaderboardUpdate_SNAPSHOT)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.listener.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	// Stop returns once the LISTEN connection is back in the pool and the channels are closed
+	select {
+	case <-p.listener.Done():
+	default:
+		t.Fatal("Done not closed after Stop")
+	}
+	if pid := p.listenerPID(t); pid != 0 {
+		t.Errorf("LISTEN session %d still active after Stop", pid)
+	}
+	if p.listener.Listening() {
+		t.Error("Listening() after Stop")
+	}
+	if _, ok := <-p.listener.Errors(); ok {
+		t.Error("errors channel still open after Stop")
+	}
+
+	// Stopping twice is harmless, and nothing is delivered any more
+	if err := p.listener.Stop(ctx); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+	p.submit(t, "Alice", 100)
+	select {
+	case u, ok := <-updates:
+		if ok {
+			t.Errorf("update after the listener stopped: %v", u)
+		}
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestNotifyPipelineBoardRouting(t *testing.T) {
+	p := setupPipeline(t)
+	global := subscribe(t, p.client, "")
+	level := subscribe(t, p.client, "level-7")
+	recv(t, global, pb.LeaderboardUpdate_SNAPSHOT)
+	recv(t, level, pb.LeaderboardUpdate_SNAPSHOT)
+
+	_, err := p.svc.SubmitScore(context.Background(), service.ScoreSubmission{LeaderboardID: "level-7", PlayerName: "Alice", Score: 100})
+	if err != nil {
+		t.Fatalf("submit on level-7 failed: %s", err)
+	}
+	p.submit(t, "Bob", 200)
+
+	// Each subscriber only sees the change of its own board
+	if u := recv(t, level, pb.LeaderboardUpdate_UPSERT); u.Changed.PlayerName != "Alice" || u.Changed.LeaderboardId != "level-7" {
+		t.Fatalf("level-7 subscriber got %s on %q, want Alice on level-7", u.Changed.PlayerName, u.Changed.LeaderboardId)
+	}
+	if u := recv(t, global, pb.LeaderboardUpdate_UPSERT); u.Changed.PlayerName != "Bob" || u.Changed.LeaderboardId != service.DefaultLeaderboardID {
+		t.Fatalf("global subscriber got %s on %q, want Bob on global", u.Changed.PlayerName, u.Changed.LeaderboardId)
+	}
+
+	// A change of the other board would show up before the next one of its own
+	_, err = p.svc.SubmitScore(context.Background(), service.ScoreSubmission{LeaderboardID: "level-7", PlayerName: "Carol", Score: 50})
+	if err != nil {
+		t.Fatalf("submit on level-7 failed: %s", err)
+	}
+	p.submit(t, "Dave", 50)
+	if u := recv(t, level, pb.LeaderboardUpdate_UPSERT); u.Changed.PlayerName != "Carol" {
+		t.Errorf("unexpected update on level-7: %v", u)
+	}
+	if u := recv(t, global, pb.LeaderboardUpdate_UPSERT); u.Changed.PlayerName != "Dave" {
+		t.Errorf("unexpected update on global: %v", u)
+	}
+}
+
+func TestNotifyPipelineOutbox(t *testing.T) {
+	p := setupPipeline(t)
+	ctx := context.Background()
+	updates := subscribe(t, p.client, "")
+	recv(t, updates, pb.LeaderboardUpdate_SNAPSHOT)
+
+	// Watch the raw payload from a second LISTEN session
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire: %s", err)
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, "LISTEN "+notify.ScoresChangesChannel); err != nil {
+		t.Fatalf("LISTEN failed: %s", err)
+	}
+
+	p.submit(t, "Alice", 100)
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	n, err := conn.Conn().WaitForNotification(waitCtx)
+	if err != nil {
+		t.Fatalf("wait for notification: %s", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(n.Payload), &raw); err != nil {
+		t.Fatalf("invalid payload %q: %s", n.Payload, err)
+	}
+	if _, ok := raw["id"]; !ok || raw["score"] != nil {
+		t.Errorf("payload %s: want the outbox id and no score", n.Payload)
+	}
+
+	// The listener reads the full change from the outbox
+	if u := recv(t, updates, pb.LeaderboardUpdate_UPSERT); u.Changed.PlayerName != "Alice" || u.Changed.Score != 100 {
+		t.Fatalf("expected UPSERT Alice=100, got %s=%d", u.Changed.PlayerName, u.Changed.Score)
+	}
+
+	// Full payloads from a pre-outbox trigger are still accepted
+	legacy := `{"leaderboard_id":"global","player_name":"Bob","score":50,"rank_score":50,"achieved_at":"2025-01-15T10:00:00Z","op":"insert"}`
+	if _, err := p.pool.Exec(ctx, "SELECT pg_notify($1, $2)", notify.ScoresChangesChannel, legacy); err != nil {
+		t.Fatalf("pg_notify failed: %s", err)
+	}
+	if u := recv(t, updates, pb.LeaderboardUpdate_UPSERT); u.Changed.PlayerName != "Bob" || u.Changed.Score != 50 {
+		t.Fatalf("expected UPSERT Bob=50, got %s=%d", u.Changed.PlayerName, u.Changed.Score)
+	}
+
+	// A change missing from the outbox turns into a resync
+	pruned := `{"id":999999,"leaderboard_id":"global","player_name":"Carol","op":"insert"}`
+	if _, err := p.pool.Exec(ctx, "SELECT pg_notify($1, $2)", notify.ScoresChangesChannel, pruned); err != nil {
+		t.Fatalf("pg_notify failed: %s", err)
+	}
+	recv(t, updates, pb.LeaderboardUpdate_SNAPSHOT)
+}
+
+func TestResetLeaderboardResync(t *testing.T) {
+	p := setupPipeline(t)
+	updates := subscribe(t, p.client, "")
+	recv(t, updates, pb.LeaderboardUpdate_SNAPSHOT)
+
+	p.submit(t, "Alice", 100)
+	p.submit(t, "Bob", 200)
+	recv(t, updates, pb.LeaderboardUpdate_UPSERT)
+	recv(t, updates, pb.LeaderboardUpdate_UPSERT)
+
+	// Subscribers get one fresh, empty snapshot instead of a DELETE per player
+	if _, err := store.NewStore(p.pool).ResetLeaderboard(context.Background(), service.DefaultLeaderboardID, false); err != nil {
+		t.Fatalf("ResetLeaderboard failed: %s", err)
+	}
+	if u := recv(t, updates, pb.LeaderboardUpdate_SNAPSHOT); len(u.Snapshot) != 0 {
+		t.Errorf("snapshot after reset has %d entries, want 0", len(u.Snapshot))
+	}
+}
+
+func TestDeleteBackfillsView(t *testing.T) {
+	p := setupPipeline(t)
+	p.submit(t, "Alice", 300)
+	p.submit(t, "Bob", 200)
+	p.submit(t, "Carol", 100)
+
+	updates := subscribeTop(t, p.client, "", 2)
+	if u := recv(t, updates, pb.LeaderboardUpdate_SNAPSHOT); len(u.Snapshot) != 2 {
+		t.Fatalf("snapshot has %d entries, want 2", len(u.Snapshot))
+	}
+
+	if err := p.svc.DeleteScore(context.Background(), "", "Alice"); err != nil {
+		t.Fatalf("DeleteScore failed: %s", err)
+	}
+	if u := recv(t, updates, pb.LeaderboardUpdate_DELETE); u.Changed.PlayerName != "Alice" {
+		t.Errorf("expected DELETE for Alice, got %s", u.Changed.PlayerName)
+	}
+	// Carol moves into the top 2
+	if u := recv(t, updates, pb.LeaderboardUpdate_UPSERT); u.Changed.PlayerName != "Carol" || u.Changed.Score != 100 {
+		t.Errorf("expected UPSERT for Carol=100, got %s=%d", u.Changed.PlayerName, u.Changed.Score)
+	}
+}
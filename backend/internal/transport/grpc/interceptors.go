@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// UnaryRequestContext populates the request context from incoming metadata for unary RPCs,
+// and echoes the request id in the x-request-id response header
+func UnaryRequestContext() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = withRequestInfo(ctx)
+		// SetHeader only fails outside a server transport, e.g. in tests
+		_ = grpc.SetHeader(ctx, requestIDHeader(ctx))
+		return handler(ctx, req)
+	}
+}
+
+// StreamRequestContext populates the request context from incoming metadata for streaming RPCs,
+// and echoes the request id in the x-request-id response header
+func StreamRequestContext() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withRequestInfo(ss.Context())
+		_ = ss.SetHeader(requestIDHeader(ctx))
+		return handler(srv, &requestStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// UnaryUsage counts unary RPCs in the usage statistics of their API key, with their
+// outcome. It must run after UnaryRequestContext.
+func UnaryUsage(svc service.Leaderboard) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		svc.RecordUsage(ctx, path.Base(info.FullMethod), err != nil)
+		return resp, err
+	}
+}
+
+// StreamUsage counts streaming RPCs in the usage statistics of their API key when
+// they are opened: streams live for hours, and a spike is one of new streams.
+// It must run after StreamRequestContext.
+func StreamUsage(svc service.Leaderboard) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		svc.RecordUsage(ss.Context(), path.Base(info.FullMethod), false)
+		return handler(srv, ss)
+	}
+}
+
+// withRequestInfo attaches the caller info found in the incoming metadata
+func withRequestInfo(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(name string) string {
+		if values := md.Get(strings.ToLower(name)); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	info := requestctx.FromHeaders(requestctx.TransportGRPC, get)
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	info.ClientIP = requestctx.ClientIP(get, remoteAddr)
+	return requestctx.NewContext(ctx, info)
+}
+
+// requestIDHeader returns the response metadata echoing the request id of ctx
+func requestIDHeader(ctx context.Context) metadata.MD {
+	return metadata.Pairs(strings.ToLower(requestctx.HeaderRequestID), requestctx.FromContext(ctx).RequestID)
+}
+
+// requestStream overrides the context of a server stream
+type requestStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestStream) Context() context.Context {
+	return s.ctx
+}
+
+// loggerFor returns the server logger annotated with the caller of the request in ctx
+func (s *Server) loggerFor(ctx context.Context) *zerolog.Logger {
+	return requestctx.Logger(ctx, s.logger)
+}
@@ -0,0 +1,32 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryRequestContext(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-tenant-id", "acme",
+		"x-client-version", "godot-1.4.2",
+		"accept-language", "ja-JP",
+		"x-request-id", "req-42",
+	))
+
+	var got requestctx.Info
+	handler := func(ctx context.Context, _ any) (any, error) {
+		got = requestctx.FromContext(ctx)
+		return nil, nil
+	}
+	if _, err := UnaryRequestContext()(ctx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+
+	if got.Transport != requestctx.TransportGRPC || got.Tenant != "acme" || got.ClientVersion != "godot-1.4.2" || got.Locale != "ja-JP" || got.RequestID != "req-42" {
+		t.Errorf("request info = %+v", got)
+	}
+}
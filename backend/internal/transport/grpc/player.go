@@ -0,0 +1,156 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/service"
+)
+
+// defaultRankRefresh is how long a player stream waits after a change of another
+// player before reading the player's rank again. Changes arriving meanwhile are
+// covered by the same read, so a stream costs at most one rank query per interval
+// however busy the board is.
+const defaultRankRefresh = time.Second
+
+// playerStream is the state of a StreamPlayer call
+type playerStream struct {
+	board   string
+	player  string
+	stream  pb.LeaderboardService_StreamPlayerServer
+	rank    int64  // last rank sent, 0 when the player has no score
+	variant string // ranking variant that computed rank
+}
+
+// StreamPlayer implements the StreamPlayer server-streaming RPC. It sends the
+// player's standing, then a SCORE when their best changes, a RANK when other
+// players' changes move them and a DELETE when their score is deleted.
+func (s *Server) StreamPlayer(req *pb.StreamPlayerRequest, stream pb.LeaderboardService_StreamPlayerServer) error {
+	ctx := stream.Context()
+	if req.PlayerName == "" {
+		return invalidArgument(ReasonMissingField, "player_name", "player_name is required")
+	}
+	board, err := service.ResolveLeaderboardID(req.LeaderboardId)
+	if err != nil {
+		return s.fromServiceError(ctx, err, "subscribe")
+	}
+
+	// Subscribe before reading the standing: a change made in between then
+	// triggers another read instead of being lost
+	updateChan := make(chan *pb.LeaderboardUpdate, 50)
+	sub := newSubscriber(ctx, streamPlayer, 0)
+	sub.player = req.PlayerName
+	if err := s.addSubscriber(board, updateChan, sub); err != nil {
+		return err
+	}
+	defer s.removeSubscriber(board, updateChan)
+
+	p := &playerStream{board: board, player: req.PlayerName, stream: stream}
+	if err := s.sendStanding(ctx, p, pb.PlayerUpdate_SNAPSHOT, 0); err != nil {
+		return err
+	}
+
+	s.loggerFor(ctx).Info().Str("leaderboard", board).Str("player", p.player).Msg("client subscribed to player stream")
+
+	heartbeat, stopHeartbeat := s.heartbeatTicker()
+	defer stopHeartbeat()
+
+	// Armed by changes of other players while the player is ranked
+	var refresh <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.loggerFor(ctx).Info().Msg("client disconnected from player stream")
+			return nil
+		case <-sub.kicked:
+			return s.disconnected(ctx, sub)
+		case <-heartbeat:
+			if err := s.sendPlayerUpdate(ctx, p, &pb.PlayerUpdate{
+				Kind:       pb.PlayerUpdate_HEARTBEAT,
+				ServerTime: time.Now().UTC().Format(time.RFC3339),
+			}); err != nil {
+				return err
+			}
+		case <-refresh:
+			refresh = nil
+			if err := s.sendStanding(ctx, p, pb.PlayerUpdate_RANK, 0); err != nil {
+				return err
+			}
+		case update := <-updateChan:
+			switch {
+			case sub.needsResync(update, updateChan):
+				refresh = nil
+				if err := s.sendStanding(ctx, p, pb.PlayerUpdate_SNAPSHOT, 0); err != nil {
+					return err
+				}
+			case update.Kind != pb.LeaderboardUpdate_UPSERT && update.Kind != pb.LeaderboardUpdate_DELETE:
+				continue
+			case update.Changed.PlayerName != p.player:
+				metrics.StreamUpdates.WithLabelValues("filtered").Inc()
+				if p.rank > 0 && refresh == nil {
+					refresh = time.After(s.rankRefresh)
+				}
+			case update.Kind == pb.LeaderboardUpdate_DELETE:
+				p.rank, refresh = 0, nil
+				if err := s.sendPlayerUpdate(ctx, p, &pb.PlayerUpdate{Kind: pb.PlayerUpdate_DELETE, Seq: update.Seq}); err != nil {
+					return err
+				}
+			default:
+				if err := s.sendStanding(ctx, p, pb.PlayerUpdate_SCORE, update.Seq); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// sendStanding reads the player's best and rank and sends them as an update of
+// the given kind. A RANK is only sent when the rank moved, as computed by the
+// ranking variant of the last one sent so that a ranking experiment does not
+// make it flap. A SCORE or RANK of a player deleted meanwhile is skipped: the
+// DELETE follows on the stream.
+func (s *Server) sendStanding(ctx context.Context, p *playerStream, kind pb.PlayerUpdate_Kind, seq int64) error {
+	rank, err := s.svc.GetPlayerRank(ctx, p.board, p.player)
+	if errors.Is(err, service.ErrPlayerNotFound) {
+		if kind != pb.PlayerUpdate_SNAPSHOT {
+			return nil
+		}
+		p.rank = 0
+		return s.sendPlayerUpdate(ctx, p, &pb.PlayerUpdate{Kind: kind, NotFound: true})
+	}
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("player", p.player).Msg("failed to get player rank")
+		return internalError("failed to get player rank")
+	}
+	if kind == pb.PlayerUpdate_RANK && (rank.Rank == p.rank || rank.RankingVariant != p.variant) {
+		return nil
+	}
+
+	update := &pb.PlayerUpdate{
+		Kind:  kind,
+		Entry: s.toEntry(rank.Score, s.svc.PlayerProfiles(ctx, []string{rank.Score.PlayerName})),
+		Rank:  rank.Rank,
+		Seq:   seq,
+	}
+	if kind != pb.PlayerUpdate_SNAPSHOT {
+		update.PreviousRank = p.rank
+	}
+	p.rank, p.variant = rank.Rank, rank.RankingVariant
+	return s.sendPlayerUpdate(ctx, p, update)
+}
+
+// sendPlayerUpdate sends an update on a player stream
+func (s *Server) sendPlayerUpdate(ctx context.Context, p *playerStream, update *pb.PlayerUpdate) error {
+	if err := p.stream.Send(update); err != nil {
+		s.loggerFor(ctx).Error().Err(err).Msg("failed to send player update")
+		return internalError("failed to send update")
+	}
+	if update.Kind != pb.PlayerUpdate_HEARTBEAT {
+		metrics.StreamUpdates.WithLabelValues("sent").Inc()
+	}
+	return nil
+}
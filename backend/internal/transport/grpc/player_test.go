@@ -0,0 +1,131 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// testPlayerStream is a StreamPlayer server stream forwarding what it is sent
+type testPlayerStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	updates chan *pb.PlayerUpdate
+}
+
+func (s *testPlayerStream) Context() context.Context { return s.ctx }
+
+func (s *testPlayerStream) Send(update *pb.PlayerUpdate) error {
+	s.updates <- update
+	return nil
+}
+
+func TestStreamPlayer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := service.New(st, &logger, service.Options{})
+
+	changes := make(chan notify.ScoreChange)
+	s := NewServer(svc, changes, &logger, 10, 10, 0, 0)
+	s.rankRefresh = 10 * time.Millisecond
+
+	// submit writes a score and broadcasts its change like the notify listener would
+	seq := int64(0)
+	submit := func(name string, score int64) int64 {
+		t.Helper()
+		if _, err := svc.SubmitScore(ctx, service.ScoreSubmission{PlayerName: name, Score: score}); err != nil {
+			t.Fatal(err)
+		}
+		seq++
+		changes <- notify.ScoreChange{ID: seq, LeaderboardID: "global", PlayerName: name, Score: score, RankScore: score, Op: "update"}
+		return seq
+	}
+	open := func(name string) chan *pb.PlayerUpdate {
+		stream := &testPlayerStream{ctx: ctx, updates: make(chan *pb.PlayerUpdate, 10)}
+		go s.StreamPlayer(&pb.StreamPlayerRequest{PlayerName: name}, stream)
+		return stream.updates
+	}
+	next := func(updates chan *pb.PlayerUpdate) *pb.PlayerUpdate {
+		t.Helper()
+		select {
+		case u := <-updates:
+			return u
+		case <-time.After(time.Second):
+			t.Fatal("no update")
+			return nil
+		}
+	}
+
+	// Written before any stream opens, so none gets their changes
+	for name, score := range map[string]int64{"Alice": 300, "Bob": 200} {
+		if _, err := svc.SubmitScore(ctx, service.ScoreSubmission{PlayerName: name, Score: score}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bob := open("Bob")
+	if u := next(bob); u.Kind != pb.PlayerUpdate_SNAPSHOT || u.Rank != 2 || u.Entry.GetScore() != 200 || u.NotFound {
+		t.Fatalf("first update = %v, want a SNAPSHOT at rank 2 with 200", u)
+	}
+	dave := open("Dave")
+	if u := next(dave); u.Kind != pb.PlayerUpdate_SNAPSHOT || !u.NotFound || u.Rank != 0 {
+		t.Fatalf("first update of a player without score = %v, want a not_found SNAPSHOT", u)
+	}
+
+	// Carol enters behind Bob, then overtakes him: only the second change moves him
+	submit("Carol", 100)
+	submit("Carol", 400)
+	if u := next(bob); u.Kind != pb.PlayerUpdate_RANK || u.Rank != 3 || u.PreviousRank != 2 || u.Entry.GetScore() != 200 {
+		t.Errorf("after Carol overtook Bob: %v, want a RANK from 2 to 3", u)
+	}
+
+	seqBob := submit("Bob", 500)
+	if u := next(bob); u.Kind != pb.PlayerUpdate_SCORE || u.Rank != 1 || u.PreviousRank != 3 || u.Entry.GetScore() != 500 || u.Seq != seqBob {
+		t.Errorf("after Bob's best: %v, want a SCORE of 500 at rank 1 (from 3) with seq %d", u, seqBob)
+	}
+
+	if err := svc.DeleteScore(ctx, "", "Bob"); err != nil {
+		t.Fatal(err)
+	}
+	changes <- notify.ScoreChange{ID: seq + 1, LeaderboardID: "global", PlayerName: "Bob", Score: 500, Op: "delete"}
+	if u := next(bob); u.Kind != pb.PlayerUpdate_DELETE || u.Seq != seq+1 || u.Entry != nil {
+		t.Errorf("after Bob's delete: %v, want a DELETE", u)
+	}
+
+	// Dave is not ranked: other players' changes do not concern him
+	submit("Alice", 600)
+	seqDave := submit("Dave", 50)
+	if u := next(dave); u.Kind != pb.PlayerUpdate_SCORE || u.Rank != 3 || u.PreviousRank != 0 || u.Seq != seqDave {
+		t.Errorf("after Dave's first score: %v, want a SCORE at rank 3 from 0", u)
+	}
+
+	s.broadcastAll(resyncMarker)
+	if u := next(dave); u.Kind != pb.PlayerUpdate_SNAPSHOT || u.Rank != 3 {
+		t.Errorf("after a resync: %v, want a SNAPSHOT at rank 3", u)
+	}
+	if u := next(bob); u.Kind != pb.PlayerUpdate_SNAPSHOT || !u.NotFound {
+		t.Errorf("Bob's stream got %v after his delete and a resync, want a not_found SNAPSHOT", u)
+	}
+}
+
+func TestStreamPlayerRequiresName(t *testing.T) {
+	s := newHub()
+	err := s.StreamPlayer(&pb.StreamPlayerRequest{}, &testPlayerStream{ctx: context.Background()})
+	if st, info, _ := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonMissingField {
+		t.Errorf("StreamPlayer without player_name = %v, want InvalidArgument %s", err, ReasonMissingField)
+	}
+}
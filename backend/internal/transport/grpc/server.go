@@ -3,289 +3,1484 @@ package grpc
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
 	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/maintenance"
+	"github.com/yourorg/leaderboard/internal/metrics"
 	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/requestctx"
 	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/status"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/version"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// resyncMarker is queued to every subscriber after the notify listener reconnects,
+// and to the subscribers of a board after it is reset; a subscriber whose queue is
+// full is flagged instead (subscriber.resync). It never reaches clients: the
+// stream goroutine replaces it with a fresh snapshot.
+var resyncMarker = &pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_SNAPSHOT}
+
 // Server implements the gRPC LeaderboardService
 type Server struct {
 	pb.UnimplementedLeaderboardServiceServer
-	svc            *service.Service
-	logger         *zerolog.Logger
-	notifyListener *notify.Listener
+	svc     service.Leaderboard
+	status  *status.Reporter        // nil leaves the status out of GetServerInfo
+	archive *maintenance.ArchiveJob // nil fails PreviewExpirations
+	logger  *zerolog.Logger
+	changes <-chan notify.ScoreChange
 
-	// Broadcast channel for real-time updates
-	mu          sync.RWMutex
-	subscribers map[chan *pb.LeaderboardUpdate]struct{}
+	// Broadcast channels for real-time updates, by leaderboard id. A change is
+	// only offered to the subscribers of its board, so broadcasting costs the
+	// same whatever the number of boards.
+	mu              sync.RWMutex
+	subscribers     map[string]map[chan *pb.LeaderboardUpdate]*subscriber
+	subscriberCount int
+	lastSubscriber  uint64 // id of the last subscriber added
 
-	defaultLimit int32
-	maxLimit     int32
+	// peerStreams counts the subscribers of each client address, for the stream
+	// quotas set by operators
+	peerStreams map[netip.Addr]int
+	quotas      map[netip.Addr]int
+
+	// maxSubscribers caps the subscribers of each board, 0 for no cap
+	maxSubscribers int
+
+	// history retains the last updates of each board for resumed streams.
+	// Updates are recorded under mu, so a subscriber resuming from it misses none.
+	history *history
+
+	// limits are the default and maximum page sizes, replaced by SetPageLimits
+	limits atomic.Pointer[pageLimits]
+
+	// heartbeat is the interval of HEARTBEAT updates on streams, 0 disables them
+	heartbeat time.Duration
+
+	// coalesce is the window in which changes of a player are merged before
+	// broadcasting, 0 broadcasts every change as it arrives
+	coalesce time.Duration
+
+	// rankRefresh is how long player streams wait before reading the rank of
+	// their player again after changes of other players
+	rankRefresh time.Duration
+
+	// v2 serves leaderboard.v2, and the v1 RPCs it superseded
+	v2 *V2Server
 }
 
-// NewServer creates a new gRPC server
-func NewServer(svc *service.Service, listener *notify.Listener, logger *zerolog.Logger, defaultLimit, maxLimit int32) *Server {
+// NewServer creates a new gRPC server. Streams send a HEARTBEAT update every
+// heartbeat so that NATs and proxies do not drop them while idle (0 disables it).
+// Score changes of a player within coalesce are broadcast once, as the latest
+// of them (0 disables coalescing).
+func NewServer(svc service.Leaderboard, changes <-chan notify.ScoreChange, logger *zerolog.Logger, defaultLimit, maxLimit int32, heartbeat, coalesce time.Duration) *Server {
 	s := &Server{
-		svc:            svc,
-		logger:         logger,
-		notifyListener: listener,
-		subscribers:    make(map[chan *pb.LeaderboardUpdate]struct{}),
-		defaultLimit:   defaultLimit,
-		maxLimit:       maxLimit,
+		svc:         svc,
+		logger:      logger,
+		changes:     changes,
+		subscribers: make(map[string]map[chan *pb.LeaderboardUpdate]*subscriber),
+		peerStreams: make(map[netip.Addr]int),
+		quotas:      make(map[netip.Addr]int),
+		history:     newHistory(0),
+		heartbeat:   heartbeat,
+		coalesce:    coalesce,
+		rankRefresh: defaultRankRefresh,
 	}
+	s.v2 = &V2Server{s: s}
+	s.SetPageLimits(defaultLimit, maxLimit)
 
 	// Start broadcasting notifications to subscribers
 	go s.broadcastNotifications()
+	go s.broadcastTierChanges()
 
 	return s
 }
 
-// SubmitScore implements the SubmitScore RPC
-func (s *Server) SubmitScore(ctx context.Context, req *pb.SubmitScoreRequest) (*pb.SubmitScoreResponse, error) {
+// SetResumeBuffer sets how many updates of each board are retained for streams
+// resuming from a seq (0, the default, disables resuming).
+func (s *Server) SetResumeBuffer(size int) {
+	s.history.resize(size)
+}
+
+// SetMaxSubscribers caps the streams of each board, counting leaderboard,
+// subscription and player streams alike (0, the default, disables the cap).
+// Streams beyond it fail with ResourceExhausted. Call it before serving.
+func (s *Server) SetMaxSubscribers(max int) {
+	s.maxSubscribers = max
+}
+
+// SetStatusReporter sets the reporter of the status returned by GetServerInfo.
+// The health checker behind it reads the server's subscriber count, so it can
+// only be set once the server exists; call it before serving.
+func (s *Server) SetStatusReporter(r *status.Reporter) {
+	s.status = r
+}
+
+// SetArchiveJob sets the archival job previewed by PreviewExpirations, when it
+// is scheduled. Call it before serving.
+func (s *Server) SetArchiveJob(job *maintenance.ArchiveJob) {
+	s.archive = job
+}
+
+// VerifyReceipt implements the VerifyReceipt RPC
+func (s *Server) VerifyReceipt(ctx context.Context, req *pb.VerifyReceiptRequest) (*pb.VerifyReceiptResponse, error) {
+	if req.Receipt == nil {
+		return nil, invalidArgument(ReasonMissingField, "receipt", "receipt is required")
+	}
+	r := req.Receipt
+	res, err := s.svc.VerifyReceipt(service.Receipt{
+		LeaderboardID: r.LeaderboardId,
+		PlayerName:    r.PlayerName,
+		Score:         r.Score,
+		IssuedAt:      r.IssuedAt,
+		Applied:       r.Applied,
+		KeyID:         r.KeyId,
+		Signature:     r.Signature,
+	})
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "verify receipt")
+	}
+
+	return &pb.VerifyReceiptResponse{
+		Valid:      res.Valid,
+		Result:     res.Result,
+		CurrentKey: res.CurrentKey,
+	}, nil
+}
+
+// offlineOutcomes maps service offline run outcomes to their protobuf enum
+var offlineOutcomes = map[string]pb.OfflineRunResult_Outcome{
+	service.OfflineApplied:     pb.OfflineRunResult_APPLIED,
+	service.OfflineNotImproved: pb.OfflineRunResult_NOT_IMPROVED,
+	service.OfflineInvalid:     pb.OfflineRunResult_INVALID,
+	service.OfflineOutOfWindow: pb.OfflineRunResult_OUT_OF_WINDOW,
+	service.OfflineLimited:     pb.OfflineRunResult_LIMITED,
+	service.OfflineBanned:      pb.OfflineRunResult_BANNED,
+}
+
+// SyncOfflineScores implements the SyncOfflineScores RPC
+func (s *Server) SyncOfflineScores(ctx context.Context, req *pb.SyncOfflineScoresRequest) (*pb.SyncOfflineScoresResponse, error) {
+	if req.Signature == "" {
+		return nil, fieldError(codes.Unauthenticated, ReasonInvalidSignature, "signature", "signature is required")
+	}
+
+	runs := make([]service.OfflineRun, len(req.Runs))
+	for i, r := range req.Runs {
+		runs[i] = service.OfflineRun{
+			RunID:         r.RunId,
+			LeaderboardID: r.LeaderboardId,
+			PlayerName:    r.PlayerName,
+			Score:         r.Score,
+			AchievedAt:    r.AchievedAt,
+		}
+	}
+
+	results, err := s.svc.SyncOfflineScores(ctx, service.OfflineBatch{
+		DeviceID:  req.DeviceId,
+		Runs:      runs,
+		Signature: req.Signature,
+	})
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "sync offline scores")
+	}
+
+	resp := &pb.SyncOfflineScoresResponse{Results: make([]*pb.OfflineRunResult, len(results))}
+	for i, r := range results {
+		out := &pb.OfflineRunResult{
+			RunId:   r.RunID,
+			Outcome: offlineOutcomes[r.Outcome],
+			Reason:  r.Reason,
+		}
+		if r.Entry != nil {
+			out.Entry = &pb.ScoreEntry{
+				PlayerName:     r.Entry.PlayerName,
+				Score:          r.Entry.Score,
+				UpdatedAt:      formatTime(r.Entry.UpdatedAt, time.RFC3339),
+				Tier:           s.svc.TierFor(r.Entry.LeaderboardID, r.Entry.Score),
+				AchievedAt:     formatTime(r.Entry.AchievedAt, time.RFC3339Nano),
+				LeaderboardId:  r.Entry.LeaderboardID,
+				Metadata:       r.Entry.Metadata,
+				SecondaryScore: r.Entry.SecondaryScore,
+				Region:         r.Entry.Country,
+				Platform:       r.Entry.Platform,
+				UpdatedTime:    toTimestamp(r.Entry.UpdatedAt),
+				AchievedTime:   toTimestamp(r.Entry.AchievedAt),
+			}
+		}
+		resp.Results[i] = out
+	}
+	return resp, nil
+}
+
+// GetChangesSince implements the GetChangesSince RPC
+func (s *Server) GetChangesSince(ctx context.Context, req *pb.GetChangesSinceRequest) (*pb.GetChangesSinceResponse, error) {
+	var since time.Time
+	if req.SinceTime != "" {
+		t, err := time.Parse(time.RFC3339, req.SinceTime)
+		if err != nil {
+			return nil, invalidArgument(ReasonInvalidTimestamp, "since_time", "since_time must be an RFC3339 timestamp")
+		}
+		since = t
+	}
+
+	page, err := s.svc.GetChangesSince(ctx, req.LeaderboardId, req.SinceSeq, since, req.Limit)
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "get changes")
+	}
+
+	var names []string
+	for _, change := range page.Changes {
+		if change.Op != "delete" {
+			names = append(names, change.PlayerName)
+		}
+	}
+	profiles := s.svc.PlayerProfiles(ctx, names)
+	resp := &pb.GetChangesSinceResponse{
+		Changes: make([]*pb.LeaderboardUpdate, 0, len(page.Changes)),
+		NextSeq: page.LastID,
+		HasMore: page.More,
+		Resync:  page.Resync,
+	}
+	for _, change := range page.Changes {
+		update := s.changeUpdate(change.LeaderboardID, change)
+		if update == nil {
+			continue
+		}
+		if p, ok := profiles[change.PlayerName]; ok && update.Kind == pb.LeaderboardUpdate_UPSERT {
+			update.Changed.Profile = toProfile(p)
+		}
+		resp.Changes = append(resp.Changes, update)
+	}
+	return resp, nil
+}
+
+// UpsertPlayerProfile implements the UpsertPlayerProfile RPC
+func (s *Server) UpsertPlayerProfile(ctx context.Context, req *pb.UpsertPlayerProfileRequest) (*pb.UpsertPlayerProfileResponse, error) {
 	if req.PlayerName == "" {
-		return nil, status.Error(codes.InvalidArgument, "player_name is required")
+		return nil, invalidArgument(ReasonMissingField, "player_name", "player_name is required")
 	}
-	if req.Score < 0 {
-		return nil, status.Error(codes.InvalidArgument, "score must be non-negative")
+
+	profile, err := s.svc.UpsertPlayerProfile(ctx, service.ProfileUpdate{
+		PlayerName:  req.PlayerName,
+		DisplayName: req.DisplayName,
+		CountryCode: req.CountryCode,
+		AvatarURL:   req.AvatarUrl,
+	})
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "upsert player profile")
 	}
 
-	result, err := s.svc.SubmitScore(ctx, req.PlayerName, req.Score)
+	return &pb.UpsertPlayerProfileResponse{Profile: toProfile(*profile)}, nil
+}
+
+// GetPlayerProfile implements the GetPlayerProfile RPC
+func (s *Server) GetPlayerProfile(ctx context.Context, req *pb.GetPlayerProfileRequest) (*pb.GetPlayerProfileResponse, error) {
+	if req.PlayerName == "" {
+		return nil, invalidArgument(ReasonMissingField, "player_name", "player_name is required")
+	}
+
+	profile, err := s.svc.GetPlayerProfile(ctx, req.PlayerName)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidPlayerName) {
-			return nil, status.Error(codes.InvalidArgument, err.Error())
+		if errors.Is(err, service.ErrPlayerNotFound) {
+			return &pb.GetPlayerProfileResponse{NotFound: true}, nil
 		}
-		if errors.Is(err, service.ErrInvalidScore) {
-			return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, s.fromServiceError(ctx, err, "get player profile")
+	}
+
+	return &pb.GetPlayerProfileResponse{Profile: toProfile(*profile)}, nil
+}
+
+// UpsertLeaderboard implements the UpsertLeaderboard RPC
+func (s *Server) UpsertLeaderboard(ctx context.Context, req *pb.UpsertLeaderboardRequest) (*pb.UpsertLeaderboardResponse, error) {
+	if req.Leaderboard == nil {
+		return nil, invalidArgument(ReasonMissingField, "leaderboard", "leaderboard is required")
+	}
+	order, err := fromSortOrder(req.Leaderboard.SortOrder)
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "upsert leaderboard")
+	}
+	secondary, err := fromSortOrder(req.Leaderboard.SecondarySortOrder)
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "upsert leaderboard")
+	}
+
+	def, err := s.svc.UpsertLeaderboard(ctx, req.Leaderboard.LeaderboardId, order, secondary)
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "upsert leaderboard")
+	}
+
+	return &pb.UpsertLeaderboardResponse{Leaderboard: toLeaderboard(*def)}, nil
+}
+
+// GetLeaderboard implements the GetLeaderboard RPC
+func (s *Server) GetLeaderboard(ctx context.Context, req *pb.GetLeaderboardRequest) (*pb.GetLeaderboardResponse, error) {
+	def, err := s.svc.GetLeaderboard(ctx, req.LeaderboardId)
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "get leaderboard")
+	}
+
+	return &pb.GetLeaderboardResponse{Leaderboard: toLeaderboard(*def)}, nil
+}
+
+// ResetLeaderboard implements the ResetLeaderboard RPC
+func (s *Server) ResetLeaderboard(ctx context.Context, req *pb.ResetLeaderboardRequest) (*pb.ResetLeaderboardResponse, error) {
+	ctx, err := s.authenticateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.svc.ResetLeaderboard(ctx, req.LeaderboardId, req.Snapshot, req.ConfirmationToken)
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "reset leaderboard")
+	}
+
+	resp := &pb.ResetLeaderboardResponse{
+		LeaderboardId:     res.LeaderboardID,
+		Completed:         res.Completed,
+		Entries:           res.Entries,
+		ConfirmationToken: res.ConfirmationToken,
+		Deleted:           res.Deleted,
+		SnapshotId:        res.SnapshotID,
+	}
+	if !res.ExpiresAt.IsZero() {
+		resp.ExpiresAt = res.ExpiresAt.Format(time.RFC3339)
+	}
+	return resp, nil
+}
+
+// RenamePlayer implements the RenamePlayer RPC. Admin only.
+func (s *Server) RenamePlayer(ctx context.Context, req *pb.RenamePlayerRequest) (*pb.RenamePlayerResponse, error) {
+	ctx, err := s.authenticateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.svc.RenamePlayer(ctx, req.PlayerName, req.NewPlayerName, req.Merge)
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "rename player")
+	}
+
+	var profiles map[string]store.Player
+	resp := &pb.RenamePlayerResponse{PlayerName: req.NewPlayerName, MergedLeaderboardIds: res.Merged}
+	if res.Profile != nil {
+		profiles = map[string]store.Player{req.NewPlayerName: *res.Profile}
+		resp.Profile = toProfile(*res.Profile)
+	}
+	for _, score := range res.Scores {
+		resp.Entries = append(resp.Entries, s.toEntry(score, profiles))
+	}
+	return resp, nil
+}
+
+// PreviewExpirations implements the PreviewExpirations RPC. Admin only.
+func (s *Server) PreviewExpirations(ctx context.Context, req *pb.PreviewExpirationsRequest) (*pb.PreviewExpirationsResponse, error) {
+	ctx, err := s.authenticateAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if s.archive == nil {
+		return nil, statusError(codes.FailedPrecondition, ReasonArchiveDisabled, "archival is not scheduled (PRUNE_SCHEDULE unset)")
+	}
+	days := int(req.Days)
+	if days == 0 {
+		days = maintenance.DefaultPreviewDays
+	}
+
+	preview, err := s.archive.Preview(ctx, days, s.clampLimit(req.Limit), req.PageToken)
+	switch {
+	case errors.Is(err, maintenance.ErrInvalidPreviewDays):
+		return nil, invalidArgument(ReasonInvalidPreviewDays, "days", err.Error())
+	case errors.Is(err, maintenance.ErrInvalidPageToken):
+		return nil, invalidArgument(ReasonInvalidPageToken, "page_token", err.Error())
+	case err != nil:
+		return nil, s.fromServiceError(ctx, err, "preview expirations")
+	}
+
+	resp := &pb.PreviewExpirationsResponse{
+		Entries:       make([]*pb.ExpiringScore, len(preview.Entries)),
+		NextPageToken: preview.NextPageToken,
+		DryRun:        preview.DryRun,
+	}
+	for i, e := range preview.Entries {
+		resp.Entries[i] = &pb.ExpiringScore{
+			LeaderboardId: e.LeaderboardID,
+			PlayerName:    e.PlayerName,
+			Score:         e.Score,
+			UpdatedAt:     e.UpdatedAt,
+			Reason:        e.Reason,
+			ExpiresAt:     e.ExpiresAt,
 		}
-		s.logger.Error().Err(err).Msg("failed to submit score")
-		return nil, status.Error(codes.Internal, "failed to submit score")
 	}
+	return resp, nil
+}
 
-	return &pb.SubmitScoreResponse{
-		Applied: result.Applied,
-		Entry: &pb.ScoreEntry{
-			PlayerName: result.PlayerName,
-			Score:      result.Score,
-			UpdatedAt:  result.UpdatedAt,
-		},
+// GetCurrentDailyBoard implements the GetCurrentDailyBoard RPC
+func (s *Server) GetCurrentDailyBoard(ctx context.Context, req *pb.GetCurrentDailyBoardRequest) (*pb.GetCurrentDailyBoardResponse, error) {
+	board, err := s.svc.CurrentDailyBoard(ctx)
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "get daily board")
+	}
+
+	return &pb.GetCurrentDailyBoardResponse{
+		LeaderboardId:         board.LeaderboardID,
+		Date:                  board.Date,
+		StartsAt:              board.StartsAt.Format(time.RFC3339),
+		EndsAt:                board.EndsAt.Format(time.RFC3339),
+		Seed:                  board.Seed,
+		SortOrder:             toSortOrder(board.SortOrder),
+		PreviousLeaderboardId: board.PreviousLeaderboardID,
 	}, nil
 }
 
-// GetTopScores implements the GetTopScores RPC
-func (s *Server) GetTopScores(ctx context.Context, req *pb.GetTopScoresRequest) (*pb.GetTopScoresResponse, error) {
-	limit := req.Limit
-	if limit <= 0 {
-		limit = s.defaultLimit
+// Protocol capabilities reported by GetServerInfo
+const (
+	CapabilityPageTokens       = "page_tokens"       // GetTopScores keyset pagination
+	CapabilityFieldMasks       = "field_masks"       // GetTopScores read_mask
+	CapabilitySubscribeControl = "subscribe_control" // SubscribeLeaderboard bidirectional stream
+	CapabilityErrorDetails     = "error_details"     // machine-readable google.rpc.ErrorInfo reasons
+	CapabilityRankingVariant   = "ranking_variant"   // rank reads say which ranking served them
+)
+
+// apiVersion is the proto package version of the service
+const apiVersion = "v1"
+
+// GetServerInfo implements the GetServerInfo RPC
+func (s *Server) GetServerInfo(ctx context.Context, req *pb.GetServerInfoRequest) (*pb.GetServerInfoResponse, error) {
+	info, err := s.svc.ServerInfo(ctx)
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "get server info")
 	}
-	if limit > s.maxLimit {
-		limit = s.maxLimit
+
+	limits := s.pageLimits()
+	resp := &pb.GetServerInfoResponse{
+		Version:    version.String(),
+		ApiVersion: apiVersion,
+		Capabilities: []string{
+			CapabilityPageTokens,
+			CapabilityFieldMasks,
+			CapabilitySubscribeControl,
+			CapabilityErrorDetails,
+			CapabilityRankingVariant,
+		},
+		Boards: make([]*pb.ServerBoard, len(info.Boards)),
+		Limits: &pb.ServerLimits{
+			DefaultPageSize:        limits.defaultLimit,
+			MaxPageSize:            limits.maxLimit,
+			MinPlayerNameLength:    int32(info.Limits.MinPlayerNameLength),
+			MaxPlayerNameLength:    int32(info.Limits.MaxPlayerNameLength),
+			MaxLeaderboardIdLength: int32(info.Limits.MaxLeaderboardIDLength),
+			MaxOfflineRuns:         int32(info.Limits.MaxOfflineRuns),
+			StreamHeartbeatSeconds: int32(s.heartbeat / time.Second),
+			MaxScore:               info.Limits.MaxScore,
+			PlayerNamePattern:      info.Limits.PlayerNamePattern,
+		},
+		Features: &pb.ServerFeatures{
+			SubmitSignatures:  info.Features.SubmitSignatures,
+			OfflineSync:       info.Features.OfflineSync,
+			Receipts:          info.Features.Receipts,
+			Tiers:             info.Features.Tiers,
+			PlayerIdentities:  info.Features.PlayerIdentities,
+			RankingExperiment: info.Features.RankingExperiment,
+		},
+		ServerTime: time.Now().UTC().Format(time.RFC3339),
 	}
+	for i, b := range info.Boards {
+		resp.Boards[i] = &pb.ServerBoard{LeaderboardId: b.LeaderboardID, SortOrder: toSortOrder(b.SortOrder), Daily: b.Daily}
+	}
+	if s.status != nil {
+		resp.Status = s.status.Report(ctx).Status
+	}
+	return resp, nil
+}
 
-	offset := req.Offset
-	if offset < 0 {
-		offset = 0
+// authenticateAdmin checks the bearer token of the authorization metadata
+func (s *Server) authenticateAdmin(ctx context.Context) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = requestctx.BearerToken(values[0])
+		}
 	}
 
-	scores, err := s.svc.GetTopScores(ctx, limit, offset)
+	authCtx, err := s.svc.AuthenticateAdmin(ctx, token)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to get top scores")
-		return nil, status.Error(codes.Internal, "failed to get top scores")
+		return nil, s.fromServiceError(ctx, err, "authenticate admin")
 	}
+	return authCtx, nil
+}
 
-	entries := make([]*pb.ScoreEntry, len(scores))
-	for i, score := range scores {
-		entries[i] = &pb.ScoreEntry{
-			PlayerName: score.PlayerName,
-			Score:      score.Score,
-			UpdatedAt:  score.UpdatedAt.Time.Format(time.RFC3339),
+// GetPercentileBuckets implements the GetPercentileBuckets RPC
+func (s *Server) GetPercentileBuckets(ctx context.Context, req *pb.GetPercentileBucketsRequest) (*pb.GetPercentileBucketsResponse, error) {
+	snapshot, err := s.svc.GetPercentileBuckets(ctx, req.LeaderboardId, service.Segment{Region: req.Region, Platform: req.Platform})
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "get percentile buckets")
+	}
+
+	buckets := make([]*pb.PercentileBucket, len(snapshot.Buckets))
+	for i, b := range snapshot.Buckets {
+		buckets[i] = &pb.PercentileBucket{
+			TopPercent: b.TopPercent,
+			MinScore:   b.MinScore,
 		}
 	}
 
-	return &pb.GetTopScoresResponse{
-		Entries: entries,
+	return &pb.GetPercentileBucketsResponse{
+		Buckets:      buckets,
+		TotalPlayers: snapshot.TotalPlayers,
+		SortOrder:    toSortOrder(snapshot.SortOrder),
 	}, nil
 }
 
-// GetPlayerRank implements the GetPlayerRank RPC
-func (s *Server) GetPlayerRank(ctx context.Context, req *pb.GetPlayerRankRequest) (*pb.GetPlayerRankResponse, error) {
-	if req.PlayerName == "" {
-		return nil, status.Error(codes.InvalidArgument, "player_name is required")
+// GetEntryCount implements the GetEntryCount RPC
+func (s *Server) GetEntryCount(ctx context.Context, req *pb.GetEntryCountRequest) (*pb.GetEntryCountResponse, error) {
+	total, err := s.svc.GetEntryCount(ctx, req.LeaderboardId, service.Segment{Region: req.Region, Platform: req.Platform})
+	if err != nil {
+		return nil, s.fromServiceError(ctx, err, "get entry count")
 	}
+	return &pb.GetEntryCountResponse{TotalCount: total}, nil
+}
 
-	rank, score, err := s.svc.GetPlayerRank(ctx, req.PlayerName)
+// SimulateRank implements the SimulateRank RPC
+func (s *Server) SimulateRank(ctx context.Context, req *pb.SimulateRankRequest) (*pb.SimulateRankResponse, error) {
+	sim, err := s.svc.SimulateRank(ctx, req.LeaderboardId, req.Score, req.PlayerName, service.Segment{Region: req.Region, Platform: req.Platform})
 	if err != nil {
-		if errors.Is(err, service.ErrPlayerNotFound) {
-			return &pb.GetPlayerRankResponse{
-				NotFound: true,
-			}, nil
-		}
-		if errors.Is(err, service.ErrInvalidPlayerName) {
-			return nil, status.Error(codes.InvalidArgument, err.Error())
-		}
-		s.logger.Error().Err(err).Msg("failed to get player rank")
-		return nil, status.Error(codes.Internal, "failed to get player rank")
+		return nil, s.fromServiceError(ctx, err, "simulate rank")
 	}
 
-	return &pb.GetPlayerRankResponse{
-		NotFound: false,
-		Rank:     rank,
-		Entry: &pb.ScoreEntry{
-			PlayerName: score.PlayerName,
-			Score:      score.Score,
-			UpdatedAt:  score.UpdatedAt.Time.Format(time.RFC3339),
-		},
+	return &pb.SimulateRankResponse{
+		Rank:             sim.Rank,
+		TotalPlayers:     sim.TotalPlayers,
+		PointsToNextRank: sim.PointsToNextRank,
+		Tier:             sim.Tier,
 	}, nil
 }
 
 // StreamLeaderboard implements the StreamLeaderboard server-streaming RPC
 func (s *Server) StreamLeaderboard(req *pb.SubscribeRequest, stream pb.LeaderboardService_StreamLeaderboardServer) error {
+	return s.streamBoard(req, stream, true)
+}
+
+// StreamBoardChanges streams a board like StreamLeaderboard, but with every score
+// change of the board instead of those affecting the subscriber's top N, and
+// without tier changes: a feed of the board's activity after a snapshot of its
+// top N. It is not an RPC; the REST server serves it as GET /scores/stream.
+func (s *Server) StreamBoardChanges(req *pb.SubscribeRequest, stream pb.LeaderboardService_StreamLeaderboardServer) error {
+	return s.streamBoard(req, stream, false)
+}
+
+// streamBoard serves a leaderboard stream. With topN, updates are filtered to the
+// subscriber's top N, and deletes within it are backfilled.
+func (s *Server) streamBoard(req *pb.SubscribeRequest, stream pb.LeaderboardService_StreamLeaderboardServer, topN bool) error {
 	ctx := stream.Context()
 
-	// Determine initial limit
-	limit := req.InitialLimit
-	if limit <= 0 {
-		limit = s.defaultLimit
+	board, err := service.ResolveLeaderboardID(req.LeaderboardId)
+	if err != nil {
+		return s.fromServiceError(ctx, err, "subscribe")
+	}
+
+	order, secondary, err := s.sortOrders(ctx, board)
+	if err != nil {
+		return err
 	}
-	if limit > s.maxLimit {
-		limit = s.maxLimit
+
+	// Determine initial limit
+	limit := s.clampLimit(req.InitialLimit)
+	view := newTopView(limit, order, secondary)
+	view.trackRanks = topN && req.RankChanges
+
+	// A resumed stream loads its view before subscribing: the changes made
+	// meanwhile are among those replayed
+	resumable := req.ResumeFromSeq > 0 && s.canResume(board, req.ResumeFromSeq)
+	if resumable && topN {
+		if err := s.seedView(ctx, board, view, limit); err != nil {
+			return err
+		}
 	}
 
-	// Send initial snapshot
-	scores, err := s.svc.GetTopScores(ctx, limit, 0)
+	// Create a subscriber channel
+	updateChan := make(chan *pb.LeaderboardUpdate, 50)
+	rpc := streamBoardChanges
+	if topN {
+		rpc = streamLeaderboard
+	}
+	sub := newSubscriber(ctx, rpc, limit)
+	var missed []*pb.LeaderboardUpdate
+	if resumable {
+		missed, resumable, err = s.addSubscriberFrom(board, updateChan, sub, req.ResumeFromSeq)
+	} else {
+		err = s.addSubscriber(board, updateChan, sub)
+	}
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to get initial snapshot")
-		return status.Error(codes.Internal, "failed to get initial snapshot")
+		return err
 	}
+	defer s.removeSubscriber(board, updateChan)
 
-	snapshot := make([]*pb.ScoreEntry, len(scores))
-	for i, score := range scores {
-		snapshot[i] = &pb.ScoreEntry{
-			PlayerName: score.PlayerName,
-			Score:      score.Score,
-			UpdatedAt:  score.UpdatedAt.Time.Format(time.RFC3339),
+	if resumable {
+		if err := s.replay(ctx, stream, view, missed, topN); err != nil {
+			return err
 		}
+	} else if err := s.sendSnapshot(ctx, stream, board, view, limit); err != nil {
+		return err
 	}
 
-	if err := stream.Send(&pb.LeaderboardUpdate{
-		Kind:     pb.LeaderboardUpdate_SNAPSHOT,
-		Snapshot: snapshot,
-	}); err != nil {
-		s.logger.Error().Err(err).Msg("failed to send initial snapshot")
-		return status.Error(codes.Internal, "failed to send snapshot")
+	s.loggerFor(ctx).Info().Str("leaderboard", board).Int32("limit", limit).Bool("top_n", topN).Bool("resumed", resumable).Msg("client subscribed to leaderboard stream")
+
+	heartbeat, stopHeartbeat := s.heartbeatTicker()
+	defer stopHeartbeat()
+
+	// Stream updates to client
+	for {
+		select {
+		case <-ctx.Done():
+			s.loggerFor(ctx).Info().Msg("client disconnected from stream")
+			return nil
+		case <-sub.kicked:
+			return s.disconnected(ctx, sub)
+		case <-heartbeat:
+			if err := s.sendHeartbeat(stream); err != nil {
+				return err
+			}
+		case update := <-updateChan:
+			if sub.needsResync(update, updateChan) {
+				if err := s.sendSnapshot(ctx, stream, board, view, view.limit); err != nil {
+					return err
+				}
+				continue
+			}
+			before := view.positions()
+			switch {
+			case !topN && update.Kind == pb.LeaderboardUpdate_TIER_CHANGE:
+				continue
+			case !topN:
+				metrics.StreamUpdates.WithLabelValues("sent").Inc()
+			case !s.filter(view, update):
+				continue
+			}
+			if err := stream.Send(update); err != nil {
+				s.loggerFor(ctx).Error().Err(err).Msg("failed to send update")
+				return internalError("failed to send update")
+			}
+			if topN {
+				if err := s.backfill(ctx, stream, board, view, update); err != nil {
+					return err
+				}
+				if err := s.sendRankChanges(ctx, stream, view, before); err != nil {
+					return err
+				}
+			}
+		}
 	}
+}
 
-	s.logger.Info().Int32("limit", limit).Msg("client subscribed to leaderboard stream")
+// SubscribeLeaderboard implements the bidirectional SubscribeLeaderboard RPC.
+// It behaves like StreamLeaderboard but lets the client change its limit,
+// pause/resume updates or request a fresh snapshot without reconnecting.
+func (s *Server) SubscribeLeaderboard(stream pb.LeaderboardService_SubscribeLeaderboardServer) error {
+	ctx := stream.Context()
 
-	// Create a subscriber channel
+	// The first control message opens the subscription
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The board is fixed for the life of the subscription
+	board, err := service.ResolveLeaderboardID(first.LeaderboardId)
+	if err != nil {
+		return s.fromServiceError(ctx, err, "subscribe")
+	}
+
+	limit := s.pageLimits().defaultLimit
+	if first.Action == pb.SubscribeControl_SET_LIMIT {
+		limit = s.clampLimit(first.Limit)
+	}
+	paused := first.Action == pb.SubscribeControl_PAUSE
+	order, secondary, err := s.sortOrders(ctx, board)
+	if err != nil {
+		return err
+	}
+	view := newTopView(limit, order, secondary)
+	view.trackRanks = first.RankChanges
+
+	// Subscribe before the snapshot: the changes made while it is read are
+	// queued, and a subscriber refused by a cap gets no snapshot
 	updateChan := make(chan *pb.LeaderboardUpdate, 50)
-	s.addSubscriber(updateChan)
-	defer s.removeSubscriber(updateChan)
+	sub := newSubscriber(ctx, subscribeLeaderboard, limit)
+	if err := s.addSubscriber(board, updateChan, sub); err != nil {
+		return err
+	}
+	defer s.removeSubscriber(board, updateChan)
+
+	if !paused {
+		if err := s.sendSnapshot(ctx, stream, board, view, limit); err != nil {
+			return err
+		}
+	}
+
+	s.loggerFor(ctx).Info().Str("leaderboard", board).Int32("limit", limit).Bool("paused", paused).Msg("client opened bidirectional leaderboard subscription")
+
+	// Read control messages in the background; only this goroutine calls Send
+	controls := make(chan *pb.SubscribeControl)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case controls <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Paused subscriptions get heartbeats too: they are the most likely to sit idle
+	heartbeat, stopHeartbeat := s.heartbeatTicker()
+	defer stopHeartbeat()
 
-	// Stream updates to client
 	for {
 		select {
 		case <-ctx.Done():
-			s.logger.Info().Msg("client disconnected from stream")
+			s.loggerFor(ctx).Info().Msg("client disconnected from subscription")
 			return nil
+
+		case <-sub.kicked:
+			return s.disconnected(ctx, sub)
+
+		case <-heartbeat:
+			if err := s.sendHeartbeat(stream); err != nil {
+				return err
+			}
+
+		case err := <-recvErr:
+			if err != io.EOF {
+				return err
+			}
+			// Client half-closed: no more control messages, keep streaming
+			recvErr = nil
+
+		case msg := <-controls:
+			switch msg.Action {
+			case pb.SubscribeControl_SET_LIMIT:
+				limit = s.clampLimit(msg.Limit)
+				sub.limit.Store(limit)
+				if !paused {
+					if err := s.sendSnapshot(ctx, stream, board, view, limit); err != nil {
+						return err
+					}
+				}
+			case pb.SubscribeControl_PAUSE:
+				paused = true
+			case pb.SubscribeControl_RESUME:
+				if paused {
+					paused = false
+					if err := s.sendSnapshot(ctx, stream, board, view, limit); err != nil {
+						return err
+					}
+				}
+			case pb.SubscribeControl_SNAPSHOT:
+				if err := s.sendSnapshot(ctx, stream, board, view, limit); err != nil {
+					return err
+				}
+			default:
+				return invalidArgument(ReasonInvalidControl, "action", fmt.Sprintf("unknown control action: %v", msg.Action))
+			}
+			s.loggerFor(ctx).Debug().Str("action", msg.Action.String()).Int32("limit", limit).Bool("paused", paused).Msg("subscription control applied")
+
 		case update := <-updateChan:
+			if sub.needsResync(update, updateChan) {
+				// A paused subscriber gets a fresh snapshot on RESUME anyway
+				if !paused {
+					if err := s.sendSnapshot(ctx, stream, board, view, limit); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if paused {
+				continue
+			}
+			before := view.positions()
+			if !s.filter(view, update) {
+				continue
+			}
 			if err := stream.Send(update); err != nil {
-				s.logger.Error().Err(err).Msg("failed to send update")
-				return status.Error(codes.Internal, "failed to send update")
+				s.loggerFor(ctx).Error().Err(err).Msg("failed to send update")
+				return internalError("failed to send update")
+			}
+			if err := s.backfill(ctx, stream, board, view, update); err != nil {
+				return err
+			}
+			if err := s.sendRankChanges(ctx, stream, view, before); err != nil {
+				return err
 			}
 		}
 	}
 }
 
+// updateSender is implemented by both leaderboard stream types
+type updateSender interface {
+	Send(*pb.LeaderboardUpdate) error
+}
+
+// sendSnapshot sends the current top N of a board as a SNAPSHOT update and resets the subscriber's view
+func (s *Server) sendSnapshot(ctx context.Context, stream updateSender, board string, view *topView, limit int32) error {
+	scores, err := s.svc.GetTopScores(ctx, board, limit, 0)
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Msg("failed to get snapshot")
+		return internalError("failed to get initial snapshot")
+	}
+
+	entries := s.toEntries(ctx, scores)
+	if err := stream.Send(&pb.LeaderboardUpdate{
+		Kind:     pb.LeaderboardUpdate_SNAPSHOT,
+		Snapshot: entries,
+	}); err != nil {
+		s.loggerFor(ctx).Error().Err(err).Msg("failed to send snapshot")
+		return internalError("failed to send snapshot")
+	}
+	view.reset(limit, entries)
+	return nil
+}
+
+// seedView loads the current top N of a board into the view of a resumed stream
+func (s *Server) seedView(ctx context.Context, board string, view *topView, limit int32) error {
+	scores, err := s.svc.GetTopScores(ctx, board, limit, 0)
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Msg("failed to get snapshot")
+		return internalError("failed to get initial snapshot")
+	}
+	view.seed(limit, s.toEntries(ctx, scores))
+	return nil
+}
+
+// replay sends the updates a resumed stream missed, whether they affect the
+// subscriber's top N or not: the server does not know which entries the client
+// holds. With topN they are applied to the view, which filters the updates
+// that follow.
+func (s *Server) replay(ctx context.Context, stream updateSender, view *topView, missed []*pb.LeaderboardUpdate, topN bool) error {
+	for _, update := range missed {
+		before := view.positions()
+		if topN {
+			view.accept(update)
+		}
+		if err := stream.Send(update); err != nil {
+			s.loggerFor(ctx).Error().Err(err).Msg("failed to send update")
+			return internalError("failed to send update")
+		}
+		if err := s.sendRankChanges(ctx, stream, view, before); err != nil {
+			return err
+		}
+	}
+	metrics.StreamUpdates.WithLabelValues("replayed").Add(float64(len(missed)))
+	s.loggerFor(ctx).Debug().Int("missed", len(missed)).Msg("stream resumed")
+	return nil
+}
+
+// heartbeatTicker returns the channel stream loops receive heartbeat ticks
+// from, and the function stopping it. The channel is nil (never ready) when
+// heartbeats are disabled.
+func (s *Server) heartbeatTicker() (<-chan time.Time, func()) {
+	if s.heartbeat <= 0 {
+		return nil, func() {}
+	}
+	ticker := time.NewTicker(s.heartbeat)
+	return ticker.C, ticker.Stop
+}
+
+// sendHeartbeat sends a HEARTBEAT update carrying the server time
+func (s *Server) sendHeartbeat(stream updateSender) error {
+	if err := stream.Send(&pb.LeaderboardUpdate{
+		Kind:       pb.LeaderboardUpdate_HEARTBEAT,
+		ServerTime: time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		s.logger.Error().Err(err).Msg("failed to send heartbeat")
+		return internalError("failed to send heartbeat")
+	}
+	return nil
+}
+
+// backfillWindow is how many entries backfill reads below the view. The top cache
+// may not have applied the delete yet, in which case the deleted player still
+// takes a slot above the one to fill.
+const backfillWindow = 2
+
+// backfill follows a DELETE that removed a visible entry with an UPSERT of the
+// entry moving into the freed last slot of the subscriber's top-N, so clients
+// do not have to re-query. On error the view simply stays short: it then
+// forwards every upsert until it fills up again.
+func (s *Server) backfill(ctx context.Context, stream updateSender, board string, view *topView, update *pb.LeaderboardUpdate) error {
+	if update.Kind != pb.LeaderboardUpdate_DELETE || view.full() {
+		return nil
+	}
+	deleted := update.GetChanged().GetPlayerName()
+
+	scores, err := s.svc.GetTopScores(ctx, board, backfillWindow, int32(len(view.entries)))
+	if err != nil {
+		s.loggerFor(ctx).Warn().Err(err).Str("leaderboard", board).Msg("failed to backfill stream view")
+		return nil
+	}
+	for _, score := range scores {
+		if score.PlayerName == deleted || view.contains(score.PlayerName) {
+			continue
+		}
+		entry := s.toEntry(score, nil)
+		entry.Profile = s.profileOf(ctx, score.PlayerName)
+		shift := &pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_UPSERT, Changed: entry}
+		if !s.filter(view, shift) {
+			return nil
+		}
+		if err := stream.Send(shift); err != nil {
+			s.loggerFor(ctx).Error().Err(err).Msg("failed to send update")
+			return internalError("failed to send update")
+		}
+		s.loggerFor(ctx).Debug().Str("leaderboard", board).Str("player", score.PlayerName).Msg("stream view backfilled after delete")
+		return nil
+	}
+	return nil
+}
+
+// sendRankChanges follows an update with a RANK_CHANGED for each player it
+// moved in a view tracking ranks; before is the view's positions() before it
+func (s *Server) sendRankChanges(ctx context.Context, stream updateSender, view *topView, before []*pb.ScoreEntry) error {
+	if !view.trackRanks {
+		return nil
+	}
+	for _, change := range view.rankChanges(before) {
+		if err := stream.Send(change); err != nil {
+			s.loggerFor(ctx).Error().Err(err).Msg("failed to send rank change")
+			return internalError("failed to send update")
+		}
+	}
+	return nil
+}
+
+// filter reports whether an update affects the subscriber's visible top-N
+func (s *Server) filter(view *topView, update *pb.LeaderboardUpdate) bool {
+	if view.accept(update) {
+		metrics.StreamUpdates.WithLabelValues("sent").Inc()
+		return true
+	}
+	metrics.StreamUpdates.WithLabelValues("filtered").Inc()
+	return false
+}
+
+// overloaded builds the ResourceExhausted status for a shed request, with a
+// retry-after header (in seconds) and a RetryInfo detail telling the client when to try again
+func (s *Server) overloaded(ctx context.Context, err error) error {
+	retryAfter := int(math.Ceil(s.svc.RetryAfter().Seconds()))
+	if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter))); err != nil {
+		s.loggerFor(ctx).Debug().Err(err).Msg("failed to set retry-after header")
+	}
+	return overloadedError(err, retryAfter)
+}
+
+// unavailable converts a call rejected by the open database circuit to its
+// status, with a retry-after header telling the client when the circuit probes
+// the database again (at least a second)
+func (s *Server) unavailable(ctx context.Context, err error) error {
+	retryAfter := 1
+	var u *service.UnavailableError
+	if errors.As(err, &u) {
+		retryAfter = max(int(math.Ceil(u.RetryAfter.Seconds())), 1)
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter))); err != nil {
+		s.loggerFor(ctx).Debug().Err(err).Msg("failed to set retry-after header")
+	}
+	return unavailableError(err, retryAfter)
+}
+
+// pageLimits are the default and maximum page sizes
+type pageLimits struct {
+	defaultLimit int32
+	maxLimit     int32
+}
+
+// SetPageLimits replaces the default and maximum page sizes, e.g. on a
+// configuration reload. Open streams keep the limit they were given.
+func (s *Server) SetPageLimits(defaultLimit, maxLimit int32) {
+	s.limits.Store(&pageLimits{defaultLimit: defaultLimit, maxLimit: maxLimit})
+}
+
+// pageLimits returns the page sizes in force
+func (s *Server) pageLimits() pageLimits {
+	if limits := s.limits.Load(); limits != nil {
+		return *limits
+	}
+	return pageLimits{}
+}
+
+// clampLimit applies the default and maximum limits to a requested limit
+func (s *Server) clampLimit(limit int32) int32 {
+	limits := s.pageLimits()
+	if limit <= 0 {
+		return limits.defaultLimit
+	}
+	if limit > limits.maxLimit {
+		return limits.maxLimit
+	}
+	return limit
+}
+
 // broadcastNotifications listens for database notifications and broadcasts them to subscribers
 func (s *Server) broadcastNotifications() {
-	s.logger.Info().Msg("🎧 Started listening for database changes to broadcast to gRPC clients")
+	s.logger.Info().Dur("coalesce_window", s.coalesce).Msg("🎧 Started listening for database changes to broadcast to gRPC clients")
 
-	for change := range s.notifyListener.Changes() {
-		s.logger.Info().
-			Str("player", change.PlayerName).
-			Int64("score", change.Score).
-			Str("op", change.Op).
-			Msg("🔔 BACKEND received change notification from DB listener")
-
-		var kind pb.LeaderboardUpdate_Kind
-		switch change.Op {
-		case "insert", "update":
-			kind = pb.LeaderboardUpdate_UPSERT
-		case "delete":
-			kind = pb.LeaderboardUpdate_DELETE
-		default:
-			s.logger.Warn().Str("op", change.Op).Msg("⚠️  unknown notification operation")
-			continue
+	if s.coalesce <= 0 {
+		for change := range s.changes {
+			if change.Op == notify.OpResync {
+				s.resync(change.LeaderboardID)
+				continue
+			}
+			s.broadcastChange(change)
 		}
+		return
+	}
 
-		update := &pb.LeaderboardUpdate{
-			Kind: kind,
-			Changed: &pb.ScoreEntry{
-				PlayerName: change.PlayerName,
-				Score:      change.Score,
-				UpdatedAt:  time.Now().Format(time.RFC3339), // Best effort timestamp
-			},
+	// The window opens with the first change after a flush, so a steady stream
+	// of changes is broadcast every window rather than held back
+	pending := newCoalescer()
+	var flush <-chan time.Time
+	for {
+		select {
+		case change, ok := <-s.changes:
+			if !ok {
+				for _, change := range pending.take() {
+					s.broadcastChange(change)
+				}
+				return
+			}
+			if change.Op == notify.OpResync {
+				// Subscribers reload the board from the database, pending changes included
+				pending.drop(change.LeaderboardID)
+				s.resync(change.LeaderboardID)
+				continue
+			}
+			if change.LeaderboardID == "" {
+				change.LeaderboardID = service.DefaultLeaderboardID // payload of a server that predates boards
+			}
+			if pending.add(change) {
+				metrics.StreamCoalesced.Inc()
+			}
+			if flush == nil {
+				flush = time.After(s.coalesce)
+			}
+		case <-flush:
+			flush = nil
+			for _, change := range pending.take() {
+				s.broadcastChange(change)
+			}
 		}
+	}
+}
 
+// resync tells the subscribers of a board, or of every board when board is
+// empty, to reload it from the database
+func (s *Server) resync(board string) {
+	if board != "" {
+		s.logger.Info().Str("leaderboard", board).Msg("🔄 Leaderboard reset, resyncing its gRPC subscribers")
+		s.broadcast(board, resyncMarker)
+		return
+	}
+	s.logger.Info().Msg("🔄 Notify listener reconnected, resyncing gRPC subscribers")
+	s.broadcastAll(resyncMarker)
+}
+
+// broadcastChange converts a score change to an update and broadcasts it to the subscribers of its board
+func (s *Server) broadcastChange(change notify.ScoreChange) {
+	board := change.LeaderboardID
+	if board == "" {
+		board = service.DefaultLeaderboardID // payload of a server that predates boards
+	}
+
+	s.logger.Info().
+		Str("leaderboard", board).
+		Str("player", change.PlayerName).
+		Int64("score", change.Score).
+		Str("op", change.Op).
+		Msg("🔔 BACKEND received change notification from DB listener")
+
+	update := s.changeUpdate(board, change)
+	if update == nil {
+		s.logger.Warn().Str("op", change.Op).Msg("⚠️  unknown notification operation")
+		return
+	}
+	if update.Kind == pb.LeaderboardUpdate_UPSERT {
+		update.Changed.Profile = s.profileOf(context.Background(), change.PlayerName)
+	}
+
+	s.logger.Info().
+		Str("player", change.PlayerName).
+		Str("kind", update.Kind.String()).
+		Msg("📡 Broadcasting to gRPC subscribers")
+
+	s.broadcast(board, update)
+}
+
+// changeUpdate converts a score change of board to an UPSERT or DELETE update,
+// without the player's profile; nil for an unknown operation
+func (s *Server) changeUpdate(board string, change notify.ScoreChange) *pb.LeaderboardUpdate {
+	var kind pb.LeaderboardUpdate_Kind
+	switch change.Op {
+	case "insert", "update":
+		kind = pb.LeaderboardUpdate_UPSERT
+	case "delete":
+		kind = pb.LeaderboardUpdate_DELETE
+	default:
+		return nil
+	}
+
+	updatedAt := change.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now() // change of a trigger that predates migration 0015
+	}
+	update := &pb.LeaderboardUpdate{
+		Kind: kind,
+		Seq:  change.ID,
+		Changed: &pb.ScoreEntry{
+			PlayerName:     change.PlayerName,
+			Score:          change.Score,
+			UpdatedAt:      updatedAt.Format(time.RFC3339),
+			AchievedAt:     change.AchievedAt.Format(time.RFC3339Nano),
+			LeaderboardId:  board,
+			Metadata:       change.Metadata,
+			SecondaryScore: change.SecondaryScore,
+			Region:         change.CountryCode,
+			Platform:       change.Platform,
+			UpdatedTime:    timestamppb.New(updatedAt),
+			AchievedTime:   timestamppb.New(change.AchievedAt),
+		},
+	}
+	if kind == pb.LeaderboardUpdate_UPSERT {
+		update.Changed.Tier = s.svc.TierFor(board, change.Score)
+	}
+	return update
+}
+
+// broadcastTierChanges forwards tier promotions and demotions to the subscribers
+// of their board
+func (s *Server) broadcastTierChanges() {
+	for change := range s.svc.TierChanges() {
 		s.logger.Info().
+			Str("leaderboard", change.LeaderboardID).
 			Str("player", change.PlayerName).
-			Str("kind", kind.String()).
-			Msg("📡 Broadcasting to gRPC subscribers")
+			Str("old_tier", change.OldTier).
+			Str("new_tier", change.NewTier).
+			Msg("🏅 Broadcasting tier change to gRPC subscribers")
 
-		s.broadcast(update)
+		now := time.Now()
+		s.broadcast(change.LeaderboardID, &pb.LeaderboardUpdate{
+			Kind: pb.LeaderboardUpdate_TIER_CHANGE,
+			Changed: &pb.ScoreEntry{
+				PlayerName:    change.PlayerName,
+				Score:         change.Score,
+				UpdatedAt:     now.Format(time.RFC3339),
+				Tier:          change.NewTier,
+				LeaderboardId: change.LeaderboardID,
+				UpdatedTime:   timestamppb.New(now),
+			},
+			PreviousTier: change.OldTier,
+		})
 	}
 }
 
-// broadcast sends an update to all subscribers
-func (s *Server) broadcast(update *pb.LeaderboardUpdate) {
+// broadcast sends an update to the subscribers of a board
+func (s *Server) broadcast(board string, update *pb.LeaderboardUpdate) {
 	s.mu.RLock()
-	subscriberCount := len(s.subscribers)
-	s.mu.RUnlock()
+	defer s.mu.RUnlock()
 
+	s.history.record(board, update)
+	subs := s.subscribers[board]
 	s.logger.Info().
-		Int("subscriber_count", subscriberCount).
-		Str("player", update.Changed.PlayerName).
+		Str("leaderboard", board).
+		Int("subscriber_count", len(subs)).
+		Str("player", update.GetChanged().GetPlayerName()).
 		Msg("📤 Sending update to gRPC subscribers")
 
+	successCount := s.send(subs, update)
+
+	s.logger.Info().
+		Int("sent_to", successCount).
+		Int("total_subscribers", len(subs)).
+		Msg("✅ Update broadcast complete")
+}
+
+// broadcastAll sends an update to the subscribers of every board
+func (s *Server) broadcastAll(update *pb.LeaderboardUpdate) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	s.history.record("", update)
 	successCount := 0
-	for ch := range s.subscribers {
+	for _, subs := range s.subscribers {
+		successCount += s.send(subs, update)
+	}
+
+	s.logger.Info().
+		Int("sent_to", successCount).
+		Int("total_subscribers", s.subscriberCount).
+		Msg("✅ Update broadcast to all boards complete")
+}
+
+// send queues an update to each subscriber of subs and returns how many accepted it.
+// The caller holds s.mu.
+func (s *Server) send(subs map[chan *pb.LeaderboardUpdate]*subscriber, update *pb.LeaderboardUpdate) int {
+	successCount := 0
+	for ch, sub := range subs {
 		select {
 		case ch <- update:
+			sub.delivered.Add(1)
 			successCount++
 		default:
+			if update == resyncMarker {
+				// A lost marker would leave the stream stale for good
+				sub.resync.Store(true)
+				sub.delivered.Add(1)
+				successCount++
+				s.logger.Warn().Uint64("subscriber", sub.id).Msg("⚠️  subscriber channel full, resync flagged")
+				continue
+			}
 			// Channel full, skip (backpressure handling)
-			s.logger.Warn().Msg("⚠️  subscriber channel full, skipping update")
+			sub.dropped.Add(1)
+			metrics.StreamUpdates.WithLabelValues("dropped").Inc()
+			s.logger.Warn().Uint64("subscriber", sub.id).Msg("⚠️  subscriber channel full, skipping update")
 		}
 	}
+	return successCount
+}
 
-	s.logger.Info().
-		Int("sent_to", successCount).
-		Int("total_subscribers", subscriberCount).
-		Msg("✅ Update broadcast complete")
+// toEntry converts a store row to its protobuf representation, with the player's
+// profile when profiles has one
+func (s *Server) toEntry(score store.Score, profiles map[string]store.Player) *pb.ScoreEntry {
+	entry := &pb.ScoreEntry{
+		PlayerName:     score.PlayerName,
+		Score:          score.Score,
+		UpdatedAt:      score.UpdatedAt.Time.Format(time.RFC3339),
+		Tier:           s.svc.TierFor(score.LeaderboardID, score.Score),
+		AchievedAt:     score.AchievedAt.Time.Format(time.RFC3339Nano),
+		LeaderboardId:  score.LeaderboardID,
+		Metadata:       service.DecodeMetadata(score.Metadata),
+		SecondaryScore: score.SecondaryScore,
+		Region:         score.CountryCode,
+		Platform:       score.Platform,
+		UpdatedTime:    timestamppb.New(score.UpdatedAt.Time),
+		AchievedTime:   timestamppb.New(score.AchievedAt.Time),
+	}
+	if p, ok := profiles[score.PlayerName]; ok {
+		entry.Profile = toProfile(p)
+	}
+	return entry
+}
+
+// toEntries converts store rows to their protobuf representation, loading
+// the profiles of the page's players in one batch
+func (s *Server) toEntries(ctx context.Context, scores []store.Score) []*pb.ScoreEntry {
+	names := make([]string, len(scores))
+	for i, score := range scores {
+		names[i] = score.PlayerName
+	}
+	profiles := s.svc.PlayerProfiles(ctx, names)
+
+	entries := make([]*pb.ScoreEntry, len(scores))
+	for i, score := range scores {
+		entries[i] = s.toEntry(score, profiles)
+	}
+	return entries
+}
+
+// profileOf returns a player's profile, or nil if the player has none
+func (s *Server) profileOf(ctx context.Context, playerName string) *pb.PlayerProfile {
+	if p, ok := s.svc.PlayerProfiles(ctx, []string{playerName})[playerName]; ok {
+		return toProfile(p)
+	}
+	return nil
+}
+
+// toProfile converts a store profile to its protobuf representation
+func toProfile(p store.Player) *pb.PlayerProfile {
+	profile := &pb.PlayerProfile{
+		PlayerName:  p.PlayerName,
+		DisplayName: p.DisplayName,
+		CountryCode: p.CountryCode,
+		AvatarUrl:   p.AvatarUrl,
+	}
+	// Profiles built from the identity service alone have no stored timestamps
+	if p.CreatedAt.Valid {
+		profile.CreatedAt = p.CreatedAt.Time.Format(time.RFC3339)
+		profile.UpdatedAt = p.UpdatedAt.Time.Format(time.RFC3339)
+		profile.CreatedTime = timestamppb.New(p.CreatedAt.Time)
+		profile.UpdatedTime = timestamppb.New(p.UpdatedAt.Time)
+	}
+	return profile
+}
+
+// toTimestamp converts a time to its protobuf representation, nil for the zero time
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// fromTimestamp converts a protobuf time, the zero time for nil
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// formatTime formats a time with layout, "" for the zero time
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
 }
 
-// addSubscriber registers a new subscriber
-func (s *Server) addSubscriber(ch chan *pb.LeaderboardUpdate) {
+// toLeaderboard converts a leaderboard definition to its protobuf representation
+func toLeaderboard(l store.Leaderboard) *pb.Leaderboard {
+	def := &pb.Leaderboard{
+		LeaderboardId:      l.LeaderboardID,
+		SortOrder:          toSortOrder(service.SortOrder(l.SortOrder)),
+		SecondarySortOrder: toSortOrder(service.SortOrder(l.SecondarySortOrder)),
+	}
+	if l.CreatedAt.Valid {
+		def.CreatedAt = l.CreatedAt.Time.Format(time.RFC3339)
+		def.UpdatedAt = l.UpdatedAt.Time.Format(time.RFC3339)
+	}
+	return def
+}
+
+// toSortOrder converts a sort order to its protobuf enum
+func toSortOrder(order service.SortOrder) pb.SortOrder {
+	if order == service.SortAscending {
+		return pb.SortOrder_SORT_ORDER_ASC
+	}
+	return pb.SortOrder_SORT_ORDER_DESC
+}
+
+// fromSortOrder converts a protobuf sort order; unspecified means descending
+func fromSortOrder(order pb.SortOrder) (service.SortOrder, error) {
+	switch order {
+	case pb.SortOrder_SORT_ORDER_UNSPECIFIED, pb.SortOrder_SORT_ORDER_DESC:
+		return service.SortDescending, nil
+	case pb.SortOrder_SORT_ORDER_ASC:
+		return service.SortAscending, nil
+	}
+	return "", fmt.Errorf("%w: unknown sort order %v", service.ErrInvalidSortOrder, order)
+}
+
+// sortOrders returns the sort orders of a board, used to order stream views
+func (s *Server) sortOrders(ctx context.Context, board string) (order, secondary service.SortOrder, err error) {
+	def, err := s.svc.GetLeaderboard(ctx, board)
+	if err != nil {
+		s.loggerFor(ctx).Error().Err(err).Str("leaderboard", board).Msg("failed to get leaderboard")
+		return "", "", internalError("failed to get leaderboard")
+	}
+	return service.SortOrder(def.SortOrder), service.SortOrder(def.SecondarySortOrder), nil
+}
+
+// SubscriberCount returns the number of connected stream subscribers, all boards included
+func (s *Server) SubscriberCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.subscriberCount
+}
+
+// addSubscriber registers a new subscriber of a board, receiving its updates on
+// ch, failing with ResourceExhausted when the board is at its subscriber cap
+func (s *Server) addSubscriber(board string, ch chan *pb.LeaderboardUpdate, sub *subscriber) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.subscribers[ch] = struct{}{}
-	s.logger.Debug().Int("total", len(s.subscribers)).Msg("subscriber added")
+	return s.subscribe(board, ch, sub)
 }
 
-// removeSubscriber unregisters a subscriber
-func (s *Server) removeSubscriber(ch chan *pb.LeaderboardUpdate) {
+// subscribe adds a subscriber to the set of its board, unless the board is at
+// its subscriber cap or the client address at its stream quota, and assigns it
+// an id. The caller holds s.mu.
+func (s *Server) subscribe(board string, ch chan *pb.LeaderboardUpdate, sub *subscriber) error {
+	subs, ok := s.subscribers[board]
+	if s.maxSubscribers > 0 && len(subs) >= s.maxSubscribers {
+		s.logger.Warn().Str("leaderboard", board).Int("max", s.maxSubscribers).Msg("subscriber refused: board at its subscriber cap")
+		return subscriberLimitError(board, s.maxSubscribers)
+	}
+	if quota, ok := s.quotas[sub.peer]; ok && s.peerStreams[sub.peer] >= quota {
+		s.logger.Warn().Str("leaderboard", board).Int("quota", quota).Msg("subscriber refused: client address at its stream quota")
+		return streamQuotaError(quota)
+	}
+	if sub.peer.IsValid() {
+		s.peerStreams[sub.peer]++
+	}
+	sub.kicked = make(chan struct{})
+	if !ok {
+		subs = make(map[chan *pb.LeaderboardUpdate]*subscriber)
+		s.subscribers[board] = subs
+	}
+	s.lastSubscriber++
+	sub.id, sub.board = s.lastSubscriber, board
+	subs[ch] = sub
+	s.subscriberCount++
+	s.logger.Debug().Str("leaderboard", board).Uint64("subscriber", sub.id).Int("total", s.subscriberCount).Msg("subscriber added")
+	return nil
+}
+
+// canResume reports whether a stream of a board can currently resume from seq
+func (s *Server) canResume(board string, seq int64) bool {
+	_, ok := s.history.since(board, seq)
+	return ok
+}
+
+// addSubscriberFrom registers a new subscriber of a board resuming from seq, and
+// returns the updates it missed since then. When they are no longer retained it
+// returns false, and the subscriber needs a snapshot. Registering and reading the
+// history under the same lock guarantees that every update after seq is either
+// returned or queued to the subscriber, and never both. It fails like
+// addSubscriber when the board is at its subscriber cap.
+func (s *Server) addSubscriberFrom(board string, ch chan *pb.LeaderboardUpdate, sub *subscriber, seq int64) ([]*pb.LeaderboardUpdate, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.subscribe(board, ch, sub); err != nil {
+		return nil, false, err
+	}
+	missed, ok := s.history.since(board, seq)
+	return missed, ok, nil
+}
+
+// removeSubscriber unregisters a subscriber of a board
+func (s *Server) removeSubscriber(board string, ch chan *pb.LeaderboardUpdate) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.subscribers, ch)
+	subs := s.subscribers[board]
+	if sub := subs[ch]; sub != nil && sub.peer.IsValid() {
+		if s.peerStreams[sub.peer]--; s.peerStreams[sub.peer] <= 0 {
+			delete(s.peerStreams, sub.peer)
+		}
+	}
+	delete(subs, ch)
+	if len(subs) == 0 {
+		// Boards come and go with their players: drop empty sets
+		delete(s.subscribers, board)
+	}
+	s.subscriberCount--
 	close(ch)
-	s.logger.Debug().Int("total", len(s.subscribers)).Msg("subscriber removed")
+	s.logger.Debug().Str("leaderboard", board).Int("total", s.subscriberCount).Msg("subscriber removed")
 }
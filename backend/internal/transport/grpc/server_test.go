@@ -0,0 +1,478 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	pbv2 "github.com/yourorg/leaderboard/gen/leaderboard/v2"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newHub returns a Server with only the subscriber hub set up
+func newHub() *Server {
+	logger := zerolog.Nop()
+	return &Server{
+		logger:      &logger,
+		subscribers: make(map[string]map[chan *pb.LeaderboardUpdate]*subscriber),
+		peerStreams: make(map[netip.Addr]int),
+		quotas:      make(map[netip.Addr]int),
+		history:     newHistory(0),
+	}
+}
+
+func TestBroadcastRoutesByBoard(t *testing.T) {
+	s := newHub()
+	level1 := make(chan *pb.LeaderboardUpdate, 10)
+	level2 := make(chan *pb.LeaderboardUpdate, 10)
+	s.addSubscriber("level-1", level1, &subscriber{})
+	s.addSubscriber("level-2", level2, &subscriber{})
+
+	s.broadcast("level-1", update(pb.LeaderboardUpdate_UPSERT, "Alice", 100))
+	s.broadcast("level-3", update(pb.LeaderboardUpdate_UPSERT, "Bob", 200)) // no subscribers
+	if len(level1) != 1 || len(level2) != 0 {
+		t.Errorf("queued updates = %d on level-1, %d on level-2, want 1 and 0", len(level1), len(level2))
+	}
+
+	s.broadcastAll(resyncMarker)
+	if len(level1) != 2 || len(level2) != 1 {
+		t.Errorf("after broadcastAll: %d on level-1, %d on level-2, want 2 and 1", len(level1), len(level2))
+	}
+
+	if got := s.SubscriberCount(); got != 2 {
+		t.Errorf("SubscriberCount() = %d, want 2", got)
+	}
+	s.removeSubscriber("level-2", level2)
+	if _, ok := s.subscribers["level-2"]; ok {
+		t.Error("empty board still has a subscriber set")
+	}
+	if got := s.SubscriberCount(); got != 1 {
+		t.Errorf("SubscriberCount() after remove = %d, want 1", got)
+	}
+}
+
+func TestResyncOnFullQueue(t *testing.T) {
+	s := newHub()
+	ch := make(chan *pb.LeaderboardUpdate, 2)
+	sub := &subscriber{}
+	s.addSubscriber("level-1", ch, sub)
+
+	s.broadcast("level-1", update(pb.LeaderboardUpdate_UPSERT, "Alice", 100))
+	s.broadcast("level-1", update(pb.LeaderboardUpdate_UPSERT, "Bob", 200))
+	s.broadcast("level-1", update(pb.LeaderboardUpdate_UPSERT, "Carol", 300)) // dropped
+	s.broadcastAll(resyncMarker)
+	if len(ch) != 2 || sub.dropped.Load() != 1 || !sub.resync.Load() {
+		t.Fatalf("queued %d, dropped %d, resync flagged %v; want 2, 1 and a flagged resync", len(ch), sub.dropped.Load(), sub.resync.Load())
+	}
+
+	// The next update received turns into the resync, discarding the stale queue
+	if !sub.needsResync(<-ch, ch) {
+		t.Fatal("flagged resync not reported")
+	}
+	if len(ch) != 0 || sub.resync.Load() {
+		t.Errorf("after the resync: %d queued, flag %v; want an empty queue and a cleared flag", len(ch), sub.resync.Load())
+	}
+	s.broadcast("level-1", update(pb.LeaderboardUpdate_UPSERT, "Dave", 400))
+	if u := <-ch; sub.needsResync(u, ch) || u.Changed.PlayerName != "Dave" {
+		t.Errorf("update after the resync = %v, want Dave's upsert", u)
+	}
+
+	// With room in the queue, the marker keeps its place among the updates
+	s.broadcast("level-1", update(pb.LeaderboardUpdate_UPSERT, "Erin", 500))
+	s.broadcast("level-1", resyncMarker)
+	if u := <-ch; sub.needsResync(u, ch) || !sub.needsResync(<-ch, ch) {
+		t.Error("queued marker not reported in order")
+	}
+}
+
+func TestBroadcastChangeCarriesSeq(t *testing.T) {
+	s := newHub()
+	ch := make(chan *pb.LeaderboardUpdate, 1)
+	s.addSubscriber("level-1", ch, &subscriber{})
+
+	updatedAt := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	s.broadcastChange(notify.ScoreChange{ID: 42, UpdatedAt: updatedAt, LeaderboardID: "level-1", PlayerName: "Alice", Op: "delete"})
+
+	got := <-ch
+	if got.Kind != pb.LeaderboardUpdate_DELETE || got.Seq != 42 {
+		t.Errorf("update = %v seq %d, want DELETE seq 42", got.Kind, got.Seq)
+	}
+	if got.Changed.UpdatedAt != "2025-01-15T10:30:00Z" || !got.Changed.GetUpdatedTime().AsTime().Equal(updatedAt) {
+		t.Errorf("updated_at = %q (%v), want the time of the change", got.Changed.UpdatedAt, got.Changed.UpdatedTime)
+	}
+}
+
+func TestSubscriberCap(t *testing.T) {
+	s := newHub()
+	s.SetMaxSubscribers(2)
+	first := make(chan *pb.LeaderboardUpdate, 1)
+	for _, ch := range []chan *pb.LeaderboardUpdate{first, make(chan *pb.LeaderboardUpdate, 1)} {
+		if err := s.addSubscriber("level-1", ch, &subscriber{}); err != nil {
+			t.Fatalf("addSubscriber: %v", err)
+		}
+	}
+
+	err := s.addSubscriber("level-1", make(chan *pb.LeaderboardUpdate, 1), &subscriber{})
+	st, info, _ := details(t, err)
+	if st.Code() != codes.ResourceExhausted || info.Reason != ReasonTooManySubscribers || info.Metadata["max_subscribers"] != "2" {
+		t.Errorf("status = %v, info = %v", st.Code(), info)
+	}
+	if _, _, err := s.addSubscriberFrom("level-1", make(chan *pb.LeaderboardUpdate, 1), &subscriber{}, 1); err == nil {
+		t.Error("resumed subscriber beyond the cap accepted")
+	}
+	if got := s.SubscriberCount(); got != 2 {
+		t.Errorf("SubscriberCount() = %d, want 2: refused subscribers are not counted", got)
+	}
+
+	// The cap is per board, and a stream ending frees its place
+	if err := s.addSubscriber("level-2", make(chan *pb.LeaderboardUpdate, 1), &subscriber{}); err != nil {
+		t.Errorf("subscriber of another board: %v", err)
+	}
+	s.removeSubscriber("level-1", first)
+	if err := s.addSubscriber("level-1", make(chan *pb.LeaderboardUpdate, 1), &subscriber{}); err != nil {
+		t.Errorf("subscriber after one left: %v", err)
+	}
+}
+
+func TestMaskEntry(t *testing.T) {
+	mask, err := service.ParseFieldMask([]string{"player_name", "score"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := maskEntry(&pbv2.ScoreEntry{
+		LeaderboardId: "global",
+		PlayerName:    "Alice",
+		Score:         100,
+		UpdatedAt:     timestamppb.Now(),
+		Tier:          "Gold",
+		AchievedAt:    timestamppb.Now(),
+		Profile:       &pbv2.PlayerProfile{DisplayName: "Alice"},
+	}, mask)
+	want := &pbv2.ScoreEntry{PlayerName: "Alice", Score: 100}
+	if !proto.Equal(entry, want) {
+		t.Errorf("maskEntry() = %v, want %v", entry, want)
+	}
+}
+
+// BenchmarkBroadcastManyBoards broadcasts changes of 10k boards with 5 subscribers each:
+// each change only reaches the subscribers of its own board
+func BenchmarkBroadcastManyBoards(b *testing.B) {
+	const boards, perBoard = 10_000, 5
+	s := newHub()
+	ids := make([]string, boards)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("level-%d", i)
+		for range perBoard {
+			s.addSubscriber(ids[i], make(chan *pb.LeaderboardUpdate, 1), &subscriber{})
+		}
+	}
+	u := update(pb.LeaderboardUpdate_UPSERT, "Alice", 100)
+
+	b.ResetTimer()
+	for i := range b.N {
+		s.broadcast(ids[i%boards], u)
+	}
+}
+
+// recorder is an updateSender keeping what it was sent
+type recorder struct{ sent []*pb.LeaderboardUpdate }
+
+func (r *recorder) Send(update *pb.LeaderboardUpdate) error {
+	r.sent = append(r.sent, update)
+	return nil
+}
+
+func TestHeartbeat(t *testing.T) {
+	s := newHub()
+	if tick, stop := s.heartbeatTicker(); tick != nil {
+		stop()
+		t.Error("heartbeats disabled but ticker created")
+	}
+
+	s.heartbeat = time.Millisecond
+	tick, stop := s.heartbeatTicker()
+	defer stop()
+	<-tick
+
+	var r recorder
+	if err := s.sendHeartbeat(&r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.sent) != 1 || r.sent[0].Kind != pb.LeaderboardUpdate_HEARTBEAT || r.sent[0].ServerTime == "" {
+		t.Errorf("sent %v, want one HEARTBEAT with the server time", r.sent)
+	}
+}
+
+// testStream is a StreamLeaderboard server stream forwarding what it is sent
+type testStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	updates chan *pb.LeaderboardUpdate
+}
+
+func (s *testStream) Context() context.Context { return s.ctx }
+
+func (s *testStream) Send(update *pb.LeaderboardUpdate) error {
+	s.updates <- update
+	return nil
+}
+
+func TestStreamBoardChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := service.New(st, &logger, service.Options{})
+	for _, sub := range []service.ScoreSubmission{{PlayerName: "Alice", Score: 300}, {PlayerName: "Bob", Score: 200}} {
+		if _, err := svc.SubmitScore(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes := make(chan notify.ScoreChange)
+	s := NewServer(svc, changes, &logger, 1, 10, 0, 0)
+	open := func(serve func(*pb.SubscribeRequest, pb.LeaderboardService_StreamLeaderboardServer) error) chan *pb.LeaderboardUpdate {
+		stream := &testStream{ctx: ctx, updates: make(chan *pb.LeaderboardUpdate, 10)}
+		go serve(&pb.SubscribeRequest{InitialLimit: 1}, stream)
+		if u := <-stream.updates; u.Kind != pb.LeaderboardUpdate_SNAPSHOT || len(u.Snapshot) != 1 {
+			t.Fatalf("first update = %v, want a SNAPSHOT of the top 1", u)
+		}
+		return stream.updates
+	}
+	top := open(s.StreamLeaderboard)
+	all := open(s.StreamBoardChanges)
+	for s.SubscriberCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Bob is outside the top 1: only the feed of every change gets his improvement
+	changes <- notify.ScoreChange{LeaderboardID: "global", PlayerName: "Bob", Score: 250, RankScore: 250, Op: "update"}
+	if u := <-all; u.Kind != pb.LeaderboardUpdate_UPSERT || u.Changed.PlayerName != "Bob" {
+		t.Errorf("board changes got %v, want Bob's UPSERT", u)
+	}
+	changes <- notify.ScoreChange{LeaderboardID: "global", PlayerName: "Alice", Score: 400, RankScore: 400, Op: "update"}
+	for name, updates := range map[string]chan *pb.LeaderboardUpdate{"top": top, "all": all} {
+		if u := <-updates; u.Changed.GetPlayerName() != "Alice" {
+			t.Errorf("%s stream got %v, want Alice's UPSERT", name, u)
+		}
+	}
+	if len(top) != 0 {
+		t.Errorf("top 1 stream got %v", <-top)
+	}
+}
+
+func TestStreamResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := service.New(st, &logger, service.Options{})
+
+	changes := make(chan notify.ScoreChange)
+	s := NewServer(svc, changes, &logger, 10, 10, 0, 0)
+	s.SetResumeBuffer(10)
+
+	feed := &testStream{ctx: ctx, updates: make(chan *pb.LeaderboardUpdate, 10)}
+	go s.StreamBoardChanges(&pb.SubscribeRequest{}, feed)
+	<-feed.updates // snapshot
+	for s.SubscriberCount() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	for i, name := range []string{"Alice", "Bob", "Carol"} {
+		changes <- notify.ScoreChange{ID: int64(i + 1), LeaderboardID: "global", PlayerName: name, Score: 100, RankScore: 100, Op: "insert"}
+		<-feed.updates // recorded once broadcast
+	}
+
+	for name, serve := range map[string]func(*pb.SubscribeRequest, pb.LeaderboardService_StreamLeaderboardServer) error{
+		"top":      s.StreamLeaderboard,
+		"all":      s.StreamBoardChanges,
+		"snapshot": s.StreamLeaderboard,
+	} {
+		req := &pb.SubscribeRequest{ResumeFromSeq: 1}
+		if name == "snapshot" {
+			req.ResumeFromSeq = 0
+		}
+		stream := &testStream{ctx: ctx, updates: make(chan *pb.LeaderboardUpdate, 10)}
+		go serve(req, stream)
+
+		first := <-stream.updates
+		if name == "snapshot" {
+			if first.Kind != pb.LeaderboardUpdate_SNAPSHOT {
+				t.Errorf("stream without seq started with %v, want a SNAPSHOT", first.Kind)
+			}
+			continue
+		}
+		second := <-stream.updates
+		if first.Seq != 2 || second.Seq != 3 {
+			t.Errorf("%s stream resumed with %v then %v, want the changes with seq 2 and 3", name, first, second)
+		}
+	}
+}
+
+// subscribeStream is a SubscribeLeaderboard server stream: it opens with first,
+// then sends no other control message, and forwards what it is sent
+type subscribeStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	first   *pb.SubscribeControl
+	updates chan *pb.LeaderboardUpdate
+}
+
+func (s *subscribeStream) Context() context.Context { return s.ctx }
+
+func (s *subscribeStream) Recv() (*pb.SubscribeControl, error) {
+	if first := s.first; first != nil {
+		s.first = nil
+		return first, nil
+	}
+	<-s.ctx.Done()
+	return nil, io.EOF
+}
+
+func (s *subscribeStream) Send(update *pb.LeaderboardUpdate) error {
+	s.updates <- update
+	return nil
+}
+
+// snapshotHook runs afterRead once, right after the first top scores read
+type snapshotHook struct {
+	service.Leaderboard
+	afterRead func()
+}
+
+func (h *snapshotHook) GetTopScores(ctx context.Context, board string, limit, offset int32) ([]store.Score, error) {
+	scores, err := h.Leaderboard.GetTopScores(ctx, board, limit, offset)
+	if h.afterRead != nil {
+		h.afterRead()
+		h.afterRead = nil
+	}
+	return scores, err
+}
+
+func TestSubscribeLeaderboardChangeDuringSnapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := service.New(st, &logger, service.Options{})
+	for _, sub := range []service.ScoreSubmission{{PlayerName: "Alice", Score: 300}, {PlayerName: "Bob", Score: 200}} {
+		if _, err := svc.SubmitScore(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Bob improves once the snapshot is read, before it is sent. The change of
+	// another board only returns once Bob's has been broadcast.
+	changes := make(chan notify.ScoreChange)
+	hook := &snapshotHook{Leaderboard: svc, afterRead: func() {
+		changes <- notify.ScoreChange{LeaderboardID: "global", PlayerName: "Bob", Score: 350, RankScore: 350, Op: "update"}
+		changes <- notify.ScoreChange{LeaderboardID: "level-1", PlayerName: "Carol", Score: 100, RankScore: 100, Op: "insert"}
+	}}
+	s := NewServer(hook, changes, &logger, 10, 10, 0, 0)
+
+	stream := &subscribeStream{ctx: ctx, first: &pb.SubscribeControl{}, updates: make(chan *pb.LeaderboardUpdate, 10)}
+	go s.SubscribeLeaderboard(stream)
+	if u := <-stream.updates; u.Kind != pb.LeaderboardUpdate_SNAPSHOT || len(u.Snapshot) != 2 || u.Snapshot[1].Score != 200 {
+		t.Fatalf("first update = %v, want the SNAPSHOT read before Bob's change", u)
+	}
+	select {
+	case u := <-stream.updates:
+		if u.Kind != pb.LeaderboardUpdate_UPSERT || u.Changed.PlayerName != "Bob" || u.Changed.Score != 350 {
+			t.Errorf("update after the snapshot = %v, want Bob's UPSERT to 350", u)
+		}
+	case <-time.After(time.Second):
+		t.Error("change made during the snapshot was lost")
+	}
+}
+
+func TestSubscribeLeaderboardOverCap(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	s := NewServer(service.New(st, &logger, service.Options{}), make(chan notify.ScoreChange), &logger, 10, 10, 0, 0)
+	s.SetMaxSubscribers(1)
+	if err := s.addSubscriber("global", make(chan *pb.LeaderboardUpdate, 1), &subscriber{}); err != nil {
+		t.Fatalf("addSubscriber: %v", err)
+	}
+
+	stream := &subscribeStream{ctx: ctx, first: &pb.SubscribeControl{}, updates: make(chan *pb.LeaderboardUpdate, 10)}
+	err = s.SubscribeLeaderboard(stream)
+	if st, info, _ := details(t, err); st.Code() != codes.ResourceExhausted || info.Reason != ReasonTooManySubscribers {
+		t.Errorf("subscription over the cap: got %v %s, want ResourceExhausted", st.Code(), info.Reason)
+	}
+	if len(stream.updates) != 0 {
+		t.Errorf("refused subscriber was sent %v", <-stream.updates)
+	}
+}
+
+func TestStreamRankChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := service.New(st, &logger, service.Options{})
+	for _, sub := range []service.ScoreSubmission{{PlayerName: "Alice", Score: 300}, {PlayerName: "Bob", Score: 200}} {
+		if _, err := svc.SubmitScore(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes := make(chan notify.ScoreChange)
+	s := NewServer(svc, changes, &logger, 10, 10, 0, 0)
+	stream := &testStream{ctx: ctx, updates: make(chan *pb.LeaderboardUpdate, 10)}
+	go s.StreamLeaderboard(&pb.SubscribeRequest{InitialLimit: 2, RankChanges: true}, stream)
+	<-stream.updates // snapshot
+	for s.SubscriberCount() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	changes <- notify.ScoreChange{LeaderboardID: "global", PlayerName: "Bob", Score: 400, RankScore: 400, Op: "update"}
+	if u := <-stream.updates; u.Kind != pb.LeaderboardUpdate_UPSERT || u.Changed.PlayerName != "Bob" {
+		t.Fatalf("first update = %v, want Bob's UPSERT", u)
+	}
+	for _, want := range []struct {
+		name             string
+		oldRank, newRank int64
+	}{{"Bob", 2, 1}, {"Alice", 1, 2}} {
+		u := <-stream.updates
+		if u.Kind != pb.LeaderboardUpdate_RANK_CHANGED || u.Changed.GetPlayerName() != want.name || u.OldRank != want.oldRank || u.NewRank != want.newRank {
+			t.Errorf("got %v, want %s's RANK_CHANGED from %d to %d", u, want.name, want.oldRank, want.newRank)
+		}
+	}
+	if len(stream.updates) != 0 {
+		t.Errorf("unexpected update %v", <-stream.updates)
+	}
+}
@@ -0,0 +1,280 @@
+package grpc
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"google.golang.org/grpc/codes"
+)
+
+// Stream RPCs a subscriber can come from
+const (
+	streamLeaderboard    = "StreamLeaderboard"
+	streamBoardChanges   = "StreamBoardChanges"
+	subscribeLeaderboard = "SubscribeLeaderboard"
+	streamPlayer         = "StreamPlayer"
+)
+
+// subscriber is the bookkeeping of a stream subscribed to a board, listed by
+// ListSubscribers. The counters are updated by broadcasts under the read lock,
+// hence atomic.
+type subscriber struct {
+	id          uint64 // set by subscribe
+	board       string // set by subscribe
+	stream      string
+	transport   string
+	peer        netip.Addr
+	player      string
+	connectedAt time.Time
+
+	limit     atomic.Int32 // current top N, changed by SubscribeLeaderboard controls
+	delivered atomic.Int64
+	dropped   atomic.Int64 // updates skipped because the stream's queue was full
+
+	// resync is set when a resync marker found the stream's queue full. The
+	// queue is full, so the stream goroutine receives another update soon and
+	// checks it then (see needsResync).
+	resync atomic.Bool
+
+	// kicked is closed, under the server's lock, when an operator disconnects
+	// the stream
+	kicked       chan struct{}
+	disconnected bool
+}
+
+// newSubscriber describes a stream of the request in ctx
+func newSubscriber(ctx context.Context, stream string, limit int32) *subscriber {
+	info := requestctx.FromContext(ctx)
+	sub := &subscriber{
+		stream:      stream,
+		transport:   info.Transport,
+		peer:        info.ClientIP,
+		connectedAt: time.Now(),
+	}
+	sub.limit.Store(limit)
+	return sub
+}
+
+// needsResync reports whether the stream must reload its board before update:
+// update is a resync marker, or a marker was flagged while queue was full. The
+// updates queued since a flagged marker are superseded by the reload, so they
+// are discarded with update.
+func (sub *subscriber) needsResync(update *pb.LeaderboardUpdate, queue <-chan *pb.LeaderboardUpdate) bool {
+	flagged := sub.resync.Swap(false)
+	if update == resyncMarker {
+		return true
+	}
+	if !flagged {
+		return false
+	}
+	for {
+		select {
+		case <-queue:
+		default:
+			return true
+		}
+	}
+}
+
+// toSubscriber converts the bookkeeping of a subscriber to its protobuf representation
+func (sub *subscriber) toSubscriber() *pb.Subscriber {
+	out := &pb.Subscriber{
+		Id:            sub.id,
+		LeaderboardId: sub.board,
+		Stream:        sub.stream,
+		Transport:     sub.transport,
+		ConnectedAt:   sub.connectedAt.Format(time.RFC3339),
+		Limit:         sub.limit.Load(),
+		PlayerName:    sub.player,
+		Delivered:     sub.delivered.Load(),
+		Dropped:       sub.dropped.Load(),
+	}
+	if sub.peer.IsValid() {
+		out.PeerAddress = sub.peer.String()
+	}
+	return out
+}
+
+// Subscribers lists the active subscribers of a board, or of every board when
+// board is empty, oldest first. REST serves it as GET /admin/subscribers.
+func (s *Server) Subscribers(board string) []*pb.Subscriber {
+	s.mu.RLock()
+	var subs []*subscriber
+	for b, set := range s.subscribers {
+		if board != "" && b != board {
+			continue
+		}
+		for _, sub := range set {
+			subs = append(subs, sub)
+		}
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(subs, func(a, b *subscriber) int { return cmp.Compare(a.id, b.id) })
+	out := make([]*pb.Subscriber, len(subs))
+	for i, sub := range subs {
+		out[i] = sub.toSubscriber()
+	}
+	return out
+}
+
+// ListSubscribers implements the ListSubscribers RPC. Admin only.
+func (s *Server) ListSubscribers(ctx context.Context, req *pb.ListSubscribersRequest) (*pb.ListSubscribersResponse, error) {
+	if _, err := s.authenticateAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return &pb.ListSubscribersResponse{Subscribers: s.Subscribers(req.LeaderboardId)}, nil
+}
+
+// Disconnect ends the stream of the subscriber with id, or when id is 0 every
+// stream of the client address peerAddress, and returns how many it ended. The
+// streams fail with Aborted once they notice, which is immediate unless they are
+// blocked sending to a slow client. Errors are status errors: InvalidArgument for
+// a bad request, NotFound when no stream matches. REST serves it as
+// POST /admin/subscribers/disconnect.
+func (s *Server) Disconnect(id uint64, peerAddress string) (int, error) {
+	if (id == 0) == (peerAddress == "") {
+		return 0, invalidArgument(ReasonMissingField, "id", "set exactly one of id and peer_address")
+	}
+	var peer netip.Addr
+	if peerAddress != "" {
+		var err error
+		if peer, err = parsePeerAddress(peerAddress); err != nil {
+			return 0, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, set := range s.subscribers {
+		for _, sub := range set {
+			if sub.disconnected || (id != 0 && sub.id != id) || (id == 0 && sub.peer != peer) {
+				continue
+			}
+			sub.disconnected = true
+			close(sub.kicked)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, statusError(codes.NotFound, ReasonSubscriberNotFound, "no active stream matches")
+	}
+	s.logger.Info().Uint64("subscriber", id).Int("disconnected", n).Msg("subscribers disconnected by an operator")
+	return n, nil
+}
+
+// disconnected returns the status ending a stream disconnected by an operator
+func (s *Server) disconnected(ctx context.Context, sub *subscriber) error {
+	s.loggerFor(ctx).Info().Uint64("subscriber", sub.id).Str("leaderboard", sub.board).Msg("stream disconnected by an operator")
+	return statusError(codes.Aborted, ReasonStreamDisconnected, "stream disconnected by an operator")
+}
+
+// maxStreamQuota bounds stream quotas; higher ones are better lifted
+const maxStreamQuota = 100000
+
+// SetPeerQuota caps the streams the client address peerAddress may hold, 0
+// blocking it. Streams already open are kept. Errors are InvalidArgument status
+// errors. REST serves it as PUT /admin/stream-quotas/{peer_address}.
+func (s *Server) SetPeerQuota(peerAddress string, maxStreams int32) (*pb.StreamQuota, error) {
+	peer, err := parsePeerAddress(peerAddress)
+	if err != nil {
+		return nil, err
+	}
+	if maxStreams < 0 || maxStreams > maxStreamQuota {
+		return nil, invalidArgument(ReasonInvalidStreamQuota, "max_streams", fmt.Sprintf("max_streams must be between 0 and %d", maxStreamQuota))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotas[peer] = int(maxStreams)
+	s.logger.Info().Int32("max_streams", maxStreams).Msg("stream quota set")
+	return s.quota(peer), nil
+}
+
+// RemovePeerQuota lifts the stream quota of a client address, if any. Errors are
+// InvalidArgument status errors.
+func (s *Server) RemovePeerQuota(peerAddress string) error {
+	peer, err := parsePeerAddress(peerAddress)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.quotas, peer)
+	return nil
+}
+
+// StreamQuotas lists the stream quotas, by address
+func (s *Server) StreamQuotas() []*pb.StreamQuota {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	peers := slices.SortedFunc(maps.Keys(s.quotas), netip.Addr.Compare)
+	out := make([]*pb.StreamQuota, len(peers))
+	for i, peer := range peers {
+		out[i] = s.quota(peer)
+	}
+	return out
+}
+
+// quota returns the stream quota of a client address. The caller holds s.mu.
+func (s *Server) quota(peer netip.Addr) *pb.StreamQuota {
+	return &pb.StreamQuota{PeerAddress: peer.String(), MaxStreams: int32(s.quotas[peer]), ActiveStreams: int32(s.peerStreams[peer])}
+}
+
+// parsePeerAddress parses the client address of a stream quota or disconnect
+// request; IPv4-mapped IPv6 addresses are unmapped, as client addresses are.
+func parsePeerAddress(value string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return netip.Addr{}, invalidArgument(ReasonInvalidPeerAddress, "peer_address", "peer_address must be an IPv4 or IPv6 address")
+	}
+	return addr.Unmap(), nil
+}
+
+// DisconnectSubscribers implements the DisconnectSubscribers RPC. Admin only.
+func (s *Server) DisconnectSubscribers(ctx context.Context, req *pb.DisconnectSubscribersRequest) (*pb.DisconnectSubscribersResponse, error) {
+	if _, err := s.authenticateAdmin(ctx); err != nil {
+		return nil, err
+	}
+	n, err := s.Disconnect(req.Id, req.PeerAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DisconnectSubscribersResponse{Disconnected: int32(n)}, nil
+}
+
+// SetStreamQuota implements the SetStreamQuota RPC. Admin only.
+func (s *Server) SetStreamQuota(ctx context.Context, req *pb.SetStreamQuotaRequest) (*pb.SetStreamQuotaResponse, error) {
+	if _, err := s.authenticateAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if req.Remove {
+		if err := s.RemovePeerQuota(req.PeerAddress); err != nil {
+			return nil, err
+		}
+		return &pb.SetStreamQuotaResponse{}, nil
+	}
+	quota, err := s.SetPeerQuota(req.PeerAddress, req.MaxStreams)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SetStreamQuotaResponse{Quota: quota}, nil
+}
+
+// ListStreamQuotas implements the ListStreamQuotas RPC. Admin only.
+func (s *Server) ListStreamQuotas(ctx context.Context, req *pb.ListStreamQuotasRequest) (*pb.ListStreamQuotasResponse, error) {
+	if _, err := s.authenticateAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return &pb.ListStreamQuotasResponse{Quotas: s.StreamQuotas()}, nil
+}
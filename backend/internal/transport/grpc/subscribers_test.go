@@ -0,0 +1,129 @@
+package grpc
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/rs/zerolog"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/requestctx"
+	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestListSubscribers(t *testing.T) {
+	s := newFakeServer(&fakeService{adminToken: "secret"})
+	client := requestctx.NewContext(context.Background(), requestctx.Info{Transport: requestctx.TransportREST, ClientIP: netip.MustParseAddr("203.0.113.7")})
+
+	top := make(chan *pb.LeaderboardUpdate, 1)
+	if err := s.addSubscriber("level-1", top, newSubscriber(client, streamLeaderboard, 10)); err != nil {
+		t.Fatal(err)
+	}
+	player := newSubscriber(context.Background(), streamPlayer, 0)
+	player.player = "Alice"
+	if err := s.addSubscriber("level-2", make(chan *pb.LeaderboardUpdate, 5), player); err != nil {
+		t.Fatal(err)
+	}
+	// The first update fills the queue of the top stream, which drops the second
+	s.broadcast("level-1", update(pb.LeaderboardUpdate_UPSERT, "Bob", 100))
+	s.broadcast("level-1", update(pb.LeaderboardUpdate_UPSERT, "Carol", 200))
+
+	_, err := s.ListSubscribers(context.Background(), &pb.ListSubscribersRequest{})
+	if st, info, _ := details(t, err); st.Code() != codes.Unauthenticated || info.Reason != ReasonAdminUnauthorized {
+		t.Errorf("without token: got %v %s, want Unauthenticated", st.Code(), info.Reason)
+	}
+
+	admin := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	resp, err := s.ListSubscribers(admin, &pb.ListSubscribersRequest{})
+	if err != nil {
+		t.Fatalf("ListSubscribers: %v", err)
+	}
+	if len(resp.Subscribers) != 2 {
+		t.Fatalf("subscribers = %v, want 2", resp.Subscribers)
+	}
+	first, second := resp.Subscribers[0], resp.Subscribers[1]
+	if first.LeaderboardId != "level-1" || first.Stream != streamLeaderboard || first.Transport != "rest" || first.PeerAddress != "203.0.113.7" ||
+		first.Limit != 10 || first.Delivered != 1 || first.Dropped != 1 || first.ConnectedAt == "" {
+		t.Errorf("first subscriber = %v", first)
+	}
+	if second.LeaderboardId != "level-2" || second.PlayerName != "Alice" || second.PeerAddress != "" || second.Id <= first.Id {
+		t.Errorf("second subscriber = %v", second)
+	}
+
+	resp, err = s.ListSubscribers(admin, &pb.ListSubscribersRequest{LeaderboardId: "level-2"})
+	if err != nil || len(resp.Subscribers) != 1 || resp.Subscribers[0].PlayerName != "Alice" {
+		t.Errorf("level-2 subscribers = %v, %v", resp.GetSubscribers(), err)
+	}
+
+	s.removeSubscriber("level-1", top)
+	if subs := s.Subscribers(""); len(subs) != 1 || subs[0].LeaderboardId != "level-2" {
+		t.Errorf("subscribers after the top stream left = %v", subs)
+	}
+}
+
+func TestDisconnectAndStreamQuotas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	s := NewServer(service.New(st, &logger, service.Options{}), make(chan notify.ScoreChange), &logger, 10, 10, 0, 0)
+	client := requestctx.NewContext(ctx, requestctx.Info{ClientIP: netip.MustParseAddr("203.0.113.7")})
+
+	if _, err := s.SetPeerQuota("::ffff:203.0.113.7", 1); err != nil {
+		t.Fatalf("SetPeerQuota: %v", err)
+	}
+	stream := &testStream{ctx: client, updates: make(chan *pb.LeaderboardUpdate, 10)}
+	done := make(chan error, 1)
+	go func() { done <- s.StreamBoardChanges(&pb.SubscribeRequest{}, stream) }()
+	<-stream.updates // snapshot
+
+	err = s.StreamLeaderboard(&pb.SubscribeRequest{}, &testStream{ctx: client, updates: make(chan *pb.LeaderboardUpdate, 10)})
+	if st, info, _ := details(t, err); st.Code() != codes.ResourceExhausted || info.Reason != ReasonStreamQuotaExceeded {
+		t.Errorf("stream over the quota: got %v %s, want ResourceExhausted", st.Code(), info.Reason)
+	}
+	if quotas := s.StreamQuotas(); len(quotas) != 1 || quotas[0].PeerAddress != "203.0.113.7" || quotas[0].ActiveStreams != 1 {
+		t.Errorf("quotas = %v", quotas)
+	}
+
+	if _, err := s.Disconnect(0, "198.51.100.1"); status.Code(err) != codes.NotFound {
+		t.Errorf("disconnect of an address without streams: got %v, want NotFound", err)
+	}
+	if n, err := s.Disconnect(0, "203.0.113.7"); err != nil || n != 1 {
+		t.Fatalf("Disconnect = %d, %v", n, err)
+	}
+	if st, info, _ := details(t, <-done); st.Code() != codes.Aborted || info.Reason != ReasonStreamDisconnected {
+		t.Errorf("disconnected stream ended with %v %s, want Aborted", st.Code(), info.Reason)
+	}
+	if quotas := s.StreamQuotas(); quotas[0].ActiveStreams != 0 {
+		t.Errorf("active streams after the disconnect = %d", quotas[0].ActiveStreams)
+	}
+
+	for _, tt := range []struct {
+		peer string
+		max  int32
+	}{{"not-an-ip", 1}, {"203.0.113.7", -1}} {
+		if _, err := s.SetPeerQuota(tt.peer, tt.max); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("SetPeerQuota(%q, %d): got %v, want InvalidArgument", tt.peer, tt.max, err)
+		}
+	}
+	if _, err := s.Disconnect(1, "203.0.113.7"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("disconnect by id and address: got %v, want InvalidArgument", err)
+	}
+
+	admin := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	if _, err := s.SetStreamQuota(admin, &pb.SetStreamQuotaRequest{PeerAddress: "203.0.113.7", Remove: true}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("SetStreamQuota with admin disabled: got %v, want PermissionDenied", err)
+	}
+	if err := s.RemovePeerQuota("203.0.113.7"); err != nil || len(s.StreamQuotas()) != 0 {
+		t.Errorf("RemovePeerQuota: %v, quotas %v", err, s.StreamQuotas())
+	}
+}
@@ -0,0 +1,251 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	pbv2 "github.com/yourorg/leaderboard/gen/leaderboard/v2"
+	"github.com/yourorg/leaderboard/internal/service"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The v1 RPCs superseded by leaderboard.v2 are shims: they convert their request
+// to v2, call the v2 implementation and convert its response back, so that both
+// versions answer alike until v1 is retired.
+
+// SubmitScore implements the SubmitScore RPC
+func (s *Server) SubmitScore(ctx context.Context, req *pb.SubmitScoreRequest) (*pb.SubmitScoreResponse, error) {
+	var achievedAt *timestamppb.Timestamp
+	if req.AchievedAt != "" {
+		t, err := time.Parse(time.RFC3339, req.AchievedAt)
+		if err != nil {
+			return nil, invalidArgument(ReasonInvalidTimestamp, "achieved_at", "achieved_at must be an RFC3339 timestamp")
+		}
+		achievedAt = timestamppb.New(t)
+	}
+	var signedAt *timestamppb.Timestamp
+	if req.SignedAt != 0 {
+		signedAt = &timestamppb.Timestamp{Seconds: req.SignedAt}
+	}
+
+	resp, err := s.v2.SubmitScore(ctx, &pbv2.SubmitScoreRequest{
+		LeaderboardId:  req.LeaderboardId,
+		PlayerName:     req.PlayerName,
+		Score:          req.Score,
+		SecondaryScore: req.SecondaryScore,
+		AchievedAt:     achievedAt,
+		Metadata:       req.Metadata,
+		DeviceId:       req.DeviceId,
+		Country:        req.Country,
+		Platform:       req.Platform,
+		Nonce:          req.Nonce,
+		SignedAt:       signedAt,
+		Signature:      req.Signature,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.SubmitScoreResponse{
+		Applied:   resp.Applied,
+		Entry:     entryToV1(resp.Entry),
+		Receipt:   receiptToV1(resp.Receipt),
+		Rank:      resp.Rank,
+		RankDelta: resp.RankDelta,
+		Accepted:  resp.Accepted,
+	}, nil
+}
+
+// GetTopScores implements the GetTopScores RPC
+func (s *Server) GetTopScores(ctx context.Context, req *pb.GetTopScoresRequest) (*pb.GetTopScoresResponse, error) {
+	resp, err := s.v2.topScores(ctx, &pbv2.GetTopScoresRequest{
+		LeaderboardId: req.LeaderboardId,
+		PageSize:      req.Limit,
+		PageToken:     req.PageToken,
+		ReadMask:      req.ReadMask,
+		Region:        req.Region,
+		Platform:      req.Platform,
+	}, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*pb.ScoreEntry, len(resp.Entries))
+	for i, entry := range resp.Entries {
+		entries[i] = entryToV1(entry)
+	}
+	return &pb.GetTopScoresResponse{
+		Entries:        entries,
+		NextPageToken:  resp.NextPageToken,
+		RankingVariant: resp.RankingVariant,
+		TotalCount:     resp.TotalCount,
+	}, nil
+}
+
+// GetPlayerRank implements the GetPlayerRank RPC
+func (s *Server) GetPlayerRank(ctx context.Context, req *pb.GetPlayerRankRequest) (*pb.GetPlayerRankResponse, error) {
+	if req.PlayerName == "" {
+		return nil, invalidArgument(ReasonMissingField, "player_name", "player_name is required")
+	}
+
+	resp, err := s.v2.playerRank(ctx, &pbv2.GetPlayerRankRequest{
+		LeaderboardId: req.LeaderboardId,
+		PlayerName:    req.PlayerName,
+		Region:        req.Region,
+		Platform:      req.Platform,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrPlayerNotFound) {
+			return &pb.GetPlayerRankResponse{
+				NotFound: true,
+			}, nil
+		}
+		return nil, s.fromServiceError(ctx, err, "get player rank")
+	}
+
+	return &pb.GetPlayerRankResponse{
+		NotFound:       false,
+		Rank:           resp.Rank,
+		Entry:          entryToV1(resp.Entry),
+		RankingVariant: resp.RankingVariant,
+	}, nil
+}
+
+// GetPlayerRanks implements the GetPlayerRanks RPC
+func (s *Server) GetPlayerRanks(ctx context.Context, req *pb.GetPlayerRanksRequest) (*pb.GetPlayerRanksResponse, error) {
+	resp, err := s.v2.GetPlayerRanks(ctx, &pbv2.GetPlayerRanksRequest{
+		LeaderboardId: req.LeaderboardId,
+		PlayerNames:   req.PlayerNames,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ranks := make([]*pb.PlayerRankEntry, len(resp.Ranks))
+	for i, rank := range resp.Ranks {
+		ranks[i] = &pb.PlayerRankEntry{Rank: rank.Rank, Entry: entryToV1(rank.Entry)}
+	}
+	return &pb.GetPlayerRanksResponse{
+		Ranks:    ranks,
+		NotFound: resp.NotFound,
+	}, nil
+}
+
+// entryToV1 converts a v2 entry to v1, with its times in both their string and
+// Timestamp forms (nil stays nil)
+func entryToV1(e *pbv2.ScoreEntry) *pb.ScoreEntry {
+	if e == nil {
+		return nil
+	}
+	return &pb.ScoreEntry{
+		PlayerName:     e.PlayerName,
+		Score:          e.Score,
+		UpdatedAt:      formatTimestamp(e.UpdatedAt, time.RFC3339),
+		Tier:           e.Tier,
+		AchievedAt:     formatTimestamp(e.AchievedAt, time.RFC3339Nano),
+		Profile:        profileToV1(e.Profile),
+		LeaderboardId:  e.LeaderboardId,
+		Metadata:       e.Metadata,
+		SecondaryScore: e.SecondaryScore,
+		Region:         e.Region,
+		Platform:       e.Platform,
+		UpdatedTime:    e.UpdatedAt,
+		AchievedTime:   e.AchievedAt,
+	}
+}
+
+// profileToV1 converts a v2 profile to v1 (nil stays nil)
+func profileToV1(p *pbv2.PlayerProfile) *pb.PlayerProfile {
+	if p == nil {
+		return nil
+	}
+	return &pb.PlayerProfile{
+		PlayerName:  p.PlayerName,
+		DisplayName: p.DisplayName,
+		CountryCode: p.CountryCode,
+		AvatarUrl:   p.AvatarUrl,
+		CreatedAt:   formatTimestamp(p.CreatedAt, time.RFC3339),
+		UpdatedAt:   formatTimestamp(p.UpdatedAt, time.RFC3339),
+		CreatedTime: p.CreatedAt,
+		UpdatedTime: p.UpdatedAt,
+	}
+}
+
+// receiptToV1 converts a v2 receipt to v1 (nil stays nil)
+func receiptToV1(r *pbv2.ScoreReceipt) *pb.ScoreReceipt {
+	if r == nil {
+		return nil
+	}
+	return &pb.ScoreReceipt{
+		LeaderboardId: r.LeaderboardId,
+		PlayerName:    r.PlayerName,
+		Score:         r.Score,
+		IssuedAt:      r.IssuedAt,
+		Applied:       r.Applied,
+		KeyId:         r.KeyId,
+		Signature:     r.Signature,
+	}
+}
+
+// updateToV2 converts a v1 stream update to v2, for v2 streams served by the v1
+// stream implementation
+func updateToV2(u *pb.LeaderboardUpdate) *pbv2.LeaderboardUpdate {
+	out := &pbv2.LeaderboardUpdate{
+		Kind:         pbv2.LeaderboardUpdate_Kind(u.Kind), // both enums share their values
+		Seq:          u.Seq,
+		Changed:      entryToV2(u.Changed),
+		PreviousTier: u.PreviousTier,
+		OldRank:      u.OldRank,
+		NewRank:      u.NewRank,
+	}
+	if u.Snapshot != nil {
+		out.Snapshot = make([]*pbv2.ScoreEntry, len(u.Snapshot))
+		for i, entry := range u.Snapshot {
+			out.Snapshot[i] = entryToV2(entry)
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, u.ServerTime); err == nil {
+		out.ServerTime = timestamppb.New(t)
+	}
+	return out
+}
+
+// entryToV2 converts a v1 entry to v2 from its Timestamp fields (nil stays nil)
+func entryToV2(e *pb.ScoreEntry) *pbv2.ScoreEntry {
+	if e == nil {
+		return nil
+	}
+	entry := &pbv2.ScoreEntry{
+		LeaderboardId:  e.LeaderboardId,
+		PlayerName:     e.PlayerName,
+		Score:          e.Score,
+		SecondaryScore: e.SecondaryScore,
+		AchievedAt:     e.AchievedTime,
+		UpdatedAt:      e.UpdatedTime,
+		Tier:           e.Tier,
+		Metadata:       e.Metadata,
+		Region:         e.Region,
+		Platform:       e.Platform,
+	}
+	if p := e.Profile; p != nil {
+		entry.Profile = &pbv2.PlayerProfile{
+			PlayerName:  p.PlayerName,
+			DisplayName: p.DisplayName,
+			CountryCode: p.CountryCode,
+			AvatarUrl:   p.AvatarUrl,
+			CreatedAt:   p.CreatedTime,
+			UpdatedAt:   p.UpdatedTime,
+		}
+	}
+	return entry
+}
+
+// formatTimestamp formats a protobuf time with layout, "" for nil
+func formatTimestamp(ts *timestamppb.Timestamp, layout string) string {
+	if ts == nil {
+		return ""
+	}
+	return ts.AsTime().Format(layout)
+}
@@ -0,0 +1,312 @@
+package grpc
+
+import (
+	"context"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	pbv2 "github.com/yourorg/leaderboard/gen/leaderboard/v2"
+	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/store"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// V2Server implements leaderboard.v2.LeaderboardService. Its unary RPCs are the
+// implementation of record: their v1 counterparts are shims converting to and
+// from them (v1shims.go). Streams share the broadcast hub of the v1 server,
+// whose updates are converted on the way out.
+type V2Server struct {
+	pbv2.UnimplementedLeaderboardServiceServer
+	s *Server
+}
+
+// V2 returns the leaderboard.v2 service of s, to register next to s on the same gRPC server
+func (s *Server) V2() *V2Server {
+	return s.v2
+}
+
+// SubmitScore implements the v2 SubmitScore RPC
+func (v *V2Server) SubmitScore(ctx context.Context, req *pbv2.SubmitScoreRequest) (*pbv2.SubmitScoreResponse, error) {
+	if req.PlayerName == "" {
+		return nil, invalidArgument(ReasonMissingField, "player_name", "player_name is required")
+	}
+	if req.Score < 0 {
+		return nil, invalidArgument(ReasonInvalidScore, "score", "score must be non-negative")
+	}
+	if req.AchievedAt != nil {
+		if err := req.AchievedAt.CheckValid(); err != nil {
+			return nil, invalidArgument(ReasonInvalidTimestamp, "achieved_at", "achieved_at must be a valid timestamp")
+		}
+	}
+
+	result, err := v.s.svc.SubmitScore(ctx, service.ScoreSubmission{
+		LeaderboardID:  req.LeaderboardId,
+		PlayerName:     req.PlayerName,
+		Score:          req.Score,
+		DeviceID:       req.DeviceId,
+		AchievedAt:     fromTimestamp(req.AchievedAt),
+		Metadata:       req.Metadata,
+		SecondaryScore: req.SecondaryScore,
+		Country:        req.Country,
+		Platform:       req.Platform,
+		Nonce:          req.Nonce,
+		SignedAt:       req.GetSignedAt().GetSeconds(),
+		Signature:      req.Signature,
+	})
+	if err != nil {
+		return nil, v.s.fromServiceError(ctx, err, "submit score")
+	}
+
+	return &pbv2.SubmitScoreResponse{
+		Entry: &pbv2.ScoreEntry{
+			LeaderboardId:  result.LeaderboardID,
+			PlayerName:     result.PlayerName,
+			Score:          result.Score,
+			SecondaryScore: result.SecondaryScore,
+			AchievedAt:     toTimestamp(result.AchievedAt),
+			UpdatedAt:      toTimestamp(result.UpdatedAt),
+			Tier:           v.s.svc.TierFor(result.LeaderboardID, result.Score),
+			Profile:        v.profileOf(ctx, result.PlayerName),
+			Metadata:       result.Metadata,
+			Region:         result.Country,
+			Platform:       result.Platform,
+		},
+		Applied:   result.Applied,
+		Rank:      result.Rank,
+		RankDelta: result.RankDelta,
+		Receipt:   toReceiptV2(result.Receipt),
+		Accepted:  result.Accepted,
+	}, nil
+}
+
+// GetTopScores implements the v2 GetTopScores RPC
+func (v *V2Server) GetTopScores(ctx context.Context, req *pbv2.GetTopScoresRequest) (*pbv2.GetTopScoresResponse, error) {
+	return v.topScores(ctx, req, 0)
+}
+
+// topScores serves a page of the top scores. offset only comes from v1
+// requests, which may still page by offset; it is ignored with a page token.
+func (v *V2Server) topScores(ctx context.Context, req *pbv2.GetTopScoresRequest, offset int32) (*pbv2.GetTopScoresResponse, error) {
+	limit := v.s.clampLimit(req.PageSize)
+	mask, err := service.ParseFieldMask(req.GetReadMask().GetPaths())
+	if err != nil {
+		return nil, v.s.fromServiceError(ctx, err, "get top scores")
+	}
+
+	var page *service.TopScoresPage
+	if seg := (service.Segment{Region: req.Region, Platform: req.Platform}); !seg.IsZero() {
+		page, err = v.s.svc.GetSegmentTopScoresPage(ctx, req.LeaderboardId, seg, limit, max(offset, 0), req.PageToken)
+	} else {
+		page, err = v.s.svc.GetTopScoresPage(ctx, req.LeaderboardId, limit, max(offset, 0), req.PageToken)
+	}
+	if err != nil {
+		return nil, v.s.fromServiceError(ctx, err, "get top scores")
+	}
+
+	return &pbv2.GetTopScoresResponse{
+		Entries:        v.toMaskedEntries(ctx, page.Scores, mask),
+		NextPageToken:  page.NextPageToken,
+		RankingVariant: page.RankingVariant,
+		TotalCount:     page.TotalCount,
+	}, nil
+}
+
+// GetPlayerRank implements the v2 GetPlayerRank RPC
+func (v *V2Server) GetPlayerRank(ctx context.Context, req *pbv2.GetPlayerRankRequest) (*pbv2.GetPlayerRankResponse, error) {
+	if req.PlayerName == "" {
+		return nil, invalidArgument(ReasonMissingField, "player_name", "player_name is required")
+	}
+	resp, err := v.playerRank(ctx, req)
+	if err != nil {
+		return nil, v.s.fromServiceError(ctx, err, "get player rank")
+	}
+	return resp, nil
+}
+
+// playerRank ranks a player, returning service errors as they are: v1 answers
+// ErrPlayerNotFound with a not_found flag rather than a status
+func (v *V2Server) playerRank(ctx context.Context, req *pbv2.GetPlayerRankRequest) (*pbv2.GetPlayerRankResponse, error) {
+	var rank *service.PlayerRank
+	var err error
+	if seg := (service.Segment{Region: req.Region, Platform: req.Platform}); !seg.IsZero() {
+		rank, err = v.s.svc.GetSegmentPlayerRank(ctx, req.LeaderboardId, req.PlayerName, seg)
+	} else {
+		rank, err = v.s.svc.GetPlayerRank(ctx, req.LeaderboardId, req.PlayerName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &pbv2.GetPlayerRankResponse{
+		Rank:           rank.Rank,
+		Entry:          v.toEntry(rank.Score, v.s.svc.PlayerProfiles(ctx, []string{rank.Score.PlayerName})),
+		RankingVariant: rank.RankingVariant,
+	}, nil
+}
+
+// GetPlayerRanks implements the v2 GetPlayerRanks RPC
+func (v *V2Server) GetPlayerRanks(ctx context.Context, req *pbv2.GetPlayerRanksRequest) (*pbv2.GetPlayerRanksResponse, error) {
+	if len(req.PlayerNames) == 0 {
+		return nil, invalidArgument(ReasonMissingField, "player_names", "player_names is required")
+	}
+
+	ranks, err := v.s.svc.GetPlayerRanks(ctx, req.LeaderboardId, req.PlayerNames)
+	if err != nil {
+		return nil, v.s.fromServiceError(ctx, err, "get player ranks")
+	}
+
+	names := make([]string, len(ranks.Ranks))
+	for i, rank := range ranks.Ranks {
+		names[i] = rank.Score.PlayerName
+	}
+	profiles := v.s.svc.PlayerProfiles(ctx, names)
+	resp := &pbv2.GetPlayerRanksResponse{
+		Ranks:    make([]*pbv2.PlayerRank, len(ranks.Ranks)),
+		NotFound: ranks.NotFound,
+	}
+	for i, rank := range ranks.Ranks {
+		resp.Ranks[i] = &pbv2.PlayerRank{Rank: rank.Rank, Entry: v.toEntry(rank.Score, profiles)}
+	}
+	return resp, nil
+}
+
+// StreamLeaderboard implements the v2 StreamLeaderboard RPC on the stream of the
+// v1 server: same snapshot, filtering, resuming and heartbeats
+func (v *V2Server) StreamLeaderboard(req *pbv2.StreamLeaderboardRequest, stream pbv2.LeaderboardService_StreamLeaderboardServer) error {
+	return v.s.StreamLeaderboard(&pb.SubscribeRequest{
+		LeaderboardId: req.LeaderboardId,
+		InitialLimit:  req.Limit,
+		ResumeFromSeq: req.ResumeFromSeq,
+		RankChanges:   req.RankChanges,
+	}, &v2Stream{ServerStream: stream, out: stream})
+}
+
+// v2Stream sends the v1 updates of a stream as v2 updates
+type v2Stream struct {
+	grpc.ServerStream
+	out pbv2.LeaderboardService_StreamLeaderboardServer
+}
+
+var _ pb.LeaderboardService_StreamLeaderboardServer = (*v2Stream)(nil)
+
+func (st *v2Stream) Send(update *pb.LeaderboardUpdate) error {
+	return st.out.Send(updateToV2(update))
+}
+
+// toEntry converts a store row to its v2 representation, with the player's
+// profile when profiles has one
+func (v *V2Server) toEntry(score store.Score, profiles map[string]store.Player) *pbv2.ScoreEntry {
+	entry := &pbv2.ScoreEntry{
+		LeaderboardId:  score.LeaderboardID,
+		PlayerName:     score.PlayerName,
+		Score:          score.Score,
+		SecondaryScore: score.SecondaryScore,
+		AchievedAt:     timestamppb.New(score.AchievedAt.Time),
+		UpdatedAt:      timestamppb.New(score.UpdatedAt.Time),
+		Tier:           v.s.svc.TierFor(score.LeaderboardID, score.Score),
+		Metadata:       service.DecodeMetadata(score.Metadata),
+		Region:         score.CountryCode,
+		Platform:       score.Platform,
+	}
+	if p, ok := profiles[score.PlayerName]; ok {
+		entry.Profile = toProfileV2(p)
+	}
+	return entry
+}
+
+// toMaskedEntries converts store rows to their v2 representation with only the
+// fields selected by mask set. Profiles are not loaded when the mask leaves them out.
+func (v *V2Server) toMaskedEntries(ctx context.Context, scores []store.Score, mask service.FieldMask) []*pbv2.ScoreEntry {
+	var profiles map[string]store.Player
+	if mask.Has(service.FieldProfile) {
+		names := make([]string, len(scores))
+		for i, score := range scores {
+			names[i] = score.PlayerName
+		}
+		profiles = v.s.svc.PlayerProfiles(ctx, names)
+	}
+
+	entries := make([]*pbv2.ScoreEntry, len(scores))
+	for i, score := range scores {
+		entries[i] = maskEntry(v.toEntry(score, profiles), mask)
+	}
+	return entries
+}
+
+// maskEntry clears the fields of entry that mask does not select
+func maskEntry(entry *pbv2.ScoreEntry, mask service.FieldMask) *pbv2.ScoreEntry {
+	if !mask.Has(service.FieldLeaderboardID) {
+		entry.LeaderboardId = ""
+	}
+	if !mask.Has(service.FieldPlayerName) {
+		entry.PlayerName = ""
+	}
+	if !mask.Has(service.FieldScore) {
+		entry.Score = 0
+	}
+	if !mask.Has(service.FieldUpdatedAt) {
+		entry.UpdatedAt = nil
+	}
+	if !mask.Has(service.FieldTier) {
+		entry.Tier = ""
+	}
+	if !mask.Has(service.FieldAchievedAt) {
+		entry.AchievedAt = nil
+	}
+	if !mask.Has(service.FieldProfile) {
+		entry.Profile = nil
+	}
+	if !mask.Has(service.FieldMetadata) {
+		entry.Metadata = nil
+	}
+	if !mask.Has(service.FieldSecondary) {
+		entry.SecondaryScore = 0
+	}
+	if !mask.Has(service.FieldRegion) {
+		entry.Region = ""
+	}
+	if !mask.Has(service.FieldPlatform) {
+		entry.Platform = ""
+	}
+	return entry
+}
+
+// profileOf returns a player's v2 profile, or nil if the player has none
+func (v *V2Server) profileOf(ctx context.Context, playerName string) *pbv2.PlayerProfile {
+	if p, ok := v.s.svc.PlayerProfiles(ctx, []string{playerName})[playerName]; ok {
+		return toProfileV2(p)
+	}
+	return nil
+}
+
+// toProfileV2 converts a store profile to its v2 representation
+func toProfileV2(p store.Player) *pbv2.PlayerProfile {
+	profile := &pbv2.PlayerProfile{
+		PlayerName:  p.PlayerName,
+		DisplayName: p.DisplayName,
+		CountryCode: p.CountryCode,
+		AvatarUrl:   p.AvatarUrl,
+	}
+	// Profiles built from the identity service alone have no stored timestamps
+	if p.CreatedAt.Valid {
+		profile.CreatedAt = timestamppb.New(p.CreatedAt.Time)
+		profile.UpdatedAt = timestamppb.New(p.UpdatedAt.Time)
+	}
+	return profile
+}
+
+// toReceiptV2 converts a submission receipt to its v2 representation
+func toReceiptV2(r *service.Receipt) *pbv2.ScoreReceipt {
+	if r == nil {
+		return nil
+	}
+	return &pbv2.ScoreReceipt{
+		LeaderboardId: r.LeaderboardID,
+		PlayerName:    r.PlayerName,
+		Score:         r.Score,
+		IssuedAt:      r.IssuedAt,
+		Applied:       r.Applied,
+		KeyId:         r.KeyID,
+		Signature:     r.Signature,
+	}
+}
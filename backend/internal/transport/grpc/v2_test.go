@@ -0,0 +1,190 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	pbv2 "github.com/yourorg/leaderboard/gen/leaderboard/v2"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/store/sqlite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestV2SubmitScore(t *testing.T) {
+	ctx := context.Background()
+	achieved := time.Date(2025, 1, 15, 10, 29, 41, 123456000, time.UTC)
+	svc := &fakeService{result: &service.ScoreResult{
+		LeaderboardID: "level-1",
+		PlayerName:    "Alice",
+		Score:         1500,
+		UpdatedAt:     time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
+		AchievedAt:    achieved,
+		Applied:       true,
+		Rank:          3,
+	}}
+	s := newFakeServer(svc)
+
+	resp, err := s.V2().SubmitScore(ctx, &pbv2.SubmitScoreRequest{
+		LeaderboardId: "level-1",
+		PlayerName:    "Alice",
+		Score:         1500,
+		AchievedAt:    timestamppb.New(achieved),
+		SignedAt:      &timestamppb.Timestamp{Seconds: 1736937000},
+	})
+	if err != nil {
+		t.Fatalf("SubmitScore: %v", err)
+	}
+	if sub := svc.submitted[0]; !sub.AchievedAt.Equal(achieved) || sub.SignedAt != 1736937000 {
+		t.Errorf("service got achieved at %v signed at %d", sub.AchievedAt, sub.SignedAt)
+	}
+	e := resp.Entry
+	if !resp.Applied || resp.Rank != 3 || e.PlayerName != "Alice" || e.Tier != "Gold" ||
+		!e.GetAchievedAt().AsTime().Equal(achieved) || !e.GetUpdatedAt().AsTime().Equal(svc.result.UpdatedAt) {
+		t.Errorf("response = %v", resp)
+	}
+
+	_, err = s.V2().SubmitScore(ctx, &pbv2.SubmitScoreRequest{PlayerName: "Alice", AchievedAt: &timestamppb.Timestamp{Nanos: -1}})
+	if st, info, _ := details(t, err); st.Code() != codes.InvalidArgument || info.Reason != ReasonInvalidTimestamp {
+		t.Errorf("invalid achieved_at: got %v %s, want InvalidArgument %s", st.Code(), info.Reason, ReasonInvalidTimestamp)
+	}
+
+	// A queued submission has no update time: the v1 shim sends empty strings
+	svc = &fakeService{result: &service.ScoreResult{PlayerName: "Alice", Score: 100, Accepted: true}}
+	v1, err := newFakeServer(svc).SubmitScore(ctx, &pb.SubmitScoreRequest{PlayerName: "Alice", Score: 100, SignedAt: 0})
+	if err != nil {
+		t.Fatalf("v1 SubmitScore: %v", err)
+	}
+	if !v1.Accepted || v1.Entry.UpdatedAt != "" || v1.Entry.UpdatedTime != nil || svc.submitted[0].SignedAt != 0 {
+		t.Errorf("queued v1 response = %v", v1)
+	}
+}
+
+func TestV2GetTopScores(t *testing.T) {
+	ctx := context.Background()
+	updated := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	svc := &fakeService{page: &service.TopScoresPage{
+		Scores:        []store.Score{{LeaderboardID: "global", PlayerName: "Alice", Score: 1200, UpdatedAt: pgtype.Timestamptz{Time: updated, Valid: true}}},
+		NextPageToken: "next",
+		TotalCount:    7,
+	}}
+	s := newFakeServer(svc)
+
+	resp, err := s.V2().GetTopScores(ctx, &pbv2.GetTopScoresRequest{PageSize: 500, PageToken: "tok"})
+	if err != nil {
+		t.Fatalf("GetTopScores: %v", err)
+	}
+	if got := svc.pageArgs; got[1] != int32(50) || got[2] != int32(0) || got[3] != "tok" {
+		t.Errorf("service got limit, offset, token %v, want 50, 0, tok", got[1:])
+	}
+	if len(resp.Entries) != 1 || resp.NextPageToken != "next" || resp.TotalCount != 7 || !resp.Entries[0].GetUpdatedAt().AsTime().Equal(updated) {
+		t.Errorf("response = %v", resp)
+	}
+
+	// v1 still pages by offset
+	if v1, err := s.GetTopScores(ctx, &pb.GetTopScoresRequest{Offset: 20}); err != nil || v1.TotalCount != 7 {
+		t.Fatalf("v1 GetTopScores = %v, %v; want a total count of 7", v1, err)
+	}
+	if got := svc.pageArgs; got[2] != int32(20) {
+		t.Errorf("v1 offset: service got %v, want 20", got[2])
+	}
+}
+
+func TestV2GetPlayerRank(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := newFakeServer(&fakeService{err: service.ErrPlayerNotFound}).V2().GetPlayerRank(ctx, &pbv2.GetPlayerRankRequest{PlayerName: "Nobody"})
+	if st, info, _ := details(t, err); st.Code() != codes.NotFound || info.Reason != ReasonPlayerNotFound {
+		t.Errorf("unknown player: got %v %s, want NotFound %s", st.Code(), info.Reason, ReasonPlayerNotFound)
+	}
+
+	svc := &fakeService{rank: &service.PlayerRank{Rank: 4, Score: store.Score{LeaderboardID: "global", PlayerName: "Bob", Score: 900}}}
+	resp, err := newFakeServer(svc).V2().GetPlayerRank(ctx, &pbv2.GetPlayerRankRequest{PlayerName: "Bob"})
+	if err != nil || resp.Rank != 4 || resp.Entry.GetPlayerName() != "Bob" {
+		t.Errorf("GetPlayerRank = %v, %v, want Bob at rank 4", resp, err)
+	}
+}
+
+func TestUpdateToV2(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	u := updateToV2(&pb.LeaderboardUpdate{
+		Kind:       pb.LeaderboardUpdate_HEARTBEAT,
+		ServerTime: now.Format(time.RFC3339),
+	})
+	if u.Kind != pbv2.LeaderboardUpdate_HEARTBEAT || !u.GetServerTime().AsTime().Equal(now) {
+		t.Errorf("heartbeat = %v", u)
+	}
+
+	u = updateToV2(&pb.LeaderboardUpdate{
+		Kind: pb.LeaderboardUpdate_RANK_CHANGED,
+		Changed: &pb.ScoreEntry{
+			PlayerName:  "Bob",
+			UpdatedAt:   now.Format(time.RFC3339),
+			UpdatedTime: timestamppb.New(now),
+			Profile:     &pb.PlayerProfile{PlayerName: "Bob", DisplayName: "Bobby"},
+		},
+		OldRank: 2,
+		NewRank: 1,
+	})
+	if u.Kind != pbv2.LeaderboardUpdate_RANK_CHANGED || u.OldRank != 2 || u.NewRank != 1 || u.ServerTime != nil {
+		t.Errorf("rank change = %v", u)
+	}
+	if c := u.Changed; c.PlayerName != "Bob" || !c.GetUpdatedAt().AsTime().Equal(now) || c.GetProfile().GetDisplayName() != "Bobby" {
+		t.Errorf("changed = %v", c)
+	}
+}
+
+// testV2Stream is a v2 StreamLeaderboard server stream forwarding what it is sent
+type testV2Stream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	updates chan *pbv2.LeaderboardUpdate
+}
+
+func (s *testV2Stream) Context() context.Context { return s.ctx }
+
+func (s *testV2Stream) Send(update *pbv2.LeaderboardUpdate) error {
+	s.updates <- update
+	return nil
+}
+
+func TestV2StreamLeaderboard(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer st.Close()
+	logger := zerolog.Nop()
+	svc := service.New(st, &logger, service.Options{})
+	if _, err := svc.SubmitScore(ctx, service.ScoreSubmission{PlayerName: "Alice", Score: 300}); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan notify.ScoreChange)
+	s := NewServer(svc, changes, &logger, 10, 10, 0, 0)
+	stream := &testV2Stream{ctx: ctx, updates: make(chan *pbv2.LeaderboardUpdate, 10)}
+	go s.V2().StreamLeaderboard(&pbv2.StreamLeaderboardRequest{Limit: 5}, stream)
+
+	snapshot := <-stream.updates
+	if snapshot.Kind != pbv2.LeaderboardUpdate_SNAPSHOT || len(snapshot.Snapshot) != 1 ||
+		snapshot.Snapshot[0].PlayerName != "Alice" || snapshot.Snapshot[0].UpdatedAt == nil {
+		t.Fatalf("snapshot = %v, want Alice with her update time", snapshot)
+	}
+	for s.SubscriberCount() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	changes <- notify.ScoreChange{LeaderboardID: "global", PlayerName: "Bob", Score: 400, RankScore: 400, Op: "update", ID: 7}
+	if u := <-stream.updates; u.Kind != pbv2.LeaderboardUpdate_UPSERT || u.Seq != 7 || u.Changed.GetPlayerName() != "Bob" {
+		t.Errorf("update = %v, want Bob's UPSERT with seq 7", u)
+	}
+}
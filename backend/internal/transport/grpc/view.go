@@ -0,0 +1,209 @@
+package grpc
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/service"
+)
+
+// topView tracks the top-N a single stream subscriber is looking at, so that
+// updates which cannot change what the client displays are not sent at all.
+//
+// The view is reset by every snapshot and then kept current from the updates
+// it accepts. Once full, its last entry is the threshold an upsert must beat.
+// When an entry is deleted the view is no longer full until the stream backfills
+// it with the entry moving up; if that fails every upsert is forwarded until it
+// fills up again: the view may then hold a lower threshold than the database,
+// which only ever costs extra updates, never missed ones.
+//
+// An upsert identical to the entry the view already shows is a duplicate and is
+// not sent: typically a change whose notification was still queued when a resync
+// snapshot read it from the database.
+//
+// A resumed stream sends no snapshot: its view is seeded from the database
+// without the client seeing it, so an upsert identical to a seeded entry may be
+// the very change the client has yet to receive, and is sent.
+type topView struct {
+	limit     int32
+	order     service.SortOrder // sort orders of the board, fixed for the subscription
+	secondary service.SortOrder
+	entries   []*pb.ScoreEntry // ordered best first, then achieved_at ASC, player_name ASC
+	unsent    map[string]bool  // players whose seeded entry the client was not sent
+
+	// trackRanks is set when the subscriber asked for RANK_CHANGED updates
+	trackRanks bool
+}
+
+func newTopView(limit int32, order, secondary service.SortOrder) *topView {
+	return &topView{limit: limit, order: order, secondary: secondary}
+}
+
+// reset replaces the view with a fresh snapshot
+func (v *topView) reset(limit int32, snapshot []*pb.ScoreEntry) {
+	v.limit = limit
+	v.entries = append(v.entries[:0], snapshot...)
+	v.unsent = nil
+}
+
+// seed replaces the view with the current top N of a resumed stream, which
+// the client is not sent
+func (v *topView) seed(limit int32, entries []*pb.ScoreEntry) {
+	v.reset(limit, entries)
+	v.unsent = make(map[string]bool, len(entries))
+	for _, e := range entries {
+		v.unsent[e.PlayerName] = true
+	}
+}
+
+// full reports whether the view holds limit entries
+func (v *topView) full() bool {
+	return int32(len(v.entries)) >= v.limit
+}
+
+// accept applies an update to the view and reports whether it should be sent
+func (v *topView) accept(update *pb.LeaderboardUpdate) bool {
+	if update.Kind == pb.LeaderboardUpdate_SNAPSHOT {
+		return true
+	}
+	changed := update.Changed
+	if changed == nil {
+		return true
+	}
+
+	previous := v.remove(changed.PlayerName)
+	visible := previous != nil
+	unsent := v.unsent[changed.PlayerName]
+	delete(v.unsent, changed.PlayerName)
+
+	switch update.Kind {
+	case pb.LeaderboardUpdate_UPSERT:
+		if visible && !unsent && sameResult(previous, changed) {
+			v.insert(previous)
+			return false
+		}
+		if !v.full() {
+			v.insert(changed)
+			return true
+		}
+		last := v.entries[len(v.entries)-1]
+		if ranksBefore(v.order, v.secondary, changed, last) {
+			// Entering (or moving within) the view pushes the last entry out
+			v.insert(changed)
+			v.entries = v.entries[:v.limit]
+			return true
+		}
+		// Only relevant if the player was visible: the client must drop them
+		return visible
+
+	case pb.LeaderboardUpdate_DELETE:
+		return visible
+
+	case pb.LeaderboardUpdate_TIER_CHANGE:
+		// Rank is unchanged: keep the entry where it was
+		if visible {
+			v.insert(previous)
+		}
+		return visible
+	}
+
+	return true
+}
+
+// insert places an entry at its ranked position
+func (v *topView) insert(entry *pb.ScoreEntry) {
+	pos := sort.Search(len(v.entries), func(i int) bool {
+		return ranksBefore(v.order, v.secondary, entry, v.entries[i])
+	})
+	v.entries = append(v.entries, nil)
+	copy(v.entries[pos+1:], v.entries[pos:])
+	v.entries[pos] = entry
+}
+
+// contains reports whether a player is in the view
+func (v *topView) contains(playerName string) bool {
+	for _, e := range v.entries {
+		if e.PlayerName == playerName {
+			return true
+		}
+	}
+	return false
+}
+
+// remove deletes a player's entry and returns it, or nil if it was not visible
+func (v *topView) remove(playerName string) *pb.ScoreEntry {
+	for i, e := range v.entries {
+		if e.PlayerName == playerName {
+			v.entries = append(v.entries[:i], v.entries[i+1:]...)
+			return e
+		}
+	}
+	return nil
+}
+
+// positions returns a copy of the entries of a view tracking ranks, to compare
+// with after an update, and nil otherwise
+func (v *topView) positions() []*pb.ScoreEntry {
+	if !v.trackRanks {
+		return nil
+	}
+	return slices.Clone(v.entries)
+}
+
+// rankChanges returns a RANK_CHANGED update for every player whose rank in the
+// view differs from their rank in before, the entries of the view before an
+// update: first by new rank, then the players who left the view by old rank
+func (v *topView) rankChanges(before []*pb.ScoreEntry) []*pb.LeaderboardUpdate {
+	oldRanks := make(map[string]int64, len(before))
+	for i, e := range before {
+		oldRanks[e.PlayerName] = int64(i + 1)
+	}
+
+	var changes []*pb.LeaderboardUpdate
+	for i, e := range v.entries {
+		oldRank, newRank := oldRanks[e.PlayerName], int64(i+1)
+		delete(oldRanks, e.PlayerName)
+		if oldRank != newRank {
+			changes = append(changes, rankChanged(e, oldRank, newRank))
+		}
+	}
+	for _, e := range before {
+		if oldRank, left := oldRanks[e.PlayerName]; left {
+			changes = append(changes, rankChanged(e, oldRank, 0))
+		}
+	}
+	return changes
+}
+
+func rankChanged(entry *pb.ScoreEntry, oldRank, newRank int64) *pb.LeaderboardUpdate {
+	return &pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_RANK_CHANGED, Changed: entry, OldRank: oldRank, NewRank: newRank}
+}
+
+// sameResult reports whether two entries of a player hold the same score achieved at the same time
+func sameResult(a, b *pb.ScoreEntry) bool {
+	if a.Score != b.Score || a.SecondaryScore != b.SecondaryScore {
+		return false
+	}
+	aAt, _ := time.Parse(time.RFC3339Nano, a.AchievedAt)
+	bAt, _ := time.Parse(time.RFC3339Nano, b.AchievedAt)
+	return aAt.Equal(bAt)
+}
+
+// ranksBefore reports whether a ranks strictly above b on a board sorted in order, then
+// secondary (better score first, then better secondary score, achieved_at ASC, player_name ASC)
+func ranksBefore(order, secondary service.SortOrder, a, b *pb.ScoreEntry) bool {
+	if a.Score != b.Score {
+		return order.RankScore(a.Score) > order.RankScore(b.Score)
+	}
+	if a.SecondaryScore != b.SecondaryScore {
+		return secondary.RankScore(a.SecondaryScore) > secondary.RankScore(b.SecondaryScore)
+	}
+	aAt, _ := time.Parse(time.RFC3339Nano, a.AchievedAt)
+	bAt, _ := time.Parse(time.RFC3339Nano, b.AchievedAt)
+	if !aAt.Equal(bAt) {
+		return aAt.Before(bAt)
+	}
+	return a.PlayerName < b.PlayerName
+}
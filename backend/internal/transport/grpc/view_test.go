@@ -0,0 +1,255 @@
+package grpc
+
+import (
+	"slices"
+	"testing"
+
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/service"
+)
+
+func entry(name string, score int64) *pb.ScoreEntry {
+	return &pb.ScoreEntry{PlayerName: name, Score: score}
+}
+
+func update(kind pb.LeaderboardUpdate_Kind, name string, score int64) *pb.LeaderboardUpdate {
+	return &pb.LeaderboardUpdate{Kind: kind, Changed: entry(name, score)}
+}
+
+func viewNames(v *topView) []string {
+	out := make([]string, len(v.entries))
+	for i, e := range v.entries {
+		out[i] = e.PlayerName
+	}
+	return out
+}
+
+func TestTopViewAccept(t *testing.T) {
+	tests := []struct {
+		name      string
+		order     service.SortOrder // empty means descending
+		limit     int32
+		snapshot  []*pb.ScoreEntry
+		update    *pb.LeaderboardUpdate
+		wantSent  bool
+		wantNames []string
+	}{
+		{
+			name:      "upsert below full view is filtered",
+			limit:     2,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300), entry("B", 200)},
+			update:    update(pb.LeaderboardUpdate_UPSERT, "C", 100),
+			wantSent:  false,
+			wantNames: []string{"A", "B"},
+		},
+		{
+			name:      "upsert entering full view evicts last",
+			limit:     2,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300), entry("B", 200)},
+			update:    update(pb.LeaderboardUpdate_UPSERT, "C", 250),
+			wantSent:  true,
+			wantNames: []string{"A", "C"},
+		},
+		{
+			name:      "tie with last loses on player name",
+			limit:     2,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300), entry("B", 200)},
+			update:    update(pb.LeaderboardUpdate_UPSERT, "C", 200),
+			wantSent:  false,
+			wantNames: []string{"A", "B"},
+		},
+		{
+			name:      "upsert into view that is not full",
+			limit:     5,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300)},
+			update:    update(pb.LeaderboardUpdate_UPSERT, "B", 1),
+			wantSent:  true,
+			wantNames: []string{"A", "B"},
+		},
+		{
+			name:      "visible player moving up",
+			limit:     2,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300), entry("B", 200)},
+			update:    update(pb.LeaderboardUpdate_UPSERT, "B", 400),
+			wantSent:  true,
+			wantNames: []string{"B", "A"},
+		},
+		{
+			name:      "upsert already in the snapshot is a duplicate",
+			limit:     5,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300), {PlayerName: "B", Score: 200, AchievedAt: "2025-01-15T10:29:41.123456Z"}},
+			update:    &pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_UPSERT, Changed: &pb.ScoreEntry{PlayerName: "B", Score: 200, AchievedAt: "2025-01-15T11:29:41.123456+01:00"}},
+			wantSent:  false,
+			wantNames: []string{"A", "B"},
+		},
+		{
+			name:      "same score achieved again later is sent",
+			limit:     5,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300), {PlayerName: "B", Score: 200, AchievedAt: "2025-01-15T10:29:41Z"}},
+			update:    &pb.LeaderboardUpdate{Kind: pb.LeaderboardUpdate_UPSERT, Changed: &pb.ScoreEntry{PlayerName: "B", Score: 200, AchievedAt: "2025-01-15T10:35:00Z"}},
+			wantSent:  true,
+			wantNames: []string{"A", "B"},
+		},
+		{
+			name:      "delete of visible player",
+			limit:     2,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300), entry("B", 200)},
+			update:    update(pb.LeaderboardUpdate_DELETE, "A", 300),
+			wantSent:  true,
+			wantNames: []string{"B"},
+		},
+		{
+			name:      "delete of hidden player is filtered",
+			limit:     2,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300), entry("B", 200)},
+			update:    update(pb.LeaderboardUpdate_DELETE, "C", 100),
+			wantSent:  false,
+			wantNames: []string{"A", "B"},
+		},
+		{
+			name:      "tier change of hidden player is filtered",
+			limit:     2,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300), entry("B", 200)},
+			update:    update(pb.LeaderboardUpdate_TIER_CHANGE, "C", 100),
+			wantSent:  false,
+			wantNames: []string{"A", "B"},
+		},
+		{
+			name:      "tier change of visible player",
+			limit:     2,
+			snapshot:  []*pb.ScoreEntry{entry("A", 300), entry("B", 200)},
+			update:    update(pb.LeaderboardUpdate_TIER_CHANGE, "B", 200),
+			wantSent:  true,
+			wantNames: []string{"A", "B"},
+		},
+		{
+			name:      "ascending: lower score enters full view",
+			order:     service.SortAscending,
+			limit:     2,
+			snapshot:  []*pb.ScoreEntry{entry("A", 100), entry("B", 200)},
+			update:    update(pb.LeaderboardUpdate_UPSERT, "C", 150),
+			wantSent:  true,
+			wantNames: []string{"A", "C"},
+		},
+		{
+			name:      "ascending: higher score is filtered",
+			order:     service.SortAscending,
+			limit:     2,
+			snapshot:  []*pb.ScoreEntry{entry("A", 100), entry("B", 200)},
+			update:    update(pb.LeaderboardUpdate_UPSERT, "C", 300),
+			wantSent:  false,
+			wantNames: []string{"A", "B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTopView(tt.limit, tt.order, "")
+			v.reset(tt.limit, tt.snapshot)
+
+			if got := v.accept(tt.update); got != tt.wantSent {
+				t.Errorf("accept() = %v, want %v", got, tt.wantSent)
+			}
+
+			got := viewNames(v)
+			if len(got) != len(tt.wantNames) {
+				t.Fatalf("entries = %v, want %v", got, tt.wantNames)
+			}
+			for i := range got {
+				if got[i] != tt.wantNames[i] {
+					t.Errorf("entries = %v, want %v", got, tt.wantNames)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestTopViewContains(t *testing.T) {
+	v := newTopView(2, "", "")
+	v.reset(2, []*pb.ScoreEntry{entry("A", 300), entry("B", 200)})
+	v.accept(update(pb.LeaderboardUpdate_DELETE, "A", 300))
+
+	if v.full() || v.contains("A") || !v.contains("B") {
+		t.Errorf("after delete: full = %v, entries = %v", v.full(), viewNames(v))
+	}
+}
+
+func TestTopViewSeed(t *testing.T) {
+	v := newTopView(2, "", "")
+	v.seed(2, []*pb.ScoreEntry{entry("A", 300), entry("B", 200)})
+
+	// The client of a resumed stream was never sent A's entry
+	if !v.accept(update(pb.LeaderboardUpdate_UPSERT, "A", 300)) {
+		t.Error("upsert identical to a seeded entry was filtered")
+	}
+	if v.accept(update(pb.LeaderboardUpdate_UPSERT, "A", 300)) {
+		t.Error("upsert identical to a sent entry was not filtered")
+	}
+
+	v.reset(2, []*pb.ScoreEntry{entry("A", 300), entry("B", 200)})
+	if v.accept(update(pb.LeaderboardUpdate_UPSERT, "B", 200)) {
+		t.Error("upsert identical to a snapshot entry was not filtered")
+	}
+}
+
+func TestTopViewRankChanges(t *testing.T) {
+	type move struct {
+		name             string
+		oldRank, newRank int64
+	}
+	tests := []struct {
+		name   string
+		update *pb.LeaderboardUpdate
+		want   []move
+	}{
+		{
+			name:   "entering pushes the last out",
+			update: update(pb.LeaderboardUpdate_UPSERT, "D", 250),
+			want:   []move{{"D", 0, 2}, {"B", 2, 3}, {"C", 3, 0}},
+		},
+		{
+			name:   "overtaking swaps two players",
+			update: update(pb.LeaderboardUpdate_UPSERT, "C", 350),
+			want:   []move{{"C", 3, 1}, {"A", 1, 2}, {"B", 2, 3}},
+		},
+		{
+			name:   "improving without overtaking moves no one",
+			update: update(pb.LeaderboardUpdate_UPSERT, "B", 250),
+		},
+		{
+			name:   "delete moves the players below up",
+			update: update(pb.LeaderboardUpdate_DELETE, "A", 300),
+			want:   []move{{"B", 2, 1}, {"C", 3, 2}, {"A", 1, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTopView(3, "", "")
+			v.trackRanks = true
+			v.reset(3, []*pb.ScoreEntry{entry("A", 300), entry("B", 200), entry("C", 100)})
+
+			before := v.positions()
+			v.accept(tt.update)
+			var got []move
+			for _, c := range v.rankChanges(before) {
+				if c.Kind != pb.LeaderboardUpdate_RANK_CHANGED {
+					t.Fatalf("kind = %v, want RANK_CHANGED", c.Kind)
+				}
+				got = append(got, move{c.Changed.PlayerName, c.OldRank, c.NewRank})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("rank changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopViewPositionsUntracked(t *testing.T) {
+	v := newTopView(2, "", "")
+	v.reset(2, []*pb.ScoreEntry{entry("A", 300)})
+	if p := v.positions(); p != nil {
+		t.Errorf("positions of a view not tracking ranks = %v, want nil", p)
+	}
+}
@@ -0,0 +1,28 @@
+package rest
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// EnableGzip compresses the responses of at least minSize bytes to clients
+// accepting gzip. Streamed responses (Server-Sent Events, NDJSON) are compressed
+// from their first flush whatever their size. Call it before serving.
+func (s *Server) EnableGzip(minSize int) {
+	s.echo.Use(middleware.GzipWithConfig(middleware.GzipConfig{
+		Skipper:   skipGzip,
+		MinLength: minSize,
+	}))
+}
+
+// skipGzip leaves alone the responses compressed on their own: metrics, which
+// the Prometheus handler compresses for clients accepting it, and zstd exports
+func skipGzip(c echo.Context) bool {
+	switch c.Path() {
+	case "/metrics":
+		return true
+	case "/scores/export":
+		return c.QueryParam("compression") == "zstd"
+	}
+	return false
+}
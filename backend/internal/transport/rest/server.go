@@ -19,69 +19,206 @@
 //	@produce					json
 //	@consumes					json
 //
+//	@securityDefinitions.apikey	AdminToken
+//	@in							header
+//	@name						Authorization
+//	@description				Admin token (ADMIN_TOKEN) as "Bearer <token>"
+//
 //	@tag.name					Health
 //	@tag.description			Health check endpoints
+//	@tag.name					Status
+//	@tag.description			Public status page data
 //	@tag.name					Scores
 //	@tag.description			Score management operations
+//	@tag.name					Leaderboard
+//	@tag.description			Read-only leaderboard statistics
+//	@tag.name					Players
+//	@tag.description			Player profile operations
+//	@tag.name					Leaderboards
+//	@tag.description			Leaderboard definitions
+//	@tag.name					Admin
+//	@tag.description			Operator statistics and maintenance
 package rest
 
 import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/rs/zerolog"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"github.com/yourorg/leaderboard/internal/events"
+	"github.com/yourorg/leaderboard/internal/health"
+	"github.com/yourorg/leaderboard/internal/maintenance"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/requestctx"
 	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/status"
+	"github.com/yourorg/leaderboard/internal/store"
+	"github.com/yourorg/leaderboard/internal/usage"
+	"github.com/yourorg/leaderboard/internal/webhook"
 )
 
 // Server implements the REST API using Echo
 type Server struct {
-	echo   *echo.Echo
-	svc    *service.Service
-	logger *zerolog.Logger
+	echo        *echo.Echo
+	svc         service.Leaderboard
+	checker     *health.Checker
+	status      *status.Reporter
+	maintenance *maintenance.Job
+	archive     *maintenance.ArchiveJob
+	streamer    Streamer
+	events      *events.Registry
+	logger      *zerolog.Logger
+
+	// limits are the default and maximum page sizes, replaced by SetPageLimits
+	limits atomic.Pointer[pageLimits]
+}
+
+// pageLimits are the default and maximum page sizes
+type pageLimits struct {
+	defaultLimit int32
+	maxLimit     int32
 }
 
 // NewServer creates a new REST server
-func NewServer(svc *service.Service, logger *zerolog.Logger) *Server {
+func NewServer(svc service.Leaderboard, checker *health.Checker, reporter *status.Reporter, job *maintenance.Job, logger *zerolog.Logger, defaultLimit, maxLimit int32) *Server {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
 
 	// Middleware
 	e.Use(middleware.Recover())
-	e.Use(middleware.RequestID())
+	e.Use(requestContextMiddleware())
+	e.Use(usageMiddleware(svc))
 	e.Use(middleware.CORS())
 	e.Use(loggingMiddleware(logger))
 
 	s := &Server{
-		echo:   e,
-		svc:    svc,
-		logger: logger,
+		echo:        e,
+		svc:         svc,
+		checker:     checker,
+		status:      reporter,
+		maintenance: job,
+		logger:      logger,
 	}
+	s.SetPageLimits(defaultLimit, maxLimit)
 
 	s.registerRoutes()
 	return s
 }
 
+// SetPageLimits replaces the default and maximum page sizes, e.g. on a configuration reload
+func (s *Server) SetPageLimits(defaultLimit, maxLimit int32) {
+	s.limits.Store(&pageLimits{defaultLimit: defaultLimit, maxLimit: maxLimit})
+}
+
+// SetArchiveJob sets the archival job reported by GET /admin/stats and previewed
+// by GET /admin/archive/preview, when it is scheduled. Call it before serving.
+func (s *Server) SetArchiveJob(job *maintenance.ArchiveJob) {
+	s.archive = job
+}
+
 func (s *Server) registerRoutes() {
 	// Swagger documentation
 	s.echo.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	// Health check
+	// Health checks (liveness vs readiness for Kubernetes probes)
 	s.echo.GET("/health", s.healthCheck)
+	s.echo.GET("/health/live", s.liveness)
+	s.echo.GET("/health/ready", s.readiness)
+
+	// Public status page data
+	s.echo.GET("/status", s.getStatus)
+
+	// Prometheus metrics
+	s.echo.GET("/metrics", echo.WrapHandler(metrics.Handler()))
 
 	// Score management endpoints
 	s.echo.POST("/scores", s.createOrUpdateScore)
+	s.echo.POST("/scores/batch", s.importScores)
+	s.echo.POST("/scores/import", s.importScoresStream)
+	s.echo.GET("/scores/export", s.exportScores)
+	s.echo.GET("/scores/stream", s.streamScores)
 	s.echo.PUT("/scores/:player_name", s.updateScore)
 	s.echo.DELETE("/scores/:player_name", s.deleteScore)
+	s.echo.POST("/scores/:player_name/restore", s.restoreScore, s.adminAuth)
+	s.echo.DELETE("/scores", s.resetLeaderboard, s.adminAuth)
+	s.echo.POST("/scores/restore", s.restoreLeaderboard, s.adminAuth)
+	s.echo.POST("/receipts/verify", s.verifyReceipt)
+
+	// Leaderboard statistics
+	s.echo.GET("/leaderboard/top", s.getTopScores, s.boardETag)
+	s.echo.GET("/leaderboard/rank/:player_name", s.getPlayerRank, s.boardETag)
+	s.echo.GET("/leaderboard/ranks", s.getPlayerRanks, s.boardETag)
+	s.echo.GET("/leaderboard/changes", s.getChangesSince)
+	s.echo.GET("/leaderboard/stream", s.streamLeaderboard)
+	s.echo.GET("/leaderboard/percentiles", s.getPercentileBuckets)
+	s.echo.GET("/leaderboard/count", s.getEntryCount)
+	s.echo.GET("/leaderboard/simulate", s.simulateRank, s.boardETag)
+	s.echo.GET("/stats/distribution", s.getScoreDistribution)
+
+	// Player profiles
+	s.echo.GET("/players/:player_name", s.getPlayerProfile)
+	s.echo.PUT("/players/:player_name", s.upsertPlayerProfile)
+	s.echo.GET("/players/:player_name/data", s.exportPlayerData, s.adminAuth)
+	s.echo.DELETE("/players/:player_name/data", s.erasePlayerData, s.adminAuth)
+	s.echo.POST("/players/:player_name/rename", s.renamePlayer, s.adminAuth)
+
+	// Leaderboard definitions
+	s.echo.GET("/leaderboards/daily/current", s.getCurrentDailyBoard)
+	s.echo.GET("/leaderboards/:leaderboard_id", s.getLeaderboard)
+	s.echo.PUT("/leaderboards/:leaderboard_id", s.upsertLeaderboard)
+
+	// Operator endpoints
+	admin := s.echo.Group("/admin")
+	admin.GET("/stats", s.getAdminStats, s.adminAuth)
+	admin.GET("/archive/preview", s.previewExpirations, s.adminAuth)
+	admin.POST("/events/replay", s.replayEvents, s.adminAuth)
+	admin.POST("/webhooks", s.createWebhook, s.adminAuth)
+	admin.GET("/webhooks", s.listWebhooks, s.adminAuth)
+	admin.GET("/webhooks/:id", s.getWebhook, s.adminAuth)
+	admin.PUT("/webhooks/:id", s.updateWebhook, s.adminAuth)
+	admin.DELETE("/webhooks/:id", s.deleteWebhook, s.adminAuth)
+	admin.GET("/webhooks/:id/deliveries", s.listWebhookDeliveries, s.adminAuth)
+	admin.GET("/keys/usage", s.listKeyUsage, s.adminAuth)
+	admin.GET("/keys/:id/usage", s.getKeyUsage, s.adminAuth)
+	admin.GET("/scores/deleted", s.getDeletedScores, s.adminAuth)
+	admin.GET("/bans", s.listBans, s.adminAuth)
+	admin.GET("/bans/:player_name", s.getBan, s.adminAuth)
+	admin.PUT("/bans/:player_name", s.banPlayer, s.adminAuth)
+	admin.DELETE("/bans/:player_name", s.unbanPlayer, s.adminAuth)
+	admin.GET("/subscribers", s.listSubscribers, s.adminAuth)
+	admin.POST("/subscribers/disconnect", s.disconnectSubscribers, s.adminAuth)
+	admin.GET("/stream-quotas", s.listStreamQuotas, s.adminAuth)
+	admin.PUT("/stream-quotas/:peer_address", s.setStreamQuota, s.adminAuth)
+	admin.DELETE("/stream-quotas/:peer_address", s.removeStreamQuota, s.adminAuth)
 }
 
-// Start starts the REST server
-func (s *Server) Start(addr string) error {
-	s.logger.Info().Str("addr", addr).Msg("starting REST server")
-	return s.echo.Start(addr)
+// Serve serves the REST API on ln until Shutdown. It may be called for several
+// listeners, which Shutdown closes together.
+func (s *Server) Serve(ln net.Listener) error {
+	s.echo.Server.Handler = s.echo
+	err := s.echo.Server.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
 // Shutdown gracefully shuts down the server
@@ -93,21 +230,539 @@ func (s *Server) Shutdown() error {
 
 // CreateScoreRequest represents the request body for creating or updating a score
 type CreateScoreRequest struct {
-	PlayerName string `json:"player_name" validate:"required,min=1,max=20" example:"Alice" minLength:"1" maxLength:"20"`
-	Score      int64  `json:"score" validate:"required,min=0" example:"1000" minimum:"0"`
+	LeaderboardID  string            `json:"leaderboard_id,omitempty" example:"level-42" maxLength:"64"` // Optional board, default "global"
+	PlayerName     string            `json:"player_name" validate:"required,min=1,max=20" example:"Alice" minLength:"1" maxLength:"20"`
+	Score          int64             `json:"score" validate:"required,min=0" example:"1000" minimum:"0"`
+	DeviceID       string            `json:"device_id,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015" maxLength:"128"` // Optional device fingerprint hash
+	AchievedAt     time.Time         `json:"achieved_at,omitempty" example:"2025-01-15T10:29:41Z"`                           // Optional RFC3339 completion time of the run
+	Metadata       map[string]string `json:"metadata,omitempty"`                                                             // Optional attributes of the run (at most 16 entries, 2048 bytes)
+	SecondaryScore int64             `json:"secondary_score,omitempty" example:"87" minimum:"0"`                             // Optional tiebreaker among equal scores
+	Country        string            `json:"country,omitempty" example:"FR" minLength:"2" maxLength:"2"`                     // Optional ISO 3166-1 alpha-2 country, resolved from the client address when empty
+	Platform       string            `json:"platform,omitempty" example:"mobile" enums:"pc,mobile,console,web"`              // Optional platform family, derived from X-Client-Platform when empty
+	Nonce          string            `json:"nonce,omitempty" example:"3f2a9c1e" maxLength:"64"`                              // Unique per attempt when submissions are signed
+	SignedAt       int64             `json:"signed_at,omitempty" example:"1736936981"`                                       // Unix seconds when the submission was signed
+	Signature      string            `json:"signature,omitempty" example:"5d41402abc4b2a76b9719d911017c592"`                 // Hex HMAC-SHA256 over player_name, score, nonce and signed_at
+}
+
+// ImportScoreEntry is one score of a bulk import
+type ImportScoreEntry struct {
+	LeaderboardID string    `json:"leaderboard_id,omitempty" example:"level-42" maxLength:"64"` // Optional board, default "global"
+	PlayerName    string    `json:"player_name" example:"Alice" minLength:"1" maxLength:"20"`
+	Score         int64     `json:"score" example:"1000" minimum:"0"`
+	AchievedAt    time.Time `json:"achieved_at,omitempty" example:"2024-06-01T18:00:00Z"` // Optional original completion time, default now
+}
+
+// ImportScoreResult is the outcome of one entry of a bulk import
+type ImportScoreResult struct {
+	Index   int            `json:"index" example:"0"` // Position of the entry in the request
+	Outcome string         `json:"outcome" example:"applied" enums:"applied,not_improved,invalid,failed"`
+	Reason  string         `json:"reason,omitempty" example:"achieved_at is in the future"`
+	Entry   *ScoreResponse `json:"entry,omitempty"` // Player's best after the entry (applied and not_improved)
+}
+
+// ImportScoresResponse reports the outcome of every entry of a bulk import
+type ImportScoresResponse struct {
+	Applied     int                 `json:"applied" example:"950"`
+	NotImproved int                 `json:"not_improved" example:"40"`
+	Invalid     int                 `json:"invalid" example:"8"`
+	Failed      int                 `json:"failed" example:"2"`
+	Results     []ImportScoreResult `json:"results"`
+}
+
+// ImportProgress is one line of the NDJSON response of a streamed import, written
+// after every committed chunk. Counts are cumulative over the request.
+type ImportProgress struct {
+	OffsetToken string            `json:"offset_token" example:"eyJsIjoxMDAwLCJiIjo2NzQyMX0"` // Resumes the import after the last committed line
+	Lines       int64             `json:"lines" example:"1000"`                               // Lines consumed from the start of the stream
+	Applied     int               `json:"applied" example:"950"`
+	NotImproved int               `json:"not_improved" example:"40"`
+	Invalid     int               `json:"invalid" example:"8"`
+	Failed      int               `json:"failed" example:"2"`
+	Errors      []ImportLineError `json:"errors,omitempty"`                         // Entries of the chunk that were not imported
+	Done        bool              `json:"done" example:"false"`                     // The whole stream was imported
+	Error       string            `json:"error,omitempty" example:"unexpected EOF"` // Why the import stopped early
+}
+
+// ImportLineError is an entry of a streamed import that was not imported
+type ImportLineError struct {
+	Line    int64  `json:"line" example:"42"` // 1-based line number in the whole stream
+	Outcome string `json:"outcome" example:"invalid" enums:"invalid,failed"`
+	Reason  string `json:"reason" example:"achieved_at is in the future"`
+}
+
+// maxImportLineBytes bounds a line of a streamed import
+const maxImportLineBytes = 64 * 1024
+
+// ExportEntry is one row of a leaderboard export
+type ExportEntry struct {
+	Rank           int64  `json:"rank" example:"1"`
+	LeaderboardID  string `json:"leaderboard_id" example:"global"`
+	PlayerName     string `json:"player_name" example:"Alice"`
+	Score          int64  `json:"score" example:"1000"`
+	AchievedAt     string `json:"achieved_at" example:"2025-01-15T10:29:41.123456Z"` // Full precision: breaks ties
+	UpdatedAt      string `json:"updated_at" example:"2025-01-15T10:30:00Z"`
+	SecondaryScore int64  `json:"secondary_score" example:"0"` // Absent from exports made before secondary scores
 }
 
+// exportCSVHeader is the first line of CSV exports, in ExportEntry order
+var exportCSVHeader = []string{"rank", "leaderboard_id", "player_name", "score", "achieved_at", "updated_at", "secondary_score"}
+
 // UpdateScoreRequest represents the request body for updating a score
 type UpdateScoreRequest struct {
-	Score int64 `json:"score" validate:"required,min=0" example:"1500" minimum:"0"`
+	Score          int64             `json:"score" validate:"required,min=0" example:"1500" minimum:"0"`
+	DeviceID       string            `json:"device_id,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015" maxLength:"128"` // Optional device fingerprint hash
+	AchievedAt     time.Time         `json:"achieved_at,omitempty" example:"2025-01-15T10:29:41Z"`                           // Optional RFC3339 completion time of the run
+	Metadata       map[string]string `json:"metadata,omitempty"`                                                             // Optional attributes of the run (at most 16 entries, 2048 bytes)
+	SecondaryScore int64             `json:"secondary_score,omitempty" example:"87" minimum:"0"`                             // Optional tiebreaker among equal scores
+	Country        string            `json:"country,omitempty" example:"FR" minLength:"2" maxLength:"2"`                     // Optional ISO 3166-1 alpha-2 country, resolved from the client address when empty
+	Platform       string            `json:"platform,omitempty" example:"mobile" enums:"pc,mobile,console,web"`              // Optional platform family, derived from X-Client-Platform when empty
+	Nonce          string            `json:"nonce,omitempty" example:"3f2a9c1e" maxLength:"64"`                              // Unique per attempt when submissions are signed
+	SignedAt       int64             `json:"signed_at,omitempty" example:"1736936981"`                                       // Unix seconds when the submission was signed
+	Signature      string            `json:"signature,omitempty" example:"5d41402abc4b2a76b9719d911017c592"`                 // Hex HMAC-SHA256 over player_name, score, nonce and signed_at
 }
 
 // ScoreResponse represents a score entry in the response
 type ScoreResponse struct {
+	LeaderboardID  string            `json:"leaderboard_id" example:"global"`
+	PlayerName     string            `json:"player_name" example:"Alice"`
+	Score          int64             `json:"score" example:"1000"`
+	UpdatedAt      string            `json:"updated_at" example:"2025-01-15T10:30:00Z"`
+	Applied        bool              `json:"applied,omitempty" example:"true"` // Only for create/update responses
+	Tier           string            `json:"tier,omitempty" example:"Gold"`    // Only when tiers are configured, on the default board
+	AchievedAt     string            `json:"achieved_at,omitempty" example:"2025-01-15T10:29:41Z"`
+	Profile        *ProfileResponse  `json:"profile,omitempty"`                      // Only when the player has a profile
+	Metadata       map[string]string `json:"metadata,omitempty"`                     // Attributes of the best score, if any
+	SecondaryScore int64             `json:"secondary_score,omitempty" example:"87"` // Tiebreaker of the best score, if any
+	Region         string            `json:"region,omitempty" example:"FR"`          // Country the best score was submitted from, if known
+	Platform       string            `json:"platform,omitempty" example:"mobile"`    // Platform family the best score was submitted from, if known
+	Receipt        *ReceiptResponse  `json:"receipt,omitempty"`                      // Only for submissions, when receipts are enabled
+	Rank           int64             `json:"rank,omitempty" example:"12"`            // Only for submissions, when SUBMIT_RANK is on
+	RankDelta      int64             `json:"rank_delta,omitempty" example:"3"`       // Places gained by the submission
+	Accepted       bool              `json:"accepted,omitempty" example:"false"`     // Only in async submit mode: queued, not written yet
+}
+
+// TopScoreEntry is a leaderboard entry of GET /leaderboard/top. Fields left out by
+// the fields parameter are omitted.
+type TopScoreEntry struct {
+	LeaderboardID  string            `json:"leaderboard_id,omitempty" example:"global"`
+	PlayerName     string            `json:"player_name,omitempty" example:"Alice"`
+	Score          *int64            `json:"score,omitempty" example:"1000"`
+	UpdatedAt      string            `json:"updated_at,omitempty" example:"2025-01-15T10:30:00Z"`
+	Tier           string            `json:"tier,omitempty" example:"Gold"` // Only when tiers are configured, on the default board
+	AchievedAt     string            `json:"achieved_at,omitempty" example:"2025-01-15T10:29:41.123456Z"`
+	Profile        *ProfileResponse  `json:"profile,omitempty"`                      // Only when the player has a profile
+	Metadata       map[string]string `json:"metadata,omitempty"`                     // Attributes of the best score, if any
+	SecondaryScore int64             `json:"secondary_score,omitempty" example:"87"` // Tiebreaker of the best score, if any
+	Region         string            `json:"region,omitempty" example:"FR"`          // Country the best score was submitted from, if known
+	Platform       string            `json:"platform,omitempty" example:"mobile"`    // Platform family the best score was submitted from, if known
+}
+
+// DeletedScoreResponse is a deleted score entry that an admin can restore
+type DeletedScoreResponse struct {
+	LeaderboardID string `json:"leaderboard_id" example:"global"`
+	PlayerName    string `json:"player_name" example:"Alice"`
+	Score         int64  `json:"score" example:"1000"`
+	AchievedAt    string `json:"achieved_at" example:"2025-01-15T10:29:41.123456Z"`
+	UpdatedAt     string `json:"updated_at" example:"2025-01-15T10:30:00Z"`
+	DeletedAt     string `json:"deleted_at" example:"2025-01-16T08:00:00Z"`
+}
+
+// TopScoresResponse is a page of the leaderboard
+type TopScoresResponse struct {
+	Entries        []TopScoreEntry `json:"entries"`
+	NextPageToken  string          `json:"next_page_token,omitempty"` // Set when the page is full
+	RankingVariant string          `json:"ranking_variant" example:"control"`
+	TotalCount     int64           `json:"total_count" example:"1200"` // Entries of the board or segment listed
+}
+
+// ReceiptResponse is the signed receipt of a score submission. Store it as is:
+// any change to a field invalidates the signature.
+type ReceiptResponse struct {
+	LeaderboardID string `json:"leaderboard_id" example:"global"`
+	PlayerName    string `json:"player_name" example:"Alice"`
+	Score         int64  `json:"score" example:"1000"` // Score submitted, not the player's best
+	IssuedAt      string `json:"issued_at" example:"2025-01-15T10:30:00.123456789Z"`
+	Applied       bool   `json:"applied" example:"true"`
+	KeyID         string `json:"key_id" example:"2025-01"`
+	Signature     string `json:"signature" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+// VerifyReceiptResponse is the outcome of a receipt verification
+type VerifyReceiptResponse struct {
+	Valid      bool   `json:"valid" example:"true"`
+	Result     string `json:"result" example:"valid" enums:"valid,unknown_key,signature_mismatch"`
+	CurrentKey bool   `json:"current_key" example:"true"` // Signed with the current signing key
+}
+
+// UpsertProfileRequest represents the request body for creating or replacing a player profile
+type UpsertProfileRequest struct {
+	DisplayName string `json:"display_name" example:"Alice the Great" maxLength:"32"`                    // Empty = show player_name
+	CountryCode string `json:"country_code" example:"FR" minLength:"2" maxLength:"2"`                    // ISO 3166-1 alpha-2
+	AvatarURL   string `json:"avatar_url" example:"https://cdn.example.com/a/alice.png" maxLength:"512"` // Absolute http(s) URL
+}
+
+// ProfileResponse represents a player profile
+type ProfileResponse struct {
+	PlayerName  string `json:"player_name" example:"Alice"`
+	DisplayName string `json:"display_name,omitempty" example:"Alice the Great"`
+	CountryCode string `json:"country_code,omitempty" example:"FR"`
+	AvatarURL   string `json:"avatar_url,omitempty" example:"https://cdn.example.com/a/alice.png"`
+	CreatedAt   string `json:"created_at,omitempty" example:"2025-01-15T10:30:00Z"` // empty when the profile comes from the identity service only
+	UpdatedAt   string `json:"updated_at,omitempty" example:"2025-01-15T10:30:00Z"`
+}
+
+// PlayerDataResponse is everything stored about a player, for data subject access requests
+type PlayerDataResponse struct {
+	PlayerName        string                    `json:"player_name" example:"Alice"`
+	ExportedAt        string                    `json:"exported_at" example:"2025-01-15T10:30:00Z"`
+	Scores            []PlayerDataScore         `json:"scores"`            // Every board, deleted entries included
+	ArchivedScores    []PlayerDataArchivedScore `json:"archived_scores"`   // Entries moved off the boards by the archival job
+	Profile           *ProfileResponse          `json:"profile,omitempty"` // Absent without a stored profile
+	Devices           []PlayerDataDevice        `json:"devices"`
+	DeviceSubmissions []PlayerDataSubmission    `json:"device_submissions"`
+	SnapshotEntries   []PlayerDataSnapshotEntry `json:"snapshot_entries"` // Copies of the player's entries taken by board resets
+	ScoreChanges      []PlayerDataScoreChange   `json:"score_changes"`    // History of the player's scores still in the change outbox
+	WebhookDeliveries []PlayerDataDelivery      `json:"webhook_deliveries"`
+	Erasures          []PlayerErasureResponse   `json:"erasures"` // Earlier erasures of the player name
+}
+
+// PlayerDataScore is a score entry of a player data export
+type PlayerDataScore struct {
+	LeaderboardID    string            `json:"leaderboard_id" example:"global"`
+	Score            int64             `json:"score" example:"1000"`
+	SecondaryScore   int64             `json:"secondary_score,omitempty" example:"87"`
+	Region           string            `json:"region,omitempty" example:"FR"`       // Country the score was submitted from, if known
+	Platform         string            `json:"platform,omitempty" example:"mobile"` // Platform family the score was submitted from, if known
+	AchievedAt       string            `json:"achieved_at" example:"2025-01-15T10:29:41.123456Z"`
+	ClientAchievedAt string            `json:"client_achieved_at,omitempty" example:"2025-01-15T10:29:40Z"` // Completion time reported by the client, if any
+	UpdatedAt        string            `json:"updated_at" example:"2025-01-15T10:30:00Z"`
+	DeletedAt        string            `json:"deleted_at,omitempty" example:"2025-01-16T08:00:00Z"` // Set on deleted entries
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// PlayerDataArchivedScore is a score entry moved to the archive by the archival job
+type PlayerDataArchivedScore struct {
+	PlayerDataScore
+	Reason     string `json:"reason" example:"retention" enums:"retention,score_floor"`
+	ArchivedAt string `json:"archived_at" example:"2025-06-01T03:00:00Z"`
+}
+
+// PlayerDataDevice is a device fingerprint the player submitted from
+type PlayerDataDevice struct {
+	DeviceHash  string `json:"device_hash" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	FirstSeenAt string `json:"first_seen_at" example:"2025-01-15T10:30:00Z"`
+}
+
+// PlayerDataSubmission is a submission counted by the per-device limits
+type PlayerDataSubmission struct {
+	DeviceHash  string `json:"device_hash" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	SubmittedAt string `json:"submitted_at" example:"2025-01-15T10:30:00Z"`
+}
+
+// PlayerDataSnapshotEntry is the player's entry in a board snapshot
+type PlayerDataSnapshotEntry struct {
+	SnapshotID     int64  `json:"snapshot_id" example:"12"`
+	Score          int64  `json:"score" example:"1000"`
+	SecondaryScore int64  `json:"secondary_score,omitempty" example:"87"`
+	Region         string `json:"region,omitempty" example:"FR"`
+	Platform       string `json:"platform,omitempty" example:"mobile"`
+	AchievedAt     string `json:"achieved_at" example:"2025-01-15T10:29:41.123456Z"`
+	UpdatedAt      string `json:"updated_at" example:"2025-01-15T10:30:00Z"`
+}
+
+// PlayerDataScoreChange is a change of one of the player's scores
+type PlayerDataScoreChange struct {
+	Seq            int64             `json:"seq" example:"42"`
+	LeaderboardID  string            `json:"leaderboard_id" example:"global"`
+	Op             string            `json:"op" example:"update" enums:"insert,update,delete"`
+	Score          int64             `json:"score" example:"1000"`
+	SecondaryScore int64             `json:"secondary_score,omitempty" example:"87"`
+	Region         string            `json:"region,omitempty" example:"FR"`
+	Platform       string            `json:"platform,omitempty" example:"mobile"`
+	AchievedAt     string            `json:"achieved_at" example:"2025-01-15T10:29:41.123456Z"`
+	ChangedAt      string            `json:"changed_at" example:"2025-01-15T10:30:00Z"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// PlayerDataDelivery is a webhook delivery whose event names the player
+type PlayerDataDelivery struct {
+	WebhookDeliveryResponse
+	WebhookID int64           `json:"webhook_id" example:"3"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"` // Event as sent to the receiver
+}
+
+// PlayerErasureResponse is the audit record of a player data erasure
+type PlayerErasureResponse struct {
+	ID            int64  `json:"id" example:"7"`
+	PlayerHash    string `json:"player_hash" example:"3bc51062973c458d5a6f2d8d64a023246354ad7e064b1e4e009ec8a0699a3043"` // Hex SHA-256 of the player name
+	RequestID     string `json:"request_id,omitempty" example:"01J9Z8K3M2N4P5Q6R7S8T9V0W1"`                              // Request that erased the data
+	DeletedScores int64  `json:"deleted_scores" example:"3"`
+	DeletedRows   int64  `json:"deleted_rows" example:"41"` // Every table, scores included
+	ErasedAt      string `json:"erased_at" example:"2025-01-16T08:00:00Z"`
+}
+
+// ErasePlayerDataResponse reports a player data erasure
+type ErasePlayerDataResponse struct {
+	Erasure        PlayerErasureResponse `json:"erasure"`
+	LeaderboardIDs []string              `json:"leaderboard_ids"` // Boards the player was ranked on, resynced
+}
+
+// RenamePlayerRequest represents the request body of POST /players/{player_name}/rename
+type RenamePlayerRequest struct {
+	NewPlayerName string `json:"new_player_name" example:"Carol" minLength:"1" maxLength:"20"`
+	Merge         bool   `json:"merge" example:"false"` // Merge into new_player_name if it already has scores or a profile
+}
+
+// RenamePlayerResponse reports a player rename
+type RenamePlayerResponse struct {
+	PlayerName           string           `json:"player_name" example:"Carol"` // The new name
+	PreviousPlayerName   string           `json:"previous_player_name" example:"Alice"`
+	Entries              []ScoreResponse  `json:"entries"`                // Best of the new name on each board the player was on
+	MergedLeaderboardIDs []string         `json:"merged_leaderboard_ids"` // Boards where the new name already had a score
+	Profile              *ProfileResponse `json:"profile,omitempty"`      // Profile of the new name, if any
+}
+
+// BanRequest represents the request body of PUT /admin/bans/{player_name}
+type BanRequest struct {
+	Reason string `json:"reason" example:"cheating" maxLength:"256"`
+	Purge  bool   `json:"purge" example:"true"` // Delete the player's scores on every board
+}
+
+// BanResponse represents a banned player
+type BanResponse struct {
+	PlayerName           string   `json:"player_name" example:"Mallory"`
+	Reason               string   `json:"reason" example:"cheating"`
+	BannedAt             string   `json:"banned_at" example:"2025-01-15T10:30:00Z"`
+	PurgedLeaderboardIDs []string `json:"purged_leaderboard_ids,omitempty"` // Boards whose score of the player the ban deleted
+}
+
+// UpsertLeaderboardRequest represents the request body for creating or updating a leaderboard definition
+type UpsertLeaderboardRequest struct {
+	SortOrder          string `json:"sort_order" example:"asc" enums:"desc,asc"`            // Empty = desc
+	SecondarySortOrder string `json:"secondary_sort_order" example:"desc" enums:"desc,asc"` // Order of the secondary scores breaking ties, empty = desc
+}
+
+// LeaderboardResponse represents a leaderboard definition
+type LeaderboardResponse struct {
+	LeaderboardID      string `json:"leaderboard_id" example:"level-42"`
+	SortOrder          string `json:"sort_order" example:"asc" enums:"desc,asc"`
+	SecondarySortOrder string `json:"secondary_sort_order" example:"desc" enums:"desc,asc"`
+	CreatedAt          string `json:"created_at,omitempty" example:"2025-01-15T10:30:00Z"` // Empty for boards without a definition
+	UpdatedAt          string `json:"updated_at,omitempty" example:"2025-01-15T10:30:00Z"`
+}
+
+// DailyBoardResponse describes today's daily challenge board
+type DailyBoardResponse struct {
+	LeaderboardID         string `json:"leaderboard_id" example:"daily-2025-01-15"`
+	Date                  string `json:"date" example:"2025-01-15"` // Day in the daily timezone
+	StartsAt              string `json:"starts_at" example:"2025-01-15T00:00:00Z"`
+	EndsAt                string `json:"ends_at" example:"2025-01-16T00:00:00Z"` // When the next board opens
+	Seed                  int64  `json:"seed" example:"4821937510023847"`        // Deterministic per day
+	SortOrder             string `json:"sort_order" example:"desc" enums:"desc,asc"`
+	PreviousLeaderboardID string `json:"previous_leaderboard_id" example:"daily-2025-01-14"` // Yesterday's board, frozen
+}
+
+// PercentileBucketResponse is the score required to be in the top X% of players
+// (a minimum on descending boards, a maximum on ascending ones)
+type PercentileBucketResponse struct {
+	TopPercent float64 `json:"top_percent" example:"1"`
+	MinScore   int64   `json:"min_score" example:"9850"`
+}
+
+// PercentilesResponse represents the configured percentile buckets
+type PercentilesResponse struct {
+	Buckets      []PercentileBucketResponse `json:"buckets"`
+	SortOrder    string                     `json:"sort_order" example:"desc" enums:"desc,asc"`
+	TotalPlayers int64                      `json:"total_players" example:"1200"`
+	ComputedAt   string                     `json:"computed_at" example:"2025-01-15T10:30:00Z"`
+}
+
+// EntryCountResponse is the number of entries of a board or segment
+type EntryCountResponse struct {
+	LeaderboardID string `json:"leaderboard_id" example:"global"`
+	TotalCount    int64  `json:"total_count" example:"1200"`
+}
+
+// DistributionResponse represents a histogram of the scores of a board
+type DistributionResponse struct {
+	LeaderboardID string                       `json:"leaderboard_id" example:"global"`
+	Buckets       []DistributionBucketResponse `json:"buckets"`
+	BucketWidth   int64                        `json:"bucket_width" example:"50"`
+	MinScore      int64                        `json:"min_score" example:"0"`
+	MaxScore      int64                        `json:"max_score" example:"998"`
+	TotalPlayers  int64                        `json:"total_players" example:"1200"`
+	ComputedAt    string                       `json:"computed_at" example:"2025-01-15T10:30:00Z"`
+}
+
+// DistributionBucketResponse represents the players whose score is in [min_score, max_score]
+type DistributionBucketResponse struct {
+	MinScore int64 `json:"min_score" example:"0"`
+	MaxScore int64 `json:"max_score" example:"49"`
+	Players  int64 `json:"players" example:"85"`
+}
+
+// PlayerRankResponse represents a player's rank and entry on a board
+type PlayerRankResponse struct {
+	Rank           int64         `json:"rank" example:"3"` // 1-based
+	Entry          TopScoreEntry `json:"entry"`
+	RankingVariant string        `json:"ranking_variant" example:"control"`
+}
+
+// PlayerRankEntry is the rank and best score of one player of GET /leaderboard/ranks
+type PlayerRankEntry struct {
+	Rank  int64         `json:"rank" example:"3"` // 1-based
+	Entry TopScoreEntry `json:"entry"`
+}
+
+// PlayerRanksResponse represents the ranks of several players
+type PlayerRanksResponse struct {
+	Ranks    []PlayerRankEntry `json:"ranks"`                        // Players with a score, best rank first
+	NotFound []string          `json:"not_found" example:"Newcomer"` // Requested players without a score, in request order
+}
+
+// ChangesResponse represents the changes of a board since a checkpoint
+type ChangesResponse struct {
+	Changes []StreamEvent `json:"changes"`                 // UPSERT and DELETE events with their seq, the last change of each player, in seq order
+	NextSeq int64         `json:"next_seq" example:"1234"` // since_seq of the next poll
+	HasMore bool          `json:"has_more"`                // More changes follow next_seq: poll again without waiting
+	Resync  bool          `json:"resync"`                  // Changes since the checkpoint are lost: reload the board, then poll from next_seq
+}
+
+// SimulateRankResponse represents the rank a hypothetical score would achieve
+type SimulateRankResponse struct {
+	Score            int64  `json:"score" example:"1500"`
+	Rank             int64  `json:"rank" example:"12"`
+	TotalPlayers     int64  `json:"total_players" example:"1200"`
+	PointsToNextRank int64  `json:"points_to_next_rank" example:"40"`
+	Tier             string `json:"tier,omitempty" example:"Gold"`
+}
+
+// AdminStatsResponse represents leaderboard and database statistics for operators
+type AdminStatsResponse struct {
+	Players       int64  `json:"players" example:"1200"`
+	LastUpdatedAt string `json:"last_updated_at,omitempty" example:"2025-01-15T10:30:00Z"`
+	// Maintenance is the report of the last maintenance run, null before the first one
+	Maintenance *maintenance.Report `json:"maintenance"`
+	// Archive is the report of the last archival run, null before the first one or when archival is not scheduled
+	Archive *maintenance.ArchiveReport `json:"archive"`
+}
+
+// ResetLeaderboardResponse is the preview or the outcome of a leaderboard reset
+type ResetLeaderboardResponse struct {
+	LeaderboardID     string `json:"leaderboard_id" example:"level-42"`
+	Completed         bool   `json:"completed" example:"false"`                                                // True once the scores were deleted
+	Entries           int64  `json:"entries,omitempty" example:"1200"`                                         // Preview: scores the board holds now
+	ConfirmationToken string `json:"confirmation_token,omitempty" example:"1736937101.5d41402abc4b2a76b9719d"` // Preview: pass as confirm to reset
+	ExpiresAt         string `json:"expires_at,omitempty" example:"2025-01-15T10:31:41Z"`                      // Preview: when the token expires
+	Deleted           int64  `json:"deleted,omitempty" example:"1200"`                                         // Outcome: scores deleted
+	SnapshotID        int64  `json:"snapshot_id,omitempty" example:"7"`                                        // Outcome: snapshot of the board, if requested
+}
+
+// RestoreLeaderboardResponse is the preview or outcome of a board restore
+type RestoreLeaderboardResponse struct {
+	LeaderboardID     string                  `json:"leaderboard_id" example:"level-42"`
+	Completed         bool                    `json:"completed" example:"false"`                                                // True once the board was replaced
+	Entries           int64                   `json:"entries" example:"1200"`                                                   // Entries of the restored state
+	Added             int64                   `json:"added" example:"3"`                                                        // Players only in the restored state
+	Removed           int64                   `json:"removed" example:"12"`                                                     // Players only on the board, deleted by the restore
+	Changed           int64                   `json:"changed" example:"840"`                                                    // Players whose score changes
+	Unchanged         int64                   `json:"unchanged" example:"357"`                                                  // Players whose score stays the same
+	Samples           []RestoreChangeResponse `json:"samples"`                                                                  // First changes by player name (at most 50)
+	ConfirmationToken string                  `json:"confirmation_token,omitempty" example:"1736937101.5d41402abc4b2a76b9719d"` // Preview: pass as confirm, with the same body
+	ExpiresAt         string                  `json:"expires_at,omitempty" example:"2025-01-15T10:31:41Z"`                      // Preview: when the token expires
+	Deleted           int64                   `json:"deleted,omitempty" example:"1209"`                                         // Outcome: scores replaced
+	Restored          int64                   `json:"restored,omitempty" example:"1200"`                                        // Outcome: scores written
+	SnapshotID        int64                   `json:"snapshot_id,omitempty" example:"8"`                                        // Outcome: snapshot of the replaced scores, if requested
+}
+
+// RestoreChangeResponse is the score of a player before and after a restore
+type RestoreChangeResponse struct {
 	PlayerName string `json:"player_name" example:"Alice"`
-	Score      int64  `json:"score" example:"1000"`
-	UpdatedAt  string `json:"updated_at" example:"2025-01-15T10:30:00Z"`
-	Applied    bool   `json:"applied,omitempty" example:"true"` // Only for create/update responses
+	Before     *int64 `json:"before" example:"999999"` // null when the restore adds the player
+	After      *int64 `json:"after" example:"1500"`    // null when the restore removes the player
+}
+
+// ReplayEventsRequest selects the outbox range of an event replay
+type ReplayEventsRequest struct {
+	FromSeq int64 `json:"from_seq" example:"1200"` // First outbox sequence to replay
+	ToSeq   int64 `json:"to_seq" example:"1500"`   // Last outbox sequence to replay
+	DryRun  bool  `json:"dry_run" example:"true"`  // Report what would be replayed without dispatching it
+}
+
+// ReplayEventsResponse reports the events of an outbox replay
+type ReplayEventsResponse struct {
+	FromSeq   int64          `json:"from_seq" example:"1200"`
+	ToSeq     int64          `json:"to_seq" example:"1500"`
+	DryRun    bool           `json:"dry_run" example:"true"`
+	Events    int            `json:"events" example:"298"`               // Events found in the range
+	FirstSeq  int64          `json:"first_seq,omitempty" example:"1201"` // Sequence of the first event found
+	LastSeq   int64          `json:"last_seq,omitempty" example:"1500"`  // Sequence of the last event found
+	Ops       map[string]int `json:"ops"`                                // Events found per op (insert, update, delete, resync)
+	Boards    []string       `json:"boards"`                             // Boards resynced after the replay
+	OldestSeq int64          `json:"oldest_seq" example:"1201"`          // Oldest event still in the outbox; older ones were pruned
+	LatestSeq int64          `json:"latest_seq" example:"1893"`          // Latest event in the outbox
+}
+
+// KeyUsageResponse is the usage of an API key over the usage window
+type KeyUsageResponse struct {
+	KeyID         string                `json:"key_id" example:"k_9f86d081884c7d65"`
+	WindowSeconds int64                 `json:"window_seconds" example:"3600"`
+	Requests      int64                 `json:"requests" example:"18240"`
+	Errors        int64                 `json:"errors" example:"312"`
+	ErrorRate     float64               `json:"error_rate" example:"0.0171"`
+	LastSeen      string                `json:"last_seen" example:"2025-01-15T10:29:58Z"`
+	Methods       []MethodUsageResponse `json:"methods"`          // Most requested methods first (top 10)
+	Series        []UsagePointResponse  `json:"series,omitempty"` // Requests per bucket of the window, oldest first
+}
+
+// MethodUsageResponse is the usage of an API key for one RPC or REST route
+type MethodUsageResponse struct {
+	Method    string  `json:"method" example:"SubmitScore"` // RPC name, or "<HTTP method> <route>"
+	Requests  int64   `json:"requests" example:"15100"`
+	Errors    int64   `json:"errors" example:"290"`
+	ErrorRate float64 `json:"error_rate" example:"0.0192"`
+}
+
+// UsagePointResponse is the usage of an API key over one bucket of the window
+type UsagePointResponse struct {
+	Start    string `json:"start" example:"2025-01-15T10:29:00Z"`
+	Requests int64  `json:"requests" example:"304"`
+	Errors   int64  `json:"errors" example:"5"`
+}
+
+// WebhookRequest is the settings of a webhook
+type WebhookRequest struct {
+	URL           string   `json:"url" example:"https://hooks.example.com/leaderboard"`          // http or https endpoint receiving the events
+	Events        []string `json:"events" example:"score.high_score,leaderboard.leader_changed"` // score.high_score, leaderboard.leader_changed, score.deleted, lifecycle.<type>
+	LeaderboardID string   `json:"leaderboard_id,omitempty" example:"level-42"`                  // Only events of this board; every board when empty
+	Enabled       *bool    `json:"enabled,omitempty" example:"true"`                             // Default true
+}
+
+// WebhookResponse is a registered webhook
+type WebhookResponse struct {
+	ID            int64    `json:"id" example:"3"`
+	URL           string   `json:"url" example:"https://hooks.example.com/leaderboard"`
+	Events        []string `json:"events" example:"leaderboard.leader_changed,score.high_score"`
+	LeaderboardID string   `json:"leaderboard_id,omitempty" example:"level-42"`
+	Enabled       bool     `json:"enabled" example:"true"`
+	Secret        string   `json:"secret,omitempty" example:"whsec_5d41402abc4b2a76b9719d911017c592"` // Signing secret, only returned on creation
+	CreatedAt     string   `json:"created_at" example:"2025-01-15T10:30:00Z"`
+	UpdatedAt     string   `json:"updated_at" example:"2025-01-15T10:30:00Z"`
+}
+
+// WebhookDeliveryResponse is an entry of the delivery log of a webhook
+type WebhookDeliveryResponse struct {
+	ID             int64  `json:"id" example:"981"`
+	EventType      string `json:"event_type" example:"score.high_score"`
+	ChangeID       int64  `json:"change_id" example:"1500"` // Outbox sequence of the score change
+	Replayed       bool   `json:"replayed,omitempty" example:"false"`
+	Status         string `json:"status" example:"pending"` // pending, delivered or failed
+	Attempts       int32  `json:"attempts" example:"2"`
+	NextAttemptAt  string `json:"next_attempt_at,omitempty" example:"2025-01-15T10:31:20Z"` // Pending: when the next attempt is due
+	LastStatusCode int32  `json:"last_status_code,omitempty" example:"503"`                 // HTTP status of the last attempt
+	LastError      string `json:"last_error,omitempty" example:"receiver answered 503 Service Unavailable"`
+	CreatedAt      string `json:"created_at" example:"2025-01-15T10:30:00Z"`
+	UpdatedAt      string `json:"updated_at" example:"2025-01-15T10:31:00Z"`
 }
 
 // ErrorResponse represents an error response
@@ -121,17 +776,61 @@ type ErrorResponse struct {
 // healthCheck godoc
 //
 //	@Summary		Health check
-//	@Description	Check if the API server is running
+//	@Description	Report database, notify listener and stream subscriber status.
+//	@Description	Always 200 while the process is up; see /health/ready for a probe that fails.
 //	@Tags			Health
 //	@Produce		json
-//	@Success		200	{object}	map[string]string	"API is healthy"
+//	@Success		200	{object}	health.Report	"Health report"
 //	@Router			/health [get]
 func (s *Server) healthCheck(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.checker.Check(c.Request().Context()))
+}
+
+// liveness godoc
+//
+//	@Summary		Liveness probe
+//	@Description	Check if the API server is running. Does not check dependencies.
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	map[string]string	"API is alive"
+//	@Router			/health/live [get]
+func (s *Server) liveness(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{
-		"status": "ok",
+		"status": health.StatusOK,
 	})
 }
 
+// readiness godoc
+//
+//	@Summary		Readiness probe
+//	@Description	Check if the server can serve traffic: database reachable and notify listener connected
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	health.Report	"Ready"
+//	@Failure		503	{object}	health.Report	"Not ready"
+//	@Router			/health/ready [get]
+func (s *Server) readiness(c echo.Context) error {
+	report := s.checker.Check(c.Request().Context())
+	if !report.Ready() {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// getStatus godoc
+//
+//	@Summary		Public status
+//	@Description	Uptime, leaderboard sizes, last update time and degraded-mode flags for a public status page.
+//	@Description	The JSON shape is stable (fields are only added) and results are cached for a few seconds.
+//	@Description	Always 200: the outage state is reported in the body.
+//	@Tags			Status
+//	@Produce		json
+//	@Success		200	{object}	status.Status	"Current status"
+//	@Router			/status [get]
+func (s *Server) getStatus(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.status.Report(c.Request().Context()))
+}
+
 // createOrUpdateScore godoc
 //
 //	@Summary		Create or update a player score
@@ -142,8 +841,14 @@ func (s *Server) healthCheck(c echo.Context) error {
 //	@Produce		json
 //	@Param			request	body		CreateScoreRequest	true	"Player name and score"
 //	@Success		200		{object}	ScoreResponse		"Score created or updated"
+//	@Success		202		{object}	ScoreResponse		"Score queued (SUBMIT_MODE=async), echoed as submitted"
 //	@Failure		400		{object}	ErrorResponse		"Validation error"
+//	@Failure		401		{object}	ErrorResponse		"Missing or invalid signature"
+//	@Failure		403		{object}	ErrorResponse		"Player banned"
+//	@Failure		409		{object}	ErrorResponse		"Daily board closed"
+//	@Failure		429		{object}	ErrorResponse		"Device limit exceeded"
 //	@Failure		500		{object}	ErrorResponse		"Internal server error"
+//	@Failure		503		{object}	ErrorResponse		"Overloaded, retry after the Retry-After delay"
 //	@Router			/scores [post]
 func (s *Server) createOrUpdateScore(c echo.Context) error {
 	var req CreateScoreRequest
@@ -168,17 +873,449 @@ func (s *Server) createOrUpdateScore(c echo.Context) error {
 		})
 	}
 
-	result, err := s.svc.SubmitScore(c.Request().Context(), req.PlayerName, req.Score)
+	result, err := s.svc.SubmitScore(c.Request().Context(), service.ScoreSubmission{
+		LeaderboardID:  req.LeaderboardID,
+		PlayerName:     req.PlayerName,
+		Score:          req.Score,
+		DeviceID:       req.DeviceID,
+		AchievedAt:     req.AchievedAt,
+		Metadata:       req.Metadata,
+		SecondaryScore: req.SecondaryScore,
+		Country:        req.Country,
+		Platform:       req.Platform,
+		Nonce:          req.Nonce,
+		SignedAt:       req.SignedAt,
+		Signature:      req.Signature,
+	})
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	return c.JSON(submittedStatus(result), s.toScoreResponse(c, result))
+}
+
+// verifyReceipt godoc
+//
+//	@Summary		Verify a score receipt
+//	@Description	Check the signature of a receipt returned by POST /scores or PUT /scores/{player_name},
+//	@Description	against the current and retired receipt keys. A forged or altered receipt is reported
+//	@Description	as signature_mismatch, one signed with a key no longer configured as unknown_key.
+//	@Tags			Scores
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		ReceiptResponse			true	"Receipt as returned on submission"
+//	@Success		200		{object}	VerifyReceiptResponse	"Verification result"
+//	@Failure		400		{object}	ErrorResponse			"Incomplete receipt"
+//	@Failure		409		{object}	ErrorResponse			"Receipts are disabled"
+//	@Router			/receipts/verify [post]
+func (s *Server) verifyReceipt(c echo.Context) error {
+	var req ReceiptResponse
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "invalid request body",
+		})
+	}
+
+	res, err := s.svc.VerifyReceipt(service.Receipt{
+		LeaderboardID: req.LeaderboardID,
+		PlayerName:    req.PlayerName,
+		Score:         req.Score,
+		IssuedAt:      req.IssuedAt,
+		Applied:       req.Applied,
+		KeyID:         req.KeyID,
+		Signature:     req.Signature,
+	})
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, VerifyReceiptResponse{
+		Valid:      res.Valid,
+		Result:     res.Result,
+		CurrentKey: res.CurrentKey,
+	})
+}
+
+// importScores godoc
+//
+//	@Summary		Import scores in bulk
+//	@Description	Import an array of scores, e.g. historical data or a migration from another system, with best score retention.
+//	@Description	Device limits and signatures do not apply; achieved_at is kept as sent unless it is in the future.
+//	@Description	Entries are written in chunked transactions (IMPORT_CHUNK_SIZE). Each entry gets an outcome:
+//	@Description	invalid entries are skipped, and every entry of a chunk that fails to commit is reported as failed.
+//	@Tags			Scores
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		[]ImportScoreEntry		true	"Scores to import (at most IMPORT_MAX_ENTRIES)"
+//	@Success		200		{object}	ImportScoresResponse	"Outcome of every entry"
+//	@Failure		400		{object}	ErrorResponse			"Invalid body"
+//	@Failure		413		{object}	ErrorResponse			"Too many entries"
+//	@Failure		500		{object}	ErrorResponse			"Internal server error"
+//	@Failure		503		{object}	ErrorResponse			"Overloaded, retry after the Retry-After delay"
+//	@Router			/scores/batch [post]
+func (s *Server) importScores(c echo.Context) error {
+	var req []ImportScoreEntry
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "request body must be a JSON array of scores",
+		})
+	}
+
+	entries := make([]service.ScoreImport, len(req))
+	for i, e := range req {
+		entries[i] = service.ScoreImport{
+			LeaderboardID: e.LeaderboardID,
+			PlayerName:    e.PlayerName,
+			Score:         e.Score,
+			AchievedAt:    e.AchievedAt,
+		}
+	}
+
+	results, err := s.svc.ImportScores(c.Request().Context(), entries)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	resp := ImportScoresResponse{Results: make([]ImportScoreResult, len(results))}
+	for i, r := range results {
+		resp.Results[i] = ImportScoreResult{Index: i, Outcome: r.Outcome, Reason: r.Reason}
+		if r.Entry != nil {
+			// No profile lookup: imports can hold thousands of entries
+			resp.Results[i].Entry = &ScoreResponse{
+				LeaderboardID:  r.Entry.LeaderboardID,
+				PlayerName:     r.Entry.PlayerName,
+				Score:          r.Entry.Score,
+				UpdatedAt:      formatTime(r.Entry.UpdatedAt, time.RFC3339),
+				Applied:        r.Entry.Applied,
+				AchievedAt:     formatTime(r.Entry.AchievedAt, time.RFC3339Nano),
+				Metadata:       r.Entry.Metadata,
+				SecondaryScore: r.Entry.SecondaryScore,
+				Region:         r.Entry.Country,
+				Platform:       r.Entry.Platform,
+			}
+		}
+		switch r.Outcome {
+		case service.ImportApplied:
+			resp.Applied++
+		case service.ImportNotImproved:
+			resp.NotImproved++
+		case service.ImportInvalid:
+			resp.Invalid++
+		case service.ImportFailed:
+			resp.Failed++
+		}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// importScoresStream godoc
+//
+//	@Summary		Import scores from an NDJSON stream
+//	@Description	Import a stream of scores, one ImportScoreEntry JSON object per line (an NDJSON export works as is),
+//	@Description	with the same rules as /scores/batch but no limit on the number of entries.
+//	@Description	Send the body with Content-Encoding: zstd to compress it. Entries are written in chunks (IMPORT_CHUNK_SIZE,
+//	@Description	default 1000), and an ImportProgress line is streamed back after each committed chunk.
+//	@Description	If the transfer fails midway, resume with offset set to the last offset_token received and a body holding
+//	@Description	the rest of the stream, from that position of the uncompressed data: re-sent entries are harmless
+//	@Description	(best score logic), and reported line numbers keep counting from the start of the stream.
+//	@Tags			Scores
+//	@Accept			application/x-ndjson
+//	@Produce		application/x-ndjson
+//	@Param			offset				query		string			false	"Offset token of a previous import to resume"
+//	@Param			Content-Encoding	header		string			false	"Body compression"	Enums(zstd)
+//	@Param			request				body		ImportScoreEntry	true	"One entry per line"
+//	@Success		200					{object}	ImportProgress	"One progress line per chunk, the last one done or with an error"
+//	@Failure		400					{object}	ErrorResponse	"Invalid offset token"
+//	@Failure		415					{object}	ErrorResponse	"Unsupported Content-Encoding"
+//	@Router			/scores/import [post]
+func (s *Server) importScoresStream(c echo.Context) error {
+	offset, err := service.ParseImportOffset(c.QueryParam("offset"))
 	if err != nil {
 		return s.handleServiceError(c, err)
 	}
 
-	return c.JSON(http.StatusOK, ScoreResponse{
-		PlayerName: result.PlayerName,
-		Score:      result.Score,
-		UpdatedAt:  result.UpdatedAt,
-		Applied:    result.Applied,
+	body := io.Reader(c.Request().Body)
+	switch enc := c.Request().Header.Get(echo.HeaderContentEncoding); enc {
+	case "", "identity":
+	case "zstd":
+		zr, err := zstd.NewReader(body)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		return c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
+			Error:   "unsupported_encoding",
+			Message: fmt.Sprintf("Content-Encoding %q is not supported, use zstd or none", enc),
+		})
+	}
+
+	// Count the exact bytes of every line, separators included, for offset tokens.
+	// The line cut short by a failed read is not a line: it is sent again on resume.
+	src := &readErrorTracker{r: body}
+	consumed := offset.Bytes
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && src.err != nil {
+			return 0, nil, src.err
+		}
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		consumed += int64(advance)
+		return advance, token, err
 	})
+
+	// Progress is written while the body is still being read
+	resp := c.Response()
+	if err := http.NewResponseController(resp).EnableFullDuplex(); err != nil {
+		return err
+	}
+
+	// Errors past this point are reported in the stream: the status is already sent
+	resp.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	resp.WriteHeader(http.StatusOK)
+	out := json.NewEncoder(resp)
+	var progress ImportProgress
+	report := func() {
+		progress.OffsetToken = offset.Token()
+		progress.Lines = offset.Line
+		out.Encode(progress)
+		resp.Flush()
+		progress.Errors = nil
+	}
+
+	ctx := c.Request().Context()
+	chunk := s.svc.StreamChunkSize()
+	line := offset.Line
+	for {
+		var entries []service.ScoreImport
+		var lines []int64 // line number of every entry
+		for len(entries) < chunk && scanner.Scan() {
+			line++
+			text := scanner.Bytes()
+			if len(text) == 0 {
+				continue
+			}
+			var e ImportScoreEntry
+			if err := json.Unmarshal(text, &e); err != nil {
+				progress.Invalid++
+				progress.Errors = append(progress.Errors, ImportLineError{Line: line, Outcome: service.ImportInvalid, Reason: "invalid JSON: " + err.Error()})
+				continue
+			}
+			entries = append(entries, service.ScoreImport{
+				LeaderboardID: e.LeaderboardID,
+				PlayerName:    e.PlayerName,
+				Score:         e.Score,
+				AchievedAt:    e.AchievedAt,
+			})
+			lines = append(lines, line)
+		}
+		end := consumed
+
+		if len(entries) > 0 {
+			results, err := s.svc.ImportScores(ctx, entries)
+			if err != nil {
+				progress.Error = err.Error()
+				report()
+				s.loggerFor(c).Error().Err(err).Int64("line", offset.Line).Msg("streamed import aborted")
+				return nil
+			}
+			for i, r := range results {
+				switch r.Outcome {
+				case service.ImportApplied:
+					progress.Applied++
+				case service.ImportNotImproved:
+					progress.NotImproved++
+				case service.ImportInvalid:
+					progress.Invalid++
+				case service.ImportFailed:
+					progress.Failed++
+				}
+				if r.Outcome == service.ImportInvalid || r.Outcome == service.ImportFailed {
+					progress.Errors = append(progress.Errors, ImportLineError{Line: lines[i], Outcome: r.Outcome, Reason: r.Reason})
+				}
+			}
+		}
+		offset = service.ImportOffset{Line: line, Bytes: end}
+
+		if len(entries) < chunk {
+			// The scanner stopped: end of stream or read error
+			if err := scanner.Err(); err != nil {
+				progress.Error = err.Error()
+				report()
+				s.loggerFor(c).Warn().Err(err).Int64("line", offset.Line).Msg("streamed import interrupted")
+				return nil
+			}
+			progress.Done = true
+			report()
+			s.loggerFor(c).Info().
+				Int64("lines", offset.Line).
+				Int("applied", progress.Applied).
+				Int("invalid", progress.Invalid).
+				Int("failed", progress.Failed).
+				Msg("📥 streamed import complete")
+			return nil
+		}
+		report()
+	}
+}
+
+// readErrorTracker records the first read error other than io.EOF
+type readErrorTracker struct {
+	r   io.Reader
+	err error
+}
+
+func (t *readErrorTracker) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err != nil && err != io.EOF && t.err == nil {
+		t.err = err
+	}
+	return n, err
+}
+
+// exportScores godoc
+//
+//	@Summary		Export a leaderboard
+//	@Description	Stream every entry of a board in rank order, for backups and analytics pipelines.
+//	@Description	The response is written page by page while the board is read (chunked transfer), so its size is not bounded:
+//	@Description	a failure midway truncates the body (an incomplete JSON array, or CSV/NDJSON with fewer rows than expected).
+//	@Description	With compression=zstd the body is a zstd stream (application/zstd) of the chosen format, flushed after every page.
+//	@Tags			Scores
+//	@Produce		json
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Produce		application/zstd
+//	@Param			format			query		string			false	"Output format"				Enums(csv, json, ndjson)	default(csv)
+//	@Param			compression		query		string			false	"Body compression"			Enums(none, zstd)			default(none)
+//	@Param			leaderboard_id	query		string			false	"Board (default global)"	maxlength(64)
+//	@Success		200				{array}		ExportEntry		"Entries in rank order (CSV has a header line with the same fields)"
+//	@Failure		400				{object}	ErrorResponse	"Validation error"
+//	@Router			/scores/export [get]
+func (s *Server) exportScores(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" && format != "ndjson" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "format must be csv, json or ndjson",
+		})
+	}
+	compression := c.QueryParam("compression")
+	if compression != "" && compression != "none" && compression != "zstd" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "compression must be none or zstd",
+		})
+	}
+	board, err := service.ResolveLeaderboardID(c.QueryParam("leaderboard_id"))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	// Errors past this point cannot change the status: the body is already streaming
+	resp := c.Response()
+	filename := fmt.Sprintf("leaderboard-%s.%s", board, format)
+	switch format {
+	case "csv":
+		resp.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	case "json":
+		resp.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+	case "ndjson":
+		resp.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	}
+
+	out := io.Writer(resp)
+	flush := resp.Flush
+	if compression == "zstd" {
+		zw, err := zstd.NewWriter(resp)
+		if err != nil {
+			return err
+		}
+		defer zw.Close()
+		out = zw
+		flush = func() {
+			zw.Flush()
+			resp.Flush()
+		}
+		filename += ".zst"
+		resp.Header().Set(echo.HeaderContentType, "application/zstd")
+	}
+	resp.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	resp.WriteHeader(http.StatusOK)
+
+	var rank int64
+	var writePage func(scores []store.Score) error
+
+	switch format {
+	case "csv":
+		w := csv.NewWriter(out)
+		if err := w.Write(exportCSVHeader); err != nil {
+			return nil
+		}
+		writePage = func(scores []store.Score) error {
+			for _, sc := range scores {
+				rank++
+				e := toExportEntry(rank, sc)
+				w.Write([]string{strconv.FormatInt(e.Rank, 10), e.LeaderboardID, e.PlayerName, strconv.FormatInt(e.Score, 10), e.AchievedAt, e.UpdatedAt,
+					strconv.FormatInt(e.SecondaryScore, 10)})
+			}
+			w.Flush()
+			flush()
+			return w.Error()
+		}
+
+	case "json", "ndjson":
+		if format == "json" {
+			if _, err := out.Write([]byte("[")); err != nil {
+				return nil
+			}
+		}
+		enc := json.NewEncoder(out)
+		writePage = func(scores []store.Score) error {
+			for _, sc := range scores {
+				if rank > 0 && format == "json" {
+					if _, err := out.Write([]byte(",")); err != nil {
+						return err
+					}
+				}
+				rank++
+				if err := enc.Encode(toExportEntry(rank, sc)); err != nil {
+					return err
+				}
+			}
+			flush()
+			return nil
+		}
+	}
+
+	if err := s.svc.ExportScores(c.Request().Context(), board, writePage); err != nil {
+		s.loggerFor(c).Error().Err(err).Str("leaderboard", board).Int64("rows", rank).Msg("leaderboard export aborted")
+		return nil
+	}
+	if format == "json" {
+		out.Write([]byte("]\n"))
+	}
+	s.loggerFor(c).Info().Str("leaderboard", board).Str("format", format).Str("compression", compression).Int64("rows", rank).Msg("leaderboard exported")
+	return nil
+}
+
+// toExportEntry converts a stored score at a given rank to an export row
+func toExportEntry(rank int64, sc store.Score) ExportEntry {
+	return ExportEntry{
+		Rank:           rank,
+		LeaderboardID:  sc.LeaderboardID,
+		PlayerName:     sc.PlayerName,
+		Score:          sc.Score,
+		AchievedAt:     sc.AchievedAt.Time.UTC().Format(time.RFC3339Nano),
+		UpdatedAt:      sc.UpdatedAt.Time.UTC().Format(time.RFC3339),
+		SecondaryScore: sc.SecondaryScore,
+	}
 }
 
 // updateScore godoc
@@ -188,11 +1325,18 @@ func (s *Server) createOrUpdateScore(c echo.Context) error {
 //	@Tags			Scores
 //	@Accept			json
 //	@Produce		json
-//	@Param			player_name	path		string				true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
-//	@Param			request		body		UpdateScoreRequest	true	"New score value"
-//	@Success		200			{object}	ScoreResponse		"Score updated"
-//	@Failure		400			{object}	ErrorResponse		"Validation error"
-//	@Failure		500			{object}	ErrorResponse		"Internal server error"
+//	@Param			player_name		path		string				true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Param			leaderboard_id	query		string				false	"Board (default global)"		maxlength(64)
+//	@Param			request			body		UpdateScoreRequest	true	"New score value"
+//	@Success		200				{object}	ScoreResponse		"Score updated"
+//	@Success		202				{object}	ScoreResponse		"Score queued (SUBMIT_MODE=async), echoed as submitted"
+//	@Failure		400				{object}	ErrorResponse		"Validation error"
+//	@Failure		401				{object}	ErrorResponse		"Missing or invalid signature"
+//	@Failure		403				{object}	ErrorResponse		"Player banned"
+//	@Failure		409				{object}	ErrorResponse		"Daily board closed"
+//	@Failure		429				{object}	ErrorResponse		"Device limit exceeded"
+//	@Failure		500				{object}	ErrorResponse		"Internal server error"
+//	@Failure		503				{object}	ErrorResponse		"Overloaded, retry after the Retry-After delay"
 //	@Router			/scores/{player_name} [put]
 func (s *Server) updateScore(c echo.Context) error {
 	playerName := c.Param("player_name")
@@ -218,30 +1362,42 @@ func (s *Server) updateScore(c echo.Context) error {
 		})
 	}
 
-	result, err := s.svc.SubmitScore(c.Request().Context(), playerName, req.Score)
+	result, err := s.svc.SubmitScore(c.Request().Context(), service.ScoreSubmission{
+		LeaderboardID:  c.QueryParam("leaderboard_id"),
+		PlayerName:     playerName,
+		Score:          req.Score,
+		DeviceID:       req.DeviceID,
+		AchievedAt:     req.AchievedAt,
+		Metadata:       req.Metadata,
+		SecondaryScore: req.SecondaryScore,
+		Country:        req.Country,
+		Platform:       req.Platform,
+		Nonce:          req.Nonce,
+		SignedAt:       req.SignedAt,
+		Signature:      req.Signature,
+	})
 	if err != nil {
 		return s.handleServiceError(c, err)
 	}
 
-	return c.JSON(http.StatusOK, ScoreResponse{
-		PlayerName: result.PlayerName,
-		Score:      result.Score,
-		UpdatedAt:  result.UpdatedAt,
-		Applied:    result.Applied,
-	})
+	return c.JSON(submittedStatus(result), s.toScoreResponse(c, result))
 }
 
 // deleteScore godoc
 //
 //	@Summary		Delete a player's score
-//	@Description	Remove a player's score entry from the leaderboard entirely
+//	@Description	Remove a player's score entry from a leaderboard. The entry is soft-deleted: an admin can list it with
+//	@Description	GET /admin/scores/deleted and restore it with POST /scores/{player_name}/restore, until the player
+//	@Description	submits a new score, which starts over from it.
 //	@Tags			Scores
 //	@Produce		json
-//	@Param			player_name	path	string	true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
-//	@Success		204			"Score deleted successfully"
-//	@Failure		400			{object}	ErrorResponse	"Validation error"
-//	@Failure		404			{object}	ErrorResponse	"Player not found"
-//	@Failure		500			{object}	ErrorResponse	"Internal server error"
+//	@Param			player_name		path	string	true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Param			leaderboard_id	query	string	false	"Board (default global)"		maxlength(64)
+//	@Success		204				"Score deleted successfully"
+//	@Failure		400				{object}	ErrorResponse	"Validation error"
+//	@Failure		404				{object}	ErrorResponse	"Player not found"
+//	@Failure		500				{object}	ErrorResponse	"Internal server error"
+//	@Failure		503				{object}	ErrorResponse	"Overloaded, retry after the Retry-After delay"
 //	@Router			/scores/{player_name} [delete]
 func (s *Server) deleteScore(c echo.Context) error {
 	playerName := c.Param("player_name")
@@ -252,15 +1408,2032 @@ func (s *Server) deleteScore(c echo.Context) error {
 		})
 	}
 
-	if err := s.svc.DeleteScore(c.Request().Context(), playerName); err != nil {
+	if err := s.svc.DeleteScore(c.Request().Context(), c.QueryParam("leaderboard_id"), playerName); err != nil {
 		return s.handleServiceError(c, err)
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
-func (s *Server) handleServiceError(c echo.Context, err error) error {
-	if errors.Is(err, service.ErrInvalidPlayerName) {
+// restoreScore godoc
+//
+//	@Summary		Restore a deleted score
+//	@Description	Put back a player's score entry removed by DELETE /scores/{player_name}, as it was when deleted.
+//	@Description	Stream subscribers see it come back like a new entry.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			player_name		path		string			true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Param			leaderboard_id	query		string			false	"Board (default global)"		maxlength(64)
+//	@Success		200				{object}	ScoreResponse	"Restored entry"
+//	@Failure		400				{object}	ErrorResponse	"Validation error"
+//	@Failure		401				{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403				{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404				{object}	ErrorResponse	"No deleted score for the player"
+//	@Failure		500				{object}	ErrorResponse	"Internal server error"
+//	@Router			/scores/{player_name}/restore [post]
+func (s *Server) restoreScore(c echo.Context) error {
+	score, err := s.svc.RestoreScore(c.Request().Context(), c.QueryParam("leaderboard_id"), c.Param("player_name"))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, s.toStoredScoreResponse(*score))
+}
+
+// getDeletedScores godoc
+//
+//	@Summary		List deleted scores
+//	@Description	List the score entries of a board removed by DELETE /scores/{player_name} that can still be restored,
+//	@Description	most recently deleted first. An entry leaves the list when it is restored or the player submits again.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			leaderboard_id	query		string					false	"Board (default global)"	maxlength(64)
+//	@Param			limit			query		int						false	"Number of entries (default 50, max 500)"
+//	@Success		200				{array}		DeletedScoreResponse	"Deleted entries"
+//	@Failure		400				{object}	ErrorResponse			"Validation error"
+//	@Failure		401				{object}	ErrorResponse			"Missing or wrong admin token"
+//	@Failure		403				{object}	ErrorResponse			"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		500				{object}	ErrorResponse			"Internal server error"
+//	@Router			/admin/scores/deleted [get]
+func (s *Server) getDeletedScores(c echo.Context) error {
+	var limit int32
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "limit must be a non-negative integer",
+			})
+		}
+		limit = int32(n)
+	}
+
+	scores, err := s.svc.GetDeletedScores(c.Request().Context(), c.QueryParam("leaderboard_id"), limit)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	resp := make([]DeletedScoreResponse, len(scores))
+	for i, sc := range scores {
+		resp[i] = DeletedScoreResponse{
+			LeaderboardID: sc.LeaderboardID,
+			PlayerName:    sc.PlayerName,
+			Score:         sc.Score,
+			AchievedAt:    sc.AchievedAt.Time.UTC().Format(time.RFC3339Nano),
+			UpdatedAt:     sc.UpdatedAt.Time.UTC().Format(time.RFC3339),
+			DeletedAt:     sc.DeletedAt.Time.UTC().Format(time.RFC3339),
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// resetLeaderboard godoc
+//
+//	@Summary		Reset a leaderboard
+//	@Description	Delete every score of a board, in two steps to avoid accidents. Without confirm nothing is deleted:
+//	@Description	the response carries the number of entries and a confirmation token bound to the board, valid for
+//	@Description	ADMIN_CONFIRM_TTL. Repeat the request with confirm set to that token to delete the scores, after copying
+//	@Description	them into a snapshot when snapshot is true. Stream subscribers of the board receive a fresh snapshot.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			leaderboard_id	query		string						false	"Board (default global)"	maxlength(64)
+//	@Param			snapshot		query		bool						false	"Snapshot the board before deleting"
+//	@Param			confirm			query		string						false	"Confirmation token of a previous response"
+//	@Success		200				{object}	ResetLeaderboardResponse	"Preview (completed false) or outcome"
+//	@Failure		400				{object}	ErrorResponse				"Validation error"
+//	@Failure		401				{object}	ErrorResponse				"Missing or wrong admin token"
+//	@Failure		403				{object}	ErrorResponse				"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		412				{object}	ErrorResponse				"Invalid or expired confirmation token"
+//	@Failure		500				{object}	ErrorResponse				"Internal server error"
+//	@Router			/scores [delete]
+func (s *Server) resetLeaderboard(c echo.Context) error {
+	snapshot := false
+	if v := c.QueryParam("snapshot"); v != "" {
+		var err error
+		if snapshot, err = strconv.ParseBool(v); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "snapshot must be true or false",
+			})
+		}
+	}
+
+	res, err := s.svc.ResetLeaderboard(c.Request().Context(), c.QueryParam("leaderboard_id"), snapshot, c.QueryParam("confirm"))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	resp := ResetLeaderboardResponse{
+		LeaderboardID:     res.LeaderboardID,
+		Completed:         res.Completed,
+		Entries:           res.Entries,
+		ConfirmationToken: res.ConfirmationToken,
+		Deleted:           res.Deleted,
+		SnapshotID:        res.SnapshotID,
+	}
+	if !res.ExpiresAt.IsZero() {
+		resp.ExpiresAt = res.ExpiresAt.Format(time.RFC3339)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// restoreLeaderboard godoc
+//
+//	@Summary		Restore a leaderboard
+//	@Description	Replace every score of a board with a previous state: an export of the board (GET /scores/export) sent as
+//	@Description	the body, or a snapshot taken by a reset or restore (snapshot_id). Entries are written as they are, without
+//	@Description	best-score logic, and players missing from the state are removed. In two steps, like resets: without confirm
+//	@Description	nothing is written and the response carries the diff with the current board and a confirmation token bound
+//	@Description	to the board and to the exact state, valid for ADMIN_CONFIRM_TTL. Repeat the request with the same body and
+//	@Description	confirm set to that token to replace the board in one transaction, after copying the current scores into a
+//	@Description	snapshot when snapshot is true. Stream subscribers of the board receive a fresh snapshot.
+//	@Tags			Admin
+//	@Accept			text/csv
+//	@Accept			json
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			leaderboard_id		query		string						false	"Board (default global)"	maxlength(64)
+//	@Param			format				query		string						false	"Body format, as exported"	Enums(csv, json, ndjson)	default(csv)
+//	@Param			snapshot_id			query		int							false	"Restore this snapshot instead of the body"
+//	@Param			snapshot			query		bool						false	"Snapshot the board before replacing it"
+//	@Param			confirm				query		string						false	"Confirmation token of a previous response"
+//	@Param			Content-Encoding	header		string						false	"Body compression"	Enums(zstd)
+//	@Param			request				body		ExportEntry					false	"Export of the board"
+//	@Success		200					{object}	RestoreLeaderboardResponse	"Preview (completed false) or outcome"
+//	@Failure		400					{object}	ErrorResponse				"Invalid export or validation error"
+//	@Failure		401					{object}	ErrorResponse				"Missing or wrong admin token"
+//	@Failure		403					{object}	ErrorResponse				"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404					{object}	ErrorResponse				"Snapshot not found"
+//	@Failure		412					{object}	ErrorResponse				"Invalid or expired confirmation token"
+//	@Failure		413					{object}	ErrorResponse				"Too many entries"
+//	@Failure		415					{object}	ErrorResponse				"Unsupported Content-Encoding"
+//	@Failure		500					{object}	ErrorResponse				"Internal server error"
+//	@Router			/scores/restore [post]
+func (s *Server) restoreLeaderboard(c echo.Context) error {
+	snapshot := false
+	if v := c.QueryParam("snapshot"); v != "" {
+		var err error
+		if snapshot, err = strconv.ParseBool(v); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "snapshot must be true or false",
+			})
+		}
+	}
+
+	var src service.RestoreSource
+	if v := c.QueryParam("snapshot_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "snapshot_id must be a positive integer",
+			})
+		}
+		src.SnapshotID = id
+	} else {
+		format := c.QueryParam("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "json" && format != "ndjson" {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "format must be csv, json or ndjson",
+			})
+		}
+
+		body := io.Reader(c.Request().Body)
+		switch enc := c.Request().Header.Get(echo.HeaderContentEncoding); enc {
+		case "", "identity":
+		case "zstd":
+			zr, err := zstd.NewReader(body)
+			if err != nil {
+				return err
+			}
+			defer zr.Close()
+			body = zr
+		default:
+			return c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{
+				Error:   "unsupported_encoding",
+				Message: fmt.Sprintf("Content-Encoding %q is not supported, use zstd or none", enc),
+			})
+		}
+
+		entries, err := decodeExport(body, format)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_export",
+				Message: err.Error(),
+			})
+		}
+		src.Entries = entries
+	}
+
+	res, err := s.svc.RestoreLeaderboard(c.Request().Context(), c.QueryParam("leaderboard_id"), src, snapshot, c.QueryParam("confirm"))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	resp := RestoreLeaderboardResponse{
+		LeaderboardID:     res.LeaderboardID,
+		Completed:         res.Completed,
+		Entries:           res.Entries,
+		Added:             res.Diff.Added,
+		Removed:           res.Diff.Removed,
+		Changed:           res.Diff.Changed,
+		Unchanged:         res.Diff.Unchanged,
+		Samples:           make([]RestoreChangeResponse, len(res.Diff.Samples)),
+		ConfirmationToken: res.ConfirmationToken,
+		Deleted:           res.Deleted,
+		Restored:          res.Restored,
+		SnapshotID:        res.SnapshotID,
+	}
+	for i, ch := range res.Diff.Samples {
+		resp.Samples[i] = RestoreChangeResponse{PlayerName: ch.PlayerName, Before: ch.Before, After: ch.After}
+	}
+	if !res.ExpiresAt.IsZero() {
+		resp.ExpiresAt = res.ExpiresAt.Format(time.RFC3339)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// decodeExport reads the entries of an export in one of the formats of GET /scores/export
+func decodeExport(r io.Reader, format string) ([]service.RestoreEntry, error) {
+	var rows []ExportEntry
+	switch format {
+	case "json":
+		if err := json.NewDecoder(r).Decode(&rows); err != nil {
+			return nil, fmt.Errorf("invalid JSON export: %w", err)
+		}
+
+	case "ndjson":
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+		for line := 1; scanner.Scan(); line++ {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var e ExportEntry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rows = append(rows, e)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+
+	case "csv":
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV export: %w", err)
+		}
+		col := make(map[string]int, len(header))
+		for i, name := range header {
+			col[name] = i
+		}
+		for _, name := range exportCSVHeader {
+			if _, ok := col[name]; !ok && name != "rank" && name != "secondary_score" {
+				return nil, fmt.Errorf("invalid CSV export: missing column %q", name)
+			}
+		}
+		for line := 2; ; line++ {
+			record, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			score, err := strconv.ParseInt(record[col["score"]], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid score %q", line, record[col["score"]])
+			}
+			var secondary int64
+			if i, ok := col["secondary_score"]; ok {
+				if secondary, err = strconv.ParseInt(record[i], 10, 64); err != nil {
+					return nil, fmt.Errorf("line %d: invalid secondary_score %q", line, record[i])
+				}
+			}
+			rows = append(rows, ExportEntry{
+				LeaderboardID:  record[col["leaderboard_id"]],
+				PlayerName:     record[col["player_name"]],
+				Score:          score,
+				AchievedAt:     record[col["achieved_at"]],
+				UpdatedAt:      record[col["updated_at"]],
+				SecondaryScore: secondary,
+			})
+		}
+	}
+
+	entries := make([]service.RestoreEntry, len(rows))
+	for i, e := range rows {
+		achievedAt, err := time.Parse(time.RFC3339Nano, e.AchievedAt)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: invalid achieved_at %q", i, e.AchievedAt)
+		}
+		var updatedAt time.Time
+		if e.UpdatedAt != "" {
+			if updatedAt, err = time.Parse(time.RFC3339Nano, e.UpdatedAt); err != nil {
+				return nil, fmt.Errorf("entry %d: invalid updated_at %q", i, e.UpdatedAt)
+			}
+		}
+		entries[i] = service.RestoreEntry{
+			LeaderboardID:  e.LeaderboardID,
+			PlayerName:     e.PlayerName,
+			Score:          e.Score,
+			SecondaryScore: e.SecondaryScore,
+			AchievedAt:     achievedAt,
+			UpdatedAt:      updatedAt,
+		}
+	}
+	return entries, nil
+}
+
+// replayEvents godoc
+//
+//	@Summary		Replay outbox events
+//	@Description	Re-dispatch the score changes of the outbox with sequences in [from_seq, to_seq] to stream subscribers,
+//	@Description	webhooks and the broadcast bus, for consumers that missed them; every board they belong to is then
+//	@Description	resynced. Events older than NOTIFY_OUTBOX_RETENTION are pruned and cannot be replayed. With dry_run
+//	@Description	nothing is dispatched. PostgreSQL only.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			request	body		ReplayEventsRequest		true	"Outbox range"
+//	@Success		200		{object}	ReplayEventsResponse	"Events replayed, or found for a dry run"
+//	@Failure		400		{object}	ErrorResponse			"Validation error"
+//	@Failure		401		{object}	ErrorResponse			"Missing or wrong admin token"
+//	@Failure		403		{object}	ErrorResponse			"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		409		{object}	ErrorResponse			"Storage backend without an outbox"
+//	@Failure		500		{object}	ErrorResponse			"Internal server error"
+//	@Router			/admin/events/replay [post]
+func (s *Server) replayEvents(c echo.Context) error {
+	var req ReplayEventsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "invalid request body",
+		})
+	}
+
+	res, err := s.svc.ReplayEvents(c.Request().Context(), req.FromSeq, req.ToSeq, req.DryRun)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	resp := ReplayEventsResponse{
+		FromSeq:   res.FromID,
+		ToSeq:     res.ToID,
+		DryRun:    res.DryRun,
+		Events:    res.Events,
+		FirstSeq:  res.FirstID,
+		LastSeq:   res.LastID,
+		Ops:       res.Ops,
+		Boards:    res.Boards,
+		OldestSeq: res.OldestID,
+		LatestSeq: res.LatestID,
+	}
+	if resp.Boards == nil {
+		resp.Boards = []string{}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// createWebhook godoc
+//
+//	@Summary		Register a webhook
+//	@Description	Register an endpoint receiving signed JSON POSTs on score.high_score, leaderboard.leader_changed and
+//	@Description	score.deleted events, and on lifecycle.<type> server lifecycle events, of one board or of every board.
+//	@Description	The response carries the signing secret, which is not returned again. PostgreSQL only.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			request	body		WebhookRequest	true	"Webhook settings"
+//	@Success		201		{object}	WebhookResponse	"Webhook registered"
+//	@Failure		400		{object}	ErrorResponse	"Validation error"
+//	@Failure		401		{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403		{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		409		{object}	ErrorResponse	"Storage backend without webhooks"
+//	@Failure		500		{object}	ErrorResponse	"Internal server error"
+//	@Router			/admin/webhooks [post]
+func (s *Server) createWebhook(c echo.Context) error {
+	spec, ok := bindWebhook(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "invalid request body",
+		})
+	}
+
+	hook, err := s.svc.CreateWebhook(c.Request().Context(), spec)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusCreated, toWebhookResponse(*hook))
+}
+
+// listWebhooks godoc
+//
+//	@Summary		List webhooks
+//	@Description	List the registered webhooks, without their secrets. PostgreSQL only.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{array}		WebhookResponse	"Webhooks"
+//	@Failure		401	{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403	{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		409	{object}	ErrorResponse	"Storage backend without webhooks"
+//	@Failure		500	{object}	ErrorResponse	"Internal server error"
+//	@Router			/admin/webhooks [get]
+func (s *Server) listWebhooks(c echo.Context) error {
+	hooks, err := s.svc.ListWebhooks(c.Request().Context())
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	resp := make([]WebhookResponse, len(hooks))
+	for i, h := range hooks {
+		resp[i] = toWebhookResponse(h)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// getWebhook godoc
+//
+//	@Summary		Get a webhook
+//	@Description	Get a registered webhook, without its secret. PostgreSQL only.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			id	path		int				true	"Webhook id"
+//	@Success		200	{object}	WebhookResponse	"Webhook"
+//	@Failure		400	{object}	ErrorResponse	"Invalid id"
+//	@Failure		401	{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403	{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404	{object}	ErrorResponse	"Webhook not found"
+//	@Failure		409	{object}	ErrorResponse	"Storage backend without webhooks"
+//	@Failure		500	{object}	ErrorResponse	"Internal server error"
+//	@Router			/admin/webhooks/{id} [get]
+func (s *Server) getWebhook(c echo.Context) error {
+	id, ok := webhookID(c)
+	if !ok {
+		return invalidWebhookID(c)
+	}
+	hook, err := s.svc.GetWebhook(c.Request().Context(), id)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, toWebhookResponse(*hook))
+}
+
+// updateWebhook godoc
+//
+//	@Summary		Update a webhook
+//	@Description	Replace the settings of a webhook; its secret is kept. Queued deliveries are sent to the new URL.
+//	@Description	PostgreSQL only.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			id		path		int				true	"Webhook id"
+//	@Param			request	body		WebhookRequest	true	"Webhook settings"
+//	@Success		200		{object}	WebhookResponse	"Webhook updated"
+//	@Failure		400		{object}	ErrorResponse	"Validation error"
+//	@Failure		401		{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403		{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404		{object}	ErrorResponse	"Webhook not found"
+//	@Failure		409		{object}	ErrorResponse	"Storage backend without webhooks"
+//	@Failure		500		{object}	ErrorResponse	"Internal server error"
+//	@Router			/admin/webhooks/{id} [put]
+func (s *Server) updateWebhook(c echo.Context) error {
+	id, ok := webhookID(c)
+	if !ok {
+		return invalidWebhookID(c)
+	}
+	spec, ok := bindWebhook(c)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "invalid request body",
+		})
+	}
+
+	hook, err := s.svc.UpdateWebhook(c.Request().Context(), id, spec)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, toWebhookResponse(*hook))
+}
+
+// deleteWebhook godoc
+//
+//	@Summary		Delete a webhook
+//	@Description	Remove a webhook with its queued deliveries and delivery log. PostgreSQL only.
+//	@Tags			Admin
+//	@Security		AdminToken
+//	@Param			id	path	int	true	"Webhook id"
+//	@Success		204	"Webhook deleted"
+//	@Failure		400	{object}	ErrorResponse	"Invalid id"
+//	@Failure		401	{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403	{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404	{object}	ErrorResponse	"Webhook not found"
+//	@Failure		409	{object}	ErrorResponse	"Storage backend without webhooks"
+//	@Failure		500	{object}	ErrorResponse	"Internal server error"
+//	@Router			/admin/webhooks/{id} [delete]
+func (s *Server) deleteWebhook(c echo.Context) error {
+	id, ok := webhookID(c)
+	if !ok {
+		return invalidWebhookID(c)
+	}
+	if err := s.svc.DeleteWebhook(c.Request().Context(), id); err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// listWebhookDeliveries godoc
+//
+//	@Summary		List webhook deliveries
+//	@Description	List the most recent deliveries of a webhook, newest first: queued and retried ones (pending), and
+//	@Description	finished ones (delivered, failed) for WEBHOOK_DELIVERY_RETENTION. PostgreSQL only.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			id		path		int							true	"Webhook id"
+//	@Param			limit	query		int							false	"Number of deliveries (default 50, max 500)"
+//	@Success		200		{array}		WebhookDeliveryResponse		"Deliveries"
+//	@Failure		400		{object}	ErrorResponse				"Validation error"
+//	@Failure		401		{object}	ErrorResponse				"Missing or wrong admin token"
+//	@Failure		403		{object}	ErrorResponse				"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404		{object}	ErrorResponse				"Webhook not found"
+//	@Failure		409		{object}	ErrorResponse				"Storage backend without webhooks"
+//	@Failure		500		{object}	ErrorResponse				"Internal server error"
+//	@Router			/admin/webhooks/{id}/deliveries [get]
+func (s *Server) listWebhookDeliveries(c echo.Context) error {
+	id, ok := webhookID(c)
+	if !ok {
+		return invalidWebhookID(c)
+	}
+	var limit int32
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "limit must be a non-negative integer",
+			})
+		}
+		limit = int32(n)
+	}
+
+	deliveries, err := s.svc.ListWebhookDeliveries(c.Request().Context(), id, limit)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	resp := make([]WebhookDeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		resp[i] = toWebhookDeliveryResponse(d)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// listKeyUsage godoc
+//
+//	@Summary		List API key usage
+//	@Description	Usage of the API keys (X-Api-Key) that sent the most requests to this server over the usage window
+//	@Description	(USAGE_WINDOW), busiest first, with their error rate and most requested methods. Keys are identified
+//	@Description	by their key id, the fingerprint logged as api_key_id. Statistics are kept per server.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			limit	query		int					false	"Number of keys (default 10, max 100)"
+//	@Success		200		{array}		KeyUsageResponse	"Busiest keys, without series"
+//	@Failure		400		{object}	ErrorResponse		"Validation error"
+//	@Failure		401		{object}	ErrorResponse		"Missing or wrong admin token"
+//	@Failure		403		{object}	ErrorResponse		"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Router			/admin/keys/usage [get]
+func (s *Server) listKeyUsage(c echo.Context) error {
+	var limit int32
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "limit must be a non-negative integer",
+			})
+		}
+		limit = int32(n)
+	}
+
+	reports, err := s.svc.TopKeyUsage(c.Request().Context(), limit)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	resp := make([]KeyUsageResponse, len(reports))
+	for i, r := range reports {
+		resp[i] = toKeyUsageResponse(r)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// getKeyUsage godoc
+//
+//	@Summary		Get API key usage
+//	@Description	Usage of an API key over the usage window of this server: requests, errors (4xx/5xx answers and
+//	@Description	failed RPCs), most requested methods and requests per bucket of the window, to spot traffic spikes.
+//	@Description	The key id is the fingerprint logged as api_key_id; streaming RPCs are counted when opened.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			id	path		string				true	"Key id, e.g. k_9f86d081884c7d65"
+//	@Success		200	{object}	KeyUsageResponse	"Key usage"
+//	@Failure		401	{object}	ErrorResponse		"Missing or wrong admin token"
+//	@Failure		403	{object}	ErrorResponse		"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404	{object}	ErrorResponse		"No request of the key in the window"
+//	@Router			/admin/keys/{id}/usage [get]
+func (s *Server) getKeyUsage(c echo.Context) error {
+	report, err := s.svc.KeyUsage(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, toKeyUsageResponse(*report))
+}
+
+// bindWebhook reads the webhook settings of a request body
+func bindWebhook(c echo.Context) (service.WebhookSpec, bool) {
+	var req WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return service.WebhookSpec{}, false
+	}
+	spec := service.WebhookSpec{
+		URL:           req.URL,
+		Events:        req.Events,
+		LeaderboardID: req.LeaderboardID,
+		Enabled:       true,
+	}
+	if req.Enabled != nil {
+		spec.Enabled = *req.Enabled
+	}
+	return spec, true
+}
+
+func webhookID(c echo.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	return id, err == nil && id > 0
+}
+
+func invalidWebhookID(c echo.Context) error {
+	return c.JSON(http.StatusBadRequest, ErrorResponse{
+		Error:   "validation_error",
+		Message: "id must be a positive integer",
+	})
+}
+
+// getPercentileBuckets godoc
+//
+//	@Summary		Get percentile buckets
+//	@Description	Returns the minimum score required to reach each configured percentile bucket (top 1%, 5%, 10%...).
+//	@Description	Thresholds are cached server-side for a short period.
+//	@Tags			Leaderboard
+//	@Produce		json
+//	@Param			leaderboard_id	query		string				false	"Board (default global)"	maxlength(64)
+//	@Param			region			query		string				false	"Count only the players of an ISO 3166-1 alpha-2 country"	minlength(2)	maxlength(2)
+//	@Param			platform		query		string				false	"Count only the players of a platform family"	Enums(pc, mobile, console, web)
+//	@Success		200				{object}	PercentilesResponse	"Percentile thresholds"
+//	@Failure		400				{object}	ErrorResponse		"Validation error"
+//	@Failure		500				{object}	ErrorResponse		"Internal server error"
+//	@Router			/leaderboard/percentiles [get]
+func (s *Server) getPercentileBuckets(c echo.Context) error {
+	snapshot, err := s.svc.GetPercentileBuckets(c.Request().Context(), c.QueryParam("leaderboard_id"), segmentParams(c))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	buckets := make([]PercentileBucketResponse, len(snapshot.Buckets))
+	for i, b := range snapshot.Buckets {
+		buckets[i] = PercentileBucketResponse{
+			TopPercent: b.TopPercent,
+			MinScore:   b.MinScore,
+		}
+	}
+
+	return c.JSON(http.StatusOK, PercentilesResponse{
+		Buckets:      buckets,
+		SortOrder:    string(snapshot.SortOrder),
+		TotalPlayers: snapshot.TotalPlayers,
+		ComputedAt:   snapshot.ComputedAt.Format(time.RFC3339),
+	})
+}
+
+// getEntryCount godoc
+//
+//	@Summary		Count entries
+//	@Description	Returns the number of entries of a board, as the GetEntryCount RPC, e.g. to size a paginated
+//	@Description	listing. Counts are cached server-side for COUNT_CACHE_TTL.
+//	@Tags			Leaderboard
+//	@Produce		json
+//	@Param			leaderboard_id	query		string				false	"Board (default global)"	maxlength(64)
+//	@Param			region			query		string				false	"Count only the players of an ISO 3166-1 alpha-2 country"	minlength(2)	maxlength(2)
+//	@Param			platform		query		string				false	"Count only the players of a platform family"	Enums(pc, mobile, console, web)
+//	@Success		200				{object}	EntryCountResponse	"Number of entries"
+//	@Failure		400				{object}	ErrorResponse		"Validation error"
+//	@Failure		500				{object}	ErrorResponse		"Internal server error"
+//	@Router			/leaderboard/count [get]
+func (s *Server) getEntryCount(c echo.Context) error {
+	board, err := service.ResolveLeaderboardID(c.QueryParam("leaderboard_id"))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	total, err := s.svc.GetEntryCount(c.Request().Context(), board, segmentParams(c))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, EntryCountResponse{LeaderboardID: board, TotalCount: total})
+}
+
+// getScoreDistribution godoc
+//
+//	@Summary		Get score distribution
+//	@Description	Returns a histogram of the scores of a board: equal-width buckets from the lowest to the highest
+//	@Description	score with the number of players in each. Boards with a narrow score range get fewer buckets
+//	@Description	than requested. Histograms are cached server-side for PERCENTILE_CACHE_TTL.
+//	@Tags			Leaderboard
+//	@Produce		json
+//	@Param			leaderboard_id	query		string					false	"Board (default global)"	maxlength(64)
+//	@Param			buckets			query		int						false	"Number of buckets (default 20)"	minimum(1)	maximum(100)
+//	@Success		200				{object}	DistributionResponse	"Score histogram"
+//	@Failure		400				{object}	ErrorResponse			"Validation error"
+//	@Failure		500				{object}	ErrorResponse			"Internal server error"
+//	@Router			/stats/distribution [get]
+func (s *Server) getScoreDistribution(c echo.Context) error {
+	var buckets int32
+	if v := c.QueryParam("buckets"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 1 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "buckets must be a positive integer",
+			})
+		}
+		buckets = int32(n)
+	}
+
+	dist, err := s.svc.GetScoreDistribution(c.Request().Context(), c.QueryParam("leaderboard_id"), buckets)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	resp := DistributionResponse{
+		LeaderboardID: dist.LeaderboardID,
+		Buckets:       make([]DistributionBucketResponse, len(dist.Buckets)),
+		BucketWidth:   dist.BucketWidth,
+		MinScore:      dist.MinScore,
+		MaxScore:      dist.MaxScore,
+		TotalPlayers:  dist.TotalPlayers,
+		ComputedAt:    dist.ComputedAt.Format(time.RFC3339),
+	}
+	for i, b := range dist.Buckets {
+		resp.Buckets[i] = DistributionBucketResponse{MinScore: b.MinScore, MaxScore: b.MaxScore, Players: b.Players}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// getTopScores godoc
+//
+//	@Summary		Top scores
+//	@Description	A page of the leaderboard in rank order, as the GetTopScores RPC.
+//	@Description	fields selects the entry fields to return (comma-separated, default all): profiles are not even
+//	@Description	loaded when left out, which saves bandwidth and lookups for clients that only draw names and scores.
+//	@Description	region lists only the scores submitted from a country, ranked within it, in the control ranking.
+//	@Tags			Leaderboard
+//	@Produce		json
+//	@Param			limit			query		int					false	"Page size (default DEFAULT_LIMIT, at most MAX_LIMIT)"
+//	@Param			offset			query		int					false	"Entries to skip, ignored with page_token"	minimum(0)
+//	@Param			page_token		query		string				false	"next_page_token of the previous page"
+//	@Param			leaderboard_id	query		string				false	"Board (default global)"	maxlength(64)
+//	@Param			fields			query		string				false	"Entry fields to return, e.g. player_name,score"
+//	@Param			region			query		string				false	"ISO 3166-1 alpha-2 country, e.g. FR"	minlength(2)	maxlength(2)
+//	@Param			platform		query		string				false	"Platform family"	Enums(pc, mobile, console, web)
+//	@Param			If-None-Match	header		string				false	"ETag of a previous response"
+//	@Success		200				{object}	TopScoresResponse	"Page of entries"
+//	@Success		304				"Unchanged since the ETag in If-None-Match"
+//	@Failure		400				{object}	ErrorResponse		"Validation error"
+//	@Failure		500				{object}	ErrorResponse		"Internal server error"
+//	@Router			/leaderboard/top [get]
+func (s *Server) getTopScores(c echo.Context) error {
+	limits := s.limits.Load()
+	limit, offset := limits.defaultLimit, int32(0)
+	for name, dst := range map[string]*int32{"limit": &limit, "offset": &offset} {
+		if v := c.QueryParam(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil || n < 0 {
+				return c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:   "validation_error",
+					Message: name + " must be a non-negative integer",
+				})
+			}
+			*dst = int32(n)
+		}
+	}
+	if limit <= 0 {
+		limit = limits.defaultLimit
+	}
+	limit = min(limit, limits.maxLimit)
+
+	var paths []string
+	if fields := c.QueryParam("fields"); fields != "" {
+		paths = strings.Split(fields, ",")
+	}
+	mask, err := service.ParseFieldMask(paths)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	ctx := c.Request().Context()
+	var page *service.TopScoresPage
+	if seg := segmentParams(c); !seg.IsZero() {
+		page, err = s.svc.GetSegmentTopScoresPage(ctx, c.QueryParam("leaderboard_id"), seg, limit, offset, c.QueryParam("page_token"))
+	} else {
+		page, err = s.svc.GetTopScoresPage(ctx, c.QueryParam("leaderboard_id"), limit, offset, c.QueryParam("page_token"))
+	}
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	var profiles map[string]store.Player
+	if mask.Has(service.FieldProfile) {
+		names := make([]string, len(page.Scores))
+		for i, sc := range page.Scores {
+			names[i] = sc.PlayerName
+		}
+		profiles = s.svc.PlayerProfiles(ctx, names)
+	}
+
+	resp := TopScoresResponse{Entries: make([]TopScoreEntry, len(page.Scores)), NextPageToken: page.NextPageToken, RankingVariant: page.RankingVariant, TotalCount: page.TotalCount}
+	for i, sc := range page.Scores {
+		e := &resp.Entries[i]
+		if mask.Has(service.FieldLeaderboardID) {
+			e.LeaderboardID = sc.LeaderboardID
+		}
+		if mask.Has(service.FieldPlayerName) {
+			e.PlayerName = sc.PlayerName
+		}
+		if mask.Has(service.FieldScore) {
+			e.Score = &sc.Score
+		}
+		if mask.Has(service.FieldUpdatedAt) {
+			e.UpdatedAt = sc.UpdatedAt.Time.UTC().Format(time.RFC3339)
+		}
+		if mask.Has(service.FieldTier) {
+			e.Tier = s.svc.TierFor(sc.LeaderboardID, sc.Score)
+		}
+		if mask.Has(service.FieldAchievedAt) {
+			e.AchievedAt = sc.AchievedAt.Time.UTC().Format(time.RFC3339Nano)
+		}
+		if mask.Has(service.FieldMetadata) {
+			e.Metadata = service.DecodeMetadata(sc.Metadata)
+		}
+		if mask.Has(service.FieldSecondary) {
+			e.SecondaryScore = sc.SecondaryScore
+		}
+		if mask.Has(service.FieldRegion) {
+			e.Region = sc.CountryCode
+		}
+		if mask.Has(service.FieldPlatform) {
+			e.Platform = sc.Platform
+		}
+		if p, ok := profiles[sc.PlayerName]; ok {
+			profile := toProfileResponse(p)
+			e.Profile = &profile
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// segmentParams reads the optional region and platform filters of a read
+func segmentParams(c echo.Context) service.Segment {
+	return service.Segment{Region: c.QueryParam("region"), Platform: c.QueryParam("platform")}
+}
+
+// getPlayerRank godoc
+//
+//	@Summary		Player rank
+//	@Description	A player's rank and best score on a board, as the GetPlayerRank RPC.
+//	@Tags			Leaderboard
+//	@Produce		json
+//	@Param			player_name		path		string				true	"Player name (1-20 characters)"
+//	@Param			leaderboard_id	query		string				false	"Board (default global)"	maxlength(64)
+//	@Param			region			query		string				false	"Rank within an ISO 3166-1 alpha-2 country, e.g. FR"	minlength(2)	maxlength(2)
+//	@Param			platform		query		string				false	"Rank within a platform family"	Enums(pc, mobile, console, web)
+//	@Param			If-None-Match	header		string				false	"ETag of a previous response"
+//	@Success		200				{object}	PlayerRankResponse	"Rank and entry"
+//	@Success		304				"Unchanged since the ETag in If-None-Match"
+//	@Failure		400				{object}	ErrorResponse		"Validation error"
+//	@Failure		404				{object}	ErrorResponse		"Player has no score on the board"
+//	@Failure		500				{object}	ErrorResponse		"Internal server error"
+//	@Router			/leaderboard/rank/{player_name} [get]
+func (s *Server) getPlayerRank(c echo.Context) error {
+	ctx := c.Request().Context()
+	var rank *service.PlayerRank
+	var err error
+	if seg := segmentParams(c); !seg.IsZero() {
+		rank, err = s.svc.GetSegmentPlayerRank(ctx, c.QueryParam("leaderboard_id"), c.Param("player_name"), seg)
+	} else {
+		rank, err = s.svc.GetPlayerRank(ctx, c.QueryParam("leaderboard_id"), c.Param("player_name"))
+	}
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	profiles := s.svc.PlayerProfiles(ctx, []string{rank.Score.PlayerName})
+	return c.JSON(http.StatusOK, PlayerRankResponse{
+		Rank:           rank.Rank,
+		Entry:          s.toRankEntry(rank.Score, profiles),
+		RankingVariant: rank.RankingVariant,
+	})
+}
+
+// getPlayerRanks godoc
+//
+//	@Summary		Ranks of several players
+//	@Description	The ranks and best scores of up to 100 players of a board, computed in a single query, as the
+//	@Description	GetPlayerRanks RPC: e.g. the placement of every lobby member on a match results screen.
+//	@Description	Ranks are always in the control ranking. Players without a score are listed in not_found.
+//	@Tags			Leaderboard
+//	@Produce		json
+//	@Param			player_name		query		[]string				true	"Player names, repeated (1 to 100)"	collectionFormat(multi)
+//	@Param			leaderboard_id	query		string					false	"Board (default global)"	maxlength(64)
+//	@Param			If-None-Match	header		string				false	"ETag of a previous response"
+//	@Success		200				{object}	PlayerRanksResponse		"Ranks, best first"
+//	@Success		304				"Unchanged since the ETag in If-None-Match"
+//	@Failure		400				{object}	ErrorResponse			"Validation error"
+//	@Failure		500				{object}	ErrorResponse			"Internal server error"
+//	@Router			/leaderboard/ranks [get]
+func (s *Server) getPlayerRanks(c echo.Context) error {
+	ctx := c.Request().Context()
+	names := c.QueryParams()["player_name"]
+	if len(names) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "player_name is required",
+		})
+	}
+
+	ranks, err := s.svc.GetPlayerRanks(ctx, c.QueryParam("leaderboard_id"), names)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	found := make([]string, len(ranks.Ranks))
+	for i, rank := range ranks.Ranks {
+		found[i] = rank.Score.PlayerName
+	}
+	profiles := s.svc.PlayerProfiles(ctx, found)
+	resp := PlayerRanksResponse{
+		Ranks:    make([]PlayerRankEntry, len(ranks.Ranks)),
+		NotFound: ranks.NotFound,
+	}
+	for i, rank := range ranks.Ranks {
+		resp.Ranks[i] = PlayerRankEntry{Rank: rank.Rank, Entry: s.toRankEntry(rank.Score, profiles)}
+	}
+	if resp.NotFound == nil {
+		resp.NotFound = []string{}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// getChangesSince godoc
+//
+//	@Summary		Changes since a checkpoint
+//	@Description	The GetChangesSince RPC over HTTP, for clients that poll instead of holding a stream open: the
+//	@Description	entries of a board changed since since_seq (next_seq of the previous poll) or since a time, as
+//	@Description	upsert and delete events shaped like those of /leaderboard/stream, the last change of each
+//	@Description	player only. Without a checkpoint, or when changes since it are no longer retained, resync is
+//	@Description	set: reload the board with /leaderboard/top, then poll from next_seq. Requires PostgreSQL.
+//	@Tags			Leaderboard
+//	@Produce		json
+//	@Param			leaderboard_id	query		string			false	"Board (default global)"	maxlength(64)
+//	@Param			since_seq		query		int				false	"Seq of the last change applied"
+//	@Param			since			query		string			false	"Or RFC3339 time of the last poll"
+//	@Param			limit			query		int				false	"Changes read at most (default 100, at most 1000)"
+//	@Success		200				{object}	ChangesResponse	"Changes, in seq order"
+//	@Failure		400				{object}	ErrorResponse	"Validation error"
+//	@Failure		409				{object}	ErrorResponse	"Storage backend keeps no change history"
+//	@Failure		500				{object}	ErrorResponse	"Internal server error"
+//	@Router			/leaderboard/changes [get]
+func (s *Server) getChangesSince(c echo.Context) error {
+	ctx := c.Request().Context()
+	var sinceSeq int64
+	if v := c.QueryParam("since_seq"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "since_seq must be an integer",
+			})
+		}
+		sinceSeq = n
+	}
+	var since time.Time
+	if v := c.QueryParam("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "since must be an RFC3339 timestamp",
+			})
+		}
+		since = t
+	}
+	var limit int32
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "limit must be a non-negative integer",
+			})
+		}
+		limit = int32(n)
+	}
+
+	page, err := s.svc.GetChangesSince(ctx, c.QueryParam("leaderboard_id"), sinceSeq, since, limit)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	var names []string
+	for _, change := range page.Changes {
+		if change.Op != "delete" {
+			names = append(names, change.PlayerName)
+		}
+	}
+	profiles := s.svc.PlayerProfiles(ctx, names)
+	resp := ChangesResponse{
+		Changes: make([]StreamEvent, 0, len(page.Changes)),
+		NextSeq: page.LastID,
+		HasMore: page.More,
+		Resync:  page.Resync,
+	}
+	for _, change := range page.Changes {
+		if ev, ok := s.toChangeEvent(change, profiles); ok {
+			resp.Changes = append(resp.Changes, ev)
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// toChangeEvent converts a score change to an upsert or delete event, with the
+// player's profile on upserts when profiles has one; false for an unknown operation
+func (s *Server) toChangeEvent(change notify.ScoreChange, profiles map[string]store.Player) (StreamEvent, bool) {
+	var kind string
+	switch change.Op {
+	case "insert", "update":
+		kind = "UPSERT"
+	case "delete":
+		kind = "DELETE"
+	default:
+		return StreamEvent{}, false
+	}
+
+	entry := TopScoreEntry{
+		LeaderboardID:  change.LeaderboardID,
+		PlayerName:     change.PlayerName,
+		Score:          &change.Score,
+		UpdatedAt:      change.UpdatedAt.UTC().Format(time.RFC3339),
+		AchievedAt:     change.AchievedAt.UTC().Format(time.RFC3339Nano),
+		Metadata:       change.Metadata,
+		SecondaryScore: change.SecondaryScore,
+		Region:         change.CountryCode,
+		Platform:       change.Platform,
+	}
+	if kind == "UPSERT" {
+		entry.Tier = s.svc.TierFor(change.LeaderboardID, change.Score)
+		if p, ok := profiles[change.PlayerName]; ok {
+			profile := toProfileResponse(p)
+			entry.Profile = &profile
+		}
+	}
+	return StreamEvent{Kind: kind, Changed: &entry, Seq: change.ID}, true
+}
+
+// toRankEntry converts the best score of a ranked player to its JSON
+// representation, with the player's profile when profiles has one
+func (s *Server) toRankEntry(sc store.Score, profiles map[string]store.Player) TopScoreEntry {
+	entry := TopScoreEntry{
+		LeaderboardID:  sc.LeaderboardID,
+		PlayerName:     sc.PlayerName,
+		Score:          &sc.Score,
+		UpdatedAt:      sc.UpdatedAt.Time.UTC().Format(time.RFC3339),
+		Tier:           s.svc.TierFor(sc.LeaderboardID, sc.Score),
+		AchievedAt:     sc.AchievedAt.Time.UTC().Format(time.RFC3339Nano),
+		Metadata:       service.DecodeMetadata(sc.Metadata),
+		SecondaryScore: sc.SecondaryScore,
+		Region:         sc.CountryCode,
+		Platform:       sc.Platform,
+	}
+	if p, ok := profiles[sc.PlayerName]; ok {
+		profile := toProfileResponse(p)
+		entry.Profile = &profile
+	}
+	return entry
+}
+
+// simulateRank godoc
+//
+//	@Summary		Simulate a score's rank
+//	@Description	Returns the rank a score would achieve if it were the player's best, without persisting anything.
+//	@Description	With player_name, the player's current entry is not counted against them.
+//	@Tags			Leaderboard
+//	@Produce		json
+//	@Param			score			query		int						true	"Hypothetical score"	minimum(0)
+//	@Param			player_name		query		string					false	"Simulating player (1-20 characters)"
+//	@Param			leaderboard_id	query		string					false	"Board (default global)"	maxlength(64)
+//	@Param			region			query		string					false	"Count only the players of an ISO 3166-1 alpha-2 country"	minlength(2)	maxlength(2)
+//	@Param			platform		query		string					false	"Count only the players of a platform family"	Enums(pc, mobile, console, web)
+//	@Param			If-None-Match	header		string				false	"ETag of a previous response"
+//	@Success		200				{object}	SimulateRankResponse	"Simulated rank"
+//	@Success		304				"Unchanged since the ETag in If-None-Match"
+//	@Failure		400				{object}	ErrorResponse			"Validation error"
+//	@Failure		500				{object}	ErrorResponse			"Internal server error"
+//	@Router			/leaderboard/simulate [get]
+func (s *Server) simulateRank(c echo.Context) error {
+	score, err := strconv.ParseInt(c.QueryParam("score"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "score must be an integer",
+		})
+	}
+
+	sim, err := s.svc.SimulateRank(c.Request().Context(), c.QueryParam("leaderboard_id"), score, c.QueryParam("player_name"), segmentParams(c))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, SimulateRankResponse{
+		Score:            sim.Score,
+		Rank:             sim.Rank,
+		TotalPlayers:     sim.TotalPlayers,
+		PointsToNextRank: sim.PointsToNextRank,
+		Tier:             sim.Tier,
+	})
+}
+
+// getAdminStats godoc
+//
+//	@Summary		Admin statistics
+//	@Description	Size of the default leaderboard and the last maintenance report: ANALYZE runs, table and index health
+//	@Description	(dead rows, sequential scans, estimated index bloat) and the recommendations derived from them,
+//	@Description	and the last archival report: scores moved to the archive, or counted by a dry run, per board and reason.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{object}	AdminStatsResponse	"Statistics"
+//	@Failure		401	{object}	ErrorResponse		"Missing or wrong admin token"
+//	@Failure		403	{object}	ErrorResponse		"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		500	{object}	ErrorResponse		"Internal server error"
+//	@Router			/admin/stats [get]
+func (s *Server) getAdminStats(c echo.Context) error {
+	stats, err := s.svc.GetBoardStats(c.Request().Context(), service.DefaultLeaderboardID)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	resp := AdminStatsResponse{
+		Players:     stats.Players,
+		Maintenance: s.maintenance.LastReport(),
+	}
+	if s.archive != nil {
+		resp.Archive = s.archive.LastReport()
+	}
+	if !stats.LastUpdatedAt.IsZero() {
+		resp.LastUpdatedAt = stats.LastUpdatedAt.UTC().Format(time.RFC3339)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// previewExpirations godoc
+//
+//	@Summary		Preview archival
+//	@Description	List the live scores the archival job (PRUNE_SCHEDULE) moves to the archive within the next days,
+//	@Description	oldest update first: scores older than PRUNE_RETENTION by then, and scores below PRUNE_SCORE_FLOOR.
+//	@Description	expires_at is the first scheduled run archiving the entry. A score improved before it expires is
+//	@Description	archived later, or not at all. Pass next_page_token as page_token to read the next page.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			days		query		int								false	"Window in days (default 7)"	minimum(1)	maximum(366)
+//	@Param			limit		query		int								false	"Number of entries (default and max from DEFAULT_LIMIT and MAX_LIMIT)"
+//	@Param			page_token	query		string							false	"next_page_token of the previous page"
+//	@Success		200			{object}	maintenance.ExpirationPreview	"Expiring entries"
+//	@Failure		400			{object}	ErrorResponse					"Validation error"
+//	@Failure		401			{object}	ErrorResponse					"Missing or wrong admin token"
+//	@Failure		403			{object}	ErrorResponse					"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		409			{object}	ErrorResponse					"Archival not scheduled (PRUNE_SCHEDULE unset)"
+//	@Failure		500			{object}	ErrorResponse					"Internal server error"
+//	@Router			/admin/archive/preview [get]
+func (s *Server) previewExpirations(c echo.Context) error {
+	if s.archive == nil {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "archive_disabled",
+			Message: "archival is not scheduled (PRUNE_SCHEDULE unset)",
+		})
+	}
+	limits := s.limits.Load()
+	days, limit := int64(maintenance.DefaultPreviewDays), int64(limits.defaultLimit)
+	for name, dst := range map[string]*int64{"days": &days, "limit": &limit} {
+		if v := c.QueryParam(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil || n < 0 {
+				return c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:   "validation_error",
+					Message: name + " must be a non-negative integer",
+				})
+			}
+			*dst = n
+		}
+	}
+	if limit <= 0 {
+		limit = int64(limits.defaultLimit)
+	}
+	limit = min(limit, int64(limits.maxLimit))
+
+	preview, err := s.archive.Preview(c.Request().Context(), int(days), int32(limit), c.QueryParam("page_token"))
+	if errors.Is(err, maintenance.ErrInvalidPreviewDays) || errors.Is(err, maintenance.ErrInvalidPageToken) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, preview)
+}
+
+// getPlayerProfile godoc
+//
+//	@Summary		Get a player's profile
+//	@Description	Returns the display name, country and avatar of a player
+//	@Tags			Players
+//	@Produce		json
+//	@Param			player_name	path		string			true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Success		200			{object}	ProfileResponse	"Player profile"
+//	@Failure		400			{object}	ErrorResponse	"Validation error"
+//	@Failure		404			{object}	ErrorResponse	"Player has no profile"
+//	@Failure		500			{object}	ErrorResponse	"Internal server error"
+//	@Router			/players/{player_name} [get]
+func (s *Server) getPlayerProfile(c echo.Context) error {
+	profile, err := s.svc.GetPlayerProfile(c.Request().Context(), c.Param("player_name"))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, toProfileResponse(*profile))
+}
+
+// upsertPlayerProfile godoc
+//
+//	@Summary		Create or replace a player's profile
+//	@Description	Set the display name, country and avatar shown next to the player's scores.
+//	@Description	All fields are replaced: omitted or empty fields clear the stored value.
+//	@Tags			Players
+//	@Accept			json
+//	@Produce		json
+//	@Param			player_name	path		string					true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Param			request		body		UpsertProfileRequest	true	"Profile fields"
+//	@Success		200			{object}	ProfileResponse			"Profile saved"
+//	@Failure		400			{object}	ErrorResponse			"Validation error"
+//	@Failure		500			{object}	ErrorResponse			"Internal server error"
+//	@Failure		503			{object}	ErrorResponse			"Overloaded, retry after the Retry-After delay"
+//	@Router			/players/{player_name} [put]
+func (s *Server) upsertPlayerProfile(c echo.Context) error {
+	var req UpsertProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "invalid request body",
+		})
+	}
+
+	profile, err := s.svc.UpsertPlayerProfile(c.Request().Context(), service.ProfileUpdate{
+		PlayerName:  c.Param("player_name"),
+		DisplayName: req.DisplayName,
+		CountryCode: req.CountryCode,
+		AvatarURL:   req.AvatarURL,
+	})
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, toProfileResponse(*profile))
+}
+
+// exportPlayerData godoc
+//
+//	@Summary		Export a player's data
+//	@Description	Everything the server stores about a player, for data subject access requests (GDPR article 15):
+//	@Description	scores of every board including deleted ones, profile, device fingerprints, entries in board
+//	@Description	snapshots, score history still in the change outbox, webhook deliveries naming the player and earlier
+//	@Description	erasures of the name. A player the server holds nothing about gets empty lists, not 404.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			player_name	path		string				true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Success		200			{object}	PlayerDataResponse	"Player data"
+//	@Failure		400			{object}	ErrorResponse		"Validation error"
+//	@Failure		401			{object}	ErrorResponse		"Missing or wrong admin token"
+//	@Failure		403			{object}	ErrorResponse		"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		500			{object}	ErrorResponse		"Internal server error"
+//	@Router			/players/{player_name}/data [get]
+func (s *Server) exportPlayerData(c echo.Context) error {
+	playerName := c.Param("player_name")
+	data, err := s.svc.ExportPlayerData(c.Request().Context(), playerName)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, toPlayerDataResponse(playerName, data, time.Now()))
+}
+
+// erasePlayerData godoc
+//
+//	@Summary		Erase a player's data
+//	@Description	Delete everything the server stores about a player, on every board, for data subject erasure requests
+//	@Description	(GDPR article 17). Unlike DELETE /scores/{player_name} nothing can be restored. Stream subscribers of
+//	@Description	the boards the player was on receive a fresh snapshot. The erasure is recorded with the SHA-256 of the
+//	@Description	name, the request id and the number of rows deleted; it shows in later exports of the same name.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			player_name	path		string					true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Success		200			{object}	ErasePlayerDataResponse	"Erasure record"
+//	@Failure		400			{object}	ErrorResponse			"Validation error"
+//	@Failure		401			{object}	ErrorResponse			"Missing or wrong admin token"
+//	@Failure		403			{object}	ErrorResponse			"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		500			{object}	ErrorResponse			"Internal server error"
+//	@Router			/players/{player_name}/data [delete]
+func (s *Server) erasePlayerData(c echo.Context) error {
+	res, err := s.svc.ErasePlayerData(c.Request().Context(), c.Param("player_name"))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, ErasePlayerDataResponse{
+		Erasure:        toPlayerErasureResponse(res.Erasure),
+		LeaderboardIDs: res.Boards,
+	})
+}
+
+// renamePlayer godoc
+//
+//	@Summary		Rename a player
+//	@Description	Move a player's scores on every board, profile and devices to a new name, in one transaction.
+//	@Description	If the new name already has scores or a profile the request fails with 409 unless merge is set:
+//	@Description	each board then keeps the better of the two scores, and the new name keeps its profile. Stream
+//	@Description	subscribers receive a DELETE of the old name followed by an UPSERT of the new one on each board.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			player_name	path		string					true	"Current player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Param			request		body		RenamePlayerRequest		true	"New name"
+//	@Success		200			{object}	RenamePlayerResponse	"Player renamed"
+//	@Failure		400			{object}	ErrorResponse			"Validation error"
+//	@Failure		401			{object}	ErrorResponse			"Missing or wrong admin token"
+//	@Failure		403			{object}	ErrorResponse			"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404			{object}	ErrorResponse			"Player has neither a score nor a profile"
+//	@Failure		409			{object}	ErrorResponse			"New name in use and merge not set"
+//	@Failure		500			{object}	ErrorResponse			"Internal server error"
+//	@Router			/players/{player_name}/rename [post]
+func (s *Server) renamePlayer(c echo.Context) error {
+	var req RenamePlayerRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "invalid request body",
+		})
+	}
+
+	res, err := s.svc.RenamePlayer(c.Request().Context(), c.Param("player_name"), req.NewPlayerName, req.Merge)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+
+	resp := RenamePlayerResponse{
+		PlayerName:           req.NewPlayerName,
+		PreviousPlayerName:   c.Param("player_name"),
+		Entries:              make([]ScoreResponse, len(res.Scores)),
+		MergedLeaderboardIDs: res.Merged,
+	}
+	if resp.MergedLeaderboardIDs == nil {
+		resp.MergedLeaderboardIDs = []string{}
+	}
+	if res.Profile != nil {
+		profile := toProfileResponse(*res.Profile)
+		resp.Profile = &profile
+	}
+	for i, score := range res.Scores {
+		resp.Entries[i] = s.toStoredScoreResponse(score)
+		resp.Entries[i].Profile = resp.Profile
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// listBans godoc
+//
+//	@Summary		List banned players
+//	@Description	List every banned player, most recently banned first.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{array}		BanResponse		"Banned players"
+//	@Failure		401	{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403	{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		500	{object}	ErrorResponse	"Internal server error"
+//	@Router			/admin/bans [get]
+func (s *Server) listBans(c echo.Context) error {
+	bans, err := s.svc.ListBans(c.Request().Context())
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	resp := make([]BanResponse, len(bans))
+	for i, b := range bans {
+		resp[i] = toBanResponse(b)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// getBan godoc
+//
+//	@Summary		Get the ban of a player
+//	@Description	Get the ban of a player; 404 when the player is not banned.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			player_name	path		string			true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Success		200			{object}	BanResponse		"Ban"
+//	@Failure		400			{object}	ErrorResponse	"Validation error"
+//	@Failure		401			{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403			{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404			{object}	ErrorResponse	"Player not banned"
+//	@Failure		500			{object}	ErrorResponse	"Internal server error"
+//	@Router			/admin/bans/{player_name} [get]
+func (s *Server) getBan(c echo.Context) error {
+	ban, err := s.svc.GetBan(c.Request().Context(), c.Param("player_name"))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, toBanResponse(*ban))
+}
+
+// banPlayer godoc
+//
+//	@Summary		Ban a player
+//	@Description	Ban a player: their submissions are rejected with 403 player_banned (PermissionDenied over gRPC)
+//	@Description	until the ban is lifted. Banning a banned player replaces the reason. With purge, the player's
+//	@Description	scores on every board are deleted and stream subscribers receive a DELETE of each; they can be
+//	@Description	restored like any deleted score.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			player_name	path		string			true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Param			request		body		BanRequest		true	"Ban"
+//	@Success		200			{object}	BanResponse		"Player banned"
+//	@Failure		400			{object}	ErrorResponse	"Validation error"
+//	@Failure		401			{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403			{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		500			{object}	ErrorResponse	"Internal server error"
+//	@Router			/admin/bans/{player_name} [put]
+func (s *Server) banPlayer(c echo.Context) error {
+	var req BanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "invalid request body",
+		})
+	}
+
+	res, err := s.svc.BanPlayer(c.Request().Context(), c.Param("player_name"), req.Reason, req.Purge)
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	resp := toBanResponse(res.Ban)
+	resp.PurgedLeaderboardIDs = res.Purged
+	return c.JSON(http.StatusOK, resp)
+}
+
+// unbanPlayer godoc
+//
+//	@Summary		Lift the ban of a player
+//	@Description	Lift the ban of a player. Scores deleted by the ban stay deleted.
+//	@Tags			Admin
+//	@Security		AdminToken
+//	@Param			player_name	path	string	true	"Player name (1-20 characters)"	minlength(1)	maxlength(20)
+//	@Success		204			"Ban lifted"
+//	@Failure		400			{object}	ErrorResponse	"Validation error"
+//	@Failure		401			{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403			{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404			{object}	ErrorResponse	"Player not banned"
+//	@Failure		500			{object}	ErrorResponse	"Internal server error"
+//	@Router			/admin/bans/{player_name} [delete]
+func (s *Server) unbanPlayer(c echo.Context) error {
+	if err := s.svc.UnbanPlayer(c.Request().Context(), c.Param("player_name")); err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// getLeaderboard godoc
+//
+//	@Summary		Get a leaderboard definition
+//	@Description	Returns the settings of a board. Boards without a definition report the defaults (desc).
+//	@Tags			Leaderboards
+//	@Produce		json
+//	@Param			leaderboard_id	path		string				true	"Board id"	maxlength(64)
+//	@Success		200				{object}	LeaderboardResponse	"Leaderboard definition"
+//	@Failure		400				{object}	ErrorResponse		"Validation error"
+//	@Failure		500				{object}	ErrorResponse		"Internal server error"
+//	@Router			/leaderboards/{leaderboard_id} [get]
+func (s *Server) getLeaderboard(c echo.Context) error {
+	def, err := s.svc.GetLeaderboard(c.Request().Context(), c.Param("leaderboard_id"))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, toLeaderboardResponse(*def))
+}
+
+// getCurrentDailyBoard godoc
+//
+//	@Summary		Get today's daily challenge board
+//	@Description	Returns the board of the current day, created on first use. Boards roll over at midnight in the daily timezone;
+//	@Description	past daily boards reject submissions (409) but stay readable.
+//	@Tags			Leaderboards
+//	@Produce		json
+//	@Success		200	{object}	DailyBoardResponse	"Today's board"
+//	@Failure		500	{object}	ErrorResponse		"Internal server error"
+//	@Router			/leaderboards/daily/current [get]
+func (s *Server) getCurrentDailyBoard(c echo.Context) error {
+	board, err := s.svc.CurrentDailyBoard(c.Request().Context())
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, DailyBoardResponse{
+		LeaderboardID:         board.LeaderboardID,
+		Date:                  board.Date,
+		StartsAt:              board.StartsAt.Format(time.RFC3339),
+		EndsAt:                board.EndsAt.Format(time.RFC3339),
+		Seed:                  board.Seed,
+		SortOrder:             string(board.SortOrder),
+		PreviousLeaderboardID: board.PreviousLeaderboardID,
+	})
+}
+
+// upsertLeaderboard godoc
+//
+//	@Summary		Create or update a leaderboard definition
+//	@Description	Set how a board ranks scores: desc (higher is better, the default) or asc (lower is better, e.g. lap times).
+//	@Description	The sort order can only change while the board has no scores.
+//	@Tags			Leaderboards
+//	@Accept			json
+//	@Produce		json
+//	@Param			leaderboard_id	path		string						true	"Board id"	maxlength(64)
+//	@Param			request			body		UpsertLeaderboardRequest	true	"Leaderboard settings"
+//	@Success		200				{object}	LeaderboardResponse			"Definition saved"
+//	@Failure		400				{object}	ErrorResponse				"Validation error"
+//	@Failure		409				{object}	ErrorResponse				"Sort order locked: the board has scores"
+//	@Failure		500				{object}	ErrorResponse				"Internal server error"
+//	@Failure		503				{object}	ErrorResponse				"Overloaded, retry after the Retry-After delay"
+//	@Router			/leaderboards/{leaderboard_id} [put]
+func (s *Server) upsertLeaderboard(c echo.Context) error {
+	var req UpsertLeaderboardRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "invalid request body",
+		})
+	}
+
+	def, err := s.svc.UpsertLeaderboard(c.Request().Context(), c.Param("leaderboard_id"), service.SortOrder(req.SortOrder),
+		service.SortOrder(req.SecondarySortOrder))
+	if err != nil {
+		return s.handleServiceError(c, err)
+	}
+	return c.JSON(http.StatusOK, toLeaderboardResponse(*def))
+}
+
+// toLeaderboardResponse converts a leaderboard definition to its JSON representation
+func toLeaderboardResponse(l store.Leaderboard) LeaderboardResponse {
+	resp := LeaderboardResponse{
+		LeaderboardID:      l.LeaderboardID,
+		SortOrder:          l.SortOrder,
+		SecondarySortOrder: l.SecondarySortOrder,
+	}
+	if l.CreatedAt.Valid {
+		resp.CreatedAt = l.CreatedAt.Time.Format(time.RFC3339)
+		resp.UpdatedAt = l.UpdatedAt.Time.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// toScoreResponse converts the result of a score submission to its JSON representation
+func (s *Server) toScoreResponse(c echo.Context, result *service.ScoreResult) ScoreResponse {
+	return ScoreResponse{
+		LeaderboardID:  result.LeaderboardID,
+		PlayerName:     result.PlayerName,
+		Score:          result.Score,
+		UpdatedAt:      formatTime(result.UpdatedAt, time.RFC3339),
+		Applied:        result.Applied,
+		Tier:           s.svc.TierFor(result.LeaderboardID, result.Score),
+		AchievedAt:     formatTime(result.AchievedAt, time.RFC3339Nano),
+		Profile:        s.profileOf(c, result.PlayerName),
+		Metadata:       result.Metadata,
+		SecondaryScore: result.SecondaryScore,
+		Region:         result.Country,
+		Platform:       result.Platform,
+		Receipt:        toReceiptResponse(result.Receipt),
+		Rank:           result.Rank,
+		RankDelta:      result.RankDelta,
+		Accepted:       result.Accepted,
+	}
+}
+
+// formatTime formats a time in UTC with layout, "" for the zero time
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(layout)
+}
+
+// submittedStatus is the status of a score submission: 202 when it was queued
+// for a later write, 200 once written
+func submittedStatus(result *service.ScoreResult) int {
+	if result.Accepted {
+		return http.StatusAccepted
+	}
+	return http.StatusOK
+}
+
+// toStoredScoreResponse converts a stored score to its JSON representation, without profile
+func (s *Server) toStoredScoreResponse(score store.Score) ScoreResponse {
+	return ScoreResponse{
+		LeaderboardID:  score.LeaderboardID,
+		PlayerName:     score.PlayerName,
+		Score:          score.Score,
+		UpdatedAt:      score.UpdatedAt.Time.UTC().Format(time.RFC3339),
+		Tier:           s.svc.TierFor(score.LeaderboardID, score.Score),
+		AchievedAt:     score.AchievedAt.Time.UTC().Format(time.RFC3339Nano),
+		Metadata:       service.DecodeMetadata(score.Metadata),
+		SecondaryScore: score.SecondaryScore,
+		Region:         score.CountryCode,
+		Platform:       score.Platform,
+	}
+}
+
+// toReceiptResponse converts a service receipt to its JSON representation (nil stays nil)
+func toReceiptResponse(r *service.Receipt) *ReceiptResponse {
+	if r == nil {
+		return nil
+	}
+	return &ReceiptResponse{
+		LeaderboardID: r.LeaderboardID,
+		PlayerName:    r.PlayerName,
+		Score:         r.Score,
+		IssuedAt:      r.IssuedAt,
+		Applied:       r.Applied,
+		KeyID:         r.KeyID,
+		Signature:     r.Signature,
+	}
+}
+
+// profileOf returns a player's profile for score responses, or nil if the player has none
+func (s *Server) profileOf(c echo.Context, playerName string) *ProfileResponse {
+	if p, ok := s.svc.PlayerProfiles(c.Request().Context(), []string{playerName})[playerName]; ok {
+		resp := toProfileResponse(p)
+		return &resp
+	}
+	return nil
+}
+
+// toProfileResponse converts a store profile to its JSON representation
+func toProfileResponse(p store.Player) ProfileResponse {
+	resp := ProfileResponse{
+		PlayerName:  p.PlayerName,
+		DisplayName: p.DisplayName,
+		CountryCode: p.CountryCode,
+		AvatarURL:   p.AvatarUrl,
+	}
+	if p.CreatedAt.Valid {
+		resp.CreatedAt = p.CreatedAt.Time.Format(time.RFC3339)
+		resp.UpdatedAt = p.UpdatedAt.Time.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// toPlayerDataResponse converts a player data export to its JSON representation
+func toPlayerDataResponse(playerName string, data *store.PlayerData, now time.Time) PlayerDataResponse {
+	resp := PlayerDataResponse{
+		PlayerName:        playerName,
+		ExportedAt:        now.UTC().Format(time.RFC3339),
+		Scores:            make([]PlayerDataScore, len(data.Scores)),
+		ArchivedScores:    make([]PlayerDataArchivedScore, len(data.ArchivedScores)),
+		Devices:           make([]PlayerDataDevice, len(data.Devices)),
+		DeviceSubmissions: make([]PlayerDataSubmission, len(data.DeviceSubmissions)),
+		SnapshotEntries:   make([]PlayerDataSnapshotEntry, len(data.SnapshotEntries)),
+		ScoreChanges:      make([]PlayerDataScoreChange, len(data.Changes)),
+		WebhookDeliveries: make([]PlayerDataDelivery, len(data.WebhookDeliveries)),
+		Erasures:          make([]PlayerErasureResponse, len(data.Erasures)),
+	}
+	for i, sc := range data.Scores {
+		resp.Scores[i] = toPlayerDataScore(sc)
+	}
+	for i, a := range data.ArchivedScores {
+		resp.ArchivedScores[i] = PlayerDataArchivedScore{
+			PlayerDataScore: toPlayerDataScore(store.Score{
+				LeaderboardID:    a.LeaderboardID,
+				Score:            a.Score,
+				SecondaryScore:   a.SecondaryScore,
+				CountryCode:      a.CountryCode,
+				Platform:         a.Platform,
+				AchievedAt:       a.AchievedAt,
+				ClientAchievedAt: a.ClientAchievedAt,
+				UpdatedAt:        a.UpdatedAt,
+				DeletedAt:        a.DeletedAt,
+				Metadata:         a.Metadata,
+			}),
+			Reason:     a.Reason,
+			ArchivedAt: a.ArchivedAt.Time.UTC().Format(time.RFC3339),
+		}
+	}
+	if data.Profile != nil {
+		profile := toProfileResponse(*data.Profile)
+		resp.Profile = &profile
+	}
+	for i, d := range data.Devices {
+		resp.Devices[i] = PlayerDataDevice{DeviceHash: d.DeviceHash, FirstSeenAt: d.FirstSeenAt.Time.UTC().Format(time.RFC3339)}
+	}
+	for i, d := range data.DeviceSubmissions {
+		resp.DeviceSubmissions[i] = PlayerDataSubmission{DeviceHash: d.DeviceHash, SubmittedAt: d.SubmittedAt.Time.UTC().Format(time.RFC3339)}
+	}
+	for i, e := range data.SnapshotEntries {
+		resp.SnapshotEntries[i] = PlayerDataSnapshotEntry{
+			SnapshotID:     e.SnapshotID,
+			Score:          e.Score,
+			SecondaryScore: e.SecondaryScore,
+			Region:         e.CountryCode,
+			Platform:       e.Platform,
+			AchievedAt:     e.AchievedAt.Time.UTC().Format(time.RFC3339Nano),
+			UpdatedAt:      e.UpdatedAt.Time.UTC().Format(time.RFC3339),
+		}
+	}
+	for i, c := range data.Changes {
+		resp.ScoreChanges[i] = PlayerDataScoreChange{
+			Seq:            c.ID,
+			LeaderboardID:  c.LeaderboardID,
+			Op:             c.Op,
+			Score:          c.Score,
+			SecondaryScore: c.SecondaryScore,
+			Region:         c.CountryCode,
+			Platform:       c.Platform,
+			AchievedAt:     c.AchievedAt.Time.UTC().Format(time.RFC3339Nano),
+			ChangedAt:      c.CreatedAt.Time.UTC().Format(time.RFC3339),
+			Metadata:       service.DecodeMetadata(c.Metadata),
+		}
+	}
+	for i, d := range data.WebhookDeliveries {
+		resp.WebhookDeliveries[i] = PlayerDataDelivery{
+			WebhookDeliveryResponse: toWebhookDeliveryResponse(d),
+			WebhookID:               d.WebhookID,
+			Payload:                 d.Payload,
+		}
+	}
+	for i, e := range data.Erasures {
+		resp.Erasures[i] = toPlayerErasureResponse(e)
+	}
+	return resp
+}
+
+// toPlayerDataScore converts a score of a player data export
+func toPlayerDataScore(sc store.Score) PlayerDataScore {
+	resp := PlayerDataScore{
+		LeaderboardID:  sc.LeaderboardID,
+		Score:          sc.Score,
+		SecondaryScore: sc.SecondaryScore,
+		Region:         sc.CountryCode,
+		Platform:       sc.Platform,
+		AchievedAt:     sc.AchievedAt.Time.UTC().Format(time.RFC3339Nano),
+		UpdatedAt:      sc.UpdatedAt.Time.UTC().Format(time.RFC3339),
+		Metadata:       service.DecodeMetadata(sc.Metadata),
+	}
+	if sc.ClientAchievedAt.Valid {
+		resp.ClientAchievedAt = sc.ClientAchievedAt.Time.UTC().Format(time.RFC3339Nano)
+	}
+	if sc.DeletedAt.Valid {
+		resp.DeletedAt = sc.DeletedAt.Time.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
+
+func toPlayerErasureResponse(e store.PlayerErasure) PlayerErasureResponse {
+	return PlayerErasureResponse{
+		ID:            e.ID,
+		PlayerHash:    e.PlayerHash,
+		RequestID:     e.RequestID,
+		DeletedScores: e.DeletedScores,
+		DeletedRows:   e.DeletedRows,
+		ErasedAt:      e.ErasedAt.Time.UTC().Format(time.RFC3339),
+	}
+}
+
+// toBanResponse converts a ban to its JSON representation
+func toBanResponse(b store.BannedPlayer) BanResponse {
+	return BanResponse{
+		PlayerName: b.PlayerName,
+		Reason:     b.Reason,
+		BannedAt:   b.BannedAt.Time.UTC().Format(time.RFC3339),
+	}
+}
+
+// toKeyUsageResponse converts a usage report to its JSON representation
+func toKeyUsageResponse(r usage.Report) KeyUsageResponse {
+	resp := KeyUsageResponse{
+		KeyID:         r.KeyID,
+		WindowSeconds: int64(r.Window.Seconds()),
+		Requests:      r.Requests,
+		Errors:        r.Errors,
+		ErrorRate:     r.ErrorRate,
+		LastSeen:      r.LastSeen.Format(time.RFC3339),
+		Methods:       make([]MethodUsageResponse, len(r.Methods)),
+	}
+	for i, m := range r.Methods {
+		resp.Methods[i] = MethodUsageResponse{Method: m.Method, Requests: m.Requests, Errors: m.Errors, ErrorRate: m.ErrorRate}
+	}
+	for _, p := range r.Series {
+		resp.Series = append(resp.Series, UsagePointResponse{Start: p.Start.Format(time.RFC3339), Requests: p.Requests, Errors: p.Errors})
+	}
+	return resp
+}
+
+// toWebhookResponse converts a store webhook to its JSON representation
+func toWebhookResponse(h store.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:            h.ID,
+		URL:           h.Url,
+		Events:        h.Events,
+		LeaderboardID: h.LeaderboardID.String,
+		Enabled:       h.Enabled,
+		Secret:        h.Secret,
+		CreatedAt:     h.CreatedAt.Time.UTC().Format(time.RFC3339),
+		UpdatedAt:     h.UpdatedAt.Time.UTC().Format(time.RFC3339),
+	}
+}
+
+// toWebhookDeliveryResponse converts a store delivery to its JSON representation
+func toWebhookDeliveryResponse(d store.WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		ID:             d.ID,
+		EventType:      d.EventType,
+		ChangeID:       d.ChangeID,
+		Replayed:       d.Replayed,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode.Int32,
+		LastError:      d.LastError.String,
+		CreatedAt:      d.CreatedAt.Time.UTC().Format(time.RFC3339),
+		UpdatedAt:      d.UpdatedAt.Time.UTC().Format(time.RFC3339),
+	}
+	if d.Status == webhook.StatusPending {
+		resp.NextAttemptAt = d.NextAttemptAt.Time.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
+
+func (s *Server) handleServiceError(c echo.Context, err error) error {
+	if errors.Is(err, service.ErrInvalidLeaderboardID) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidSortOrder) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrSortOrderLocked) {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "sort_order_locked",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrLeaderboardClosed) {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "leaderboard_closed",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidFieldMask) || errors.Is(err, service.ErrInvalidPageToken) || errors.Is(err, service.ErrInvalidReplayRange) || errors.Is(err, service.ErrInvalidBucketCount) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidRestore) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrSnapshotNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidImportOffset) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidReceipt) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidWebhook) || errors.Is(err, service.ErrInvalidLimit) || errors.Is(err, service.ErrInvalidBan) || errors.Is(err, service.ErrTooManyPlayers) || errors.Is(err, service.ErrInvalidCheckpoint) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrKeyUsageNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrScoreNotDeleted) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrBanNotFound) {
+		return c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrPlayerBanned) {
+		return c.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "player_banned",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrPlayerExists) {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "player_exists",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrWebhooksUnavailable) {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "webhooks_unavailable",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrChangesUnavailable) {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "changes_unavailable",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrReplayUnavailable) {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "replay_unavailable",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrReceiptsDisabled) {
+		return c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "receipts_disabled",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidPlayerName) {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error:   "validation_error",
 			Message: err.Error(),
@@ -272,20 +3445,170 @@ func (s *Server) handleServiceError(c echo.Context, err error) error {
 			Message: err.Error(),
 		})
 	}
+	if errors.Is(err, service.ErrInvalidProfile) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidDeviceID) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidMetadata) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidCountry) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidPlatform) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrDeviceLimitExceeded) {
+		return c.JSON(http.StatusTooManyRequests, ErrorResponse{
+			Error:   "device_limit_exceeded",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidSignature) {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "invalid_signature",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrAdminUnauthorized) {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrAdminDisabled) {
+		return c.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "admin_disabled",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrInvalidConfirmation) {
+		return c.JSON(http.StatusPreconditionFailed, ErrorResponse{
+			Error:   "invalid_confirmation",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrImportTooLarge) {
+		return c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+			Error:   "import_too_large",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrOverloaded) {
+		retryAfter := int(math.Ceil(s.svc.RetryAfter().Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		return c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Error:   "overloaded",
+			Message: err.Error(),
+		})
+	}
 	if errors.Is(err, service.ErrPlayerNotFound) {
 		return c.JSON(http.StatusNotFound, ErrorResponse{
 			Error:   "not_found",
 			Message: "player not found",
 		})
 	}
+	if errors.Is(err, service.ErrUnavailable) {
+		retryAfter := 1
+		var u *service.UnavailableError
+		if errors.As(err, &u) {
+			retryAfter = max(int(math.Ceil(u.RetryAfter.Seconds())), 1)
+		}
+		c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		return c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Error:   "unavailable",
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		s.loggerFor(c).Warn().Err(err).Msg("request timed out")
+		return c.JSON(http.StatusGatewayTimeout, ErrorResponse{
+			Error:   "timeout",
+			Message: "database query timed out",
+		})
+	}
 
-	s.logger.Error().Err(err).Msg("internal server error")
+	s.loggerFor(c).Error().Err(err).Msg("internal server error")
 	return c.JSON(http.StatusInternalServerError, ErrorResponse{
 		Error:   "internal_error",
 		Message: "an internal error occurred",
 	})
 }
 
+// requestContextMiddleware populates the request context from the request headers,
+// and echoes the request id, sent by the client or generated, in the response
+func requestContextMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			info := requestctx.FromHeaders(requestctx.TransportREST, req.Header.Get)
+			info.ClientIP = requestctx.ClientIP(req.Header.Get, req.RemoteAddr)
+			c.Response().Header().Set(echo.HeaderXRequestID, info.RequestID)
+			c.SetRequest(req.WithContext(requestctx.NewContext(req.Context(), info)))
+			return next(c)
+		}
+	}
+}
+
+// loggerFor returns the server logger annotated with the caller of the request of c
+func (s *Server) loggerFor(c echo.Context) *zerolog.Logger {
+	return requestctx.Logger(c.Request().Context(), s.logger)
+}
+
+// usageMiddleware counts requests in the usage statistics of their API key, by
+// route, with their outcome (failed for 4xx and 5xx answers)
+func usageMiddleware(svc service.Leaderboard) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			status := c.Response().Status
+			var he *echo.HTTPError
+			if errors.As(err, &he) {
+				status = he.Code
+			} else if err != nil {
+				status = http.StatusInternalServerError
+			}
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			svc.RecordUsage(c.Request().Context(), c.Request().Method+" "+route, status >= 400)
+			return err
+		}
+	}
+}
+
+// adminAuth authenticates the admin token of the Authorization header
+func (s *Server) adminAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		ctx, err := s.svc.AuthenticateAdmin(req.Context(), requestctx.BearerToken(req.Header.Get(requestctx.HeaderAuthorization)))
+		if err != nil {
+			return s.handleServiceError(c, err)
+		}
+		c.SetRequest(req.WithContext(ctx))
+		return next(c)
+	}
+}
+
 // loggingMiddleware creates a logging middleware using zerolog
 func loggingMiddleware(logger *zerolog.Logger) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -0,0 +1,973 @@
+package rest
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/events"
+	"github.com/yourorg/leaderboard/internal/maintenance"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/service"
+	"github.com/yourorg/leaderboard/internal/store"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// fakeService is a service.Leaderboard answering from canned results. Methods
+// a test does not set up panic through the nil embedded interface.
+type fakeService struct {
+	service.Leaderboard
+
+	submitted []service.ScoreSubmission
+	result    *service.ScoreResult
+
+	page        *service.TopScoresPage
+	pageArgs    []any // board, limit, offset and page token of the last call
+	segmentArgs []any // board, segment, limit, offset and page token of the last segment call
+	count       int64
+	countArgs   []any // board and segment of the last GetEntryCount call
+
+	rank        *service.PlayerRank
+	rankSegment *service.Segment // segment of the last GetSegmentPlayerRank call
+	ranks       *service.PlayerRanks
+	rankNames   []string // player names of the last GetPlayerRanks call
+	changes     *notify.ChangePage
+	changesArgs []any // board, seq, time and limit of the last GetChangesSince call
+	deleted     []string
+
+	adminToken string // accepted bearer token
+	resets     int
+	playerData *store.PlayerData
+	erased     []string
+	renames    []string // "old>new" of each rename
+	bans       map[string]store.BannedPlayer
+
+	err      error // returned by every call set up above
+	profiles map[string]store.Player
+	version  string   // of every board
+	usage    []string // routes recorded, with " failed" appended to failed ones
+}
+
+func (f *fakeService) SubmitScore(_ context.Context, sub service.ScoreSubmission) (*service.ScoreResult, error) {
+	f.submitted = append(f.submitted, sub)
+	return f.result, f.err
+}
+
+func (f *fakeService) GetTopScoresPage(_ context.Context, board string, limit, offset int32, pageToken string) (*service.TopScoresPage, error) {
+	f.pageArgs = []any{board, limit, offset, pageToken}
+	return f.page, f.err
+}
+
+func (f *fakeService) GetSegmentTopScoresPage(_ context.Context, board string, seg service.Segment, limit, offset int32, pageToken string) (*service.TopScoresPage, error) {
+	f.segmentArgs = []any{board, seg, limit, offset, pageToken}
+	return f.page, f.err
+}
+
+func (f *fakeService) GetEntryCount(_ context.Context, board string, seg service.Segment) (int64, error) {
+	f.countArgs = []any{board, seg}
+	return f.count, f.err
+}
+
+func (f *fakeService) GetPlayerRank(_ context.Context, _, _ string) (*service.PlayerRank, error) {
+	return f.rank, f.err
+}
+
+func (f *fakeService) GetSegmentPlayerRank(_ context.Context, _, _ string, seg service.Segment) (*service.PlayerRank, error) {
+	f.rankSegment = &seg
+	return f.rank, f.err
+}
+
+func (f *fakeService) GetPlayerRanks(_ context.Context, _ string, playerNames []string) (*service.PlayerRanks, error) {
+	f.rankNames = playerNames
+	return f.ranks, f.err
+}
+
+func (f *fakeService) GetChangesSince(_ context.Context, board string, sinceSeq int64, since time.Time, limit int32) (*notify.ChangePage, error) {
+	f.changesArgs = []any{board, sinceSeq, since, limit}
+	return f.changes, f.err
+}
+
+func (f *fakeService) DeleteScore(_ context.Context, board, playerName string) error {
+	f.deleted = append(f.deleted, board+"/"+playerName)
+	return f.err
+}
+
+func (f *fakeService) AuthenticateAdmin(ctx context.Context, token string) (context.Context, error) {
+	if token == "" || token != f.adminToken {
+		return nil, service.ErrAdminUnauthorized
+	}
+	return ctx, nil
+}
+
+func (f *fakeService) ResetLeaderboard(_ context.Context, board string, _ bool, _ string) (*service.ResetResult, error) {
+	f.resets++
+	return &service.ResetResult{LeaderboardID: board, Completed: true, Deleted: 2}, f.err
+}
+
+func (f *fakeService) ExportPlayerData(_ context.Context, _ string) (*store.PlayerData, error) {
+	return f.playerData, f.err
+}
+
+func (f *fakeService) ErasePlayerData(_ context.Context, playerName string) (*store.ErasureResult, error) {
+	f.erased = append(f.erased, playerName)
+	return &store.ErasureResult{
+		Erasure: store.PlayerErasure{ID: 7, PlayerHash: store.PlayerHash(playerName), DeletedScores: 1, DeletedRows: 3},
+		Boards:  []string{"global"},
+	}, f.err
+}
+
+func (f *fakeService) RenamePlayer(_ context.Context, playerName, newPlayerName string, merge bool) (*store.RenameResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if !merge && newPlayerName == "Bob" {
+		return nil, service.ErrPlayerExists
+	}
+	f.renames = append(f.renames, playerName+">"+newPlayerName)
+	return &store.RenameResult{
+		Scores:  []store.Score{{LeaderboardID: "global", PlayerName: newPlayerName, Score: 100}},
+		Profile: &store.Player{PlayerName: newPlayerName, DisplayName: "Ally"},
+	}, nil
+}
+
+func (f *fakeService) BanPlayer(_ context.Context, playerName, reason string, purge bool) (*store.BanResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.bans == nil {
+		f.bans = map[string]store.BannedPlayer{}
+	}
+	ban := store.BannedPlayer{PlayerName: playerName, Reason: reason, BannedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+	f.bans[playerName] = ban
+	res := &store.BanResult{Ban: ban}
+	if purge {
+		res.Purged = []string{"global"}
+	}
+	return res, nil
+}
+
+func (f *fakeService) UnbanPlayer(_ context.Context, playerName string) error {
+	if _, ok := f.bans[playerName]; !ok {
+		return service.ErrBanNotFound
+	}
+	delete(f.bans, playerName)
+	return nil
+}
+
+func (f *fakeService) GetBan(_ context.Context, playerName string) (*store.BannedPlayer, error) {
+	ban, ok := f.bans[playerName]
+	if !ok {
+		return nil, service.ErrBanNotFound
+	}
+	return &ban, nil
+}
+
+func (f *fakeService) ListBans(_ context.Context) ([]store.BannedPlayer, error) {
+	bans := []store.BannedPlayer{}
+	for _, ban := range f.bans {
+		bans = append(bans, ban)
+	}
+	return bans, nil
+}
+
+func (f *fakeService) PlayerProfiles(_ context.Context, _ []string) map[string]store.Player {
+	return f.profiles
+}
+
+func (f *fakeService) TierFor(_ string, score int64) string {
+	if score >= 1000 {
+		return "Gold"
+	}
+	return ""
+}
+
+func (f *fakeService) BoardVersion(string) string { return f.version }
+
+func (f *fakeService) RetryAfter() time.Duration { return 2 * time.Second }
+
+func (f *fakeService) RecordUsage(_ context.Context, method string, failed bool) {
+	if failed {
+		method += " failed"
+	}
+	f.usage = append(f.usage, method)
+}
+
+// serve sends a request to a Server over svc, with a default page of 10 and a
+// maximum of 50, and decodes the JSON answer into out when it is not nil
+func serve(t *testing.T, svc *fakeService, req *http.Request, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	logger := zerolog.Nop()
+	s := NewServer(svc, nil, nil, nil, &logger, 10, 50)
+	if req.Body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	s.echo.ServeHTTP(rec, req)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", req.Method, req.URL, rec.Body, err)
+		}
+	}
+	return rec
+}
+
+func TestCreateScore(t *testing.T) {
+	t.Run("rejected requests", func(t *testing.T) {
+		svc := &fakeService{}
+		for _, body := range []string{`{"score": 100}`, `{"player_name": "Alice", "score": -1}`, `{"player_name": `} {
+			var resp ErrorResponse
+			rec := serve(t, svc, httptest.NewRequest(http.MethodPost, "/scores", strings.NewReader(body)), &resp)
+			if rec.Code != http.StatusBadRequest || resp.Error == "" {
+				t.Errorf("%s: got %d %+v, want 400", body, rec.Code, resp)
+			}
+		}
+		if len(svc.submitted) != 0 {
+			t.Errorf("service got %d submissions, want none", len(svc.submitted))
+		}
+	})
+
+	t.Run("applied", func(t *testing.T) {
+		svc := &fakeService{
+			result: &service.ScoreResult{
+				LeaderboardID: "level-1",
+				PlayerName:    "Alice",
+				Score:         1500,
+				UpdatedAt:     time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
+				AchievedAt:    time.Date(2025, 1, 15, 10, 29, 41, 0, time.UTC),
+				Applied:       true,
+				Rank:          3,
+				RankDelta:     2,
+			},
+			profiles: map[string]store.Player{"Alice": {PlayerName: "Alice", DisplayName: "Alice the Great"}},
+		}
+		body := `{"leaderboard_id": "level-1", "player_name": "Alice", "score": 1500, "achieved_at": "2025-01-15T10:29:41Z", "metadata": {"map": "dust"}}`
+		var resp ScoreResponse
+		rec := serve(t, svc, httptest.NewRequest(http.MethodPost, "/scores", strings.NewReader(body)), &resp)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+		}
+
+		sub := svc.submitted[0]
+		if sub.LeaderboardID != "level-1" || sub.PlayerName != "Alice" || sub.Score != 1500 || sub.Metadata["map"] != "dust" ||
+			!sub.AchievedAt.Equal(time.Date(2025, 1, 15, 10, 29, 41, 0, time.UTC)) {
+			t.Errorf("service got %+v", sub)
+		}
+		if !resp.Applied || resp.Score != 1500 || resp.Tier != "Gold" || resp.Rank != 3 || resp.RankDelta != 2 ||
+			resp.Profile == nil || resp.Profile.DisplayName != "Alice the Great" || resp.Receipt != nil ||
+			resp.UpdatedAt != "2025-01-15T10:30:00Z" || resp.AchievedAt != "2025-01-15T10:29:41Z" {
+			t.Errorf("response = %+v", resp)
+		}
+		if len(svc.usage) != 1 || svc.usage[0] != "POST /scores" {
+			t.Errorf("usage recorded %v, want POST /scores", svc.usage)
+		}
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		svc := &fakeService{result: &service.ScoreResult{LeaderboardID: "global", PlayerName: "Alice", Score: 100, Accepted: true}}
+		var resp ScoreResponse
+		rec := serve(t, svc, httptest.NewRequest(http.MethodPost, "/scores", strings.NewReader(`{"player_name": "Alice", "score": 100}`)), &resp)
+		if rec.Code != http.StatusAccepted || !resp.Accepted || resp.Applied {
+			t.Errorf("got %d %+v, want 202 accepted", rec.Code, resp)
+		}
+	})
+
+	t.Run("service errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			err    error
+			status int
+			code   string
+		}{
+			{fmt.Errorf("%w: too long", service.ErrInvalidPlayerName), http.StatusBadRequest, "validation_error"},
+			{service.ErrLeaderboardClosed, http.StatusConflict, "leaderboard_closed"},
+			{service.ErrDeviceLimitExceeded, http.StatusTooManyRequests, "device_limit_exceeded"},
+			{service.ErrPlayerBanned, http.StatusForbidden, "player_banned"},
+			{service.ErrOverloaded, http.StatusServiceUnavailable, "overloaded"},
+			{fmt.Errorf("upsert score: %w", &service.UnavailableError{RetryAfter: 1500 * time.Millisecond}), http.StatusServiceUnavailable, "unavailable"},
+			{fmt.Errorf("upsert score: %w", service.ErrTimeout), http.StatusGatewayTimeout, "timeout"},
+			{errors.New("connection reset"), http.StatusInternalServerError, "internal_error"},
+		} {
+			svc := &fakeService{err: tt.err}
+			var resp ErrorResponse
+			rec := serve(t, svc, httptest.NewRequest(http.MethodPost, "/scores", strings.NewReader(`{"player_name": "Alice", "score": 100}`)), &resp)
+			if rec.Code != tt.status || resp.Error != tt.code {
+				t.Errorf("%v: got %d %s, want %d %s", tt.err, rec.Code, resp.Error, tt.status, tt.code)
+			}
+			if tt.status == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") != "2" {
+				t.Errorf("%v: Retry-After = %q, want 2", tt.err, rec.Header().Get("Retry-After"))
+			}
+			if tt.status == http.StatusInternalServerError && strings.Contains(resp.Message, "connection reset") {
+				t.Errorf("internal error message %q leaks the cause", resp.Message)
+			}
+			if len(svc.usage) != 1 || !strings.HasSuffix(svc.usage[0], " failed") {
+				t.Errorf("%v: usage recorded %v, want a failed call", tt.err, svc.usage)
+			}
+		}
+	})
+}
+
+func TestGetTopScores(t *testing.T) {
+	page := &service.TopScoresPage{
+		Scores: []store.Score{
+			{LeaderboardID: "global", PlayerName: "Alice", Score: 1200},
+			{LeaderboardID: "global", PlayerName: "Bob", Score: 900},
+		},
+		NextPageToken:  "next",
+		RankingVariant: service.RankingControl,
+		TotalCount:     2,
+	}
+
+	for query, want := range map[string]int32{"": 10, "?limit=25": 25, "?limit=500": 50} {
+		svc := &fakeService{page: page}
+		if rec := serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/top"+query, nil), nil); rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, body %s", query, rec.Code, rec.Body)
+		}
+		if got := svc.pageArgs[1]; got != want {
+			t.Errorf("%q: service got limit %v, want %d", query, got, want)
+		}
+	}
+
+	svc := &fakeService{page: page}
+	var resp TopScoresResponse
+	rec := serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/top?leaderboard_id=level-1&offset=5&page_token=tok&fields=player_name,score,tier", nil), &resp)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := svc.pageArgs; got[0] != "level-1" || got[2] != int32(5) || got[3] != "tok" {
+		t.Errorf("service got board, limit, offset, token %v", got)
+	}
+	if len(resp.Entries) != 2 || resp.NextPageToken != "next" || resp.RankingVariant != service.RankingControl || resp.TotalCount != 2 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if e := resp.Entries[0]; e.PlayerName != "Alice" || e.Score == nil || *e.Score != 1200 || e.Tier != "Gold" || e.LeaderboardID != "" {
+		t.Errorf("masked entry = %+v, want only player_name, score and tier", e)
+	}
+
+	svc = &fakeService{page: &service.TopScoresPage{Scores: []store.Score{{LeaderboardID: "global", PlayerName: "Chloe", Score: 800, CountryCode: "FR", Platform: "mobile"}}}}
+	resp = TopScoresResponse{}
+	if rec := serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/top?region=fr&platform=mobile&page_token=tok", nil), &resp); rec.Code != http.StatusOK {
+		t.Fatalf("segment: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := svc.segmentArgs; got == nil || got[1] != (service.Segment{Region: "fr", Platform: "mobile"}) || got[4] != "tok" || svc.pageArgs != nil {
+		t.Errorf("segment call: service got %v, whole board call %v", got, svc.pageArgs)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Region != "FR" || resp.Entries[0].Platform != "mobile" {
+		t.Errorf("segment entries = %+v, want Chloe with region FR and platform mobile", resp.Entries)
+	}
+	var errResp ErrorResponse
+	svc = &fakeService{err: fmt.Errorf("%w: \"FRA\"", service.ErrInvalidCountry)}
+	if rec := serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/top?region=FRA", nil), &errResp); rec.Code != http.StatusBadRequest || errResp.Error != "validation_error" {
+		t.Errorf("bad region: got %d %+v, want 400 validation_error", rec.Code, errResp)
+	}
+	svc = &fakeService{err: fmt.Errorf("%w: \"android\"", service.ErrInvalidPlatform)}
+	if rec := serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/top?platform=android", nil), &errResp); rec.Code != http.StatusBadRequest || errResp.Error != "validation_error" {
+		t.Errorf("bad platform: got %d %+v, want 400 validation_error", rec.Code, errResp)
+	}
+
+	for _, query := range []string{"?limit=-1", "?offset=abc", "?fields=password"} {
+		var resp ErrorResponse
+		if rec := serve(t, &fakeService{page: page}, httptest.NewRequest(http.MethodGet, "/leaderboard/top"+query, nil), &resp); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: got %d %+v, want 400", query, rec.Code, resp)
+		}
+	}
+}
+
+func TestGetEntryCount(t *testing.T) {
+	svc := &fakeService{count: 1200}
+	var resp EntryCountResponse
+	if rec := serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/count?region=fr&platform=pc", nil), &resp); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if resp.LeaderboardID != "global" || resp.TotalCount != 1200 {
+		t.Errorf("response = %+v, want 1200 entries on global", resp)
+	}
+	if got := svc.countArgs; got[0] != "global" || got[1] != (service.Segment{Region: "fr", Platform: "pc"}) {
+		t.Errorf("service got board, segment %v", got)
+	}
+
+	var errResp ErrorResponse
+	if rec := serve(t, &fakeService{}, httptest.NewRequest(http.MethodGet, "/leaderboard/count?leaderboard_id=bad%20board", nil), &errResp); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad board: got %d %+v, want 400", rec.Code, errResp)
+	}
+}
+
+func TestBoardETag(t *testing.T) {
+	svc := &fakeService{page: &service.TopScoresPage{Scores: []store.Score{{LeaderboardID: "global", PlayerName: "Alice", Score: 1500}}}, version: "v1"}
+	get := func(target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		return serve(t, svc, req, nil)
+	}
+
+	rec := get("/leaderboard/top?limit=10", "")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) || rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("first read: got %d with ETag %q", rec.Code, etag)
+	}
+	if rec = get("/leaderboard/top?limit=10", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+		t.Errorf("unchanged board: got %d %q, want an empty 304", rec.Code, rec.Body)
+	}
+	if rec = get("/leaderboard/top?limit=10", `"other", `+strings.TrimPrefix(etag, "W/")); rec.Code != http.StatusNotModified {
+		t.Errorf("strong form in a list: got %d, want 304", rec.Code)
+	}
+	if rec = get("/leaderboard/top?limit=20", etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("other query: got %d with ETag %q, want 200 with another ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	svc.version = "v2"
+	if rec = get("/leaderboard/top?limit=10", etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("changed board: got %d with ETag %q, want 200 with a new ETag", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	svc.err = service.ErrPlayerNotFound
+	if rec = get("/leaderboard/rank/Nobody", ""); rec.Code != http.StatusNotFound || rec.Header().Get("ETag") != "" {
+		t.Errorf("error response: got %d with ETag %q, want none", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestEnableGzip(t *testing.T) {
+	var scores []store.Score
+	for i := range 50 {
+		scores = append(scores, store.Score{LeaderboardID: "global", PlayerName: fmt.Sprintf("Player%d", i), Score: int64(1000 - i)})
+	}
+	svc := &fakeService{page: &service.TopScoresPage{Scores: scores}, version: "v1"}
+	logger := zerolog.Nop()
+	s := NewServer(svc, nil, nil, nil, &logger, 10, 50)
+	s.EnableGzip(1024)
+	get := func(target, acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		rec := httptest.NewRecorder()
+		s.echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/leaderboard/top?limit=50", "gzip, deflate", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("large page: got %d with Content-Encoding %q, want gzip", rec.Code, rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip body: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("gzip body: %v", err)
+	}
+	var page TopScoresResponse
+	if err := json.Unmarshal(body, &page); err != nil || len(page.Entries) != 50 {
+		t.Errorf("decompressed page: %d entries, %v", len(page.Entries), err)
+	}
+
+	if rec = get("/leaderboard/top?limit=50", "", ""); rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("client without gzip: got %d with Content-Encoding %q", rec.Code, rec.Header().Get("Content-Encoding"))
+	}
+	if rec = get("/health/live", "gzip", ""); rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("small response: got %d with Content-Encoding %q, want it uncompressed", rec.Code, rec.Header().Get("Content-Encoding"))
+	}
+	etag := get("/leaderboard/top?limit=50", "gzip", "").Header().Get("ETag")
+	if rec = get("/leaderboard/top?limit=50", "gzip", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("revalidation: got %d %q with Content-Encoding %q, want an empty 304", rec.Code, rec.Body, rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestGetPlayerRank(t *testing.T) {
+	var missing ErrorResponse
+	rec := serve(t, &fakeService{err: service.ErrPlayerNotFound}, httptest.NewRequest(http.MethodGet, "/leaderboard/rank/Nobody", nil), &missing)
+	if rec.Code != http.StatusNotFound || missing.Error != "not_found" {
+		t.Errorf("unknown player: got %d %+v, want 404", rec.Code, missing)
+	}
+
+	svc := &fakeService{
+		rank: &service.PlayerRank{
+			Rank:           4,
+			Score:          store.Score{LeaderboardID: "global", PlayerName: "Bob", Score: 900, UpdatedAt: pgtype.Timestamptz{Time: time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC), Valid: true}},
+			RankingVariant: service.RankingControl,
+		},
+		profiles: map[string]store.Player{"Bob": {PlayerName: "Bob", CountryCode: "FR"}},
+	}
+	var resp PlayerRankResponse
+	rec = serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/rank/Bob", nil), &resp)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	e := resp.Entry
+	if resp.Rank != 4 || e.PlayerName != "Bob" || e.Score == nil || *e.Score != 900 || e.UpdatedAt != "2025-01-15T10:30:00Z" ||
+		e.Profile == nil || e.Profile.CountryCode != "FR" {
+		t.Errorf("response = %+v", resp)
+	}
+	if svc.rankSegment != nil {
+		t.Errorf("whole board rank computed in segment %+v", *svc.rankSegment)
+	}
+	if rec = serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/rank/Bob?region=fr", nil), &resp); rec.Code != http.StatusOK {
+		t.Fatalf("region: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if svc.rankSegment == nil || *svc.rankSegment != (service.Segment{Region: "fr"}) {
+		t.Errorf("regional rank: service got segment %v, want region fr", svc.rankSegment)
+	}
+}
+
+func TestGetPlayerRanks(t *testing.T) {
+	var invalid ErrorResponse
+	rec := serve(t, &fakeService{}, httptest.NewRequest(http.MethodGet, "/leaderboard/ranks", nil), &invalid)
+	if rec.Code != http.StatusBadRequest || invalid.Error != "validation_error" {
+		t.Errorf("no players: got %d %+v, want 400", rec.Code, invalid)
+	}
+	rec = serve(t, &fakeService{err: service.ErrTooManyPlayers}, httptest.NewRequest(http.MethodGet, "/leaderboard/ranks?player_name=Bob", nil), &invalid)
+	if rec.Code != http.StatusBadRequest || invalid.Error != "validation_error" {
+		t.Errorf("too many players: got %d %+v, want 400", rec.Code, invalid)
+	}
+
+	svc := &fakeService{
+		ranks: &service.PlayerRanks{
+			Ranks: []service.PlayerRank{
+				{Rank: 2, Score: store.Score{LeaderboardID: "global", PlayerName: "Alice", Score: 1200}},
+				{Rank: 4, Score: store.Score{LeaderboardID: "global", PlayerName: "Bob", Score: 900}},
+			},
+		},
+		profiles: map[string]store.Player{"Bob": {PlayerName: "Bob", CountryCode: "FR"}},
+	}
+	var resp PlayerRanksResponse
+	rec = serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/ranks?player_name=Bob&player_name=Alice", nil), &resp)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if len(svc.rankNames) != 2 || svc.rankNames[0] != "Bob" || svc.rankNames[1] != "Alice" {
+		t.Errorf("service got players %v, want [Bob Alice]", svc.rankNames)
+	}
+	if len(resp.Ranks) != 2 || resp.Ranks[0].Rank != 2 || resp.Ranks[0].Entry.PlayerName != "Alice" ||
+		resp.Ranks[1].Entry.Profile == nil || resp.Ranks[1].Entry.Profile.CountryCode != "FR" {
+		t.Errorf("response = %+v", resp)
+	}
+	if resp.NotFound == nil || !strings.Contains(rec.Body.String(), `"not_found":[]`) {
+		t.Errorf("not_found = %v, want an empty list", resp.NotFound)
+	}
+}
+
+func TestGetChangesSince(t *testing.T) {
+	for _, query := range []string{"since_seq=abc", "since=yesterday", "limit=-1"} {
+		var invalid ErrorResponse
+		rec := serve(t, &fakeService{}, httptest.NewRequest(http.MethodGet, "/leaderboard/changes?"+query, nil), &invalid)
+		if rec.Code != http.StatusBadRequest || invalid.Error != "validation_error" {
+			t.Errorf("%s: got %d %+v, want 400", query, rec.Code, invalid)
+		}
+	}
+	var conflict ErrorResponse
+	rec := serve(t, &fakeService{err: service.ErrChangesUnavailable}, httptest.NewRequest(http.MethodGet, "/leaderboard/changes?since_seq=42", nil), &conflict)
+	if rec.Code != http.StatusConflict || conflict.Error != "changes_unavailable" {
+		t.Errorf("no outbox: got %d %+v, want 409", rec.Code, conflict)
+	}
+
+	at := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	svc := &fakeService{
+		changes: &notify.ChangePage{
+			Changes: []notify.ScoreChange{
+				{ID: 43, LeaderboardID: "level-1", PlayerName: "Alice", Score: 1500, Op: "update", UpdatedAt: at, AchievedAt: at},
+				{ID: 45, LeaderboardID: "level-1", PlayerName: "Bob", Score: 900, Op: "delete", UpdatedAt: at, AchievedAt: at},
+			},
+			LastID: 46,
+		},
+		profiles: map[string]store.Player{"Alice": {PlayerName: "Alice", CountryCode: "FR"}},
+	}
+	var resp ChangesResponse
+	rec = serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/changes?leaderboard_id=level-1&since_seq=42&limit=50", nil), &resp)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if want := []any{"level-1", int64(42), time.Time{}, int32(50)}; fmt.Sprint(svc.changesArgs) != fmt.Sprint(want) {
+		t.Errorf("service got %v, want %v", svc.changesArgs, want)
+	}
+	if len(resp.Changes) != 2 || resp.NextSeq != 46 || resp.HasMore || resp.Resync {
+		t.Fatalf("response = %+v", resp)
+	}
+	upsert, del := resp.Changes[0], resp.Changes[1]
+	if upsert.Kind != "UPSERT" || upsert.Seq != 43 || *upsert.Changed.Score != 1500 || upsert.Changed.UpdatedAt != "2025-01-15T10:30:00Z" ||
+		upsert.Changed.Profile == nil || upsert.Changed.Profile.CountryCode != "FR" {
+		t.Errorf("upsert = %+v", upsert)
+	}
+	if del.Kind != "DELETE" || del.Seq != 45 || del.Changed.PlayerName != "Bob" || del.Changed.Profile != nil {
+		t.Errorf("delete = %+v", del)
+	}
+
+	svc.changes = &notify.ChangePage{LastID: 46, Resync: true}
+	rec = serve(t, svc, httptest.NewRequest(http.MethodGet, "/leaderboard/changes", nil), &resp)
+	if rec.Code != http.StatusOK || !resp.Resync || !strings.Contains(rec.Body.String(), `"changes":[]`) {
+		t.Errorf("first poll: got %d %s, want a resync with no changes", rec.Code, rec.Body)
+	}
+}
+
+func TestDeleteScore(t *testing.T) {
+	svc := &fakeService{}
+	rec := serve(t, svc, httptest.NewRequest(http.MethodDelete, "/scores/Alice?leaderboard_id=level-1", nil), nil)
+	if rec.Code != http.StatusNoContent || len(svc.deleted) != 1 || svc.deleted[0] != "level-1/Alice" {
+		t.Errorf("got %d, deleted %v, want 204 and level-1/Alice", rec.Code, svc.deleted)
+	}
+
+	var missing ErrorResponse
+	rec = serve(t, &fakeService{err: service.ErrPlayerNotFound}, httptest.NewRequest(http.MethodDelete, "/scores/Nobody", nil), &missing)
+	if rec.Code != http.StatusNotFound || missing.Error != "not_found" {
+		t.Errorf("unknown player: got %d %+v, want 404", rec.Code, missing)
+	}
+}
+
+func TestAdminStatsRequiresAdmin(t *testing.T) {
+	svc := &fakeService{adminToken: "secret"}
+	for _, header := range []string{"", "Bearer wrong"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		var resp ErrorResponse
+		rec := serve(t, svc, req, &resp)
+		if rec.Code != http.StatusUnauthorized || resp.Error != "unauthorized" || rec.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("authorization %q: got %d %+v, want 401 with a Bearer challenge", header, rec.Code, resp)
+		}
+	}
+}
+
+func TestResetLeaderboardRequiresAdmin(t *testing.T) {
+	svc := &fakeService{adminToken: "secret"}
+
+	for _, header := range []string{"", "Bearer wrong"} {
+		req := httptest.NewRequest(http.MethodDelete, "/scores?leaderboard_id=level-1", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		var resp ErrorResponse
+		rec := serve(t, svc, req, &resp)
+		if rec.Code != http.StatusUnauthorized || resp.Error != "unauthorized" || rec.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("authorization %q: got %d %+v, want 401 with a Bearer challenge", header, rec.Code, resp)
+		}
+	}
+	if svc.resets != 0 {
+		t.Fatalf("board reset %d times without a valid token", svc.resets)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/scores?leaderboard_id=level-1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	var resp ResetLeaderboardResponse
+	rec := serve(t, svc, req, &resp)
+	if rec.Code != http.StatusOK || svc.resets != 1 || !resp.Completed || resp.LeaderboardID != "level-1" || resp.Deleted != 2 {
+		t.Errorf("got %d %+v after %d resets", rec.Code, resp, svc.resets)
+	}
+}
+
+func TestPlayerData(t *testing.T) {
+	at := pgtype.Timestamptz{Time: time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC), Valid: true}
+	svc := &fakeService{adminToken: "secret", playerData: &store.PlayerData{
+		Scores:  []store.Score{{LeaderboardID: "global", PlayerName: "Alice", Score: 100, AchievedAt: at, UpdatedAt: at, DeletedAt: at}},
+		Profile: &store.Player{PlayerName: "Alice", DisplayName: "Alice the Great", CreatedAt: at, UpdatedAt: at},
+	}}
+	request := func(method string) *http.Request {
+		req := httptest.NewRequest(method, "/players/Alice/data", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		return req
+	}
+
+	if rec := serve(t, svc, httptest.NewRequest(http.MethodDelete, "/players/Alice/data", nil), nil); rec.Code != http.StatusUnauthorized || len(svc.erased) != 0 {
+		t.Fatalf("erasure without token: got %d, erased %v, want 401", rec.Code, svc.erased)
+	}
+
+	var raw map[string]any
+	rec := serve(t, svc, request(http.MethodGet), &raw)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: got %d %s", rec.Code, rec.Body)
+	}
+	// Empty categories are empty lists: the export says there is nothing
+	for _, key := range []string{"devices", "device_submissions", "snapshot_entries", "score_changes", "webhook_deliveries", "erasures"} {
+		if list, ok := raw[key].([]any); !ok || len(list) != 0 {
+			t.Errorf("%s = %v, want []", key, raw[key])
+		}
+	}
+	var export PlayerDataResponse
+	serve(t, svc, request(http.MethodGet), &export)
+	if len(export.Scores) != 1 || export.Scores[0].DeletedAt != "2025-01-15T10:30:00Z" || export.Profile == nil || export.Profile.DisplayName != "Alice the Great" {
+		t.Errorf("export = %+v, want Alice's deleted score and profile", export)
+	}
+
+	var erasure ErasePlayerDataResponse
+	rec = serve(t, svc, request(http.MethodDelete), &erasure)
+	if rec.Code != http.StatusOK || len(svc.erased) != 1 || svc.erased[0] != "Alice" ||
+		erasure.Erasure.PlayerHash != store.PlayerHash("Alice") || len(erasure.LeaderboardIDs) != 1 {
+		t.Errorf("erasure: got %d %+v, erased %v", rec.Code, erasure, svc.erased)
+	}
+}
+
+func TestRenamePlayer(t *testing.T) {
+	svc := &fakeService{adminToken: "secret"}
+	request := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/players/Alice/rename", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		return req
+	}
+
+	if rec := serve(t, svc, httptest.NewRequest(http.MethodPost, "/players/Alice/rename", strings.NewReader(`{"new_player_name":"Carol"}`)), nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("rename without token: got %d, want 401", rec.Code)
+	}
+
+	var resp RenamePlayerResponse
+	rec := serve(t, svc, request(`{"new_player_name":"Carol"}`), &resp)
+	if rec.Code != http.StatusOK || resp.PlayerName != "Carol" || resp.PreviousPlayerName != "Alice" || len(resp.Entries) != 1 ||
+		resp.Entries[0].Profile == nil || resp.MergedLeaderboardIDs == nil || resp.Profile.DisplayName != "Ally" {
+		t.Errorf("rename: got %d %+v", rec.Code, resp)
+	}
+
+	var errResp ErrorResponse
+	if rec := serve(t, svc, request(`{"new_player_name":"Bob"}`), &errResp); rec.Code != http.StatusConflict || errResp.Error != "player_exists" {
+		t.Errorf("rename to a name in use: got %d %+v, want 409 player_exists", rec.Code, errResp)
+	}
+	if rec := serve(t, svc, request(`{"new_player_name":"Bob","merge":true}`), nil); rec.Code != http.StatusOK {
+		t.Errorf("merge: got %d, want 200", rec.Code)
+	}
+	if fmt.Sprint(svc.renames) != "[Alice>Carol Alice>Bob]" {
+		t.Errorf("renames = %v", svc.renames)
+	}
+}
+
+func TestBans(t *testing.T) {
+	svc := &fakeService{adminToken: "secret"}
+	request := func(method, path, body string) *http.Request {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	if rec := serve(t, svc, httptest.NewRequest(http.MethodPut, "/admin/bans/Mallory", strings.NewReader(`{}`)), nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("ban without token: got %d, want 401", rec.Code)
+	}
+
+	var ban BanResponse
+	rec := serve(t, svc, request(http.MethodPut, "/admin/bans/Mallory", `{"reason":"cheating","purge":true}`), &ban)
+	if rec.Code != http.StatusOK || ban.PlayerName != "Mallory" || ban.Reason != "cheating" || ban.BannedAt == "" ||
+		fmt.Sprint(ban.PurgedLeaderboardIDs) != "[global]" {
+		t.Errorf("ban: got %d %+v", rec.Code, ban)
+	}
+
+	ban = BanResponse{}
+	if rec := serve(t, svc, request(http.MethodGet, "/admin/bans/Mallory", ""), &ban); rec.Code != http.StatusOK || ban.Reason != "cheating" {
+		t.Errorf("get ban: got %d %+v", rec.Code, ban)
+	}
+	var bans []BanResponse
+	if rec := serve(t, svc, request(http.MethodGet, "/admin/bans", ""), &bans); rec.Code != http.StatusOK || len(bans) != 1 {
+		t.Errorf("list bans: got %d %+v", rec.Code, bans)
+	}
+
+	if rec := serve(t, svc, request(http.MethodDelete, "/admin/bans/Mallory", ""), nil); rec.Code != http.StatusNoContent {
+		t.Errorf("unban: got %d, want 204", rec.Code)
+	}
+	var errResp ErrorResponse
+	if rec := serve(t, svc, request(http.MethodDelete, "/admin/bans/Mallory", ""), &errResp); rec.Code != http.StatusNotFound || errResp.Error != "not_found" {
+		t.Errorf("second unban: got %d %+v, want 404 not_found", rec.Code, errResp)
+	}
+	if rec := serve(t, svc, request(http.MethodGet, "/admin/bans/Mallory", ""), nil); rec.Code != http.StatusNotFound {
+		t.Errorf("get ban after the unban: got %d, want 404", rec.Code)
+	}
+}
+
+// fakeStreamer is a Streamer with canned subscribers; its leaderboard streams
+// send update and end, its board change streams panic
+type fakeStreamer struct {
+	Streamer
+	update *pb.LeaderboardUpdate
+	subs   []*pb.Subscriber
+	board  string // board of the last Subscribers call
+	quotas map[string]int32
+}
+
+func (f *fakeStreamer) StreamLeaderboard(req *pb.SubscribeRequest, stream pb.LeaderboardService_StreamLeaderboardServer) error {
+	return stream.Send(f.update)
+}
+
+func (f *fakeStreamer) Subscribers(board string) []*pb.Subscriber {
+	f.board = board
+	return f.subs
+}
+
+func (f *fakeStreamer) Disconnect(id uint64, peerAddress string) (int, error) {
+	if id == 0 && peerAddress == "" {
+		return 0, grpcstatus.Error(codes.InvalidArgument, "set exactly one of id and peer_address")
+	}
+	if id != 3 {
+		return 0, grpcstatus.Error(codes.NotFound, "no active stream matches")
+	}
+	return 1, nil
+}
+
+func (f *fakeStreamer) SetPeerQuota(peerAddress string, maxStreams int32) (*pb.StreamQuota, error) {
+	f.quotas[peerAddress] = maxStreams
+	return &pb.StreamQuota{PeerAddress: peerAddress, MaxStreams: maxStreams}, nil
+}
+
+func (f *fakeStreamer) RemovePeerQuota(peerAddress string) error {
+	delete(f.quotas, peerAddress)
+	return nil
+}
+
+func TestStreamFormat(t *testing.T) {
+	logger := zerolog.Nop()
+	s := NewServer(&fakeService{}, nil, nil, nil, &logger, 10, 50)
+	s.SetStreamer(&fakeStreamer{update: &pb.LeaderboardUpdate{
+		Kind:    pb.LeaderboardUpdate_UPSERT,
+		Seq:     42,
+		Changed: &pb.ScoreEntry{LeaderboardId: "global", PlayerName: "Alice", Score: 500},
+	}})
+	s.SetEventRegistry(events.NewRegistry("/leaderboard"))
+	stream := func(format string) (int, string) {
+		rec := httptest.NewRecorder()
+		s.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/leaderboard/stream?format="+format, nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := stream("")
+	if code != http.StatusOK || !strings.Contains(body, `event: upsert`) || !strings.Contains(body, `"kind":"UPSERT"`) {
+		t.Errorf("default format: got %d %q", code, body)
+	}
+	code, body = stream("json/v1")
+	if code != http.StatusOK || !strings.Contains(body, `"schema_version":"v1"`) || !strings.Contains(body, `"type":"upsert"`) {
+		t.Errorf("json format: got %d %q", code, body)
+	}
+	code, body = stream("cloudevents")
+	if code != http.StatusOK || !strings.Contains(body, `"specversion":"1.0"`) || !strings.Contains(body, `"type":"com.yourorg.leaderboard.upsert.v1"`) {
+		t.Errorf("cloudevents format: got %d %q", code, body)
+	}
+	for _, format := range []string{"proto", "json/v2", "xml"} {
+		if code, body := stream(format); code != http.StatusBadRequest {
+			t.Errorf("format %s: got %d %q, want 400", format, code, body)
+		}
+	}
+}
+
+func TestListSubscribers(t *testing.T) {
+	logger := zerolog.Nop()
+	s := NewServer(&fakeService{adminToken: "secret"}, nil, nil, nil, &logger, 10, 50)
+	request := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/admin/subscribers?leaderboard_id=level-1", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	s.echo.ServeHTTP(rec, request())
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without streamer: got %d, want 503", rec.Code)
+	}
+
+	streamer := &fakeStreamer{subs: []*pb.Subscriber{{Id: 3, LeaderboardId: "level-1", Stream: "StreamLeaderboard", Transport: "grpc", Limit: 10, Dropped: 2}}}
+	s.SetStreamer(streamer)
+	rec = httptest.NewRecorder()
+	s.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/subscribers", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: got %d, want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.echo.ServeHTTP(rec, request())
+	var subs []SubscriberResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &subs); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body, err)
+	}
+	if rec.Code != http.StatusOK || len(subs) != 1 || subs[0].ID != 3 || subs[0].Limit != 10 || subs[0].Dropped != 2 || streamer.board != "level-1" {
+		t.Errorf("got %d %+v for board %q", rec.Code, subs, streamer.board)
+	}
+}
+
+func TestDisconnectAndStreamQuotas(t *testing.T) {
+	logger := zerolog.Nop()
+	s := NewServer(&fakeService{adminToken: "secret"}, nil, nil, nil, &logger, 10, 50)
+	streamer := &fakeStreamer{quotas: map[string]int32{}}
+	s.SetStreamer(streamer)
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		s.echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for body, want := range map[string]int{`{"id":3}`: http.StatusOK, `{"id":4}`: http.StatusNotFound, `{}`: http.StatusBadRequest} {
+		if rec := request(http.MethodPost, "/admin/subscribers/disconnect", body); rec.Code != want {
+			t.Errorf("disconnect %s: got %d, want %d", body, rec.Code, want)
+		}
+	}
+
+	var quota StreamQuotaResponse
+	rec := request(http.MethodPut, "/admin/stream-quotas/203.0.113.7", `{"max_streams":2}`)
+	if err := json.Unmarshal(rec.Body.Bytes(), &quota); err != nil || rec.Code != http.StatusOK || quota.MaxStreams != 2 || streamer.quotas["203.0.113.7"] != 2 {
+		t.Errorf("set quota: got %d %+v, %v", rec.Code, quota, err)
+	}
+	if rec := request(http.MethodDelete, "/admin/stream-quotas/203.0.113.7", ""); rec.Code != http.StatusNoContent || len(streamer.quotas) != 0 {
+		t.Errorf("remove quota: got %d, quotas %v", rec.Code, streamer.quotas)
+	}
+}
+
+// fakeArchiver lists the candidates it holds, and records the requests
+type fakeArchiver struct {
+	store.Archiver
+	candidates []store.ArchiveCandidate
+	listed     []store.ListArchiveCandidatesParams
+}
+
+func (f *fakeArchiver) ListArchiveCandidates(_ context.Context, arg store.ListArchiveCandidatesParams) ([]store.ArchiveCandidate, error) {
+	f.listed = append(f.listed, arg)
+	return f.candidates[:min(len(f.candidates), int(arg.Limit))], nil
+}
+
+func TestPreviewExpirations(t *testing.T) {
+	logger := zerolog.Nop()
+	s := NewServer(&fakeService{adminToken: "secret"}, nil, nil, nil, &logger, 10, 50)
+	request := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/admin/archive/preview"+query, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		s.echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request(""); rec.Code != http.StatusConflict {
+		t.Errorf("without an archival job: got %d, want 409", rec.Code)
+	}
+
+	db := &fakeArchiver{candidates: []store.ArchiveCandidate{
+		{LeaderboardID: "global", PlayerName: "Alice", Score: 50, UpdatedAt: time.Now().AddDate(0, 0, -40)},
+		{LeaderboardID: "global", PlayerName: "Bob", Score: 5, UpdatedAt: time.Now(), BelowFloor: true},
+	}}
+	s.SetArchiveJob(maintenance.NewArchiveJob(db, maintenance.ArchivePolicy{Retention: 30 * 24 * time.Hour, ScoreFloor: 10}, &logger))
+
+	var preview maintenance.ExpirationPreview
+	rec := request("?days=3&limit=200")
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("preview: got %d %q, %v", rec.Code, rec.Body, err)
+	}
+	if len(preview.Entries) != 2 || preview.Entries[0].Reason != store.ArchiveReasonRetention || preview.Entries[1].Reason != store.ArchiveReasonScoreFloor {
+		t.Errorf("entries = %+v, want Alice by retention and Bob by the score floor", preview.Entries)
+	}
+	if limit := db.listed[0].Limit; limit != 50 {
+		t.Errorf("limit = %d, want the maximum page size", limit)
+	}
+
+	for _, query := range []string{"?days=0", "?days=400", "?days=x", "?page_token=garbled"} {
+		if rec := request(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", query, rec.Code)
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/archive/preview", nil)
+	if rec := serve(t, &fakeService{adminToken: "secret"}, req, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: got %d, want 401", rec.Code)
+	}
+}
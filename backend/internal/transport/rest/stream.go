@@ -0,0 +1,323 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+	"github.com/yourorg/leaderboard/internal/events"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// Streamer serves leaderboard streams and their administration; the gRPC server
+// implements it. Its errors are gRPC status errors.
+type Streamer interface {
+	// StreamLeaderboard streams the changes affecting the subscriber's top N
+	StreamLeaderboard(*pb.SubscribeRequest, pb.LeaderboardService_StreamLeaderboardServer) error
+	// StreamBoardChanges streams every score change of the board
+	StreamBoardChanges(*pb.SubscribeRequest, pb.LeaderboardService_StreamLeaderboardServer) error
+	// Subscribers lists the active streams of a board, or of every board when board is empty
+	Subscribers(board string) []*pb.Subscriber
+	// Disconnect ends the stream with id, or every stream of a client address
+	Disconnect(id uint64, peerAddress string) (int, error)
+	// SetPeerQuota caps the streams of a client address
+	SetPeerQuota(peerAddress string, maxStreams int32) (*pb.StreamQuota, error)
+	// RemovePeerQuota lifts the stream quota of a client address
+	RemovePeerQuota(peerAddress string) error
+	// StreamQuotas lists the stream quotas
+	StreamQuotas() []*pb.StreamQuota
+}
+
+// SetStreamer sets the server of GET /leaderboard/stream and GET /scores/stream.
+// Streams are served by the gRPC implementation in process, so both transports
+// share the broadcast of score changes, filtering and heartbeats. Call it before serving.
+func (s *Server) SetStreamer(st Streamer) {
+	s.streamer = st
+}
+
+// SetEventRegistry sets the serializers of the format parameter of the event
+// streams; without one, only the default StreamEvent data is served. Call it before serving.
+func (s *Server) SetEventRegistry(r *events.Registry) {
+	s.events = r
+}
+
+// StreamEvent is the data of a Server-Sent Event of a leaderboard stream, the
+// JSON form of a LeaderboardUpdate
+type StreamEvent struct {
+	Kind         string          `json:"kind" example:"UPSERT" enums:"SNAPSHOT,UPSERT,DELETE,TIER_CHANGE,HEARTBEAT,RANK_CHANGED"`
+	Snapshot     []TopScoreEntry `json:"snapshot,omitempty"`                                   // SNAPSHOT: the top N in rank order, absent when the board is empty
+	Changed      *TopScoreEntry  `json:"changed,omitempty"`                                    // UPSERT, DELETE, TIER_CHANGE and RANK_CHANGED: the entry concerned
+	PreviousTier string          `json:"previous_tier,omitempty"`                              // TIER_CHANGE: the tier the player left
+	ServerTime   string          `json:"server_time,omitempty" example:"2025-01-15T10:30:00Z"` // HEARTBEAT
+	Seq          int64           `json:"seq,omitempty" example:"42"`                           // UPSERT and DELETE: sequence number of the score change
+	OldRank      *int64          `json:"old_rank,omitempty" example:"4"`                       // RANK_CHANGED: rank before the update, 0 when not in the top N
+	NewRank      *int64          `json:"new_rank,omitempty" example:"2"`                       // RANK_CHANGED: rank after the update, 0 when out of the top N
+}
+
+// streamLeaderboard godoc
+//
+//	@Summary		Stream the leaderboard (SSE)
+//	@Description	Server-Sent Events variant of the StreamLeaderboard RPC, for web dashboards without a gRPC stack.
+//	@Description	Each event is named after its kind in lower case (snapshot, upsert, delete, tier_change,
+//	@Description	heartbeat, rank_changed) and carries a StreamEvent as data. The first event is a snapshot of the top N; it is sent again when
+//	@Description	the server resyncs. Only changes affecting the top N are sent, and a heartbeat every
+//	@Description	STREAM_HEARTBEAT_INTERVAL keeps proxies from closing idle streams. Upsert and delete events carry
+//	@Description	their seq as event id: a client reconnecting with Last-Event-ID gets the events it missed instead
+//	@Description	of a snapshot when the server still retains them. With rank_changes=true, every event changing the
+//	@Description	top N is followed by a rank_changed event for each player it moved. With format=json or
+//	@Description	format=cloudevents (optionally pinned to a schema version, e.g. cloudevents/v1), the data is the
+//	@Description	versioned JSON schema of the events package, or its CloudEvents envelope, instead of a StreamEvent.
+//	@Tags			Leaderboard
+//	@Produce		text/event-stream
+//	@Param			limit			query		int				false	"Size of the top N (default DEFAULT_LIMIT, at most MAX_LIMIT)"
+//	@Param			leaderboard_id	query		string			false	"Board (default global)"	maxlength(64)
+//	@Param			rank_changes	query		bool			false	"Send rank_changed events"
+//	@Param			format			query		string			false	"Event data format: json or cloudevents, optionally /v1"
+//	@Param			Last-Event-ID	header		int				false	"Seq of the last event received, to resume from"
+//	@Success		200				{object}	StreamEvent		"Stream of events"
+//	@Failure		400				{object}	ErrorResponse	"Validation error"
+//	@Failure		503				{object}	ErrorResponse	"Streaming unavailable"
+//	@Router			/leaderboard/stream [get]
+func (s *Server) streamLeaderboard(c echo.Context) error {
+	return s.serveEvents(c, false)
+}
+
+// streamScores godoc
+//
+//	@Summary		Stream score changes (SSE)
+//	@Description	Server-Sent Events feed of a board's activity for dashboards: a snapshot of the top N, then an upsert
+//	@Description	or delete event for every score change of the board, wherever the player ranks. Events are named
+//	@Description	and shaped like those of /leaderboard/stream, without tier changes; a client keeping a top N table
+//	@Description	places or drops changed entries itself. Like /leaderboard/stream, it resumes from Last-Event-ID
+//	@Description	and takes a format.
+//	@Tags			Scores
+//	@Produce		text/event-stream
+//	@Param			limit			query		int				false	"Size of the snapshot (default DEFAULT_LIMIT, at most MAX_LIMIT)"
+//	@Param			leaderboard_id	query		string			false	"Board (default global)"	maxlength(64)
+//	@Param			format			query		string			false	"Event data format: json or cloudevents, optionally /v1"
+//	@Param			Last-Event-ID	header		int				false	"Seq of the last event received, to resume from"
+//	@Success		200				{object}	StreamEvent		"Stream of events"
+//	@Failure		400				{object}	ErrorResponse	"Validation error"
+//	@Failure		503				{object}	ErrorResponse	"Streaming unavailable"
+//	@Router			/scores/stream [get]
+func (s *Server) streamScores(c echo.Context) error {
+	return s.serveEvents(c, true)
+}
+
+// serveEvents serves a leaderboard stream as Server-Sent Events: every score
+// change of the board with allChanges, otherwise those affecting the top N
+func (s *Server) serveEvents(c echo.Context, allChanges bool) error {
+	var limit int32
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "limit must be a non-negative integer",
+			})
+		}
+		limit = int32(n)
+	}
+	var rankChanges bool
+	if v := c.QueryParam("rank_changes"); v != "" {
+		var err error
+		if rankChanges, err = strconv.ParseBool(v); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "rank_changes must be true or false",
+			})
+		}
+	}
+	var serializer events.Serializer
+	if v := c.QueryParam("format"); v != "" {
+		var err error
+		if serializer, err = s.streamSerializer(v); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "validation_error",
+				Message: "format: " + err.Error(),
+			})
+		}
+	}
+	if s.streamer == nil {
+		return streamUnavailable(c)
+	}
+
+	serve := s.streamer.StreamLeaderboard
+	if allChanges {
+		serve = s.streamer.StreamBoardChanges
+	}
+	// EventSource sends the id of the last event received when it reconnects;
+	// an id that is not a seq just starts over with a snapshot
+	resumeFrom, _ := strconv.ParseInt(c.Request().Header.Get("Last-Event-ID"), 10, 64)
+
+	stream := &sseStream{ctx: c.Request().Context(), resp: c.Response(), serializer: serializer}
+	err := serve(&pb.SubscribeRequest{
+		InitialLimit:  limit,
+		LeaderboardId: c.QueryParam("leaderboard_id"),
+		ResumeFromSeq: resumeFrom,
+		RankChanges:   rankChanges,
+	}, stream)
+	if err == nil {
+		return nil
+	}
+	if !stream.started {
+		return s.handleStreamError(c, err)
+	}
+	// The status is already sent: the client sees the stream end and reconnects
+	s.loggerFor(c).Debug().Err(err).Msg("leaderboard event stream ended")
+	return nil
+}
+
+// streamSerializer returns the serializer of the format parameter of a stream.
+// Event data is text: the binary proto format is not served.
+func (s *Server) streamSerializer(spec string) (events.Serializer, error) {
+	if err := events.ValidateSinkFormat(spec); err != nil {
+		return nil, err
+	}
+	if s.events == nil {
+		return nil, fmt.Errorf("%w: %q", events.ErrUnknownFormat, spec)
+	}
+	return s.events.Get(spec)
+}
+
+// streamUnavailable answers a request needing the Streamer on a server without one
+func streamUnavailable(c echo.Context) error {
+	return c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+		Error:   "stream_unavailable",
+		Message: "leaderboard streaming is not available on this server",
+	})
+}
+
+// handleStreamError maps the status of a stream that failed before its first
+// event, or of a failed subscriber administration call
+func (s *Server) handleStreamError(c echo.Context, err error) error {
+	st := grpcstatus.Convert(err)
+	switch st.Code() {
+	case codes.InvalidArgument:
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "validation_error", Message: st.Message()})
+	case codes.NotFound:
+		return c.JSON(http.StatusNotFound, ErrorResponse{Error: "not_found", Message: st.Message()})
+	case codes.Unavailable, codes.ResourceExhausted:
+		return c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: "unavailable", Message: st.Message()})
+	}
+	// Messages of gRPC internal errors are meant for clients
+	s.loggerFor(c).Error().Err(err).Msg("leaderboard event stream failed")
+	return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal_error", Message: st.Message()})
+}
+
+// sseStream writes the updates of a StreamLeaderboard call as Server-Sent Events.
+// Response headers are only sent with the first event, so a stream failing
+// before it still gets a regular error response.
+type sseStream struct {
+	ctx        context.Context
+	resp       *echo.Response
+	serializer events.Serializer // encodes the data, StreamEvent JSON when nil
+	started    bool
+}
+
+var _ pb.LeaderboardService_StreamLeaderboardServer = (*sseStream)(nil)
+
+var errNoClientMessages = errors.New("leaderboard event streams receive no messages")
+
+func (st *sseStream) Send(update *pb.LeaderboardUpdate) error {
+	data, err := st.encode(update)
+	if err != nil {
+		return err
+	}
+	if !st.started {
+		h := st.resp.Header()
+		h.Set(echo.HeaderContentType, "text/event-stream")
+		h.Set(echo.HeaderCacheControl, "no-cache")
+		h.Set("X-Accel-Buffering", "no") // nginx would hold events back
+		st.resp.WriteHeader(http.StatusOK)
+		st.started = true
+	}
+	if update.Seq > 0 {
+		if _, err := fmt.Fprintf(st.resp, "id: %d\n", update.Seq); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(st.resp, "event: %s\ndata: %s\n\n", strings.ToLower(update.Kind.String()), data); err != nil {
+		return err
+	}
+	st.resp.Flush()
+	return nil
+}
+
+// encode encodes the data of the event of an update
+func (st *sseStream) encode(update *pb.LeaderboardUpdate) ([]byte, error) {
+	if st.serializer == nil {
+		return json.Marshal(toStreamEvent(update))
+	}
+	return st.serializer.Marshal(update)
+}
+
+func (st *sseStream) Context() context.Context { return st.ctx }
+
+func (st *sseStream) SendMsg(m any) error {
+	update, ok := m.(*pb.LeaderboardUpdate)
+	if !ok {
+		return fmt.Errorf("unexpected stream message %T", m)
+	}
+	return st.Send(update)
+}
+
+// SSE has neither headers nor trailers of its own, nor messages from the client
+func (st *sseStream) SetHeader(metadata.MD) error  { return nil }
+func (st *sseStream) SendHeader(metadata.MD) error { return nil }
+func (st *sseStream) SetTrailer(metadata.MD)       {}
+func (st *sseStream) RecvMsg(any) error            { return errNoClientMessages }
+
+// toStreamEvent converts a leaderboard update to its JSON form
+func toStreamEvent(update *pb.LeaderboardUpdate) StreamEvent {
+	ev := StreamEvent{
+		Kind:         update.Kind.String(),
+		PreviousTier: update.PreviousTier,
+		ServerTime:   update.ServerTime,
+		Seq:          update.Seq,
+	}
+	if update.Kind == pb.LeaderboardUpdate_RANK_CHANGED {
+		ev.OldRank, ev.NewRank = &update.OldRank, &update.NewRank
+	}
+	for _, e := range update.Snapshot {
+		ev.Snapshot = append(ev.Snapshot, toTopScoreEntry(e))
+	}
+	if update.Changed != nil {
+		changed := toTopScoreEntry(update.Changed)
+		ev.Changed = &changed
+	}
+	return ev
+}
+
+// toTopScoreEntry converts a streamed entry to its JSON form
+func toTopScoreEntry(e *pb.ScoreEntry) TopScoreEntry {
+	entry := TopScoreEntry{
+		LeaderboardID:  e.LeaderboardId,
+		PlayerName:     e.PlayerName,
+		Score:          &e.Score,
+		UpdatedAt:      e.UpdatedAt,
+		Tier:           e.Tier,
+		AchievedAt:     e.AchievedAt,
+		Metadata:       e.Metadata,
+		SecondaryScore: e.SecondaryScore,
+	}
+	if p := e.Profile; p != nil {
+		entry.Profile = &ProfileResponse{
+			PlayerName:  p.PlayerName,
+			DisplayName: p.DisplayName,
+			CountryCode: p.CountryCode,
+			AvatarURL:   p.AvatarUrl,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+		}
+	}
+	return entry
+}
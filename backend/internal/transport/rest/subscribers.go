@@ -0,0 +1,209 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	pb "github.com/yourorg/leaderboard/gen/leaderboard/v1"
+)
+
+// SubscriberResponse describes an active stream subscriber
+type SubscriberResponse struct {
+	ID            uint64 `json:"id" example:"17"`
+	LeaderboardID string `json:"leaderboard_id" example:"global"`
+	Stream        string `json:"stream" example:"StreamLeaderboard" enums:"StreamLeaderboard,StreamBoardChanges,SubscribeLeaderboard,StreamPlayer"`
+	Transport     string `json:"transport" example:"grpc" enums:"grpc,rest"`
+	PeerAddress   string `json:"peer_address,omitempty" example:"203.0.113.7"` // absent when unknown
+	ConnectedAt   string `json:"connected_at" example:"2025-01-15T10:30:00Z"`
+	Limit         int32  `json:"limit" example:"10"`                    // current top N, 0 for player streams
+	PlayerName    string `json:"player_name,omitempty" example:"Alice"` // StreamPlayer only
+	Delivered     int64  `json:"delivered" example:"1234"`              // updates queued to the stream
+	Dropped       int64  `json:"dropped" example:"0"`                   // updates dropped because the stream's queue was full
+}
+
+// listSubscribers godoc
+//
+//	@Summary		List stream subscribers
+//	@Description	List the active stream subscribers of this server instance, oldest first: gRPC streams and
+//	@Description	SSE endpoints alike, with their board, top N, client address and how many updates were
+//	@Description	delivered to them or dropped because they did not keep up.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			leaderboard_id	query		string					false	"Only the subscribers of this board (default every board)"	maxlength(64)
+//	@Success		200				{array}		SubscriberResponse		"Active subscribers"
+//	@Failure		401				{object}	ErrorResponse			"Missing or wrong admin token"
+//	@Failure		403				{object}	ErrorResponse			"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		503				{object}	ErrorResponse			"Streaming unavailable"
+//	@Router			/admin/subscribers [get]
+func (s *Server) listSubscribers(c echo.Context) error {
+	if s.streamer == nil {
+		return streamUnavailable(c)
+	}
+	subs := s.streamer.Subscribers(c.QueryParam("leaderboard_id"))
+	resp := make([]SubscriberResponse, len(subs))
+	for i, sub := range subs {
+		resp[i] = SubscriberResponse{
+			ID:            sub.Id,
+			LeaderboardID: sub.LeaderboardId,
+			Stream:        sub.Stream,
+			Transport:     sub.Transport,
+			PeerAddress:   sub.PeerAddress,
+			ConnectedAt:   sub.ConnectedAt,
+			Limit:         sub.Limit,
+			PlayerName:    sub.PlayerName,
+			Delivered:     sub.Delivered,
+			Dropped:       sub.Dropped,
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DisconnectRequest represents the request body of POST /admin/subscribers/disconnect.
+// Set exactly one field.
+type DisconnectRequest struct {
+	ID          uint64 `json:"id,omitempty" example:"17"`                    // Subscriber id from GET /admin/subscribers
+	PeerAddress string `json:"peer_address,omitempty" example:"203.0.113.7"` // Every stream of this client address
+}
+
+// DisconnectResponse reports how many streams were ended
+type DisconnectResponse struct {
+	Disconnected int32 `json:"disconnected" example:"1"`
+}
+
+// disconnectSubscribers godoc
+//
+//	@Summary		Disconnect stream subscribers
+//	@Description	End a stream of this server instance by id, or every stream of a client address. gRPC streams fail
+//	@Description	with ABORTED (reason STREAM_DISCONNECTED) and SSE streams end. Clients may reconnect: set a stream
+//	@Description	quota to keep an abusive address out.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			request	body		DisconnectRequest	true	"Stream to disconnect"
+//	@Success		200		{object}	DisconnectResponse	"Streams disconnected"
+//	@Failure		400		{object}	ErrorResponse		"Validation error"
+//	@Failure		401		{object}	ErrorResponse		"Missing or wrong admin token"
+//	@Failure		403		{object}	ErrorResponse		"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		404		{object}	ErrorResponse		"No active stream matches"
+//	@Failure		503		{object}	ErrorResponse		"Streaming unavailable"
+//	@Router			/admin/subscribers/disconnect [post]
+func (s *Server) disconnectSubscribers(c echo.Context) error {
+	var req DisconnectRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "invalid request body",
+		})
+	}
+	if s.streamer == nil {
+		return streamUnavailable(c)
+	}
+
+	n, err := s.streamer.Disconnect(req.ID, req.PeerAddress)
+	if err != nil {
+		return s.handleStreamError(c, err)
+	}
+	return c.JSON(http.StatusOK, DisconnectResponse{Disconnected: int32(n)})
+}
+
+// StreamQuotaRequest represents the request body of PUT /admin/stream-quotas/{peer_address}
+type StreamQuotaRequest struct {
+	MaxStreams int32 `json:"max_streams" example:"5" minimum:"0" maximum:"100000"` // 0 blocks the address
+}
+
+// StreamQuotaResponse is the stream quota of a client address
+type StreamQuotaResponse struct {
+	PeerAddress   string `json:"peer_address" example:"203.0.113.7"`
+	MaxStreams    int32  `json:"max_streams" example:"5"`
+	ActiveStreams int32  `json:"active_streams" example:"2"` // streams the address holds now
+}
+
+func toStreamQuotaResponse(q *pb.StreamQuota) StreamQuotaResponse {
+	return StreamQuotaResponse{PeerAddress: q.PeerAddress, MaxStreams: q.MaxStreams, ActiveStreams: q.ActiveStreams}
+}
+
+// listStreamQuotas godoc
+//
+//	@Summary		List stream quotas
+//	@Description	List the stream quotas of client addresses on this server instance, by address.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		AdminToken
+//	@Success		200	{array}		StreamQuotaResponse	"Stream quotas"
+//	@Failure		401	{object}	ErrorResponse		"Missing or wrong admin token"
+//	@Failure		403	{object}	ErrorResponse		"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		503	{object}	ErrorResponse		"Streaming unavailable"
+//	@Router			/admin/stream-quotas [get]
+func (s *Server) listStreamQuotas(c echo.Context) error {
+	if s.streamer == nil {
+		return streamUnavailable(c)
+	}
+	quotas := s.streamer.StreamQuotas()
+	resp := make([]StreamQuotaResponse, len(quotas))
+	for i, q := range quotas {
+		resp[i] = toStreamQuotaResponse(q)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// setStreamQuota godoc
+//
+//	@Summary		Set the stream quota of a client address
+//	@Description	Cap the streams a client address may hold on this server instance; 0 blocks it. Streams over
+//	@Description	the quota fail with 503 over SSE and RESOURCE_EXHAUSTED (reason STREAM_QUOTA_EXCEEDED) over gRPC.
+//	@Description	Streams already open are kept. Quotas are lost on restart and not shared between replicas.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		AdminToken
+//	@Param			peer_address	path		string				true	"IPv4 or IPv6 client address"
+//	@Param			request			body		StreamQuotaRequest	true	"Quota"
+//	@Success		200				{object}	StreamQuotaResponse	"Quota set"
+//	@Failure		400				{object}	ErrorResponse		"Validation error"
+//	@Failure		401				{object}	ErrorResponse		"Missing or wrong admin token"
+//	@Failure		403				{object}	ErrorResponse		"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		503				{object}	ErrorResponse		"Streaming unavailable"
+//	@Router			/admin/stream-quotas/{peer_address} [put]
+func (s *Server) setStreamQuota(c echo.Context) error {
+	var req StreamQuotaRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "bad_request",
+			Message: "invalid request body",
+		})
+	}
+	if s.streamer == nil {
+		return streamUnavailable(c)
+	}
+
+	quota, err := s.streamer.SetPeerQuota(c.Param("peer_address"), req.MaxStreams)
+	if err != nil {
+		return s.handleStreamError(c, err)
+	}
+	return c.JSON(http.StatusOK, toStreamQuotaResponse(quota))
+}
+
+// removeStreamQuota godoc
+//
+//	@Summary		Lift the stream quota of a client address
+//	@Description	Lift the stream quota of a client address; succeeds when it has none.
+//	@Tags			Admin
+//	@Security		AdminToken
+//	@Param			peer_address	path	string	true	"IPv4 or IPv6 client address"
+//	@Success		204				"Quota lifted"
+//	@Failure		400				{object}	ErrorResponse	"Validation error"
+//	@Failure		401				{object}	ErrorResponse	"Missing or wrong admin token"
+//	@Failure		403				{object}	ErrorResponse	"Admin operations disabled (ADMIN_TOKEN unset)"
+//	@Failure		503				{object}	ErrorResponse	"Streaming unavailable"
+//	@Router			/admin/stream-quotas/{peer_address} [delete]
+func (s *Server) removeStreamQuota(c echo.Context) error {
+	if s.streamer == nil {
+		return streamUnavailable(c)
+	}
+	if err := s.streamer.RemovePeerQuota(c.Param("peer_address")); err != nil {
+		return s.handleStreamError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
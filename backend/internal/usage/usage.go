@@ -0,0 +1,267 @@
+// Package usage keeps rolling per-API-key request statistics, so operators can
+// tell which game build or partner integration is behind a traffic spike.
+//
+// Both transports record every request carrying an API key (X-Api-Key) with its
+// method and outcome. Counts are kept in memory per server, in buckets covering a
+// rolling window (one hour by default): a key's usage is what this server saw in
+// the last window. Keys are identified by their fingerprint (requestctx.KeyID),
+// never by the raw key.
+package usage
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourorg/leaderboard/internal/metrics"
+)
+
+// Defaults of Config fields left zero
+const (
+	DefaultWindow     = time.Hour
+	DefaultBuckets    = 60
+	DefaultMaxKeys    = 10_000
+	DefaultMaxMethods = 64
+)
+
+// Config configures a Tracker
+type Config struct {
+	Window     time.Duration // rolling window of the statistics
+	Buckets    int           // buckets of the window; usage ages out one bucket at a time
+	MaxKeys    int           // keys tracked at once; requests of further keys are not tracked
+	MaxMethods int           // methods tracked per key; further ones are counted as OtherMethod
+}
+
+// OtherMethod groups the methods of a key past Config.MaxMethods
+const OtherMethod = "other"
+
+// Tracker records per-key request statistics. It is safe for concurrent use.
+type Tracker struct {
+	cfg   Config
+	width time.Duration // of a bucket
+	now   func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*keyStats
+	lastSweep int64 // bucket of the last sweep of idle keys
+}
+
+// keyStats is a ring of buckets; a bucket is reused once its slot comes round again
+type keyStats struct {
+	buckets  []bucket
+	lastSeen time.Time
+}
+
+type bucket struct {
+	epoch    int64 // bucket number since the Unix epoch; stale when older than the window
+	requests int64
+	errors   int64
+	methods  map[string]*count
+}
+
+type count struct {
+	requests int64
+	errors   int64
+}
+
+// New creates a tracker
+func New(cfg Config) *Tracker {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = DefaultBuckets
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = DefaultMaxKeys
+	}
+	if cfg.MaxMethods <= 0 {
+		cfg.MaxMethods = DefaultMaxMethods
+	}
+	return &Tracker{
+		cfg:   cfg,
+		width: cfg.Window / time.Duration(cfg.Buckets),
+		now:   time.Now,
+		keys:  make(map[string]*keyStats),
+	}
+}
+
+// Record counts a request of a key. failed marks requests answered with an error.
+func (t *Tracker) Record(keyID, method string, failed bool) {
+	now := t.now()
+	epoch := t.epoch(now)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if epoch != t.lastSweep {
+		t.sweep(epoch)
+	}
+	ks := t.keys[keyID]
+	if ks == nil {
+		if len(t.keys) >= t.cfg.MaxKeys {
+			metrics.UsageUntracked.Inc()
+			return
+		}
+		ks = &keyStats{buckets: make([]bucket, t.cfg.Buckets)}
+		t.keys[keyID] = ks
+	}
+	ks.lastSeen = now
+
+	b := &ks.buckets[epoch%int64(len(ks.buckets))]
+	if b.epoch != epoch {
+		*b = bucket{epoch: epoch, methods: make(map[string]*count)}
+	}
+	c := b.methods[method]
+	if c == nil {
+		if len(b.methods) >= t.cfg.MaxMethods {
+			method = OtherMethod
+		}
+		if c = b.methods[method]; c == nil {
+			c = &count{}
+			b.methods[method] = c
+		}
+	}
+	b.requests++
+	c.requests++
+	if failed {
+		b.errors++
+		c.errors++
+	}
+}
+
+// sweep forgets keys without requests in the window
+func (t *Tracker) sweep(epoch int64) {
+	t.lastSweep = epoch
+	for id, ks := range t.keys {
+		if t.epoch(ks.lastSeen) <= epoch-int64(t.cfg.Buckets) {
+			delete(t.keys, id)
+		}
+	}
+}
+
+func (t *Tracker) epoch(at time.Time) int64 {
+	return at.UnixNano() / int64(t.width)
+}
+
+// MethodUsage is the usage of a key for one method
+type MethodUsage struct {
+	Method    string
+	Requests  int64
+	Errors    int64
+	ErrorRate float64
+}
+
+// Point is the usage of a key over one bucket
+type Point struct {
+	Start    time.Time
+	Requests int64
+	Errors   int64
+}
+
+// Report is the usage of a key over the window
+type Report struct {
+	KeyID     string
+	Window    time.Duration
+	Requests  int64
+	Errors    int64
+	ErrorRate float64
+	LastSeen  time.Time
+	Methods   []MethodUsage // most requested first
+	Series    []Point       // every bucket of the window, oldest first; nil in summaries
+}
+
+// Usage returns the usage of a key, or false when it made no request in the window
+func (t *Tracker) Usage(keyID string, topMethods int) (*Report, bool) {
+	epoch := t.epoch(t.now())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ks := t.keys[keyID]
+	if ks == nil {
+		return nil, false
+	}
+	r := t.report(keyID, ks, epoch, topMethods)
+	if r.Requests == 0 {
+		return nil, false
+	}
+
+	r.Series = make([]Point, 0, t.cfg.Buckets)
+	for e := epoch - int64(t.cfg.Buckets) + 1; e <= epoch; e++ {
+		p := Point{Start: time.Unix(0, e*int64(t.width)).UTC()}
+		if b := ks.buckets[e%int64(len(ks.buckets))]; b.epoch == e {
+			p.Requests, p.Errors = b.requests, b.errors
+		}
+		r.Series = append(r.Series, p)
+	}
+	return r, true
+}
+
+// Top returns the usage of the n keys with the most requests in the window,
+// without their series
+func (t *Tracker) Top(n, topMethods int) []Report {
+	epoch := t.epoch(t.now())
+
+	t.mu.Lock()
+	var reports []Report
+	for id, ks := range t.keys {
+		if r := t.report(id, ks, epoch, topMethods); r.Requests > 0 {
+			reports = append(reports, *r)
+		}
+	}
+	t.mu.Unlock()
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Requests != reports[j].Requests {
+			return reports[i].Requests > reports[j].Requests
+		}
+		return reports[i].KeyID < reports[j].KeyID
+	})
+	if len(reports) > n {
+		reports = reports[:n]
+	}
+	return reports
+}
+
+// report sums the buckets of a key still in the window
+func (t *Tracker) report(keyID string, ks *keyStats, epoch int64, topMethods int) *Report {
+	r := &Report{KeyID: keyID, Window: t.cfg.Window, LastSeen: ks.lastSeen.UTC()}
+	methods := make(map[string]*count)
+	for _, b := range ks.buckets {
+		if b.epoch <= epoch-int64(t.cfg.Buckets) || b.epoch > epoch {
+			continue
+		}
+		r.Requests += b.requests
+		r.Errors += b.errors
+		for m, c := range b.methods {
+			sum := methods[m]
+			if sum == nil {
+				sum = &count{}
+				methods[m] = sum
+			}
+			sum.requests += c.requests
+			sum.errors += c.errors
+		}
+	}
+	r.ErrorRate = rate(r.Errors, r.Requests)
+
+	for m, c := range methods {
+		r.Methods = append(r.Methods, MethodUsage{Method: m, Requests: c.requests, Errors: c.errors, ErrorRate: rate(c.errors, c.requests)})
+	}
+	sort.Slice(r.Methods, func(i, j int) bool {
+		if r.Methods[i].Requests != r.Methods[j].Requests {
+			return r.Methods[i].Requests > r.Methods[j].Requests
+		}
+		return r.Methods[i].Method < r.Methods[j].Method
+	})
+	if len(r.Methods) > topMethods {
+		r.Methods = r.Methods[:topMethods]
+	}
+	return r
+}
+
+func rate(errors, requests int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(errors) / float64(requests)
+}
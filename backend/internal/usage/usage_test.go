@@ -0,0 +1,84 @@
+package usage
+
+import (
+	"testing"
+	"time"
+)
+
+// clock is a settable time source
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newTestTracker(cfg Config) (*Tracker, *clock) {
+	c := &clock{t: time.Date(2025, 1, 15, 10, 0, 30, 0, time.UTC)}
+	t := New(cfg)
+	t.now = c.now
+	return t, c
+}
+
+func TestTrackerWindow(t *testing.T) {
+	tr, c := newTestTracker(Config{Window: time.Hour, Buckets: 60})
+
+	for range 3 {
+		tr.Record("k_a", "SubmitScore", false)
+	}
+	tr.Record("k_a", "GetTopScores", true)
+	c.t = c.t.Add(30 * time.Minute)
+	tr.Record("k_a", "SubmitScore", true)
+
+	r, ok := tr.Usage("k_a", 10)
+	if !ok {
+		t.Fatal("no usage for k_a")
+	}
+	if r.Requests != 5 || r.Errors != 2 || r.ErrorRate != 0.4 {
+		t.Errorf("usage = %d requests, %d errors, rate %v; want 5, 2, 0.4", r.Requests, r.Errors, r.ErrorRate)
+	}
+	if len(r.Methods) != 2 || r.Methods[0].Method != "SubmitScore" || r.Methods[0].Requests != 4 || r.Methods[0].Errors != 1 {
+		t.Errorf("methods = %+v, want SubmitScore first with 4 requests", r.Methods)
+	}
+	if len(r.Series) != 60 || r.Series[29].Requests != 4 || r.Series[59].Requests != 1 {
+		t.Errorf("series = %+v, want 4 requests 30 buckets ago and 1 now", r.Series)
+	}
+	if !r.Series[59].Start.Equal(time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("last bucket starts at %v", r.Series[59].Start)
+	}
+
+	// Requests age out of the window one bucket at a time
+	c.t = c.t.Add(31 * time.Minute)
+	if r, _ := tr.Usage("k_a", 10); r.Requests != 1 {
+		t.Errorf("after the first requests left the window: %d requests, want 1", r.Requests)
+	}
+	c.t = c.t.Add(time.Hour)
+	if _, ok := tr.Usage("k_a", 10); ok {
+		t.Error("usage reported for a key idle for a whole window")
+	}
+	tr.Record("k_b", "SubmitScore", false)
+	if len(tr.keys) != 1 {
+		t.Errorf("%d keys tracked, want idle k_a forgotten", len(tr.keys))
+	}
+}
+
+func TestTrackerLimits(t *testing.T) {
+	tr, _ := newTestTracker(Config{MaxKeys: 2, MaxMethods: 2})
+
+	for _, m := range []string{"A", "B", "C", "D"} {
+		tr.Record("k_a", m, false)
+	}
+	r, _ := tr.Usage("k_a", 10)
+	if len(r.Methods) != 3 || r.Methods[0].Method != OtherMethod || r.Methods[0].Requests != 2 {
+		t.Errorf("methods = %+v, want C and D counted as %s", r.Methods, OtherMethod)
+	}
+
+	tr.Record("k_b", "A", false)
+	tr.Record("k_b", "A", false)
+	tr.Record("k_c", "A", false)
+	if _, ok := tr.Usage("k_c", 10); ok {
+		t.Error("key past MaxKeys tracked")
+	}
+
+	top := tr.Top(1, 1)
+	if len(top) != 1 || top[0].KeyID != "k_a" || len(top[0].Methods) != 1 || top[0].Series != nil {
+		t.Errorf("top = %+v, want k_a alone with its top method and no series", top)
+	}
+}
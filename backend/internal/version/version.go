@@ -0,0 +1,57 @@
+// Package version reports the version of the running build.
+package version
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// Version is set at build time:
+//
+//	go build -ldflags "-X github.com/yourorg/leaderboard/internal/version.Version=v1.4.0" ./cmd/server
+//
+// When left empty, String falls back to the VCS revision recorded by the Go toolchain.
+var Version string
+
+var (
+	once     sync.Once
+	resolved string
+)
+
+// String returns the version of the build: Version when set, else the short VCS
+// revision ("+dirty" when built from a modified tree), else "dev"
+func String() string {
+	once.Do(func() {
+		resolved = resolve()
+	})
+	return resolved
+}
+
+func resolve() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if revision == "" {
+		return "dev"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified == "true" {
+		revision += "+dirty"
+	}
+	return revision
+}
@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/metrics"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// Delivery statuses (webhook_deliveries.status)
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Defaults of Config fields left zero
+const (
+	DefaultTimeout      = 5 * time.Second
+	DefaultMaxAttempts  = 8
+	DefaultRetryBase    = 10 * time.Second
+	DefaultRetryMax     = time.Hour
+	DefaultPollInterval = time.Second
+	DefaultConcurrency  = 4
+	DefaultRetention    = 7 * 24 * time.Hour
+	DefaultContentType  = "application/json"
+)
+
+// maxErrorLength bounds the error text recorded for an attempt
+const maxErrorLength = 500
+
+// Config configures a Deliverer
+type Config struct {
+	Timeout      time.Duration // per request
+	MaxAttempts  int           // attempts before a delivery is marked failed
+	RetryBase    time.Duration // delay before the first retry, doubled for each next one
+	RetryMax     time.Duration // longest delay between retries
+	PollInterval time.Duration // how often due deliveries are claimed
+	Concurrency  int           // deliveries in flight per server
+	Retention    time.Duration // how long finished deliveries are logged
+	ContentType  string        // of the bodies, as encoded by the Notifier
+}
+
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultMaxAttempts
+	}
+	if c.RetryBase <= 0 {
+		c.RetryBase = DefaultRetryBase
+	}
+	if c.RetryMax <= 0 {
+		c.RetryMax = DefaultRetryMax
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = DefaultConcurrency
+	}
+	if c.Retention <= 0 {
+		c.Retention = DefaultRetention
+	}
+	if c.ContentType == "" {
+		c.ContentType = DefaultContentType
+	}
+	return c
+}
+
+// Deliverer POSTs queued deliveries and records their outcome
+type Deliverer struct {
+	store  Store
+	client *http.Client
+	cfg    Config
+	logger *zerolog.Logger
+}
+
+// NewDeliverer creates a deliverer of the deliveries queued in st
+func NewDeliverer(st Store, cfg Config, logger *zerolog.Logger) *Deliverer {
+	cfg = cfg.withDefaults()
+	return &Deliverer{
+		store: st,
+		// Receivers must answer directly: a redirect could point anywhere
+		client: &http.Client{
+			Timeout:       cfg.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Run delivers due deliveries until ctx is cancelled, and prunes the delivery log
+func (d *Deliverer) Run(ctx context.Context) {
+	d.logger.Info().Int("concurrency", d.cfg.Concurrency).Msg("📮 webhook deliverer started")
+	poll := time.NewTicker(d.cfg.PollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info().Msg("webhook deliverer stopped")
+			return
+		case <-poll.C:
+			// A full batch means more deliveries are due
+			for ctx.Err() == nil {
+				if d.deliverDue(ctx) < d.cfg.Concurrency {
+					break
+				}
+			}
+		case <-prune.C:
+			if n, err := d.store.PruneWebhookDeliveries(ctx, d.cfg.Retention.Seconds()); err != nil {
+				d.logger.Error().Err(err).Msg("failed to prune webhook deliveries")
+			} else if n > 0 {
+				d.logger.Debug().Int64("deleted", n).Msg("🧹 webhook delivery log pruned")
+			}
+		}
+	}
+}
+
+// deliverDue claims and delivers a batch of due deliveries, returning its size
+func (d *Deliverer) deliverDue(ctx context.Context) int {
+	rows, err := d.store.ClaimWebhookDeliveries(ctx, store.ClaimWebhookDeliveriesParams{
+		// Deliveries of a crashed server come back once the lease is over
+		LeaseSeconds:  (2*d.cfg.Timeout + time.Minute).Seconds(),
+		MaxDeliveries: int32(d.cfg.Concurrency),
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			d.logger.Error().Err(err).Msg("failed to claim webhook deliveries")
+		}
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, row := range rows {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(row)
+		}()
+	}
+	wg.Wait()
+	return len(rows)
+}
+
+// deliver makes one attempt of a delivery and records it. It is not cancelled by
+// shutdown: the attempt is short, and an unrecorded one would be sent again.
+func (d *Deliverer) deliver(row store.ClaimWebhookDeliveriesRow) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.cfg.Timeout+5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	code, err := d.post(ctx, row)
+	attempt := int(row.Attempts) + 1
+	log := d.logger.With().
+		Int64("delivery_id", row.ID).
+		Int64("webhook_id", row.WebhookID).
+		Str("event", row.EventType).
+		Int("attempt", attempt).
+		Dur("duration", time.Since(start)).
+		Logger()
+
+	params := store.CompleteWebhookDeliveryParams{ID: row.ID, Status: StatusDelivered, NextAttemptAt: timestamptz(time.Now())}
+	if code != 0 {
+		params.LastStatusCode = pgtype.Int4{Int32: int32(code), Valid: true}
+	}
+	switch {
+	case err == nil:
+		metrics.WebhookDeliveries.WithLabelValues("delivered").Inc()
+		log.Debug().Int("status", code).Msg("✅ webhook delivered")
+	case attempt >= d.cfg.MaxAttempts:
+		params.Status = StatusFailed
+		params.LastError = pgtype.Text{String: truncate(err.Error()), Valid: true}
+		metrics.WebhookDeliveries.WithLabelValues("failed").Inc()
+		log.Error().Err(err).Msg("❌ webhook delivery failed, giving up")
+	default:
+		params.Status = StatusPending
+		params.NextAttemptAt = timestamptz(time.Now().Add(d.backoff(attempt)))
+		params.LastError = pgtype.Text{String: truncate(err.Error()), Valid: true}
+		metrics.WebhookDeliveries.WithLabelValues("retry").Inc()
+		log.Warn().Err(err).Time("next_attempt_at", params.NextAttemptAt.Time).Msg("⚠️  webhook delivery failed, will retry")
+	}
+
+	if err := d.store.CompleteWebhookDelivery(ctx, params); err != nil {
+		log.Error().Err(err).Msg("failed to record webhook delivery attempt")
+	}
+}
+
+// post sends a delivery; any status but 2xx is an error
+func (d *Deliverer) post(ctx context.Context, row store.ClaimWebhookDeliveriesRow) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, row.Url, bytes.NewReader(row.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", d.cfg.ContentType)
+	req.Header.Set("User-Agent", "leaderboard-webhooks/1")
+	req.Header.Set(HeaderEvent, row.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(row.ID, 10))
+	req.Header.Set(HeaderSignature, Signature(row.Secret, time.Now(), row.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // lets the connection be reused
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the attempt after attempt: RetryBase doubled
+// per attempt, capped at RetryMax, with up to 20% jitter so retries of a receiver
+// that was down do not all come back at once
+func (d *Deliverer) backoff(attempt int) time.Duration {
+	delay := d.cfg.RetryBase
+	for i := 1; i < attempt && delay < d.cfg.RetryMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, d.cfg.RetryMax)
+	return delay - time.Duration(rand.Int64N(int64(delay)/5+1))
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorLength {
+		return s[:maxErrorLength]
+	}
+	return s
+}
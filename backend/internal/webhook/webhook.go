@@ -0,0 +1,366 @@
+// Package webhook notifies external services of leaderboard changes over HTTP.
+//
+// Admins register webhooks (URL, event types, optional board) through the REST API.
+// The Notifier turns the score changes of the dispatcher into events and queues a
+// delivery per subscribed webhook in webhook_deliveries; the Deliverer POSTs them,
+// signed with the webhook's secret, and retries failures with exponential backoff.
+//
+// Every server reading changes from the database queues the same events; the queue
+// keeps one delivery per webhook, event type and change, and servers claim due
+// deliveries with row locks, so each event is POSTed once whatever the number of
+// servers. Delivery is at least once: a receiver may see an event twice when a
+// server stops between the POST and recording its outcome, and should use the id.
+//
+// Bodies are encoded by a serializer of the events package: the JSON format sends
+// the Event as is, the CloudEvents format wraps it in a CloudEvents envelope.
+//
+// Server lifecycle events (lifecycle.Bus) are queued by RunLifecycle as events of
+// type "lifecycle.<type>", in the v1 lifecycle schema of the events package. Each
+// server queues its own: a daily rollover seen by every server is sent by each.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/events"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// Event types
+const (
+	// EventHighScore is sent when a player's best score on a board is set or improved
+	EventHighScore = "score.high_score"
+
+	// EventLeaderChanged is sent when another player takes the first place of a board
+	EventLeaderChanged = "leaderboard.leader_changed"
+
+	// EventScoreDeleted is sent when an admin removes a player's score
+	EventScoreDeleted = "score.deleted"
+)
+
+// EventLifecyclePrefix prefixes the lifecycle event types, e.g. "lifecycle.server_started"
+const EventLifecyclePrefix = "lifecycle."
+
+// EventTypes lists the event types a webhook may subscribe to
+var EventTypes = append([]string{EventHighScore, EventLeaderChanged, EventScoreDeleted}, lifecycleEventTypes()...)
+
+func lifecycleEventTypes() []string {
+	types := make([]string, len(lifecycle.Types))
+	for i, t := range lifecycle.Types {
+		types[i] = EventLifecyclePrefix + string(t)
+	}
+	return types
+}
+
+// ValidEventType reports whether t is a known event type
+func ValidEventType(t string) bool {
+	for _, et := range EventTypes {
+		if t == et {
+			return true
+		}
+	}
+	return false
+}
+
+// Request headers of a delivery
+const (
+	HeaderEvent     = "X-Leaderboard-Event"
+	HeaderDelivery  = "X-Leaderboard-Delivery"
+	HeaderSignature = "X-Leaderboard-Signature"
+)
+
+// Event is the body of a delivery in the JSON format, and the data of its CloudEvents envelope
+type Event struct {
+	ID         string    `json:"id"` // "<change id>.<type>", stable across retries and servers
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Replayed   bool      `json:"replayed,omitempty"` // re-sent by an admin replay of the outbox
+	Data       any       `json:"data"`
+}
+
+// Entry is a leaderboard entry in event data
+type Entry struct {
+	PlayerName string            `json:"player_name"`
+	Score      int64             `json:"score"`
+	AchievedAt time.Time         `json:"achieved_at"`
+	Metadata   map[string]string `json:"metadata,omitempty"` // attributes of the score, if any
+
+	SecondaryScore int64 `json:"secondary_score,omitempty"` // tiebreaker of the score, if any
+}
+
+// ScoreData is the data of score.high_score and score.deleted events
+type ScoreData struct {
+	LeaderboardID string `json:"leaderboard_id"`
+	Entry
+}
+
+// LeaderData is the data of leaderboard.leader_changed events
+type LeaderData struct {
+	LeaderboardID  string `json:"leaderboard_id"`
+	Leader         Entry  `json:"leader"`
+	PreviousLeader *Entry `json:"previous_leader"` // null when the board was empty
+}
+
+// Signature returns the X-Leaderboard-Signature header of a body sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">". Receivers
+// recompute it with the webhook secret and reject old timestamps to stop replays.
+func Signature(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header against body, accepting timestamps up to
+// tolerance away from now
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) bool {
+	var ts string
+	for _, part := range strings.Split(header, ",") {
+		if v, ok := strings.CutPrefix(part, "t="); ok {
+			ts = v
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	t := time.Unix(unix, 0)
+	if d := now.Sub(t); d > tolerance || d < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(Signature(secret, t, body)), []byte(header))
+}
+
+// Store is the storage used by the Notifier and the Deliverer (store.Store)
+type Store interface {
+	GetTopScores(ctx context.Context, arg store.GetTopScoresParams) ([]store.Score, error)
+	ListWebhooksForEvent(ctx context.Context, arg store.ListWebhooksForEventParams) ([]store.Webhook, error)
+	EnqueueWebhookDelivery(ctx context.Context, arg store.EnqueueWebhookDeliveryParams) (int64, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg store.ClaimWebhookDeliveriesParams) ([]store.ClaimWebhookDeliveriesRow, error)
+	CompleteWebhookDelivery(ctx context.Context, arg store.CompleteWebhookDeliveryParams) error
+	PruneWebhookDeliveries(ctx context.Context, retentionSeconds float64) (int64, error)
+}
+
+// Notifier queues webhook deliveries for the score changes it receives
+type Notifier struct {
+	store      Store
+	serializer events.Serializer // nil encodes events as JSON
+	logger     *zerolog.Logger
+
+	// leaders is the last known first place of each board whose leader changes
+	// are watched; a board is missing until a change of it has been seen
+	leaders map[string]*Entry
+}
+
+// NewNotifier creates a notifier queuing deliveries in st, encoded by serializer
+// (see events.SinkFormats); nil encodes them as JSON
+func NewNotifier(st Store, serializer events.Serializer, logger *zerolog.Logger) *Notifier {
+	return &Notifier{store: st, serializer: serializer, logger: logger, leaders: make(map[string]*Entry)}
+}
+
+// Run queues the events of changes until the channel is closed
+func (n *Notifier) Run(changes <-chan notify.ScoreChange) {
+	for change := range changes {
+		// Queue writes must not be cut short by shutdown: the change is not read again
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := n.handle(ctx, change); err != nil {
+			n.logger.Error().Err(err).Int64("change_id", change.ID).Msg("❌ failed to queue webhook deliveries")
+		}
+		cancel()
+	}
+	n.logger.Info().Msg("webhook notifier stopped")
+}
+
+// RunLifecycle queues the lifecycle events of the bus subscription until it is closed
+func (n *Notifier) RunLifecycle(sub <-chan lifecycle.Event) {
+	for event := range sub {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := n.queueLifecycle(ctx, event); err != nil {
+			n.logger.Error().Err(err).Str("type", string(event.Type)).Msg("❌ failed to queue webhook deliveries")
+		}
+		cancel()
+	}
+}
+
+// queueLifecycle queues a lifecycle event for the webhooks subscribed to it. Webhooks
+// of a board only receive the events of that board, e.g. season_rollover.
+// Lifecycle events have no change: their time in nanoseconds stands in for its id.
+func (n *Notifier) queueLifecycle(ctx context.Context, event lifecycle.Event) error {
+	eventType := EventLifecyclePrefix + string(event.Type)
+	hooks, err := n.webhooks(ctx, eventType, event.Attributes["leaderboard_id"])
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+	payload, err := events.MarshalLifecycle(n.serializer, event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	return n.enqueuePayload(ctx, hooks, event.Time.UnixNano(), false, eventType, payload)
+}
+
+func (n *Notifier) handle(ctx context.Context, change notify.ScoreChange) error {
+	if change.Op == notify.OpResync {
+		// The boards may have changed in any way: learn their leaders again
+		if change.LeaderboardID == "" {
+			clear(n.leaders)
+		} else {
+			delete(n.leaders, change.LeaderboardID)
+		}
+		return nil
+	}
+	if change.ID == 0 {
+		// Without an outbox id servers cannot agree on a single delivery
+		n.logger.Debug().Str("player", change.PlayerName).Msg("score change without id, no webhook event")
+		return nil
+	}
+
+	entry := Entry{PlayerName: change.PlayerName, Score: change.Score, AchievedAt: change.AchievedAt.UTC(), Metadata: change.Metadata,
+		SecondaryScore: change.SecondaryScore}
+	data := ScoreData{LeaderboardID: change.LeaderboardID, Entry: entry}
+	switch change.Op {
+	case "insert", "update":
+		if err := n.queue(ctx, change, EventHighScore, data); err != nil {
+			return err
+		}
+	case "delete":
+		if err := n.queue(ctx, change, EventScoreDeleted, data); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	// Leader changes are derived from the current board, which says nothing of the past
+	if change.Replayed {
+		return nil
+	}
+	return n.checkLeader(ctx, change)
+}
+
+// checkLeader queues a leader_changed event when the change moved another player
+// to the first place of its board
+func (n *Notifier) checkLeader(ctx context.Context, change notify.ScoreChange) error {
+	hooks, err := n.webhooks(ctx, EventLeaderChanged, change.LeaderboardID)
+	if err != nil || len(hooks) == 0 {
+		delete(n.leaders, change.LeaderboardID)
+		return err
+	}
+
+	// The primary: a replica may not have the change yet
+	top, err := n.store.GetTopScores(store.ReadPrimary(ctx), store.GetTopScoresParams{LeaderboardID: change.LeaderboardID, PageSize: 2})
+	if err != nil {
+		return fmt.Errorf("read leader: %w", err)
+	}
+	var leader, second *Entry
+	if len(top) > 0 {
+		leader = toEntry(top[0])
+	}
+	if len(top) > 1 {
+		second = toEntry(top[1])
+	}
+
+	previous, known := n.leaders[change.LeaderboardID]
+	n.leaders[change.LeaderboardID] = leader
+	if leader == nil {
+		return nil
+	}
+	if !known {
+		// A player inserted straight into the first place took it from the runner-up;
+		// otherwise the previous leader is unknown and this change only sets the baseline
+		if change.Op != "insert" || leader.PlayerName != change.PlayerName {
+			return nil
+		}
+		previous = second
+	} else if previous != nil && previous.PlayerName == leader.PlayerName {
+		return nil
+	}
+
+	data := LeaderData{LeaderboardID: change.LeaderboardID, Leader: *leader, PreviousLeader: previous}
+	return n.enqueue(ctx, hooks, change, EventLeaderChanged, data)
+}
+
+func toEntry(s store.Score) *Entry {
+	e := &Entry{PlayerName: s.PlayerName, Score: s.Score, AchievedAt: s.AchievedAt.Time.UTC(), SecondaryScore: s.SecondaryScore}
+	json.Unmarshal(s.Metadata, &e.Metadata) // written by the service: always an object of strings
+	return e
+}
+
+// queue queues an event for the webhooks subscribed to it
+func (n *Notifier) queue(ctx context.Context, change notify.ScoreChange, eventType string, data any) error {
+	hooks, err := n.webhooks(ctx, eventType, change.LeaderboardID)
+	if err != nil {
+		return err
+	}
+	return n.enqueue(ctx, hooks, change, eventType, data)
+}
+
+func (n *Notifier) webhooks(ctx context.Context, eventType, board string) ([]store.Webhook, error) {
+	hooks, err := n.store.ListWebhooksForEvent(ctx, store.ListWebhooksForEventParams{EventType: eventType, LeaderboardID: board})
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+func (n *Notifier) enqueue(ctx context.Context, hooks []store.Webhook, change notify.ScoreChange, eventType string, data any) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	payload, err := n.encode(Event{
+		ID:         strconv.FormatInt(change.ID, 10) + "." + eventType,
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Replayed:   change.Replayed,
+		Data:       data,
+	}, change.PlayerName)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	return n.enqueuePayload(ctx, hooks, change.ID, change.Replayed, eventType, payload)
+}
+
+func (n *Notifier) enqueuePayload(ctx context.Context, hooks []store.Webhook, changeID int64, replayed bool, eventType string, payload []byte) error {
+	for _, hook := range hooks {
+		queued, err := n.store.EnqueueWebhookDelivery(ctx, store.EnqueueWebhookDeliveryParams{
+			WebhookID: hook.ID,
+			EventType: eventType,
+			ChangeID:  changeID,
+			Replayed:  replayed,
+			Payload:   payload,
+		})
+		if err != nil {
+			return fmt.Errorf("queue delivery to webhook %d: %w", hook.ID, err)
+		}
+		if queued > 0 {
+			n.logger.Debug().Int64("webhook_id", hook.ID).Str("event", eventType).Int64("change_id", changeID).Msg("📮 webhook delivery queued")
+		}
+	}
+	return nil
+}
+
+// encode encodes the body of a delivery about player
+func (n *Notifier) encode(event Event, player string) ([]byte, error) {
+	if n.serializer == nil {
+		return json.Marshal(event)
+	}
+	return n.serializer.MarshalEvent(events.Event{
+		ID:      event.ID,
+		Type:    event.Type,
+		Time:    event.OccurredAt,
+		Subject: player,
+		Data:    event,
+	})
+}
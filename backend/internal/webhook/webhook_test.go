@@ -0,0 +1,358 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+	"github.com/yourorg/leaderboard/internal/events"
+	"github.com/yourorg/leaderboard/internal/lifecycle"
+	"github.com/yourorg/leaderboard/internal/notify"
+	"github.com/yourorg/leaderboard/internal/store"
+)
+
+// fakeStore keeps webhooks, a board and a delivery queue in memory
+type fakeStore struct {
+	mu         sync.Mutex
+	hooks      []store.Webhook
+	top        []store.Score // current board, best first
+	queued     []store.EnqueueWebhookDeliveryParams
+	claimable  []store.ClaimWebhookDeliveriesRow
+	completed  []store.CompleteWebhookDeliveryParams
+	deliveries map[[3]any]bool
+}
+
+func (f *fakeStore) GetTopScores(ctx context.Context, arg store.GetTopScoresParams) ([]store.Score, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.top[:min(len(f.top), int(arg.PageSize))], nil
+}
+
+func (f *fakeStore) ListWebhooksForEvent(ctx context.Context, arg store.ListWebhooksForEventParams) ([]store.Webhook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []store.Webhook
+	for _, h := range f.hooks {
+		for _, e := range h.Events {
+			if e == arg.EventType && (!h.LeaderboardID.Valid || h.LeaderboardID.String == arg.LeaderboardID) {
+				out = append(out, h)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) EnqueueWebhookDelivery(ctx context.Context, arg store.EnqueueWebhookDeliveryParams) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Mirrors the unique index of webhook_deliveries
+	key := [3]any{arg.WebhookID, arg.EventType, arg.ChangeID}
+	if !arg.Replayed && f.deliveries[key] {
+		return 0, nil
+	}
+	if f.deliveries == nil {
+		f.deliveries = make(map[[3]any]bool)
+	}
+	f.deliveries[key] = true
+	f.queued = append(f.queued, arg)
+	return 1, nil
+}
+
+func (f *fakeStore) ClaimWebhookDeliveries(ctx context.Context, arg store.ClaimWebhookDeliveriesParams) ([]store.ClaimWebhookDeliveriesRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rows := f.claimable
+	f.claimable = nil
+	return rows, nil
+}
+
+func (f *fakeStore) CompleteWebhookDelivery(ctx context.Context, arg store.CompleteWebhookDeliveryParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.completed = append(f.completed, arg)
+	return nil
+}
+
+func (f *fakeStore) PruneWebhookDeliveries(ctx context.Context, retentionSeconds float64) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeStore) setTop(names ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.top = nil
+	for i, name := range names {
+		f.top = append(f.top, store.Score{PlayerName: name, Score: int64(1000 - i), AchievedAt: pgtype.Timestamptz{Time: time.Unix(0, 0), Valid: true}})
+	}
+}
+
+// events returns the types of the queued deliveries and empties the queue
+func (f *fakeStore) events() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var types []string
+	for _, q := range f.queued {
+		types = append(types, q.EventType)
+	}
+	f.queued = nil
+	return types
+}
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"id":"1.score.high_score"}`)
+	now := time.Unix(1736936981, 0)
+	header := Signature("whsec_test", now, body)
+
+	if !Verify("whsec_test", header, body, 5*time.Minute, now.Add(time.Minute)) {
+		t.Fatalf("Verify(%q) = false", header)
+	}
+	for name, ok := range map[string]bool{
+		"wrong secret": Verify("whsec_other", header, body, 5*time.Minute, now),
+		"changed body": Verify("whsec_test", header, []byte(`{"id":"2.score.high_score"}`), 5*time.Minute, now),
+		"too old":      Verify("whsec_test", header, body, 5*time.Minute, now.Add(10*time.Minute)),
+		"malformed":    Verify("whsec_test", "v1=abc", body, 5*time.Minute, now),
+	} {
+		if ok {
+			t.Errorf("%s: Verify = true", name)
+		}
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	logger := zerolog.Nop()
+	st := &fakeStore{hooks: []store.Webhook{
+		{ID: 1, Events: []string{EventHighScore, EventLeaderChanged, EventScoreDeleted}},
+		{ID: 2, Events: []string{EventLeaderChanged}, LeaderboardID: pgtype.Text{String: "level-1", Valid: true}},
+	}}
+	n := NewNotifier(st, nil, &logger)
+	ctx := context.Background()
+	change := func(id int64, player, op string) notify.ScoreChange {
+		return notify.ScoreChange{ID: id, LeaderboardID: "global", PlayerName: player, Op: op}
+	}
+
+	// The first change of a board only learns its leader, unless it takes the first place
+	st.setTop("Alice", "Bob")
+	if err := n.handle(ctx, change(1, "Bob", "update")); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.events(); len(got) != 1 || got[0] != EventHighScore {
+		t.Fatalf("baseline events = %v, want a high score only", got)
+	}
+
+	st.setTop("Bob", "Alice")
+	if err := n.handle(ctx, change(2, "Bob", "update")); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.events(); len(got) != 2 || got[1] != EventLeaderChanged {
+		t.Fatalf("events = %v, want a high score and a leader change", got)
+	}
+
+	// Servers reading the same change queue it once
+	if err := n.handle(ctx, change(2, "Bob", "update")); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.events(); len(got) != 0 {
+		t.Errorf("duplicate change queued %v", got)
+	}
+
+	// Replayed changes are queued again, without leader changes
+	st.setTop("Alice", "Bob")
+	replayed := change(3, "Bob", "delete")
+	replayed.Replayed = true
+	if err := n.handle(ctx, replayed); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.events(); len(got) != 1 || got[0] != EventScoreDeleted {
+		t.Errorf("replayed events = %v, want a deletion only", got)
+	}
+
+	// A resync forgets the leader: Alice's return is not reported
+	if err := n.handle(ctx, notify.ScoreChange{LeaderboardID: "global", Op: notify.OpResync}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.handle(ctx, change(4, "Carol", "update")); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.events(); len(got) != 1 {
+		t.Errorf("events after resync = %v, want a high score only", got)
+	}
+
+	// A new player straight into the first place took it from the runner-up
+	n = NewNotifier(st, nil, &logger)
+	st.setTop("Dave", "Alice")
+	if err := n.handle(ctx, change(5, "Dave", "insert")); err != nil {
+		t.Fatal(err)
+	}
+	st.mu.Lock()
+	queued := st.queued
+	st.mu.Unlock()
+	if len(queued) != 2 || queued[1].EventType != EventLeaderChanged {
+		t.Fatalf("queued %+v, want a high score and a leader change", queued)
+	}
+	var event struct {
+		ID   string     `json:"id"`
+		Data LeaderData `json:"data"`
+	}
+	if err := json.Unmarshal(queued[1].Payload, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != "5."+EventLeaderChanged || event.Data.Leader.PlayerName != "Dave" || event.Data.PreviousLeader == nil || event.Data.PreviousLeader.PlayerName != "Alice" {
+		t.Errorf("leader change event = %+v", event)
+	}
+}
+
+func TestNotifierCloudEvents(t *testing.T) {
+	logger := zerolog.Nop()
+	st := &fakeStore{hooks: []store.Webhook{{ID: 1, Events: []string{EventHighScore}}}}
+	serializer, err := events.NewRegistry("/leaderboard").Get("cloudevents/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewNotifier(st, serializer, &logger)
+	change := notify.ScoreChange{ID: 9, LeaderboardID: "global", PlayerName: "Alice", Score: 500, Op: "insert"}
+	if err := n.handle(context.Background(), change); err != nil {
+		t.Fatal(err)
+	}
+
+	var envelope struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Source  string `json:"source"`
+		Subject string `json:"subject"`
+		Data    struct {
+			ID   string    `json:"id"`
+			Type string    `json:"type"`
+			Data ScoreData `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(st.queued[0].Payload, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.ID != "9."+EventHighScore || envelope.Type != "com.yourorg.leaderboard."+EventHighScore+".v1" || envelope.Source != "/leaderboard" ||
+		envelope.Subject != "Alice" || envelope.Data.ID != envelope.ID || envelope.Data.Data.Score != 500 {
+		t.Errorf("envelope = %+v", envelope)
+	}
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	logger := zerolog.Nop()
+	var (
+		mu   sync.Mutex
+		got  *http.Request
+		body []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	st := &fakeStore{hooks: []store.Webhook{
+		{ID: 1, Url: srv.URL, Secret: "whsec_test", Events: []string{"lifecycle.server_started"}},
+		{ID: 2, Events: []string{"lifecycle.server_started"}, LeaderboardID: pgtype.Text{String: "level-1", Valid: true}},
+		{ID: 3, Events: []string{EventHighScore}},
+	}}
+	bus := lifecycle.NewBus(&logger)
+	sub := bus.Subscribe()
+	done := make(chan struct{})
+	go func() {
+		NewNotifier(st, nil, &logger).RunLifecycle(sub)
+		close(done)
+	}()
+	bus.Publish(lifecycle.ServerStarted, map[string]string{"db_driver": "postgres"})
+	bus.Close()
+	<-done
+
+	// Server events go to the webhooks of every board only
+	if len(st.queued) != 1 || st.queued[0].WebhookID != 1 || st.queued[0].EventType != "lifecycle.server_started" || st.queued[0].ChangeID == 0 {
+		t.Fatalf("queued = %+v, want server_started for webhook 1", st.queued)
+	}
+
+	q := st.queued[0]
+	d := NewDeliverer(st, Config{}, &logger)
+	d.deliver(store.ClaimWebhookDeliveriesRow{ID: 9, WebhookID: q.WebhookID, EventType: q.EventType, Payload: q.Payload, Url: srv.URL, Secret: "whsec_test"})
+
+	mu.Lock()
+	defer mu.Unlock()
+	var event events.LifecycleV1
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("decode %q: %v", body, err)
+	}
+	if got.Header.Get(HeaderEvent) != "lifecycle.server_started" || event.Type != "server_started" || event.Attributes["db_driver"] != "postgres" {
+		t.Errorf("received %s %+v", got.Header.Get(HeaderEvent), event)
+	}
+	if !ValidEventType("lifecycle.maintenance_on") {
+		t.Error("lifecycle.maintenance_on should be a webhook event type")
+	}
+}
+
+func TestDeliverer(t *testing.T) {
+	logger := zerolog.Nop()
+	var (
+		mu     sync.Mutex
+		status = http.StatusOK
+		got    *http.Request
+		body   []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	st := &fakeStore{}
+	d := NewDeliverer(st, Config{MaxAttempts: 3, RetryBase: time.Second, RetryMax: time.Minute}, &logger)
+	row := store.ClaimWebhookDeliveriesRow{ID: 7, WebhookID: 1, EventType: EventHighScore, Payload: []byte(`{"id":"1.score.high_score"}`), Url: srv.URL, Secret: "whsec_test"}
+
+	d.deliver(row)
+	if c := st.completed[0]; c.Status != StatusDelivered || c.LastStatusCode.Int32 != http.StatusOK {
+		t.Fatalf("completion = %+v, want delivered", c)
+	}
+	if got.Header.Get(HeaderEvent) != EventHighScore || got.Header.Get(HeaderDelivery) != "7" || got.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", got.Header)
+	}
+	if !Verify("whsec_test", got.Header.Get(HeaderSignature), body, time.Minute, time.Now()) {
+		t.Errorf("signature %q does not verify", got.Header.Get(HeaderSignature))
+	}
+
+	// Failures are retried later, then given up after MaxAttempts
+	mu.Lock()
+	status = http.StatusServiceUnavailable
+	mu.Unlock()
+	row.Attempts = 1
+	d.deliver(row)
+	c := st.completed[1]
+	if c.Status != StatusPending || !c.LastError.Valid || c.LastStatusCode.Int32 != http.StatusServiceUnavailable {
+		t.Fatalf("completion = %+v, want a pending retry", c)
+	}
+	if wait := time.Until(c.NextAttemptAt.Time); wait < time.Second || wait > 2*time.Second {
+		t.Errorf("retry in %v, want the second backoff step", wait)
+	}
+	row.Attempts = 2
+	d.deliver(row)
+	if c := st.completed[2]; c.Status != StatusFailed {
+		t.Errorf("completion = %+v, want failed after 3 attempts", c)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDeliverer(&fakeStore{}, Config{RetryBase: 10 * time.Second, RetryMax: time.Minute}, nil)
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 4: time.Minute, 100: time.Minute} {
+		got := d.backoff(attempt)
+		if got > want || got < want*4/5 {
+			t.Errorf("backoff(%d) = %v, want %v minus up to 20%%", attempt, got, want)
+		}
+	}
+}